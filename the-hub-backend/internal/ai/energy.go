@@ -0,0 +1,311 @@
+package ai
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// EnergyLearningWindowDays is how far back completed tasks and time entries are read
+	EnergyLearningWindowDays = 90
+	// energyRelearnInterval is how old a learned profile may get before it is rebuilt
+	energyRelearnInterval = 24 * time.Hour
+	// MinEnergySamples is how many pieces of work are needed before anything is learned
+	MinEnergySamples = 10
+	// MinCategorySamples is how many pieces of work a category needs for its own hours
+	MinCategorySamples = 5
+	// completionMinutes is the work assumed behind a completed task without an estimate
+	completionMinutes = 30
+	// maxSampleMinutes caps a single sample so a forgotten timer does not dominate
+	maxSampleMinutes = 8 * 60
+)
+
+var weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// WorkSample is a stretch of work the energy profile learns from
+type WorkSample struct {
+	Category string
+	Start    time.Time
+	Minutes  int
+}
+
+// EnergyAt returns the energy level (1-10) for work of category starting at hour,
+// preferring what was learned for that category
+func (p *EnergyProfile) EnergyAt(hour int, category string) int {
+	key := strconv.Itoa(hour)
+	if slots, ok := p.CategorySlots[strings.ToLower(strings.TrimSpace(category))]; ok {
+		if level := slots[key]; level > 0 {
+			return level
+		}
+	}
+	if level := p.TimeSlots[key]; level > 0 {
+		return level
+	}
+	return 5 // Default moderate energy if not specified
+}
+
+// LearnEnergy works out when a user works from samples, reading hours and days in loc.
+// Energy per hour scales with the minutes worked in that hour relative to the busiest
+// hour. Nothing is learned from fewer than MinEnergySamples samples.
+func LearnEnergy(samples []WorkSample, loc *time.Location) models.LearnedEnergy {
+	learned := models.LearnedEnergy{
+		TimeSlots:     map[string]int{},
+		CategorySlots: map[string]map[string]int{},
+		WorkDays:      []string{},
+	}
+
+	var hours [24]float64
+	var days [7]float64
+	categoryHours := make(map[string]*[24]float64)
+	categoryCount := make(map[string]int)
+	for _, sample := range samples {
+		if sample.Minutes <= 0 {
+			continue
+		}
+		learned.SampleCount++
+
+		category := strings.ToLower(strings.TrimSpace(sample.Category))
+		var perCategory *[24]float64
+		if category != "" {
+			if categoryHours[category] == nil {
+				categoryHours[category] = &[24]float64{}
+			}
+			perCategory = categoryHours[category]
+			categoryCount[category]++
+		}
+
+		// Spread the minutes over the hours the work spans
+		t := sample.Start.In(loc)
+		remaining := min(sample.Minutes, maxSampleMinutes)
+		for remaining > 0 {
+			chunk := min(remaining, 60-t.Minute())
+			hours[t.Hour()] += float64(chunk)
+			days[t.Weekday()] += float64(chunk)
+			if perCategory != nil {
+				perCategory[t.Hour()] += float64(chunk)
+			}
+			t = t.Add(time.Duration(chunk) * time.Minute)
+			remaining -= chunk
+		}
+	}
+
+	if learned.SampleCount < MinEnergySamples {
+		return learned
+	}
+
+	learned.TimeSlots = energyLevels(hours)
+	for category, histogram := range categoryHours {
+		if categoryCount[category] >= MinCategorySamples {
+			learned.CategorySlots[category] = energyLevels(*histogram)
+		}
+	}
+
+	busiestDay := 0.0
+	for _, minutes := range days {
+		busiestDay = math.Max(busiestDay, minutes)
+	}
+	// Days with at least a quarter of the busiest day's work count as work days
+	for i := 1; i <= 7; i++ {
+		day := i % 7
+		if days[day] >= busiestDay/4 {
+			learned.WorkDays = append(learned.WorkDays, weekdays[day])
+		}
+	}
+
+	// The preferred window holds the middle 80% of the work
+	total := 0.0
+	for _, minutes := range hours {
+		total += minutes
+	}
+	cumulative := 0.0
+	for hour, minutes := range hours {
+		cumulative += minutes
+		if learned.StartHour == nil && cumulative >= total*0.1 {
+			start := hour
+			learned.StartHour = &start
+		}
+		if cumulative >= total*0.9 {
+			end := hour
+			learned.EndHour = &end
+			break
+		}
+	}
+	return learned
+}
+
+// energyLevels turns minutes worked per hour into energy levels from 1 to 10
+func energyLevels(hours [24]float64) map[string]int {
+	busiest := 0.0
+	for _, minutes := range hours {
+		busiest = math.Max(busiest, minutes)
+	}
+	levels := make(map[string]int, len(hours))
+	for hour, minutes := range hours {
+		level := 1
+		if busiest > 0 {
+			level += int(math.Round(9 * minutes / busiest))
+		}
+		levels[strconv.Itoa(hour)] = level
+	}
+	return levels
+}
+
+// BuildEnergyProfile combines a stored profile with the defaults. Settings made by the
+// user win over learned values, which win over the defaults. stored may be nil.
+func BuildEnergyProfile(userID uuid.UUID, stored *models.EnergyProfile) *EnergyProfile {
+	profile := getDefaultEnergyProfile(userID)
+	if stored == nil {
+		return profile
+	}
+
+	if stored.LearningEnabled {
+		learned := stored.Learned()
+		if len(learned.TimeSlots) > 0 {
+			profile.TimeSlots = learned.TimeSlots
+		}
+		if len(learned.CategorySlots) > 0 {
+			profile.CategorySlots = learned.CategorySlots
+		}
+		if len(learned.WorkDays) > 0 {
+			profile.WorkDays = learned.WorkDays
+		}
+		if learned.StartHour != nil && learned.EndHour != nil {
+			profile.PreferredStartHour = *learned.StartHour
+			profile.PreferredEndHour = *learned.EndHour
+		}
+	}
+
+	settings := stored.Settings()
+	for hour, level := range settings.TimeSlots {
+		profile.TimeSlots[hour] = level
+	}
+	for day, workload := range settings.Workload {
+		profile.Workload[day] = workload
+	}
+	if len(settings.WorkDays) > 0 {
+		profile.WorkDays = settings.WorkDays
+	}
+	if settings.PreferredStartHour != nil {
+		profile.PreferredStartHour = *settings.PreferredStartHour
+	}
+	if settings.PreferredEndHour != nil {
+		profile.PreferredEndHour = *settings.PreferredEndHour
+	}
+	if settings.BreakDuration != nil {
+		profile.BreakDuration = *settings.BreakDuration
+	}
+	return profile
+}
+
+// LoadEnergyProfile returns the energy profile used to score slots for a user, falling
+// back to the defaults when the stored profile cannot be loaded
+func LoadEnergyProfile(userID uuid.UUID, loc *time.Location) *EnergyProfile {
+	stored, err := LoadStoredEnergyProfile(userID, loc)
+	if err != nil {
+		config.Logger.Warnf("Could not load energy profile for user %s: %v", userID, err)
+		return getDefaultEnergyProfile(userID)
+	}
+	return BuildEnergyProfile(userID, stored)
+}
+
+// LoadStoredEnergyProfile returns the user's stored profile, creating it on first use
+// and relearning it when it is out of date
+func LoadStoredEnergyProfile(userID uuid.UUID, loc *time.Location) (*models.EnergyProfile, error) {
+	db := config.GetDB()
+	var stored models.EnergyProfile
+	err := db.Where("user_id = ?", userID).First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		stored = models.EnergyProfile{UserID: userID, LearningEnabled: true}
+		if err := db.Where(models.EnergyProfile{UserID: userID}).FirstOrCreate(&stored).Error; err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if stored.LearningEnabled && (stored.LearnedAt == nil || time.Since(*stored.LearnedAt) > energyRelearnInterval) {
+		if err := RelearnEnergyProfile(&stored, loc); err != nil {
+			config.Logger.Warnf("Could not relearn energy profile for user %s: %v", userID, err)
+		}
+	}
+	return &stored, nil
+}
+
+// RelearnEnergyProfile rebuilds the learned part of a profile from the user's recent
+// completed tasks and time entries and saves it
+func RelearnEnergyProfile(stored *models.EnergyProfile, loc *time.Location) error {
+	now := time.Now()
+	samples, err := collectWorkSamples(stored.UserID, now.AddDate(0, 0, -EnergyLearningWindowDays))
+	if err != nil {
+		return err
+	}
+
+	learned := LearnEnergy(samples, loc)
+	learned.LearnedAt = &now
+	if err := stored.SetLearned(learned); err != nil {
+		return err
+	}
+	return config.GetDB().Model(stored).
+		Select("learned_time_slots", "category_time_slots", "learned_work_days", "learned_start_hour",
+			"learned_end_hour", "learned_sample_count", "learned_at").
+		Updates(stored).Error
+}
+
+// collectWorkSamples reads the work a user did since the given time. Tracked time is
+// used where there is any; other completed tasks count as their estimate ending at
+// completion.
+func collectWorkSamples(userID uuid.UUID, since time.Time) ([]WorkSample, error) {
+	db := config.GetDB()
+
+	var entries []struct {
+		Category  string
+		StartTime time.Time
+		EndTime   *time.Time
+		Duration  int
+	}
+	if err := db.Table("time_entries").
+		Select("tasks.category, time_entries.start_time, time_entries.end_time, time_entries.duration").
+		Joins("JOIN tasks ON tasks.id = time_entries.task_id").
+		Where("time_entries.user_id = ? AND time_entries.start_time >= ? AND time_entries.is_running = ?", userID, since, false).
+		Where("time_entries.deleted_at IS NULL").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	var completed []models.Task
+	if err := db.Select("category", "completed_at", "time_estimate").
+		Where("user_id = ? AND completed_at >= ? AND status IN ?", userID, since, []string{"completed", "complete"}).
+		Where("NOT EXISTS (SELECT 1 FROM time_entries WHERE time_entries.task_id = tasks.id AND time_entries.deleted_at IS NULL)").
+		Find(&completed).Error; err != nil {
+		return nil, err
+	}
+
+	samples := make([]WorkSample, 0, len(entries)+len(completed))
+	for _, entry := range entries {
+		minutes := entry.Duration
+		if minutes == 0 && entry.EndTime != nil {
+			minutes = int(entry.EndTime.Sub(entry.StartTime).Minutes())
+		}
+		samples = append(samples, WorkSample{Category: entry.Category, Start: entry.StartTime, Minutes: minutes})
+	}
+	for _, task := range completed {
+		if task.CompletedAt == nil {
+			continue
+		}
+		minutes := completionMinutes
+		if task.TimeEstimate != nil && *task.TimeEstimate > 0 {
+			minutes = *task.TimeEstimate
+		}
+		start := task.CompletedAt.Add(-time.Duration(minutes) * time.Minute)
+		samples = append(samples, WorkSample{Category: task.Category, Start: start, Minutes: minutes})
+	}
+	return samples, nil
+}
@@ -1,7 +1,12 @@
 package ai
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"unicode"
 )
 
 type PDFToFlashcardInput struct {
@@ -49,3 +54,216 @@ Extract the content from the PDF document and create informative flashcards.`, n
 
 	return c.GenerateWithDocument(pdfBase64, "application/pdf", userContent, systemPrompt)
 }
+
+const (
+	// FlashcardChunkChars is the most source text sent in one request
+	FlashcardChunkChars = 6000
+	// maxFlashcardChunks caps the requests made for one source
+	maxFlashcardChunks = 12
+)
+
+// Source text formats
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+)
+
+// GeneratedFlashcard is a card generated from one chunk of a source
+type GeneratedFlashcard struct {
+	Front    string `json:"front"`
+	Back     string `json:"back"`
+	Category string `json:"category"`
+	Chunk    int    `json:"-"` // Index of the chunk it came from
+}
+
+// ChunkText splits text into chunks of at most maxChars. Chunks break between
+// paragraphs, preferring markdown headings, and only split a paragraph, between lines
+// and then words, when it is too long on its own.
+func ChunkText(text string, maxChars int) []string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil
+	}
+
+	var pieces []string
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			pieces = append(pieces, splitLong(paragraph, maxChars)...)
+		}
+	}
+
+	var chunks []string
+	var current strings.Builder
+	for _, piece := range pieces {
+		heading := strings.HasPrefix(piece, "#")
+		// A heading starts a new chunk once the current one is half full
+		if current.Len() > 0 && (current.Len()+2+len(piece) > maxChars || heading && current.Len() > maxChars/2) {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(piece)
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+	return chunks
+}
+
+// splitLong splits a paragraph longer than maxChars between lines, then between words
+func splitLong(paragraph string, maxChars int) []string {
+	if len(paragraph) <= maxChars {
+		return []string{paragraph}
+	}
+	separator := "\n"
+	parts := strings.Split(paragraph, separator)
+	if len(parts) == 1 {
+		separator = " "
+		parts = strings.Fields(paragraph)
+	}
+
+	var out []string
+	current := ""
+	for _, part := range parts {
+		for len(part) > maxChars {
+			if current != "" {
+				out = append(out, current)
+				current = ""
+			}
+			out = append(out, part[:maxChars])
+			part = part[maxChars:]
+		}
+		if current != "" && len(current)+len(separator)+len(part) > maxChars {
+			out = append(out, current)
+			current = ""
+		}
+		if current != "" {
+			current += separator
+		}
+		current += part
+	}
+	if current != "" {
+		out = append(out, current)
+	}
+	return out
+}
+
+// ChunkHash identifies a chunk's content, ignoring changes to whitespace, so cards can
+// tell whether the text they were generated from has since changed
+func ChunkHash(chunk string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(chunk), " ")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// NormalizeFlashcardText reduces a card's side to its words in lower case, for
+// spotting duplicates that differ only in case, punctuation or spacing
+func NormalizeFlashcardText(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// shareCards divides numCards over chunks in proportion to their length, at least one each
+func shareCards(chunks []string, numCards int) []int {
+	total := 0
+	for _, chunk := range chunks {
+		total += len(chunk)
+	}
+	shares := make([]int, len(chunks))
+	given := 0
+	for i, chunk := range chunks {
+		shares[i] = max(numCards*len(chunk)/max(total, 1), 1)
+		given += shares[i]
+	}
+	// Hand out what rounding down left over, longest chunks first
+	for i := 0; given < numCards; i = (i + 1) % len(chunks) {
+		shares[i]++
+		given++
+	}
+	return shares
+}
+
+// parseGeneratedFlashcards reads the JSON array of cards in a response, which may be
+// wrapped in other text
+func parseGeneratedFlashcards(response string) ([]GeneratedFlashcard, error) {
+	var cards []GeneratedFlashcard
+	if err := json.Unmarshal([]byte(response), &cards); err == nil {
+		return cards, nil
+	}
+	start, end := strings.Index(response, "["), strings.LastIndex(response, "]")
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("no JSON array of flashcards found in response")
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &cards); err != nil {
+		return nil, fmt.Errorf("failed to parse flashcards: %w", err)
+	}
+	return cards, nil
+}
+
+// GenerateFlashcardsFromText generates about numCards flashcards from text or markdown.
+// Long text is chunked and the cards shared out over the chunks by length; each card
+// records the chunk it came from. Cards repeating an earlier front are dropped.
+func (c *OpenRouterClient) GenerateFlashcardsFromText(text, format string, numCards int, instruction string) ([]GeneratedFlashcard, []string, error) {
+	chunks := ChunkText(text, FlashcardChunkChars)
+	if len(chunks) == 0 {
+		return nil, nil, fmt.Errorf("no text to generate flashcards from")
+	}
+	if len(chunks) > maxFlashcardChunks {
+		chunks = chunks[:maxFlashcardChunks]
+	}
+
+	kind := "text"
+	if format == FormatMarkdown {
+		kind = "markdown notes"
+	}
+	systemPrompt := fmt.Sprintf(`You are a learning assistant. Generate flashcards from the user's %s.
+Focus on:
+- Key concepts and definitions
+- Important formulas or relationships
+- Critical steps or processes
+- Key terminology
+Generate clear, concise questions and answers that test understanding. Only use what the text says.
+
+Respond with ONLY a JSON array of objects, no other text. Each object contains:
+- "front": question, term, or concept
+- "back": answer, definition, or explanation
+- "category": topic category or section (optional)`, kind)
+
+	var cards []GeneratedFlashcard
+	seen := map[string]bool{}
+	for i, share := range shareCards(chunks, numCards) {
+		prompt := fmt.Sprintf("Generate %d flashcards from the %s below.", share, kind)
+		if instruction != "" {
+			prompt += " " + instruction
+		}
+		if len(chunks) > 1 {
+			prompt += fmt.Sprintf(" It is part %d of %d.", i+1, len(chunks))
+		}
+		prompt += "\n\n" + chunks[i]
+
+		response, err := c.SendMessage([]Message{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: prompt},
+		}, Options{Temperature: 0.3, MaxTokens: 4096})
+		if err != nil {
+			return nil, nil, err
+		}
+		generated, err := parseGeneratedFlashcards(response)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, card := range generated {
+			card.Front, card.Back = strings.TrimSpace(card.Front), strings.TrimSpace(card.Back)
+			key := NormalizeFlashcardText(card.Front)
+			if key == "" || card.Back == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			card.Chunk = i
+			cards = append(cards, card)
+		}
+	}
+	return cards, chunks, nil
+}
@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/TheoMKgosi/The-hub/internal/quiz"
+)
+
+// GenerateQuiz generates about numQuestions questions of the given types from the
+// numbered sources, such as flashcards or notes. The output is validated against
+// quiz.Schema; a response that fails is sent back once with the problem to be fixed.
+func (c *OpenRouterClient) GenerateQuiz(sources []string, numQuestions int, types []string, instruction string) ([]quiz.Question, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no material to generate a quiz from")
+	}
+
+	systemPrompt := fmt.Sprintf(`You are a learning assistant. Write quiz questions that test understanding of the user's study material.
+Use these question types: %s.
+- multiple_choice: 3 to 6 distinct options, one of them correct; "answer" repeats the correct option exactly
+- true_false: no options; "answer" is "true" or "false"
+- short_answer: no options; "answer" is a word or short phrase
+Only ask about what the material says. "source" is the number of the material the question tests.
+
+Respond with ONLY a JSON object, no other text, matching this JSON schema:
+%s`, strings.Join(types, ", "), quiz.Schema)
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Write %d quiz questions from this material.", numQuestions)
+	if instruction != "" {
+		prompt.WriteString(" " + instruction)
+	}
+	for i, source := range sources {
+		fmt.Fprintf(&prompt, "\n\n[%d]\n%s", i+1, strings.TrimSpace(source))
+	}
+
+	messages := []Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: prompt.String()},
+	}
+	var invalid *quiz.ValidationError
+	for attempt := 0; attempt < 2; attempt++ {
+		response, err := c.SendMessage(messages, Options{Temperature: 0.4, MaxTokens: 4096})
+		if err != nil {
+			return nil, err
+		}
+		questions, err := quiz.Parse(response, len(sources), types)
+		if err == nil {
+			return questions, nil
+		}
+		if !errors.As(err, &invalid) {
+			return nil, err
+		}
+		messages = append(messages,
+			Message{Role: "assistant", Content: response},
+			Message{Role: "user", Content: fmt.Sprintf("That does not match the schema (%s). Respond with the corrected JSON object only.", err)},
+		)
+	}
+	return nil, fmt.Errorf("generated quiz is invalid: %w", invalid)
+}
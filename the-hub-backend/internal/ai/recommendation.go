@@ -3,7 +3,6 @@ package ai
 import (
 	"encoding/json"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
@@ -30,69 +29,86 @@ type EnergyProfile struct {
 	PreferredEndHour   int      `json:"preferred_end_hour"`   // Preferred end hour (0-23)
 	WorkDays           []string `json:"work_days"`            // Days of the week user works
 	BreakDuration      int      `json:"break_duration"`       // Preferred break duration in minutes
+	// Learned energy levels per task category: category -> hour -> energy level (1-10)
+	CategorySlots map[string]map[string]int `json:"category_time_slots,omitempty"`
+	// How the user has responded to earlier suggestions, if loaded
+	Feedback *SuggestionFeedback `json:"-"`
 }
 
-// GenerateScheduleSuggestions creates algorithmic schedule suggestions
-func GenerateScheduleSuggestions(userID uuid.UUID, tasks []models.Task, existingEvents []models.ScheduledTask) ([]models.ScheduledTask, error) {
-	// Use algorithmic approach for scheduling suggestions
-	return generateEnhancedRuleBasedSuggestions(userID, tasks, existingEvents)
-}
-
-// generateEnhancedRuleBasedSuggestions provides enhanced rule-based scheduling with hybrid zone + non-zone support
-func generateEnhancedRuleBasedSuggestions(userID uuid.UUID, tasks []models.Task, existingEvents []models.ScheduledTask) ([]models.ScheduledTask, error) {
-	var suggestions []models.ScheduledTask
-
-	// Get user's energy profile (for now, use default if not found)
-	energyProfile := getDefaultEnergyProfile(userID)
+// GenerateScheduleSuggestions plans pending tasks into the user's free time over the
+// next two weeks. Days, working hours and zones are interpreted in loc, the user's
+// timezone. Recurring events in existingEvents need their recurrence rule preloaded so
+// every occurrence blocks time.
+func GenerateScheduleSuggestions(userID uuid.UUID, tasks []models.Task, existingEvents []models.ScheduledTask, loc *time.Location) (*ScheduleResult, error) {
+	now := time.Now().In(loc)
 
 	// Get user's calendar zones
 	zones, err := getUserCalendarZones(userID)
 	if err != nil {
 		// Log error but continue with default behavior
+		config.Logger.Warnf("Could not load calendar zones for user %s: %v", userID, err)
 		zones = []models.CalendarZone{}
 	}
 
-	// Use the new hybrid scheduling system
-	availableSlots := findAvailableTimeSlotsHybrid(userID, tasks, zones, existingEvents, 7)
+	dependencies, err := getOpenDependencies(userID, tasks)
+	if err != nil {
+		return nil, err
+	}
 
-	// Sort tasks by priority and deadline
-	prioritizedTasks := prioritizeTasks(tasks)
+	profile := LoadEnergyProfile(userID, loc)
+	profile.Feedback = LoadSuggestionFeedback(userID, loc)
 
-	for _, task := range prioritizedTasks {
-		if len(availableSlots) == 0 {
-			break
+	result := SolveSchedule(SchedulerInput{
+		UserID:       userID,
+		Tasks:        tasks,
+		Dependencies: dependencies,
+		Events:       models.ExpandSchedule(existingEvents, now, now.AddDate(0, 0, SchedulingHorizonDays+1), loc),
+		Zones:        zones,
+		Profile:      profile,
+		Now:          now,
+	})
+
+	taskByID := make(map[uuid.UUID]*models.Task, len(tasks))
+	for i := range tasks {
+		taskByID[tasks[i].ID] = &tasks[i]
+	}
+	result.Confidence = make([]float32, len(result.Suggestions))
+	for i, suggestion := range result.Suggestions {
+		if task := taskByID[*suggestion.TaskID]; task != nil {
+			slot := TimeSlot{Start: suggestion.Start.In(loc), End: suggestion.End.In(loc)}
+			result.Confidence[i] = SuggestionConfidence(slot, *task, profile)
 		}
+	}
+	return &result, nil
+}
 
-		// Find best time slot for this task, considering zones
-		bestSlot := findBestTimeSlotWithZones(task, availableSlots, energyProfile, zones)
-
-		if bestSlot != nil {
-			suggestion := models.ScheduledTask{
-				Title:       task.Title,
-				Start:       bestSlot.Start,
-				End:         bestSlot.End,
-				UserID:      userID,
-				TaskID:      &task.ID,
-				CreatedByAI: true,
-			}
+// getOpenDependencies returns the dependencies of tasks on prerequisites that are not completed
+func getOpenDependencies(userID uuid.UUID, tasks []models.Task) ([]models.TaskDependency, error) {
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+	ids := make([]uuid.UUID, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
 
-			suggestions = append(suggestions, suggestion)
+	var deps []models.TaskDependency
+	if err := config.GetDB().Preload("DependsOn").Where("user_id = ? AND task_id IN ?", userID, ids).Find(&deps).Error; err != nil {
+		return nil, err
+	}
 
-			// Remove this slot from available slots
-			availableSlots = removeTimeSlot(availableSlots, bestSlot)
+	open := deps[:0]
+	for _, dep := range deps {
+		// Deleted prerequisites are not loaded and no longer hold anything up
+		if dep.DependsOn.ID == uuid.Nil || dep.DependsOn.Status == "completed" || dep.DependsOn.Status == "complete" {
+			continue
 		}
+		open = append(open, dep)
 	}
-
-	return suggestions, nil
-}
-
-// generateRuleBasedSuggestions provides fallback rule-based scheduling (legacy function)
-func generateRuleBasedSuggestions(userID uuid.UUID, tasks []models.Task, existingEvents []models.ScheduledTask) ([]models.ScheduledTask, error) {
-	// Use the enhanced version for backward compatibility
-	return generateEnhancedRuleBasedSuggestions(userID, tasks, existingEvents)
+	return open, nil
 }
 
-// getDefaultEnergyProfile returns a default energy profile
+// getDefaultEnergyProfile returns the energy profile used before anything is known about a user
 func getDefaultEnergyProfile(userID uuid.UUID) *EnergyProfile {
 	return &EnergyProfile{
 		UserID: userID,
@@ -126,136 +142,13 @@ type TimeSlot struct {
 	End   time.Time
 }
 
-// findAvailableTimeSlots finds available time slots for scheduling
-func findAvailableTimeSlots(userID uuid.UUID, existingEvents []models.ScheduledTask, days int) []TimeSlot {
-	var availableSlots []TimeSlot
-
-	now := time.Now()
-	endDate := now.AddDate(0, 0, days)
-
-	// Create time slots from 9 AM to 6 PM each day
-	for d := now; d.Before(endDate); d = d.AddDate(0, 0, 1) {
-		dayStart := time.Date(d.Year(), d.Month(), d.Day(), 9, 0, 0, 0, d.Location())
-		dayEnd := time.Date(d.Year(), d.Month(), d.Day(), 18, 0, 0, 0, d.Location())
-
-		current := dayStart
-		for current.Before(dayEnd) {
-			slotEnd := current.Add(time.Hour)
-
-			// Check if this slot conflicts with existing events
-			if !hasConflict(current, slotEnd, existingEvents) {
-				availableSlots = append(availableSlots, TimeSlot{
-					Start: current,
-					End:   slotEnd,
-				})
-			}
-
-			current = slotEnd
-		}
-	}
-
-	return availableSlots
-}
-
-// hasConflict checks if a time slot conflicts with existing events
-func hasConflict(start, end time.Time, events []models.ScheduledTask) bool {
-	for _, event := range events {
-		if (start.Before(event.End) && end.After(event.Start)) ||
-			(event.Start.Before(end) && event.End.After(start)) {
-			return true
-		}
-	}
-	return false
-}
-
-// prioritizeTasks sorts tasks by priority, deadline, and other factors
-func prioritizeTasks(tasks []models.Task) []models.Task {
-	if len(tasks) <= 1 {
-		return tasks
-	}
-
-	// Create a copy to avoid modifying the original slice
-	prioritized := make([]models.Task, len(tasks))
-	copy(prioritized, tasks)
-
-	// Sort tasks by a scoring system
-	for i := 0; i < len(prioritized)-1; i++ {
-		for j := i + 1; j < len(prioritized); j++ {
-			if taskScore(prioritized[j]) > taskScore(prioritized[i]) {
-				prioritized[i], prioritized[j] = prioritized[j], prioritized[i]
-			}
-		}
-	}
-
-	return prioritized
-}
-
-// taskScore calculates a priority score for a task
-func taskScore(task models.Task) int {
-	score := 0
-
-	// Priority score (higher priority = higher score)
-	if task.Priority != nil {
-		score += *task.Priority * 10
-	}
-
-	// Deadline proximity score
-	if task.DueDate != nil {
-		hoursUntilDue := time.Until(*task.DueDate).Hours()
-		if hoursUntilDue < 0 {
-			// Overdue tasks get highest priority
-			score += 100
-		} else if hoursUntilDue < 24 {
-			score += 50
-		} else if hoursUntilDue < 72 {
-			score += 25
-		}
-	}
-
-	// Time estimate consideration (shorter tasks get slight preference for scheduling)
-	if task.TimeEstimate != nil && *task.TimeEstimate <= 30 {
-		score += 5
-	}
-
-	// Recurring tasks get slight boost
-	if task.IsRecurring {
-		score += 3
-	}
-
-	return score
-}
-
-// findBestTimeSlot finds the best time slot for a task
-func findBestTimeSlot(task models.Task, availableSlots []TimeSlot, profile *EnergyProfile) *TimeSlot {
-	if len(availableSlots) == 0 {
-		return nil
-	}
-
-	bestSlot := &availableSlots[0]
-	bestScore := calculateSlotScore(availableSlots[0], task, profile)
-
-	for _, slot := range availableSlots[1:] {
-		score := calculateSlotScore(slot, task, profile)
-		if score > bestScore {
-			bestScore = score
-			bestSlot = &slot
-		}
-	}
-
-	return bestSlot
-}
-
 // calculateSlotScore calculates how good a time slot is for a task
 func calculateSlotScore(slot TimeSlot, task models.Task, profile *EnergyProfile) int {
 	score := 0
 
-	// Energy level score (0-10)
+	// Energy level score (0-10), using what was learned for the task's category if anything
 	hour := slot.Start.Hour()
-	hourStr := strconv.Itoa(hour)
-	energyLevel := profile.TimeSlots[hourStr]
-	if energyLevel == 0 {
-		energyLevel = 5 // Default moderate energy if not specified
-	}
+	energyLevel := profile.EnergyAt(hour, task.Category)
 	score += energyLevel * 3
 
 	// Workload score (prefer days with lower workload)
@@ -300,83 +193,15 @@ func calculateSlotScore(slot TimeSlot, task models.Task, profile *EnergyProfile)
 		score -= 5 // Lunch time penalty
 	}
 
-	return score
-}
+	// Prefer times at which the user tends to take suggestions
+	score += profile.Feedback.Adjustment(hour, slot.Start.Weekday())
 
-// removeTimeSlot removes a time slot from the list
-func removeTimeSlot(slots []TimeSlot, toRemove *TimeSlot) []TimeSlot {
-	var result []TimeSlot
-	for _, slot := range slots {
-		if slot.Start != toRemove.Start || slot.End != toRemove.End {
-			result = append(result, slot)
-		}
-	}
-	return result
+	return score
 }
 
-// getUserCalendarZones fetches calendar zones for a user
+// getUserCalendarZones fetches a user's active calendar zones with their blackouts
 func getUserCalendarZones(userID uuid.UUID) ([]models.CalendarZone, error) {
-	var zones []models.CalendarZone
-	err := config.GetDB().Where("user_id = ? AND is_active = ?", userID, true).Find(&zones).Error
-	return zones, err
-}
-
-// findAvailableTimeSlotsWithZones finds available time slots considering calendar zones
-func findAvailableTimeSlotsWithZones(userID uuid.UUID, existingEvents []models.ScheduledTask, zones []models.CalendarZone, days int) []TimeSlot {
-	var availableSlots []TimeSlot
-
-	now := time.Now()
-	endDate := now.AddDate(0, 0, days)
-
-	// If no zones are defined, fall back to default behavior
-	if len(zones) == 0 {
-		return findAvailableTimeSlots(userID, existingEvents, days)
-	}
-
-	// Create time slots based on zones
-	for d := now; d.Before(endDate); d = d.AddDate(0, 0, 1) {
-		for _, zone := range zones {
-			if !zone.AllowScheduling {
-				continue
-			}
-
-			// Check if zone applies to this day
-			if !zone.IsTimeInZone(d) {
-				continue
-			}
-
-			// Get available slots within this zone
-			zoneSlots := zone.GetAvailableTimeSlots(d, existingEvents, time.Hour)
-			for _, slot := range zoneSlots {
-				availableSlots = append(availableSlots, TimeSlot{
-					Start: slot,
-					End:   slot.Add(time.Hour),
-				})
-			}
-		}
-	}
-
-	return availableSlots
-}
-
-// findBestTimeSlotWithZones finds the best time slot considering calendar zones
-func findBestTimeSlotWithZones(task models.Task, availableSlots []TimeSlot, profile *EnergyProfile, zones []models.CalendarZone) *TimeSlot {
-	if len(availableSlots) == 0 {
-		return nil
-	}
-
-	bestSlot := &availableSlots[0]
-	bestScore := calculateSlotScoreWithZones(availableSlots[0], task, profile, zones)
-
-	for _, slot := range availableSlots[1:] {
-		score := calculateSlotScoreWithZones(slot, task, profile, zones)
-		if score > bestScore {
-			bestScore = score
-			bestSlot = &slot
-		}
-	}
-
-	return bestSlot
+	return models.LoadCalendarZones(config.GetDB(), userID)
 }
 
 // calculateSlotScoreWithZones calculates slot score considering calendar zones
@@ -388,7 +213,7 @@ func calculateSlotScoreWithZones(slot TimeSlot, task models.Task, profile *Energ
 
 	// Zone-based scoring
 	for _, zone := range zones {
-		if zone.IsTimeInZone(slot.Start) {
+		if zone.IsTimeInZone(slot.Start, slot.Start.Location()) {
 			// Zone preference bonus
 			score += zone.GetZoneScore()
 
@@ -600,7 +425,7 @@ func hasTaskWithKeyword(tasks []models.Task, keyword string) bool {
 }
 
 // GetAISuggestions is the main entry point for AI scheduling (deprecated - use GenerateGoalTaskRecommendations instead)
-func GetAISuggestions(userID uuid.UUID) ([]models.ScheduledTask, error) {
+func GetAISuggestions(userID uuid.UUID, loc *time.Location) ([]models.ScheduledTask, error) {
 	// Get user's tasks (exclude tasks that already have due dates)
 	var tasks []models.Task
 	if err := config.GetDB().Where("user_id = ? AND due_date IS NULL", userID).Find(&tasks).Error; err != nil {
@@ -609,11 +434,15 @@ func GetAISuggestions(userID uuid.UUID) ([]models.ScheduledTask, error) {
 
 	// Get existing scheduled events
 	var existingEvents []models.ScheduledTask
-	if err := config.GetDB().Where("user_id = ?", userID).Find(&existingEvents).Error; err != nil {
+	if err := config.GetDB().Preload("RecurrenceRule").Where("user_id = ?", userID).Find(&existingEvents).Error; err != nil {
 		return nil, err
 	}
 
-	return GenerateScheduleSuggestions(userID, tasks, existingEvents)
+	result, err := GenerateScheduleSuggestions(userID, tasks, existingEvents, loc)
+	if err != nil {
+		return nil, err
+	}
+	return result.Suggestions, nil
 }
 
 // === ENHANCED HYBRID SCHEDULING SYSTEM ===
@@ -625,142 +454,12 @@ type NonZonePreferences struct {
 	DaysOfWeek []string
 }
 
-// findAvailableTimeSlotsHybrid implements the new hybrid scheduling system
-func findAvailableTimeSlotsHybrid(userID uuid.UUID, tasks []models.Task, zones []models.CalendarZone, existingEvents []models.ScheduledTask, days int) []TimeSlot {
-	var allAvailableSlots []TimeSlot
-
-	// Phase 1: Try to schedule in zones with smart filtering
-	zoneSlots := findAvailableTimeSlotsWithSmartZones(userID, tasks, zones, existingEvents, days)
-	allAvailableSlots = append(allAvailableSlots, zoneSlots...)
-
-	// Phase 2: If zones didn't provide enough slots or no zones exist, add non-zone slots
-	if len(allAvailableSlots) == 0 || shouldAddNonZoneSlots(tasks, zoneSlots, zones) {
-		nonZoneSlots := findAvailableNonZoneSlots(userID, tasks, zones, existingEvents, days)
-		allAvailableSlots = append(allAvailableSlots, nonZoneSlots...)
-	}
-
-	return allAvailableSlots
-}
-
-// findAvailableTimeSlotsWithSmartZones processes zones with enhanced task filtering
-func findAvailableTimeSlotsWithSmartZones(userID uuid.UUID, tasks []models.Task, zones []models.CalendarZone, existingEvents []models.ScheduledTask, days int) []TimeSlot {
-	var availableSlots []TimeSlot
-
-	// If no zones exist, return empty (let non-zone handle it)
-	if len(zones) == 0 {
-		return []TimeSlot{}
-	}
-
-	// Process each zone based on its scheduling mode
-	for _, zone := range zones {
-		if !zone.IsActive {
-			continue
-		}
-
-		switch zone.SchedulingMode {
-		case "none":
-			continue // Skip this zone entirely
-		case "non_zone":
-			continue // Handle in non-zone phase
-		case "whitelist":
-			// Only allow explicitly listed task categories/types
-			zoneSlots := processZoneWithWhitelist(zone, tasks, existingEvents, days)
-			availableSlots = append(availableSlots, zoneSlots...)
-		case "blacklist":
-			// Allow all except explicitly blocked categories/types
-			zoneSlots := processZoneWithBlacklist(zone, tasks, existingEvents, days)
-			availableSlots = append(availableSlots, zoneSlots...)
-		default:
-			// Fallback to current behavior for backward compatibility
-			if zone.AllowScheduling {
-				zoneSlots := zone.GetAvailableTimeSlots(time.Now(), existingEvents, time.Hour)
-				for _, slot := range zoneSlots {
-					availableSlots = append(availableSlots, TimeSlot{
-						Start: slot,
-						End:   slot.Add(time.Hour),
-					})
-				}
-			}
-		}
-	}
-
-	return availableSlots
-}
-
-// processZoneWithWhitelist processes a zone using whitelist filtering
-func processZoneWithWhitelist(zone models.CalendarZone, tasks []models.Task, existingEvents []models.ScheduledTask, days int) []TimeSlot {
-	var availableSlots []TimeSlot
-
-	// Find tasks that are allowed in this zone
-	allowedTasks := filterTasksForZoneWhitelist(tasks, zone)
-	if len(allowedTasks) == 0 {
-		return availableSlots // No tasks can be scheduled in this zone
-	}
-
-	// Get available slots within this zone
-	zoneSlots := zone.GetAvailableTimeSlots(time.Now(), existingEvents, time.Hour)
-	for _, slot := range zoneSlots {
-		availableSlots = append(availableSlots, TimeSlot{
-			Start: slot,
-			End:   slot.Add(time.Hour),
-		})
-	}
-
-	return availableSlots
-}
-
-// processZoneWithBlacklist processes a zone using blacklist filtering
-func processZoneWithBlacklist(zone models.CalendarZone, tasks []models.Task, existingEvents []models.ScheduledTask, days int) []TimeSlot {
-	var availableSlots []TimeSlot
-
-	// Find tasks that are not blocked in this zone
-	allowedTasks := filterTasksForZoneBlacklist(tasks, zone)
-	if len(allowedTasks) == 0 {
-		return availableSlots // No tasks can be scheduled in this zone
-	}
-
-	// Get available slots within this zone
-	zoneSlots := zone.GetAvailableTimeSlots(time.Now(), existingEvents, time.Hour)
-	for _, slot := range zoneSlots {
-		availableSlots = append(availableSlots, TimeSlot{
-			Start: slot,
-			End:   slot.Add(time.Hour),
-		})
-	}
-
-	return availableSlots
-}
-
-// findAvailableNonZoneSlots generates slots outside of zone times
-func findAvailableNonZoneSlots(userID uuid.UUID, tasks []models.Task, zones []models.CalendarZone, existingEvents []models.ScheduledTask, days int) []TimeSlot {
-	var availableSlots []TimeSlot
-
-	now := time.Now()
-	endDate := now.AddDate(0, 0, days)
-
-	// Extract non-zone preferences from zones or use defaults
-	nonZonePrefs := extractNonZonePreferences(zones)
-
-	for d := now; d.Before(endDate); d = d.AddDate(0, 0, 1) {
-		// Check if this day should be included
-		if !shouldScheduleOnDay(d, nonZonePrefs.DaysOfWeek) {
-			continue
-		}
-
-		// Generate slots outside of zone times
-		daySlots := generateNonZoneSlotsForDay(d, nonZonePrefs.StartTime, nonZonePrefs.EndTime, existingEvents, zones)
-		availableSlots = append(availableSlots, daySlots...)
-	}
-
-	return availableSlots
-}
-
 // extractNonZonePreferences extracts non-zone scheduling preferences from zones
-func extractNonZonePreferences(zones []models.CalendarZone) NonZonePreferences {
+func extractNonZonePreferences(zones []models.CalendarZone, loc *time.Location) NonZonePreferences {
 	// Default preferences
 	prefs := NonZonePreferences{
-		StartTime:  time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC),  // 9 AM
-		EndTime:    time.Date(0, 1, 1, 18, 0, 0, 0, time.UTC), // 6 PM
+		StartTime:  time.Date(0, 1, 1, 9, 0, 0, 0, loc),  // 9 AM
+		EndTime:    time.Date(0, 1, 1, 18, 0, 0, 0, loc), // 6 PM
 		DaysOfWeek: []string{"monday", "tuesday", "wednesday", "thursday", "friday"},
 	}
 
@@ -792,93 +491,6 @@ func shouldScheduleOnDay(date time.Time, allowedDays []string) bool {
 	return false
 }
 
-// generateNonZoneSlotsForDay generates time slots outside of zone coverage
-func generateNonZoneSlotsForDay(date time.Time, startTime, endTime time.Time, existingEvents []models.ScheduledTask, zones []models.CalendarZone) []TimeSlot {
-	var availableSlots []TimeSlot
-
-	// Create the day's time range
-	dayStart := time.Date(date.Year(), date.Month(), date.Day(),
-		startTime.Hour(), startTime.Minute(), 0, 0, date.Location())
-	dayEnd := time.Date(date.Year(), date.Month(), date.Day(),
-		endTime.Hour(), endTime.Minute(), 0, 0, date.Location())
-
-	current := dayStart
-	for current.Before(dayEnd) {
-		slotEnd := current.Add(time.Hour)
-
-		// Check if this slot is outside all active zones (true non-zone)
-		if !isSlotInAnyActiveZone(current, slotEnd, zones) {
-			// Check for conflicts with existing events
-			if !hasConflict(current, slotEnd, existingEvents) {
-				availableSlots = append(availableSlots, TimeSlot{
-					Start: current,
-					End:   slotEnd,
-				})
-			}
-		}
-
-		current = slotEnd
-	}
-
-	return availableSlots
-}
-
-// isSlotInAnyActiveZone checks if a time slot falls within any active zone
-func isSlotInAnyActiveZone(start, end time.Time, zones []models.CalendarZone) bool {
-	for _, zone := range zones {
-		if zone.IsActive && zone.IsTimeInZone(start) {
-			return true
-		}
-	}
-	return false
-}
-
-// shouldAddNonZoneSlots determines if non-zone slots should be added
-func shouldAddNonZoneSlots(tasks []models.Task, zoneSlots []TimeSlot, zones []models.CalendarZone) bool {
-	// Always add non-zone slots if no zones allow scheduling
-	hasSchedulingZone := false
-	for _, zone := range zones {
-		if zone.AllowScheduling || zone.SchedulingMode == "whitelist" || zone.SchedulingMode == "blacklist" {
-			hasSchedulingZone = true
-			break
-		}
-	}
-
-	if !hasSchedulingZone {
-		return true
-	}
-
-	// Add non-zone slots if we have tasks but insufficient zone slots
-	minSlotsNeeded := len(tasks)
-	return len(zoneSlots) < minSlotsNeeded
-}
-
-// filterTasksForZoneWhitelist filters tasks that are allowed in a zone (whitelist mode)
-func filterTasksForZoneWhitelist(tasks []models.Task, zone models.CalendarZone) []models.Task {
-	var filteredTasks []models.Task
-
-	for _, task := range tasks {
-		if isTaskAllowedByZoneWhitelist(task, zone) {
-			filteredTasks = append(filteredTasks, task)
-		}
-	}
-
-	return filteredTasks
-}
-
-// filterTasksForZoneBlacklist filters tasks that are not blocked in a zone (blacklist mode)
-func filterTasksForZoneBlacklist(tasks []models.Task, zone models.CalendarZone) []models.Task {
-	var filteredTasks []models.Task
-
-	for _, task := range tasks {
-		if !isTaskBlockedByZoneBlacklist(task, zone) {
-			filteredTasks = append(filteredTasks, task)
-		}
-	}
-
-	return filteredTasks
-}
-
 // isTaskAllowedByZoneWhitelist checks if a task is allowed in a zone (whitelist mode)
 func isTaskAllowedByZoneWhitelist(task models.Task, zone models.CalendarZone) bool {
 	// Check category whitelist
@@ -925,27 +537,6 @@ func containsString(slice []string, item string) bool {
 
 // === TASK-ZONE COMPATIBILITY FUNCTIONS ===
 
-// buildTaskZoneMapping creates a mapping of tasks to compatible zones
-func buildTaskZoneMapping(tasks []models.Task, zones []models.CalendarZone) map[uuid.UUID][]models.CalendarZone {
-	taskZoneMap := make(map[uuid.UUID][]models.CalendarZone)
-
-	for _, task := range tasks {
-		var compatibleZones []models.CalendarZone
-
-		for _, zone := range zones {
-			if isTaskCompatibleWithZone(task, zone) {
-				compatibleZones = append(compatibleZones, zone)
-			}
-		}
-
-		if len(compatibleZones) > 0 {
-			taskZoneMap[task.ID] = compatibleZones
-		}
-	}
-
-	return taskZoneMap
-}
-
 // isTaskCompatibleWithZone checks if a task can be scheduled in a zone
 func isTaskCompatibleWithZone(task models.Task, zone models.CalendarZone) bool {
 	if !zone.IsActive {
@@ -966,30 +557,3 @@ func isTaskCompatibleWithZone(task models.Task, zone models.CalendarZone) bool {
 		return zone.AllowScheduling
 	}
 }
-
-// findSlotsForTaskInZones finds available slots for a task within its compatible zones
-func findSlotsForTaskInZones(task models.Task, compatibleZones []models.CalendarZone, existingEvents []models.ScheduledTask, days int) []TimeSlot {
-	var availableSlots []TimeSlot
-
-	now := time.Now()
-	endDate := now.AddDate(0, 0, days)
-
-	for d := now; d.Before(endDate); d = d.AddDate(0, 0, 1) {
-		for _, zone := range compatibleZones {
-			if !zone.IsTimeInZone(d) {
-				continue
-			}
-
-			// Get available slots within this zone
-			zoneSlots := zone.GetAvailableTimeSlots(d, existingEvents, time.Hour)
-			for _, slot := range zoneSlots {
-				availableSlots = append(availableSlots, TimeSlot{
-					Start: slot,
-					End:   slot.Add(time.Hour),
-				})
-			}
-		}
-	}
-
-	return availableSlots
-}
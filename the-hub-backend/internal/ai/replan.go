@@ -0,0 +1,362 @@
+package ai
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Replanning looks at the user's task blocks around now. Blocks that ended without any
+// time tracked on their task are missed, blocks whose tracked work is still running are
+// overrunning and are extended, and upcoming blocks that overlap other events are in
+// conflict. Missed and conflicting work is planned again with the scheduler around
+// everything that stays put. Pinned, recurring and synced events never move.
+
+const (
+	// ReplanLookbackDays is how far back missed blocks are picked up
+	ReplanLookbackDays = 7
+	// overrunExtension is how far past now an overrunning block is extended
+	overrunExtension = 30 * time.Minute
+)
+
+// ReplanInput is everything PlanReplan needs. It does no database access of its own.
+type ReplanInput struct {
+	UserID uuid.UUID
+	// Schedule holds the user's scheduled rows, with recurrence rules preloaded
+	Schedule []models.ScheduledTask
+	// Tasks holds the tasks the blocks in Schedule belong to
+	Tasks []models.Task
+	// TimeEntries holds the time tracked since the lookback window began, including running entries
+	TimeEntries  []models.TimeEntry
+	Dependencies []models.TaskDependency
+	Zones        []models.CalendarZone
+	Profile      *EnergyProfile
+	Now          time.Time // in the user's timezone
+}
+
+// PlanReplan returns the changes that repair the schedule at in.Now, ordered by the
+// start of the first block they touch
+func PlanReplan(in ReplanInput) []models.ReplanChange {
+	now := in.Now
+	from := now.AddDate(0, 0, -ReplanLookbackDays)
+	events := models.ExpandSchedule(in.Schedule, from, now.AddDate(0, 0, SchedulingHorizonDays+1), now.Location())
+
+	tasks := make(map[uuid.UUID]*models.Task, len(in.Tasks))
+	for i := range in.Tasks {
+		tasks[in.Tasks[i].ID] = &in.Tasks[i]
+	}
+	movable := func(event models.ScheduledTask) bool {
+		if event.TaskID == nil || event.Pinned || event.RecurringEventID != nil || event.RecurrenceRuleID != nil || event.Source != "" {
+			return false
+		}
+		task, ok := tasks[*event.TaskID]
+		return ok && task.Status != "completed" && task.Status != "complete"
+	}
+
+	var fixed, past, upcoming []models.ScheduledTask
+	for _, event := range events {
+		switch {
+		case !movable(event):
+			fixed = append(fixed, event)
+		case event.End.After(now):
+			upcoming = append(upcoming, event)
+		default:
+			past = append(past, event)
+		}
+	}
+
+	var changes []models.ReplanChange
+	var moved []models.ScheduledTask
+	reasons := make(map[uuid.UUID]string)
+
+	// Blocks that have ended were either worked on, are still being worked on or were missed
+	var extended []models.ScheduledTask
+	for _, block := range past {
+		tracked, running := trackedDuring(in.TimeEntries, block, now)
+		switch {
+		case running:
+			end := extendedEnd(block, fixed, now)
+			if !end.After(now) {
+				fixed = append(fixed, block)
+				continue
+			}
+			after := block
+			after.End = end
+			extended = append(extended, after)
+			changes = append(changes, models.ReplanChange{
+				TaskID:  *block.TaskID,
+				Title:   tasks[*block.TaskID].Title,
+				Reasons: []string{models.ReplanOverrun},
+				Before:  []models.ReplanBlock{replanBlock(block, true)},
+				After:   []models.ReplanBlock{replanBlock(after, true)},
+			})
+		case tracked:
+			fixed = append(fixed, block)
+		default:
+			moved = append(moved, block)
+			reasons[block.ID] = models.ReplanMissed
+		}
+	}
+	fixed = append(fixed, extended...)
+
+	// Upcoming blocks keep their place unless they collide with something that stays
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		if !upcoming[i].Start.Equal(upcoming[j].Start) {
+			return upcoming[i].Start.Before(upcoming[j].Start)
+		}
+		return upcoming[i].ID.String() < upcoming[j].ID.String()
+	})
+	for _, block := range upcoming {
+		if overlapsAny(block, fixed) {
+			moved = append(moved, block)
+			reasons[block.ID] = models.ReplanConflict
+			continue
+		}
+		fixed = append(fixed, block)
+	}
+
+	if len(moved) == 0 {
+		return sortChanges(changes)
+	}
+
+	// The moved work of each task is planned again as one piece
+	byTask := make(map[uuid.UUID][]models.ScheduledTask)
+	var order []uuid.UUID
+	for _, block := range moved {
+		if _, seen := byTask[*block.TaskID]; !seen {
+			order = append(order, *block.TaskID)
+		}
+		byTask[*block.TaskID] = append(byTask[*block.TaskID], block)
+	}
+	work := make([]models.Task, 0, len(order))
+	for _, taskID := range order {
+		minutes := 0
+		for _, block := range byTask[taskID] {
+			minutes += int(block.End.Sub(block.Start).Minutes())
+		}
+		task := *tasks[taskID]
+		task.TimeEstimate = &minutes
+		task.TimeSpent = 0
+		work = append(work, task)
+	}
+
+	result := SolveSchedule(SchedulerInput{
+		UserID:       in.UserID,
+		Tasks:        work,
+		Dependencies: in.Dependencies,
+		Events:       fixed,
+		Zones:        in.Zones,
+		Profile:      in.Profile,
+		Now:          now,
+	})
+	planned := make(map[uuid.UUID][]models.ScheduledTask)
+	for _, block := range result.Suggestions {
+		planned[*block.TaskID] = append(planned[*block.TaskID], block)
+	}
+	unschedulable := make(map[uuid.UUID]string)
+	for _, task := range result.Unschedulable {
+		unschedulable[task.TaskID] = task.Reason
+	}
+
+	for _, taskID := range order {
+		change := models.ReplanChange{
+			TaskID:        taskID,
+			Title:         tasks[taskID].Title,
+			Reasons:       []string{},
+			Before:        []models.ReplanBlock{},
+			After:         []models.ReplanBlock{},
+			Unschedulable: unschedulable[taskID],
+		}
+		for _, block := range byTask[taskID] {
+			change.Before = append(change.Before, replanBlock(block, true))
+			if !containsString(change.Reasons, reasons[block.ID]) {
+				change.Reasons = append(change.Reasons, reasons[block.ID])
+			}
+		}
+		// Existing blocks are reused for the new placements so their IDs survive the move
+		for i, block := range planned[taskID] {
+			after := replanBlock(block, false)
+			if i < len(change.Before) {
+				after.ID = change.Before[i].ID
+			}
+			change.After = append(change.After, after)
+		}
+		changes = append(changes, change)
+	}
+	return sortChanges(changes)
+}
+
+// trackedDuring reports whether time was tracked on a block's task while the block ran,
+// and whether that work is still running
+func trackedDuring(entries []models.TimeEntry, block models.ScheduledTask, now time.Time) (tracked, running bool) {
+	for _, entry := range entries {
+		if entry.TaskID != *block.TaskID {
+			continue
+		}
+		end := now
+		if !entry.IsRunning {
+			if entry.EndTime == nil {
+				continue
+			}
+			end = *entry.EndTime
+		}
+		if entry.StartTime.Before(block.End) && end.After(block.Start) {
+			tracked = true
+			running = running || entry.IsRunning
+		}
+	}
+	return tracked, running
+}
+
+// extendedEnd is where an overrunning block ends once extended, stopping short of the
+// next event that cannot move
+func extendedEnd(block models.ScheduledTask, fixed []models.ScheduledTask, now time.Time) time.Time {
+	end := now.Truncate(schedulerQuantum).Add(overrunExtension)
+	for _, event := range fixed {
+		if !event.Start.Before(block.End) && event.Start.Before(end) {
+			end = event.Start
+		}
+	}
+	return end
+}
+
+func overlapsAny(block models.ScheduledTask, events []models.ScheduledTask) bool {
+	for _, event := range events {
+		if event.Start.Before(block.End) && block.Start.Before(event.End) {
+			return true
+		}
+	}
+	return false
+}
+
+func replanBlock(block models.ScheduledTask, existing bool) models.ReplanBlock {
+	rb := models.ReplanBlock{Title: block.Title, Start: block.Start.UTC(), End: block.End.UTC()}
+	if existing {
+		id := block.ID
+		rb.ID = &id
+	}
+	return rb
+}
+
+func sortChanges(changes []models.ReplanChange) []models.ReplanChange {
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Before[0].Start.Before(changes[j].Before[0].Start)
+	})
+	if changes == nil {
+		return []models.ReplanChange{}
+	}
+	return changes
+}
+
+// GenerateReplan loads a user's schedule and works out how to repair it at now
+func GenerateReplan(userID uuid.UUID, now time.Time) ([]models.ReplanChange, error) {
+	db := config.GetDB()
+	from := now.AddDate(0, 0, -ReplanLookbackDays)
+
+	var schedule []models.ScheduledTask
+	if err := db.Preload("RecurrenceRule").Where("user_id = ?", userID).Find(&schedule).Error; err != nil {
+		return nil, err
+	}
+
+	var taskIDs []uuid.UUID
+	for _, row := range schedule {
+		if row.TaskID != nil {
+			taskIDs = append(taskIDs, *row.TaskID)
+		}
+	}
+	var tasks []models.Task
+	if len(taskIDs) > 0 {
+		if err := db.Where("user_id = ? AND id IN ?", userID, taskIDs).Find(&tasks).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []models.TimeEntry
+	if err := db.Where("user_id = ? AND (is_running = ? OR end_time >= ?)", userID, true, from).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	dependencies, err := getOpenDependencies(userID, tasks)
+	if err != nil {
+		return nil, err
+	}
+	zones, err := getUserCalendarZones(userID)
+	if err != nil {
+		config.Logger.Warnf("Could not load calendar zones for user %s: %v", userID, err)
+		zones = []models.CalendarZone{}
+	}
+
+	return PlanReplan(ReplanInput{
+		UserID:       userID,
+		Schedule:     schedule,
+		Tasks:        tasks,
+		TimeEntries:  entries,
+		Dependencies: dependencies,
+		Zones:        zones,
+		Profile:      LoadEnergyProfile(userID, now.Location()),
+		Now:          now,
+	}), nil
+}
+
+// ProposeReplan stores the changes that repair a user's schedule as a pending proposal,
+// superseding any earlier one. It returns nil when nothing needs to change. Automatic
+// proposals are also skipped when the latest proposal dealt with the same blocks, so a
+// rejected replan is not offered again on every run.
+func ProposeReplan(userID uuid.UUID, loc *time.Location, automatic bool) (*models.ReplanProposal, error) {
+	changes, err := GenerateReplan(userID, time.Now().In(loc))
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	db := config.GetDB()
+	if automatic {
+		var latest models.ReplanProposal
+		err := db.Where("user_id = ? AND status <> ?", userID, models.ReplanSuperseded).Order("created_at DESC").First(&latest).Error
+		if err == nil && replanKey(latest.GetChanges()) == replanKey(changes) {
+			return nil, nil
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	proposal := models.ReplanProposal{UserID: userID, Status: models.ReplanPending, Automatic: automatic}
+	if err := proposal.SetChanges(changes); err != nil {
+		return nil, err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ReplanProposal{}).
+			Where("user_id = ? AND status = ?", userID, models.ReplanPending).
+			Update("status", models.ReplanSuperseded).Error; err != nil {
+			return err
+		}
+		return tx.Create(&proposal).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// replanKey identifies the blocks a set of changes deals with and why
+func replanKey(changes []models.ReplanChange) string {
+	var parts []string
+	for _, change := range changes {
+		for _, block := range change.Before {
+			if block.ID != nil {
+				parts = append(parts, block.ID.String()+"@"+block.Start.UTC().Format(time.RFC3339)+"/"+strings.Join(change.Reasons, ","))
+			}
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"context"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/google/uuid"
+)
+
+// ReplanWorker periodically proposes replans for users whose schedule has slipped
+type ReplanWorker struct {
+	interval time.Duration
+}
+
+// NewReplanWorker creates a worker that checks schedules every interval
+func NewReplanWorker(interval time.Duration) *ReplanWorker {
+	return &ReplanWorker{interval: interval}
+}
+
+// Run checks immediately and then on every tick until ctx is cancelled
+func (w *ReplanWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.ReplanAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReplanAll proposes a replan for every user with task blocks that could need one.
+// Failures are logged per user so one broken schedule does not block the others.
+func (w *ReplanWorker) ReplanAll(ctx context.Context) {
+	now := time.Now()
+	var userIDs []uuid.UUID
+	if err := config.GetDB().Model(&models.ScheduledTask{}).Distinct("user_id").
+		Where(`task_id IS NOT NULL AND pinned IS NOT TRUE AND "end" >= ? AND "start" <= ?`,
+			now.AddDate(0, 0, -ReplanLookbackDays), now.AddDate(0, 0, SchedulingHorizonDays)).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		config.Logger.Errorf("Failed to load users for replanning: %v", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		proposal, err := ProposeReplan(userID, util.LoadUserLocation(config.GetDB(), userID), true)
+		if err != nil {
+			config.Logger.Errorf("Replanning failed for user %s: %v", userID, err)
+			continue
+		}
+		if proposal != nil {
+			config.Logger.Infof("Proposed replan %s for user %s", proposal.ID, userID)
+		}
+	}
+}
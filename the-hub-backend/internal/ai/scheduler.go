@@ -0,0 +1,661 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+)
+
+// The scheduler divides the planning horizon into 15 minute quanta. Tasks are split
+// into chunks and placed one at a time in dependency order, earliest effective
+// deadline first. Each chunk goes to the best-scoring free window that still leaves
+// room for the rest of the task before its deadline. Every choice is broken by a
+// fixed order, so the same input always gives the same plan.
+
+const (
+	// SchedulingHorizonDays is how far ahead the scheduler plans
+	SchedulingHorizonDays = 14
+
+	schedulerQuantum   = 15 * time.Minute
+	minChunkMinutes    = 30
+	maxChunkMinutes    = 120
+	defaultTaskMinutes = 60
+)
+
+// SchedulerInput is everything the scheduler needs. It does no database access of its own.
+type SchedulerInput struct {
+	UserID uuid.UUID
+	Tasks  []models.Task
+	// Dependencies of the tasks on prerequisites that are not completed yet, with DependsOn loaded
+	Dependencies []models.TaskDependency
+	// Events is the time that is already taken, with recurring events expanded
+	Events  []models.ScheduledTask
+	Zones   []models.CalendarZone
+	Profile *EnergyProfile
+	Now     time.Time // in the user's timezone
+	Days    int       // defaults to SchedulingHorizonDays
+}
+
+// UnschedulableTask explains why a task was left out of the plan
+type UnschedulableTask struct {
+	TaskID uuid.UUID `json:"task_id"`
+	Title  string    `json:"title"`
+	Reason string    `json:"reason"`
+}
+
+// ScheduleResult is the scheduler's plan
+type ScheduleResult struct {
+	// Suggestions holds one scheduled block per chunk, ordered by start time
+	Suggestions   []models.ScheduledTask
+	Unschedulable []UnschedulableTask
+	// Confidence holds how likely each suggestion is to be taken, when filled in by
+	// GenerateScheduleSuggestions
+	Confidence []float32
+}
+
+type taskState int
+
+const (
+	taskPending taskState = iota
+	taskPlaced
+	taskFailed
+)
+
+// plannedTask is a task in the scheduler's dependency graph
+type plannedTask struct {
+	task       *models.Task
+	quanta     int   // total work
+	prereqs    []int // indices of prerequisites being scheduled in the same run
+	dependents []int
+	// earliest is the first quantum the task may start in, from prerequisites scheduled earlier
+	earliest int
+	// deadline is the quantum by which the last chunk must end
+	deadline    int
+	hasDeadline bool
+	effDeadline int // deadline tightened by the dependents that must follow the task
+	state       taskState
+	end         int // quantum after the last chunk once placed
+	blockedBy   string
+}
+
+type scheduler struct {
+	in      SchedulerInput
+	loc     *time.Location
+	start   time.Time
+	quanta  int
+	buffer  int
+	busy    []bool
+	day     []int
+	zoneAt  []int // index into zones, -1 outside every zone
+	window  []int // id of the contiguous run of schedulable time, -1 where nothing may be placed
+	zones   []models.CalendarZone
+	load    map[[2]int]int // events per zone and day
+	compat  map[compatKey]bool
+	nonZone bool
+}
+
+// SolveSchedule plans in.Tasks into the free time of the next in.Days days. Tasks that
+// cannot be placed are returned with the reason.
+func SolveSchedule(in SchedulerInput) ScheduleResult {
+	if in.Days <= 0 {
+		in.Days = SchedulingHorizonDays
+	}
+	if in.Profile == nil {
+		in.Profile = getDefaultEnergyProfile(in.UserID)
+	}
+
+	s := newScheduler(in)
+	tasks := s.buildGraph()
+	order := s.order(tasks)
+
+	result := ScheduleResult{Suggestions: []models.ScheduledTask{}, Unschedulable: []UnschedulableTask{}}
+	for _, i := range order {
+		t := &tasks[i]
+		if t.state == taskPending {
+			for _, p := range t.prereqs {
+				if tasks[p].state == taskFailed {
+					t.state = taskFailed
+					t.blockedBy = fmt.Sprintf("depends on %q, which could not be scheduled", tasks[p].task.Title)
+					break
+				}
+				t.earliest = max(t.earliest, tasks[p].end+s.buffer)
+			}
+		}
+		if t.state == taskPending {
+			if blocks, reason := s.place(t); reason != "" {
+				t.state = taskFailed
+				t.blockedBy = reason
+			} else {
+				t.state = taskPlaced
+				result.Suggestions = append(result.Suggestions, blocks...)
+			}
+		}
+		if t.state == taskFailed {
+			result.Unschedulable = append(result.Unschedulable, UnschedulableTask{
+				TaskID: t.task.ID,
+				Title:  t.task.Title,
+				Reason: t.blockedBy,
+			})
+		}
+	}
+
+	sort.SliceStable(result.Suggestions, func(i, j int) bool {
+		return result.Suggestions[i].Start.Before(result.Suggestions[j].Start)
+	})
+	return result
+}
+
+func newScheduler(in SchedulerInput) *scheduler {
+	loc := in.Now.Location()
+	start := in.Now.Truncate(schedulerQuantum)
+	if start.Before(in.Now) {
+		start = start.Add(schedulerQuantum)
+	}
+	end := start.AddDate(0, 0, in.Days)
+	quanta := int(end.Sub(start) / schedulerQuantum)
+
+	s := &scheduler{
+		in:      in,
+		loc:     loc,
+		start:   start,
+		quanta:  quanta,
+		buffer:  ceilQuanta(time.Duration(in.Profile.BreakDuration) * time.Minute),
+		busy:    make([]bool, quanta),
+		day:     make([]int, quanta),
+		zoneAt:  make([]int, quanta),
+		window:  make([]int, quanta),
+		load:    make(map[[2]int]int),
+		compat:  make(map[compatKey]bool),
+		nonZone: true,
+	}
+
+	// Zones are checked in a fixed order: higher priority first
+	for _, zone := range in.Zones {
+		if zone.IsActive && zone.SchedulingMode != "non_zone" {
+			s.zones = append(s.zones, zone)
+		}
+		if zone.IsActive && !zone.AllowNonZoneScheduling {
+			s.nonZone = false
+		}
+	}
+	sort.SliceStable(s.zones, func(i, j int) bool {
+		if s.zones[i].Priority != s.zones[j].Priority {
+			return s.zones[i].Priority > s.zones[j].Priority
+		}
+		return s.zones[i].ID.String() < s.zones[j].ID.String()
+	})
+
+	prefs := extractNonZonePreferences(in.Zones, loc)
+	prefStart, prefEnd := prefs.StartTime.In(loc), prefs.EndTime.In(loc)
+	firstDay := time.Date(start.In(loc).Year(), start.In(loc).Month(), start.In(loc).Day(), 0, 0, 0, 0, loc)
+
+	window := -1
+	for q := 0; q < quanta; q++ {
+		at := s.at(q).In(loc)
+		s.day[q] = int(time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC).
+			Sub(time.Date(firstDay.Year(), firstDay.Month(), firstDay.Day(), 0, 0, 0, 0, time.UTC)).Hours() / 24)
+
+		s.zoneAt[q] = -1
+		for z := range s.zones {
+			if s.zones[z].CoversSpan(at, at.Add(schedulerQuantum), loc) {
+				s.zoneAt[q] = z
+				break
+			}
+		}
+
+		schedulable := s.zoneAt[q] >= 0
+		if !schedulable && s.nonZone && shouldScheduleOnDay(at, prefs.DaysOfWeek) {
+			minute := at.Hour()*60 + at.Minute()
+			schedulable = minute >= prefStart.Hour()*60+prefStart.Minute() &&
+				minute+int(schedulerQuantum/time.Minute) <= prefEnd.Hour()*60+prefEnd.Minute()
+		}
+
+		switch {
+		case !schedulable:
+			s.window[q] = -1
+			continue
+		case q == 0 || s.window[q-1] < 0 || s.day[q] != s.day[q-1] || s.zoneAt[q] != s.zoneAt[q-1]:
+			window++
+		}
+		s.window[q] = window
+	}
+
+	for _, event := range in.Events {
+		first, last := s.quantumOf(event.Start), s.quantumOf(event.End.Add(-time.Nanosecond))
+		if last < 0 || first >= quanta || !event.End.After(event.Start) {
+			continue
+		}
+		first, last = max(first, 0), min(last, quanta-1)
+		for q := first; q <= last; q++ {
+			s.busy[q] = true
+		}
+		if z := s.zoneAt[first]; z >= 0 && !event.Start.Before(s.start) {
+			s.load[[2]int{z, s.day[first]}]++
+		}
+	}
+	return s
+}
+
+// buildGraph turns the input into planned tasks sorted by ID and links their dependencies
+func (s *scheduler) buildGraph() []plannedTask {
+	sorted := make([]models.Task, len(s.in.Tasks))
+	copy(sorted, s.in.Tasks)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID.String() < sorted[j].ID.String() })
+
+	tasks := make([]plannedTask, len(sorted))
+	index := make(map[uuid.UUID]int, len(sorted))
+	for i := range sorted {
+		task := &sorted[i]
+		index[task.ID] = i
+		t := plannedTask{task: task, quanta: ceilQuanta(time.Duration(taskMinutes(task)) * time.Minute), deadline: s.quanta}
+		if task.DueDate != nil && task.DueDate.After(s.start) {
+			t.hasDeadline = true
+			t.deadline = min(int(task.DueDate.Sub(s.start)/schedulerQuantum), s.quanta)
+		}
+		if task.DueDate != nil && !task.DueDate.After(s.start) {
+			// Overdue work goes first and is fitted in as soon as possible
+			t.effDeadline = -1
+		} else {
+			t.effDeadline = t.deadline
+		}
+		tasks[i] = t
+	}
+
+	deps := make([]models.TaskDependency, len(s.in.Dependencies))
+	copy(deps, s.in.Dependencies)
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].TaskID != deps[j].TaskID {
+			return deps[i].TaskID.String() < deps[j].TaskID.String()
+		}
+		return deps[i].DependsOnID.String() < deps[j].DependsOnID.String()
+	})
+
+	// Latest scheduled work of prerequisites that are not part of this run
+	booked := make(map[uuid.UUID]time.Time)
+	for _, event := range s.in.Events {
+		if event.TaskID != nil && event.End.After(booked[*event.TaskID]) {
+			booked[*event.TaskID] = event.End
+		}
+	}
+
+	for _, dep := range deps {
+		i, ok := index[dep.TaskID]
+		if !ok || dep.TaskID == dep.DependsOnID {
+			continue
+		}
+		t := &tasks[i]
+		if p, ok := index[dep.DependsOnID]; ok {
+			t.prereqs = append(t.prereqs, p)
+			tasks[p].dependents = append(tasks[p].dependents, i)
+			continue
+		}
+		if end, ok := booked[dep.DependsOnID]; ok {
+			t.earliest = max(t.earliest, s.quantumOf(end.Add(schedulerQuantum-time.Nanosecond)))
+			continue
+		}
+		if t.state == taskPending {
+			t.state = taskFailed
+			t.blockedBy = fmt.Sprintf("depends on %q, which is not finished or scheduled", dep.DependsOn.Title)
+		}
+	}
+	return tasks
+}
+
+// order returns task indices in dependency order, choosing among ready tasks the one
+// with the earliest effective deadline. Tasks on a dependency cycle are marked failed.
+func (s *scheduler) order(tasks []plannedTask) []int {
+	waiting := make([]int, len(tasks))
+	for i := range tasks {
+		waiting[i] = len(tasks[i].prereqs)
+	}
+
+	// Reverse topological order tightens prerequisites' deadlines to leave room for dependents
+	var topo []int
+	queue := []int{}
+	for i := range tasks {
+		if waiting[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+	remaining := append([]int(nil), waiting...)
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		topo = append(topo, i)
+		for _, d := range tasks[i].dependents {
+			remaining[d]--
+			if remaining[d] == 0 {
+				queue = append(queue, d)
+			}
+		}
+	}
+	for k := len(topo) - 1; k >= 0; k-- {
+		t := &tasks[topo[k]]
+		for _, d := range t.dependents {
+			t.effDeadline = min(t.effDeadline, tasks[d].effDeadline-tasks[d].quanta-s.buffer)
+		}
+	}
+
+	// Tasks left out of the topological order are on a cycle or wait on one. Peeling
+	// off those nothing else waits for leaves the cycles themselves.
+	inCycle := make([]bool, len(tasks))
+	for i := range inCycle {
+		inCycle[i] = true
+	}
+	for _, i := range topo {
+		inCycle[i] = false
+	}
+	onCycle := append([]bool(nil), inCycle...)
+	for changed := true; changed; {
+		changed = false
+		for i := range tasks {
+			if !onCycle[i] {
+				continue
+			}
+			waitedOn := false
+			for _, d := range tasks[i].dependents {
+				waitedOn = waitedOn || onCycle[d]
+			}
+			if !waitedOn {
+				onCycle[i] = false
+				changed = true
+			}
+		}
+	}
+	for i := range tasks {
+		if !inCycle[i] || tasks[i].state == taskFailed {
+			continue
+		}
+		tasks[i].state = taskFailed
+		for _, p := range tasks[i].prereqs {
+			switch {
+			case onCycle[i] && onCycle[p]:
+				tasks[i].blockedBy = fmt.Sprintf("circular dependency with %q", tasks[p].task.Title)
+			case !onCycle[i] && inCycle[p]:
+				tasks[i].blockedBy = fmt.Sprintf("depends on %q, which is part of a circular dependency", tasks[p].task.Title)
+			default:
+				continue
+			}
+			break
+		}
+	}
+
+	before := func(a, b int) bool {
+		ta, tb := tasks[a], tasks[b]
+		if ta.effDeadline != tb.effDeadline {
+			return ta.effDeadline < tb.effDeadline
+		}
+		pa, pb := taskPriority(ta.task), taskPriority(tb.task)
+		if pa != pb {
+			return pa > pb
+		}
+		if ta.task.OrderIndex != tb.task.OrderIndex {
+			return ta.task.OrderIndex < tb.task.OrderIndex
+		}
+		if ta.task.Title != tb.task.Title {
+			return ta.task.Title < tb.task.Title
+		}
+		return a < b // tasks are sorted by ID
+	}
+
+	var order []int
+	ready := map[int]bool{}
+	for i := range tasks {
+		if waiting[i] == 0 && !inCycle[i] {
+			ready[i] = true
+		}
+	}
+	for len(ready) > 0 {
+		next := -1
+		for i := range ready {
+			if next < 0 || before(i, next) {
+				next = i
+			}
+		}
+		delete(ready, next)
+		order = append(order, next)
+		for _, d := range tasks[next].dependents {
+			waiting[d]--
+			if waiting[d] == 0 && !inCycle[d] {
+				ready[d] = true
+			}
+		}
+	}
+	for i := range tasks {
+		if inCycle[i] {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// place finds room for every chunk of t, or explains why there is none
+func (s *scheduler) place(t *plannedTask) ([]models.ScheduledTask, string) {
+	longest := s.longestWindow(t.task)
+	chunk := min(maxChunkMinutes/int(schedulerQuantum/time.Minute), longest)
+	if longest == 0 || chunk*int(schedulerQuantum/time.Minute) < min(minChunkMinutes, t.quanta*int(schedulerQuantum/time.Minute)) {
+		return nil, fmt.Sprintf("no calendar zone or working hours in the next %d days accept this task", s.in.Days)
+	}
+	chunks := splitChunks(t.quanta, chunk)
+
+	type candidate struct {
+		q, score int
+	}
+	cur := max(t.earliest, 0)
+	var placed [][2]int
+	for i, n := range chunks {
+		var candidates []candidate
+		for q := cur; q+n <= t.deadline; q++ {
+			if s.feasible(q, n, t.task) {
+				candidates = append(candidates, candidate{q, s.score(q, n, t.task)})
+			}
+		}
+		sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].score > candidates[b].score })
+
+		chosen := -1
+		for _, c := range candidates {
+			if s.fitsAfter(c.q+n+s.buffer, chunks[i+1:], t.deadline, t.task) {
+				chosen = c.q
+				break
+			}
+		}
+		if chosen < 0 {
+			for _, p := range placed {
+				s.release(p[0], p[1])
+			}
+			return nil, s.shortfall(t)
+		}
+		s.occupy(chosen, n)
+		placed = append(placed, [2]int{chosen, n})
+		cur = chosen + n + s.buffer
+	}
+
+	blocks := make([]models.ScheduledTask, len(placed))
+	for i, p := range placed {
+		title := t.task.Title
+		if len(placed) > 1 {
+			title = fmt.Sprintf("%s (%d/%d)", t.task.Title, i+1, len(placed))
+		}
+		taskID := t.task.ID
+		blocks[i] = models.ScheduledTask{
+			Title:       title,
+			Start:       s.at(p[0]),
+			End:         s.at(p[0] + p[1]),
+			UserID:      s.in.UserID,
+			TaskID:      &taskID,
+			CreatedByAI: true,
+		}
+	}
+	t.end = placed[len(placed)-1][0] + placed[len(placed)-1][1]
+	return blocks, ""
+}
+
+// shortfall explains a task that did not fit
+func (s *scheduler) shortfall(t *plannedTask) string {
+	needs := formatMinutes(t.quanta * int(schedulerQuantum/time.Minute))
+	switch {
+	case t.hasDeadline && t.earliest >= t.deadline:
+		return fmt.Sprintf("due %s, before its prerequisites can be finished", s.at(t.deadline).In(s.loc).Format("Mon Jan 2 15:04"))
+	case t.hasDeadline:
+		return fmt.Sprintf("not enough free time before it is due %s (needs %s)", s.at(t.deadline).In(s.loc).Format("Mon Jan 2 15:04"), needs)
+	default:
+		return fmt.Sprintf("not enough free time in the next %d days (needs %s)", s.in.Days, needs)
+	}
+}
+
+// fitsAfter reports whether chunks can still be placed, as early as possible, from
+// quantum q and finish by deadline
+func (s *scheduler) fitsAfter(q int, chunks []int, deadline int, task *models.Task) bool {
+	for _, n := range chunks {
+		for q+n <= deadline && !s.feasible(q, n, task) {
+			q++
+		}
+		if q+n > deadline {
+			return false
+		}
+		q += n + s.buffer
+	}
+	return true
+}
+
+// feasible reports whether a chunk of n quanta can start at q
+func (s *scheduler) feasible(q, n int, task *models.Task) bool {
+	if q < 0 || q+n > s.quanta || s.window[q] < 0 || s.window[q] != s.window[q+n-1] {
+		return false
+	}
+	if z := s.zoneAt[q]; z >= 0 {
+		if !s.compatible(task, z) {
+			return false
+		}
+		if limit := s.zones[z].MaxEventsPerDay; limit != nil && s.load[[2]int{z, s.day[q]}] >= *limit {
+			return false
+		}
+	}
+	for i := max(q-s.buffer, 0); i < min(q+n+s.buffer, s.quanta); i++ {
+		if s.busy[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// score rates a chunk starting at q. Nearer days are slightly preferred so that equal
+// slots are not pushed to the end of the horizon.
+func (s *scheduler) score(q, n int, task *models.Task) int {
+	slot := TimeSlot{Start: s.at(q).In(s.loc), End: s.at(q + n).In(s.loc)}
+	var zones []models.CalendarZone
+	if z := s.zoneAt[q]; z >= 0 {
+		zones = s.zones[z : z+1]
+	}
+	return calculateSlotScoreWithZones(slot, *task, s.in.Profile, zones) - s.day[q]
+}
+
+type compatKey struct {
+	task uuid.UUID
+	zone int
+}
+
+func (s *scheduler) compatible(task *models.Task, zone int) bool {
+	key := compatKey{task.ID, zone}
+	ok, seen := s.compat[key]
+	if !seen {
+		ok = isTaskCompatibleWithZone(*task, s.zones[zone])
+		s.compat[key] = ok
+	}
+	return ok
+}
+
+// longestWindow is the longest run of quanta in which task could be placed, ignoring other events
+func (s *scheduler) longestWindow(task *models.Task) int {
+	longest, run := 0, 0
+	for q := 0; q < s.quanta; q++ {
+		ok := s.window[q] >= 0 && (s.zoneAt[q] < 0 || s.compatible(task, s.zoneAt[q]))
+		switch {
+		case !ok:
+			run = 0
+		case q > 0 && s.window[q] == s.window[q-1]:
+			run++
+		default:
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+func (s *scheduler) occupy(q, n int) {
+	for i := q; i < q+n; i++ {
+		s.busy[i] = true
+	}
+	if z := s.zoneAt[q]; z >= 0 {
+		s.load[[2]int{z, s.day[q]}]++
+	}
+}
+
+func (s *scheduler) release(q, n int) {
+	for i := q; i < q+n; i++ {
+		s.busy[i] = false
+	}
+	if z := s.zoneAt[q]; z >= 0 {
+		s.load[[2]int{z, s.day[q]}]--
+	}
+}
+
+func (s *scheduler) at(q int) time.Time {
+	return s.start.Add(time.Duration(q) * schedulerQuantum)
+}
+
+// quantumOf returns the quantum containing t, which may lie outside the horizon
+func (s *scheduler) quantumOf(t time.Time) int {
+	d := t.Sub(s.start)
+	q := int(d / schedulerQuantum)
+	if d < 0 && d%schedulerQuantum != 0 {
+		q--
+	}
+	return q
+}
+
+// taskMinutes is the work left on a task: its estimate less the time already spent
+func taskMinutes(task *models.Task) int {
+	if task.TimeEstimate == nil || *task.TimeEstimate <= 0 {
+		return defaultTaskMinutes
+	}
+	return max(*task.TimeEstimate-task.TimeSpent, minChunkMinutes)
+}
+
+func taskPriority(task *models.Task) int {
+	if task.Priority == nil {
+		return 0
+	}
+	return *task.Priority
+}
+
+// splitChunks divides total quanta into the fewest chunks of at most size, as evenly as possible
+func splitChunks(total, size int) []int {
+	n := (total + size - 1) / size
+	chunks := make([]int, n)
+	for i := range chunks {
+		chunks[i] = total / n
+		if i < total%n {
+			chunks[i]++
+		}
+	}
+	return chunks
+}
+
+func ceilQuanta(d time.Duration) int {
+	return int((d + schedulerQuantum - 1) / schedulerQuantum)
+}
+
+func formatMinutes(minutes int) string {
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%dh", minutes/60)
+	}
+	if minutes > 60 {
+		return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
@@ -0,0 +1,221 @@
+package ai
+
+import (
+	"math"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// FeedbackWindowDays is how far back responses to suggestions are learned from
+	FeedbackWindowDays = 90
+	// feedbackPrior is how many responses it takes before they count for half
+	feedbackPrior = 5
+	// Largest score change feedback can make for the hour and the weekday of a slot
+	feedbackHourPoints = 12
+	feedbackDayPoints  = 6
+	// maxSlotScore is about the best score calculateSlotScore gives without feedback
+	maxSlotScore = 73
+)
+
+// SlotFeedback counts responses to suggestions in one hour or on one weekday
+type SlotFeedback struct {
+	Taken   int `json:"taken"`
+	Refused int `json:"refused"`
+}
+
+// rate returns the smoothed share of suggestions taken and how much to trust it (0-1)
+func (f SlotFeedback) rate() (float64, float64) {
+	n := float64(f.Taken + f.Refused)
+	return (float64(f.Taken) + 1) / (n + 2), n / (n + feedbackPrior)
+}
+
+// SuggestionFeedback is what a user's responses say about when they like work placed.
+// Accepting a suggestion counts for its slot; rejecting or moving it counts against,
+// and a moved block counts for the slot it was moved to.
+type SuggestionFeedback struct {
+	Hours    [24]SlotFeedback
+	Weekdays [7]SlotFeedback
+}
+
+// BuildSuggestionFeedback tallies responded suggestions by hour and weekday in loc
+func BuildSuggestionFeedback(recommendations []models.AIRecommendation, loc *time.Location) *SuggestionFeedback {
+	feedback := &SuggestionFeedback{}
+	count := func(at time.Time, taken bool) {
+		at = at.In(loc)
+		hour, day := &feedback.Hours[at.Hour()], &feedback.Weekdays[at.Weekday()]
+		if taken {
+			hour.Taken++
+			day.Taken++
+		} else {
+			hour.Refused++
+			day.Refused++
+		}
+	}
+
+	for _, rec := range recommendations {
+		switch rec.Status {
+		case models.RecommendationAccepted:
+			count(rec.SuggestedStart, true)
+		case models.RecommendationRejected:
+			count(rec.SuggestedStart, false)
+		case models.RecommendationModified:
+			count(rec.SuggestedStart, false)
+			if rec.FinalStart != nil {
+				count(*rec.FinalStart, true)
+			}
+		}
+	}
+	return feedback
+}
+
+// Adjustment returns the score change for a slot starting at hour on weekday: positive
+// where suggestions are usually taken, negative where they are refused
+func (f *SuggestionFeedback) Adjustment(hour int, weekday time.Weekday) int {
+	if f == nil || hour < 0 || hour > 23 {
+		return 0
+	}
+	hourRate, hourWeight := f.Hours[hour].rate()
+	dayRate, dayWeight := f.Weekdays[weekday].rate()
+	return int(math.Round(2*(hourRate-0.5)*hourWeight*feedbackHourPoints +
+		2*(dayRate-0.5)*dayWeight*feedbackDayPoints))
+}
+
+// SuggestionConfidence estimates how likely the user is to take a suggested slot: how
+// well it scores, pulled towards how often suggestions at that hour are taken
+func SuggestionConfidence(slot TimeSlot, task models.Task, profile *EnergyProfile) float32 {
+	confidence := math.Max(0, math.Min(1, float64(calculateSlotScore(slot, task, profile))/maxSlotScore))
+	if profile.Feedback != nil {
+		rate, weight := profile.Feedback.Hours[slot.Start.Hour()].rate()
+		confidence = (1-weight)*confidence + weight*rate
+	}
+	return float32(math.Round(confidence*100) / 100)
+}
+
+// LoadSuggestionFeedback reads the user's recent responses to suggestions
+func LoadSuggestionFeedback(userID uuid.UUID, loc *time.Location) *SuggestionFeedback {
+	var recommendations []models.AIRecommendation
+	if err := config.GetDB().
+		Where("user_id = ? AND status IN ? AND created_at > ?", userID,
+			[]string{models.RecommendationAccepted, models.RecommendationRejected, models.RecommendationModified},
+			time.Now().AddDate(0, 0, -FeedbackWindowDays)).
+		Find(&recommendations).Error; err != nil {
+		config.Logger.Warnf("Could not load suggestion feedback for user %s: %v", userID, err)
+		return nil
+	}
+	return BuildSuggestionFeedback(recommendations, loc)
+}
+
+// SuggestionStats summarises responses to a group of suggestions
+type SuggestionStats struct {
+	Suggested      int     `json:"suggested"`
+	Accepted       int     `json:"accepted"`
+	Modified       int     `json:"modified"`
+	Rejected       int     `json:"rejected"`
+	Expired        int     `json:"expired"`
+	Pending        int     `json:"pending"`
+	AcceptanceRate float64 `json:"acceptance_rate"` // share of answered suggestions taken, as suggested or moved
+}
+
+func (s *SuggestionStats) add(status string) {
+	s.Suggested++
+	switch status {
+	case models.RecommendationAccepted:
+		s.Accepted++
+	case models.RecommendationModified:
+		s.Modified++
+	case models.RecommendationRejected:
+		s.Rejected++
+	case models.RecommendationExpired:
+		s.Expired++
+	default:
+		s.Pending++
+	}
+}
+
+func (s *SuggestionStats) finish() {
+	if answered := s.Accepted + s.Modified + s.Rejected; answered > 0 {
+		s.AcceptanceRate = math.Round(float64(s.Accepted+s.Modified)/float64(answered)*1000) / 1000
+	}
+}
+
+// HourStats are the stats of suggestions starting in one hour of the day
+type HourStats struct {
+	Hour int `json:"hour"`
+	SuggestionStats
+}
+
+// WeekdayStats are the stats of suggestions starting on one day of the week
+type WeekdayStats struct {
+	Weekday string `json:"weekday"`
+	SuggestionStats
+}
+
+// SuggestionReport shows how often a user takes schedule suggestions
+type SuggestionReport struct {
+	SuggestionStats
+	AverageShiftMinutes float64        `json:"average_shift_minutes"` // how far moved suggestions were moved
+	AcceptedConfidence  float64        `json:"accepted_confidence"`   // mean confidence of taken suggestions
+	RejectedConfidence  float64        `json:"rejected_confidence"`   // mean confidence of rejected suggestions
+	ByHour              []HourStats    `json:"by_hour"`
+	ByWeekday           []WeekdayStats `json:"by_weekday"`
+}
+
+// BuildSuggestionReport summarises suggestions by outcome, hour and weekday in loc
+func BuildSuggestionReport(recommendations []models.AIRecommendation, loc *time.Location) SuggestionReport {
+	report := SuggestionReport{ByHour: []HourStats{}, ByWeekday: []WeekdayStats{}}
+	var hours [24]SuggestionStats
+	var days [7]SuggestionStats
+	var shift, takenConfidence, rejectedConfidence float64
+	var moved, taken, rejected int
+
+	for _, rec := range recommendations {
+		start := rec.SuggestedStart.In(loc)
+		report.add(rec.Status)
+		hours[start.Hour()].add(rec.Status)
+		days[start.Weekday()].add(rec.Status)
+
+		switch rec.Status {
+		case models.RecommendationModified:
+			if rec.FinalStart != nil {
+				shift += math.Abs(rec.FinalStart.Sub(rec.SuggestedStart).Minutes())
+				moved++
+			}
+			fallthrough
+		case models.RecommendationAccepted:
+			takenConfidence += float64(rec.Confidence)
+			taken++
+		case models.RecommendationRejected:
+			rejectedConfidence += float64(rec.Confidence)
+			rejected++
+		}
+	}
+
+	report.finish()
+	if moved > 0 {
+		report.AverageShiftMinutes = math.Round(shift / float64(moved))
+	}
+	if taken > 0 {
+		report.AcceptedConfidence = math.Round(takenConfidence/float64(taken)*100) / 100
+	}
+	if rejected > 0 {
+		report.RejectedConfidence = math.Round(rejectedConfidence/float64(rejected)*100) / 100
+	}
+
+	for hour := range hours {
+		if hours[hour].Suggested > 0 {
+			hours[hour].finish()
+			report.ByHour = append(report.ByHour, HourStats{Hour: hour, SuggestionStats: hours[hour]})
+		}
+	}
+	for _, day := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		if days[day].Suggested > 0 {
+			days[day].finish()
+			report.ByWeekday = append(report.ByWeekday, WeekdayStats{Weekday: day.String(), SuggestionStats: days[day]})
+		}
+	}
+	return report
+}
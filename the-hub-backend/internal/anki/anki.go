@@ -0,0 +1,407 @@
+// Package anki reads and writes Anki deck packages (.apkg): a zip holding the SQLite
+// collection (schema version 11, as written by Anki with "support older versions")
+// and its media files.
+package anki
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/notetype"
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+)
+
+const (
+	collectionLegacy = "collection.anki2"
+	collection21     = "collection.anki21"
+	collection21b    = "collection.anki21b"
+	mediaIndex       = "media"
+
+	// Limits on what a package may unpack to, against zip bombs
+	maxCollectionBytes = 256 << 20
+	maxMediaBytes      = 512 << 20
+
+	// fieldSeparator separates a note's fields in the notes table
+	fieldSeparator = "\x1f"
+)
+
+// ErrUnsupportedCollection is returned for packages that only hold the newer,
+// compressed collection format
+var ErrUnsupportedCollection = errors.New(`this package only holds the newer Anki collection format; export it again from Anki with "Support older Anki versions" ticked`)
+
+// Package is the content of an .apkg file
+type Package struct {
+	Created time.Time         // when the collection was created; review due days count from its day
+	Decks   []Deck            // every deck in the package, sub-decks named "Parent::Child"
+	Notes   []Note            // notes whose type could be converted
+	Skipped int               // notes left out because their type could not be converted
+	Media   map[string][]byte // media files by the name notes refer to them by
+}
+
+// Deck is an Anki deck
+type Deck struct {
+	ID   int64
+	Name string
+}
+
+// Note is an Anki note converted to one of the note types in package notetype. The
+// first field becomes the front, the second the back and any others the extra.
+type Note struct {
+	GUID  string
+	Type  string
+	Front string
+	Back  string
+	Extra string
+	Tags  []string
+	Cards []Card
+}
+
+// Card is one card of a note
+type Card struct {
+	DeckID   int64
+	Template string // notetype template: "forward", "reverse" or "c1", "c2", ...
+	Schedule Schedule
+}
+
+// Schedule is a card's scheduling state
+type Schedule struct {
+	New         bool // never reviewed; the other fields are unset
+	Suspended   bool
+	Interval    int     // days
+	Easiness    float64 // SM-2 easiness factor
+	Repetitions int
+	Lapses      int
+	LastReview  time.Time
+	Due         time.Time
+}
+
+// ankiModel is a note type in the collection's models JSON
+type ankiModel struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Type  int    `json:"type"` // 0 standard, 1 cloze
+	Flds  []ankiField
+	Tmpls []ankiTemplate
+}
+
+type ankiField struct {
+	Name string `json:"name"`
+	Ord  int    `json:"ord"`
+}
+
+type ankiTemplate struct {
+	Name string `json:"name"`
+	Ord  int    `json:"ord"`
+	Qfmt string `json:"qfmt"`
+	Afmt string `json:"afmt"`
+}
+
+// noteType picks the note type an Anki model converts to
+func (m *ankiModel) noteType() string {
+	if m.Type == 1 {
+		return notetype.Cloze
+	}
+	for _, tmpl := range m.Tmpls {
+		if strings.Contains(tmpl.Qfmt+tmpl.Afmt, "{{type:") {
+			return notetype.TypeIn
+		}
+	}
+	if len(m.Tmpls) >= 2 {
+		return notetype.BasicReversed
+	}
+	return notetype.Basic
+}
+
+// template names the card an Anki model makes at ord
+func template(model string, ord int) string {
+	switch {
+	case model == notetype.Cloze:
+		return "c" + strconv.Itoa(ord+1)
+	case ord == 1:
+		return notetype.TemplateReverse
+	default:
+		return notetype.TemplateForward
+	}
+}
+
+// Read parses an .apkg file
+func Read(r io.ReaderAt, size int64) (*Package, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not an Anki package: %w", err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	collection := files[collection21]
+	if collection == nil {
+		if files[collection21b] != nil {
+			return nil, ErrUnsupportedCollection
+		}
+		collection = files[collectionLegacy]
+	}
+	if collection == nil {
+		return nil, errors.New("not an Anki package: no collection found")
+	}
+
+	dir, err := os.MkdirTemp("", "apkg-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	dbPath := filepath.Join(dir, "collection.db")
+	if err := extract(collection, dbPath, maxCollectionBytes); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	pkg, err := readCollection(db)
+	if err != nil {
+		return nil, fmt.Errorf("reading Anki collection: %w", err)
+	}
+
+	pkg.Media, err = readMedia(files)
+	if err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+func extract(f *zip.File, dest string, limit int64) error {
+	if f.UncompressedSize64 > uint64(limit) {
+		return fmt.Errorf("%s is too large", f.Name)
+	}
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	n, err := io.Copy(out, io.LimitReader(src, limit+1))
+	if err != nil {
+		return err
+	}
+	if n > limit {
+		return fmt.Errorf("%s is too large", f.Name)
+	}
+	return nil
+}
+
+func readCollection(db *sql.DB) (*Package, error) {
+	var crt int64
+	var modelsJSON, decksJSON string
+	if err := db.QueryRow("SELECT crt, models, decks FROM col").Scan(&crt, &modelsJSON, &decksJSON); err != nil {
+		return nil, err
+	}
+	pkg := &Package{Created: time.Unix(crt, 0).UTC()}
+
+	var models map[string]*ankiModel
+	if err := json.Unmarshal([]byte(modelsJSON), &models); err != nil {
+		return nil, fmt.Errorf("note types: %w", err)
+	}
+	modelByID := make(map[int64]*ankiModel, len(models))
+	for _, m := range models {
+		modelByID[m.ID] = m
+	}
+
+	var decks map[string]Deck
+	if err := json.Unmarshal([]byte(decksJSON), &decks); err != nil {
+		return nil, fmt.Errorf("decks: %w", err)
+	}
+	for _, deck := range decks {
+		pkg.Decks = append(pkg.Decks, deck)
+	}
+	sort.Slice(pkg.Decks, func(i, j int) bool { return pkg.Decks[i].Name < pkg.Decks[j].Name })
+
+	cardsByNote, err := readCards(db, crt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT id, guid, mid, tags, flds FROM notes ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, mid int64
+		var guid, tags, fields string
+		if err := rows.Scan(&id, &guid, &mid, &tags, &fields); err != nil {
+			return nil, err
+		}
+		model := modelByID[mid]
+		if model == nil {
+			pkg.Skipped++
+			continue
+		}
+
+		noteType := model.noteType()
+		values := strings.Split(fields, fieldSeparator)
+		note := Note{GUID: guid, Type: noteType, Front: values[0]}
+		if tags := strings.Fields(tags); len(tags) > 0 {
+			note.Tags = tags
+		}
+		rest := values[1:]
+		if noteType != notetype.Cloze && len(rest) > 0 {
+			// Cloze notes have no back; their other fields are all extra
+			note.Back, rest = rest[0], rest[1:]
+		}
+		note.Extra = joinNonEmpty(rest...)
+		for _, card := range cardsByNote[id] {
+			card.Template = template(noteType, card.ord)
+			note.Cards = append(note.Cards, card.Card)
+		}
+		pkg.Notes = append(pkg.Notes, note)
+	}
+	return pkg, rows.Err()
+}
+
+// ordCard is a card with the template ordinal it has in its Anki model
+type ordCard struct {
+	Card
+	ord int
+}
+
+func readCards(db *sql.DB, crt int64) (map[int64][]ordCard, error) {
+	rows, err := db.Query(`SELECT nid, did, ord, type, queue, due, ivl, factor, reps, lapses, odue, odid
+		FROM cards ORDER BY nid, ord`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Review due dates count days from the day the collection was created
+	created := time.Unix(crt, 0).UTC()
+	collectionDay := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, time.UTC)
+
+	cards := make(map[int64][]ordCard)
+	for rows.Next() {
+		var nid, did, due, odue, odid int64
+		var ord, cardType, queue, ivl, factor, reps, lapses int
+		if err := rows.Scan(&nid, &did, &ord, &cardType, &queue, &due, &ivl, &factor, &reps, &lapses, &odue, &odid); err != nil {
+			return nil, err
+		}
+		if odid != 0 {
+			// In a filtered deck: its own deck and due date are kept aside
+			did, due = odid, odue
+		}
+
+		schedule := Schedule{Suspended: queue == -1}
+		switch cardType {
+		case 0:
+			schedule.New = true
+		default:
+			schedule.Interval = max(1, ivl)
+			schedule.Easiness = 2.5
+			if factor > 0 {
+				schedule.Easiness = float64(factor) / 1000
+			}
+			schedule.Repetitions = reps
+			schedule.Lapses = lapses
+			if cardType == 1 {
+				// Learning cards are due at a timestamp
+				schedule.Due = time.Unix(due, 0).UTC()
+			} else {
+				schedule.Due = collectionDay.AddDate(0, 0, int(due))
+			}
+			schedule.LastReview = schedule.Due.AddDate(0, 0, -schedule.Interval)
+		}
+		cards[nid] = append(cards[nid], ordCard{Card: Card{DeckID: did, Schedule: schedule}, ord: ord})
+	}
+	return cards, rows.Err()
+}
+
+func readMedia(files map[string]*zip.File) (map[string][]byte, error) {
+	media := make(map[string][]byte)
+	index := files[mediaIndex]
+	if index == nil {
+		return media, nil
+	}
+	reader, err := index.Open()
+	if err != nil {
+		return nil, err
+	}
+	var names map[string]string
+	err = json.NewDecoder(io.LimitReader(reader, 16<<20)).Decode(&names)
+	reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("media index: %w", err)
+	}
+
+	var total int64
+	for number, name := range names {
+		f := files[number]
+		name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+		if f == nil || name == "." || name == "/" || name == ".." {
+			continue
+		}
+		total += int64(f.UncompressedSize64)
+		if total > maxMediaBytes {
+			return nil, errors.New("media files are too large")
+		}
+		src, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(src, int64(f.UncompressedSize64)+1))
+		src.Close()
+		if err != nil {
+			return nil, err
+		}
+		media[name] = data
+	}
+	return media, nil
+}
+
+// mediaPattern matches the ways notes refer to media: src attributes, quoted or not,
+// and sound tags
+var mediaPattern = regexp.MustCompile(`(?i)(src=")([^"]+)"|(src=')([^']+)'|(src=)([^"'>\s]+)|\[sound:([^\]]+)\]`)
+
+// RewriteMedia replaces every media file name text refers to with rename's result
+func RewriteMedia(text string, rename func(name string) string) string {
+	return mediaPattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := mediaPattern.FindStringSubmatch(match)
+		switch {
+		case parts[2] != "":
+			return parts[1] + rename(parts[2]) + `"`
+		case parts[4] != "":
+			return parts[3] + rename(parts[4]) + `'`
+		case parts[6] != "":
+			return parts[5] + rename(parts[6])
+		default:
+			return "[sound:" + rename(parts[7]) + "]"
+		}
+	})
+}
+
+// MediaRefs lists the media file names text refers to
+func MediaRefs(text string) []string {
+	var names []string
+	RewriteMedia(text, func(name string) string {
+		names = append(names, name)
+		return name
+	})
+	return names
+}
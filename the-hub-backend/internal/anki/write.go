@@ -0,0 +1,339 @@
+package anki
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/notetype"
+)
+
+// schema is the collection schema of Anki 2.1 with "support older versions"
+const schema = `
+CREATE TABLE col (id integer primary key, crt integer not null, mod integer not null, scm integer not null,
+	ver integer not null, dty integer not null, usn integer not null, ls integer not null, conf text not null,
+	models text not null, decks text not null, dconf text not null, tags text not null);
+CREATE TABLE notes (id integer primary key, guid text not null, mid integer not null, mod integer not null,
+	usn integer not null, tags text not null, flds text not null, sfld integer not null, csum integer not null,
+	flags integer not null, data text not null);
+CREATE TABLE cards (id integer primary key, nid integer not null, did integer not null, ord integer not null,
+	mod integer not null, usn integer not null, type integer not null, queue integer not null, due integer not null,
+	ivl integer not null, factor integer not null, reps integer not null, lapses integer not null, left integer not null,
+	odue integer not null, odid integer not null, flags integer not null, data text not null);
+CREATE TABLE revlog (id integer primary key, cid integer not null, usn integer not null, ease integer not null,
+	ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null, type integer not null);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn on notes (usn);
+CREATE INDEX ix_cards_usn on cards (usn);
+CREATE INDEX ix_revlog_usn on revlog (usn);
+CREATE INDEX ix_cards_nid on cards (nid);
+CREATE INDEX ix_cards_sched on cards (did, queue, due);
+CREATE INDEX ix_revlog_cid on revlog (cid);
+CREATE INDEX ix_notes_csum on notes (csum);`
+
+// Model IDs of the note types written packages use, one per notetype model
+var modelIDs = map[string]int64{
+	notetype.Basic:         1700000000001,
+	notetype.BasicReversed: 1700000000002,
+	notetype.Cloze:         1700000000003,
+	notetype.TypeIn:        1700000000004,
+}
+
+const defaultDeckID = 1
+
+// Write writes pkg as an .apkg file. Card deck IDs refer to pkg.Decks; a deck
+// with ID 1 is Anki's default deck.
+func Write(w io.Writer, pkg *Package) error {
+	dir, err := os.MkdirTemp("", "apkg-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	dbPath := filepath.Join(dir, collectionLegacy)
+
+	if err := writeCollection(dbPath, pkg); err != nil {
+		return fmt.Errorf("writing Anki collection: %w", err)
+	}
+
+	archive := zip.NewWriter(w)
+	collection, err := os.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer collection.Close()
+	entry, err := archive.Create(collectionLegacy)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(entry, collection); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(pkg.Media))
+	for name := range pkg.Media {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	index := make(map[string]string, len(names))
+	for i, name := range names {
+		number := strconv.Itoa(i)
+		index[number] = name
+		entry, err := archive.Create(number)
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(pkg.Media[name]); err != nil {
+			return err
+		}
+	}
+	entry, err = archive.Create(mediaIndex)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(entry).Encode(index); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func writeCollection(dbPath string, pkg *Package) error {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	created := pkg.Created
+	if created.IsZero() {
+		created = now
+	}
+	created = created.UTC()
+	collectionDay := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, time.UTC)
+	mod := now.UnixMilli()
+
+	models := make(map[string]interface{}, len(modelIDs))
+	for model, id := range modelIDs {
+		models[strconv.FormatInt(id, 10)] = modelJSON(model, id, mod/1000)
+	}
+	decks := map[string]interface{}{
+		strconv.Itoa(defaultDeckID): deckJSON(defaultDeckID, "Default", mod/1000),
+	}
+	for _, deck := range pkg.Decks {
+		decks[strconv.FormatInt(deck.ID, 10)] = deckJSON(deck.ID, deck.Name, mod/1000)
+	}
+	conf := map[string]interface{}{
+		"nextPos": len(pkg.Notes) + 1, "estTimes": true, "activeDecks": []int{defaultDeckID},
+		"sortType": "noteFld", "timeLim": 0, "sortBackwards": false, "addToCur": true,
+		"curDeck": defaultDeckID, "newBury": true, "newSpread": 0, "dueCounts": true,
+		"curModel": strconv.FormatInt(modelIDs[notetype.Basic], 10), "collapseTime": 1200,
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		created.Unix(), mod, mod, mustJSON(conf), mustJSON(models), mustJSON(decks), defaultDeckConfig); err != nil {
+		return err
+	}
+
+	noteStmt, err := tx.Prepare(`INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`)
+	if err != nil {
+		return err
+	}
+	defer noteStmt.Close()
+	cardStmt, err := tx.Prepare(`INSERT INTO cards VALUES (?, ?, ?, ?, ?, -1, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, 0, '')`)
+	if err != nil {
+		return err
+	}
+	defer cardStmt.Close()
+
+	// Note and card IDs are millisecond timestamps in Anki; consecutive ones keep them unique
+	nextID := mod
+	newPosition := 0
+	for _, note := range pkg.Notes {
+		mid, ok := modelIDs[note.Type]
+		if !ok {
+			return fmt.Errorf("unknown note type %q", note.Type)
+		}
+		guid := note.GUID
+		if guid == "" {
+			guid = newGUID()
+		}
+		fields := note.Front + fieldSeparator + note.Back + fieldSeparator + note.Extra
+		if note.Type == notetype.Cloze {
+			// Cloze notes keep their text in the first field and anything else in "Back Extra"
+			fields = note.Front + fieldSeparator + joinNonEmpty(note.Back, note.Extra)
+		}
+		tags := ""
+		if len(note.Tags) > 0 {
+			tags = " " + strings.Join(note.Tags, " ") + " "
+		}
+		noteID := nextID
+		nextID++
+		if _, err := noteStmt.Exec(noteID, guid, mid, mod/1000, tags,
+			fields, note.Front, checksum(note.Front)); err != nil {
+			return err
+		}
+
+		for _, card := range note.Cards {
+			ord, ok := ordinal(note.Type, card.Template)
+			if !ok {
+				return fmt.Errorf("unknown template %q for %s note", card.Template, note.Type)
+			}
+			deckID := card.DeckID
+			if deckID == 0 {
+				deckID = defaultDeckID
+			}
+
+			s := card.Schedule
+			cardType, queue, due, ivl, factor := 0, 0, int64(newPosition), 0, 0
+			if s.New {
+				newPosition++
+			} else {
+				cardType, queue = 2, 2
+				due = int64(s.Due.UTC().Sub(collectionDay).Hours() / 24)
+				ivl = max(1, s.Interval)
+				factor = int(s.Easiness*1000 + 0.5)
+			}
+			if s.Suspended {
+				queue = -1
+			}
+			cardID := nextID
+			nextID++
+			if _, err := cardStmt.Exec(cardID, noteID, deckID, ord, mod/1000,
+				cardType, queue, due, ivl, factor, s.Repetitions, s.Lapses); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// ordinal is the Anki template ordinal of a notetype template
+func ordinal(model, template string) (int, bool) {
+	if model == notetype.Cloze {
+		n, err := strconv.Atoi(strings.TrimPrefix(template, "c"))
+		return n - 1, err == nil && n > 0 && strings.HasPrefix(template, "c")
+	}
+	switch template {
+	case notetype.TemplateForward:
+		return 0, true
+	case notetype.TemplateReverse:
+		return 1, model == notetype.BasicReversed
+	}
+	return 0, false
+}
+
+func joinNonEmpty(parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if strings.TrimSpace(part) != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "<br>")
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// checksum is Anki's duplicate check: the first 8 hex digits of the SHA-1 of the
+// first field with HTML stripped
+func checksum(field string) int64 {
+	sum := sha1.Sum([]byte(strings.TrimSpace(tagPattern.ReplaceAllString(field, ""))))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
+
+func newGUID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)[:10]
+}
+
+func mustJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+func deckJSON(id int64, name string, mod int64) map[string]interface{} {
+	return map[string]interface{}{
+		"id": id, "name": name, "mod": mod, "usn": -1, "desc": "", "dyn": 0, "conf": 1,
+		"collapsed": false, "browserCollapsed": false, "extendNew": 10, "extendRev": 50,
+		"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+	}
+}
+
+const modelCSS = ".card {\n font-family: arial;\n font-size: 20px;\n text-align: center;\n color: black;\n background-color: white;\n}\n"
+
+func modelJSON(model string, id, mod int64) map[string]interface{} {
+	name, kind := "", 0
+	fields := []string{"Front", "Back", "Extra"}
+	var templates [][3]string // name, question, answer
+	switch model {
+	case notetype.Basic:
+		name = "Basic"
+		templates = [][3]string{{"Card 1", "{{Front}}", "{{FrontSide}}<hr id=answer>{{Back}}<br>{{Extra}}"}}
+	case notetype.BasicReversed:
+		name = "Basic (and reversed card)"
+		templates = [][3]string{
+			{"Card 1", "{{Front}}", "{{FrontSide}}<hr id=answer>{{Back}}<br>{{Extra}}"},
+			{"Card 2", "{{Back}}", "{{FrontSide}}<hr id=answer>{{Front}}<br>{{Extra}}"},
+		}
+	case notetype.Cloze:
+		name, kind = "Cloze", 1
+		fields = []string{"Text", "Back Extra"}
+		templates = [][3]string{{"Cloze", "{{cloze:Text}}", "{{cloze:Text}}<br>{{Back Extra}}"}}
+	case notetype.TypeIn:
+		name = "Basic (type in the answer)"
+		templates = [][3]string{{"Card 1", "{{Front}}\n\n{{type:Back}}", "{{Front}}<hr id=answer>{{type:Back}}<br>{{Extra}}"}}
+	}
+
+	flds := make([]map[string]interface{}, len(fields))
+	for i, field := range fields {
+		flds[i] = map[string]interface{}{
+			"name": field, "ord": i, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []string{},
+		}
+	}
+	tmpls := make([]map[string]interface{}, len(templates))
+	req := make([]interface{}, len(templates))
+	for i, tmpl := range templates {
+		tmpls[i] = map[string]interface{}{
+			"name": tmpl[0], "ord": i, "qfmt": tmpl[1], "afmt": tmpl[2], "did": nil, "bqfmt": "", "bafmt": "",
+		}
+		req[i] = []interface{}{i, "any", []int{i}}
+	}
+	return map[string]interface{}{
+		"id": id, "name": name, "type": kind, "mod": mod, "usn": -1, "sortf": 0, "did": defaultDeckID,
+		"flds": flds, "tmpls": tmpls, "req": req, "tags": []string{}, "vers": []int{}, "css": modelCSS,
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}", "latexsvg": false,
+	}
+}
+
+const defaultDeckConfig = `{"1": {"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true,
+"timer": 0, "replayq": true, "dyn": false,
+"new": {"delays": [1, 10], "ints": [1, 4, 7], "initialFactor": 2500, "order": 1, "perDay": 20, "bury": false},
+"rev": {"perDay": 200, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500, "bury": false, "hardFactor": 1.2},
+"lapse": {"delays": [10], "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0}}}`
@@ -0,0 +1,130 @@
+// Package booking works out when others can book time with a user: the user's
+// bookable calendar zones minus everything already on their schedule.
+package booking
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/models"
+)
+
+// SlotStep is the spacing of the start times offered for booking
+const SlotStep = 15 * time.Minute
+
+// Interval is a span of time
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Rules limit which free times can be booked through a link
+type Rules struct {
+	Duration     time.Duration
+	BufferBefore time.Duration // free time kept before a booking
+	BufferAfter  time.Duration // free time kept after a booking
+	DailyLimit   int           // bookings per day, 0 for no limit
+	Earliest     time.Time     // no booking starts before this
+}
+
+// RulesFor returns the rules of a booking link at now
+func RulesFor(link *models.BookingLink, now time.Time) Rules {
+	rules := Rules{
+		Duration:     time.Duration(link.DurationMinutes) * time.Minute,
+		BufferBefore: time.Duration(link.BufferBefore) * time.Minute,
+		BufferAfter:  time.Duration(link.BufferAfter) * time.Minute,
+		Earliest:     now.Add(time.Duration(link.MinNotice) * time.Minute),
+	}
+	if link.DailyLimit != nil {
+		rules.DailyLimit = *link.DailyLimit
+	}
+	return rules
+}
+
+// DayKey names the day t falls on in loc, for counting bookings per day
+func DayKey(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02")
+}
+
+// Busy returns the merged spans of [from, to) taken by the schedule. Recurring events
+// need their recurrence rule preloaded; cancelled occurrences are free.
+func Busy(schedule []models.ScheduledTask, from, to time.Time, loc *time.Location) []Interval {
+	var busy []Interval
+	for _, event := range models.ExpandSchedule(schedule, from, to, loc) {
+		if event.Cancelled || !event.End.After(event.Start) {
+			continue
+		}
+		busy = append(busy, Interval{Start: event.Start, End: event.End})
+	}
+	sort.Slice(busy, func(i, j int) bool { return busy[i].Start.Before(busy[j].Start) })
+
+	merged := busy[:0]
+	for _, span := range busy {
+		if n := len(merged); n > 0 && !span.Start.After(merged[n-1].End) {
+			if span.End.After(merged[n-1].End) {
+				merged[n-1].End = span.End
+			}
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// Slots lists the bookable times starting in [from, to). A slot lies within one
+// occurrence of a zone in loc, stays clear of busy time by the buffers, and falls on a
+// day with fewer than the daily limit of bookings. booked counts bookings per DayKey;
+// busy must be sorted and merged as Busy returns it.
+func Slots(zones []models.CalendarZone, busy []Interval, booked map[string]int, from, to time.Time, loc *time.Location, rules Rules) []Interval {
+	slots := []Interval{}
+	if rules.Duration <= 0 || len(zones) == 0 {
+		return slots
+	}
+	if from.Before(rules.Earliest) {
+		from = rules.Earliest
+	}
+
+	start := from.Truncate(SlotStep)
+	if start.Before(from) {
+		start = start.Add(SlotStep)
+	}
+	for ; start.Before(to); start = start.Add(SlotStep) {
+		end := start.Add(rules.Duration)
+		if rules.DailyLimit > 0 && booked[DayKey(start, loc)] >= rules.DailyLimit {
+			continue
+		}
+		if !inZone(zones, start, end, loc) {
+			continue
+		}
+		if overlaps(busy, start.Add(-rules.BufferBefore), end.Add(rules.BufferAfter)) {
+			continue
+		}
+		slots = append(slots, Interval{Start: start, End: end})
+	}
+	return slots
+}
+
+// IsBookable reports whether a booking may start at start
+func IsBookable(zones []models.CalendarZone, busy []Interval, booked map[string]int, start time.Time, loc *time.Location, rules Rules) bool {
+	if !start.Truncate(SlotStep).Equal(start) {
+		return false
+	}
+	slots := Slots(zones, busy, booked, start, start.Add(time.Nanosecond), loc, rules)
+	return len(slots) == 1 && slots[0].Start.Equal(start)
+}
+
+// inZone reports whether [start, end) lies within one occurrence of a zone
+func inZone(zones []models.CalendarZone, start, end time.Time, loc *time.Location) bool {
+	for i := range zones {
+		if zones[i].CoversSpan(start, end, loc) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps reports whether [start, end) meets any busy span
+func overlaps(busy []Interval, start, end time.Time) bool {
+	i := sort.Search(len(busy), func(i int) bool { return busy[i].End.After(start) })
+	return i < len(busy) && busy[i].Start.Before(end)
+}
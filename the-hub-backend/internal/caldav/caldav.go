@@ -0,0 +1,279 @@
+// Package caldav implements the WebDAV/CalDAV XML used by the CalDAV endpoint:
+// parsing PROPFIND and REPORT bodies and writing multistatus responses.
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// XML namespaces
+const (
+	NSDAV            = "DAV:"
+	NSCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	NSCalendarServer = "http://calendarserver.org/ns/"
+	NSAppleICal      = "http://apple.com/ns/ical/"
+)
+
+var prefixes = map[string]string{
+	NSDAV:            "d",
+	NSCalDAV:         "c",
+	NSCalendarServer: "cs",
+	NSAppleICal:      "ical",
+}
+
+// Report kinds
+const (
+	ReportCalendarQuery    = "calendar-query"
+	ReportCalendarMultiget = "calendar-multiget"
+)
+
+// Name is shorthand for building property names
+func Name(space, local string) xml.Name {
+	return xml.Name{Space: space, Local: local}
+}
+
+// PropfindRequest is a parsed PROPFIND body
+type PropfindRequest struct {
+	AllProp   bool
+	PropNames []xml.Name
+}
+
+type anyElement struct {
+	XMLName xml.Name
+}
+
+type propElement struct {
+	Props []anyElement `xml:",any"`
+}
+
+type propfindBody struct {
+	XMLName  xml.Name     `xml:"DAV: propfind"`
+	AllProp  *struct{}    `xml:"DAV: allprop"`
+	PropName *struct{}    `xml:"DAV: propname"`
+	Prop     *propElement `xml:"DAV: prop"`
+}
+
+// ParsePropfind parses a PROPFIND body. An empty body means allprop.
+func ParsePropfind(r io.Reader) (*PropfindRequest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &PropfindRequest{AllProp: true}, nil
+	}
+
+	var body propfindBody
+	if err := xml.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("invalid PROPFIND body: %w", err)
+	}
+
+	req := &PropfindRequest{AllProp: body.AllProp != nil || body.PropName != nil}
+	if body.Prop != nil {
+		for _, p := range body.Prop.Props {
+			req.PropNames = append(req.PropNames, p.XMLName)
+		}
+	}
+	if len(req.PropNames) == 0 {
+		req.AllProp = true
+	}
+	return req, nil
+}
+
+// ReportRequest is a parsed calendar-query or calendar-multiget REPORT
+type ReportRequest struct {
+	Kind      string
+	PropNames []xml.Name
+	Hrefs     []string
+	// Component is the component type the query filters on (VEVENT, VTODO), if any
+	Component string
+	// Start and End bound the query's time-range filter, if any
+	Start *time.Time
+	End   *time.Time
+}
+
+type timeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+type compFilter struct {
+	Name      string       `xml:"name,attr"`
+	TimeRange *timeRange   `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	Comps     []compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type reportBody struct {
+	XMLName xml.Name
+	Prop    *propElement `xml:"DAV: prop"`
+	Hrefs   []string     `xml:"DAV: href"`
+	Filter  *struct {
+		Comp compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+// ParseReport parses a REPORT body
+func ParseReport(r io.Reader) (*ReportRequest, error) {
+	var body reportBody
+	if err := xml.NewDecoder(r).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid REPORT body: %w", err)
+	}
+
+	req := &ReportRequest{Kind: body.XMLName.Local, Hrefs: body.Hrefs}
+	if body.XMLName.Space != NSCalDAV {
+		req.Kind = body.XMLName.Space + " " + body.XMLName.Local
+	}
+	if body.Prop != nil {
+		for _, p := range body.Prop.Props {
+			req.PropNames = append(req.PropNames, p.XMLName)
+		}
+	}
+
+	if body.Filter != nil {
+		// The outer filter is always VCALENDAR; the component filter is nested inside it
+		for _, comp := range body.Filter.Comp.Comps {
+			req.Component = strings.ToUpper(comp.Name)
+			if comp.TimeRange != nil {
+				if t, err := parseUTC(comp.TimeRange.Start); err == nil {
+					req.Start = &t
+				}
+				if t, err := parseUTC(comp.TimeRange.End); err == nil {
+					req.End = &t
+				}
+			}
+			break
+		}
+	}
+	return req, nil
+}
+
+func parseUTC(value string) (time.Time, error) {
+	return time.Parse("20060102T150405Z", value)
+}
+
+// Prop is one property value in a response
+type Prop struct {
+	Name xml.Name
+	// Inner is the raw XML content of the property element
+	Inner string
+}
+
+// TextProp builds a property with escaped text content
+func TextProp(name xml.Name, value string) Prop {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(value))
+	return Prop{Name: name, Inner: b.String()}
+}
+
+// HrefProp builds a property containing a single DAV:href
+func HrefProp(name xml.Name, href string) Prop {
+	var b strings.Builder
+	b.WriteString("<d:href>")
+	xml.EscapeText(&b, []byte(href))
+	b.WriteString("</d:href>")
+	return Prop{Name: name, Inner: b.String()}
+}
+
+// ElementsProp builds a property containing empty elements, such as resourcetype
+func ElementsProp(name xml.Name, children ...xml.Name) Prop {
+	var b strings.Builder
+	for _, child := range children {
+		b.WriteString(emptyElement(child))
+	}
+	return Prop{Name: name, Inner: b.String()}
+}
+
+// Response is one DAV:response in a multistatus body
+type Response struct {
+	Href     string
+	Found    []Prop
+	NotFound []xml.Name
+	// Status, when set, reports the resource itself instead of property stats (e.g. 404 for multiget misses)
+	Status int
+}
+
+// Select splits the properties a resource has into those requested and those
+// requested but missing. allProp returns everything available.
+func Select(available []Prop, req *PropfindRequest) (found []Prop, notFound []xml.Name) {
+	if req == nil || req.AllProp {
+		return available, nil
+	}
+	byName := make(map[xml.Name]Prop, len(available))
+	for _, p := range available {
+		byName[p.Name] = p
+	}
+	for _, name := range req.PropNames {
+		if p, ok := byName[name]; ok {
+			found = append(found, p)
+		} else {
+			notFound = append(notFound, name)
+		}
+	}
+	return found, notFound
+}
+
+// WriteMultistatus writes a 207 Multi-Status body
+func WriteMultistatus(w io.Writer, responses []Response) error {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/" xmlns:ical="http://apple.com/ns/ical/">`)
+
+	for _, resp := range responses {
+		b.WriteString("<d:response><d:href>")
+		xml.EscapeText(&b, []byte(resp.Href))
+		b.WriteString("</d:href>")
+
+		if resp.Status != 0 {
+			b.WriteString(statusLine(resp.Status))
+		} else {
+			if len(resp.Found) > 0 || len(resp.NotFound) == 0 {
+				b.WriteString("<d:propstat><d:prop>")
+				for _, p := range resp.Found {
+					start, end := tags(p.Name)
+					b.WriteString(start + p.Inner + end)
+				}
+				b.WriteString("</d:prop>" + statusLine(http.StatusOK) + "</d:propstat>")
+			}
+			if len(resp.NotFound) > 0 {
+				b.WriteString("<d:propstat><d:prop>")
+				for _, name := range resp.NotFound {
+					b.WriteString(emptyElement(name))
+				}
+				b.WriteString("</d:prop>" + statusLine(http.StatusNotFound) + "</d:propstat>")
+			}
+		}
+		b.WriteString("</d:response>")
+	}
+
+	b.WriteString("</d:multistatus>")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func statusLine(code int) string {
+	return fmt.Sprintf("<d:status>HTTP/1.1 %d %s</d:status>", code, http.StatusText(code))
+}
+
+// tags returns the opening and closing tags for name, declaring unknown namespaces inline
+func tags(name xml.Name) (string, string) {
+	if prefix, ok := prefixes[name.Space]; ok {
+		return "<" + prefix + ":" + name.Local + ">", "</" + prefix + ":" + name.Local + ">"
+	}
+	if name.Space == "" {
+		return "<" + name.Local + ">", "</" + name.Local + ">"
+	}
+	var ns strings.Builder
+	xml.EscapeText(&ns, []byte(name.Space))
+	return `<x:` + name.Local + ` xmlns:x="` + ns.String() + `">`, "</x:" + name.Local + ">"
+}
+
+func emptyElement(name xml.Name) string {
+	start, _ := tags(name)
+	return strings.TrimSuffix(start, ">") + "/>"
+}
@@ -0,0 +1,215 @@
+package calendarsync
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// googleProvider implements Provider for Google Calendar
+type googleProvider struct {
+	oauth *oauth2.Config
+	opts  []option.ClientOption
+}
+
+// NewGoogleProvider creates the Google Calendar provider. Extra client options
+// (such as option.WithEndpoint) are passed to the API client.
+func NewGoogleProvider(clientID, clientSecret, redirectURL string, opts ...option.ClientOption) Provider {
+	return &googleProvider{
+		oauth: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes: []string{
+				calendar.CalendarScope,
+				calendar.CalendarEventsScope,
+			},
+			Endpoint: google.Endpoint,
+		},
+		opts: opts,
+	}
+}
+
+func (p *googleProvider) Name() models.CalendarProvider {
+	return models.ProviderGoogle
+}
+
+func (p *googleProvider) OAuthConfig() *oauth2.Config {
+	return p.oauth
+}
+
+func (p *googleProvider) service(ctx context.Context, token *oauth2.Token) (*calendar.Service, error) {
+	client := p.oauth.Client(ctx, token)
+	opts := append([]option.ClientOption{option.WithHTTPClient(client)}, p.opts...)
+	return calendar.NewService(ctx, opts...)
+}
+
+func (p *googleProvider) Account(ctx context.Context, token *oauth2.Token) (*Account, error) {
+	svc, err := p.service(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	calendarList, err := svc.CalendarList.List().Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	account := &Account{}
+	for _, cal := range calendarList.Items {
+		if cal.Primary {
+			// The primary calendar's ID is the account's email address
+			account.CalendarID = cal.Id
+			account.ProviderUserID = cal.Id
+			break
+		}
+	}
+	if account.CalendarID == "" && len(calendarList.Items) > 0 {
+		account.CalendarID = calendarList.Items[0].Id
+		account.ProviderUserID = calendarList.Items[0].Id
+	}
+	if account.CalendarID == "" {
+		return nil, errors.New("no calendars found")
+	}
+	return account, nil
+}
+
+func (p *googleProvider) Events(ctx context.Context, token *oauth2.Token, loc *time.Location) (EventsService, error) {
+	svc, err := p.service(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return NewGoogleEventsService(svc, loc), nil
+}
+
+// googleEventsService adapts *calendar.Service to EventsService
+type googleEventsService struct {
+	svc *calendar.Service
+	loc *time.Location
+}
+
+// NewGoogleEventsService wraps a Google Calendar API client. All-day events are read
+// as days in loc.
+func NewGoogleEventsService(svc *calendar.Service, loc *time.Location) EventsService {
+	return &googleEventsService{svc: svc, loc: loc}
+}
+
+func (g *googleEventsService) List(ctx context.Context, calendarID, syncToken, pageToken string) (*Page, error) {
+	call := g.svc.Events.List(calendarID).Context(ctx).
+		SingleEvents(true).
+		ShowDeleted(true).
+		MaxResults(250)
+	if syncToken != "" {
+		call = call.SyncToken(syncToken)
+	} else {
+		// A full sync only needs recent history; the resulting sync token still covers all later changes
+		call = call.TimeMin(time.Now().Add(-pushWindow).Format(time.RFC3339))
+	}
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+
+	events, err := call.Do()
+	if isGoogleStatus(err, http.StatusGone) {
+		return nil, ErrSyncTokenExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{NextPageToken: events.NextPageToken, SyncToken: events.NextSyncToken}
+	for _, item := range events.Items {
+		page.Events = append(page.Events, fromGoogleEvent(item, g.loc))
+	}
+	return page, nil
+}
+
+func (g *googleEventsService) Insert(ctx context.Context, calendarID string, event RemoteEvent) (*RemoteEvent, error) {
+	created, err := g.svc.Events.Insert(calendarID, toGoogleEvent(event)).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	saved := fromGoogleEvent(created, g.loc)
+	return &saved, nil
+}
+
+func (g *googleEventsService) Update(ctx context.Context, calendarID, eventID string, event RemoteEvent) (*RemoteEvent, error) {
+	updated, err := g.svc.Events.Patch(calendarID, eventID, toGoogleEvent(event)).Context(ctx).Do()
+	if isGoogleStatus(err, http.StatusNotFound) || isGoogleStatus(err, http.StatusGone) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	saved := fromGoogleEvent(updated, g.loc)
+	return &saved, nil
+}
+
+func (g *googleEventsService) Delete(ctx context.Context, calendarID, eventID string) error {
+	err := g.svc.Events.Delete(calendarID, eventID).Context(ctx).Do()
+	if isGoogleStatus(err, http.StatusNotFound) || isGoogleStatus(err, http.StatusGone) {
+		return ErrEventNotFound
+	}
+	return err
+}
+
+// fromGoogleEvent converts a Google event. All-day events only carry dates, which are
+// taken as days in loc.
+func fromGoogleEvent(item *calendar.Event, loc *time.Location) RemoteEvent {
+	event := RemoteEvent{
+		ID:        item.Id,
+		Title:     item.Summary,
+		Free:      item.Transparency == "transparent",
+		Cancelled: item.Status == "cancelled",
+	}
+	event.Updated, _ = time.Parse(time.RFC3339Nano, item.Updated)
+
+	if item.Start != nil && item.End != nil {
+		if item.Start.DateTime == "" {
+			event.AllDay = true
+			event.Start, _ = time.ParseInLocation("2006-01-02", item.Start.Date, loc)
+			event.End, _ = time.ParseInLocation("2006-01-02", item.End.Date, loc)
+		} else {
+			start, _ := time.Parse(time.RFC3339, item.Start.DateTime)
+			end, _ := time.Parse(time.RFC3339, item.End.DateTime)
+			event.Start, event.End = start.UTC(), end.UTC()
+		}
+	}
+	if item.ExtendedProperties != nil {
+		event.LocalID = item.ExtendedProperties.Private[scheduledTaskProperty]
+	}
+	return event
+}
+
+func toGoogleEvent(event RemoteEvent) *calendar.Event {
+	item := &calendar.Event{
+		Summary: event.Title,
+		Start: &calendar.EventDateTime{
+			DateTime: event.Start.Format(time.RFC3339),
+			TimeZone: timeZoneName(event.Start),
+		},
+		End: &calendar.EventDateTime{
+			DateTime: event.End.Format(time.RFC3339),
+			TimeZone: timeZoneName(event.End),
+		},
+	}
+	if event.LocalID != "" {
+		item.ExtendedProperties = &calendar.EventExtendedProperties{
+			Private: map[string]string{scheduledTaskProperty: event.LocalID},
+		}
+	}
+	return item
+}
+
+func isGoogleStatus(err error, code int) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
@@ -0,0 +1,298 @@
+package calendarsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/ical"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/microsoft"
+)
+
+const (
+	graphBaseURL    = "https://graph.microsoft.com/v1.0"
+	graphTimeLayout = "2006-01-02T15:04:05.9999999"
+	// graphHorizon bounds the calendar view; Graph requires an end date for delta queries
+	graphHorizon = 365 * 24 * time.Hour
+)
+
+// microsoftProvider implements Provider for Outlook / Microsoft 365 through Microsoft Graph
+type microsoftProvider struct {
+	oauth   *oauth2.Config
+	baseURL string
+}
+
+// NewMicrosoftProvider creates the Outlook provider. tenant is usually "common";
+// an empty baseURL uses the public Graph endpoint.
+func NewMicrosoftProvider(clientID, clientSecret, redirectURL, tenant, baseURL string) Provider {
+	if baseURL == "" {
+		baseURL = graphBaseURL
+	}
+	return &microsoftProvider{
+		oauth: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"offline_access", "User.Read", "Calendars.ReadWrite"},
+			Endpoint:     microsoft.AzureADEndpoint(tenant),
+		},
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+func (p *microsoftProvider) Name() models.CalendarProvider {
+	return models.ProviderOutlook
+}
+
+func (p *microsoftProvider) OAuthConfig() *oauth2.Config {
+	return p.oauth
+}
+
+func (p *microsoftProvider) Account(ctx context.Context, token *oauth2.Token) (*Account, error) {
+	graph := &graphEventsService{client: p.oauth.Client(ctx, token), baseURL: p.baseURL}
+
+	var me struct {
+		ID string `json:"id"`
+	}
+	if err := graph.do(ctx, http.MethodGet, p.baseURL+"/me", nil, &me); err != nil {
+		return nil, err
+	}
+
+	var cal struct {
+		ID string `json:"id"`
+	}
+	if err := graph.do(ctx, http.MethodGet, p.baseURL+"/me/calendar", nil, &cal); err != nil {
+		return nil, err
+	}
+
+	return &Account{ProviderUserID: me.ID, CalendarID: cal.ID}, nil
+}
+
+func (p *microsoftProvider) Events(ctx context.Context, token *oauth2.Token, loc *time.Location) (EventsService, error) {
+	return NewGraphEventsService(p.oauth.Client(ctx, token), p.baseURL, loc), nil
+}
+
+// graphEventsService implements EventsService with the Microsoft Graph REST API
+type graphEventsService struct {
+	client  *http.Client
+	baseURL string
+	loc     *time.Location
+}
+
+// NewGraphEventsService creates a Graph events client. client must add the bearer token.
+// All-day events are read as days in loc.
+func NewGraphEventsService(client *http.Client, baseURL string, loc *time.Location) EventsService {
+	if baseURL == "" {
+		baseURL = graphBaseURL
+	}
+	return &graphEventsService{client: client, baseURL: strings.TrimRight(baseURL, "/"), loc: loc}
+}
+
+// graphError is the error body Graph returns
+type graphError struct {
+	Status int
+	Code   string `json:"code"`
+	Detail string `json:"message"`
+}
+
+func (e *graphError) Error() string {
+	return fmt.Sprintf("microsoft graph: %d %s: %s", e.Status, e.Code, e.Detail)
+}
+
+type graphDateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type graphEvent struct {
+	ID                   string         `json:"id,omitempty"`
+	Subject              string         `json:"subject,omitempty"`
+	Start                *graphDateTime `json:"start,omitempty"`
+	End                  *graphDateTime `json:"end,omitempty"`
+	IsAllDay             bool           `json:"isAllDay,omitempty"`
+	IsCancelled          bool           `json:"isCancelled,omitempty"`
+	ShowAs               string         `json:"showAs,omitempty"`
+	LastModifiedDateTime string         `json:"lastModifiedDateTime,omitempty"`
+	Removed              *struct {
+		Reason string `json:"reason"`
+	} `json:"@removed,omitempty"`
+}
+
+type graphEventPage struct {
+	Value     []graphEvent `json:"value"`
+	NextLink  string       `json:"@odata.nextLink"`
+	DeltaLink string       `json:"@odata.deltaLink"`
+}
+
+// List walks a calendarView delta query. Graph hands back opaque links rather than
+// tokens, so the next page and delta links are used as page and sync tokens.
+func (g *graphEventsService) List(ctx context.Context, calendarID, syncToken, pageToken string) (*Page, error) {
+	link := pageToken
+	if link == "" {
+		link = syncToken
+	}
+	if link == "" {
+		now := time.Now().UTC()
+		query := url.Values{}
+		query.Set("startDateTime", now.Add(-pushWindow).Format(time.RFC3339))
+		query.Set("endDateTime", now.Add(graphHorizon).Format(time.RFC3339))
+		link = fmt.Sprintf("%s/me/calendars/%s/calendarView/delta?%s", g.baseURL, url.PathEscape(calendarID), query.Encode())
+	}
+
+	var body graphEventPage
+	err := g.do(ctx, http.MethodGet, link, nil, &body)
+	if ge, ok := err.(*graphError); ok && (ge.Status == http.StatusGone || strings.EqualFold(ge.Code, "SyncStateNotFound")) {
+		return nil, ErrSyncTokenExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{NextPageToken: body.NextLink, SyncToken: body.DeltaLink}
+	for _, item := range body.Value {
+		page.Events = append(page.Events, fromGraphEvent(item, g.loc))
+	}
+	return page, nil
+}
+
+func (g *graphEventsService) Insert(ctx context.Context, calendarID string, event RemoteEvent) (*RemoteEvent, error) {
+	var created graphEvent
+	link := fmt.Sprintf("%s/me/calendars/%s/events", g.baseURL, url.PathEscape(calendarID))
+	if err := g.do(ctx, http.MethodPost, link, toGraphEvent(event), &created); err != nil {
+		return nil, err
+	}
+	saved := fromGraphEvent(created, g.loc)
+	return &saved, nil
+}
+
+func (g *graphEventsService) Update(ctx context.Context, calendarID, eventID string, event RemoteEvent) (*RemoteEvent, error) {
+	var updated graphEvent
+	link := fmt.Sprintf("%s/me/events/%s", g.baseURL, url.PathEscape(eventID))
+	err := g.do(ctx, http.MethodPatch, link, toGraphEvent(event), &updated)
+	if ge, ok := err.(*graphError); ok && ge.Status == http.StatusNotFound {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	saved := fromGraphEvent(updated, g.loc)
+	return &saved, nil
+}
+
+func (g *graphEventsService) Delete(ctx context.Context, calendarID, eventID string) error {
+	link := fmt.Sprintf("%s/me/events/%s", g.baseURL, url.PathEscape(eventID))
+	err := g.do(ctx, http.MethodDelete, link, nil, nil)
+	if ge, ok := err.(*graphError); ok && ge.Status == http.StatusNotFound {
+		return ErrEventNotFound
+	}
+	return err
+}
+
+func (g *graphEventsService) do(ctx context.Context, method, link string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, link, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Prefer", `outlook.timezone="UTC", odata.maxpagesize=100`)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var envelope struct {
+			Error graphError `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&envelope)
+		envelope.Error.Status = resp.StatusCode
+		return &envelope.Error
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// fromGraphEvent converts a Graph event. All-day events are floating midnights, so their
+// dates are taken as days in loc whatever zone Graph reports them in.
+func fromGraphEvent(item graphEvent, loc *time.Location) RemoteEvent {
+	event := RemoteEvent{
+		ID:        item.ID,
+		Title:     item.Subject,
+		AllDay:    item.IsAllDay,
+		Free:      item.ShowAs == "free",
+		Cancelled: item.IsCancelled || item.Removed != nil,
+	}
+	event.Updated, _ = time.Parse(time.RFC3339Nano, item.LastModifiedDateTime)
+	if item.IsAllDay {
+		event.Start = parseGraphDate(item.Start, loc)
+		event.End = parseGraphDate(item.End, loc)
+	} else {
+		event.Start = parseGraphTime(item.Start)
+		event.End = parseGraphTime(item.End)
+	}
+	return event
+}
+
+// parseGraphDate reads the date of an all-day event's start or end as midnight in loc
+func parseGraphDate(dt *graphDateTime, loc *time.Location) time.Time {
+	if dt == nil || len(dt.DateTime) < len("2006-01-02") {
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation("2006-01-02", dt.DateTime[:len("2006-01-02")], loc)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func parseGraphTime(dt *graphDateTime) time.Time {
+	if dt == nil || dt.DateTime == "" {
+		return time.Time{}
+	}
+	loc := time.UTC
+	if dt.TimeZone != "" {
+		if l, err := ical.LoadLocation(dt.TimeZone); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(graphTimeLayout, dt.DateTime, loc)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
+
+// toGraphEvent builds the event body for a single scheduled task. It carries no
+// patternedRecurrence because recurring series are not pushed; see Syncer.push.
+func toGraphEvent(event RemoteEvent) graphEvent {
+	return graphEvent{
+		Subject: event.Title,
+		Start:   &graphDateTime{DateTime: event.Start.Format(graphTimeLayout), TimeZone: timeZoneName(event.Start)},
+		End:     &graphDateTime{DateTime: event.End.Format(graphTimeLayout), TimeZone: timeZoneName(event.End)},
+		ShowAs:  "busy",
+	}
+}
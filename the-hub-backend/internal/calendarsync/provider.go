@@ -0,0 +1,194 @@
+package calendarsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSyncTokenExpired means the provider no longer accepts the stored sync token and a full sync is needed
+	ErrSyncTokenExpired = errors.New("calendar sync token expired")
+	// ErrEventNotFound means the remote event no longer exists
+	ErrEventNotFound = errors.New("calendar event not found")
+	// ErrUnknownProvider is returned for providers that have no registered implementation
+	ErrUnknownProvider = errors.New("unsupported calendar provider")
+)
+
+// RemoteEvent is a provider-neutral calendar event
+type RemoteEvent struct {
+	ID        string
+	Title     string
+	Start     time.Time
+	End       time.Time
+	AllDay    bool
+	Free      bool // shown as free/transparent, so it does not block time
+	Cancelled bool
+	Updated   time.Time
+	// LocalID is the scheduled task the event was pushed from, when the provider can store it
+	LocalID string
+}
+
+// Busy reports whether the event blocks time in the schedule
+func (e RemoteEvent) Busy() bool {
+	return !e.Cancelled && !e.Free && !e.AllDay && e.End.After(e.Start)
+}
+
+// timeZoneName is the IANA name providers are given for t's location. Times in the
+// server's local zone are sent as UTC so the server's configuration never leaks out.
+func timeZoneName(t time.Time) string {
+	if name := t.Location().String(); name != "Local" && name != "" {
+		return name
+	}
+	return "UTC"
+}
+
+// Page is one page of a (possibly incremental) event listing
+type Page struct {
+	Events        []RemoteEvent
+	NextPageToken string
+	// SyncToken is set on the last page and resumes an incremental listing on the next sync
+	SyncToken string
+}
+
+// EventsService reads and writes events on one provider account.
+// Tests substitute a fake implementation.
+type EventsService interface {
+	// List returns one page of events. An empty syncToken performs a full listing.
+	List(ctx context.Context, calendarID, syncToken, pageToken string) (*Page, error)
+	Insert(ctx context.Context, calendarID string, event RemoteEvent) (*RemoteEvent, error)
+	// Update changes title and times, leaving attendees and other remote-only fields intact
+	Update(ctx context.Context, calendarID, eventID string, event RemoteEvent) (*RemoteEvent, error)
+	Delete(ctx context.Context, calendarID, eventID string) error
+}
+
+// Account identifies the provider account and calendar an OAuth grant gives access to
+type Account struct {
+	ProviderUserID string
+	CalendarID     string
+}
+
+// Provider abstracts authentication and event access for one calendar service
+type Provider interface {
+	Name() models.CalendarProvider
+	// OAuthConfig is used for the authorization redirect, code exchange and token refresh
+	OAuthConfig() *oauth2.Config
+	// Account looks up the user's identity and default calendar after authorization
+	Account(ctx context.Context, token *oauth2.Token) (*Account, error)
+	// Events returns a client for the calendar API authenticated with token. All-day
+	// events are read as days in loc, the user's timezone.
+	Events(ctx context.Context, token *oauth2.Token, loc *time.Location) (EventsService, error)
+}
+
+// Registry holds the configured providers
+type Registry struct {
+	providers map[models.CalendarProvider]Provider
+}
+
+// NewRegistry creates a registry containing providers
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: map[models.CalendarProvider]Provider{}}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+	return r
+}
+
+// Get returns the provider registered under name
+func (r *Registry) Get(name models.CalendarProvider) (Provider, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// Names lists the registered providers in a stable order
+func (r *Registry) Names() []models.CalendarProvider {
+	names := make([]models.CalendarProvider, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+var (
+	defaultRegistry     *Registry
+	defaultRegistryOnce sync.Once
+)
+
+// DefaultRegistry returns the providers configured from the environment. It is built on
+// first use so that variables loaded from .env at startup are picked up.
+func DefaultRegistry() *Registry {
+	defaultRegistryOnce.Do(func() {
+		defaultRegistry = NewRegistry(
+			NewGoogleProvider(
+				os.Getenv("GOOGLE_CLIENT_ID"),
+				os.Getenv("GOOGLE_CLIENT_SECRET"),
+				getEnvOrDefault("GOOGLE_REDIRECT_URL", "http://localhost:8080/calendar/google/callback"),
+			),
+			NewMicrosoftProvider(
+				os.Getenv("MICROSOFT_CLIENT_ID"),
+				os.Getenv("MICROSOFT_CLIENT_SECRET"),
+				getEnvOrDefault("MICROSOFT_REDIRECT_URL", "http://localhost:8080/calendar/outlook/callback"),
+				getEnvOrDefault("MICROSOFT_TENANT", "common"),
+				"",
+			),
+		)
+	})
+	return defaultRegistry
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// IntegrationToken returns the integration's OAuth token
+func IntegrationToken(integration *models.CalendarIntegration) *oauth2.Token {
+	return &oauth2.Token{
+		AccessToken:  integration.AccessToken,
+		RefreshToken: integration.RefreshToken,
+		Expiry:       integration.TokenExpiry,
+	}
+}
+
+// Connect returns an events client for the integration, refreshing an expired access
+// token first and saving the new token on the integration
+func Connect(ctx context.Context, db *gorm.DB, provider Provider, integration *models.CalendarIntegration) (EventsService, error) {
+	token := IntegrationToken(integration)
+	if !token.Valid() {
+		refreshed, err := provider.OAuthConfig().TokenSource(ctx, token).Token()
+		if err != nil {
+			return nil, fmt.Errorf("refresh access token: %w", err)
+		}
+
+		integration.AccessToken = refreshed.AccessToken
+		if refreshed.RefreshToken != "" {
+			integration.RefreshToken = refreshed.RefreshToken
+		}
+		integration.TokenExpiry = refreshed.Expiry
+		if err := db.Model(integration).Updates(map[string]interface{}{
+			"access_token":  integration.AccessToken,
+			"refresh_token": integration.RefreshToken,
+			"token_expiry":  integration.TokenExpiry,
+		}).Error; err != nil {
+			return nil, err
+		}
+		token = refreshed
+	}
+
+	return provider.Events(ctx, token, util.LoadUserLocation(db, integration.UserID))
+}
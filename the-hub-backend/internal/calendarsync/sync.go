@@ -0,0 +1,394 @@
+package calendarsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DirectionPull = "pull"
+	DirectionPush = "push"
+
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionDelete   = "delete"
+	ActionConflict = "conflict"
+
+	WinnerLocal  = "local"
+	WinnerRemote = "remote"
+
+	// scheduledTaskProperty links events we pushed to Google back to their scheduled task
+	scheduledTaskProperty = "theHubScheduledTaskId"
+	// pushWindow limits how far back never-synced local events are pushed
+	pushWindow = 30 * 24 * time.Hour
+)
+
+// Result summarises one sync run
+type Result struct {
+	Pulled           int  `json:"pulled"`
+	Pushed           int  `json:"pushed"`
+	DeletedLocal     int  `json:"deleted_local"`
+	DeletedRemote    int  `json:"deleted_remote"`
+	Conflicts        int  `json:"conflicts"`
+	SkippedRecurring int  `json:"skipped_recurring"` // Recurring series left out of the push
+	FullSync         bool `json:"full_sync"`
+}
+
+// Syncer runs two-way sync between scheduled tasks and an external calendar
+type Syncer struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewSyncer creates a syncer backed by db
+func NewSyncer(db *gorm.DB) *Syncer {
+	return &Syncer{db: db, now: time.Now}
+}
+
+// ResolveConflict picks the winner when an event changed on both sides since the last sync.
+// The later write wins; ties go to the local copy.
+func ResolveConflict(localUpdated, remoteUpdated time.Time) string {
+	if remoteUpdated.After(localUpdated) {
+		return WinnerRemote
+	}
+	return WinnerLocal
+}
+
+// Sync pulls remote changes since the integration's sync token, then pushes local
+// changes and deletions. The integration's sync token and last sync time are updated.
+func (s *Syncer) Sync(ctx context.Context, events EventsService, integration *models.CalendarIntegration) (*Result, error) {
+	result := &Result{}
+
+	if err := s.pull(ctx, events, integration, result); err != nil {
+		return result, fmt.Errorf("pull: %w", err)
+	}
+	// Pushed events are written in the user's timezone rather than UTC
+	loc := util.LoadUserLocation(s.db, integration.UserID)
+	if err := s.push(ctx, events, integration, loc, result); err != nil {
+		return result, fmt.Errorf("push: %w", err)
+	}
+
+	now := s.now()
+	integration.LastSyncAt = &now
+	if err := s.db.Model(integration).Updates(map[string]interface{}{
+		"sync_token":   integration.SyncToken,
+		"last_sync_at": now,
+	}).Error; err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// pull applies remote changes. An expired sync token falls back to a full listing.
+func (s *Syncer) pull(ctx context.Context, events EventsService, integration *models.CalendarIntegration, result *Result) error {
+	remote, nextSyncToken, err := listAll(ctx, events, integration.CalendarID, integration.SyncToken)
+	if errors.Is(err, ErrSyncTokenExpired) && integration.SyncToken != "" {
+		config.Logger.Infof("Sync token expired for calendar integration %s, running full sync", integration.ID)
+		remote, nextSyncToken, err = listAll(ctx, events, integration.CalendarID, "")
+		result.FullSync = true
+	} else if integration.SyncToken == "" {
+		result.FullSync = true
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, event := range remote {
+		if err := s.applyRemote(integration, event, result); err != nil {
+			config.Logger.Errorf("Failed to apply remote event %s for integration %s: %v", event.ID, integration.ID, err)
+		}
+	}
+
+	// Only advance the token once every page was read, so a failed run retries the same changes
+	integration.SyncToken = nextSyncToken
+	return nil
+}
+
+func listAll(ctx context.Context, events EventsService, calendarID, syncToken string) ([]RemoteEvent, string, error) {
+	var all []RemoteEvent
+	pageToken := ""
+	for {
+		page, err := events.List(ctx, calendarID, syncToken, pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		all = append(all, page.Events...)
+		if page.NextPageToken == "" {
+			return all, page.SyncToken, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func (s *Syncer) applyRemote(integration *models.CalendarIntegration, event RemoteEvent, result *Result) error {
+	remoteUpdated := event.Updated
+
+	var mapping models.CalendarEventSync
+	err := s.db.Where("calendar_integration_id = ? AND external_event_id = ?", integration.ID, event.ID).First(&mapping).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	mapped := err == nil
+
+	removed := !event.Busy()
+
+	if !mapped {
+		if removed {
+			return nil
+		}
+		return s.createFromRemote(integration, event, result)
+	}
+
+	// Our own pushes come back through the sync token; skip anything we've already seen
+	if !remoteUpdated.After(mapping.RemoteUpdatedAt) {
+		return nil
+	}
+
+	var task models.ScheduledTask
+	if err := s.db.Where("id = ?", mapping.ScheduledTaskID).First(&task).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Deleted locally; the push phase removes the remote event
+			s.audit(integration.ID, nil, event.ID, DirectionPull, ActionConflict, WinnerLocal, "event changed remotely after it was deleted locally")
+			result.Conflicts++
+			return nil
+		}
+		return err
+	}
+
+	if task.UpdatedAt.After(mapping.LastSyncedAt) {
+		winner := ResolveConflict(task.UpdatedAt, remoteUpdated)
+		result.Conflicts++
+		s.audit(integration.ID, &task.ID, event.ID, DirectionPull, ActionConflict, winner,
+			fmt.Sprintf("local updated %s, remote updated %s", task.UpdatedAt.UTC().Format(time.RFC3339), remoteUpdated.UTC().Format(time.RFC3339)))
+		if winner == WinnerLocal {
+			// The push phase overwrites the remote event
+			return nil
+		}
+	}
+
+	if removed {
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&mapping).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&task).Error; err != nil {
+				return err
+			}
+			result.DeletedLocal++
+			s.auditTx(tx, integration.ID, &task.ID, event.ID, DirectionPull, ActionDelete, "", task.Title)
+			return nil
+		})
+	}
+
+	task.Title = eventTitle(event)
+	task.Start = event.Start
+	task.End = event.End
+	if err := s.db.Save(&task).Error; err != nil {
+		return err
+	}
+
+	mapping.RemoteUpdatedAt = remoteUpdated
+	mapping.LastSyncedAt = s.now()
+	if err := s.db.Save(&mapping).Error; err != nil {
+		return err
+	}
+
+	result.Pulled++
+	s.audit(integration.ID, &task.ID, event.ID, DirectionPull, ActionUpdate, "", task.Title)
+	return nil
+}
+
+func (s *Syncer) createFromRemote(integration *models.CalendarIntegration, event RemoteEvent, result *Result) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		task := models.ScheduledTask{
+			Title:  eventTitle(event),
+			Start:  event.Start,
+			End:    event.End,
+			UserID: integration.UserID,
+			Source: string(integration.Provider),
+		}
+		if err := tx.Create(&task).Error; err != nil {
+			return err
+		}
+
+		mapping := models.CalendarEventSync{
+			ScheduledTaskID:       task.ID,
+			CalendarIntegrationID: integration.ID,
+			ExternalEventID:       event.ID,
+			RemoteUpdatedAt:       event.Updated,
+			LastSyncedAt:          s.now(),
+		}
+		if err := tx.Create(&mapping).Error; err != nil {
+			return err
+		}
+
+		result.Pulled++
+		s.auditTx(tx, integration.ID, &task.ID, event.ID, DirectionPull, ActionCreate, "", task.Title)
+		return nil
+	})
+}
+
+// push sends local deletions, edits and new events to the remote calendar.
+//
+// Recurring series are not pushed. Providers list events expanded into occurrences,
+// so a pushed series would come back as one unmapped event per occurrence, and its
+// overrides and cancelled occurrences have no single remote event to map to. A series
+// master is therefore skipped along with its overrides, and an event pushed before
+// its task became recurring is removed from the remote calendar.
+func (s *Syncer) push(ctx context.Context, events EventsService, integration *models.CalendarIntegration, loc *time.Location, result *Result) error {
+	var orphans []models.CalendarEventSync
+	if err := s.db.Where("calendar_integration_id = ? AND scheduled_task_id NOT IN (SELECT id FROM scheduled_tasks)", integration.ID).
+		Find(&orphans).Error; err != nil {
+		return err
+	}
+	for _, mapping := range orphans {
+		err := events.Delete(ctx, integration.CalendarID, mapping.ExternalEventID)
+		if err != nil && !errors.Is(err, ErrEventNotFound) {
+			config.Logger.Errorf("Failed to delete remote event %s: %v", mapping.ExternalEventID, err)
+			continue
+		}
+		if err := s.db.Delete(&mapping).Error; err != nil {
+			return err
+		}
+		result.DeletedRemote++
+		s.audit(integration.ID, nil, mapping.ExternalEventID, DirectionPush, ActionDelete, "", "")
+	}
+
+	// Overrides belong to a recurring series, which is not pushed
+	var tasks []models.ScheduledTask
+	if err := s.db.Preload("RecurrenceRule").Where("user_id = ? AND recurring_event_id IS NULL", integration.UserID).
+		Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	var mappings []models.CalendarEventSync
+	if err := s.db.Where("calendar_integration_id = ?", integration.ID).Find(&mappings).Error; err != nil {
+		return err
+	}
+	byTask := make(map[uuid.UUID]models.CalendarEventSync, len(mappings))
+	for _, mapping := range mappings {
+		byTask[mapping.ScheduledTaskID] = mapping
+	}
+
+	cutoff := s.now().Add(-pushWindow)
+	for _, task := range tasks {
+		mapping, mapped := byTask[task.ID]
+		if task.IsRecurring() {
+			result.SkippedRecurring++
+			if mapped {
+				if err := s.unpushSeries(ctx, events, integration, task, mapping, result); err != nil {
+					config.Logger.Errorf("Failed to remove pushed copy of recurring scheduled task %s: %v", task.ID, err)
+				}
+			}
+			continue
+		}
+		if mapped && !task.UpdatedAt.After(mapping.LastSyncedAt) {
+			continue
+		}
+		// Events pulled from another calendar stay there
+		if !mapped && (task.Source != "" || task.End.Before(cutoff)) {
+			continue
+		}
+
+		if err := s.pushTask(ctx, events, integration, task, mapping, mapped, loc); err != nil {
+			config.Logger.Errorf("Failed to push scheduled task %s: %v", task.ID, err)
+			continue
+		}
+		result.Pushed++
+	}
+
+	return nil
+}
+
+// unpushSeries removes the single remote event pushed for a task that has since become
+// a recurring series, since it only stands for the series' first occurrence
+func (s *Syncer) unpushSeries(ctx context.Context, events EventsService, integration *models.CalendarIntegration, task models.ScheduledTask, mapping models.CalendarEventSync, result *Result) error {
+	err := events.Delete(ctx, integration.CalendarID, mapping.ExternalEventID)
+	if err != nil && !errors.Is(err, ErrEventNotFound) {
+		return err
+	}
+	if err := s.db.Delete(&mapping).Error; err != nil {
+		return err
+	}
+	result.DeletedRemote++
+	s.audit(integration.ID, &task.ID, mapping.ExternalEventID, DirectionPush, ActionDelete, "", "recurring series are not synced")
+	return nil
+}
+
+func (s *Syncer) pushTask(ctx context.Context, events EventsService, integration *models.CalendarIntegration, task models.ScheduledTask, mapping models.CalendarEventSync, mapped bool, loc *time.Location) error {
+	event := RemoteEvent{
+		Title:   task.Title,
+		Start:   task.Start.In(loc),
+		End:     task.End.In(loc),
+		LocalID: task.ID.String(),
+	}
+
+	var saved *RemoteEvent
+	var err error
+	action := ActionCreate
+	if mapped {
+		action = ActionUpdate
+		saved, err = events.Update(ctx, integration.CalendarID, mapping.ExternalEventID, event)
+		if errors.Is(err, ErrEventNotFound) {
+			// The remote copy is gone but the local edit is newer, so recreate it
+			action = ActionCreate
+			saved, err = events.Insert(ctx, integration.CalendarID, event)
+		}
+	} else {
+		saved, err = events.Insert(ctx, integration.CalendarID, event)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !mapped {
+		mapping = models.CalendarEventSync{
+			ScheduledTaskID:       task.ID,
+			CalendarIntegrationID: integration.ID,
+		}
+	}
+	mapping.ExternalEventID = saved.ID
+	mapping.RemoteUpdatedAt = saved.Updated
+	mapping.LastSyncedAt = s.now()
+	if err := s.db.Save(&mapping).Error; err != nil {
+		return err
+	}
+
+	s.audit(integration.ID, &task.ID, saved.ID, DirectionPush, action, "", task.Title)
+	return nil
+}
+
+func eventTitle(event RemoteEvent) string {
+	if event.Title != "" {
+		return event.Title
+	}
+	return "Busy"
+}
+
+func (s *Syncer) audit(integrationID uuid.UUID, taskID *uuid.UUID, eventID, direction, action, winner, details string) {
+	s.auditTx(s.db, integrationID, taskID, eventID, direction, action, winner, details)
+}
+
+func (s *Syncer) auditTx(tx *gorm.DB, integrationID uuid.UUID, taskID *uuid.UUID, eventID, direction, action, winner, details string) {
+	entry := models.CalendarSyncAudit{
+		CalendarIntegrationID: integrationID,
+		ScheduledTaskID:       taskID,
+		ExternalEventID:       eventID,
+		Direction:             direction,
+		Action:                action,
+		Winner:                winner,
+		Details:               details,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		config.Logger.Errorf("Failed to record calendar sync audit: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package calendarsync
+
+import (
+	"context"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"gorm.io/gorm"
+)
+
+// Worker periodically syncs every active integration
+type Worker struct {
+	db        *gorm.DB
+	syncer    *Syncer
+	interval  time.Duration
+	providers *Registry
+}
+
+// NewWorker creates a worker that syncs every interval
+func NewWorker(db *gorm.DB, interval time.Duration, providers *Registry) *Worker {
+	return &Worker{
+		db:        db,
+		syncer:    NewSyncer(db),
+		interval:  interval,
+		providers: providers,
+	}
+}
+
+// Run syncs immediately and then on every tick until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.SyncAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncAll runs one sync for each active integration with a registered provider.
+// Failures are logged per integration so one broken account does not block the others.
+func (w *Worker) SyncAll(ctx context.Context) {
+	var integrations []models.CalendarIntegration
+	if err := w.db.Where("is_active = ? AND sync_enabled = ? AND provider IN ?", true, true, w.providers.Names()).
+		Find(&integrations).Error; err != nil {
+		config.Logger.Errorf("Failed to load calendar integrations for sync: %v", err)
+		return
+	}
+
+	for i := range integrations {
+		if ctx.Err() != nil {
+			return
+		}
+		integration := &integrations[i]
+
+		provider, err := w.providers.Get(integration.Provider)
+		if err != nil {
+			continue
+		}
+
+		events, err := Connect(ctx, w.db, provider, integration)
+		if err != nil {
+			config.Logger.Errorf("Failed to create calendar client for integration %s: %v", integration.ID, err)
+			continue
+		}
+
+		result, err := w.syncer.Sync(ctx, events, integration)
+		if err != nil {
+			config.Logger.Errorf("Calendar sync failed for integration %s: %v", integration.ID, err)
+			continue
+		}
+		config.Logger.Infof("Synced calendar integration %s: %d pulled, %d pushed, %d conflicts",
+			integration.ID, result.Pulled, result.Pushed, result.Conflicts)
+	}
+}
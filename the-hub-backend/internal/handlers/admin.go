@@ -28,6 +28,7 @@ type AdminStatsResponse struct {
 	TotalGoals         int64 `json:"total_goals"`
 	TotalDecks         int64 `json:"total_decks"`
 	TotalStudySessions int64 `json:"total_study_sessions"`
+	PendingDeckReports int64 `json:"pending_deck_reports"`
 }
 
 // UpdateUserRoleRequest represents request to update user role
@@ -235,6 +236,13 @@ func GetSystemStats(c *gin.Context) {
 		return
 	}
 
+	// Count deck reports awaiting review
+	if err := config.GetDB().Model(&models.DeckReport{}).Where("status = ?", models.DeckReportPending).Count(&stats.PendingDeckReports).Error; err != nil {
+		config.Logger.Errorf("Error counting deck reports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch deck report statistics"})
+		return
+	}
+
 	config.Logger.Info("Admin fetched system statistics")
 	c.JSON(http.StatusOK, stats)
 }
@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/anki"
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/notetype"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxAnkiFileSize = 100 << 20 // Packages carry media, so they may be larger than CSV/JSON imports
+	maxAnkiCards    = 10000
+
+	// flashcardMediaURL is where media of imported packages is served from
+	flashcardMediaURL = "/uploads/flashcards/"
+)
+
+// AnkiImportResult represents the result of importing an Anki package
+type AnkiImportResult struct {
+	ImportResult
+	Decks      []models.Deck `json:"decks"`
+	Duplicates int           `json:"duplicates"` // Notes skipped because they were imported before
+	Media      int           `json:"media"`
+}
+
+// ImportAnkiPackage godoc
+// @Summary      Import an Anki package
+// @Description  Import an Anki .apkg file, creating a deck for each Anki deck with cards (or adding to the user's deck of the same name). Basic, reversed, cloze and type-in notes are converted to notes; media is stored and linked. Notes imported before are skipped.
+// @Tags         cards
+// @Accept       multipart/form-data
+// @Produce      json
+// @Security     BearerAuth
+// @Param        file                formData  file  true   "Anki package (.apkg)"
+// @Param        include_scheduling  formData  bool  false  "Keep the cards' Anki scheduling state (default false)"
+// @Success      200  {object}  AnkiImportResult
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/import/apkg [post]
+func ImportAnkiPackage(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		config.Logger.Errorf("Invalid userID type in context: %T", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	importAnki(c, userIDUUID, nil)
+}
+
+// importAnki imports the uploaded package into target, or into decks named after the
+// package's decks when target is nil
+func importAnki(c *gin.Context, userID uuid.UUID, target *models.Deck) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		config.Logger.Warnf("Error getting uploaded file: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	defer file.Close()
+	if header.Size > maxAnkiFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File too large. Maximum size is 100MB"})
+		return
+	}
+	includeScheduling, _ := strconv.ParseBool(c.DefaultPostForm("include_scheduling", c.DefaultQuery("include_scheduling", "false")))
+
+	pkg, err := anki.Read(file, header.Size)
+	if err != nil {
+		config.Logger.Warnf("Invalid Anki package from user %s: %v", userID, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Anki package", "details": err.Error()})
+		return
+	}
+
+	cardCount := 0
+	for _, note := range pkg.Notes {
+		cardCount += len(note.Cards)
+	}
+	if cardCount > maxAnkiCards {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Too many cards. Maximum %d cards per import", maxAnkiCards)})
+		return
+	}
+
+	mediaURLs, err := saveAnkiMedia(userID, pkg.Media)
+	if err != nil {
+		config.Logger.Errorf("Failed to store Anki media for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store media files"})
+		return
+	}
+
+	var result AnkiImportResult
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		decks, err := ankiDecks(tx, userID, pkg, target)
+		if err != nil {
+			return err
+		}
+		result, err = importAnkiNotes(tx, pkg, decks, mediaURLs, includeScheduling)
+		return err
+	})
+	if err != nil {
+		config.Logger.Errorf("Error importing Anki package for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete import"})
+		return
+	}
+	if pkg.Skipped > 0 {
+		result.Errors = append(result.Errors, ImportError{
+			Error: fmt.Sprintf("%d notes had a note type that could not be read", pkg.Skipped),
+		})
+		result.ErrorCount = len(result.Errors)
+	}
+	result.Media = len(mediaURLs)
+
+	config.Logger.Infof("Imported Anki package for user %s: %d cards, %d duplicates, %d errors",
+		userID, result.SuccessCount, result.Duplicates, result.ErrorCount)
+	c.JSON(http.StatusOK, result)
+}
+
+// ankiDecks maps the IDs of the package's decks to the decks their cards go to
+func ankiDecks(tx *gorm.DB, userID uuid.UUID, pkg *anki.Package, target *models.Deck) (map[int64]*models.Deck, error) {
+	decks := make(map[int64]*models.Deck, len(pkg.Decks))
+	if target != nil {
+		for _, deck := range pkg.Decks {
+			decks[deck.ID] = target
+		}
+		return decks, nil
+	}
+
+	used := make(map[int64]bool)
+	for _, note := range pkg.Notes {
+		if len(note.Cards) > 0 {
+			used[note.Cards[0].DeckID] = true
+		}
+	}
+	for _, ankiDeck := range pkg.Decks {
+		if !used[ankiDeck.ID] {
+			continue
+		}
+		var deck models.Deck
+		err := tx.Where("name = ? AND user_id = ?", ankiDeck.Name, userID).First(&deck).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			deck = models.Deck{Name: ankiDeck.Name, UserID: userID}
+			err = tx.Create(&deck).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+		decks[ankiDeck.ID] = &deck
+	}
+	return decks, nil
+}
+
+// importAnkiNotes creates the package's notes in the decks their first card is in,
+// skipping notes already imported into that deck
+func importAnkiNotes(tx *gorm.DB, pkg *anki.Package, decks map[int64]*models.Deck, mediaURLs map[string]string, includeScheduling bool) (AnkiImportResult, error) {
+	result := AnkiImportResult{}
+	seen := make(map[uuid.UUID]bool)
+	rewrite := func(text string) string {
+		return anki.RewriteMedia(text, func(name string) string {
+			if url, found := mediaURLs[name]; found {
+				return url
+			}
+			return name
+		})
+	}
+
+	for _, ankiNote := range pkg.Notes {
+		if len(ankiNote.Cards) == 0 {
+			continue
+		}
+		deck := decks[ankiNote.Cards[0].DeckID]
+		if deck == nil {
+			continue
+		}
+		if !seen[deck.ID] {
+			seen[deck.ID] = true
+			result.Decks = append(result.Decks, *deck)
+		}
+
+		if ankiNote.GUID != "" {
+			var count int64
+			if err := tx.Model(&models.CardNote{}).
+				Where("deck_id = ? AND source_id = ?", deck.ID, ankiNote.GUID).
+				Count(&count).Error; err != nil {
+				return result, err
+			}
+			if count > 0 {
+				result.Duplicates++
+				continue
+			}
+		}
+
+		note := models.CardNote{
+			DeckID:   deck.ID,
+			Model:    ankiNote.Type,
+			Front:    rewrite(ankiNote.Front),
+			Back:     rewrite(ankiNote.Back),
+			Extra:    rewrite(ankiNote.Extra),
+			SourceID: ankiNote.GUID,
+		}
+		if _, err := notetype.Generate(note.Model, note.Front, note.Back, note.Extra); err != nil {
+			result.Errors = append(result.Errors, ImportError{
+				Error: fmt.Sprintf("Skipped %s note '%s': %v", note.Model, note.Front, err),
+			})
+			continue
+		}
+		if err := tx.Omit("Cards").Create(&note).Error; err != nil {
+			return result, err
+		}
+		cards, err := syncNoteCards(tx, &note)
+		if err != nil {
+			return result, err
+		}
+		result.SuccessCount += len(cards)
+
+		if !includeScheduling {
+			continue
+		}
+		schedules := make(map[string]anki.Schedule, len(ankiNote.Cards))
+		for _, card := range ankiNote.Cards {
+			schedules[card.Template] = card.Schedule
+		}
+		for i := range cards {
+			schedule, found := schedules[cards[i].Template]
+			if !found {
+				continue
+			}
+			applyAnkiSchedule(&cards[i], schedule)
+			if err := tx.Model(&cards[i]).
+				Select("easiness", "interval", "repetitions", "lapses", "suspended", "last_reviewed", "next_review").
+				Updates(&cards[i]).Error; err != nil {
+				return result, err
+			}
+		}
+	}
+	result.ErrorCount = len(result.Errors)
+	return result, nil
+}
+
+// applyAnkiSchedule sets a card's scheduling state from its Anki card
+func applyAnkiSchedule(card *models.Card, schedule anki.Schedule) {
+	card.Suspended = schedule.Suspended
+	if schedule.New {
+		return
+	}
+	card.Easiness = schedule.Easiness
+	card.Interval = schedule.Interval
+	card.Repetitions = schedule.Repetitions
+	card.Lapses = schedule.Lapses
+	card.LastReviewed = schedule.LastReview
+	card.NextReview = schedule.Due
+}
+
+// ankiSchedule is the Anki scheduling state of a card
+func ankiSchedule(card models.Card) anki.Schedule {
+	if card.LastReviewed.IsZero() {
+		return anki.Schedule{New: true, Suspended: card.Suspended}
+	}
+	return anki.Schedule{
+		Suspended:   card.Suspended,
+		Interval:    card.Interval,
+		Easiness:    card.Easiness,
+		Repetitions: card.Repetitions,
+		Lapses:      card.Lapses,
+		LastReview:  card.LastReviewed,
+		Due:         card.NextReview,
+	}
+}
+
+// saveAnkiMedia stores a package's media files under uploads, returning the URL each
+// is served at. Files are named by content so that importing again reuses them.
+func saveAnkiMedia(userID uuid.UUID, media map[string][]byte) (map[string]string, error) {
+	urls := make(map[string]string, len(media))
+	if len(media) == 0 {
+		return urls, nil
+	}
+	dirPath := filepath.Join("uploads", "flashcards", userID.String())
+	if err := config.EnsureDir(dirPath); err != nil {
+		return nil, err
+	}
+	for name, data := range media {
+		sum := sha1.Sum(data)
+		filename := hex.EncodeToString(sum[:6]) + "_" + strings.NewReplacer(" ", "_", "/", "_", "\\", "_").Replace(name)
+		filePath := filepath.Join(dirPath, filename)
+		if _, err := os.Stat(filePath); err != nil {
+			if err := os.WriteFile(filePath, data, 0644); err != nil {
+				return nil, err
+			}
+		}
+		urls[name] = flashcardMediaURL + userID.String() + "/" + filename
+	}
+	return urls, nil
+}
+
+// writeAnkiExport writes a deck's notes and cards as an Anki package. Cards not
+// generated from a note are exported as basic notes; stored media they refer to is
+// included.
+func writeAnkiExport(c *gin.Context, deck models.Deck, cards []models.Card, notes []models.CardNote) {
+	const ankiDeckID = 2 // 1 is Anki's default deck
+	pkg := &anki.Package{
+		Created: time.Now(),
+		Decks:   []anki.Deck{{ID: ankiDeckID, Name: deck.Name}},
+		Media:   make(map[string][]byte),
+	}
+	rewrite := func(text string) string {
+		return anki.RewriteMedia(text, func(ref string) string {
+			if !strings.HasPrefix(ref, flashcardMediaURL) || strings.Contains(ref, "..") {
+				return ref
+			}
+			name := path.Base(ref)
+			if _, found := pkg.Media[name]; !found {
+				data, err := os.ReadFile(filepath.FromSlash(strings.TrimPrefix(ref, "/")))
+				if err != nil {
+					config.Logger.Warnf("Media file %s of deck %s not found: %v", ref, deck.ID, err)
+					return ref
+				}
+				pkg.Media[name] = data
+			}
+			return name
+		})
+	}
+
+	noteIndex := make(map[uuid.UUID]int, len(notes))
+	for _, note := range notes {
+		// Notes keep their identity across round trips, so importing again skips them
+		guid := note.SourceID
+		if guid == "" {
+			guid = note.ID.String()
+		}
+		noteIndex[note.ID] = len(pkg.Notes)
+		pkg.Notes = append(pkg.Notes, anki.Note{
+			GUID:  guid,
+			Type:  note.Model,
+			Front: rewrite(note.Front),
+			Back:  rewrite(note.Back),
+			Extra: rewrite(note.Extra),
+		})
+	}
+	for _, card := range cards {
+		if card.NoteID != nil {
+			if i, found := noteIndex[*card.NoteID]; found {
+				note := &pkg.Notes[i]
+				note.Cards = append(note.Cards, anki.Card{DeckID: ankiDeckID, Template: card.Template, Schedule: ankiSchedule(card)})
+				continue
+			}
+		}
+		pkg.Notes = append(pkg.Notes, anki.Note{
+			GUID:  card.ID.String(),
+			Type:  notetype.Basic,
+			Front: rewrite(card.Question),
+			Back:  rewrite(card.Answer),
+			Cards: []anki.Card{{DeckID: ankiDeckID, Template: notetype.TemplateForward, Schedule: ankiSchedule(card)}},
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := anki.Write(&buf, pkg); err != nil {
+		config.Logger.Errorf("Error writing Anki package for deck %s: %v", deck.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not export cards"})
+		return
+	}
+
+	filename := fmt.Sprintf("%s_%s.apkg", strings.ReplaceAll(deck.Name, " ", "_"), time.Now().Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "application/apkg", buf.Bytes())
+}
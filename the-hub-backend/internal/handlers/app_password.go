@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxAppPasswordsPerUser = 20
+
+// GetAppPasswords lists the user's app passwords (without the passwords themselves)
+func GetAppPasswords(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var passwords []models.AppPassword
+	if err := config.GetDB().Where("user_id = ?", userID).Order("created_at DESC").Find(&passwords).Error; err != nil {
+		config.Logger.Errorf("Error fetching app passwords for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch app passwords"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"app_passwords": passwords})
+}
+
+// CreateAppPassword generates a new app password. The plain password is only returned here.
+func CreateAppPassword(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	var input struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" || len(input.Name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name must be between 1 and 100 characters"})
+		return
+	}
+
+	var count int64
+	if err := config.GetDB().Model(&models.AppPassword{}).Where("user_id = ?", userIDUUID).Count(&count).Error; err != nil {
+		config.Logger.Errorf("Error counting app passwords for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create app password"})
+		return
+	}
+	if count >= maxAppPasswordsPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many app passwords. Revoke an unused one first"})
+		return
+	}
+
+	var user models.User
+	if err := config.GetDB().Select("email").Where("id = ?", userIDUUID).First(&user).Error; err != nil {
+		config.Logger.Errorf("Error fetching user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create app password"})
+		return
+	}
+
+	password, err := util.GenerateSecureToken()
+	if err != nil {
+		config.Logger.Errorf("Error generating app password: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create app password"})
+		return
+	}
+
+	appPassword := models.AppPassword{
+		UserID:       userIDUUID,
+		Name:         input.Name,
+		PasswordHash: util.HashAppPassword(password),
+	}
+	if err := config.GetDB().Create(&appPassword).Error; err != nil {
+		config.Logger.Errorf("Error creating app password for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create app password"})
+		return
+	}
+
+	config.Logger.Infof("Created app password %s for user %s", appPassword.ID, userIDUUID)
+	c.JSON(http.StatusCreated, gin.H{
+		"app_password": appPassword,
+		"username":     user.Email,
+		"password":     password,
+		"caldav_url":   apiBaseURL(c) + caldavRoot + "/",
+	})
+}
+
+// DeleteAppPassword revokes an app password
+func DeleteAppPassword(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	result := config.GetDB().Where("id = ? AND user_id = ?", c.Param("ID"), userID).Delete(&models.AppPassword{})
+	if result.Error != nil {
+		config.Logger.Errorf("Error deleting app password for user %s: %v", userID, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not revoke app password"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "App password not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "App password revoked"})
+}
@@ -0,0 +1,623 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/booking"
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxBookingRange bounds how many days of slots one request lists
+const maxBookingRange = 31 * 24 * time.Hour
+
+var errSlotTaken = errors.New("this time is no longer available")
+
+// BookingLinkRequest creates or changes a booking link. Omitted fields keep their
+// current value, or the default for new links.
+type BookingLinkRequest struct {
+	Title           *string   `json:"title"`
+	Description     *string   `json:"description"`
+	DurationMinutes *int      `json:"duration_minutes"`
+	BufferBefore    *int      `json:"buffer_before_minutes"`
+	BufferAfter     *int      `json:"buffer_after_minutes"`
+	DailyLimit      *int      `json:"daily_limit"` // 0 removes the limit
+	MinNotice       *int      `json:"min_notice_minutes"`
+	MaxDaysAhead    *int      `json:"max_days_ahead"`
+	ZoneIDs         *[]string `json:"zone_ids"`
+	IsActive        *bool     `json:"is_active"`
+}
+
+// CreateBookingRequest books time through a public booking link
+type CreateBookingRequest struct {
+	Start    time.Time `json:"start" binding:"required"`
+	Name     string    `json:"name" binding:"required"`
+	Email    string    `json:"email" binding:"required"`
+	Notes    string    `json:"notes"`
+	Timezone string    `json:"timezone"` // used for the confirmation email; defaults to the owner's
+}
+
+// bookingLinkURL is the public URL of a booking link: the frontend's booking page when
+// FRONTEND_URL is set, the public API otherwise
+func bookingLinkURL(c *gin.Context, token string) string {
+	if frontend := strings.TrimRight(os.Getenv("FRONTEND_URL"), "/"); frontend != "" {
+		return fmt.Sprintf("%s/book/%s", frontend, token)
+	}
+	return fmt.Sprintf("%s/book/%s", apiBaseURL(c), token)
+}
+
+func bookingLinkResponse(c *gin.Context, link models.BookingLink) gin.H {
+	return gin.H{
+		"link": link,
+		"url":  bookingLinkURL(c, link.Token),
+	}
+}
+
+// applyBookingLinkRequest copies the request onto link and checks the result
+func applyBookingLinkRequest(db *gorm.DB, link *models.BookingLink, input BookingLinkRequest) error {
+	if input.Title != nil {
+		link.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Description != nil {
+		link.Description = *input.Description
+	}
+	if input.DurationMinutes != nil {
+		link.DurationMinutes = *input.DurationMinutes
+	}
+	if input.BufferBefore != nil {
+		link.BufferBefore = *input.BufferBefore
+	}
+	if input.BufferAfter != nil {
+		link.BufferAfter = *input.BufferAfter
+	}
+	if input.DailyLimit != nil {
+		link.DailyLimit = input.DailyLimit
+		if *input.DailyLimit == 0 {
+			link.DailyLimit = nil
+		}
+	}
+	if input.MinNotice != nil {
+		link.MinNotice = *input.MinNotice
+	}
+	if input.MaxDaysAhead != nil {
+		link.MaxDaysAhead = *input.MaxDaysAhead
+	}
+	if input.IsActive != nil {
+		link.IsActive = *input.IsActive
+	}
+
+	switch {
+	case link.Title == "":
+		return errors.New("title is required")
+	case link.DurationMinutes < 5 || link.DurationMinutes > 8*60:
+		return errors.New("duration_minutes must be between 5 and 480")
+	case link.BufferBefore < 0 || link.BufferBefore > 240 || link.BufferAfter < 0 || link.BufferAfter > 240:
+		return errors.New("buffers must be between 0 and 240 minutes")
+	case link.DailyLimit != nil && *link.DailyLimit < 0:
+		return errors.New("daily_limit must not be negative")
+	case link.MinNotice < 0:
+		return errors.New("min_notice_minutes must not be negative")
+	case link.MaxDaysAhead < 1 || link.MaxDaysAhead > 365:
+		return errors.New("max_days_ahead must be between 1 and 365")
+	}
+
+	if input.ZoneIDs != nil {
+		zoneIDs := make([]string, 0, len(*input.ZoneIDs))
+		for _, id := range *input.ZoneIDs {
+			zoneID, err := uuid.Parse(id)
+			if err != nil {
+				return fmt.Errorf("invalid zone ID %q", id)
+			}
+			zoneIDs = append(zoneIDs, zoneID.String())
+		}
+		if len(zoneIDs) > 0 {
+			var count int64
+			if err := db.Model(&models.CalendarZone{}).Where("user_id = ? AND id IN ?", link.UserID, zoneIDs).Count(&count).Error; err != nil {
+				return err
+			}
+			if int(count) != len(zoneIDs) {
+				return errors.New("zone_ids must be your own calendar zones")
+			}
+		}
+		link.ZoneIDs = zoneIDs
+	}
+	return nil
+}
+
+// GetBookingLinks godoc
+// @Summary      List booking links
+// @Description  List the user's booking links with their public URLs
+// @Tags         booking
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /booking-links [get]
+func GetBookingLinks(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var links []models.BookingLink
+	if err := config.GetDB().Where("user_id = ?", userID).Order("created_at").Find(&links).Error; err != nil {
+		config.Logger.Errorf("Error fetching booking links for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch booking links"})
+		return
+	}
+
+	response := make([]gin.H, 0, len(links))
+	for _, link := range links {
+		response = append(response, bookingLinkResponse(c, link))
+	}
+	c.JSON(http.StatusOK, gin.H{"links": response})
+}
+
+// CreateBookingLink godoc
+// @Summary      Create booking link
+// @Description  Create a public link through which others can book time in the user's bookable calendar zones
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        link  body      BookingLinkRequest  true  "Booking link"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /booking-links [post]
+func CreateBookingLink(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	var input BookingLinkRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid booking link input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	token, err := util.GenerateSecureToken()
+	if err != nil {
+		config.Logger.Errorf("Error generating booking link token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate booking link token"})
+		return
+	}
+
+	link := models.BookingLink{
+		UserID:          userIDUUID,
+		Token:           token,
+		DurationMinutes: 30,
+		MinNotice:       60,
+		MaxDaysAhead:    30,
+		IsActive:        true,
+	}
+	if err := applyBookingLinkRequest(config.GetDB(), &link, input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// is_active defaults to true in the database, so an inactive link is written explicitly
+	if err := config.GetDB().Select("*").Omit("id").Create(&link).Error; err != nil {
+		config.Logger.Errorf("Error creating booking link for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create booking link"})
+		return
+	}
+
+	config.Logger.Infof("Created booking link %s for user %s", link.ID, userIDUUID)
+	c.JSON(http.StatusCreated, bookingLinkResponse(c, link))
+}
+
+// UpdateBookingLink godoc
+// @Summary      Update booking link
+// @Description  Change a booking link's duration, buffers, limits, zones or status
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        linkID  path      string              true  "Booking link ID"
+// @Param        link    body      BookingLinkRequest  true  "Booking link changes"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /booking-links/{linkID} [put]
+func UpdateBookingLink(c *gin.Context) {
+	link, ok := ownBookingLink(c)
+	if !ok {
+		return
+	}
+
+	var input BookingLinkRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid booking link input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if err := applyBookingLinkRequest(config.GetDB(), link, input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.GetDB().Select("*").Omit("id", "user_id", "token", "created_at").Updates(link).Error; err != nil {
+		config.Logger.Errorf("Error updating booking link %s: %v", link.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update booking link"})
+		return
+	}
+
+	config.Logger.Infof("Updated booking link %s", link.ID)
+	c.JSON(http.StatusOK, bookingLinkResponse(c, *link))
+}
+
+// DeleteBookingLink godoc
+// @Summary      Delete booking link
+// @Description  Delete a booking link so its URL stops working. Booked events stay on the schedule.
+// @Tags         booking
+// @Produce      json
+// @Security     BearerAuth
+// @Param        linkID  path      string  true  "Booking link ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /booking-links/{linkID} [delete]
+func DeleteBookingLink(c *gin.Context) {
+	link, ok := ownBookingLink(c)
+	if !ok {
+		return
+	}
+
+	if err := config.GetDB().Delete(link).Error; err != nil {
+		config.Logger.Errorf("Error deleting booking link %s: %v", link.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete booking link"})
+		return
+	}
+
+	config.Logger.Infof("Deleted booking link %s", link.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Booking link deleted"})
+}
+
+// GetBookings godoc
+// @Summary      List bookings
+// @Description  List the bookings others made through the user's booking links, latest first
+// @Tags         booking
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string][]models.Booking
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /bookings [get]
+func GetBookings(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var bookings []models.Booking
+	if err := config.GetDB().Where("user_id = ?", userID).Order("start DESC").Find(&bookings).Error; err != nil {
+		config.Logger.Errorf("Error fetching bookings for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch bookings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bookings": bookings})
+}
+
+// GetPublicBookingLink godoc
+// @Summary      Get public booking link
+// @Description  Describe a booking link to the person booking. No authentication; the token in the URL grants access.
+// @Tags         booking
+// @Produce      json
+// @Param        token  path      string  true  "Booking link token"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]string
+// @Router       /book/{token} [get]
+func GetPublicBookingLink(c *gin.Context) {
+	link, owner, ok := publicBookingLink(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"title":            link.Title,
+		"description":      link.Description,
+		"duration_minutes": link.DurationMinutes,
+		"max_days_ahead":   link.MaxDaysAhead,
+		"owner_name":       owner.Name,
+		"timezone":         util.UserLocation(owner.Settings).String(),
+	})
+}
+
+// GetBookingSlots godoc
+// @Summary      List bookable times
+// @Description  List the start times that can be booked through a link between from and to (RFC 3339 or YYYY-MM-DD in the owner's timezone; defaults to the next 7 days)
+// @Tags         booking
+// @Produce      json
+// @Param        token  path      string  true   "Booking link token"
+// @Param        from   query     string  false  "Start of the range"
+// @Param        to     query     string  false  "End of the range"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /book/{token}/slots [get]
+func GetBookingSlots(c *gin.Context) {
+	link, owner, ok := publicBookingLink(c)
+	if !ok {
+		return
+	}
+	loc := util.UserLocation(owner.Settings)
+	now := time.Now()
+
+	from, to := now, now.AddDate(0, 0, 7)
+	var err error
+	if value := c.Query("from"); value != "" {
+		if from, err = parseScheduleBound(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC 3339 or YYYY-MM-DD"})
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = parseScheduleBound(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC 3339 or YYYY-MM-DD"})
+			return
+		}
+	}
+	if from.Before(now) {
+		from = now
+	}
+	if limit := now.AddDate(0, 0, link.MaxDaysAhead); to.After(limit) {
+		to = limit
+	}
+	if to.Sub(from) > maxBookingRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The range can span at most 31 days"})
+		return
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusOK, gin.H{"slots": []booking.Interval{}, "timezone": loc.String()})
+		return
+	}
+
+	slots, err := bookableSlots(config.GetDB(), link, loc, from, to, now)
+	if err != nil {
+		config.Logger.Errorf("Error listing slots for booking link %s: %v", link.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not list available times"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"slots": slots, "timezone": loc.String()})
+}
+
+// CreateBooking godoc
+// @Summary      Book time
+// @Description  Book a time listed by the slots endpoint. The booking is added to the owner's schedule and both sides are emailed.
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Param        token    path      string                true  "Booking link token"
+// @Param        booking  body      CreateBookingRequest  true  "Booking"
+// @Success      201  {object}  models.Booking
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /book/{token} [post]
+func CreateBooking(c *gin.Context) {
+	link, owner, ok := publicBookingLink(c)
+	if !ok {
+		return
+	}
+
+	var input CreateBookingRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	input.Email = strings.TrimSpace(input.Email)
+	if input.Name == "" || strings.ContainsAny(input.Name, "\r\n") || len(input.Name) > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Please give a name of at most 200 characters"})
+		return
+	}
+	if !util.ValidateEmailFormat(input.Email) || strings.ContainsAny(input.Email, "\r\n") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Please give a valid email address"})
+		return
+	}
+	loc := util.UserLocation(owner.Settings)
+	guestLoc := loc
+	if input.Timezone != "" {
+		var err error
+		if guestLoc, err = util.LoadTimezone(input.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone", "details": err.Error()})
+			return
+		}
+	}
+
+	start := input.Start.UTC()
+	end := start.Add(time.Duration(link.DurationMinutes) * time.Minute)
+
+	var created models.Booking
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Bookings of one owner, through any of their links, are made one at a time so two
+		// people cannot take the same free time
+		if err := lockBookingOwner(tx, link.UserID); err != nil {
+			return err
+		}
+		if err := checkBookable(tx, link, loc, start, time.Now()); err != nil {
+			return err
+		}
+
+		event := models.ScheduledTask{
+			Title:  fmt.Sprintf("%s with %s", link.Title, input.Name),
+			Start:  start,
+			End:    end,
+			UserID: link.UserID,
+			Pinned: true,
+		}
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+
+		created = models.Booking{
+			BookingLinkID:   link.ID,
+			UserID:          link.UserID,
+			ScheduledTaskID: &event.ID,
+			Name:            input.Name,
+			Email:           input.Email,
+			Notes:           input.Notes,
+			Start:           start,
+			End:             end,
+		}
+		return tx.Create(&created).Error
+	})
+	if errors.Is(err, errSlotTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		config.Logger.Errorf("Error creating booking through link %s: %v", link.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create booking"})
+		return
+	}
+
+	config.Logger.Infof("Created booking %s through link %s", created.ID, link.ID)
+
+	go func() {
+		emailService := util.NewEmailService()
+		if err := emailService.SendBookingConfirmationEmail(created.Email, created.Name, owner.Name, link.Title,
+			created.Start.In(guestLoc), created.End.In(guestLoc)); err != nil {
+			config.Logger.Errorf("Failed to send booking confirmation for booking %s: %v", created.ID, err)
+		}
+		if err := emailService.SendNewBookingEmail(owner.Email, created.Name, created.Email, link.Title, created.Notes,
+			created.Start.In(loc), created.End.In(loc)); err != nil {
+			config.Logger.Errorf("Failed to send booking notice for booking %s: %v", created.ID, err)
+		}
+	}()
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// ownBookingLink loads the authenticated user's booking link named in the request,
+// writing the error response when there is none
+func ownBookingLink(c *gin.Context) (*models.BookingLink, bool) {
+	linkIDStr := c.Param("linkID")
+	linkID, err := uuid.Parse(linkIDStr)
+	if err != nil {
+		config.Logger.Warnf("Invalid booking link ID param: %s", linkIDStr)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking link ID"})
+		return nil, false
+	}
+
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	var link models.BookingLink
+	if err := config.GetDB().Where("id = ? AND user_id = ?", linkID, userID).First(&link).Error; err != nil {
+		config.Logger.Warnf("Booking link %s not found for user %v", linkID, userID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking link not found"})
+		return nil, false
+	}
+	return &link, true
+}
+
+// publicBookingLink loads the active booking link for the token in the URL and its
+// owner, writing a 404 when there is none
+func publicBookingLink(c *gin.Context) (*models.BookingLink, *models.User, bool) {
+	var link models.BookingLink
+	if err := config.GetDB().Preload("User").Where("token = ? AND is_active = ?", c.Param("token"), true).First(&link).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking link not found"})
+		return nil, nil, false
+	}
+	return &link, &link.User, true
+}
+
+// bookingAvailability loads what decides which times a link offers in [from, to):
+// the owner's bookable zones, busy time and bookings per day
+func bookingAvailability(db *gorm.DB, link *models.BookingLink, loc *time.Location, from, to time.Time) ([]models.CalendarZone, []booking.Interval, map[string]int, error) {
+	allZones, err := models.LoadCalendarZones(db, link.UserID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	zones := allZones[:0]
+	for i := range allZones {
+		if link.IncludesZone(&allZones[i]) {
+			zones = append(zones, allZones[i])
+		}
+	}
+
+	// Busy time is read a day either side so buffers and long events at the edges count
+	busyFrom, busyTo := from.AddDate(0, 0, -1), to.AddDate(0, 0, 1)
+	var schedule []models.ScheduledTask
+	if err := db.Preload("RecurrenceRule").
+		Where("user_id = ?", link.UserID).
+		Where(`recurrence_rule_id IS NOT NULL OR recurring_event_id IS NOT NULL OR ("start" < ? AND "end" > ?)`, busyTo, busyFrom).
+		Find(&schedule).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	busy := booking.Busy(schedule, busyFrom, busyTo, loc)
+
+	// Bookings whose event the owner deleted no longer count towards the daily limit
+	var bookings []models.Booking
+	if err := db.Where("booking_link_id = ? AND scheduled_task_id IS NOT NULL AND start >= ? AND start < ?", link.ID, busyFrom, busyTo).
+		Find(&bookings).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	booked := make(map[string]int)
+	for _, b := range bookings {
+		booked[booking.DayKey(b.Start, loc)]++
+	}
+	return zones, busy, booked, nil
+}
+
+func bookableSlots(db *gorm.DB, link *models.BookingLink, loc *time.Location, from, to, now time.Time) ([]booking.Interval, error) {
+	zones, busy, booked, err := bookingAvailability(db, link, loc, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return booking.Slots(zones, busy, booked, from, to, loc, booking.RulesFor(link, now)), nil
+}
+
+// lockBookingOwner holds, until the transaction ends, a lock on booking the owner's time
+func lockBookingOwner(tx *gorm.DB, ownerID uuid.UUID) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", "booking:"+ownerID.String()).Error
+}
+
+// checkBookable returns errSlotTaken unless a booking through link may start at start
+func checkBookable(db *gorm.DB, link *models.BookingLink, loc *time.Location, start, now time.Time) error {
+	if start.After(now.AddDate(0, 0, link.MaxDaysAhead)) {
+		return errSlotTaken
+	}
+	zones, busy, booked, err := bookingAvailability(db, link, loc, start, start.Add(time.Duration(link.DurationMinutes)*time.Minute))
+	if err != nil {
+		return err
+	}
+	if !booking.IsBookable(zones, busy, booked, start, loc, booking.RulesFor(link, now)) {
+		return errSlotTaken
+	}
+	return nil
+}
@@ -0,0 +1,926 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/caldav"
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/ical"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	caldavRoot          = "/caldav"
+	caldavPrincipalPath = caldavRoot + "/principal/"
+	caldavHomePath      = caldavRoot + "/calendars/"
+	// CalDAVRealm is the HTTP Basic realm CalDAV clients authenticate against
+	CalDAVRealm         = "The Hub CalDAV"
+	maxCalDAVObjectSize = 1 << 20 // 1MB
+)
+
+// CalDAVMethods are the HTTP methods served under /caldav
+var CalDAVMethods = []string{"OPTIONS", "PROPFIND", "REPORT", "GET", "HEAD", "PUT", "DELETE"}
+
+// caldavCollection is one calendar exposed over CalDAV
+type caldavCollection struct {
+	Name        string
+	DisplayName string
+	Component   string
+}
+
+var caldavCollections = []caldavCollection{
+	{Name: "schedule", DisplayName: "The Hub Schedule", Component: "VEVENT"},
+	{Name: "tasks", DisplayName: "The Hub Tasks", Component: "VTODO"},
+}
+
+func (coll caldavCollection) href() string {
+	return caldavHomePath + coll.Name + "/"
+}
+
+func findCalDAVCollection(name string) *caldavCollection {
+	for i := range caldavCollections {
+		if caldavCollections[i].Name == name {
+			return &caldavCollections[i]
+		}
+	}
+	return nil
+}
+
+// caldavObject is a scheduled task or task rendered as a calendar object resource
+type caldavObject struct {
+	ID           uuid.UUID
+	Name         string
+	UID          string
+	Data         []byte
+	ETag         string
+	LastModified time.Time
+	// Start and End are used for time-range queries; nil means unbounded
+	Start *time.Time
+	End   *time.Time
+}
+
+func (obj caldavObject) href(coll caldavCollection) string {
+	return coll.href() + obj.Name
+}
+
+func (obj caldavObject) props(coll caldavCollection) []caldav.Prop {
+	return []caldav.Prop{
+		caldav.ElementsProp(caldav.Name(caldav.NSDAV, "resourcetype")),
+		caldav.TextProp(caldav.Name(caldav.NSDAV, "getetag"), obj.ETag),
+		caldav.TextProp(caldav.Name(caldav.NSDAV, "getcontenttype"),
+			fmt.Sprintf("text/calendar; charset=utf-8; component=%s", strings.ToLower(coll.Component))),
+		caldav.TextProp(caldav.Name(caldav.NSDAV, "getcontentlength"), fmt.Sprintf("%d", len(obj.Data))),
+		caldav.TextProp(caldav.Name(caldav.NSDAV, "getlastmodified"), obj.LastModified.UTC().Format(http.TimeFormat)),
+	}
+}
+
+// overlaps reports whether the object falls in a query's time range
+func (obj caldavObject) overlaps(start, end *time.Time) bool {
+	if start != nil && obj.End != nil && !obj.End.After(*start) {
+		return false
+	}
+	if end != nil && obj.Start != nil && !obj.Start.Before(*end) {
+		return false
+	}
+	return true
+}
+
+// CalDAVWellKnown redirects service discovery (RFC 6764) to the CalDAV root
+func CalDAVWellKnown(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, caldavRoot+"/")
+}
+
+// CalDAV serves the CalDAV endpoint. Users authenticate with an app password and see
+// two calendars: their schedule as events and their tasks as to-dos.
+func CalDAV(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	var parts []string
+	if path := strings.Trim(c.Param("path"), "/"); path != "" {
+		parts = strings.Split(path, "/")
+	}
+
+	switch c.Request.Method {
+	case "OPTIONS":
+		c.Header("DAV", "1, 3, calendar-access")
+		c.Header("Allow", strings.Join(CalDAVMethods, ", "))
+		c.Status(http.StatusOK)
+	case "PROPFIND":
+		caldavPropfind(c, userIDUUID, parts)
+	case "REPORT":
+		caldavReport(c, userIDUUID, parts)
+	case http.MethodGet, http.MethodHead:
+		caldavGet(c, userIDUUID, parts)
+	case http.MethodPut:
+		caldavPut(c, userIDUUID, parts)
+	case http.MethodDelete:
+		caldavDelete(c, userIDUUID, parts)
+	default:
+		c.Header("Allow", strings.Join(CalDAVMethods, ", "))
+		c.Status(http.StatusMethodNotAllowed)
+	}
+}
+
+// caldavObjectPath splits calendars/<collection>/<name> into its collection and resource name
+func caldavObjectPath(parts []string) (*caldavCollection, string) {
+	if len(parts) != 3 || parts[0] != "calendars" {
+		return nil, ""
+	}
+	coll := findCalDAVCollection(parts[1])
+	if coll == nil {
+		return nil, ""
+	}
+	return coll, parts[2]
+}
+
+func caldavPropfind(c *gin.Context, userID uuid.UUID, parts []string) {
+	req, err := caldav.ParsePropfind(io.LimitReader(c.Request.Body, maxCalDAVObjectSize))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	depth := c.GetHeader("Depth")
+	db := config.GetDB()
+
+	var responses []caldav.Response
+	add := func(href string, props []caldav.Prop) {
+		found, notFound := caldav.Select(props, req)
+		responses = append(responses, caldav.Response{Href: href, Found: found, NotFound: notFound})
+	}
+
+	switch {
+	case len(parts) == 0:
+		add(caldavRoot+"/", caldavRootProps())
+	case len(parts) == 1 && parts[0] == "principal":
+		var user models.User
+		if err := db.Select("id", "name", "email").Where("id = ?", userID).First(&user).Error; err != nil {
+			config.Logger.Errorf("Error loading CalDAV principal %s: %v", userID, err)
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		add(caldavPrincipalPath, caldavPrincipalProps(user))
+	case len(parts) == 1 && parts[0] == "calendars":
+		add(caldavHomePath, caldavHomeProps())
+		if depth != "0" {
+			for _, coll := range caldavCollections {
+				objects, err := loadCalDAVObjects(db, userID, coll)
+				if err != nil {
+					config.Logger.Errorf("Error loading CalDAV %s for user %s: %v", coll.Name, userID, err)
+					c.Status(http.StatusInternalServerError)
+					return
+				}
+				add(coll.href(), caldavCollectionProps(coll, objects))
+			}
+		}
+	case len(parts) == 2 && parts[0] == "calendars" && findCalDAVCollection(parts[1]) != nil:
+		coll := *findCalDAVCollection(parts[1])
+		objects, err := loadCalDAVObjects(db, userID, coll)
+		if err != nil {
+			config.Logger.Errorf("Error loading CalDAV %s for user %s: %v", coll.Name, userID, err)
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		add(coll.href(), caldavCollectionProps(coll, objects))
+		if depth != "0" {
+			for _, obj := range objects {
+				add(obj.href(coll), obj.props(coll))
+			}
+		}
+	default:
+		coll, name := caldavObjectPath(parts)
+		if coll == nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		obj, err := loadCalDAVObject(db, userID, *coll, name)
+		if err != nil {
+			config.Logger.Errorf("Error loading CalDAV object %s for user %s: %v", name, userID, err)
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		if obj == nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		add(obj.href(*coll), obj.props(*coll))
+	}
+
+	writeMultistatus(c, responses)
+}
+
+func caldavReport(c *gin.Context, userID uuid.UUID, parts []string) {
+	if len(parts) != 2 || parts[0] != "calendars" || findCalDAVCollection(parts[1]) == nil {
+		c.String(http.StatusForbidden, "Reports are only supported on calendar collections")
+		return
+	}
+	coll := *findCalDAVCollection(parts[1])
+
+	report, err := caldav.ParseReport(io.LimitReader(c.Request.Body, maxCalDAVObjectSize))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if report.Kind != caldav.ReportCalendarQuery && report.Kind != caldav.ReportCalendarMultiget {
+		c.String(http.StatusForbidden, "Unsupported report: "+report.Kind)
+		return
+	}
+
+	objects, err := loadCalDAVObjects(config.GetDB(), userID, coll)
+	if err != nil {
+		config.Logger.Errorf("Error loading CalDAV %s for user %s: %v", coll.Name, userID, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	props := &caldav.PropfindRequest{PropNames: report.PropNames, AllProp: len(report.PropNames) == 0}
+	respond := func(obj caldavObject) caldav.Response {
+		available := append(obj.props(coll),
+			caldav.TextProp(caldav.Name(caldav.NSCalDAV, "calendar-data"), string(obj.Data)))
+		found, notFound := caldav.Select(available, props)
+		return caldav.Response{Href: obj.href(coll), Found: found, NotFound: notFound}
+	}
+
+	var responses []caldav.Response
+	if report.Kind == caldav.ReportCalendarMultiget {
+		byHref := make(map[string]caldavObject, len(objects))
+		for _, obj := range objects {
+			byHref[obj.href(coll)] = obj
+		}
+		for _, href := range report.Hrefs {
+			if obj, ok := byHref[caldavHrefPath(href)]; ok {
+				responses = append(responses, respond(obj))
+			} else {
+				responses = append(responses, caldav.Response{Href: href, Status: http.StatusNotFound})
+			}
+		}
+	} else if report.Component == "" || report.Component == coll.Component {
+		for _, obj := range objects {
+			if obj.overlaps(report.Start, report.End) {
+				responses = append(responses, respond(obj))
+			}
+		}
+	}
+
+	writeMultistatus(c, responses)
+}
+
+// caldavHrefPath reduces an href, which clients may send as a full URL, to its path
+func caldavHrefPath(href string) string {
+	if idx := strings.Index(href, caldavRoot+"/"); idx > 0 {
+		return href[idx:]
+	}
+	return href
+}
+
+func caldavGet(c *gin.Context, userID uuid.UUID, parts []string) {
+	coll, name := caldavObjectPath(parts)
+	if coll == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	obj, err := loadCalDAVObject(config.GetDB(), userID, *coll, name)
+	if err != nil {
+		config.Logger.Errorf("Error loading CalDAV object %s for user %s: %v", name, userID, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if obj == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	c.Header("ETag", obj.ETag)
+	c.Header("Last-Modified", obj.LastModified.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", obj.Data)
+}
+
+func caldavPut(c *gin.Context, userID uuid.UUID, parts []string) {
+	coll, name := caldavObjectPath(parts)
+	if coll == nil || name == "" {
+		c.String(http.StatusForbidden, "Objects can only be stored in a calendar collection")
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCalDAVObjectSize+1))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxCalDAVObjectSize {
+		c.Status(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	cal, err := ical.Parse(bytes.NewReader(data))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid iCalendar data: "+err.Error())
+		return
+	}
+
+	db := config.GetDB()
+	existing, err := loadCalDAVObject(db, userID, *coll, name)
+	if err != nil {
+		config.Logger.Errorf("Error loading CalDAV object %s for user %s: %v", name, userID, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if !caldavPreconditionsMet(c, existing) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	var objectID *uuid.UUID
+	if existing != nil {
+		objectID = &existing.ID
+	}
+
+	var savedID uuid.UUID
+	var uid string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		switch coll.Component {
+		case "VEVENT":
+			savedID, uid, err = saveCalDAVEvent(tx, userID, objectID, cal)
+		case "VTODO":
+			savedID, uid, err = saveCalDAVTodo(tx, userID, objectID, cal)
+		}
+		if err != nil {
+			return err
+		}
+		return saveCalDAVResource(tx, userID, *coll, name, savedID, uid)
+	})
+	var invalid caldavInvalidError
+	if errors.As(err, &invalid) {
+		c.String(http.StatusBadRequest, invalid.Error())
+		return
+	}
+	if err != nil {
+		config.Logger.Errorf("Error saving CalDAV object %s for user %s: %v", name, userID, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	// No ETag is returned: the stored representation is re-serialised, so clients must refetch it
+	if existing == nil {
+		config.Logger.Infof("CalDAV client created %s %s for user %s", coll.Name, savedID, userID)
+		c.Status(http.StatusCreated)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func caldavDelete(c *gin.Context, userID uuid.UUID, parts []string) {
+	coll, name := caldavObjectPath(parts)
+	if coll == nil {
+		c.String(http.StatusForbidden, "Only calendar objects can be deleted")
+		return
+	}
+
+	db := config.GetDB()
+	existing, err := loadCalDAVObject(db, userID, *coll, name)
+	if err != nil {
+		config.Logger.Errorf("Error loading CalDAV object %s for user %s: %v", name, userID, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if !caldavPreconditionsMet(c, existing) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		switch coll.Component {
+		case "VEVENT":
+			var schedule models.ScheduledTask
+			if err := tx.Where("id = ? AND user_id = ?", existing.ID, userID).First(&schedule).Error; err != nil {
+				return err
+			}
+			if schedule.TaskID != nil {
+				if err := tx.Model(&models.Task{}).Where("id = ?", *schedule.TaskID).Update("due_date", nil).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Delete(&schedule).Error; err != nil {
+				return err
+			}
+		case "VTODO":
+			if err := tx.Where("id = ? AND user_id = ?", existing.ID, userID).Delete(&models.Task{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("user_id = ? AND object_id = ?", userID, existing.ID).Delete(&models.CalDAVResource{}).Error
+	})
+	if err != nil {
+		config.Logger.Errorf("Error deleting CalDAV object %s for user %s: %v", name, userID, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// caldavPreconditionsMet evaluates If-Match and If-None-Match against the current object
+func caldavPreconditionsMet(c *gin.Context, existing *caldavObject) bool {
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		if existing == nil || (ifMatch != "*" && ifMatch != existing.ETag) {
+			return false
+		}
+	}
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" && existing != nil {
+		if ifNoneMatch == "*" || ifNoneMatch == existing.ETag {
+			return false
+		}
+	}
+	return true
+}
+
+func writeMultistatus(c *gin.Context, responses []caldav.Response) {
+	var buf bytes.Buffer
+	if err := caldav.WriteMultistatus(&buf, responses); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", buf.Bytes())
+}
+
+func caldavPrivileges() caldav.Prop {
+	var inner strings.Builder
+	for _, privilege := range []string{"read", "write", "write-content", "bind", "unbind"} {
+		inner.WriteString("<d:privilege><d:" + privilege + "/></d:privilege>")
+	}
+	return caldav.Prop{Name: caldav.Name(caldav.NSDAV, "current-user-privilege-set"), Inner: inner.String()}
+}
+
+func caldavRootProps() []caldav.Prop {
+	return []caldav.Prop{
+		caldav.ElementsProp(caldav.Name(caldav.NSDAV, "resourcetype"), caldav.Name(caldav.NSDAV, "collection")),
+		caldav.HrefProp(caldav.Name(caldav.NSDAV, "current-user-principal"), caldavPrincipalPath),
+		caldav.HrefProp(caldav.Name(caldav.NSCalDAV, "calendar-home-set"), caldavHomePath),
+	}
+}
+
+func caldavPrincipalProps(user models.User) []caldav.Prop {
+	return []caldav.Prop{
+		caldav.ElementsProp(caldav.Name(caldav.NSDAV, "resourcetype"), caldav.Name(caldav.NSDAV, "principal")),
+		caldav.TextProp(caldav.Name(caldav.NSDAV, "displayname"), user.Name),
+		caldav.HrefProp(caldav.Name(caldav.NSDAV, "current-user-principal"), caldavPrincipalPath),
+		caldav.HrefProp(caldav.Name(caldav.NSDAV, "principal-URL"), caldavPrincipalPath),
+		caldav.HrefProp(caldav.Name(caldav.NSCalDAV, "calendar-home-set"), caldavHomePath),
+		caldav.HrefProp(caldav.Name(caldav.NSCalDAV, "calendar-user-address-set"), "mailto:"+user.Email),
+	}
+}
+
+func caldavHomeProps() []caldav.Prop {
+	return []caldav.Prop{
+		caldav.ElementsProp(caldav.Name(caldav.NSDAV, "resourcetype"), caldav.Name(caldav.NSDAV, "collection")),
+		caldav.TextProp(caldav.Name(caldav.NSDAV, "displayname"), "The Hub"),
+		caldav.HrefProp(caldav.Name(caldav.NSDAV, "current-user-principal"), caldavPrincipalPath),
+		caldavPrivileges(),
+	}
+}
+
+func caldavCollectionProps(coll caldavCollection, objects []caldavObject) []caldav.Prop {
+	// The ctag changes whenever any object in the collection changes
+	h := sha256.New()
+	for _, obj := range objects {
+		h.Write([]byte(obj.Name + obj.ETag))
+	}
+	ctag := hex.EncodeToString(h.Sum(nil))[:32]
+
+	reports := ""
+	for _, report := range []string{caldav.ReportCalendarQuery, caldav.ReportCalendarMultiget} {
+		reports += "<d:supported-report><d:report><c:" + report + "/></d:report></d:supported-report>"
+	}
+
+	return []caldav.Prop{
+		caldav.ElementsProp(caldav.Name(caldav.NSDAV, "resourcetype"),
+			caldav.Name(caldav.NSDAV, "collection"), caldav.Name(caldav.NSCalDAV, "calendar")),
+		caldav.TextProp(caldav.Name(caldav.NSDAV, "displayname"), coll.DisplayName),
+		caldav.HrefProp(caldav.Name(caldav.NSDAV, "current-user-principal"), caldavPrincipalPath),
+		caldavPrivileges(),
+		{Name: caldav.Name(caldav.NSCalDAV, "supported-calendar-component-set"), Inner: `<c:comp name="` + coll.Component + `"/>`},
+		{Name: caldav.Name(caldav.NSDAV, "supported-report-set"), Inner: reports},
+		caldav.TextProp(caldav.Name(caldav.NSCalendarServer, "getctag"), ctag),
+	}
+}
+
+// loadCalDAVObjects renders every object in a collection, ordered by name
+func loadCalDAVObjects(db *gorm.DB, userID uuid.UUID, coll caldavCollection) ([]caldavObject, error) {
+	resources, err := loadCalDAVResources(db, userID, coll, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []caldavObject
+	switch coll.Component {
+	case "VEVENT":
+		var schedule []models.ScheduledTask
+		if err := db.Preload("Task").Preload("RecurrenceRule").Where("user_id = ?", userID).Find(&schedule).Error; err != nil {
+			return nil, err
+		}
+		// Changed occurrences are part of their series' calendar object
+		items, overrides := groupOverrides(schedule)
+		for _, item := range items {
+			objects = append(objects, renderCalDAVEvent(item, overrides[item.ID], resources[item.ID]))
+		}
+	case "VTODO":
+		var tasks []models.Task
+		if err := db.Where("user_id = ?", userID).Find(&tasks).Error; err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			objects = append(objects, renderCalDAVTodo(task, resources[task.ID]))
+		}
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+// loadCalDAVObject finds an object by resource name; it returns nil when there is none
+func loadCalDAVObject(db *gorm.DB, userID uuid.UUID, coll caldavCollection, name string) (*caldavObject, error) {
+	var resource models.CalDAVResource
+	var objectID uuid.UUID
+	err := db.Where("user_id = ? AND collection = ? AND name = ?", userID, coll.Name, name).First(&resource).Error
+	switch {
+	case err == nil:
+		objectID = resource.ObjectID
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		id, parseErr := uuid.Parse(strings.TrimSuffix(name, ".ics"))
+		if parseErr != nil {
+			return nil, nil
+		}
+		objectID = id
+	default:
+		return nil, err
+	}
+
+	resources, err := loadCalDAVResources(db, userID, coll, &objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj caldavObject
+	switch coll.Component {
+	case "VEVENT":
+		var item models.ScheduledTask
+		err = db.Preload("Task").Preload("RecurrenceRule").
+			Where("id = ? AND user_id = ? AND recurring_event_id IS NULL", objectID, userID).First(&item).Error
+		if err == nil {
+			var overrides []models.ScheduledTask
+			err = db.Where("recurring_event_id = ?", item.ID).Find(&overrides).Error
+			obj = renderCalDAVEvent(item, overrides, resources[objectID])
+		}
+	case "VTODO":
+		var task models.Task
+		err = db.Where("id = ? AND user_id = ?", objectID, userID).First(&task).Error
+		if err == nil {
+			obj = renderCalDAVTodo(task, resources[objectID])
+		}
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// An object created under a client-chosen name is only reachable by that name
+	if obj.Name != name {
+		return nil, nil
+	}
+	return &obj, nil
+}
+
+func loadCalDAVResources(db *gorm.DB, userID uuid.UUID, coll caldavCollection, objectID *uuid.UUID) (map[uuid.UUID]*models.CalDAVResource, error) {
+	query := db.Where("user_id = ? AND collection = ?", userID, coll.Name)
+	if objectID != nil {
+		query = query.Where("object_id = ?", *objectID)
+	}
+	var rows []models.CalDAVResource
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	resources := make(map[uuid.UUID]*models.CalDAVResource, len(rows))
+	for i := range rows {
+		resources[rows[i].ObjectID] = &rows[i]
+	}
+	return resources, nil
+}
+
+func newCalDAVObject(id uuid.UUID, defaultUID string, resource *models.CalDAVResource, lastModified time.Time) caldavObject {
+	obj := caldavObject{ID: id, Name: id.String() + ".ics", UID: defaultUID, LastModified: lastModified}
+	if resource != nil {
+		obj.Name = resource.Name
+		if resource.UID != "" {
+			obj.UID = resource.UID
+		}
+	}
+	return obj
+}
+
+func (obj *caldavObject) setData(cal *ical.Calendar) {
+	data, _ := cal.Bytes()
+	sum := sha256.Sum256(data)
+	obj.Data = data
+	obj.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func renderCalDAVEvent(item models.ScheduledTask, overrides []models.ScheduledTask, resource *models.CalDAVResource) caldavObject {
+	obj := newCalDAVObject(item.ID, fmt.Sprintf("schedule-%s@the-hub", item.ID), resource, item.UpdatedAt)
+	events := scheduleEvents(item, overrides, obj.UID)
+	for _, event := range events {
+		if event.LastModified.After(obj.LastModified) {
+			obj.LastModified = event.LastModified
+		}
+	}
+
+	start, end := item.Start, item.End
+	obj.Start = &start
+	if item.RecurrenceRule != nil {
+		if item.RecurrenceRule.UpdatedAt.After(obj.LastModified) {
+			obj.LastModified = item.RecurrenceRule.UpdatedAt
+		}
+		// Recurring events may have instances in any later range
+		if events[0].RRule == "" {
+			obj.End = &end
+		}
+	} else {
+		obj.End = &end
+	}
+
+	obj.setData(&ical.Calendar{Stamp: obj.LastModified, Events: events})
+	return obj
+}
+
+func renderCalDAVTodo(task models.Task, resource *models.CalDAVResource) caldavObject {
+	obj := newCalDAVObject(task.ID, fmt.Sprintf("task-%s@the-hub", task.ID), resource, task.UpdatedAt)
+
+	todo := ical.Todo{
+		UID:          obj.UID,
+		Summary:      task.Title,
+		Description:  task.Description,
+		Status:       icalTodoStatus(task.Status),
+		Completed:    task.CompletedAt,
+		LastModified: task.UpdatedAt,
+	}
+	if task.DueDate != nil {
+		todo.Due = *task.DueDate
+		obj.Start, obj.End = task.DueDate, task.DueDate
+	}
+	if task.Priority != nil {
+		todo.Priority = 11 - 2*(*task.Priority)
+	}
+
+	obj.setData(&ical.Calendar{Stamp: obj.LastModified, Todos: []ical.Todo{todo}})
+	return obj
+}
+
+// caldavInvalidError is returned for well-formed iCalendar the hub cannot store
+type caldavInvalidError struct {
+	msg string
+}
+
+func (e caldavInvalidError) Error() string {
+	return e.msg
+}
+
+// saveCalDAVEvent creates or updates a scheduled task from the VEVENT in cal
+func saveCalDAVEvent(tx *gorm.DB, userID uuid.UUID, objectID *uuid.UUID, cal *ical.Component) (uuid.UUID, string, error) {
+	events, err := ical.ParseEvents(cal, time.UTC)
+	if err != nil {
+		return uuid.Nil, "", caldavInvalidError{err.Error()}
+	}
+	var event *ical.EventSpec
+	for i := range events {
+		// Overrides of single instances are stored after the master event
+		if events[i].RecurrenceID == nil {
+			event = &events[i]
+			break
+		}
+	}
+	if event == nil {
+		return uuid.Nil, "", caldavInvalidError{"calendar object must contain a VEVENT"}
+	}
+	if !event.End.After(event.Start) {
+		return uuid.Nil, "", caldavInvalidError{"event must end after it starts"}
+	}
+
+	var schedule models.ScheduledTask
+	if objectID != nil {
+		if err := tx.Preload("RecurrenceRule").Where("id = ? AND user_id = ?", *objectID, userID).First(&schedule).Error; err != nil {
+			return uuid.Nil, "", err
+		}
+	} else if err := checkCalDAVUID(tx, userID, "schedule", event.UID); err != nil {
+		return uuid.Nil, "", err
+	}
+
+	schedule.UserID = userID
+	schedule.Title = event.Summary
+	if schedule.Title == "" {
+		schedule.Title = "Untitled event"
+	}
+	schedule.Start = event.Start.UTC()
+	schedule.End = event.End.UTC()
+
+	if event.RRule == nil {
+		schedule.RecurrenceRuleID = nil
+	} else {
+		rule, err := recurrenceRuleFromRRule(userID, event.RRule, event.Start)
+		if err != nil {
+			return uuid.Nil, "", err
+		}
+		// Rules can be shared, so an edited rule is stored as a new one
+		if schedule.RecurrenceRule == nil || schedule.RecurrenceRule.RRule() != rule.RRule() {
+			if err := tx.Create(&rule).Error; err != nil {
+				return uuid.Nil, "", err
+			}
+			schedule.RecurrenceRuleID = &rule.ID
+		}
+	}
+	schedule.RecurrenceRule = nil
+
+	if err := tx.Save(&schedule).Error; err != nil {
+		return uuid.Nil, "", err
+	}
+
+	var exdates []time.Time
+	if event.RRule != nil {
+		exdates = event.ExDates
+	} else {
+		events = nil
+	}
+	if err := saveOverrides(tx, &schedule, events, exdates); err != nil {
+		return uuid.Nil, "", err
+	}
+	return schedule.ID, event.UID, nil
+}
+
+// saveCalDAVTodo creates or updates a task from the VTODO in cal
+func saveCalDAVTodo(tx *gorm.DB, userID uuid.UUID, objectID *uuid.UUID, cal *ical.Component) (uuid.UUID, string, error) {
+	todos, err := ical.ParseTodos(cal, time.UTC)
+	if err != nil {
+		return uuid.Nil, "", caldavInvalidError{err.Error()}
+	}
+	if len(todos) == 0 {
+		return uuid.Nil, "", caldavInvalidError{"calendar object must contain a VTODO"}
+	}
+	todo := todos[0]
+
+	var task models.Task
+	if objectID != nil {
+		if err := tx.Where("id = ? AND user_id = ?", *objectID, userID).First(&task).Error; err != nil {
+			return uuid.Nil, "", err
+		}
+	} else {
+		if err := checkCalDAVUID(tx, userID, "tasks", todo.UID); err != nil {
+			return uuid.Nil, "", err
+		}
+		task.Status = "pending"
+	}
+
+	task.UserID = userID
+	task.Title = todo.Summary
+	if task.Title == "" {
+		task.Title = "Untitled task"
+	}
+	task.Description = todo.Description
+	task.DueDate = nil
+	if todo.Due != nil {
+		due := todo.Due.UTC()
+		task.DueDate = &due
+	}
+
+	task.Priority = nil
+	if todo.Priority > 0 {
+		// iCalendar 1 (highest) - 9 (lowest) maps onto task priority 5 - 1
+		priority := (11 - todo.Priority) / 2
+		task.Priority = &priority
+	}
+
+	wasCompleted := task.Status == "completed" || task.Status == "complete"
+	switch todo.Status {
+	case "COMPLETED":
+		task.Status = "completed"
+		if todo.Completed != nil {
+			completed := todo.Completed.UTC()
+			task.CompletedAt = &completed
+		} else if !wasCompleted {
+			now := time.Now()
+			task.CompletedAt = &now
+		}
+	case "IN-PROCESS":
+		task.Status = "in_progress"
+		task.CompletedAt = nil
+	default:
+		task.Status = "pending"
+		task.CompletedAt = nil
+	}
+
+	if err := tx.Save(&task).Error; err != nil {
+		return uuid.Nil, "", err
+	}
+	return task.ID, todo.UID, nil
+}
+
+// checkCalDAVUID rejects a new object whose UID is already used in the collection
+func checkCalDAVUID(tx *gorm.DB, userID uuid.UUID, collection, uid string) error {
+	if uid == "" {
+		return caldavInvalidError{"calendar object must have a UID"}
+	}
+	var count int64
+	if err := tx.Model(&models.CalDAVResource{}).
+		Where("user_id = ? AND collection = ? AND uid = ?", userID, collection, uid).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 || strings.HasSuffix(uid, "@the-hub") {
+		return caldavInvalidError{"a calendar object with this UID already exists"}
+	}
+	return nil
+}
+
+// saveCalDAVResource remembers the client's name and UID for an object when they
+// differ from the ones the hub would generate
+func saveCalDAVResource(tx *gorm.DB, userID uuid.UUID, coll caldavCollection, name string, objectID uuid.UUID, uid string) error {
+	defaultUID := fmt.Sprintf("schedule-%s@the-hub", objectID)
+	if coll.Name == "tasks" {
+		defaultUID = fmt.Sprintf("task-%s@the-hub", objectID)
+	}
+	if name == objectID.String()+".ics" && (uid == "" || uid == defaultUID) {
+		return nil
+	}
+
+	var resource models.CalDAVResource
+	err := tx.Where("user_id = ? AND collection = ? AND object_id = ?", userID, coll.Name, objectID).First(&resource).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	resource.UserID = userID
+	resource.Collection = coll.Name
+	resource.Name = name
+	resource.ObjectID = objectID
+	resource.UID = uid
+	return tx.Save(&resource).Error
+}
+
+// recurrenceRuleFromRRule converts a client's RRULE into a recurrence rule
+func recurrenceRuleFromRRule(userID uuid.UUID, rule *ical.RRule, start time.Time) (models.RecurrenceRule, error) {
+	rr := models.RecurrenceRule{
+		UserID:    userID,
+		Frequency: strings.ToLower(rule.Freq),
+		Interval:  rule.Interval,
+		StartDate: &start,
+		EndDate:   rule.Until,
+	}
+	switch rule.Freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return rr, caldavInvalidError{fmt.Sprintf("unsupported recurrence frequency %q", rule.Freq)}
+	}
+	if rule.Count > 0 {
+		count := rule.Count
+		rr.Count = &count
+	}
+
+	var days []string
+	for _, day := range rule.ByDay {
+		if day.N != 0 {
+			return rr, caldavInvalidError{"recurrence rules with numbered weekdays (e.g. 2TU) are not supported"}
+		}
+		days = append(days, ical.WeekdayCode(day.Day))
+	}
+	rr.ByDay = strings.Join(days, ",")
+
+	if len(rule.ByMonthDay) > 1 || len(rule.ByMonth) > 1 {
+		return rr, caldavInvalidError{"recurrence rules with several month days or months are not supported"}
+	}
+	if len(rule.ByMonthDay) == 1 {
+		rr.ByMonthDay = &rule.ByMonthDay[0]
+	}
+	if len(rule.ByMonth) == 1 {
+		rr.ByMonth = &rule.ByMonth[0]
+	}
+	return rr, nil
+}
@@ -1,64 +1,38 @@
 package handlers
 
 import (
-	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
 	"log"
 	"net/http"
-	"time"
 
+	"github.com/TheoMKgosi/The-hub/internal/calendarsync"
 	"github.com/TheoMKgosi/The-hub/internal/config"
 	"github.com/TheoMKgosi/The-hub/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/calendar/v3"
-	"google.golang.org/api/option"
 	"gorm.io/gorm"
 )
 
-// OAuth2 configuration for Google Calendar
-var googleOAuthConfig *oauth2.Config
-
-func init() {
-	// Initialize Google OAuth2 config
-	googleOAuthConfig = &oauth2.Config{
-		ClientID:     getEnvOrDefault("GOOGLE_CLIENT_ID", ""),
-		ClientSecret: getEnvOrDefault("GOOGLE_CLIENT_SECRET", ""),
-		RedirectURL:  getEnvOrDefault("GOOGLE_REDIRECT_URL", "http://localhost:8080/auth/google/callback"),
-		Scopes: []string{
-			calendar.CalendarScope,
-			calendar.CalendarEventsScope,
-		},
-		Endpoint: google.Endpoint,
-	}
-}
-
-func getEnvOrDefault(key, defaultValue string) string {
-	// Simple environment variable getter - in production use proper env handling
-	if key == "GOOGLE_CLIENT_ID" {
-		return "your-google-client-id" // Replace with actual env var
-	}
-	if key == "GOOGLE_CLIENT_SECRET" {
-		return "your-google-client-secret" // Replace with actual env var
-	}
-	if key == "GOOGLE_REDIRECT_URL" {
-		return "http://localhost:8080/auth/google/callback"
-	}
-	return defaultValue
-}
+// calendarProviders holds the calendar services users can connect
+var calendarProviders = calendarsync.DefaultRegistry
 
-// InitiateGoogleCalendarAuth starts the OAuth flow for Google Calendar
-func InitiateGoogleCalendarAuth(c *gin.Context) {
+// InitiateCalendarAuth starts the OAuth flow for the calendar provider in the URL (google, outlook)
+func InitiateCalendarAuth(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
+	provider, err := calendarProviders().Get(models.CalendarProvider(c.Param("provider")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported calendar provider"})
+		return
+	}
+
 	// Generate state parameter for CSRF protection
 	state, err := generateState()
 	if err != nil {
@@ -71,12 +45,12 @@ func InitiateGoogleCalendarAuth(c *gin.Context) {
 	// In production, store in Redis/session store
 	_ = stateKey
 
-	authURL := googleOAuthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline)
+	authURL := provider.OAuthConfig().AuthCodeURL(state, oauth2.AccessTypeOffline)
 	c.JSON(http.StatusOK, gin.H{"auth_url": authURL})
 }
 
-// HandleGoogleCalendarCallback handles the OAuth callback from Google
-func HandleGoogleCalendarCallback(c *gin.Context) {
+// HandleCalendarCallback handles the OAuth callback from the calendar provider
+func HandleCalendarCallback(c *gin.Context) {
 	code := c.Query("code")
 	_ = c.Query("state") // State verification would be implemented in production
 
@@ -92,52 +66,36 @@ func HandleGoogleCalendarCallback(c *gin.Context) {
 		return
 	}
 
-	// Exchange code for token
-	token, err := googleOAuthConfig.Exchange(context.Background(), code)
+	provider, err := calendarProviders().Get(models.CalendarProvider(c.Param("provider")))
 	if err != nil {
-		log.Printf("Failed to exchange code: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to exchange authorization code"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported calendar provider"})
 		return
 	}
 
-	// Get user profile from Google
-	client := googleOAuthConfig.Client(context.Background(), token)
-	calendarService, err := calendar.NewService(context.Background(), option.WithHTTPClient(client))
+	// Exchange code for token
+	token, err := provider.OAuthConfig().Exchange(c.Request.Context(), code)
 	if err != nil {
-		log.Printf("Failed to create calendar service: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar service"})
+		log.Printf("Failed to exchange code: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to exchange authorization code"})
 		return
 	}
 
-	// Get user's calendar list to find primary calendar
-	calendarList, err := calendarService.CalendarList.List().Do()
+	account, err := provider.Account(c.Request.Context(), token)
 	if err != nil {
-		log.Printf("Failed to get calendar list: %v", err)
+		log.Printf("Failed to get %s calendar account: %v", provider.Name(), err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendar list"})
 		return
 	}
 
-	var primaryCalendarID string
-	for _, cal := range calendarList.Items {
-		if cal.Primary != true {
-			primaryCalendarID = cal.Id
-			break
-		}
-	}
-
-	if primaryCalendarID == "" && len(calendarList.Items) > 0 {
-		primaryCalendarID = calendarList.Items[0].Id
-	}
-
 	// Save integration to database
 	integration := models.CalendarIntegration{
 		UserID:         userID.(uuid.UUID),
-		Provider:       models.ProviderGoogle,
-		ProviderUserID: "google_user_id", // Get from Google profile
+		Provider:       provider.Name(),
+		ProviderUserID: account.ProviderUserID,
 		AccessToken:    token.AccessToken,
 		RefreshToken:   token.RefreshToken,
 		TokenExpiry:    token.Expiry,
-		CalendarID:     primaryCalendarID,
+		CalendarID:     account.CalendarID,
 		IsActive:       true,
 		SyncEnabled:    true,
 	}
@@ -149,7 +107,7 @@ func HandleGoogleCalendarCallback(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":        "Google Calendar integration successful",
+		"message":        "Calendar integration successful",
 		"integration_id": integration.ID,
 	})
 }
@@ -172,7 +130,8 @@ func GetCalendarIntegrations(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"integrations": integrations})
 }
 
-// SyncCalendarEvents syncs events between local schedule and external calendar
+// SyncCalendarEvents syncs events between local schedule and external calendar.
+// Recurring series stay local; see calendarsync.Syncer.
 func SyncCalendarEvents(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -197,45 +156,60 @@ func SyncCalendarEvents(c *gin.Context) {
 		return
 	}
 
-	// Refresh token if needed
-	if time.Now().After(integration.TokenExpiry) {
-		if err := refreshGoogleToken(&integration); err != nil {
-			log.Printf("Failed to refresh token: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh access token"})
-			return
-		}
-	}
-
-	// Create OAuth2 token
-	token := &oauth2.Token{
-		AccessToken:  integration.AccessToken,
-		RefreshToken: integration.RefreshToken,
-		Expiry:       integration.TokenExpiry,
+	provider, err := calendarProviders().Get(integration.Provider)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported calendar provider"})
+		return
 	}
 
-	// Create calendar service
-	client := googleOAuthConfig.Client(context.Background(), token)
-	calendarService, err := calendar.NewService(context.Background(), option.WithHTTPClient(client))
+	events, err := calendarsync.Connect(c.Request.Context(), config.GetDB(), provider, &integration)
 	if err != nil {
 		log.Printf("Failed to create calendar service: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar service"})
 		return
 	}
 
-	// Sync scheduled tasks to calendar
-	if err := syncScheduledTasksToCalendar(calendarService, integration); err != nil {
-		log.Printf("Failed to sync to calendar: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync scheduled tasks"})
+	result, err := calendarsync.NewSyncer(config.GetDB()).Sync(c.Request.Context(), events, &integration)
+	if err != nil {
+		log.Printf("Failed to sync calendar: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Calendar sync completed successfully",
+		"result":  result,
+	})
+}
+
+// GetCalendarSyncAudit returns the most recent changes applied by sync for an integration
+func GetCalendarSyncAudit(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var integration models.CalendarIntegration
+	if err := config.GetDB().Where("id = ? AND user_id = ?", c.Param("integrationID"), userID).First(&integration).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar integration not found"})
+			return
+		}
+		log.Printf("Failed to get calendar integration: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendar integration"})
 		return
 	}
 
-	// Update last sync time
-	now := time.Now()
-	if err := config.GetDB().Model(&integration).Update("last_sync_at", &now).Error; err != nil {
-		log.Printf("Failed to update last sync time: %v", err)
+	var entries []models.CalendarSyncAudit
+	if err := config.GetDB().Where("calendar_integration_id = ?", integration.ID).
+		Order("created_at DESC").Limit(200).Find(&entries).Error; err != nil {
+		log.Printf("Failed to get calendar sync audit: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendar sync audit"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Calendar sync completed successfully"})
+	c.JSON(http.StatusOK, gin.H{"audit": entries})
 }
 
 // DeleteCalendarIntegration removes a calendar integration
@@ -268,6 +242,13 @@ func DeleteCalendarIntegration(c *gin.Context) {
 		return
 	}
 
+	if err := tx.Where("calendar_integration_id = ?", integrationID).Delete(&models.CalendarSyncAudit{}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Failed to delete calendar sync audit: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar sync records"})
+		return
+	}
+
 	// Delete integration
 	if err := tx.Where("id = ? AND user_id = ?", integrationID, userID).Delete(&models.CalendarIntegration{}).Error; err != nil {
 		tx.Rollback()
@@ -295,98 +276,3 @@ func generateState() (string, error) {
 	}
 	return base64.URLEncoding.EncodeToString(b), nil
 }
-
-func refreshGoogleToken(integration *models.CalendarIntegration) error {
-	token := &oauth2.Token{
-		AccessToken:  integration.AccessToken,
-		RefreshToken: integration.RefreshToken,
-		Expiry:       integration.TokenExpiry,
-	}
-
-	tokenSource := googleOAuthConfig.TokenSource(context.Background(), token)
-	newToken, err := tokenSource.Token()
-	if err != nil {
-		return err
-	}
-
-	// Update integration with new token
-	integration.AccessToken = newToken.AccessToken
-	integration.RefreshToken = newToken.RefreshToken
-	integration.TokenExpiry = newToken.Expiry
-
-	return config.GetDB().Save(integration).Error
-}
-
-func syncScheduledTasksToCalendar(svc *calendar.Service, integration models.CalendarIntegration) error {
-	// Get scheduled tasks for the user
-	var scheduledTasks []models.ScheduledTask
-	if err := config.GetDB().Where("user_id = ?", integration.UserID).Find(&scheduledTasks).Error; err != nil {
-		return err
-	}
-
-	for _, task := range scheduledTasks {
-		// Check if already synced
-		var existingSync models.CalendarEventSync
-		err := config.GetDB().Where("scheduled_task_id = ? AND calendar_integration_id = ?", task.ID, integration.ID).First(&existingSync).Error
-
-		if err == gorm.ErrRecordNotFound {
-			// Create new calendar event
-			event := &calendar.Event{
-				Summary: task.Title,
-				Start: &calendar.EventDateTime{
-					DateTime: task.Start.Format(time.RFC3339),
-					TimeZone: "UTC",
-				},
-				End: &calendar.EventDateTime{
-					DateTime: task.End.Format(time.RFC3339),
-					TimeZone: "UTC",
-				},
-			}
-
-			createdEvent, err := svc.Events.Insert(integration.CalendarID, event).Do()
-			if err != nil {
-				log.Printf("Failed to create calendar event: %v", err)
-				continue
-			}
-
-			// Save sync record
-			sync := models.CalendarEventSync{
-				ScheduledTaskID:       task.ID,
-				CalendarIntegrationID: integration.ID,
-				ExternalEventID:       createdEvent.Id,
-				LastSyncedAt:          time.Now(),
-			}
-
-			if err := config.GetDB().Create(&sync).Error; err != nil {
-				log.Printf("Failed to save sync record: %v", err)
-			}
-		} else if err == nil {
-			// Update existing event
-			event := &calendar.Event{
-				Summary: task.Title,
-				Start: &calendar.EventDateTime{
-					DateTime: task.Start.Format(time.RFC3339),
-					TimeZone: "UTC",
-				},
-				End: &calendar.EventDateTime{
-					DateTime: task.End.Format(time.RFC3339),
-					TimeZone: "UTC",
-				},
-			}
-
-			_, err := svc.Events.Update(integration.CalendarID, existingSync.ExternalEventID, event).Do()
-			if err != nil {
-				log.Printf("Failed to update calendar event: %v", err)
-				continue
-			}
-
-			// Update sync record
-			existingSync.LastSyncedAt = time.Now()
-			if err := config.GetDB().Save(&existingSync).Error; err != nil {
-				log.Printf("Failed to update sync record: %v", err)
-			}
-		}
-	}
-
-	return nil
-}
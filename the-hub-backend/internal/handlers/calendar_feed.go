@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/ical"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// feedHistoryWindow limits how far back one-off events are published in a feed
+const feedHistoryWindow = 90 * 24 * time.Hour
+
+// calendarFeedURL builds the public subscription URL for a feed token
+func calendarFeedURL(c *gin.Context, token string) string {
+	return fmt.Sprintf("%s/calendar/feed/%s.ics", apiBaseURL(c), token)
+}
+
+// apiBaseURL is the public URL of the API, from API_BASE_URL or the request host
+func apiBaseURL(c *gin.Context) string {
+	baseURL := os.Getenv("API_BASE_URL")
+	if baseURL == "" {
+		scheme := "http"
+		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		baseURL = fmt.Sprintf("%s://%s", scheme, c.Request.Host)
+	}
+	return strings.TrimRight(baseURL, "/")
+}
+
+// calendarFeedResponse describes a feed, with its subscription URL when its token was
+// just issued; only the token's hash is kept, so the URL cannot be shown again later
+func calendarFeedResponse(c *gin.Context, feed models.CalendarFeed, token string) gin.H {
+	response := gin.H{"feed": feed}
+	if token != "" {
+		response["url"] = calendarFeedURL(c, token)
+	}
+	return response
+}
+
+// GetCalendarFeed returns the user's iCalendar feed settings; its URL is only given when the token is rotated
+func GetCalendarFeed(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var feed models.CalendarFeed
+	if err := config.GetDB().Where("user_id = ?", userID).First(&feed).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+			return
+		}
+		config.Logger.Errorf("Error fetching calendar feed for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, calendarFeedResponse(c, feed, ""))
+}
+
+// RotateCalendarFeedToken creates the user's feed, or replaces its token so old URLs stop working
+func RotateCalendarFeedToken(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	token, err := util.GenerateSecureToken()
+	if err != nil {
+		config.Logger.Errorf("Error generating calendar feed token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate feed token"})
+		return
+	}
+
+	var feed models.CalendarFeed
+	err = config.GetDB().Where("user_id = ?", userIDUUID).First(&feed).Error
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		feed = models.CalendarFeed{
+			UserID:          userIDUUID,
+			TokenHash:       util.HashFeedToken(token),
+			IsActive:        true,
+			IncludeSchedule: true,
+			IncludeTasks:    true,
+		}
+		if err := config.GetDB().Create(&feed).Error; err != nil {
+			config.Logger.Errorf("Error creating calendar feed for user %s: %v", userIDUUID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create calendar feed"})
+			return
+		}
+		config.Logger.Infof("Created calendar feed %s for user %s", feed.ID, userIDUUID)
+		c.JSON(http.StatusCreated, calendarFeedResponse(c, feed, token))
+		return
+	case err != nil:
+		config.Logger.Errorf("Error fetching calendar feed for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch calendar feed"})
+		return
+	}
+
+	if err := config.GetDB().Model(&feed).Updates(map[string]interface{}{
+		"token_hash": util.HashFeedToken(token),
+		"is_active":  true,
+	}).Error; err != nil {
+		config.Logger.Errorf("Error rotating calendar feed token for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not rotate feed token"})
+		return
+	}
+	if err := config.GetDB().Where("id = ?", feed.ID).First(&feed).Error; err != nil {
+		config.Logger.Errorf("Error reloading calendar feed %s: %v", feed.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch calendar feed"})
+		return
+	}
+
+	config.Logger.Infof("Rotated calendar feed token for user %s", userIDUUID)
+	c.JSON(http.StatusOK, calendarFeedResponse(c, feed, token))
+}
+
+// UpdateCalendarFeed changes which goals, categories and item types the feed publishes
+func UpdateCalendarFeed(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var feed models.CalendarFeed
+	if err := config.GetDB().Where("user_id = ?", userID).First(&feed).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+
+	var input struct {
+		IncludeSchedule *bool    `json:"include_schedule"`
+		IncludeTasks    *bool    `json:"include_tasks"`
+		GoalIDs         []string `json:"goal_ids"`
+		Categories      []string `json:"categories"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid calendar feed update input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	for _, goalID := range input.GoalIDs {
+		if _, err := uuid.Parse(goalID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid goal ID: %s", goalID)})
+			return
+		}
+	}
+
+	if input.IncludeSchedule != nil {
+		feed.IncludeSchedule = *input.IncludeSchedule
+	}
+	if input.IncludeTasks != nil {
+		feed.IncludeTasks = *input.IncludeTasks
+	}
+	if input.GoalIDs != nil {
+		feed.GoalIDs = input.GoalIDs
+	}
+	if input.Categories != nil {
+		feed.Categories = input.Categories
+	}
+
+	if err := config.GetDB().Save(&feed).Error; err != nil {
+		config.Logger.Errorf("Error updating calendar feed %s: %v", feed.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, calendarFeedResponse(c, feed, ""))
+}
+
+// RevokeCalendarFeed disables the feed; a new URL can be issued by rotating the token
+func RevokeCalendarFeed(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	result := config.GetDB().Model(&models.CalendarFeed{}).Where("user_id = ?", userID).Update("is_active", false)
+	if result.Error != nil {
+		config.Logger.Errorf("Error revoking calendar feed for user %v: %v", userID, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not revoke calendar feed"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+
+	config.Logger.Infof("Revoked calendar feed for user %v", userID)
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed revoked successfully"})
+}
+
+// ServeCalendarFeed publishes a user's schedule as an .ics file; the token in the URL is the only credential
+func ServeCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	var feed models.CalendarFeed
+	if err := config.GetDB().Where("token_hash = ? AND is_active = ?", util.HashFeedToken(token), true).First(&feed).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+
+	cal, lastModified, err := buildCalendarFeed(config.GetDB(), feed)
+	if err != nil {
+		config.Logger.Errorf("Error building calendar feed %s: %v", feed.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not build calendar feed"})
+		return
+	}
+
+	body, err := cal.Bytes()
+	if err != nil {
+		config.Logger.Errorf("Error encoding calendar feed %s: %v", feed.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not encode calendar feed"})
+		return
+	}
+
+	now := time.Now()
+	if err := config.GetDB().Model(&feed).UpdateColumn("last_accessed_at", &now).Error; err != nil {
+		config.Logger.Errorf("Error recording access to calendar feed %s: %v", feed.ID, err)
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "private, max-age=300")
+
+	// Only the ETag decides a 304: deleting an event or task changes the body but not
+	// the latest modification time of what is left, so If-Modified-Since is not trusted
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="the-hub.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", body)
+}
+
+// etagMatches reports whether an If-None-Match header, a comma-separated list of entity
+// tags or "*", matches etag. Weak tags compare by their opaque tag (RFC 7232 section 3.2).
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// buildCalendarFeed collects the feed's events and todos and the most recent modification time
+func buildCalendarFeed(db *gorm.DB, feed models.CalendarFeed) (*ical.Calendar, time.Time, error) {
+	lastModified := feed.UpdatedAt
+	cal := &ical.Calendar{Name: "The Hub", Method: "PUBLISH"}
+
+	if feed.IncludeSchedule {
+		var schedule []models.ScheduledTask
+		if err := db.Preload("Task").Preload("RecurrenceRule").
+			Where(`user_id = ? AND ("end" > ? OR recurrence_rule_id IS NOT NULL OR recurring_event_id IS NOT NULL)`, feed.UserID, time.Now().Add(-feedHistoryWindow)).
+			Order(`"start" ASC`).Find(&schedule).Error; err != nil {
+			return nil, lastModified, err
+		}
+
+		items, overrides := groupOverrides(schedule)
+		for _, item := range items {
+			if !feed.IncludesTask(item.Task) {
+				continue
+			}
+
+			if item.RecurrenceRule != nil && item.RecurrenceRule.UpdatedAt.After(lastModified) {
+				lastModified = item.RecurrenceRule.UpdatedAt
+			}
+			for _, event := range scheduleEvents(item, overrides[item.ID], fmt.Sprintf("schedule-%s@the-hub", item.ID)) {
+				if event.LastModified.After(lastModified) {
+					lastModified = event.LastModified
+				}
+				cal.Events = append(cal.Events, event)
+			}
+		}
+	}
+
+	if feed.IncludeTasks {
+		var tasks []models.Task
+		if err := db.Where("user_id = ? AND due_date IS NOT NULL", feed.UserID).
+			Order("due_date ASC").Find(&tasks).Error; err != nil {
+			return nil, lastModified, err
+		}
+
+		for i := range tasks {
+			task := &tasks[i]
+			if !feed.IncludesTask(task) {
+				continue
+			}
+
+			todo := ical.Todo{
+				UID:          fmt.Sprintf("task-%s@the-hub", task.ID),
+				Summary:      task.Title,
+				Description:  task.Description,
+				Due:          *task.DueDate,
+				Status:       icalTodoStatus(task.Status),
+				Completed:    task.CompletedAt,
+				LastModified: task.UpdatedAt,
+			}
+			if task.Priority != nil {
+				// Task priority 5 is the most urgent; iCalendar uses 1 for highest
+				todo.Priority = 11 - 2*(*task.Priority)
+			}
+			if task.UpdatedAt.After(lastModified) {
+				lastModified = task.UpdatedAt
+			}
+			cal.Todos = append(cal.Todos, todo)
+		}
+	}
+
+	cal.Stamp = lastModified
+	return cal, lastModified, nil
+}
+
+func icalTodoStatus(status string) string {
+	switch status {
+	case "completed":
+		return "COMPLETED"
+	case "in_progress":
+		return "IN-PROCESS"
+	default:
+		return "NEEDS-ACTION"
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"strconv"
@@ -12,13 +13,18 @@ import (
 
 	"github.com/TheoMKgosi/The-hub/internal/config"
 	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/notetype"
+	"github.com/TheoMKgosi/The-hub/internal/srs"
+	"github.com/TheoMKgosi/The-hub/internal/studyqueue"
+	"github.com/TheoMKgosi/The-hub/internal/util"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // GetCards godoc
 // @Summary      Get all cards for a deck
-// @Description  Fetch cards for a specific deck with optional ordering
+// @Description  Fetch cards for a deck the user owns or collaborates on, with optional ordering. Cards carry the user's own review state.
 // @Tags         cards
 // @Accept       json
 // @Produce      json
@@ -29,6 +35,7 @@ import (
 // @Success      200  {object}  map[string][]models.Card
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /decks/{deckID}/cards [get]
@@ -48,11 +55,9 @@ func GetCards(c *gin.Context) {
 		return
 	}
 
-	// Verify deck belongs to user
-	var deck models.Deck
-	if err := config.GetDB().Where("id = ? AND user_id = ?", deckID, userID).First(&deck).Error; err != nil {
-		config.Logger.Warnf("Deck ID %d not found for user %v: %v", deckID, userID, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+	userIDUUID := userID.(uuid.UUID)
+	deck, ok := accessibleDeck(c, deckID, userIDUUID, models.DeckRoleViewer)
+	if !ok {
 		return
 	}
 
@@ -83,23 +88,28 @@ func GetCards(c *gin.Context) {
 		return
 	}
 
-	orderClause := orderBy + " " + sortDir
+	orderClause := userCardColumn(orderBy, deck.Role) + " " + sortDir
 
 	var cards []models.Card
-	config.Logger.Infof("Fetching cards for deck ID: %d with order: %s", deckID, orderClause)
-	if err := config.GetDB().Where("deck_id = ?", deckID).Order(orderClause).Find(&cards).Error; err != nil {
-		config.Logger.Errorf("Error fetching cards for deck %d: %v", deckID, err)
+	config.Logger.Infof("Fetching cards for deck ID: %s with order: %s", deckID, orderClause)
+	if err := userCardsQuery(config.GetDB(), deckID, userIDUUID, deck.Role).Order(orderClause).Find(&cards).Error; err != nil {
+		config.Logger.Errorf("Error fetching cards for deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch cards"})
+		return
+	}
+	if err := withUserState(config.GetDB(), cards, userIDUUID, deck.Role); err != nil {
+		config.Logger.Errorf("Error fetching review state for deck %s: %v", deckID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch cards"})
 		return
 	}
 
-	config.Logger.Infof("Found %d cards for deck ID %d", len(cards), deckID)
+	config.Logger.Infof("Found %d cards for deck ID %s", len(cards), deckID)
 	c.JSON(http.StatusOK, gin.H{"cards": cards})
 }
 
 // GetDueCards godoc
 // @Summary      Get cards due for review
-// @Description  Fetch cards that are due for review in a specific deck
+// @Description  Fetch today's cards for the user from a deck they own or collaborate on and its sub-decks: due reviews first, then new cards, each while the daily limits of the card's deck and of the decks above it allow. Suspended and buried cards are left out. Collaborators are due on their own schedule.
 // @Tags         cards
 // @Accept       json
 // @Produce      json
@@ -108,6 +118,7 @@ func GetCards(c *gin.Context) {
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /decks/{deckID}/cards/due [get]
@@ -127,27 +138,63 @@ func GetDueCards(c *gin.Context) {
 		return
 	}
 
-	// Verify deck belongs to user
-	var deck models.Deck
-	if err := config.GetDB().Where("id = ? AND user_id = ?", deckID, userID).First(&deck).Error; err != nil {
-		config.Logger.Warnf("Deck ID %d not found for user %v: %v", deckID, userID, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+	userIDUUID := userID.(uuid.UUID)
+	deck, ok := accessibleDeck(c, deckID, userIDUUID, models.DeckRoleViewer)
+	if !ok {
+		return
+	}
+
+	// A role in a deck covers its sub-decks
+	tree, err := deckTree(config.GetDB(), deckID)
+	if err != nil {
+		config.Logger.Errorf("Error fetching sub-decks of deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch due cards"})
 		return
 	}
+	ids := deckIDs(tree)
 
 	var cards []models.Card
 	now := time.Now()
-	config.Logger.Infof("Fetching due cards for deck ID: %d", deckID)
-	if err := config.GetDB().Where("deck_id = ? AND next_review <= ?", deckID, now).Find(&cards).Error; err != nil {
-		config.Logger.Errorf("Error fetching due cards for deck %d: %v", deckID, err)
+	config.Logger.Infof("Fetching due cards for deck ID: %s", deckID)
+	if err := userDecksCardsQuery(config.GetDB(), ids, userIDUUID, deck.Role).
+		Where(userCardColumn("next_review", deck.Role)+" <= ? AND NOT "+userCardColumn("suspended", deck.Role), now).
+		Where("("+userCardColumn("buried_until", deck.Role)+" IS NULL OR "+userCardColumn("buried_until", deck.Role)+" <= ?)", now).
+		Find(&cards).Error; err != nil {
+		config.Logger.Errorf("Error fetching due cards for deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch due cards"})
+		return
+	}
+	if err := withUserState(config.GetDB(), cards, userIDUUID, deck.Role); err != nil {
+		config.Logger.Errorf("Error fetching review state for deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch due cards"})
+		return
+	}
+
+	studied, err := studiedToday(config.GetDB(), userIDUUID, ids, util.StartOfDay(now.In(util.GetUserLocation(c))))
+	if err != nil {
+		config.Logger.Errorf("Error counting today's reviews for deck %s: %v", deckID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch due cards"})
 		return
 	}
+	decks := make([]studyqueue.Deck, len(tree))
+	for i, d := range tree {
+		decks[i] = studyqueue.Deck{
+			ID:       d.ID,
+			ParentID: d.ParentID,
+			Limits:   studyqueue.Limits{New: d.NewCardsPerDay, Reviews: d.ReviewsPerDay},
+			Studied:  studied[d.ID],
+		}
+	}
+	queue := studyqueue.Build(deckID, decks, cards, now)
 
-	config.Logger.Infof("Found %d due cards for deck ID %d", len(cards), deckID)
+	config.Logger.Infof("Found %d due cards for deck ID %s, %d held back by daily limits", len(queue.Cards), deckID, queue.Held)
 	c.JSON(http.StatusOK, gin.H{
-		"cards": cards,
-		"count": len(cards),
+		"cards":        queue.Cards,
+		"count":        len(queue.Cards),
+		"new_count":    queue.New,
+		"review_count": queue.Reviews,
+		"held_back":    queue.Held,
+		"left":         queue.Left,
 	})
 }
 
@@ -162,6 +209,7 @@ func GetDueCards(c *gin.Context) {
 // @Success      200  {object}  map[string]models.Card
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /cards/{ID} [get]
@@ -181,19 +229,13 @@ func GetCard(c *gin.Context) {
 		return
 	}
 
-	var card models.Card
-	config.Logger.Infof("Fetching card ID: %d for user ID: %v", cardID, userID)
-
-	// Join with decks table to ensure user owns the deck that contains this card
-	if err := config.GetDB().Joins("JOIN decks ON cards.deck_id = decks.id").
-		Where("cards.id = ? AND decks.user_id = ?", cardID, userID).
-		First(&card).Error; err != nil {
-		config.Logger.Errorf("Card ID %d not found for user %v: %v", cardID, userID, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+	config.Logger.Infof("Fetching card ID: %s for user ID: %v", cardID, userID)
+	card, ok := accessibleCard(c, cardID, userID.(uuid.UUID), models.DeckRoleViewer)
+	if !ok {
 		return
 	}
 
-	config.Logger.Infof("Successfully retrieved card ID %d for user %v", cardID, userID)
+	config.Logger.Infof("Successfully retrieved card ID %s for user %v", cardID, userID)
 	c.JSON(http.StatusOK, gin.H{"card": card})
 }
 
@@ -202,11 +244,12 @@ type CreateCardRequest struct {
 	DeckID   uuid.UUID `json:"deck_id" binding:"required" example:"550e8400-e29b-41d4-a716-446655440000"`
 	Question string    `json:"question" binding:"required" example:"What is the capital of France?"`
 	Answer   string    `json:"answer" binding:"required" example:"Paris"`
+	Tags     []string  `json:"tags" example:"geography,capitals"`
 }
 
 // CreateCard godoc
 // @Summary      Create a new card
-// @Description  Create a new flashcard in a deck
+// @Description  Create a new flashcard in a deck the user owns or is an editor of
 // @Tags         cards
 // @Accept       json
 // @Produce      json
@@ -215,6 +258,7 @@ type CreateCardRequest struct {
 // @Success      201   {object}  models.Card
 // @Failure      400   {object}  map[string]string
 // @Failure      401   {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      404   {object}  map[string]string
 // @Failure      500   {object}  map[string]string
 // @Router       /cards [post]
@@ -234,11 +278,7 @@ func CreateCard(c *gin.Context) {
 		return
 	}
 
-	// Verify deck belongs to user
-	var deck models.Deck
-	if err := config.GetDB().Where("id = ? AND user_id = ?", input.DeckID, userID).First(&deck).Error; err != nil {
-		config.Logger.Warnf("Deck ID %d not found for user %v during card creation: %v", input.DeckID, userID, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+	if _, ok := accessibleDeck(c, input.DeckID, userID.(uuid.UUID), models.DeckRoleEditor); !ok {
 		return
 	}
 
@@ -251,28 +291,31 @@ func CreateCard(c *gin.Context) {
 		Repetitions:  0,
 		LastReviewed: time.Time{},
 		NextReview:   time.Now(),
+		Tags:         models.NewCardTags(input.Tags),
 	}
 
-	config.Logger.Infof("Creating card for deck %d: %s", input.DeckID, input.Question)
+	config.Logger.Infof("Creating card for deck %s: %s", input.DeckID, input.Question)
 	if err := config.GetDB().Create(&card).Error; err != nil {
-		config.Logger.Errorf("Error creating card for deck %d: %v", input.DeckID, err)
+		config.Logger.Errorf("Error creating card for deck %s: %v", input.DeckID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create card"})
 		return
 	}
 
-	config.Logger.Infof("Successfully created card ID %d for deck %d", card.ID, input.DeckID)
+	config.Logger.Infof("Successfully created card ID %s for deck %s", card.ID, input.DeckID)
 	c.JSON(http.StatusCreated, card)
 }
 
 // UpdateCardRequest represents the request body for updating a card
 type UpdateCardRequest struct {
-	Question *string `json:"question" example:"Updated question"`
-	Answer   *string `json:"answer" example:"Updated answer"`
+	Question  *string   `json:"question" example:"Updated question"`
+	Answer    *string   `json:"answer" example:"Updated answer"`
+	Tags      *[]string `json:"tags" example:"geography,capitals"`
+	Suspended *bool     `json:"suspended" example:"false"`
 }
 
 // UpdateCard godoc
 // @Summary      Update a card
-// @Description  Update a specific card by ID
+// @Description  Update a card's content or tags (owners and editors) or suspend it from the user's own reviews (any collaborator)
 // @Tags         cards
 // @Accept       json
 // @Produce      json
@@ -282,6 +325,7 @@ type UpdateCardRequest struct {
 // @Success      200   {object}  models.Card
 // @Failure      400   {object}  map[string]string
 // @Failure      401   {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      404   {object}  map[string]string
 // @Failure      500   {object}  map[string]string
 // @Router       /cards/{ID} [put]
@@ -301,23 +345,31 @@ func UpdateCard(c *gin.Context) {
 		return
 	}
 
-	var card models.Card
-	// Join with decks table to ensure user owns the deck that contains this card
-	if err := config.GetDB().Joins("JOIN decks ON cards.deck_id = decks.id").
-		Where("cards.id = ? AND decks.user_id = ?", cardID, userID).
-		First(&card).Error; err != nil {
-		config.Logger.Warnf("Card not found for update: ID %d, User %v", cardID, userID)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
-		return
-	}
+	userIDUUID := userID.(uuid.UUID)
 
 	var input UpdateCardRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
-		config.Logger.Warnf("Invalid update input for card ID %d: %v", cardID, err)
+		config.Logger.Warnf("Invalid update input for card ID %s: %v", cardID, err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
 		return
 	}
 
+	// Suspending only changes the user's own reviews; changing content needs an editor
+	required := models.DeckRoleViewer
+	if input.Question != nil || input.Answer != nil || input.Tags != nil {
+		required = models.DeckRoleEditor
+	}
+	card, ok := accessibleCard(c, cardID, userIDUUID, required)
+	if !ok {
+		return
+	}
+	role := card.Deck.Role
+
+	if card.NoteID != nil && (input.Question != nil || input.Answer != nil) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This card is generated from a note; edit the note instead", "note_id": card.NoteID})
+		return
+	}
+
 	updates := map[string]interface{}{}
 	if input.Question != nil {
 		updates["question"] = *input.Question
@@ -325,48 +377,74 @@ func UpdateCard(c *gin.Context) {
 	if input.Answer != nil {
 		updates["answer"] = *input.Answer
 	}
+	if input.Tags != nil {
+		updates["tags"] = models.NewCardTags(*input.Tags)
+	}
+	// Rewriting a generated card brings it up to date with its changed source
+	if input.Question != nil || input.Answer != nil {
+		updates["source_stale"] = false
+	}
+	if input.Suspended != nil && role == models.DeckRoleOwner {
+		updates["suspended"] = *input.Suspended
+	}
 
-	if len(updates) == 0 {
-		config.Logger.Warnf("No valid fields provided for card update: ID %d", cardID)
+	if len(updates) == 0 && input.Suspended == nil {
+		config.Logger.Warnf("No valid fields provided for card update: ID %s", cardID)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid fields to update"})
 		return
 	}
 
-	config.Logger.Infof("Updating card ID %d for user %v with data: %+v", cardID, userID, updates)
-	if err := config.GetDB().Model(&card).Updates(updates).Error; err != nil {
-		config.Logger.Errorf("Failed to update card ID %d: %v", cardID, err)
+	config.Logger.Infof("Updating card ID %s for user %v with data: %+v", cardID, userID, updates)
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&models.Card{}).Where("id = ?", card.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if input.Suspended != nil && role != models.DeckRoleOwner {
+			card.Suspended = *input.Suspended
+			return saveUserState(tx, card, userIDUUID, role)
+		}
+		return nil
+	})
+	if err != nil {
+		config.Logger.Errorf("Failed to update card ID %s: %v", cardID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card"})
 		return
 	}
 
 	// Reload the updated card
-	if err := config.GetDB().First(&card, card.ID).Error; err != nil {
-		config.Logger.Errorf("Error retrieving updated card ID %d: %v", card.ID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not reload updated card"})
+	if card, ok = accessibleCard(c, cardID, userIDUUID, models.DeckRoleViewer); !ok {
 		return
 	}
 
-	config.Logger.Infof("Successfully updated card ID %d for user %v", card.ID, userID)
+	config.Logger.Infof("Successfully updated card ID %s for user %v", card.ID, userID)
 	c.JSON(http.StatusOK, card)
 }
 
-// ReviewCardRequest represents the request body for reviewing a card
+// ReviewCardRequest represents the request body for reviewing a card. Give either an
+// SM-2 quality (0-5) or an FSRS rating (1 again, 2 hard, 3 good, 4 easy); type-in
+// cards can instead be given the typed answer to be graded.
 type ReviewCardRequest struct {
-	Quality int `json:"quality" binding:"required,min=0,max=5" example:"4"`
+	Quality     *int    `json:"quality" binding:"omitempty,min=0,max=5" example:"4"`
+	Rating      int     `json:"rating" binding:"omitempty,min=1,max=4" example:"3"`
+	TypedAnswer *string `json:"typed_answer" binding:"omitempty,max=2000" example:"Paris"`
+	TimeTakenMs int     `json:"time_taken_ms" binding:"omitempty,min=0,max=3600000" example:"5400"`
 }
 
 // ReviewCard godoc
 // @Summary      Review a card
-// @Description  Review a card using SM-2 spaced repetition algorithm
+// @Description  Review a card and schedule its next review with the deck's scheduler, SM-2 or FSRS. Both algorithms' state is updated on every review, so a deck can switch schedulers without losing progress. The review is logged, linked to the user's active study session if any, and a card forgotten as often as the deck's leech threshold is suspended. Type-in cards may be sent the typed answer instead of a grade; it is graded leniently on the server.
 // @Tags         cards
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
 // @Param        ID      path      int                true  "Card ID"
-// @Param        review  body      ReviewCardRequest  true  "Review data with quality (0-5)"
+// @Param        review  body      ReviewCardRequest  true  "Review data with quality (0-5) or rating (1-4)"
 // @Success      200     {object}  map[string]interface{}
 // @Failure      400     {object}  map[string]string
 // @Failure      401     {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      404     {object}  map[string]string
 // @Failure      500     {object}  map[string]string
 // @Router       /cards/{ID}/review [post]
@@ -385,67 +463,103 @@ func ReviewCard(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
+	userIDUUID := userID.(uuid.UUID)
 
-	var card models.Card
-	// Join with decks table to ensure user owns the deck that contains this card
-	if err := config.GetDB().Joins("JOIN decks ON cards.deck_id = decks.id").
-		Where("cards.id = ? AND decks.user_id = ?", cardID, userID).
-		First(&card).Error; err != nil {
-		config.Logger.Warnf("Card not found for review: ID %d, User %v", cardID, userID)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+	// Any collaborator can study the deck; their reviews only change their own state
+	card, ok := accessibleCard(c, cardID, userIDUUID, models.DeckRoleViewer)
+	if !ok {
 		return
 	}
 
 	var input ReviewCardRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
-		config.Logger.Warnf("Invalid review input for card ID %d: %v", cardID, err)
+		config.Logger.Warnf("Invalid review input for card ID %s: %v", cardID, err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Quality must be between 0 and 5", "details": err.Error()})
 		return
 	}
-
-	// SM-2 algorithm implementation
-	quality := float64(input.Quality)
-	config.Logger.Infof("Reviewing card ID %d with quality %d", cardID, input.Quality)
-
-	if quality >= 3 {
-		if card.Repetitions == 0 {
-			card.Interval = 1
-		} else if card.Repetitions == 1 {
-			card.Interval = 6
-		} else {
-			card.Interval = int(float64(card.Interval) * card.Easiness)
-		}
-		card.Repetitions++
-	} else {
-		card.Repetitions = 0
-		card.Interval = 1
-	}
-
-	card.Easiness = card.Easiness + (0.1 - (5-quality)*(0.08+(5-quality)*0.02))
-	if card.Easiness < 1.3 {
-		card.Easiness = 1.3
+	var grade srs.Grade
+	var typed *notetype.TypedGrade
+	switch {
+	case input.Quality != nil:
+		grade = srs.GradeFromQuality(*input.Quality)
+	case input.Rating != 0:
+		grade = srs.GradeFromRating(srs.Rating(input.Rating))
+	case input.TypedAnswer != nil && card.CardType == notetype.CardTypeIn:
+		result := notetype.GradeTypedAnswer(card.Answer, *input.TypedAnswer)
+		typed = &result
+		grade = srs.GradeFromQuality(result.Quality)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Give a quality (0-5), a rating (1-4) or, for type-in cards, the typed answer"})
+		return
 	}
 
-	card.LastReviewed = time.Now()
-	card.NextReview = time.Now().AddDate(0, 0, card.Interval)
+	params := loadSRSParameters(config.GetDB(), userIDUUID)
+	scheduler := deckScheduler(&card.Deck, params)
+	config.Logger.Infof("Reviewing card ID %s with quality %d using %s", cardID, grade.Quality, scheduler.Name())
 
-	if err := config.GetDB().Save(&card).Error; err != nil {
-		config.Logger.Errorf("Error updating card after review ID %d: %v", cardID, err)
+	now := time.Now()
+	before := card.Memory()
+	intervalBefore := 0
+	if !before.LastReviewed.IsZero() {
+		intervalBefore = int(math.Round(card.NextReview.Sub(before.LastReviewed).Hours() / 24))
+	}
+	memory, interval := srs.Review(before, grade, now, scheduler, params, card.Deck.DesiredRetention)
+	card.SetMemory(memory)
+	card.NextReview = now.AddDate(0, 0, interval)
+	leech := memory.Lapses > before.Lapses && srs.IsLeech(memory.Lapses, card.Deck.LeechThreshold)
+	if leech {
+		card.Suspended = true
+	}
+
+	review := models.CardReview{
+		CardID:         card.ID,
+		DeckID:         card.DeckID,
+		UserID:         userIDUUID,
+		Rating:         int(grade.Rating),
+		Quality:        grade.Quality,
+		Scheduler:      scheduler.Name(),
+		FirstReview:    before.LastReviewed.IsZero() && before.Repetitions == 0,
+		IntervalBefore: intervalBefore,
+		Interval:       interval,
+		TimeTakenMs:    input.TimeTakenMs,
+		Stability:      memory.Stability,
+		Difficulty:     memory.Difficulty,
+		ReviewedAt:     now,
+		StudySessionID: liveStudySessionID(config.GetDB(), userIDUUID, card.DeckID),
+	}
+	if !before.LastReviewed.IsZero() {
+		review.ElapsedDays = int(now.Sub(before.LastReviewed).Hours() / 24)
+	}
+
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := saveUserState(tx, card, userIDUUID, card.Deck.Role); err != nil {
+			return err
+		}
+		return tx.Create(&review).Error
+	})
+	if err != nil {
+		config.Logger.Errorf("Error updating card after review ID %s: %v", cardID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card after review"})
 		return
 	}
 
-	config.Logger.Infof("Successfully reviewed card ID %d, next review: %v", cardID, card.NextReview)
+	if leech {
+		config.Logger.Infof("Suspended card ID %s as a leech after %d lapses", cardID, card.Lapses)
+	}
+	config.Logger.Infof("Successfully reviewed card ID %s, next review: %v", cardID, card.NextReview)
 	c.JSON(http.StatusOK, gin.H{
 		"card":          card,
-		"next_interval": card.Interval,
+		"next_interval": interval,
 		"next_review":   card.NextReview,
+		"scheduler":     scheduler.Name(),
+		"leech":         leech,
+		"typed_grade":   typed,
 	})
 }
 
 // DeleteCard godoc
 // @Summary      Delete a card
-// @Description  Delete a specific card by ID
+// @Description  Delete a card from a deck the user owns or is an editor of
 // @Tags         cards
 // @Accept       json
 // @Produce      json
@@ -454,6 +568,7 @@ func ReviewCard(c *gin.Context) {
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /cards/{ID} [delete]
@@ -473,24 +588,24 @@ func DeleteCard(c *gin.Context) {
 		return
 	}
 
-	var card models.Card
-	// Join with decks table to ensure user owns the deck that contains this card
-	if err := config.GetDB().Joins("JOIN decks ON cards.deck_id = decks.id").
-		Where("cards.id = ? AND decks.user_id = ?", cardID, userID).
-		First(&card).Error; err != nil {
-		config.Logger.Warnf("Card not found for delete: ID %d, User %v", cardID, userID)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+	card, ok := accessibleCard(c, cardID, userID.(uuid.UUID), models.DeckRoleEditor)
+	if !ok {
 		return
 	}
 
-	config.Logger.Infof("Deleting card ID %d for user %v", cardID, userID)
-	if err := config.GetDB().Delete(&card).Error; err != nil {
-		config.Logger.Errorf("Failed to delete card ID %d: %v", cardID, err)
+	if card.NoteID != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "This card is generated from a note; edit or delete the note instead", "note_id": card.NoteID})
+		return
+	}
+
+	config.Logger.Infof("Deleting card ID %s for user %v", cardID, userID)
+	if err := config.GetDB().Delete(card).Error; err != nil {
+		config.Logger.Errorf("Failed to delete card ID %s: %v", cardID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete card"})
 		return
 	}
 
-	config.Logger.Infof("Successfully deleted card ID %d for user %v", cardID, userID)
+	config.Logger.Infof("Successfully deleted card ID %s for user %v", cardID, userID)
 	c.JSON(http.StatusOK, gin.H{"message": "Card deleted successfully", "card": card})
 }
 
@@ -499,10 +614,15 @@ type ExportCardRequest struct {
 	Format string `form:"format" binding:"required,oneof=json csv"`
 }
 
-// ExportCard represents a card for export (without internal fields)
+// ExportCard represents a card for export (without internal fields). A card generated
+// from a note is exported with the note's type, front and back as its question and
+// answer, and its template, so that importing recreates the note with the card's state.
 type ExportCard struct {
+	Type         string     `json:"type,omitempty"`
 	Question     string     `json:"question"`
 	Answer       string     `json:"answer"`
+	Extra        string     `json:"extra,omitempty"`
+	Template     string     `json:"template,omitempty"`
 	Easiness     float64    `json:"easiness"`
 	Interval     int        `json:"interval"`
 	Repetitions  int        `json:"repetitions"`
@@ -519,13 +639,13 @@ type ExportData struct {
 
 // ExportCards godoc
 // @Summary      Export cards from a deck
-// @Description  Export all cards from a specific deck in JSON or CSV format
+// @Description  Export all cards from a specific deck in JSON, CSV or Anki package (apkg) format. Cards generated from notes are exported with their note's type and content.
 // @Tags         cards
 // @Accept       json
-// @Produce      json,csv
+// @Produce      json,csv,octet-stream
 // @Security     BearerAuth
 // @Param        deckID   path      string  true   "Deck ID"
-// @Param        format   query     string  true   "Export format (json or csv)"  Enums(json,csv)
+// @Param        format   query     string  true   "Export format (json, csv or apkg)"  Enums(json,csv,apkg)
 // @Success      200      {object}  ExportData  "JSON export"
 // @Success      200      {string}  string      "CSV export"
 // @Failure      400      {object}  map[string]string
@@ -560,8 +680,8 @@ func ExportCards(c *gin.Context) {
 
 	// Get format parameter
 	format := c.DefaultQuery("format", "json")
-	if format != "json" && format != "csv" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be 'json' or 'csv'"})
+	if format != "json" && format != "csv" && format != "apkg" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be 'json', 'csv' or 'apkg'"})
 		return
 	}
 
@@ -572,31 +692,28 @@ func ExportCards(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch cards"})
 		return
 	}
+	var notes []models.CardNote
+	if err := config.GetDB().Where("deck_id = ?", deckID).Find(&notes).Error; err != nil {
+		config.Logger.Errorf("Error fetching notes for deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch cards"})
+		return
+	}
+	exportCards := make([]ExportCard, len(cards))
+	for i, card := range cards {
+		exportCards[i] = NewExportCard(card, notes)
+	}
 
 	config.Logger.Infof("Exporting %d cards from deck %s in %s format", len(cards), deckID, format)
 
+	if format == "apkg" {
+		writeAnkiExport(c, deck, cards, notes)
+		return
+	}
 	if format == "json" {
 		exportData := ExportData{
 			DeckName:   deck.Name,
 			ExportedAt: time.Now(),
-			Cards:      make([]ExportCard, len(cards)),
-		}
-
-		for i, card := range cards {
-			var lastReviewed *time.Time
-			if !card.LastReviewed.IsZero() {
-				lastReviewed = &card.LastReviewed
-			}
-
-			exportData.Cards[i] = ExportCard{
-				Question:     card.Question,
-				Answer:       card.Answer,
-				Easiness:     card.Easiness,
-				Interval:     card.Interval,
-				Repetitions:  card.Repetitions,
-				LastReviewed: lastReviewed,
-				NextReview:   card.NextReview,
-			}
+			Cards:      exportCards,
 		}
 
 		// Set headers for file download
@@ -618,16 +735,16 @@ func ExportCards(c *gin.Context) {
 		defer writer.Flush()
 
 		// Write header
-		header := []string{"question", "answer", "easiness", "interval", "repetitions", "last_reviewed", "next_review"}
+		header := []string{"question", "answer", "easiness", "interval", "repetitions", "last_reviewed", "next_review", "type", "extra", "template"}
 		if err := writer.Write(header); err != nil {
 			config.Logger.Errorf("Error writing CSV header: %v", err)
 			return
 		}
 
 		// Write cards
-		for _, card := range cards {
+		for _, card := range exportCards {
 			lastReviewed := ""
-			if !card.LastReviewed.IsZero() {
+			if card.LastReviewed != nil {
 				lastReviewed = card.LastReviewed.Format(time.RFC3339)
 			}
 
@@ -639,6 +756,9 @@ func ExportCards(c *gin.Context) {
 				strconv.Itoa(card.Repetitions),
 				lastReviewed,
 				card.NextReview.Format(time.RFC3339),
+				card.Type,
+				strings.ReplaceAll(card.Extra, "\n", "\\n"),
+				card.Template,
 			}
 
 			if err := writer.Write(record); err != nil {
@@ -651,10 +771,42 @@ func ExportCards(c *gin.Context) {
 	config.Logger.Infof("Successfully exported %d cards from deck %s", len(cards), deckID)
 }
 
-// ImportCard represents a card for import
+// NewExportCard converts a card for export, taking the content of cards generated from
+// a note from the note
+func NewExportCard(card models.Card, notes []models.CardNote) ExportCard {
+	export := ExportCard{
+		Question:    card.Question,
+		Answer:      card.Answer,
+		Easiness:    card.Easiness,
+		Interval:    card.Interval,
+		Repetitions: card.Repetitions,
+		NextReview:  card.NextReview,
+	}
+	if !card.LastReviewed.IsZero() {
+		lastReviewed := card.LastReviewed
+		export.LastReviewed = &lastReviewed
+	}
+	if card.NoteID != nil {
+		for _, note := range notes {
+			if note.ID == *card.NoteID {
+				export.Type, export.Question, export.Answer, export.Extra = note.Model, note.Front, note.Back, note.Extra
+				export.Template = card.Template
+				break
+			}
+		}
+	}
+	return export
+}
+
+// ImportCard represents a card for import. Cards with a type other than plain are
+// notes: rows with the same type, question, answer and extra make one note, and a
+// row's scheduling fields apply to the generated card named by its template.
 type ImportCard struct {
+	Type         string  `json:"type,omitempty" csv:"type"`
 	Question     string  `json:"question" csv:"question"`
 	Answer       string  `json:"answer" csv:"answer"`
+	Extra        string  `json:"extra,omitempty" csv:"extra"`
+	Template     string  `json:"template,omitempty" csv:"template"`
 	Easiness     float64 `json:"easiness,omitempty" csv:"easiness"`
 	Interval     int     `json:"interval,omitempty" csv:"interval"`
 	Repetitions  int     `json:"repetitions,omitempty" csv:"repetitions"`
@@ -679,14 +831,15 @@ type ImportError struct {
 
 // ImportCards godoc
 // @Summary      Import cards to a deck
-// @Description  Import cards from JSON or CSV file to a specific deck
+// @Description  Import cards from a JSON, CSV or Anki package (apkg) file to a specific deck. Rows with a type (basic, basic_reversed, cloze or type_in) are imported as notes; all notes of an Anki package go to the deck.
 // @Tags         cards
 // @Accept       multipart/form-data
 // @Produce      json
 // @Security     BearerAuth
 // @Param        deckID   path      string  true   "Deck ID"
-// @Param        format   query     string  true   "Import format (json or csv)"  Enums(json,csv)
+// @Param        format   query     string  true   "Import format (json, csv or apkg)"  Enums(json,csv,apkg)
 // @Param        file     formData  file    true   "File to import"
+// @Param        include_scheduling  formData  bool  false  "Keep the cards' Anki scheduling state (apkg only)"
 // @Success      200      {object}  ImportResult
 // @Failure      400      {object}  map[string]string
 // @Failure      401      {object}  map[string]string
@@ -720,8 +873,13 @@ func ImportCards(c *gin.Context) {
 
 	// Get format parameter
 	format := c.DefaultQuery("format", "json")
-	if format != "json" && format != "csv" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be 'json' or 'csv'"})
+	if format != "json" && format != "csv" && format != "apkg" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be 'json', 'csv' or 'apkg'"})
+		return
+	}
+
+	if format == "apkg" {
+		importAnki(c, deck.UserID, &deck)
 		return
 	}
 
@@ -758,8 +916,12 @@ func ImportCards(c *gin.Context) {
 		return
 	}
 
-	// Validate and prepare cards for import
+	// Validate and prepare cards for import. Plain cards are imported as they are;
+	// note rows are grouped into notes whose cards are generated on import.
 	var validCards []models.Card
+	var notes []*noteImport
+	notesByKey := make(map[string]*noteImport)
+	noteCards := 0
 	for i, importCard := range importCards {
 		if err := ValidateImportCard(importCard); err != nil {
 			errors = append(errors, ImportError{
@@ -770,43 +932,43 @@ func ImportCards(c *gin.Context) {
 			continue
 		}
 
-		// Convert to model
-		card := models.Card{
-			DeckID:      deckID,
-			Question:    strings.ReplaceAll(importCard.Question, "\\n", "\n"), // Unescape newlines
-			Answer:      strings.ReplaceAll(importCard.Answer, "\\n", "\n"),   // Unescape newlines
-			Easiness:    importCard.Easiness,
-			Interval:    importCard.Interval,
-			Repetitions: importCard.Repetitions,
-			NextReview:  time.Now(),
-		}
-
-		// Set default values if not provided
-		if card.Easiness == 0 {
-			card.Easiness = 2.5
-		}
-		if card.Interval == 0 {
-			card.Interval = 1
-		}
-
-		// Parse dates if provided
-		if importCard.LastReviewed != nil && *importCard.LastReviewed != "" {
-			if parsedTime, err := time.Parse(time.RFC3339, *importCard.LastReviewed); err == nil {
-				card.LastReviewed = parsedTime
+		if importCard.Type == "" {
+			// Convert to model
+			card := models.Card{
+				DeckID:   deckID,
+				Question: strings.ReplaceAll(importCard.Question, "\\n", "\n"), // Unescape newlines
+				Answer:   strings.ReplaceAll(importCard.Answer, "\\n", "\n"),   // Unescape newlines
 			}
+			applyImportState(&card, importCard)
+			validCards = append(validCards, card)
+			continue
 		}
 
-		if importCard.NextReview != nil && *importCard.NextReview != "" {
-			if parsedTime, err := time.Parse(time.RFC3339, *importCard.NextReview); err == nil {
-				card.NextReview = parsedTime
+		key := strings.Join([]string{importCard.Type, importCard.Question, importCard.Answer, importCard.Extra}, "\x00")
+		note, found := notesByKey[key]
+		if !found {
+			note = &noteImport{
+				note: models.CardNote{
+					DeckID: deckID,
+					Model:  importCard.Type,
+					Front:  strings.ReplaceAll(importCard.Question, "\\n", "\n"),
+					Back:   strings.ReplaceAll(importCard.Answer, "\\n", "\n"),
+					Extra:  strings.ReplaceAll(importCard.Extra, "\\n", "\n"),
+				},
+				states: make(map[string]ImportCard),
 			}
+			generated, _ := notetype.Generate(note.note.Model, note.note.Front, note.note.Back, note.note.Extra)
+			noteCards += len(generated)
+			notesByKey[key] = note
+			notes = append(notes, note)
+		}
+		if importCard.Template != "" {
+			note.states[importCard.Template] = importCard
 		}
-
-		validCards = append(validCards, card)
 	}
 
 	// Limit import to 1000 cards
-	if len(validCards) > 1000 {
+	if len(validCards)+noteCards > 1000 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many cards. Maximum 1000 cards per import"})
 		return
 	}
@@ -826,6 +988,18 @@ func ImportCards(c *gin.Context) {
 		successCount++
 	}
 
+	for _, note := range notes {
+		imported, err := importNote(tx, note)
+		if err != nil {
+			config.Logger.Errorf("Error importing note: %v", err)
+			errors = append(errors, ImportError{
+				Error: fmt.Sprintf("Failed to import note '%s': %v", note.note.Front, err),
+			})
+			continue
+		}
+		successCount += imported
+	}
+
 	if successCount == 0 {
 		tx.Rollback()
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -851,6 +1025,66 @@ func ImportCards(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// noteImport is a note being imported and the scheduling state of its cards by template
+type noteImport struct {
+	note   models.CardNote
+	states map[string]ImportCard
+}
+
+// importNote creates an imported note and its cards, returning how many cards it made
+func importNote(tx *gorm.DB, imported *noteImport) (int, error) {
+	if err := tx.Omit("Cards").Create(&imported.note).Error; err != nil {
+		return 0, err
+	}
+	cards, err := syncNoteCards(tx, &imported.note)
+	if err != nil {
+		return 0, err
+	}
+	for i := range cards {
+		state, found := imported.states[cards[i].Template]
+		if !found {
+			continue
+		}
+		applyImportState(&cards[i], state)
+		if err := tx.Model(&cards[i]).
+			Select("easiness", "interval", "repetitions", "last_reviewed", "next_review").
+			Updates(&cards[i]).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(cards), nil
+}
+
+// applyImportState sets a card's scheduling fields from an imported card, with the
+// defaults of a new card for those not given
+func applyImportState(card *models.Card, importCard ImportCard) {
+	card.Easiness = importCard.Easiness
+	card.Interval = importCard.Interval
+	card.Repetitions = importCard.Repetitions
+	card.NextReview = time.Now()
+
+	// Set default values if not provided
+	if card.Easiness == 0 {
+		card.Easiness = 2.5
+	}
+	if card.Interval == 0 {
+		card.Interval = 1
+	}
+
+	// Parse dates if provided
+	if importCard.LastReviewed != nil && *importCard.LastReviewed != "" {
+		if parsedTime, err := time.Parse(time.RFC3339, *importCard.LastReviewed); err == nil {
+			card.LastReviewed = parsedTime
+		}
+	}
+
+	if importCard.NextReview != nil && *importCard.NextReview != "" {
+		if parsedTime, err := time.Parse(time.RFC3339, *importCard.NextReview); err == nil {
+			card.NextReview = parsedTime
+		}
+	}
+}
+
 // ParseJSONImport parses cards from JSON format (supports both flat array and wrapped format)
 func ParseJSONImport(file multipart.File) ([]ImportCard, []ImportError) {
 	var errors []ImportError
@@ -966,6 +1200,18 @@ func ParseCSVImport(file multipart.File) ([]ImportCard, []ImportError) {
 			card.NextReview = &record[nextReviewIdx]
 		}
 
+		if typeIdx, ok := headerMap["type"]; ok && typeIdx < len(record) {
+			card.Type = strings.TrimSpace(record[typeIdx])
+		}
+
+		if extraIdx, ok := headerMap["extra"]; ok && extraIdx < len(record) {
+			card.Extra = record[extraIdx]
+		}
+
+		if templateIdx, ok := headerMap["template"]; ok && templateIdx < len(record) {
+			card.Template = strings.TrimSpace(record[templateIdx])
+		}
+
 		cards = append(cards, card)
 	}
 
@@ -974,12 +1220,20 @@ func ParseCSVImport(file multipart.File) ([]ImportCard, []ImportError) {
 
 // ValidateImportCard validates an import card
 func ValidateImportCard(card ImportCard) error {
+	if card.Type != "" && !notetype.Valid(card.Type) {
+		return fmt.Errorf("unknown card type %q", card.Type)
+	}
 	if strings.TrimSpace(card.Question) == "" {
 		return fmt.Errorf("question is required")
 	}
-	if strings.TrimSpace(card.Answer) == "" {
+	if card.Type != notetype.Cloze && strings.TrimSpace(card.Answer) == "" {
 		return fmt.Errorf("answer is required")
 	}
+	if card.Type != "" {
+		if _, err := notetype.Generate(card.Type, card.Question, card.Answer, card.Extra); err != nil {
+			return err
+		}
+	}
 	if len(card.Question) > 1000 {
 		return fmt.Errorf("question too long (max 1000 characters)")
 	}
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/notetype"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateCardNoteRequest represents the request body for creating a note
+type CreateCardNoteRequest struct {
+	DeckID uuid.UUID `json:"deck_id" binding:"required"`
+	Model  string    `json:"model" binding:"required,oneof=basic basic_reversed cloze type_in" example:"cloze"`
+	Front  string    `json:"front" binding:"required" example:"The capital of {{c1::France}} is {{c2::Paris}}"`
+	Back   string    `json:"back"`
+	Extra  string    `json:"extra"`
+}
+
+// UpdateCardNoteRequest represents the request body for updating a note
+type UpdateCardNoteRequest struct {
+	Model *string `json:"model" binding:"omitempty,oneof=basic basic_reversed cloze type_in"`
+	Front *string `json:"front"`
+	Back  *string `json:"back"`
+	Extra *string `json:"extra"`
+}
+
+// syncNoteCards makes the note's cards match what its note type generates from it.
+// Cards that still exist keep their review state and only get new text; cards the
+// note no longer makes are deleted and new ones start unreviewed.
+func syncNoteCards(tx *gorm.DB, note *models.CardNote) ([]models.Card, error) {
+	generated, err := notetype.Generate(note.Model, note.Front, note.Back, note.Extra)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []models.Card
+	if err := tx.Where("note_id = ?", note.ID).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	byTemplate := make(map[string]models.Card, len(existing))
+	for _, card := range existing {
+		byTemplate[card.Template] = card
+	}
+
+	cards := make([]models.Card, 0, len(generated))
+	for _, g := range generated {
+		card, found := byTemplate[g.Template]
+		delete(byTemplate, g.Template)
+		if found {
+			if card.Question != g.Question || card.Answer != g.Answer || card.CardType != g.Type {
+				card.Question, card.Answer, card.CardType = g.Question, g.Answer, g.Type
+				if err := tx.Model(&card).Select("question", "answer", "card_type").Updates(&card).Error; err != nil {
+					return nil, err
+				}
+			}
+			cards = append(cards, card)
+			continue
+		}
+
+		noteID := note.ID
+		card = models.Card{
+			DeckID:     note.DeckID,
+			NoteID:     &noteID,
+			Template:   g.Template,
+			CardType:   g.Type,
+			Question:   g.Question,
+			Answer:     g.Answer,
+			Easiness:   2.5,
+			Interval:   1,
+			NextReview: time.Now(),
+		}
+		if err := tx.Create(&card).Error; err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+
+	for _, card := range byTemplate {
+		if err := tx.Delete(&card).Error; err != nil {
+			return nil, err
+		}
+	}
+	return cards, nil
+}
+
+// ownCardNote loads the note named in the path if it is in a deck the user has at
+// least the required role in, writing the error response otherwise
+func ownCardNote(c *gin.Context, required string) (*models.CardNote, bool) {
+	noteID, err := uuid.Parse(c.Param("ID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return nil, false
+	}
+
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	var note models.CardNote
+	err = config.GetDB().Preload("Deck").Where("id = ?", noteID).First(&note).Error
+	if err == nil {
+		note.Deck.Role, err = deckRole(config.GetDB(), &note.Deck, userID.(uuid.UUID))
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && note.Deck.Role == "") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return nil, false
+	}
+	if err != nil {
+		config.Logger.Errorf("Error fetching note %s: %v", noteID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch note"})
+		return nil, false
+	}
+	if !models.DeckRoleAllows(note.Deck.Role, required) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role in this deck does not allow this", "role": note.Deck.Role})
+		return nil, false
+	}
+	return &note, true
+}
+
+// GetCardNotes godoc
+// @Summary      Get the notes of a deck
+// @Description  Fetch the notes of a deck the user owns or collaborates on, with the cards each generates
+// @Tags         cards
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deck_id  query     string  true  "Deck ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /card-notes [get]
+func GetCardNotes(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	deckID, err := uuid.Parse(c.Query("deck_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid deck_id is required"})
+		return
+	}
+
+	userIDUUID := userID.(uuid.UUID)
+	deck, ok := accessibleDeck(c, deckID, userIDUUID, models.DeckRoleViewer)
+	if !ok {
+		return
+	}
+
+	var notes []models.CardNote
+	if err := config.GetDB().Preload("Cards").Where("deck_id = ?", deckID).Order("created_at").
+		Find(&notes).Error; err != nil {
+		config.Logger.Errorf("Error fetching notes for deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch notes"})
+		return
+	}
+	for i := range notes {
+		if err := withUserState(config.GetDB(), notes[i].Cards, userIDUUID, deck.Role); err != nil {
+			config.Logger.Errorf("Error fetching review state for deck %s: %v", deckID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch notes"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notes": notes})
+}
+
+// GetCardNote godoc
+// @Summary      Get a note
+// @Description  Fetch a note with the cards it generates
+// @Tags         cards
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID   path      string  true  "Note ID"
+// @Success      200  {object}  models.CardNote
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /card-notes/{ID} [get]
+func GetCardNote(c *gin.Context) {
+	note, ok := ownCardNote(c, models.DeckRoleViewer)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("userID")
+	if err := config.GetDB().Where("note_id = ?", note.ID).Find(&note.Cards).Error; err != nil {
+		config.Logger.Errorf("Error fetching cards of note %s: %v", note.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch note"})
+		return
+	}
+	if err := withUserState(config.GetDB(), note.Cards, userID.(uuid.UUID), note.Deck.Role); err != nil {
+		config.Logger.Errorf("Error fetching review state of note %s: %v", note.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch note"})
+		return
+	}
+	c.JSON(http.StatusOK, note)
+}
+
+// CreateCardNote godoc
+// @Summary      Create a note
+// @Description  Create a note and the cards its note type generates: one for basic and type-in notes, both directions for basic and reversed notes, and one per {{cN::...}} deletion for cloze notes
+// @Tags         cards
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        note  body      CreateCardNoteRequest  true  "Note"
+// @Success      201   {object}  models.CardNote
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /card-notes [post]
+func CreateCardNote(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input CreateCardNoteRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid note input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if _, err := notetype.Generate(input.Model, input.Front, input.Back, input.Extra); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deck, ok := accessibleDeck(c, input.DeckID, userID.(uuid.UUID), models.DeckRoleEditor)
+	if !ok {
+		return
+	}
+
+	note := models.CardNote{
+		DeckID: deck.ID,
+		Model:  input.Model,
+		Front:  input.Front,
+		Back:   input.Back,
+		Extra:  input.Extra,
+	}
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Cards").Create(&note).Error; err != nil {
+			return err
+		}
+		cards, err := syncNoteCards(tx, &note)
+		note.Cards = cards
+		return err
+	})
+	if err != nil {
+		config.Logger.Errorf("Error creating note in deck %s: %v", deck.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create note"})
+		return
+	}
+
+	config.Logger.Infof("Created %s note %s with %d cards in deck %s", note.Model, note.ID, len(note.Cards), deck.ID)
+	c.JSON(http.StatusCreated, note)
+}
+
+// UpdateCardNote godoc
+// @Summary      Update a note
+// @Description  Edit a note and update the cards it generates. Cards the note still generates keep their review state; cards it no longer generates are deleted.
+// @Tags         cards
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID    path      string                 true  "Note ID"
+// @Param        note  body      UpdateCardNoteRequest  true  "Fields to change"
+// @Success      200   {object}  models.CardNote
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /card-notes/{ID} [patch]
+func UpdateCardNote(c *gin.Context) {
+	note, ok := ownCardNote(c, models.DeckRoleEditor)
+	if !ok {
+		return
+	}
+
+	var input UpdateCardNoteRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if input.Model != nil {
+		note.Model = *input.Model
+	}
+	if input.Front != nil {
+		note.Front = *input.Front
+	}
+	if input.Back != nil {
+		note.Back = *input.Back
+	}
+	if input.Extra != nil {
+		note.Extra = *input.Extra
+	}
+	if _, err := notetype.Generate(note.Model, note.Front, note.Back, note.Extra); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(note).Select("model", "front", "back", "extra").Updates(note).Error; err != nil {
+			return err
+		}
+		cards, err := syncNoteCards(tx, note)
+		note.Cards = cards
+		return err
+	})
+	if err != nil {
+		config.Logger.Errorf("Error updating note %s: %v", note.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update note"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if err := withUserState(config.GetDB(), note.Cards, userID.(uuid.UUID), note.Deck.Role); err != nil {
+		config.Logger.Warnf("Could not fetch review state of note %s: %v", note.ID, err)
+	}
+
+	config.Logger.Infof("Updated note %s, now generating %d cards", note.ID, len(note.Cards))
+	c.JSON(http.StatusOK, note)
+}
+
+// DeleteCardNote godoc
+// @Summary      Delete a note
+// @Description  Delete a note and every card it generates
+// @Tags         cards
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID   path      string  true  "Note ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /card-notes/{ID} [delete]
+func DeleteCardNote(c *gin.Context) {
+	note, ok := ownCardNote(c, models.DeckRoleEditor)
+	if !ok {
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("note_id = ?", note.ID).Delete(&models.Card{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(note).Error
+	})
+	if err != nil {
+		config.Logger.Errorf("Error deleting note %s: %v", note.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete note"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Note deleted successfully"})
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/srs"
+	"github.com/TheoMKgosi/The-hub/internal/studyqueue"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// customStudyLimit is how many cards custom study returns unless asked for fewer
+	customStudyLimit = 100
+	// maxCustomStudyCards caps the cards a custom study query looks through
+	maxCustomStudyCards = 2000
+)
+
+// studyableDecksQuery selects the decks a user can study, with their role in each:
+// their own, those they collaborate on and the public ones they subscribe to
+func studyableDecksQuery(db *gorm.DB, userID interface{}) *gorm.DB {
+	return db.Model(&models.Deck{}).Select("decks.*, COALESCE(deck_users.role, ?) AS role", models.DeckRoleOwner).
+		Joins("LEFT JOIN deck_users ON deck_users.deck_id = decks.id AND deck_users.user_id = ?", userID).
+		Where("decks.user_id = ? OR (deck_users.id IS NOT NULL AND (deck_users.role <> ? OR decks.is_public))", userID, models.DeckRoleSubscriber)
+}
+
+// deckTree loads a deck and all the decks nested under it
+func deckTree(db *gorm.DB, deckID uuid.UUID) ([]models.Deck, error) {
+	var decks []models.Deck
+	err := db.Raw(`WITH RECURSIVE tree AS (
+			SELECT * FROM decks WHERE id = ? AND deleted_at IS NULL
+			UNION
+			SELECT decks.* FROM decks JOIN tree ON decks.parent_id = tree.id WHERE decks.deleted_at IS NULL
+		) SELECT * FROM tree`, deckID).Scan(&decks).Error
+	return decks, err
+}
+
+func deckIDs(decks []models.Deck) []uuid.UUID {
+	ids := make([]uuid.UUID, len(decks))
+	for i := range decks {
+		ids[i] = decks[i].ID
+	}
+	return ids
+}
+
+// validDeckParent checks a deck can be nested under parentID, writing the error
+// response otherwise: the parent must be another of the owner's decks, and not one
+// nested under the deck being moved (deckID, nil for a new deck)
+func validDeckParent(c *gin.Context, userID uuid.UUID, deckID *uuid.UUID, parentID uuid.UUID) bool {
+	var parent models.Deck
+	if err := config.GetDB().Where("id = ? AND user_id = ?", parentID, userID).First(&parent).Error; err != nil {
+		config.Logger.Warnf("Parent deck %s not found for user %s", parentID, userID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Parent deck not found"})
+		return false
+	}
+	if deckID == nil {
+		return true
+	}
+	tree, err := deckTree(config.GetDB(), *deckID)
+	if err != nil {
+		config.Logger.Errorf("Error fetching sub-decks of deck %s: %v", *deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch sub-decks"})
+		return false
+	}
+	for _, deck := range tree {
+		if deck.ID == parentID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A deck cannot be moved under itself or one of its sub-decks"})
+			return false
+		}
+	}
+	return true
+}
+
+// userDecksCardsQuery is userCardsQuery over several decks the user has the same role in
+func userDecksCardsQuery(db *gorm.DB, deckIDs []uuid.UUID, userID uuid.UUID, role string) *gorm.DB {
+	query := db.Model(&models.Card{}).Where("cards.deck_id IN ?", deckIDs)
+	if role == models.DeckRoleOwner {
+		return query
+	}
+	return query.Select("cards.*").
+		Joins("LEFT JOIN card_progress ON card_progress.card_id = cards.id AND card_progress.user_id = ?", userID)
+}
+
+// studiedToday counts the new cards and reviews the user studied in each deck since dayStart
+func studiedToday(db *gorm.DB, userID uuid.UUID, deckIDs []uuid.UUID, dayStart time.Time) (map[uuid.UUID]studyqueue.Counts, error) {
+	var rows []struct {
+		DeckID  uuid.UUID
+		New     int
+		Reviews int
+	}
+	err := db.Model(&models.CardReview{}).
+		Select("deck_id, COUNT(DISTINCT card_id) FILTER (WHERE first_review) AS new, COUNT(DISTINCT card_id) FILTER (WHERE NOT first_review) AS reviews").
+		Where("user_id = ? AND deck_id IN ? AND reviewed_at >= ?", userID, deckIDs, dayStart).
+		Group("deck_id").Scan(&rows).Error
+	studied := make(map[uuid.UUID]studyqueue.Counts, len(rows))
+	for _, row := range rows {
+		studied[row.DeckID] = studyqueue.Counts{New: row.New, Reviews: row.Reviews}
+	}
+	return studied, err
+}
+
+// CustomStudy godoc
+// @Summary      Custom study
+// @Description  Find cards to study across every deck the user can study, or one deck and its sub-decks, by tags and recent failures, such as cards tagged networking failed in the last 7 days. Suspended and buried cards are left out; daily limits do not apply.
+// @Tags         cards
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deck_id             query     string  false  "Only this deck and its sub-decks"
+// @Param        tags                query     string  false  "Comma-separated tags the cards must all have"
+// @Param        failed_within_days  query     int     false  "Only cards the user answered Again within this many days"
+// @Param        due_only            query     bool    false  "Only cards due now"
+// @Param        include_new         query     bool    false  "Include cards never reviewed"  default(true)
+// @Param        limit               query     int     false  "Most cards to return"  default(100)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /cards/study [get]
+func CustomStudy(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	limit := customStudyLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		limit = n
+	}
+	failedDays := 0
+	if value := c.Query("failed_within_days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed_within_days must be between 1 and 365"})
+			return
+		}
+		failedDays = n
+	}
+	dueOnly := c.Query("due_only") == "true"
+	includeNew := c.DefaultQuery("include_new", "true") == "true"
+	var tags models.CardTags
+	if value := c.Query("tags"); value != "" {
+		tags = models.NewCardTags(strings.Split(value, ","))
+	}
+
+	// The decks to look in, by the user's role in them
+	var decks []models.Deck
+	if value := c.Query("deck_id"); value != "" {
+		deckID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID"})
+			return
+		}
+		deck, ok := accessibleDeck(c, deckID, userIDUUID, models.DeckRoleViewer)
+		if !ok {
+			return
+		}
+		if decks, err = deckTree(config.GetDB(), deck.ID); err != nil {
+			config.Logger.Errorf("Error fetching sub-decks of deck %s: %v", deck.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch cards"})
+			return
+		}
+		for i := range decks {
+			decks[i].Role = deck.Role
+		}
+	} else if err := studyableDecksQuery(config.GetDB(), userIDUUID).Find(&decks).Error; err != nil {
+		config.Logger.Errorf("Error fetching decks for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch cards"})
+		return
+	}
+	roles := map[string][]uuid.UUID{}
+	for _, deck := range decks {
+		roles[deck.Role] = append(roles[deck.Role], deck.ID)
+	}
+
+	now := time.Now()
+	var cards []models.Card
+	for role, ids := range roles {
+		query := userDecksCardsQuery(config.GetDB(), ids, userIDUUID, role).
+			Where("NOT " + userCardColumn("suspended", role))
+		if len(tags) > 0 {
+			encoded, _ := json.Marshal(tags)
+			query = query.Where("cards.tags @> ?::jsonb", string(encoded))
+		}
+		if failedDays > 0 {
+			query = query.Where("cards.id IN (?)", config.GetDB().Model(&models.CardReview{}).Select("card_id").
+				Where("user_id = ? AND rating = ? AND reviewed_at >= ?", userIDUUID, int(srs.Again), now.AddDate(0, 0, -failedDays)))
+		}
+		if dueOnly {
+			query = query.Where(userCardColumn("next_review", role)+" <= ?", now)
+		}
+		var found []models.Card
+		if err := query.Order(userCardColumn("next_review", role)).Limit(maxCustomStudyCards).Find(&found).Error; err != nil {
+			config.Logger.Errorf("Error fetching custom study cards for user %s: %v", userIDUUID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch cards"})
+			return
+		}
+		if err := withUserState(config.GetDB(), found, userIDUUID, role); err != nil {
+			config.Logger.Errorf("Error fetching review state for user %s: %v", userIDUUID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch cards"})
+			return
+		}
+		cards = append(cards, found...)
+	}
+
+	picked := studyqueue.Custom(cards, dueOnly, includeNew, limit, now)
+	config.Logger.Infof("Custom study found %d cards for user %s", len(picked), userIDUUID)
+	c.JSON(http.StatusOK, gin.H{"cards": picked, "count": len(picked)})
+}
+
+// GetCardTags godoc
+// @Summary      Get card tags
+// @Description  List the tags on cards in the decks the user can study, with how many cards have each
+// @Tags         cards
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /cards/tags [get]
+func GetCardTags(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	tags := []struct {
+		Tag   string `json:"tag"`
+		Cards int    `json:"cards"`
+	}{}
+	err := config.GetDB().Raw(`SELECT tag, COUNT(*) AS cards
+		FROM cards CROSS JOIN LATERAL jsonb_array_elements_text(cards.tags) AS tag
+		WHERE cards.deleted_at IS NULL AND cards.deck_id IN (?)
+		GROUP BY tag ORDER BY tag`,
+		studyableDecksQuery(config.GetDB(), userID).Select("decks.id")).Scan(&tags).Error
+	if err != nil {
+		config.Logger.Errorf("Error fetching card tags for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch tags"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// changeCardState applies change to the user's own review state of the card named by
+// the ID param, which any collaborator can do, and responds with the card
+func changeCardState(c *gin.Context, action string, change func(card *models.Card)) {
+	cardID, err := uuid.Parse(c.Param("ID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card ID"})
+		return
+	}
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	card, ok := accessibleCard(c, cardID, userIDUUID, models.DeckRoleViewer)
+	if !ok {
+		return
+	}
+	change(card)
+	if err := saveUserState(config.GetDB(), card, userIDUUID, card.Deck.Role); err != nil {
+		config.Logger.Errorf("Failed to %s card %s for user %s: %v", action, cardID, userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + action + " card"})
+		return
+	}
+
+	config.Logger.Infof("User %s did %s on card %s", userIDUUID, action, cardID)
+	c.JSON(http.StatusOK, gin.H{"card": card})
+}
+
+// BuryCard godoc
+// @Summary      Bury a card
+// @Description  Leave a card out of the user's reviews until the start of tomorrow in their timezone
+// @Tags         cards
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Card ID"
+// @Success      200  {object}  map[string]models.Card
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /cards/bury/{ID} [post]
+func BuryCard(c *gin.Context) {
+	until := studyqueue.BuryUntil(time.Now(), util.GetUserLocation(c))
+	changeCardState(c, "bury", func(card *models.Card) { card.BuriedUntil = &until })
+}
+
+// UnburyCard godoc
+// @Summary      Unbury a card
+// @Description  Bring a buried card back into the user's reviews
+// @Tags         cards
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Card ID"
+// @Success      200  {object}  map[string]models.Card
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /cards/bury/{ID} [delete]
+func UnburyCard(c *gin.Context) {
+	changeCardState(c, "unbury", func(card *models.Card) { card.BuriedUntil = nil })
+}
+
+// SuspendCard godoc
+// @Summary      Suspend a card
+// @Description  Leave a card out of the user's reviews until it is unsuspended
+// @Tags         cards
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Card ID"
+// @Success      200  {object}  map[string]models.Card
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /cards/suspend/{ID} [post]
+func SuspendCard(c *gin.Context) {
+	changeCardState(c, "suspend", func(card *models.Card) { card.Suspended = true })
+}
+
+// UnsuspendCard godoc
+// @Summary      Unsuspend a card
+// @Description  Bring a suspended card back into the user's reviews
+// @Tags         cards
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Card ID"
+// @Success      200  {object}  map[string]models.Card
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /cards/suspend/{ID} [delete]
+func UnsuspendCard(c *gin.Context) {
+	changeCardState(c, "unsuspend", func(card *models.Card) { card.Suspended = false })
+}
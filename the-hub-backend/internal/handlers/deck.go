@@ -5,13 +5,16 @@ import (
 
 	"github.com/TheoMKgosi/The-hub/internal/config"
 	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/srs"
+	"github.com/TheoMKgosi/The-hub/internal/studyqueue"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // GetDecks godoc
 // @Summary      Get all decks
-// @Description  Fetch decks for the logged-in user with optional ordering
+// @Description  Fetch the logged-in user's decks and the decks shared with them, with optional ordering. Each deck has the user's role in it.
 // @Tags         decks
 // @Accept       json
 // @Produce      json
@@ -58,7 +61,7 @@ func GetDecks(c *gin.Context) {
 	orderClause := orderBy + " " + sortDir
 
 	config.Logger.Infof("Fetching decks for user ID: %v with order: %s", userID, orderClause)
-	if err := config.GetDB().Where("user_id = ?", userID).Order(orderClause).Find(&decks).Error; err != nil {
+	if err := studyableDecksQuery(config.GetDB(), userID).Order("decks." + orderClause).Find(&decks).Error; err != nil {
 		config.Logger.Errorf("Error fetching decks for user %v: %v", userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch decks"})
 		return
@@ -70,7 +73,7 @@ func GetDecks(c *gin.Context) {
 
 // GetDeck godoc
 // @Summary      Get a specific deck
-// @Description  Fetch a specific deck by ID for the logged-in user
+// @Description  Fetch a deck the logged-in user owns or collaborates on, with their role in it
 // @Tags         decks
 // @Accept       json
 // @Produce      json
@@ -98,27 +101,32 @@ func GetDeck(c *gin.Context) {
 		return
 	}
 
-	config.Logger.Infof("Fetching deck ID: %d for user ID: %v", deckID, userID)
-	var deck models.Deck
-	// Ensure user can only access their own decks
-	if err := config.GetDB().Where("id = ? AND user_id = ?", deckID, userID).First(&deck).Error; err != nil {
-		config.Logger.Errorf("Deck ID %d not found for user %v: %v", deckID, userID, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+	config.Logger.Infof("Fetching deck ID: %s for user ID: %v", deckID, userID)
+	deck, ok := accessibleDeck(c, deckID, userID.(uuid.UUID), models.DeckRoleViewer)
+	if !ok {
 		return
 	}
 
-	config.Logger.Infof("Successfully retrieved deck ID %d for user %v", deckID, userID)
+	config.Logger.Infof("Successfully retrieved deck ID %s for user %v", deckID, userID)
 	c.JSON(http.StatusOK, gin.H{"deck": deck})
 }
 
 // CreateDeckRequest represents the request body for creating a deck
 type CreateDeckRequest struct {
-	Name string `json:"name" binding:"required" example:"Spanish Vocabulary"`
+	Name             string     `json:"name" binding:"required" example:"Spanish Vocabulary"`
+	Description      string     `json:"description" example:"The 500 most common Spanish words"`
+	IsPublic         bool       `json:"is_public" example:"false"`
+	Scheduler        string     `json:"scheduler" binding:"omitempty,oneof=sm2 fsrs" example:"fsrs"`
+	DesiredRetention *float64   `json:"desired_retention" binding:"omitempty,min=0.7,max=0.99" example:"0.9"`
+	LeechThreshold   *int       `json:"leech_threshold" binding:"omitempty,min=0,max=100" example:"8"`
+	ParentID         *uuid.UUID `json:"parent_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	NewCardsPerDay   *int       `json:"new_cards_per_day" binding:"omitempty,min=0,max=9999" example:"20"`
+	ReviewsPerDay    *int       `json:"reviews_per_day" binding:"omitempty,min=0,max=9999" example:"200"`
 }
 
 // CreateDeck godoc
 // @Summary      Create a new deck
-// @Description  Create a new flashcard deck for the logged-in user
+// @Description  Create a new flashcard deck for the logged-in user, optionally as a sub-deck of another of their decks
 // @Tags         decks
 // @Accept       json
 // @Produce      json
@@ -161,12 +169,60 @@ func CreateDeck(c *gin.Context) {
 	}
 
 	deck := models.Deck{
-		Name:   input.Name,
-		UserID: userIDUUID,
+		Name:             input.Name,
+		Description:      input.Description,
+		IsPublic:         input.IsPublic,
+		UserID:           userIDUUID,
+		Scheduler:        srs.SchedulerSM2,
+		DesiredRetention: srs.DefaultRetention,
+		LeechThreshold:   srs.DefaultLeechThreshold,
+		NewCardsPerDay:   studyqueue.DefaultNewPerDay,
+		ReviewsPerDay:    studyqueue.DefaultReviewsPerDay,
+	}
+	if input.Scheduler != "" {
+		deck.Scheduler = input.Scheduler
+	}
+	if input.DesiredRetention != nil {
+		deck.DesiredRetention = *input.DesiredRetention
+	}
+	if input.LeechThreshold != nil {
+		deck.LeechThreshold = *input.LeechThreshold
+	}
+	if input.NewCardsPerDay != nil {
+		deck.NewCardsPerDay = *input.NewCardsPerDay
+	}
+	if input.ReviewsPerDay != nil {
+		deck.ReviewsPerDay = *input.ReviewsPerDay
+	}
+	if input.ParentID != nil {
+		if !validDeckParent(c, userIDUUID, nil, *input.ParentID) {
+			return
+		}
+		deck.ParentID = input.ParentID
 	}
 
 	config.Logger.Infof("Creating deck for user %s: %s", userIDUUID, input.Name)
-	if err := config.GetDB().Create(&deck).Error; err != nil {
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&deck).Error; err != nil {
+			return err
+		}
+		// Zero settings are left out of the insert in favour of the columns' defaults
+		zeros := map[string]interface{}{}
+		if deck.LeechThreshold == 0 {
+			zeros["leech_threshold"] = 0
+		}
+		if input.NewCardsPerDay != nil && *input.NewCardsPerDay == 0 {
+			zeros["new_cards_per_day"] = 0
+		}
+		if input.ReviewsPerDay != nil && *input.ReviewsPerDay == 0 {
+			zeros["reviews_per_day"] = 0
+		}
+		if len(zeros) == 0 {
+			return nil
+		}
+		return tx.Model(&deck).Updates(zeros).Error
+	})
+	if err != nil {
 		config.Logger.Errorf("Error creating deck for user %s: %v", userIDUUID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create deck"})
 		return
@@ -178,12 +234,20 @@ func CreateDeck(c *gin.Context) {
 
 // UpdateDeckRequest represents the request body for updating a deck
 type UpdateDeckRequest struct {
-	Name *string `json:"name" example:"Updated deck name"`
+	Name             *string  `json:"name" example:"Updated deck name"`
+	Description      *string  `json:"description" example:"The 500 most common Spanish words"`
+	IsPublic         *bool    `json:"is_public" example:"true"`
+	Scheduler        *string  `json:"scheduler" binding:"omitempty,oneof=sm2 fsrs" example:"fsrs"`
+	DesiredRetention *float64 `json:"desired_retention" binding:"omitempty,min=0.7,max=0.99" example:"0.9"`
+	LeechThreshold   *int     `json:"leech_threshold" binding:"omitempty,min=0,max=100" example:"8"`
+	ParentID         *string  `json:"parent_id" example:"550e8400-e29b-41d4-a716-446655440000"` // Empty to make it a top-level deck
+	NewCardsPerDay   *int     `json:"new_cards_per_day" binding:"omitempty,min=0,max=9999" example:"20"`
+	ReviewsPerDay    *int     `json:"reviews_per_day" binding:"omitempty,min=0,max=9999" example:"200"`
 }
 
 // UpdateDeck godoc
 // @Summary      Update a deck
-// @Description  Update a specific deck by ID for the logged-in user
+// @Description  Update a specific deck by ID for the logged-in user. Changing the scheduler or desired retention reschedules the deck's cards. Making it public lists it in the deck library. Setting parent_id moves it under another of the user's decks, or to the top level when empty.
 // @Tags         decks
 // @Accept       json
 // @Produce      json
@@ -215,14 +279,14 @@ func UpdateDeck(c *gin.Context) {
 	var deck models.Deck
 	// Ensure user can only update their own decks
 	if err := config.GetDB().Where("id = ? AND user_id = ?", deckID, userID).First(&deck).Error; err != nil {
-		config.Logger.Warnf("Deck not found for update: ID %d, User %v", deckID, userID)
+		config.Logger.Warnf("Deck not found for update: ID %s, User %v", deckID, userID)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
 		return
 	}
 
 	var input UpdateDeckRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
-		config.Logger.Warnf("Invalid update input for deck ID %d: %v", deckID, err)
+		config.Logger.Warnf("Invalid update input for deck ID %s: %v", deckID, err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
 		return
 	}
@@ -238,34 +302,88 @@ func UpdateDeck(c *gin.Context) {
 		}
 		updates["name"] = *input.Name
 	}
+	if input.Description != nil {
+		updates["description"] = *input.Description
+	}
+	if input.IsPublic != nil {
+		updates["is_public"] = *input.IsPublic
+	}
+	if input.LeechThreshold != nil {
+		updates["leech_threshold"] = *input.LeechThreshold
+	}
+	if input.NewCardsPerDay != nil {
+		updates["new_cards_per_day"] = *input.NewCardsPerDay
+	}
+	if input.ReviewsPerDay != nil {
+		updates["reviews_per_day"] = *input.ReviewsPerDay
+	}
+	if input.ParentID != nil {
+		if *input.ParentID == "" {
+			updates["parent_id"] = nil
+		} else {
+			parentID, err := uuid.Parse(*input.ParentID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent deck ID"})
+				return
+			}
+			if !validDeckParent(c, deck.UserID, &deck.ID, parentID) {
+				return
+			}
+			updates["parent_id"] = parentID
+		}
+	}
+	reschedule := false
+	if input.Scheduler != nil && *input.Scheduler != deck.Scheduler {
+		updates["scheduler"] = *input.Scheduler
+		reschedule = true
+	}
+	if input.DesiredRetention != nil && *input.DesiredRetention != deck.DesiredRetention {
+		updates["desired_retention"] = *input.DesiredRetention
+		reschedule = true
+	}
 
 	if len(updates) == 0 {
-		config.Logger.Warnf("No valid fields provided for deck update: ID %d", deckID)
+		config.Logger.Warnf("No valid fields provided for deck update: ID %s", deckID)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid fields to update"})
 		return
 	}
 
-	config.Logger.Infof("Updating deck ID %d for user %v with data: %+v", deckID, userID, updates)
-	if err := config.GetDB().Model(&deck).Updates(updates).Error; err != nil {
-		config.Logger.Errorf("Failed to update deck ID %d: %v", deckID, err)
+	config.Logger.Infof("Updating deck ID %s for user %v with data: %+v", deckID, userID, updates)
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&deck).Updates(updates).Error; err != nil {
+			return err
+		}
+		if !reschedule {
+			return nil
+		}
+		if input.Scheduler != nil {
+			deck.Scheduler = *input.Scheduler
+		}
+		if input.DesiredRetention != nil {
+			deck.DesiredRetention = *input.DesiredRetention
+		}
+		return rescheduleDeck(tx, &deck, loadSRSParameters(tx, deck.UserID))
+	})
+	if err != nil {
+		config.Logger.Errorf("Failed to update deck ID %s: %v", deckID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update deck"})
 		return
 	}
 
 	// Reload the updated deck
 	if err := config.GetDB().First(&deck, deck.ID).Error; err != nil {
-		config.Logger.Errorf("Error retrieving updated deck ID %d: %v", deck.ID, err)
+		config.Logger.Errorf("Error retrieving updated deck ID %s: %v", deck.ID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not reload updated deck"})
 		return
 	}
 
-	config.Logger.Infof("Successfully updated deck ID %d for user %v", deck.ID, userID)
+	config.Logger.Infof("Successfully updated deck ID %s for user %v", deck.ID, userID)
 	c.JSON(http.StatusOK, deck)
 }
 
 // DeleteDeck godoc
 // @Summary      Delete a deck
-// @Description  Delete a specific deck by ID for the logged-in user
+// @Description  Delete a specific deck by ID for the logged-in user. Its sub-decks move up to its parent.
 // @Tags         decks
 // @Accept       json
 // @Produce      json
@@ -296,7 +414,7 @@ func DeleteDeck(c *gin.Context) {
 	var deck models.Deck
 	// Ensure user can only delete their own decks
 	if err := config.GetDB().Where("id = ? AND user_id = ?", deckID, userID).First(&deck).Error; err != nil {
-		config.Logger.Warnf("Deck not found for delete: ID %d, User %v", deckID, userID)
+		config.Logger.Warnf("Deck not found for delete: ID %s, User %v", deckID, userID)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
 		return
 	}
@@ -304,18 +422,24 @@ func DeleteDeck(c *gin.Context) {
 	// Check if deck has cards
 	var cardCount int64
 	if err := config.GetDB().Model(&models.Card{}).Where("deck_id = ?", deckID).Count(&cardCount).Error; err != nil {
-		config.Logger.Errorf("Error checking card count for deck ID %d: %v", deckID, err)
+		config.Logger.Errorf("Error checking card count for deck ID %s: %v", deckID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check deck usage"})
 		return
 	}
 
-	config.Logger.Infof("Deleting deck ID %d for user %v", deckID, userID)
-	if err := config.GetDB().Delete(&deck).Error; err != nil {
-		config.Logger.Errorf("Failed to delete deck ID %d: %v", deckID, err)
+	config.Logger.Infof("Deleting deck ID %s for user %v", deckID, userID)
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Deck{}).Where("parent_id = ?", deck.ID).Update("parent_id", deck.ParentID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&deck).Error
+	})
+	if err != nil {
+		config.Logger.Errorf("Failed to delete deck ID %s: %v", deckID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete deck"})
 		return
 	}
 
-	config.Logger.Infof("Successfully deleted deck ID %d for user %v", deckID, userID)
+	config.Logger.Infof("Successfully deleted deck ID %s for user %v", deckID, userID)
 	c.JSON(http.StatusOK, gin.H{"message": "Deck deleted successfully", "deck": deck})
 }
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/srs"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// hardestCardsShown is how many of a deck's hardest cards analytics list
+const hardestCardsShown = 10
+
+// GetDeckAnalytics godoc
+// @Summary      Deck analytics
+// @Description  Show how well a deck is being learned: true retention over the last days (default 30), split into young and mature cards, the cards due each of the next 30 days, reviews per day over the last year and the most often forgotten cards
+// @Tags         decks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deckID  path      string  true   "Deck ID"
+// @Param        days    query     int     false  "Days to measure retention over (1-365)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/analytics/{deckID} [get]
+func GetDeckAnalytics(c *gin.Context) {
+	deckIDStr := c.Param("deckID")
+	deckID, err := uuid.Parse(deckIDStr)
+	if err != nil {
+		config.Logger.Warnf("Invalid deck ID param for analytics: %s", deckIDStr)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID"})
+		return
+	}
+
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	days := 30
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+			return
+		}
+		days = parsed
+	}
+
+	db := config.GetDB()
+	var deck models.Deck
+	if err := db.Where("id = ? AND user_id = ?", deckID, userID).First(&deck).Error; err != nil {
+		config.Logger.Warnf("Deck ID %s not found for user %v: %v", deckID, userID, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+		return
+	}
+
+	var cards []models.Card
+	if err := db.Where("deck_id = ?", deckID).Find(&cards).Error; err != nil {
+		config.Logger.Errorf("Error fetching cards for deck %s analytics: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not build deck analytics"})
+		return
+	}
+
+	now := time.Now()
+	var reviews []models.CardReview
+	if err := db.Where("deck_id = ? AND reviewed_at >= ?", deckID, now.AddDate(0, 0, -srs.HeatmapDays)).
+		Order("reviewed_at").Find(&reviews).Error; err != nil {
+		config.Logger.Errorf("Error fetching reviews for deck %s analytics: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not build deck analytics"})
+		return
+	}
+
+	states := make([]srs.CardState, len(cards))
+	for i := range cards {
+		states[i] = cards[i].State()
+	}
+	events := make([]srs.ReviewEvent, len(reviews))
+	for i := range reviews {
+		events[i] = reviews[i].Event()
+	}
+
+	analytics := srs.BuildDeckAnalytics(states, events, now.AddDate(0, 0, -days), now, util.GetUserLocation(c), hardestCardsShown)
+	c.JSON(http.StatusOK, gin.H{
+		"deck_id":   deck.ID,
+		"days":      days,
+		"analytics": analytics,
+	})
+}
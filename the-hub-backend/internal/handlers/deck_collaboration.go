@@ -0,0 +1,645 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// deckInviteTTL is how long an invite can be accepted for
+const deckInviteTTL = 14 * 24 * time.Hour
+
+var (
+	errInviteNotPending = errors.New("invite is no longer pending")
+	errInviteExpired    = errors.New("invite has expired")
+)
+
+// maxDeckDepth bounds how far up its parents a deck's access is looked for
+const maxDeckDepth = 32
+
+// deckChainCTE selects, as deck_chain, a deck and the decks above it with their depth
+// from it, nearest first. Its arguments are the deck's ID and maxDeckDepth.
+const deckChainCTE = `WITH RECURSIVE deck_chain AS (
+		SELECT id, parent_id, is_public, 0 AS depth FROM decks WHERE id = ? AND deleted_at IS NULL
+		UNION
+		SELECT decks.id, decks.parent_id, decks.is_public, deck_chain.depth + 1 FROM decks JOIN deck_chain ON decks.id = deck_chain.parent_id
+		WHERE decks.deleted_at IS NULL AND deck_chain.depth < ?
+	) `
+
+// deckRole returns the user's role in a deck: DeckRoleOwner for their own decks,
+// their collaborator role for decks shared with them, or "" when they have no access.
+// A role in a deck covers its sub-decks, so the nearest deck up the tree the user has
+// a role in decides it. Subscribers lose access when the deck is taken out of the library.
+func deckRole(db *gorm.DB, deck *models.Deck, userID uuid.UUID) (string, error) {
+	if deck.UserID == userID {
+		return models.DeckRoleOwner, nil
+	}
+	var members []struct {
+		Role     string
+		IsPublic bool
+	}
+	err := db.Raw(deckChainCTE+`SELECT deck_users.role, deck_chain.is_public FROM deck_chain
+		JOIN deck_users ON deck_users.deck_id = deck_chain.id AND deck_users.user_id = ?
+		ORDER BY deck_chain.depth LIMIT 1`, deck.ID, maxDeckDepth, userID).Scan(&members).Error
+	if err != nil {
+		return "", err
+	}
+	if len(members) == 0 {
+		return "", nil
+	}
+	if members[0].Role == models.DeckRoleSubscriber && !members[0].IsPublic {
+		return "", nil
+	}
+	return members[0].Role, nil
+}
+
+// accessibleDeck loads a deck the user has at least the required role in, with
+// Role set, writing the error response otherwise: 404 when they have no access to
+// the deck, 403 when their role does not allow the action
+func accessibleDeck(c *gin.Context, deckID, userID uuid.UUID, required string) (*models.Deck, bool) {
+	var deck models.Deck
+	err := config.GetDB().Where("id = ?", deckID).First(&deck).Error
+	if err == nil {
+		deck.Role, err = deckRole(config.GetDB(), &deck, userID)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && deck.Role == "") {
+		config.Logger.Warnf("Deck ID %s not found for user %s", deckID, userID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+		return nil, false
+	}
+	if err != nil {
+		config.Logger.Errorf("Error fetching deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch deck"})
+		return nil, false
+	}
+	if !models.DeckRoleAllows(deck.Role, required) {
+		config.Logger.Warnf("User %s with role %s in deck %s needs role %s", userID, deck.Role, deckID, required)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role in this deck does not allow this", "role": deck.Role})
+		return nil, false
+	}
+	return &deck, true
+}
+
+// accessibleCard loads a card, with its deck, from a deck the user has at least the
+// required role in, writing the error response otherwise. The card carries the
+// user's own review state.
+func accessibleCard(c *gin.Context, cardID, userID uuid.UUID, required string) (*models.Card, bool) {
+	var card models.Card
+	err := config.GetDB().Preload("Deck").Where("id = ?", cardID).First(&card).Error
+	if err == nil {
+		card.Deck.Role, err = deckRole(config.GetDB(), &card.Deck, userID)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && card.Deck.Role == "") {
+		config.Logger.Warnf("Card ID %s not found for user %s", cardID, userID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return nil, false
+	}
+	if err != nil {
+		config.Logger.Errorf("Error fetching card %s: %v", cardID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch card"})
+		return nil, false
+	}
+	if !models.DeckRoleAllows(card.Deck.Role, required) {
+		config.Logger.Warnf("User %s with role %s in deck %s needs role %s", userID, card.Deck.Role, card.DeckID, required)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role in this deck does not allow this", "role": card.Deck.Role})
+		return nil, false
+	}
+
+	cards := []models.Card{card}
+	if err := withUserState(config.GetDB(), cards, userID, card.Deck.Role); err != nil {
+		config.Logger.Errorf("Error fetching review state of card %s for user %s: %v", cardID, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch card"})
+		return nil, false
+	}
+	return &cards[0], true
+}
+
+// userCardsQuery selects a deck's cards joined, for collaborators, with their own
+// review state, so that it can be filtered and ordered on with userCardColumn
+func userCardsQuery(db *gorm.DB, deckID, userID uuid.UUID, role string) *gorm.DB {
+	return userDecksCardsQuery(db, []uuid.UUID{deckID}, userID, role)
+}
+
+// userCardColumn names a card column in a userCardsQuery, taking review state
+// columns from the collaborator's progress, as a card they never studied has it
+func userCardColumn(column, role string) string {
+	if role == models.DeckRoleOwner {
+		return "cards." + column
+	}
+	switch column {
+	case "easiness":
+		return "COALESCE(card_progress.easiness, 2.5)"
+	case "interval":
+		return `COALESCE(card_progress."interval", 1)`
+	case "next_review":
+		return "COALESCE(card_progress.next_review, cards.created_at)"
+	case "suspended":
+		return "COALESCE(card_progress.suspended, false)"
+	case "buried_until":
+		return "card_progress.buried_until"
+	}
+	return "cards." + column
+}
+
+// withUserState replaces the review state of cards with the user's own when they
+// are a collaborator rather than the owner of the deck
+func withUserState(db *gorm.DB, cards []models.Card, userID uuid.UUID, role string) error {
+	if role == models.DeckRoleOwner || len(cards) == 0 {
+		return nil
+	}
+	cardIDs := make([]uuid.UUID, len(cards))
+	for i, card := range cards {
+		cardIDs[i] = card.ID
+	}
+	var progress []models.CardProgress
+	if err := db.Where("user_id = ? AND card_id IN ?", userID, cardIDs).Find(&progress).Error; err != nil {
+		return err
+	}
+	byCard := make(map[uuid.UUID]*models.CardProgress, len(progress))
+	for i := range progress {
+		byCard[progress[i].CardID] = &progress[i]
+	}
+	for i := range cards {
+		cards[i].ApplyProgress(byCard[cards[i].ID])
+	}
+	return nil
+}
+
+// saveUserState stores a card's review state as the user's: on the card for the
+// deck's owner, as their progress for a collaborator
+func saveUserState(tx *gorm.DB, card *models.Card, userID uuid.UUID, role string) error {
+	if role == models.DeckRoleOwner {
+		return tx.Omit("Deck").Save(card).Error
+	}
+	progress := models.NewCardProgress(card, userID)
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "card_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"easiness", "interval", "repetitions", "stability", "difficulty",
+			"lapses", "suspended", "buried_until", "last_reviewed", "next_review", "updated_at"}),
+	}).Create(&progress).Error
+}
+
+// CreateDeckInviteRequest represents the request body for inviting a collaborator
+type CreateDeckInviteRequest struct {
+	Email string `json:"email" binding:"required,email" example:"friend@example.com"`
+	Role  string `json:"role" binding:"required,oneof=editor viewer" example:"viewer"`
+}
+
+// UpdateCollaboratorRequest represents the request body for changing a collaborator's role
+type UpdateCollaboratorRequest struct {
+	Role string `json:"role" binding:"required,oneof=editor viewer" example:"editor"`
+}
+
+// GetDeckCollaborators godoc
+// @Summary      Get a deck's collaborators
+// @Description  List the owner, collaborators and pending invites of a deck the user owns or collaborates on. Library subscribers are not listed.
+// @Tags         decks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deckID  path      string  true  "Deck ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/collaborators/{deckID} [get]
+func GetDeckCollaborators(c *gin.Context) {
+	deckID, err := uuid.Parse(c.Param("deckID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID"})
+		return
+	}
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	deck, ok := accessibleDeck(c, deckID, userID.(uuid.UUID), models.DeckRoleViewer)
+	if !ok {
+		return
+	}
+
+	db := config.GetDB()
+	var owner models.User
+	if err := db.First(&owner, "id = ?", deck.UserID).Error; err != nil {
+		config.Logger.Errorf("Error fetching owner of deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch collaborators"})
+		return
+	}
+	var collaborators []models.DeckUser
+	if err := db.Preload("User").Where("deck_id = ? AND role <> ?", deckID, models.DeckRoleSubscriber).
+		Order("created_at").Find(&collaborators).Error; err != nil {
+		config.Logger.Errorf("Error fetching collaborators of deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch collaborators"})
+		return
+	}
+
+	response := gin.H{"owner": owner, "collaborators": collaborators, "role": deck.Role}
+	if deck.Role == models.DeckRoleOwner {
+		var invites []models.DeckInvite
+		if err := db.Preload("Invitee").
+			Where("deck_id = ? AND status = ? AND expires_at > ?", deckID, models.DeckInvitePending, time.Now()).
+			Order("created_at").Find(&invites).Error; err != nil {
+			config.Logger.Errorf("Error fetching invites of deck %s: %v", deckID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch collaborators"})
+			return
+		}
+		response["invites"] = invites
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateDeckInvite godoc
+// @Summary      Invite a collaborator to a deck
+// @Description  Invite another user, by email, to collaborate on one of the user's decks as an editor (can change cards) or a viewer (can study them). Collaborators keep their own review schedule.
+// @Tags         decks
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deckID  path      string                   true  "Deck ID"
+// @Param        invite  body      CreateDeckInviteRequest  true  "Invitee and role"
+// @Success      201  {object}  models.DeckInvite
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/invites/{deckID} [post]
+func CreateDeckInvite(c *gin.Context) {
+	deckID, err := uuid.Parse(c.Param("deckID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID"})
+		return
+	}
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	var input CreateDeckInviteRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid deck invite input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	deck, ok := accessibleDeck(c, deckID, userIDUUID, models.DeckRoleOwner)
+	if !ok {
+		return
+	}
+
+	db := config.GetDB()
+	var invitee models.User
+	if err := db.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(input.Email))).First(&invitee).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No user with that email"})
+		return
+	}
+	if invitee.ID == deck.UserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You already own this deck"})
+		return
+	}
+	// Subscribers can be invited to take part in the deck
+	if role, err := deckRole(db, deck, invitee.ID); err == nil && role != "" && role != models.DeckRoleSubscriber {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already collaborates on this deck", "role": role})
+		return
+	}
+	var pending int64
+	if err := db.Model(&models.DeckInvite{}).
+		Where("deck_id = ? AND invitee_id = ? AND status = ? AND expires_at > ?", deckID, invitee.ID, models.DeckInvitePending, time.Now()).
+		Count(&pending).Error; err != nil {
+		config.Logger.Errorf("Error checking invites of deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not invite collaborator"})
+		return
+	}
+	if pending > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already has a pending invite to this deck"})
+		return
+	}
+
+	invite := models.DeckInvite{
+		DeckID:    deckID,
+		InviterID: userIDUUID,
+		InviteeID: invitee.ID,
+		Role:      input.Role,
+		Status:    models.DeckInvitePending,
+		ExpiresAt: time.Now().Add(deckInviteTTL),
+	}
+	if err := db.Create(&invite).Error; err != nil {
+		config.Logger.Errorf("Error creating invite to deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not invite collaborator"})
+		return
+	}
+	invite.Invitee = &invitee
+
+	config.Logger.Infof("User %s invited user %s to deck %s as %s", userIDUUID, invitee.ID, deckID, input.Role)
+	c.JSON(http.StatusCreated, invite)
+}
+
+// GetDeckInvites godoc
+// @Summary      Get deck invites
+// @Description  List the user's pending invites to collaborate on other users' decks
+// @Tags         decks
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string][]models.DeckInvite
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /deck-invites [get]
+func GetDeckInvites(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var invites []models.DeckInvite
+	if err := config.GetDB().Preload("Deck").Preload("Inviter").
+		Where("invitee_id = ? AND status = ? AND expires_at > ?", userID, models.DeckInvitePending, time.Now()).
+		Order("created_at DESC").Find(&invites).Error; err != nil {
+		config.Logger.Errorf("Error fetching deck invites for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch invites"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invites": invites})
+}
+
+// respondToInvite accepts or declines one of the user's pending invites
+func respondToInvite(c *gin.Context, accept bool) {
+	inviteID, err := uuid.Parse(c.Param("ID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invite ID"})
+		return
+	}
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var invite models.DeckInvite
+	var member models.DeckUser
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND invitee_id = ?", inviteID, userID).First(&invite).Error; err != nil {
+			return err
+		}
+		if invite.Status != models.DeckInvitePending {
+			return errInviteNotPending
+		}
+		if time.Now().After(invite.ExpiresAt) {
+			return errInviteExpired
+		}
+
+		now := time.Now()
+		invite.Status = models.DeckInviteDeclined
+		if accept {
+			invite.Status = models.DeckInviteAccepted
+			member = models.DeckUser{DeckID: invite.DeckID, UserID: invite.InviteeID, Role: invite.Role}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "deck_id"}, {Name: "user_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+			}).Create(&member).Error; err != nil {
+				return err
+			}
+		}
+		invite.RespondedAt = &now
+		return tx.Model(&invite).Select("status", "responded_at").Updates(&invite).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
+		return
+	case errors.Is(err, errInviteNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "Invite was already " + invite.Status})
+		return
+	case errors.Is(err, errInviteExpired):
+		c.JSON(http.StatusGone, gin.H{"error": "Invite has expired"})
+		return
+	case err != nil:
+		config.Logger.Errorf("Error responding to deck invite %s: %v", inviteID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not respond to invite"})
+		return
+	}
+
+	config.Logger.Infof("User %v %s invite %s to deck %s", userID, invite.Status, inviteID, invite.DeckID)
+	response := gin.H{"invite": invite}
+	if accept {
+		response["collaborator"] = member
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AcceptDeckInvite godoc
+// @Summary      Accept a deck invite
+// @Description  Join a deck as a collaborator with the role the invite offers
+// @Tags         decks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Invite ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      410  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /deck-invites/{ID}/accept [post]
+func AcceptDeckInvite(c *gin.Context) {
+	respondToInvite(c, true)
+}
+
+// DeclineDeckInvite godoc
+// @Summary      Decline a deck invite
+// @Description  Decline an invite to collaborate on a deck
+// @Tags         decks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Invite ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      410  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /deck-invites/{ID}/decline [post]
+func DeclineDeckInvite(c *gin.Context) {
+	respondToInvite(c, false)
+}
+
+// RevokeDeckInvite godoc
+// @Summary      Revoke a deck invite
+// @Description  Withdraw a pending invite to one of the user's decks
+// @Tags         decks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Invite ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /deck-invites/{ID} [delete]
+func RevokeDeckInvite(c *gin.Context) {
+	inviteID, err := uuid.Parse(c.Param("ID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invite ID"})
+		return
+	}
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var invite models.DeckInvite
+	if err := config.GetDB().Joins("JOIN decks ON deck_invites.deck_id = decks.id").
+		Where("deck_invites.id = ? AND decks.user_id = ?", inviteID, userID).
+		First(&invite).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
+		return
+	}
+	if invite.Status != models.DeckInvitePending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Invite was already " + invite.Status})
+		return
+	}
+	if err := config.GetDB().Model(&invite).Update("status", models.DeckInviteRevoked).Error; err != nil {
+		config.Logger.Errorf("Error revoking deck invite %s: %v", inviteID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not revoke invite"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Invite revoked"})
+}
+
+// UpdateDeckCollaborator godoc
+// @Summary      Change a collaborator's role
+// @Description  Make a collaborator on one of the user's decks an editor or a viewer
+// @Tags         decks
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deckID  path      string                     true  "Deck ID"
+// @Param        userID  path      string                     true  "Collaborator's user ID"
+// @Param        role    body      UpdateCollaboratorRequest  true  "New role"
+// @Success      200  {object}  models.DeckUser
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/collaborators/{deckID}/{userID} [patch]
+func UpdateDeckCollaborator(c *gin.Context) {
+	deckID, err := uuid.Parse(c.Param("deckID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID"})
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input UpdateCollaboratorRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if _, ok := accessibleDeck(c, deckID, userID.(uuid.UUID), models.DeckRoleOwner); !ok {
+		return
+	}
+
+	var member models.DeckUser
+	if err := config.GetDB().Preload("User").Where("deck_id = ? AND user_id = ?", deckID, memberID).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collaborator not found"})
+		return
+	}
+	if err := config.GetDB().Model(&member).Update("role", input.Role).Error; err != nil {
+		config.Logger.Errorf("Error updating collaborator %s of deck %s: %v", memberID, deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update collaborator"})
+		return
+	}
+
+	config.Logger.Infof("Collaborator %s of deck %s is now %s", memberID, deckID, input.Role)
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveDeckCollaborator godoc
+// @Summary      Remove a collaborator
+// @Description  Remove a collaborator from one of the user's decks, or leave a deck shared with the user. Their review state is kept in case they rejoin.
+// @Tags         decks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deckID  path      string  true  "Deck ID"
+// @Param        userID  path      string  true  "Collaborator's user ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/collaborators/{deckID}/{userID} [delete]
+func RemoveDeckCollaborator(c *gin.Context) {
+	deckID, err := uuid.Parse(c.Param("deckID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID"})
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	// Collaborators may leave; only the owner may remove others
+	required := models.DeckRoleOwner
+	if memberID == userIDUUID {
+		required = models.DeckRoleViewer
+	}
+	if _, ok := accessibleDeck(c, deckID, userIDUUID, required); !ok {
+		return
+	}
+
+	result := config.GetDB().Where("deck_id = ? AND user_id = ?", deckID, memberID).Delete(&models.DeckUser{})
+	if result.Error != nil {
+		config.Logger.Errorf("Error removing collaborator %s from deck %s: %v", memberID, deckID, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not remove collaborator"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collaborator not found"})
+		return
+	}
+
+	config.Logger.Infof("User %s removed collaborator %s from deck %s", userIDUUID, memberID, deckID)
+	c.JSON(http.StatusOK, gin.H{"message": "Collaborator removed"})
+}
@@ -0,0 +1,722 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// libraryPreviewCards is how many of a library deck's cards are shown before cloning
+// or subscribing to it
+const libraryPreviewCards = 20
+
+var errDeckNameTaken = errors.New("deck name already exists")
+
+// librarySorts are the orders the deck library can be browsed in
+var librarySorts = map[string]string{
+	"popular": "subscriber_count DESC, rating_count DESC, decks.created_at DESC",
+	"rating":  "rating_average DESC, rating_count DESC, decks.created_at DESC",
+	"newest":  "decks.created_at DESC",
+	"cards":   "card_count DESC, decks.created_at DESC",
+}
+
+// publicDecksQuery selects public decks with their owner's name, card count,
+// ratings, subscriber count and whether the user subscribes to them
+func publicDecksQuery(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return db.Model(&models.Deck{}).
+		Select(`decks.*, users.name AS owner_name,
+			(SELECT COUNT(*) FROM cards WHERE cards.deck_id = decks.id AND cards.deleted_at IS NULL) AS card_count,
+			COALESCE((SELECT AVG(stars) FROM deck_ratings WHERE deck_ratings.deck_id = decks.id), 0) AS rating_average,
+			(SELECT COUNT(*) FROM deck_ratings WHERE deck_ratings.deck_id = decks.id) AS rating_count,
+			(SELECT COUNT(*) FROM deck_users WHERE deck_users.deck_id = decks.id AND deck_users.role = ?) AS subscriber_count,
+			EXISTS (SELECT 1 FROM deck_users WHERE deck_users.deck_id = decks.id AND deck_users.user_id = ? AND deck_users.role = ?) AS subscribed`,
+			models.DeckRoleSubscriber, userID, models.DeckRoleSubscriber).
+		Joins("JOIN users ON users.id = decks.user_id").
+		Where("decks.is_public")
+}
+
+// libraryDeck loads a deck in the public library, writing a 404 response otherwise
+func libraryDeck(c *gin.Context, deckID uuid.UUID) (*models.Deck, bool) {
+	var deck models.Deck
+	if err := config.GetDB().Where("id = ? AND is_public", deckID).First(&deck).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			config.Logger.Errorf("Error fetching library deck %s: %v", deckID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch deck"})
+			return nil, false
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found in the library"})
+		return nil, false
+	}
+	return &deck, true
+}
+
+// libraryRequest reads the deck ID in the path and the authenticated user, writing
+// the error response when either is missing
+func libraryRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	deckID, err := uuid.Parse(c.Param("deckID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return deckID, userID.(uuid.UUID), true
+}
+
+// LibraryCard is a card as previewed in the deck library, without review state
+type LibraryCard struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	CardType string `json:"card_type"`
+}
+
+// CloneDeckRequest represents the request body for cloning a library deck
+type CloneDeckRequest struct {
+	Name string `json:"name" example:"Spanish Vocabulary (mine)"`
+}
+
+// RateDeckRequest represents the request body for rating a library deck
+type RateDeckRequest struct {
+	Stars  int    `json:"stars" binding:"required,min=1,max=5" example:"5"`
+	Review string `json:"review" binding:"max=2000" example:"Clear cards with good examples"`
+}
+
+// ReportDeckRequest represents the request body for reporting a library deck
+type ReportDeckRequest struct {
+	Reason  string `json:"reason" binding:"required,oneof=spam offensive copyright misleading other" example:"spam"`
+	Details string `json:"details" binding:"max=2000" example:"Every card links to the same shop"`
+}
+
+// UpdateDeckReportRequest represents an admin's resolution of a deck report
+type UpdateDeckReportRequest struct {
+	Status        string `json:"status" binding:"omitempty,oneof=pending reviewed actioned dismissed" example:"actioned"`
+	AdminResponse string `json:"admin_response" example:"The deck was removed from the library"`
+	Unpublish     bool   `json:"unpublish" example:"true"` // Take the deck out of the library
+}
+
+// GetPublicDecks godoc
+// @Summary      Browse the public deck library
+// @Description  Search decks their owners made public, with card counts, ratings and subscriber counts
+// @Tags         deck library
+// @Produce      json
+// @Security     BearerAuth
+// @Param        search  query     string  false  "Search in name and description"
+// @Param        sort    query     string  false  "popular, rating, newest or cards"  default(popular)
+// @Param        page    query     int     false  "Page number"  default(1)
+// @Param        limit   query     int     false  "Decks per page"  default(20)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/library [get]
+func GetPublicDecks(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	order, valid := librarySorts[c.DefaultQuery("sort", "popular")]
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort. Use popular, rating, newest or cards"})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	db := config.GetDB()
+	filter := func(query *gorm.DB) *gorm.DB {
+		if search := strings.TrimSpace(c.Query("search")); search != "" {
+			searchTerm := "%" + search + "%"
+			query = query.Where("(decks.name ILIKE ? OR decks.description ILIKE ?)", searchTerm, searchTerm)
+		}
+		return query
+	}
+
+	var total int64
+	if err := filter(db.Model(&models.Deck{}).Where("decks.is_public")).Count(&total).Error; err != nil {
+		config.Logger.Errorf("Error counting library decks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve library decks"})
+		return
+	}
+	var decks []models.PublicDeck
+	if err := filter(publicDecksQuery(db, userID.(uuid.UUID))).
+		Order(order).Offset((page - 1) * limit).Limit(limit).Scan(&decks).Error; err != nil {
+		config.Logger.Errorf("Error fetching library decks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve library decks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"decks": decks,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetPublicDeck godoc
+// @Summary      Get a library deck
+// @Description  Fetch a public deck with a preview of its cards, its latest reviews and the user's own rating
+// @Tags         deck library
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deckID  path      string  true  "Deck ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/library/{deckID} [get]
+func GetPublicDeck(c *gin.Context) {
+	deckID, userID, ok := libraryRequest(c)
+	if !ok {
+		return
+	}
+
+	db := config.GetDB()
+	var deck models.PublicDeck
+	result := publicDecksQuery(db, userID).Where("decks.id = ?", deckID).Limit(1).Scan(&deck)
+	if result.Error != nil {
+		config.Logger.Errorf("Error fetching library deck %s: %v", deckID, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch deck"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found in the library"})
+		return
+	}
+
+	var cards []LibraryCard
+	if err := db.Model(&models.Card{}).Select("question, answer, card_type").
+		Where("deck_id = ?", deckID).Order("created_at").Limit(libraryPreviewCards).
+		Scan(&cards).Error; err != nil {
+		config.Logger.Errorf("Error fetching preview cards of deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch deck"})
+		return
+	}
+	var ratings []models.DeckRating
+	if err := db.Model(&models.DeckRating{}).Select("deck_ratings.*, users.name AS user_name").
+		Joins("JOIN users ON users.id = deck_ratings.user_id").
+		Where("deck_ratings.deck_id = ? AND deck_ratings.review <> ''", deckID).
+		Order("deck_ratings.updated_at DESC").Limit(10).Find(&ratings).Error; err != nil {
+		config.Logger.Errorf("Error fetching ratings of deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch deck"})
+		return
+	}
+
+	response := gin.H{"deck": deck, "cards": cards, "reviews": ratings}
+	var own models.DeckRating
+	if err := db.Where("deck_id = ? AND user_id = ?", deckID, userID).First(&own).Error; err == nil {
+		response["my_rating"] = own
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CloneDeck godoc
+// @Summary      Clone a library deck
+// @Description  Copy a public deck, or one shared with the user, into a new deck of their own. The copy's cards start unreviewed and later changes to the original do not reach it.
+// @Tags         deck library
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deckID  path      string            true   "Deck ID"
+// @Param        deck    body      CloneDeckRequest  false  "Name for the copy, the original's by default"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/library/{deckID}/clone [post]
+func CloneDeck(c *gin.Context) {
+	deckID, userID, ok := libraryRequest(c)
+	if !ok {
+		return
+	}
+	var input CloneDeckRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+			return
+		}
+	}
+
+	db := config.GetDB()
+	var source models.Deck
+	if err := db.Where("id = ?", deckID).First(&source).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+		return
+	}
+	if !source.IsPublic {
+		// Decks shared with the user can be copied too
+		if _, ok := accessibleDeck(c, deckID, userID, models.DeckRoleViewer); !ok {
+			return
+		}
+	}
+
+	clonedFrom := source.ID
+	deck := models.Deck{
+		Name:             strings.TrimSpace(input.Name),
+		Description:      source.Description,
+		UserID:           userID,
+		ClonedFromID:     &clonedFrom,
+		Scheduler:        source.Scheduler,
+		DesiredRetention: source.DesiredRetention,
+		LeechThreshold:   source.LeechThreshold,
+	}
+	if deck.Name == "" {
+		deck.Name = source.Name
+	}
+
+	var cardCount int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.Deck{}).Where("name = ? AND user_id = ?", deck.Name, userID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return errDeckNameTaken
+		}
+		if err := tx.Create(&deck).Error; err != nil {
+			return err
+		}
+
+		var notes []models.CardNote
+		if err := tx.Where("deck_id = ?", source.ID).Order("created_at").Find(&notes).Error; err != nil {
+			return err
+		}
+		for _, original := range notes {
+			note := models.CardNote{DeckID: deck.ID, Model: original.Model, Front: original.Front, Back: original.Back, Extra: original.Extra}
+			if err := tx.Create(&note).Error; err != nil {
+				return err
+			}
+			cards, err := syncNoteCards(tx, &note)
+			if err != nil {
+				return err
+			}
+			cardCount += len(cards)
+		}
+
+		var originals []models.Card
+		if err := tx.Where("deck_id = ? AND note_id IS NULL", source.ID).Order("created_at").Find(&originals).Error; err != nil {
+			return err
+		}
+		if len(originals) == 0 {
+			return nil
+		}
+		now := time.Now()
+		cards := make([]models.Card, len(originals))
+		for i, original := range originals {
+			cards[i] = models.Card{
+				DeckID:     deck.ID,
+				Question:   original.Question,
+				Answer:     original.Answer,
+				CardType:   original.CardType,
+				Easiness:   2.5,
+				Interval:   1,
+				NextReview: now,
+			}
+		}
+		cardCount += len(cards)
+		return tx.CreateInBatches(&cards, 500).Error
+	})
+	if errors.Is(err, errDeckNameTaken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Deck name already exists"})
+		return
+	}
+	if err != nil {
+		config.Logger.Errorf("Error cloning deck %s for user %s: %v", deckID, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not clone deck"})
+		return
+	}
+
+	config.Logger.Infof("User %s cloned deck %s into deck %s with %d cards", userID, deckID, deck.ID, cardCount)
+	deck.Role = models.DeckRoleOwner
+	c.JSON(http.StatusCreated, gin.H{"deck": deck, "card_count": cardCount})
+}
+
+// SubscribeDeck godoc
+// @Summary      Subscribe to a library deck
+// @Description  Study a public deck on the user's own schedule while its owner keeps editing it. The deck appears among the user's decks for as long as it stays public.
+// @Tags         deck library
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deckID  path      string  true  "Deck ID"
+// @Success      201  {object}  models.DeckUser
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/library/{deckID}/subscribe [post]
+func SubscribeDeck(c *gin.Context) {
+	deckID, userID, ok := libraryRequest(c)
+	if !ok {
+		return
+	}
+	deck, ok := libraryDeck(c, deckID)
+	if !ok {
+		return
+	}
+
+	db := config.GetDB()
+	role, err := deckRole(db, deck, userID)
+	if err != nil {
+		config.Logger.Errorf("Error fetching role of user %s in deck %s: %v", userID, deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not subscribe to deck"})
+		return
+	}
+	switch role {
+	case models.DeckRoleOwner:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You own this deck"})
+		return
+	case models.DeckRoleSubscriber:
+		c.JSON(http.StatusConflict, gin.H{"error": "You already subscribe to this deck"})
+		return
+	case models.DeckRoleEditor, models.DeckRoleViewer:
+		c.JSON(http.StatusConflict, gin.H{"error": "You already collaborate on this deck", "role": role})
+		return
+	}
+
+	member := models.DeckUser{DeckID: deckID, UserID: userID, Role: models.DeckRoleSubscriber}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error; err != nil {
+		config.Logger.Errorf("Error subscribing user %s to deck %s: %v", userID, deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not subscribe to deck"})
+		return
+	}
+
+	config.Logger.Infof("User %s subscribed to deck %s", userID, deckID)
+	c.JSON(http.StatusCreated, member)
+}
+
+// UnsubscribeDeck godoc
+// @Summary      Unsubscribe from a library deck
+// @Description  Stop studying a subscribed deck. The user's review state is kept in case they subscribe again.
+// @Tags         deck library
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deckID  path      string  true  "Deck ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/library/{deckID}/subscribe [delete]
+func UnsubscribeDeck(c *gin.Context) {
+	deckID, userID, ok := libraryRequest(c)
+	if !ok {
+		return
+	}
+
+	result := config.GetDB().Where("deck_id = ? AND user_id = ? AND role = ?", deckID, userID, models.DeckRoleSubscriber).
+		Delete(&models.DeckUser{})
+	if result.Error != nil {
+		config.Logger.Errorf("Error unsubscribing user %s from deck %s: %v", userID, deckID, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not unsubscribe from deck"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You do not subscribe to this deck"})
+		return
+	}
+
+	config.Logger.Infof("User %s unsubscribed from deck %s", userID, deckID)
+	c.JSON(http.StatusOK, gin.H{"message": "Unsubscribed from deck"})
+}
+
+// RateDeck godoc
+// @Summary      Rate a library deck
+// @Description  Give a public deck 1 to 5 stars with an optional review, replacing the user's earlier rating
+// @Tags         deck library
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deckID  path      string           true  "Deck ID"
+// @Param        rating  body      RateDeckRequest  true  "Stars and review"
+// @Success      200  {object}  models.DeckRating
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/library/{deckID}/rating [put]
+func RateDeck(c *gin.Context) {
+	deckID, userID, ok := libraryRequest(c)
+	if !ok {
+		return
+	}
+	var input RateDeckRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	deck, ok := libraryDeck(c, deckID)
+	if !ok {
+		return
+	}
+	if deck.UserID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot rate your own deck"})
+		return
+	}
+
+	rating := models.DeckRating{DeckID: deckID, UserID: userID, Stars: input.Stars, Review: strings.TrimSpace(input.Review)}
+	if err := config.GetDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "deck_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"stars", "review", "updated_at"}),
+	}).Create(&rating).Error; err != nil {
+		config.Logger.Errorf("Error rating deck %s for user %s: %v", deckID, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not rate deck"})
+		return
+	}
+
+	config.Logger.Infof("User %s rated deck %s %d stars", userID, deckID, input.Stars)
+	c.JSON(http.StatusOK, rating)
+}
+
+// DeleteDeckRating godoc
+// @Summary      Remove a deck rating
+// @Description  Withdraw the user's rating of a library deck
+// @Tags         deck library
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deckID  path      string  true  "Deck ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/library/{deckID}/rating [delete]
+func DeleteDeckRating(c *gin.Context) {
+	deckID, userID, ok := libraryRequest(c)
+	if !ok {
+		return
+	}
+
+	result := config.GetDB().Where("deck_id = ? AND user_id = ?", deckID, userID).Delete(&models.DeckRating{})
+	if result.Error != nil {
+		config.Logger.Errorf("Error deleting rating of deck %s for user %s: %v", deckID, userID, result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not remove rating"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rating not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Rating removed"})
+}
+
+// ReportDeck godoc
+// @Summary      Report a library deck
+// @Description  Report a public deck for spam, offensive or misleading content or copyright infringement. Admins review reports and can take the deck out of the library.
+// @Tags         deck library
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deckID  path      string             true  "Deck ID"
+// @Param        report  body      ReportDeckRequest  true  "Reason and details"
+// @Success      201  {object}  models.DeckReport
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /decks/library/{deckID}/reports [post]
+func ReportDeck(c *gin.Context) {
+	deckID, userID, ok := libraryRequest(c)
+	if !ok {
+		return
+	}
+	var input ReportDeckRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	deck, ok := libraryDeck(c, deckID)
+	if !ok {
+		return
+	}
+	if deck.UserID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot report your own deck"})
+		return
+	}
+
+	db := config.GetDB()
+	var pending int64
+	if err := db.Model(&models.DeckReport{}).
+		Where("deck_id = ? AND reporter_id = ? AND status = ?", deckID, userID, models.DeckReportPending).
+		Count(&pending).Error; err != nil {
+		config.Logger.Errorf("Error checking reports of deck %s: %v", deckID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not report deck"})
+		return
+	}
+	if pending > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already reported this deck"})
+		return
+	}
+
+	report := models.DeckReport{
+		DeckID:     deckID,
+		ReporterID: userID,
+		Reason:     input.Reason,
+		Details:    strings.TrimSpace(input.Details),
+		Status:     models.DeckReportPending,
+	}
+	if err := db.Create(&report).Error; err != nil {
+		config.Logger.Errorf("Error reporting deck %s for user %s: %v", deckID, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not report deck"})
+		return
+	}
+
+	config.Logger.Infof("User %s reported deck %s for %s", userID, deckID, input.Reason)
+	c.JSON(http.StatusCreated, report)
+}
+
+// GetDeckReports godoc
+// @Summary      Get deck reports (admin only)
+// @Description  Fetch abuse reports on library decks, newest first, with pagination and a status filter
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        status  query     string  false  "pending, reviewed, actioned or dismissed"
+// @Param        page    query     int     false  "Page number"  default(1)
+// @Param        limit   query     int     false  "Reports per page"  default(10)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/deck-reports [get]
+func GetDeckReports(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	query := config.GetDB().Model(&models.DeckReport{})
+	if status := c.Query("status"); status != "" {
+		validStatuses := map[string]bool{
+			models.DeckReportPending: true, models.DeckReportReviewed: true,
+			models.DeckReportActioned: true, models.DeckReportDismissed: true,
+		}
+		if validStatuses[status] {
+			query = query.Where("status = ?", status)
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		config.Logger.Errorf("Failed to count deck reports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve deck reports"})
+		return
+	}
+	var reports []models.DeckReport
+	if err := query.Preload("Deck", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).Preload("Reporter").
+		Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&reports).Error; err != nil {
+		config.Logger.Errorf("Failed to retrieve deck reports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve deck reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports": reports,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// UpdateDeckReport godoc
+// @Summary      Resolve a deck report (admin only)
+// @Description  Set a deck report's status and response, optionally taking the deck out of the library, which cuts its subscribers off from it
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path      string                   true  "Report ID"
+// @Param        report  body      UpdateDeckReportRequest  true  "Resolution"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/deck-reports/{id} [patch]
+func UpdateDeckReport(c *gin.Context) {
+	reportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+	var input UpdateDeckReportRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid update data", "details": err.Error()})
+		return
+	}
+	if input.Unpublish && input.Status == "" {
+		input.Status = models.DeckReportActioned
+	}
+
+	var report models.DeckReport
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&report, "id = ?", reportID).Error; err != nil {
+			return err
+		}
+		updates := map[string]interface{}{}
+		if input.Status != "" {
+			updates["status"] = input.Status
+			if input.Status == models.DeckReportPending {
+				updates["resolved_at"] = nil
+			} else {
+				updates["resolved_at"] = time.Now()
+			}
+		}
+		if input.AdminResponse != "" {
+			updates["admin_response"] = input.AdminResponse
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&report).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if input.Unpublish {
+			return tx.Model(&models.Deck{}).Where("id = ?", report.DeckID).Update("is_public", false).Error
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
+	if err != nil {
+		config.Logger.Errorf("Failed to update deck report %s: %v", reportID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update deck report"})
+		return
+	}
+
+	config.Logger.Infof("Deck report %s updated: status %s, unpublished %t", reportID, report.Status, input.Unpublish)
+	c.JSON(http.StatusOK, gin.H{"message": "Deck report updated successfully", "report": report})
+}
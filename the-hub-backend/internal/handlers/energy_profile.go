@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TheoMKgosi/The-hub/internal/ai"
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UpdateEnergyProfileRequest changes the manual settings of an energy profile.
+// Omitted fields are left as they are.
+type UpdateEnergyProfileRequest struct {
+	TimeSlots          map[string]int `json:"time_slots"` // hour (0-23) -> energy level (1-10)
+	Workload           map[string]int `json:"workload"`   // day -> workload score (1-10)
+	WorkDays           []string       `json:"work_days"`
+	PreferredStartHour *int           `json:"preferred_start_hour"`
+	PreferredEndHour   *int           `json:"preferred_end_hour"`
+	BreakDuration      *int           `json:"break_duration"`
+	LearningEnabled    *bool          `json:"learning_enabled"`
+}
+
+// energyProfileResponse shows the profile used for scheduling next to what it is made of
+func energyProfileResponse(userID uuid.UUID, stored *models.EnergyProfile) gin.H {
+	return gin.H{
+		"energy_profile": ai.BuildEnergyProfile(userID, stored),
+		"settings":       stored.Settings(),
+		"learned":        stored.Learned(),
+	}
+}
+
+// GetEnergyProfile godoc
+// @Summary      Get energy profile
+// @Description  Get the energy profile used to schedule tasks, with the user's settings and what was learned from completed tasks and tracked time
+// @Tags         energy-profile
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /energy-profile [get]
+func GetEnergyProfile(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	stored, err := ai.LoadStoredEnergyProfile(userIDUUID, util.GetUserLocation(c))
+	if err != nil {
+		config.Logger.Errorf("Error loading energy profile for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load energy profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, energyProfileResponse(userIDUUID, stored))
+}
+
+// UpdateEnergyProfile godoc
+// @Summary      Update energy profile
+// @Description  Change the energy profile settings. Settings take precedence over learned values.
+// @Tags         energy-profile
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        profile  body      UpdateEnergyProfileRequest  true  "Energy profile settings"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /energy-profile [put]
+func UpdateEnergyProfile(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	var input UpdateEnergyProfileRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid energy profile input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	stored, err := ai.LoadStoredEnergyProfile(userIDUUID, util.GetUserLocation(c))
+	if err != nil {
+		config.Logger.Errorf("Error loading energy profile for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load energy profile"})
+		return
+	}
+
+	settings := stored.Settings()
+	if input.TimeSlots != nil {
+		settings.TimeSlots = input.TimeSlots
+	}
+	if input.Workload != nil {
+		settings.Workload = input.Workload
+	}
+	if input.WorkDays != nil {
+		settings.WorkDays = input.WorkDays
+	}
+	if input.PreferredStartHour != nil {
+		settings.PreferredStartHour = input.PreferredStartHour
+	}
+	if input.PreferredEndHour != nil {
+		settings.PreferredEndHour = input.PreferredEndHour
+	}
+	if input.BreakDuration != nil {
+		settings.BreakDuration = input.BreakDuration
+	}
+	if input.LearningEnabled != nil {
+		settings.LearningEnabled = *input.LearningEnabled
+	}
+
+	if err := validateEnergySettings(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := saveEnergySettings(stored, settings); err != nil {
+		config.Logger.Errorf("Error updating energy profile for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update energy profile"})
+		return
+	}
+
+	config.Logger.Infof("Updated energy profile for user %s", userIDUUID)
+	c.JSON(http.StatusOK, energyProfileResponse(userIDUUID, stored))
+}
+
+// ResetEnergyProfile godoc
+// @Summary      Reset energy profile settings
+// @Description  Clear the energy profile settings so scheduling uses learned values and defaults again
+// @Tags         energy-profile
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /energy-profile [delete]
+func ResetEnergyProfile(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	stored, err := ai.LoadStoredEnergyProfile(userIDUUID, util.GetUserLocation(c))
+	if err != nil {
+		config.Logger.Errorf("Error loading energy profile for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load energy profile"})
+		return
+	}
+
+	if err := saveEnergySettings(stored, models.EnergyProfileSettings{LearningEnabled: true}); err != nil {
+		config.Logger.Errorf("Error resetting energy profile for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not reset energy profile"})
+		return
+	}
+
+	config.Logger.Infof("Reset energy profile settings for user %s", userIDUUID)
+	c.JSON(http.StatusOK, energyProfileResponse(userIDUUID, stored))
+}
+
+// LearnEnergyProfile godoc
+// @Summary      Relearn energy profile
+// @Description  Rebuild the learned part of the energy profile from recent completed tasks and tracked time
+// @Tags         energy-profile
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /energy-profile/learn [post]
+func LearnEnergyProfile(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+	loc := util.GetUserLocation(c)
+
+	stored, err := ai.LoadStoredEnergyProfile(userIDUUID, loc)
+	if err != nil {
+		config.Logger.Errorf("Error loading energy profile for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load energy profile"})
+		return
+	}
+	if !stored.LearningEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Learning is turned off for this energy profile"})
+		return
+	}
+
+	if err := ai.RelearnEnergyProfile(stored, loc); err != nil {
+		config.Logger.Errorf("Error learning energy profile for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not learn energy profile"})
+		return
+	}
+
+	config.Logger.Infof("Relearned energy profile for user %s from %d samples", userIDUUID, stored.LearnedSampleCount)
+	c.JSON(http.StatusOK, energyProfileResponse(userIDUUID, stored))
+}
+
+func saveEnergySettings(stored *models.EnergyProfile, settings models.EnergyProfileSettings) error {
+	if err := stored.SetSettings(settings); err != nil {
+		return err
+	}
+	return config.GetDB().Model(stored).
+		Select("time_slots", "workload", "work_days", "preferred_start_hour", "preferred_end_hour",
+			"break_duration", "learning_enabled").
+		Updates(stored).Error
+}
+
+// validateEnergySettings checks the ranges of the settings and normalises day names
+func validateEnergySettings(settings *models.EnergyProfileSettings) error {
+	for hour, level := range settings.TimeSlots {
+		h, err := strconv.Atoi(hour)
+		if err != nil || h < 0 || h > 23 {
+			return fmt.Errorf("invalid hour %q in time_slots: use 0-23", hour)
+		}
+		if level < 1 || level > 10 {
+			return fmt.Errorf("energy level for hour %s must be between 1 and 10", hour)
+		}
+	}
+
+	workload := make(map[string]int, len(settings.Workload))
+	for day, score := range settings.Workload {
+		name := strings.ToLower(strings.TrimSpace(day))
+		if !isWeekday(name) {
+			return fmt.Errorf("invalid day %q in workload", day)
+		}
+		if score < 1 || score > 10 {
+			return fmt.Errorf("workload for %s must be between 1 and 10", name)
+		}
+		workload[name] = score
+	}
+	settings.Workload = workload
+
+	for i, day := range settings.WorkDays {
+		name := strings.ToLower(strings.TrimSpace(day))
+		if !isWeekday(name) {
+			return fmt.Errorf("invalid day %q in work_days", day)
+		}
+		settings.WorkDays[i] = name
+	}
+
+	for _, hour := range []*int{settings.PreferredStartHour, settings.PreferredEndHour} {
+		if hour != nil && (*hour < 0 || *hour > 23) {
+			return fmt.Errorf("preferred hours must be between 0 and 23")
+		}
+	}
+	if settings.PreferredStartHour != nil && settings.PreferredEndHour != nil &&
+		*settings.PreferredStartHour > *settings.PreferredEndHour {
+		return fmt.Errorf("preferred_start_hour must not be after preferred_end_hour")
+	}
+	if settings.BreakDuration != nil && (*settings.BreakDuration < 0 || *settings.BreakDuration > 120) {
+		return fmt.Errorf("break_duration must be between 0 and 120 minutes")
+	}
+	return nil
+}
+
+func isWeekday(day string) bool {
+	switch day {
+	case "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/ai"
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GenerateFlashcardsFromSourceRequest represents the request body for generating
+// flashcards from a note, a task learning's notes, or pasted text or markdown
+type GenerateFlashcardsFromSourceRequest struct {
+	Source      string `json:"source" binding:"required,oneof=note task_learning text markdown" example:"note"`
+	SourceID    string `json:"source_id" example:"550e8400-e29b-41d4-a716-446655440000"` // For note and task_learning
+	Text        string `json:"text"`                                                     // For text and markdown
+	NumCards    int    `json:"num_cards" example:"10"`
+	DeckID      string `json:"deck_id"`
+	NewDeckName string `json:"new_deck_name"`
+	Instruction string `json:"instruction"`
+}
+
+// GenerateFlashcardsFromSourceResponse is the cards created, with how many generated
+// cards were left out as duplicates of cards already in the deck
+type GenerateFlashcardsFromSourceResponse struct {
+	GenerateFlashcardsResponse
+	SourceType string     `json:"source_type"`
+	SourceID   *uuid.UUID `json:"source_id,omitempty"`
+	Skipped    int        `json:"skipped"`
+}
+
+// flashcardSource loads the text to generate cards from, writing the error response
+// if the user has no such source. It returns the text, its format and the source's ID.
+func flashcardSource(c *gin.Context, req GenerateFlashcardsFromSourceRequest, userID uuid.UUID) (string, string, *uuid.UUID, bool) {
+	if req.Source == models.CardSourceText || req.Source == models.CardSourceMarkdown {
+		format := ai.FormatText
+		if req.Source == models.CardSourceMarkdown {
+			format = ai.FormatMarkdown
+		}
+		return req.Text, format, nil, true
+	}
+
+	sourceID, err := uuid.Parse(req.SourceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source ID"})
+		return "", "", nil, false
+	}
+
+	if req.Source == models.CardSourceNote {
+		var note models.Note
+		if err := config.GetDB().Where("id = ? AND user_id = ?", sourceID, userID).First(&note).Error; err != nil {
+			config.Logger.Warnf("Note %s not found for user %s", sourceID, userID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+			return "", "", nil, false
+		}
+		return note.Content, ai.FormatMarkdown, &note.ID, true
+	}
+
+	var taskLearning models.Task_learning
+	if err := config.GetDB().Preload("Topic").Where("id = ?", sourceID).First(&taskLearning).Error; err != nil || taskLearning.Topic.UserID != userID {
+		config.Logger.Warnf("Task learning %s not found for user %s", sourceID, userID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task learning not found"})
+		return "", "", nil, false
+	}
+	return taskLearning.Notes, ai.FormatMarkdown, &taskLearning.ID, true
+}
+
+// markStaleSourceCards flags the cards generated from a source whose text has changed
+// since, keeping those whose chunk of the source is still in the new text
+func markStaleSourceCards(db *gorm.DB, sourceType string, sourceID uuid.UUID, text string) error {
+	query := db.Model(&models.Card{}).
+		Where("source_type = ? AND source_id = ? AND source_stale = ?", sourceType, sourceID, false)
+	var hashes []string
+	for _, chunk := range ai.ChunkText(text, ai.FlashcardChunkChars) {
+		hashes = append(hashes, ai.ChunkHash(chunk))
+	}
+	if len(hashes) > 0 {
+		query = query.Where("source_hash NOT IN ?", hashes)
+	}
+	return query.Update("source_stale", true).Error
+}
+
+// GenerateFlashcardsFromSource godoc
+// @Summary      Generate flashcards from a note or text
+// @Description  Generate flashcards with AI from a note, a task learning's notes, or pasted text or markdown. Long text is chunked; cards repeating one already in the deck are skipped. Each card remembers its source so it is flagged as stale when the source changes.
+// @Tags         flashcards
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      GenerateFlashcardsFromSourceRequest  true  "Source and target deck"
+// @Success      200  {object}  GenerateFlashcardsFromSourceResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Failure      502  {object}  map[string]string
+// @Router       /flashcards/from-text [post]
+func GenerateFlashcardsFromSource(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context during flashcard generation")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		config.Logger.Errorf("Invalid userID type in context: %T", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	var req GenerateFlashcardsFromSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		config.Logger.Warnf("Invalid flashcard generation input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+	if req.NumCards <= 0 {
+		req.NumCards = 10
+	}
+	if req.NumCards > 50 {
+		req.NumCards = 50
+	}
+
+	text, format, sourceID, ok := flashcardSource(c, req, userIDUUID)
+	if !ok {
+		return
+	}
+	if len(ai.ChunkText(text, ai.FlashcardChunkChars)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The source has no text to generate flashcards from"})
+		return
+	}
+
+	// Check the target deck before spending a generation on it
+	var deck *models.Deck
+	if req.DeckID != "" {
+		deckID, err := uuid.Parse(req.DeckID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID"})
+			return
+		}
+		if deck, ok = accessibleDeck(c, deckID, userIDUUID, models.DeckRoleEditor); !ok {
+			return
+		}
+	} else if req.NewDeckName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Deck name required when creating new deck"})
+		return
+	}
+
+	client, err := ai.GetOpenRouterClient()
+	if err != nil {
+		config.Logger.Errorf("Failed to get AI client: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "AI service unavailable"})
+		return
+	}
+
+	generated, chunks, err := client.GenerateFlashcardsFromText(text, format, req.NumCards, req.Instruction)
+	if err != nil {
+		config.Logger.Errorf("Failed to generate flashcards for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to generate flashcards"})
+		return
+	}
+	if len(generated) == 0 {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "No flashcards generated"})
+		return
+	}
+
+	// Cards already in the deck, by question
+	existing := map[string]bool{}
+	if deck != nil {
+		var questions []string
+		if err := config.GetDB().Model(&models.Card{}).Where("deck_id = ?", deck.ID).Pluck("question", &questions).Error; err != nil {
+			config.Logger.Errorf("Error fetching cards of deck %s: %v", deck.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch deck cards"})
+			return
+		}
+		for _, question := range questions {
+			existing[ai.NormalizeFlashcardText(question)] = true
+		}
+	}
+
+	now := time.Now()
+	var cards []models.Card
+	previews := []FlashcardPreview{}
+	for _, fc := range generated {
+		if existing[ai.NormalizeFlashcardText(fc.Front)] {
+			continue
+		}
+		cards = append(cards, models.Card{
+			Question:   fc.Front,
+			Answer:     fc.Back,
+			SourceType: req.Source,
+			SourceID:   sourceID,
+			SourceHash: ai.ChunkHash(chunks[fc.Chunk]),
+			Easiness:   2.5,
+			Interval:   1,
+			NextReview: now,
+		})
+		previews = append(previews, FlashcardPreview{Front: fc.Front, Back: fc.Back, Category: fc.Category})
+	}
+
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if deck == nil {
+			deck = &models.Deck{Name: req.NewDeckName, UserID: userIDUUID}
+			if err := tx.Create(deck).Error; err != nil {
+				return err
+			}
+		}
+		for i := range cards {
+			cards[i].DeckID = deck.ID
+		}
+		if len(cards) == 0 {
+			return nil
+		}
+		return tx.Create(&cards).Error
+	})
+	if err != nil {
+		config.Logger.Errorf("Failed to save generated flashcards for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save flashcards"})
+		return
+	}
+
+	skipped := len(generated) - len(cards)
+	config.Logger.Infof("Generated %d flashcards (%d skipped) from %s for user %s into deck %s", len(cards), skipped, req.Source, userIDUUID, deck.ID)
+	c.JSON(http.StatusOK, GenerateFlashcardsFromSourceResponse{
+		GenerateFlashcardsResponse: GenerateFlashcardsResponse{
+			Cards:    previews,
+			DeckID:   deck.ID.String(),
+			DeckName: deck.Name,
+			Message:  fmt.Sprintf("Created %d flashcards, skipped %d already in the deck", len(cards), skipped),
+		},
+		SourceType: req.Source,
+		SourceID:   sourceID,
+		Skipped:    skipped,
+	})
+}
@@ -1,31 +1,253 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/learningpath"
 	"github.com/TheoMKgosi/The-hub/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+var errLearningPathTopicNotFound = errors.New("topic not found")
+
+// LearningPathTopicInput places a topic in a learning path
+type LearningPathTopicInput struct {
+	TopicID         uuid.UUID   `json:"topic_id" binding:"required" example:"550e8400-e29b-41d4-a716-446655440000"`
+	PrerequisiteIDs []uuid.UUID `json:"prerequisite_ids"` // Topics earlier or later in the same path that must be done first
+}
+
 // CreateLearningPathRequest represents the request body for creating a learning path
 type CreateLearningPathRequest struct {
-	Title       string   `json:"title" binding:"required"`
-	Description string   `json:"description"`
-	TopicIDs    []string `json:"topic_ids" binding:"required,min=1"`
+	Title       string                   `json:"title" binding:"required"`
+	Description string                   `json:"description"`
+	Topics      []LearningPathTopicInput `json:"topics" binding:"omitempty,dive"`
+	TopicIDs    []uuid.UUID              `json:"topic_ids"` // Topics in order with no prerequisites, when topics is not given
+}
+
+// UpdateLearningPathRequest represents the request body for updating a learning path.
+// Topics, when given, replace the path's topics.
+type UpdateLearningPathRequest struct {
+	Title       *string                   `json:"title"`
+	Description *string                   `json:"description"`
+	Topics      *[]LearningPathTopicInput `json:"topics" binding:"omitempty,dive"`
+}
+
+// LearningPathTopicResponse is a topic in a learning path with its place, its
+// prerequisites and how far along it is
+type LearningPathTopicResponse struct {
+	models.Topic
+	OrderIndex      int         `json:"order_index"`
+	PrerequisiteIDs []uuid.UUID `json:"prerequisite_ids"`
+	learningpath.TopicProgress
+}
+
+// LearningPathResponse is a learning path with its topics in order and its progress
+type LearningPathResponse struct {
+	models.LearningPath
+	Topics   []LearningPathTopicResponse `json:"topics"`
+	Progress learningpath.Summary        `json:"progress"`
+}
+
+// learningPathTopics turns request topics into the path's topics in order
+func learningPathTopics(inputs []LearningPathTopicInput) []learningpath.Topic {
+	topics := make([]learningpath.Topic, len(inputs))
+	for i, input := range inputs {
+		topics[i] = learningpath.Topic{ID: input.TopicID, Prerequisites: input.PrerequisiteIDs}
+	}
+	return topics
+}
+
+// validLearningPathTopics checks the prerequisites form a DAG over the path's
+// topics, writing a 400 response otherwise
+func validLearningPathTopics(c *gin.Context, topics []learningpath.Topic) bool {
+	err := learningpath.Validate(topics)
+	if err == nil {
+		return true
+	}
+	config.Logger.Warnf("Invalid learning path topics: %v", err)
+	var cycle *learningpath.CycleError
+	if errors.As(err, &cycle) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Topic prerequisites form a cycle", "cycle": cycle.Cycle})
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid topic prerequisites", "details": err.Error()})
+	return false
+}
+
+// replaceLearningPathTopics swaps a path's topics for the given ones, which must all
+// be the user's own
+func replaceLearningPathTopics(tx *gorm.DB, pathID, userID uuid.UUID, topics []learningpath.Topic) error {
+	ids := make([]uuid.UUID, len(topics))
+	for i, topic := range topics {
+		ids[i] = topic.ID
+	}
+	var owned int64
+	if err := tx.Model(&models.Topic{}).Where("id IN ? AND user_id = ?", ids, userID).Count(&owned).Error; err != nil {
+		return err
+	}
+	if int(owned) != len(ids) {
+		return errLearningPathTopicNotFound
+	}
+
+	if err := tx.Where("learning_path_id = ?", pathID).Delete(&models.LearningPathTopic{}).Error; err != nil {
+		return err
+	}
+	rows := make([]models.LearningPathTopic, len(topics))
+	for i, topic := range topics {
+		rows[i] = models.LearningPathTopic{LearningPathID: pathID, TopicID: topic.ID, OrderIndex: i}
+		if err := rows[i].SetPrerequisiteIDs(topic.Prerequisites); err != nil {
+			return err
+		}
+	}
+	return tx.Create(&rows).Error
+}
+
+// taskCount is how many of a topic's learning tasks there are and how many are done
+type taskCount struct {
+	TopicID uuid.UUID
+	Total   int
+	Done    int
+}
+
+// learningPathResponses loads the topics of each path, in order, and works out
+// their progress from the topics' statuses and their learning tasks
+func learningPathResponses(db *gorm.DB, paths []models.LearningPath) ([]LearningPathResponse, error) {
+	responses := make([]LearningPathResponse, len(paths))
+	if len(paths) == 0 {
+		return responses, nil
+	}
+	pathIDs := make([]uuid.UUID, len(paths))
+	for i := range paths {
+		pathIDs[i] = paths[i].ID
+	}
+
+	var rows []models.LearningPathTopic
+	if err := db.Preload("Topic.Tags").Where("learning_path_id IN ?", pathIDs).
+		Order("order_index").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	topicIDs := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		topicIDs = append(topicIDs, row.TopicID)
+	}
+	var counts []taskCount
+	if len(topicIDs) > 0 {
+		if err := db.Model(&models.Task_learning{}).
+			Select("topic_id, COUNT(*) AS total, COUNT(*) FILTER (WHERE status IN ?) AS done",
+				[]string{learningpath.StatusCompleted, learningpath.TaskStatusDone}).
+			Where("topic_id IN ?", topicIDs).Group("topic_id").Scan(&counts).Error; err != nil {
+			return nil, err
+		}
+	}
+	tasks := make(map[uuid.UUID]taskCount, len(counts))
+	for _, count := range counts {
+		tasks[count.TopicID] = count
+	}
+
+	byPath := make(map[uuid.UUID][]models.LearningPathTopic, len(paths))
+	for _, row := range rows {
+		byPath[row.LearningPathID] = append(byPath[row.LearningPathID], row)
+	}
+	for i, path := range paths {
+		pathTopics := byPath[path.ID]
+		states := make([]learningpath.TopicState, len(pathTopics))
+		for j := range pathTopics {
+			states[j] = learningpath.TopicState{
+				Topic:      pathTopics[j].PathTopic(),
+				Status:     pathTopics[j].Topic.Status,
+				TasksTotal: tasks[pathTopics[j].TopicID].Total,
+				TasksDone:  tasks[pathTopics[j].TopicID].Done,
+			}
+		}
+		progress, summary := learningpath.Evaluate(states)
+
+		response := LearningPathResponse{LearningPath: path, Topics: make([]LearningPathTopicResponse, len(pathTopics)), Progress: summary}
+		for j := range pathTopics {
+			response.Topics[j] = LearningPathTopicResponse{
+				Topic:           pathTopics[j].Topic,
+				OrderIndex:      pathTopics[j].OrderIndex,
+				PrerequisiteIDs: states[j].Prerequisites,
+				TopicProgress:   progress[j],
+			}
+		}
+		responses[i] = response
+	}
+	return responses, nil
+}
+
+// topicBlockers returns the prerequisites, across all the user's learning paths,
+// that keep a topic locked
+func topicBlockers(db *gorm.DB, userID, topicID uuid.UUID) ([]uuid.UUID, error) {
+	var paths []models.LearningPath
+	if err := db.Where("user_id = ? AND id IN (?)", userID,
+		db.Model(&models.LearningPathTopic{}).Select("learning_path_id").Where("topic_id = ?", topicID)).
+		Find(&paths).Error; err != nil {
+		return nil, err
+	}
+	responses, err := learningPathResponses(db, paths)
+	if err != nil {
+		return nil, err
+	}
+	var blockers []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	for _, response := range responses {
+		for _, topic := range response.Topics {
+			if topic.ID != topicID || !topic.Locked {
+				continue
+			}
+			for _, id := range topic.BlockedBy {
+				if !seen[id] {
+					seen[id] = true
+					blockers = append(blockers, id)
+				}
+			}
+		}
+	}
+	return blockers, nil
+}
+
+// topicUnlocked checks no learning path keeps a topic locked, writing a 409
+// response with the unfinished prerequisites otherwise
+func topicUnlocked(c *gin.Context, userID, topicID uuid.UUID) bool {
+	blockers, err := topicBlockers(config.GetDB(), userID, topicID)
+	if err != nil {
+		config.Logger.Errorf("Error checking prerequisites of topic %s: %v", topicID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check topic prerequisites"})
+		return false
+	}
+	if len(blockers) > 0 {
+		config.Logger.Warnf("Topic %s for user %s is locked by %v", topicID, userID, blockers)
+		c.JSON(http.StatusConflict, gin.H{"error": "Topic is locked until its prerequisites are done", "blocked_by": blockers})
+		return false
+	}
+	return true
+}
+
+// respondWithLearningPath writes a path with its topics and progress
+func respondWithLearningPath(c *gin.Context, status int, path *models.LearningPath) {
+	responses, err := learningPathResponses(config.GetDB(), []models.LearningPath{*path})
+	if err != nil {
+		config.Logger.Errorf("Error fetching topics of learning path %s: %v", path.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch learning path topics"})
+		return
+	}
+	c.JSON(status, responses[0])
 }
 
 // CreateLearningPath godoc
 // @Summary      Create a new learning path
-// @Description  Create a new learning path with ordered topics for the logged-in user
+// @Description  Create a new learning path with ordered topics for the logged-in user. Each topic may list prerequisites, other topics in the path that must be done before it can be started; they may not form a cycle.
 // @Tags         learning-paths
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
 // @Param        learning_path  body      CreateLearningPathRequest  true  "Learning path creation data"
-// @Success      201  {object}  models.LearningPath
-// @Failure      400  {object}  map[string]string
+// @Success      201  {object}  LearningPathResponse
+// @Failure      400  {object}  map[string]interface{}
 // @Failure      401  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /learning-paths [post]
@@ -52,65 +274,56 @@ func CreateLearningPath(c *gin.Context) {
 		return
 	}
 
-	// Validate that all topics exist and belong to the user
-	var topics []models.Topic
-	for _, topicIDStr := range input.TopicIDs {
-		topicID, err := uuid.Parse(topicIDStr)
-		if err != nil {
-			config.Logger.Warnf("Invalid topic ID in learning path: %s", topicIDStr)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid topic ID", "topic_id": topicIDStr})
-			return
-		}
-
-		var topic models.Topic
-		if err := config.GetDB().Where("id = ? AND user_id = ?", topicID, userIDUUID).First(&topic).Error; err != nil {
-			config.Logger.Warnf("Topic ID %s not found or not owned by user %s", topicID, userIDUUID)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Topic not found or access denied", "topic_id": topicIDStr})
-			return
+	topics := learningPathTopics(input.Topics)
+	if len(input.Topics) == 0 {
+		for _, topicID := range input.TopicIDs {
+			topics = append(topics, learningpath.Topic{ID: topicID})
 		}
-		topics = append(topics, topic)
+	}
+	if len(topics) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A learning path needs at least one topic"})
+		return
+	}
+	if !validLearningPathTopics(c, topics) {
+		return
 	}
 
-	// Create learning path
 	learningPath := models.LearningPath{
 		UserID:      userIDUUID,
 		Title:       input.Title,
 		Description: input.Description,
-		Topics:      topics,
 	}
 
 	config.Logger.Infof("Creating learning path for user %s: %s", userIDUUID, input.Title)
-	if err := config.GetDB().Create(&learningPath).Error; err != nil {
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&learningPath).Error; err != nil {
+			return err
+		}
+		return replaceLearningPathTopics(tx, learningPath.ID, userIDUUID, topics)
+	})
+	if errors.Is(err, errLearningPathTopicNotFound) {
+		config.Logger.Warnf("Learning path for user %s names topics they do not own", userIDUUID)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Topic not found or access denied"})
+		return
+	}
+	if err != nil {
 		config.Logger.Errorf("Error creating learning path for user %s: %v", userIDUUID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create learning path"})
 		return
 	}
 
-	// Create learning path topics with order
-	for i, topic := range topics {
-		learningPathTopic := models.LearningPathTopic{
-			LearningPathID: learningPath.ID,
-			TopicID:        topic.ID,
-			OrderIndex:     i,
-		}
-		if err := config.GetDB().Create(&learningPathTopic).Error; err != nil {
-			config.Logger.Errorf("Error creating learning path topic: %v", err)
-			// Continue with other topics even if one fails
-		}
-	}
-
 	config.Logger.Infof("Successfully created learning path ID %s for user %s", learningPath.ID, userIDUUID)
-	c.JSON(http.StatusCreated, learningPath)
+	respondWithLearningPath(c, http.StatusCreated, &learningPath)
 }
 
 // GetLearningPaths godoc
 // @Summary      Get learning paths
-// @Description  Fetch learning paths for the logged-in user
+// @Description  Fetch learning paths for the logged-in user, each with its topics in order and its progress
 // @Tags         learning-paths
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Success      200  {object}  map[string][]models.LearningPath
+// @Success      200  {object}  map[string][]LearningPathResponse
 // @Failure      401  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /learning-paths [get]
@@ -130,123 +343,182 @@ func GetLearningPaths(c *gin.Context) {
 	}
 
 	var learningPaths []models.LearningPath
-	if err := config.GetDB().Preload("Topics").Where("user_id = ?", userIDUUID).Find(&learningPaths).Error; err != nil {
+	if err := config.GetDB().Where("user_id = ?", userIDUUID).Order("created_at").Find(&learningPaths).Error; err != nil {
 		config.Logger.Errorf("Error fetching learning paths for user %s: %v", userIDUUID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch learning paths"})
 		return
 	}
+	responses, err := learningPathResponses(config.GetDB(), learningPaths)
+	if err != nil {
+		config.Logger.Errorf("Error fetching learning path topics for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch learning paths"})
+		return
+	}
 
 	config.Logger.Infof("Found %d learning paths for user %s", len(learningPaths), userIDUUID)
-	c.JSON(http.StatusOK, gin.H{"learning_paths": learningPaths})
+	c.JSON(http.StatusOK, gin.H{"learning_paths": responses})
+}
+
+// ownedLearningPath loads one of the user's learning paths from the ID param,
+// writing the error response otherwise
+func ownedLearningPath(c *gin.Context) (*models.LearningPath, bool) {
+	learningPathIDStr := c.Param("ID")
+	learningPathID, err := uuid.Parse(learningPathIDStr)
+	if err != nil {
+		config.Logger.Warnf("Invalid learning path ID param: %s", learningPathIDStr)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid learning path ID"})
+		return nil, false
+	}
+
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	var learningPath models.LearningPath
+	if err := config.GetDB().Where("id = ? AND user_id = ?", learningPathID, userID).First(&learningPath).Error; err != nil {
+		config.Logger.Warnf("Learning path ID %s not found for user %v: %v", learningPathID, userID, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Learning path not found"})
+		return nil, false
+	}
+	return &learningPath, true
 }
 
 // GetLearningPath godoc
 // @Summary      Get a specific learning path
-// @Description  Fetch a specific learning path by ID for the logged-in user
+// @Description  Fetch a specific learning path by ID for the logged-in user, with its topics in order, which of them are locked, and its progress
 // @Tags         learning-paths
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        ID   path      int  true  "Learning Path ID"
-// @Success      200  {object}  models.LearningPath
+// @Param        ID   path      string  true  "Learning Path ID"
+// @Success      200  {object}  LearningPathResponse
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /learning-paths/{ID} [get]
 func GetLearningPath(c *gin.Context) {
-	learningPathIDStr := c.Param("ID")
-	learningPathID, err := uuid.Parse(learningPathIDStr)
-	if err != nil {
-		config.Logger.Warnf("Invalid learning path ID param: %s", learningPathIDStr)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid learning path ID"})
+	learningPath, ok := ownedLearningPath(c)
+	if !ok {
 		return
 	}
 
-	userID, exist := c.Get("userID")
-	if !exist {
-		config.Logger.Warn("userID not found in context")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	config.Logger.Infof("Successfully retrieved learning path ID %s for user %s", learningPath.ID, learningPath.UserID)
+	respondWithLearningPath(c, http.StatusOK, learningPath)
+}
+
+// UpdateLearningPath godoc
+// @Summary      Update a learning path
+// @Description  Update a learning path's title or description, or replace its topics and their prerequisites
+// @Tags         learning-paths
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID             path      string                     true  "Learning Path ID"
+// @Param        learning_path  body      UpdateLearningPathRequest  true  "Learning path update data"
+// @Success      200  {object}  LearningPathResponse
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /learning-paths/{ID} [patch]
+func UpdateLearningPath(c *gin.Context) {
+	learningPath, ok := ownedLearningPath(c)
+	if !ok {
 		return
 	}
 
-	userIDUUID, ok := userID.(uuid.UUID)
-	if !ok {
-		config.Logger.Errorf("Invalid userID type in context: %T", userID)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	var input UpdateLearningPathRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid update input for learning path ID %s: %v", learningPath.ID, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
 		return
 	}
 
-	var learningPath models.LearningPath
-	if err := config.GetDB().Preload("Topics").Where("id = ? AND user_id = ?", learningPathID, userIDUUID).First(&learningPath).Error; err != nil {
-		config.Logger.Warnf("Learning path ID %s not found for user %s: %v", learningPathID, userIDUUID, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Learning path not found"})
+	updates := map[string]interface{}{}
+	if input.Title != nil {
+		if *input.Title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Title cannot be empty"})
+			return
+		}
+		updates["title"] = *input.Title
+	}
+	if input.Description != nil {
+		updates["description"] = *input.Description
+	}
+	var topics []learningpath.Topic
+	if input.Topics != nil {
+		topics = learningPathTopics(*input.Topics)
+		if len(topics) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A learning path needs at least one topic"})
+			return
+		}
+		if !validLearningPathTopics(c, topics) {
+			return
+		}
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(learningPath).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if input.Topics == nil {
+			return nil
+		}
+		return replaceLearningPathTopics(tx, learningPath.ID, learningPath.UserID, topics)
+	})
+	if errors.Is(err, errLearningPathTopicNotFound) {
+		config.Logger.Warnf("Learning path %s update names topics user %s does not own", learningPath.ID, learningPath.UserID)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Topic not found or access denied"})
+		return
+	}
+	if err != nil {
+		config.Logger.Errorf("Failed to update learning path ID %s: %v", learningPath.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update learning path"})
 		return
 	}
 
-	config.Logger.Infof("Successfully retrieved learning path ID %s for user %s", learningPathID, userIDUUID)
-	c.JSON(http.StatusOK, learningPath)
+	config.Logger.Infof("Successfully updated learning path ID %s for user %s", learningPath.ID, learningPath.UserID)
+	respondWithLearningPath(c, http.StatusOK, learningPath)
 }
 
 // DeleteLearningPath godoc
 // @Summary      Delete a learning path
-// @Description  Delete a specific learning path by ID for the logged-in user
+// @Description  Delete a specific learning path by ID for the logged-in user. Its topics are kept.
 // @Tags         learning-paths
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        ID   path      int  true  "Learning Path ID"
+// @Param        ID   path      string  true  "Learning Path ID"
 // @Success      200  {object}  map[string]string
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
-// @Failure      403  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /learning-paths/{ID} [delete]
 func DeleteLearningPath(c *gin.Context) {
-	learningPathIDStr := c.Param("ID")
-	learningPathID, err := uuid.Parse(learningPathIDStr)
-	if err != nil {
-		config.Logger.Warnf("Invalid learning path ID param for delete: %s", learningPathIDStr)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid learning path ID"})
-		return
-	}
-
-	userID, exist := c.Get("userID")
-	if !exist {
-		config.Logger.Warn("userID not found in context during learning path deletion")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	userIDUUID, ok := userID.(uuid.UUID)
+	learningPath, ok := ownedLearningPath(c)
 	if !ok {
-		config.Logger.Errorf("Invalid userID type in context: %T", userID)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
 	}
 
-	var learningPath models.LearningPath
-	if err := config.GetDB().Where("id = ? AND user_id = ?", learningPathID, userIDUUID).First(&learningPath).Error; err != nil {
-		config.Logger.Warnf("Learning path not found for delete: ID %s, User %s", learningPathID, userIDUUID)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Learning path not found"})
-		return
-	}
-
-	// Delete associated learning path topics first
-	if err := config.GetDB().Where("learning_path_id = ?", learningPathID).Delete(&models.LearningPathTopic{}).Error; err != nil {
-		config.Logger.Errorf("Failed to delete learning path topics for path ID %s: %v", learningPathID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete learning path topics"})
-		return
-	}
-
-	// Delete the learning path
-	if err := config.GetDB().Delete(&learningPath).Error; err != nil {
-		config.Logger.Errorf("Failed to delete learning path ID %s: %v", learningPathID, err)
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("learning_path_id = ?", learningPath.ID).Delete(&models.LearningPathTopic{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(learningPath).Error
+	})
+	if err != nil {
+		config.Logger.Errorf("Failed to delete learning path ID %s: %v", learningPath.ID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete learning path"})
 		return
 	}
 
-	config.Logger.Infof("Successfully deleted learning path ID %s for user %s", learningPathID, userIDUUID)
+	config.Logger.Infof("Successfully deleted learning path ID %s for user %s", learningPath.ID, learningPath.UserID)
 	c.JSON(http.StatusOK, gin.H{"message": "Learning path deleted successfully"})
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/TheoMKgosi/The-hub/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type CreateNoteRequest struct {
@@ -177,7 +178,17 @@ func UpdateNote(c *gin.Context) {
 	}
 
 	config.Logger.Infof("Updating note ID %s for user %v with data: %+v", noteID, userID, updates)
-	if err := config.GetDB().Model(&note).Updates(updates).Error; err != nil {
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&note).Updates(updates).Error; err != nil {
+			return err
+		}
+		// Cards generated from parts of the note that changed are now out of date
+		if input.Content != nil && *input.Content != note.Content {
+			return markStaleSourceCards(tx, models.CardSourceNote, note.ID, *input.Content)
+		}
+		return nil
+	})
+	if err != nil {
 		config.Logger.Errorf("Failed to update note ID %s: %v", noteID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
 		return
@@ -217,7 +228,13 @@ func DeleteNote(c *gin.Context) {
 	}
 
 	config.Logger.Infof("Deleting note ID %s for user %v", noteID, userID)
-	if err := config.GetDB().Delete(&note).Error; err != nil {
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&note).Error; err != nil {
+			return err
+		}
+		return markStaleSourceCards(tx, models.CardSourceNote, note.ID, "")
+	})
+	if err != nil {
 		config.Logger.Errorf("Failed to delete note ID %s: %v", noteID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete note"})
 		return
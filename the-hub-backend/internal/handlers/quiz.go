@@ -0,0 +1,522 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/ai"
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/quiz"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxQuizSources caps the cards or notes a quiz is generated from
+	maxQuizSources = 40
+	// quizSourceChars is the most of one task learning's notes a quiz is generated from
+	quizSourceChars = 2000
+)
+
+// CreateQuizRequest represents the request body for generating a quiz from a deck or a topic
+type CreateQuizRequest struct {
+	DeckID       *uuid.UUID `json:"deck_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	TopicID      *uuid.UUID `json:"topic_id"`
+	Title        string     `json:"title" example:"Go concurrency"`
+	NumQuestions int        `json:"num_questions" example:"10"`
+	Types        []string   `json:"types" binding:"omitempty,dive,oneof=multiple_choice true_false short_answer" example:"multiple_choice,true_false"`
+	Instruction  string     `json:"instruction"`
+}
+
+// SubmitQuizAttemptRequest represents the request body for answering a quiz. Answers are
+// in question order; multiple choice answers may be the option or its letter.
+type SubmitQuizAttemptRequest struct {
+	Answers []string `json:"answers" binding:"required"`
+}
+
+// QuizQuestionView is a quiz question as shown to the user, without its answer
+type QuizQuestionView struct {
+	Number   int      `json:"number"`
+	Type     string   `json:"type"`
+	Question string   `json:"question"`
+	Options  []string `json:"options,omitempty"`
+}
+
+// QuizResponse is a quiz with its questions to answer
+type QuizResponse struct {
+	models.Quiz
+	Questions []QuizQuestionView `json:"questions"`
+}
+
+// QuizAttemptResponse is a graded attempt with the result of each answer
+type QuizAttemptResponse struct {
+	models.QuizAttempt
+	Results []quiz.Result `json:"results"`
+}
+
+// quizQuestions decodes a quiz's stored questions
+func quizQuestions(q *models.Quiz) []quiz.Question {
+	questions := []quiz.Question{}
+	_ = json.Unmarshal([]byte(q.Questions), &questions)
+	return questions
+}
+
+func quizResponse(q *models.Quiz) QuizResponse {
+	questions := quizQuestions(q)
+	views := make([]QuizQuestionView, len(questions))
+	for i, question := range questions {
+		views[i] = QuizQuestionView{Number: i + 1, Type: question.Type, Question: question.Question, Options: question.Options}
+	}
+	return QuizResponse{Quiz: *q, Questions: views}
+}
+
+func quizAttemptResponse(attempt *models.QuizAttempt) QuizAttemptResponse {
+	results := []quiz.Result{}
+	_ = json.Unmarshal([]byte(attempt.Results), &results)
+	return QuizAttemptResponse{QuizAttempt: *attempt, Results: results}
+}
+
+// deckQuizSources loads the cards of a deck the user can study as quiz material, those
+// due soonest first, writing the error response if they cannot
+func deckQuizSources(c *gin.Context, deckID, userID uuid.UUID) ([]string, []*uuid.UUID, string, bool) {
+	deck, ok := accessibleDeck(c, deckID, userID, models.DeckRoleViewer)
+	if !ok {
+		return nil, nil, "", false
+	}
+	var cards []models.Card
+	if err := config.GetDB().Where("deck_id = ?", deck.ID).Order("next_review").Limit(maxQuizSources).Find(&cards).Error; err != nil {
+		config.Logger.Errorf("Error fetching cards of deck %s: %v", deck.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch deck cards"})
+		return nil, nil, "", false
+	}
+	sources := make([]string, len(cards))
+	ids := make([]*uuid.UUID, len(cards))
+	for i := range cards {
+		sources[i] = fmt.Sprintf("Q: %s\nA: %s", cards[i].Question, cards[i].Answer)
+		ids[i] = &cards[i].ID
+	}
+	return sources, ids, deck.Name, true
+}
+
+// topicQuizSources loads a topic's description and its task learnings' notes as quiz
+// material, writing the error response if the user has no such topic
+func topicQuizSources(c *gin.Context, topicID, userID uuid.UUID) ([]string, []*uuid.UUID, string, bool) {
+	var topic models.Topic
+	if err := config.GetDB().Where("id = ? AND user_id = ?", topicID, userID).First(&topic).Error; err != nil {
+		config.Logger.Warnf("Topic %s not found for user %s", topicID, userID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+		return nil, nil, "", false
+	}
+	var taskLearnings []models.Task_learning
+	if err := config.GetDB().Where("topic_id = ? AND notes <> ''", topic.ID).Order("order_index").
+		Limit(maxQuizSources).Find(&taskLearnings).Error; err != nil {
+		config.Logger.Errorf("Error fetching task learnings of topic %s: %v", topic.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch topic notes"})
+		return nil, nil, "", false
+	}
+
+	var sources []string
+	var ids []*uuid.UUID
+	if description := strings.TrimSpace(topic.Description); description != "" {
+		sources = append(sources, topic.Title+"\n"+description)
+		ids = append(ids, nil)
+	}
+	for i := range taskLearnings {
+		chunks := ai.ChunkText(taskLearnings[i].Notes, quizSourceChars)
+		if len(chunks) == 0 {
+			continue
+		}
+		sources = append(sources, taskLearnings[i].Title+"\n"+chunks[0])
+		ids = append(ids, &taskLearnings[i].ID)
+	}
+	return sources, ids, topic.Title, true
+}
+
+// shortenQuizCards brings forward, in the user's own review state, the cards behind
+// the questions answered wrongly: the cards themselves for a deck quiz, and the cards
+// generated from the task learnings' notes for a topic quiz. It returns how many changed.
+func shortenQuizCards(tx *gorm.DB, q *models.Quiz, questions []quiz.Question, results []quiz.Result, userID uuid.UUID, now time.Time) (int, error) {
+	var sourceIDs []uuid.UUID
+	for i, result := range results {
+		if !result.Correct && questions[i].SourceID != nil {
+			sourceIDs = append(sourceIDs, *questions[i].SourceID)
+		}
+	}
+	if len(sourceIDs) == 0 {
+		return 0, nil
+	}
+
+	query := tx.Preload("Deck")
+	if q.TopicID != nil {
+		query = query.Where("source_type = ? AND source_id IN ?", models.CardSourceTaskLearning, sourceIDs)
+	} else {
+		query = query.Where("id IN ?", sourceIDs)
+	}
+	var cards []models.Card
+	if err := query.Find(&cards).Error; err != nil {
+		return 0, err
+	}
+
+	roles := map[uuid.UUID]string{}
+	shortened := 0
+	for i := range cards {
+		card := &cards[i]
+		role, known := roles[card.DeckID]
+		if !known {
+			var err error
+			if role, err = deckRole(tx, &card.Deck, userID); err != nil {
+				return 0, err
+			}
+			roles[card.DeckID] = role
+		}
+		if role == "" {
+			continue
+		}
+		if err := withUserState(tx, cards[i:i+1], userID, role); err != nil {
+			return 0, err
+		}
+		if card.Suspended {
+			continue
+		}
+		interval, next := quiz.Shorten(card.Interval, card.NextReview, now)
+		if interval == card.Interval && next.Equal(card.NextReview) {
+			continue
+		}
+		card.Interval, card.NextReview = interval, next
+		if err := saveUserState(tx, card, userID, role); err != nil {
+			return 0, err
+		}
+		shortened++
+	}
+	return shortened, nil
+}
+
+// ownedQuiz loads the quiz named by the ID param if the user made it, writing the error response otherwise
+func ownedQuiz(c *gin.Context) (*models.Quiz, bool) {
+	quizID, err := uuid.Parse(c.Param("ID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quiz ID"})
+		return nil, false
+	}
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	var q models.Quiz
+	if err := config.GetDB().Where("id = ? AND user_id = ?", quizID, userID).First(&q).Error; err != nil {
+		config.Logger.Warnf("Quiz ID %s not found for user %v: %v", quizID, userID, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quiz not found"})
+		return nil, false
+	}
+	return &q, true
+}
+
+// CreateQuiz godoc
+// @Summary      Generate a quiz
+// @Description  Generate multiple choice, true/false and short answer questions with AI from a deck's cards or a topic's notes. The generated questions are validated against a JSON schema before the quiz is saved.
+// @Tags         quizzes
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        quiz  body      CreateQuizRequest  true  "Deck or topic to quiz on"
+// @Success      201  {object}  QuizResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Failure      502  {object}  map[string]string
+// @Router       /quizzes [post]
+func CreateQuiz(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context during quiz creation")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		config.Logger.Errorf("Invalid userID type in context: %T", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	var input CreateQuizRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid quiz input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if (input.DeckID == nil) == (input.TopicID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Give either a deck_id or a topic_id"})
+		return
+	}
+	if input.NumQuestions <= 0 {
+		input.NumQuestions = 10
+	}
+	if input.NumQuestions > 30 {
+		input.NumQuestions = 30
+	}
+	if len(input.Types) == 0 {
+		input.Types = quiz.Types
+	}
+
+	var sources []string
+	var sourceIDs []*uuid.UUID
+	var name string
+	if input.DeckID != nil {
+		sources, sourceIDs, name, ok = deckQuizSources(c, *input.DeckID, userIDUUID)
+	} else {
+		sources, sourceIDs, name, ok = topicQuizSources(c, *input.TopicID, userIDUUID)
+	}
+	if !ok {
+		return
+	}
+	if len(sources) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "There is nothing to quiz on yet"})
+		return
+	}
+
+	client, err := ai.GetOpenRouterClient()
+	if err != nil {
+		config.Logger.Errorf("Failed to get AI client: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "AI service unavailable"})
+		return
+	}
+	questions, err := client.GenerateQuiz(sources, input.NumQuestions, input.Types, input.Instruction)
+	if err != nil {
+		config.Logger.Errorf("Failed to generate quiz for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to generate quiz"})
+		return
+	}
+	for i := range questions {
+		questions[i].SourceID = sourceIDs[questions[i].Source-1]
+	}
+
+	encoded, err := json.Marshal(questions)
+	if err != nil {
+		config.Logger.Errorf("Failed to encode quiz questions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create quiz"})
+		return
+	}
+	q := models.Quiz{
+		UserID:        userIDUUID,
+		DeckID:        input.DeckID,
+		TopicID:       input.TopicID,
+		Title:         strings.TrimSpace(input.Title),
+		Questions:     string(encoded),
+		QuestionCount: len(questions),
+	}
+	if q.Title == "" {
+		q.Title = "Quiz: " + name
+	}
+	if err := config.GetDB().Create(&q).Error; err != nil {
+		config.Logger.Errorf("Error creating quiz for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create quiz"})
+		return
+	}
+
+	config.Logger.Infof("Created quiz %s with %d questions for user %s", q.ID, q.QuestionCount, userIDUUID)
+	c.JSON(http.StatusCreated, quizResponse(&q))
+}
+
+// GetQuizzes godoc
+// @Summary      Get quizzes
+// @Description  Fetch the logged-in user's quizzes, newest first
+// @Tags         quizzes
+// @Produce      json
+// @Security     BearerAuth
+// @Param        deck_id   query     string  false  "Only quizzes on this deck"
+// @Param        topic_id  query     string  false  "Only quizzes on this topic"
+// @Success      200  {object}  map[string][]models.Quiz
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /quizzes [get]
+func GetQuizzes(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	query := config.GetDB().Where("user_id = ?", userID)
+	if deckID := c.Query("deck_id"); deckID != "" {
+		query = query.Where("deck_id = ?", deckID)
+	}
+	if topicID := c.Query("topic_id"); topicID != "" {
+		query = query.Where("topic_id = ?", topicID)
+	}
+	var quizzes []models.Quiz
+	if err := query.Order("created_at DESC").Find(&quizzes).Error; err != nil {
+		config.Logger.Errorf("Error fetching quizzes for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch quizzes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"quizzes": quizzes})
+}
+
+// GetQuiz godoc
+// @Summary      Get a quiz
+// @Description  Fetch one of the logged-in user's quizzes with its questions, without their answers
+// @Tags         quizzes
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Quiz ID"
+// @Success      200  {object}  QuizResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /quizzes/{ID} [get]
+func GetQuiz(c *gin.Context) {
+	q, ok := ownedQuiz(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, quizResponse(q))
+}
+
+// DeleteQuiz godoc
+// @Summary      Delete a quiz
+// @Description  Delete a quiz and its attempts
+// @Tags         quizzes
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Quiz ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /quizzes/{ID} [delete]
+func DeleteQuiz(c *gin.Context) {
+	q, ok := ownedQuiz(c)
+	if !ok {
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("quiz_id = ?", q.ID).Delete(&models.QuizAttempt{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(q).Error
+	})
+	if err != nil {
+		config.Logger.Errorf("Failed to delete quiz %s: %v", q.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete quiz"})
+		return
+	}
+
+	config.Logger.Infof("Deleted quiz %s for user %s", q.ID, q.UserID)
+	c.JSON(http.StatusOK, gin.H{"message": "Quiz deleted successfully"})
+}
+
+// SubmitQuizAttempt godoc
+// @Summary      Answer a quiz
+// @Description  Grade answers to a quiz and store the attempt. Cards behind the questions answered wrongly have their intervals shortened so they come back sooner.
+// @Tags         quizzes
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID       path      string                    true  "Quiz ID"
+// @Param        attempt  body      SubmitQuizAttemptRequest  true  "Answers in question order"
+// @Success      201  {object}  QuizAttemptResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /quizzes/{ID}/attempts [post]
+func SubmitQuizAttempt(c *gin.Context) {
+	q, ok := ownedQuiz(c)
+	if !ok {
+		return
+	}
+
+	var input SubmitQuizAttemptRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid quiz attempt input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	questions := quizQuestions(q)
+	if len(input.Answers) > len(questions) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The quiz has %d questions", len(questions))})
+		return
+	}
+
+	results, correct := quiz.Score(questions, input.Answers)
+	encoded, err := json.Marshal(results)
+	if err != nil {
+		config.Logger.Errorf("Failed to encode quiz results: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not grade quiz"})
+		return
+	}
+	attempt := models.QuizAttempt{
+		QuizID:  q.ID,
+		UserID:  q.UserID,
+		Results: string(encoded),
+		Correct: correct,
+		Total:   len(questions),
+	}
+	if len(questions) > 0 {
+		attempt.Score = math.Round(float64(correct)/float64(len(questions))*1000) / 1000
+	}
+
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		shortened, err := shortenQuizCards(tx, q, questions, results, q.UserID, time.Now())
+		if err != nil {
+			return err
+		}
+		attempt.CardsShortened = shortened
+		return tx.Create(&attempt).Error
+	})
+	if err != nil {
+		config.Logger.Errorf("Failed to save attempt at quiz %s: %v", q.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save quiz attempt"})
+		return
+	}
+
+	config.Logger.Infof("User %s scored %d/%d on quiz %s, %d cards brought forward", q.UserID, correct, len(questions), q.ID, attempt.CardsShortened)
+	c.JSON(http.StatusCreated, quizAttemptResponse(&attempt))
+}
+
+// GetQuizAttempts godoc
+// @Summary      Get a quiz's attempts
+// @Description  Fetch the graded attempts at one of the logged-in user's quizzes, newest first
+// @Tags         quizzes
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Quiz ID"
+// @Success      200  {object}  map[string][]QuizAttemptResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /quizzes/{ID}/attempts [get]
+func GetQuizAttempts(c *gin.Context) {
+	q, ok := ownedQuiz(c)
+	if !ok {
+		return
+	}
+
+	var attempts []models.QuizAttempt
+	if err := config.GetDB().Where("quiz_id = ?", q.ID).Order("created_at DESC").Find(&attempts).Error; err != nil {
+		config.Logger.Errorf("Error fetching attempts at quiz %s: %v", q.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch quiz attempts"})
+		return
+	}
+	responses := make([]QuizAttemptResponse, len(attempts))
+	for i := range attempts {
+		responses[i] = quizAttemptResponse(&attempts[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"attempts": responses})
+}
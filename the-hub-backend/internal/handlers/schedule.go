@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,18 +10,21 @@ import (
 	"github.com/TheoMKgosi/The-hub/internal/ai"
 	"github.com/TheoMKgosi/The-hub/internal/config"
 	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-// hasTimeConflict checks if a new time slot conflicts with existing scheduled tasks
-func hasTimeConflict(db *gorm.DB, userID uuid.UUID, start, end time.Time, excludeID *uuid.UUID) (bool, error) {
+// hasTimeConflict checks if a new time slot conflicts with existing scheduled tasks.
+// Cancelled occurrences of recurring events do not block time.
+func hasTimeConflict(db *gorm.DB, userID uuid.UUID, start, end time.Time, excludeIDs ...uuid.UUID) (bool, error) {
 	var count int64
-	query := db.Model(&models.ScheduledTask{}).Where(`user_id = ? AND (("start" < ? AND "end" > ?) OR ("start" < ? AND "end" > ?))`, userID, end, start, start, end)
+	query := db.Model(&models.ScheduledTask{}).Where(`user_id = ? AND (("start" < ? AND "end" > ?) OR ("start" < ? AND "end" > ?))`, userID, end, start, start, end).
+		Where("cancelled IS NOT TRUE")
 
-	if excludeID != nil {
-		query = query.Where("id != ?", *excludeID)
+	if len(excludeIDs) > 0 {
+		query = query.Where("id NOT IN ?", excludeIDs)
 	}
 
 	if err := query.Count(&count).Error; err != nil {
@@ -37,6 +41,7 @@ func validateScheduleInput(input struct {
 	End              time.Time  `json:"end" binding:"required"`
 	TaskID           *uuid.UUID `json:"task_id"`
 	RecurrenceRuleID *uuid.UUID `json:"recurrence_rule_id"`
+	Pinned           bool       `json:"pinned"`
 }) error {
 	if input.Start.After(input.End) || input.Start.Equal(input.End) {
 		return fmt.Errorf("start time must be before end time")
@@ -55,7 +60,8 @@ func validateScheduleInput(input struct {
 	return nil
 }
 
-// Get all schedule
+// Get all schedule. With from and to query parameters recurring events are expanded
+// into their occurrences within that range.
 func GetSchedule(c *gin.Context) {
 	var schedule []models.ScheduledTask
 	userID, exist := c.Get("userID")
@@ -66,20 +72,58 @@ func GetSchedule(c *gin.Context) {
 		return
 	}
 
-	result := config.GetDB().Preload("Task").Preload("RecurrenceRule").Where("user_id = ?", userID).Find(&schedule)
+	fromParam, toParam := c.Query("from"), c.Query("to")
+	if fromParam == "" && toParam == "" {
+		result := config.GetDB().Preload("Task").Preload("RecurrenceRule").Where("user_id = ?", userID).Find(&schedule)
 
+		if result.Error != nil {
+			log.Println(result.Error)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": result.Error,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"schedule": schedule,
+		})
+		return
+	}
+
+	loc := util.GetUserLocation(c)
+	from, err := parseScheduleBound(fromParam, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 time or a YYYY-MM-DD date"})
+		return
+	}
+	to, err := parseScheduleBound(toParam, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 time or a YYYY-MM-DD date"})
+		return
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+		return
+	}
+	if to.Sub(from) > maxScheduleRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Range cannot exceed one year"})
+		return
+	}
+
+	// Series and their overrides are loaded whatever their dates; other events only when they overlap the range
+	result := config.GetDB().Preload("Task").Preload("RecurrenceRule").
+		Where("user_id = ?", userID).
+		Where(`recurrence_rule_id IS NOT NULL OR recurring_event_id IS NOT NULL OR ("start" < ? AND "end" > ?)`, to, from).
+		Find(&schedule)
 	if result.Error != nil {
 		log.Println(result.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": result.Error,
-		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch schedule"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"schedule": schedule,
+		"schedule": models.ExpandSchedule(schedule, from, to, loc),
 	})
-
 }
 
 // Create a scheduled task
@@ -91,6 +135,7 @@ func CreateSchedule(c *gin.Context) {
 		End              time.Time  `json:"end" binding:"required"`
 		TaskID           *uuid.UUID `json:"task_id"`
 		RecurrenceRuleID *uuid.UUID `json:"recurrence_rule_id"`
+		Pinned           bool       `json:"pinned"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -120,7 +165,7 @@ func CreateSchedule(c *gin.Context) {
 	}
 
 	// Check for time conflicts
-	hasConflict, err := hasTimeConflict(config.GetDB(), userIDUUID, input.Start, input.End, nil)
+	hasConflict, err := hasTimeConflict(config.GetDB(), userIDUUID, input.Start, input.End)
 	if err != nil {
 		log.Println("Error checking for conflicts:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check for scheduling conflicts"})
@@ -139,6 +184,7 @@ func CreateSchedule(c *gin.Context) {
 		UserID:           userIDUUID,
 		TaskID:           input.TaskID,
 		RecurrenceRuleID: input.RecurrenceRuleID,
+		Pinned:           input.Pinned,
 	}
 
 	if err := config.GetDB().Create(&schedule).Error; err != nil {
@@ -150,12 +196,14 @@ func CreateSchedule(c *gin.Context) {
 
 }
 
-// Update a specific task
+// Update a specific task. For recurring events scope chooses whether the change applies
+// to the occurrence at recurrence_id ("this"), to it and the following occurrences
+// ("following") or to the whole series ("all").
 func UpdateSchedule(c *gin.Context) {
 	var schedule models.ScheduledTask
 
 	scheduleTaskID := c.Param("ID")
-	if err := config.GetDB().Where("id = ?", scheduleTaskID).First(&schedule).Error; err != nil {
+	if err := config.GetDB().Preload("RecurrenceRule").Where("id = ?", scheduleTaskID).First(&schedule).Error; err != nil {
 		log.Println("Error ID: ", err.Error())
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Task not found",
@@ -188,6 +236,9 @@ func UpdateSchedule(c *gin.Context) {
 		End              *time.Time `json:"end"`
 		TaskID           *uuid.UUID `json:"task_id"`
 		RecurrenceRuleID *uuid.UUID `json:"recurrence_rule_id"`
+		Pinned           *bool      `json:"pinned"`
+		Scope            string     `json:"scope"`
+		RecurrenceID     *time.Time `json:"recurrence_id"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -196,6 +247,28 @@ func UpdateSchedule(c *gin.Context) {
 		return
 	}
 
+	scope, err := resolveScope(input.Scope, &schedule, input.RecurrenceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Work out which series and occurrence the change applies to. Overrides edited on
+	// their own, and plain or whole-series edits, update the row directly.
+	loc := util.GetUserLocation(c)
+	var master *models.ScheduledTask
+	var recurrenceID time.Time
+	if editsSeries(scope, &schedule) {
+		master, recurrenceID, err = loadSeries(config.GetDB(), &schedule, input.RecurrenceID, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if scope == scopeFollowing && recurrenceID.Equal(master.Start) {
+			scope = scopeAll
+		}
+	}
+
 	// Validate time fields if provided
 	if input.Start != nil && input.End != nil {
 		if input.Start.After(*input.End) || input.Start.Equal(*input.End) {
@@ -209,7 +282,11 @@ func UpdateSchedule(c *gin.Context) {
 		}
 
 		// Check for time conflicts if both start and end are being updated
-		hasConflict, err := hasTimeConflict(config.GetDB(), userIDUUID, *input.Start, *input.End, &schedule.ID)
+		excludeIDs := []uuid.UUID{schedule.ID}
+		if master != nil {
+			excludeIDs = append(excludeIDs, master.ID)
+		}
+		hasConflict, err := hasTimeConflict(config.GetDB(), userIDUUID, *input.Start, *input.End, excludeIDs...)
 		if err != nil {
 			log.Println("Error checking for conflicts:", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check for scheduling conflicts"})
@@ -235,27 +312,60 @@ func UpdateSchedule(c *gin.Context) {
 	if input.TaskID != nil {
 		updatedSchedule["task_id"] = *input.TaskID
 	}
+	if input.Pinned != nil {
+		updatedSchedule["pinned"] = *input.Pinned
+	}
 	if input.RecurrenceRuleID != nil {
+		if scope == scopeThis {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A single occurrence cannot have its own recurrence rule"})
+			return
+		}
 		updatedSchedule["recurrence_rule_id"] = *input.RecurrenceRuleID
 	}
 
-	if err := config.GetDB().Model(&schedule).Updates(updatedSchedule).Error; err != nil {
+	target := &schedule
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		switch {
+		case master == nil:
+		case scope == scopeThis:
+			target, err = findOrCreateOverride(tx, master, recurrenceID)
+		case scope == scopeFollowing:
+			target, err = splitSeries(tx, master, recurrenceID, loc)
+		default:
+			target = master
+		}
+		if err != nil {
+			return err
+		}
+
+		// Moving a series keeps its overrides attached to the occurrences they replace
+		if input.Start != nil && target.IsRecurring() {
+			if err := shiftOverrides(tx, target, input.Start.Sub(target.Start)); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(target).Updates(updatedSchedule).Error
+	})
+	if err != nil {
 		log.Println("Error updating task:", err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// update or remove calendar event based on new due date
-	c.JSON(http.StatusOK, schedule)
+	c.JSON(http.StatusOK, target)
 
 }
 
-// Delete a specific task
+// Delete a specific task. Occurrences of recurring events are deleted with the scope
+// and recurrence_id query parameters, which work as in UpdateSchedule.
 func DeleteSchedule(c *gin.Context) {
 	var schedule models.ScheduledTask
 
 	scheduleTaskID := c.Param("ID")
-	if err := config.GetDB().Where("id = ?", scheduleTaskID).First(&schedule).Error; err != nil {
+	if err := config.GetDB().Preload("RecurrenceRule").Where("id = ?", scheduleTaskID).First(&schedule).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Scheduled task not found",
 		})
@@ -281,44 +391,77 @@ func DeleteSchedule(c *gin.Context) {
 		return
 	}
 
-	// Use transaction for safe deletion
-	tx := config.GetDB().Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	var recurrenceID *time.Time
+	if value := c.Query("recurrence_id"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "recurrence_id must be an RFC 3339 time"})
+			return
 		}
-	}()
+		recurrenceID = &parsed
+	}
 
-	if err := tx.Error; err != nil {
-		log.Println("Error starting transaction:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+	scope, err := resolveScope(c.Query("scope"), &schedule, recurrenceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// If this scheduled task is linked to a task, update the task's due date
-	if schedule.TaskID != nil {
-		if err := tx.Model(&models.Task{}).Where("id = ?", *schedule.TaskID).Update("due_date", nil).Error; err != nil {
-			tx.Rollback()
-			log.Println("Error updating task due date:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update associated task"})
+	// Overrides cancelled on their own and plain or whole-series deletes need no series lookup
+	target := &schedule
+	var master *models.ScheduledTask
+	var occurrence time.Time
+	if editsSeries(scope, &schedule) {
+		master, occurrence, err = loadSeries(config.GetDB(), &schedule, recurrenceID, util.GetUserLocation(c))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if scope == scopeFollowing && occurrence.Equal(master.Start) {
+			scope = scopeAll
+		}
+		if scope == scopeAll {
+			target = master
+		}
 	}
 
-	if err := tx.Delete(&schedule).Error; err != nil {
-		tx.Rollback()
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		switch {
+		case scope == scopeThis:
+			if master != nil {
+				override, err := findOrCreateOverride(tx, master, occurrence)
+				if err != nil {
+					return err
+				}
+				target = override
+			}
+			target.Cancelled = true
+			return tx.Model(target).Update("cancelled", true).Error
+		case scope == scopeFollowing:
+			target = master
+			return endSeriesBefore(tx, master, occurrence)
+		}
+
+		// If this scheduled task is linked to a task, update the task's due date
+		if target.TaskID != nil {
+			if err := tx.Model(&models.Task{}).Where("id = ?", *target.TaskID).Update("due_date", nil).Error; err != nil {
+				return fmt.Errorf("could not update associated task: %w", err)
+			}
+		}
+
+		// Overrides of a series are removed with it
+		if err := tx.Where("recurring_event_id = ?", target.ID).Delete(&models.ScheduledTask{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(target).Error
+	})
+	if err != nil {
 		log.Println("Error deleting scheduled task:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		log.Println("Error committing transaction:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not commit transaction"})
-		return
-	}
-
-	c.JSON(http.StatusOK, schedule)
+	c.JSON(http.StatusOK, target)
 }
 
 // CreateRecurrenceRule creates a new recurrence rule
@@ -386,6 +529,7 @@ func BulkCreateSchedule(c *gin.Context) {
 		End              time.Time  `json:"end" binding:"required"`
 		TaskID           *uuid.UUID `json:"task_id"`
 		RecurrenceRuleID *uuid.UUID `json:"recurrence_rule_id"`
+		Pinned           bool       `json:"pinned"`
 	}
 
 	if err := c.ShouldBindJSON(&inputs); err != nil {
@@ -420,7 +564,7 @@ func BulkCreateSchedule(c *gin.Context) {
 
 	// Check for conflicts
 	for i, input := range inputs {
-		hasConflict, err := hasTimeConflict(config.GetDB(), userIDUUID, input.Start, input.End, nil)
+		hasConflict, err := hasTimeConflict(config.GetDB(), userIDUUID, input.Start, input.End)
 		if err != nil {
 			log.Println("Error checking for conflicts:", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check for scheduling conflicts"})
@@ -458,6 +602,7 @@ func BulkCreateSchedule(c *gin.Context) {
 			UserID:           userIDUUID,
 			TaskID:           input.TaskID,
 			RecurrenceRuleID: input.RecurrenceRuleID,
+			Pinned:           input.Pinned,
 		}
 
 		if err := tx.Create(&schedule).Error; err != nil {
@@ -565,20 +710,22 @@ func BulkDeleteSchedule(c *gin.Context) {
 // CreateCalendarZone creates a new calendar zone
 func CreateCalendarZone(c *gin.Context) {
 	var input struct {
-		Name            string     `json:"name" binding:"required"`
-		Description     string     `json:"description"`
-		Category        string     `json:"category" binding:"required"`
-		Color           string     `json:"color"`
-		StartTime       time.Time  `json:"start_time" binding:"required"`
-		EndTime         time.Time  `json:"end_time" binding:"required"`
-		DaysOfWeek      string     `json:"days_of_week"`
-		Priority        int        `json:"priority"`
-		IsActive        *bool      `json:"is_active"`
-		AllowScheduling *bool      `json:"allow_scheduling"`
-		MaxEventsPerDay *int       `json:"max_events_per_day"`
-		IsRecurring     *bool      `json:"is_recurring"`
-		RecurrenceStart *time.Time `json:"recurrence_start"`
-		RecurrenceEnd   *time.Time `json:"recurrence_end"`
+		Name            string            `json:"name" binding:"required"`
+		Description     string            `json:"description"`
+		Category        string            `json:"category" binding:"required"`
+		Color           string            `json:"color"`
+		StartTime       time.Time         `json:"start_time" binding:"required"`
+		EndTime         time.Time         `json:"end_time" binding:"required"`
+		DaysOfWeek      models.WeekdaySet `json:"days_of_week"`
+		Priority        int               `json:"priority"`
+		IsActive        *bool             `json:"is_active"`
+		AllowScheduling *bool             `json:"allow_scheduling"`
+		MaxEventsPerDay *int              `json:"max_events_per_day"`
+		IsBookable      *bool             `json:"is_bookable"`
+		IsRecurring     *bool             `json:"is_recurring"`
+		RecurrenceStart *time.Time        `json:"recurrence_start"`
+		RecurrenceEnd   *time.Time        `json:"recurrence_end"`
+		AllowOverlap    *bool             `json:"allow_overlap"` // false rejects zones that overlap existing ones
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -595,12 +742,6 @@ func CreateCalendarZone(c *gin.Context) {
 	}
 	userIDUUID := userID.(uuid.UUID)
 
-	// Validate time range
-	if input.StartTime.After(input.EndTime) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Start time must be before end time"})
-		return
-	}
-
 	// Set defaults
 	isActive := true
 	if input.IsActive != nil {
@@ -643,11 +784,28 @@ func CreateCalendarZone(c *gin.Context) {
 		IsActive:        isActive,
 		AllowScheduling: allowScheduling,
 		MaxEventsPerDay: input.MaxEventsPerDay,
+		IsBookable:      input.IsBookable != nil && *input.IsBookable,
 		IsRecurring:     isRecurring,
 		RecurrenceStart: input.RecurrenceStart,
 		RecurrenceEnd:   input.RecurrenceEnd,
 	}
 
+	if err := validateCalendarZone(&zone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	overlaps, err := overlappingZones(config.GetDB(), &zone, util.GetUserLocation(c))
+	if err != nil {
+		config.Logger.Errorf("Error checking calendar zone overlaps for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create calendar zone"})
+		return
+	}
+	if len(overlaps) > 0 && input.AllowOverlap != nil && !*input.AllowOverlap {
+		c.JSON(http.StatusConflict, gin.H{"error": "The zone overlaps other zones", "overlaps": overlaps})
+		return
+	}
+
 	if err := config.GetDB().Create(&zone).Error; err != nil {
 		config.Logger.Errorf("Error creating calendar zone: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create calendar zone"})
@@ -655,7 +813,7 @@ func CreateCalendarZone(c *gin.Context) {
 	}
 
 	config.Logger.Infof("Created calendar zone %s for user %s", zone.ID, userIDUUID)
-	c.JSON(http.StatusCreated, zone)
+	c.JSON(http.StatusCreated, calendarZoneResponse{CalendarZone: zone, Overlaps: overlaps})
 }
 
 // GetCalendarZones gets all calendar zones for the user
@@ -705,20 +863,22 @@ func UpdateCalendarZone(c *gin.Context) {
 	}
 
 	var input struct {
-		Name            *string    `json:"name"`
-		Description     *string    `json:"description"`
-		Category        *string    `json:"category"`
-		Color           *string    `json:"color"`
-		StartTime       *time.Time `json:"start_time"`
-		EndTime         *time.Time `json:"end_time"`
-		DaysOfWeek      *string    `json:"days_of_week"`
-		Priority        *int       `json:"priority"`
-		IsActive        *bool      `json:"is_active"`
-		AllowScheduling *bool      `json:"allow_scheduling"`
-		MaxEventsPerDay *int       `json:"max_events_per_day"`
-		IsRecurring     *bool      `json:"is_recurring"`
-		RecurrenceStart *time.Time `json:"recurrence_start"`
-		RecurrenceEnd   *time.Time `json:"recurrence_end"`
+		Name            *string            `json:"name"`
+		Description     *string            `json:"description"`
+		Category        *string            `json:"category"`
+		Color           *string            `json:"color"`
+		StartTime       *time.Time         `json:"start_time"`
+		EndTime         *time.Time         `json:"end_time"`
+		DaysOfWeek      *models.WeekdaySet `json:"days_of_week"`
+		Priority        *int               `json:"priority"`
+		IsActive        *bool              `json:"is_active"`
+		AllowScheduling *bool              `json:"allow_scheduling"`
+		MaxEventsPerDay *int               `json:"max_events_per_day"`
+		IsBookable      *bool              `json:"is_bookable"`
+		IsRecurring     *bool              `json:"is_recurring"`
+		RecurrenceStart *time.Time         `json:"recurrence_start"`
+		RecurrenceEnd   *time.Time         `json:"recurrence_end"`
+		AllowOverlap    *bool              `json:"allow_overlap"` // false rejects changes that make the zone overlap others
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -727,12 +887,6 @@ func UpdateCalendarZone(c *gin.Context) {
 		return
 	}
 
-	// Validate time range if both times are provided
-	if input.StartTime != nil && input.EndTime != nil && input.StartTime.After(*input.EndTime) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Start time must be before end time"})
-		return
-	}
-
 	// Update fields
 	updates := make(map[string]interface{})
 	if input.Name != nil {
@@ -768,6 +922,9 @@ func UpdateCalendarZone(c *gin.Context) {
 	if input.MaxEventsPerDay != nil {
 		updates["max_events_per_day"] = *input.MaxEventsPerDay
 	}
+	if input.IsBookable != nil {
+		updates["is_bookable"] = *input.IsBookable
+	}
 	if input.IsRecurring != nil {
 		updates["is_recurring"] = *input.IsRecurring
 	}
@@ -778,6 +935,44 @@ func UpdateCalendarZone(c *gin.Context) {
 		updates["recurrence_end"] = *input.RecurrenceEnd
 	}
 
+	// Check the zone as it will be after the update
+	updated := zone
+	if input.StartTime != nil {
+		updated.StartTime = *input.StartTime
+	}
+	if input.EndTime != nil {
+		updated.EndTime = *input.EndTime
+	}
+	if input.DaysOfWeek != nil {
+		updated.DaysOfWeek = *input.DaysOfWeek
+	}
+	if input.IsActive != nil {
+		updated.IsActive = *input.IsActive
+	}
+	if input.IsRecurring != nil {
+		updated.IsRecurring = *input.IsRecurring
+	}
+	if input.RecurrenceStart != nil {
+		updated.RecurrenceStart = input.RecurrenceStart
+	}
+	if input.RecurrenceEnd != nil {
+		updated.RecurrenceEnd = input.RecurrenceEnd
+	}
+	if err := validateCalendarZone(&updated); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	overlaps, err := overlappingZones(config.GetDB(), &updated, util.GetUserLocation(c))
+	if err != nil {
+		config.Logger.Errorf("Error checking calendar zone overlaps for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update calendar zone"})
+		return
+	}
+	if len(overlaps) > 0 && input.AllowOverlap != nil && !*input.AllowOverlap {
+		c.JSON(http.StatusConflict, gin.H{"error": "The zone overlaps other zones", "overlaps": overlaps})
+		return
+	}
+
 	if len(updates) > 0 {
 		if err := config.GetDB().Model(&zone).Updates(updates).Error; err != nil {
 			config.Logger.Errorf("Error updating calendar zone: %v", err)
@@ -794,7 +989,50 @@ func UpdateCalendarZone(c *gin.Context) {
 	}
 
 	config.Logger.Infof("Updated calendar zone %s for user %s", zoneID, userIDUUID)
-	c.JSON(http.StatusOK, zone)
+	c.JSON(http.StatusOK, calendarZoneResponse{CalendarZone: zone, Overlaps: overlaps})
+}
+
+// calendarZoneResponse is a saved zone along with the zones it overlaps, so clients can
+// warn about double-booked hours
+type calendarZoneResponse struct {
+	models.CalendarZone
+	Overlaps []zoneOverlap `json:"overlaps,omitempty"`
+}
+
+// zoneOverlap names another zone whose hours a zone shares
+type zoneOverlap struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// validateCalendarZone checks the zone's hours and recurrence range
+func validateCalendarZone(zone *models.CalendarZone) error {
+	start, end := zone.StartTime.In(time.UTC), zone.EndTime.In(time.UTC)
+	if start.Hour() == end.Hour() && start.Minute() == end.Minute() {
+		return errors.New("start time and end time must differ; an end time before the start time makes the zone run past midnight")
+	}
+	if zone.RecurrenceStart != nil && zone.RecurrenceEnd != nil && zone.RecurrenceEnd.Before(*zone.RecurrenceStart) {
+		return errors.New("recurrence_end must not be before recurrence_start")
+	}
+	return nil
+}
+
+// overlappingZones lists the user's other active zones whose hours meet zone's
+func overlappingZones(db *gorm.DB, zone *models.CalendarZone, loc *time.Location) ([]zoneOverlap, error) {
+	if !zone.IsActive {
+		return nil, nil
+	}
+	var others []models.CalendarZone
+	if err := db.Where("user_id = ? AND id <> ? AND is_active = ?", zone.UserID, zone.ID, true).Find(&others).Error; err != nil {
+		return nil, err
+	}
+	var overlaps []zoneOverlap
+	for i := range others {
+		if zone.Overlaps(&others[i], loc) {
+			overlaps = append(overlaps, zoneOverlap{ID: others[i].ID, Name: others[i].Name})
+		}
+	}
+	return overlaps, nil
 }
 
 // DeleteCalendarZone deletes a calendar zone
@@ -855,9 +1093,12 @@ func GetScheduleSuggestions(c *gin.Context) {
 		return
 	}
 
-	// Get user's pending tasks (exclude tasks that already have due dates)
+	// Get user's unfinished tasks that have no upcoming scheduled work. Due dates act as deadlines.
 	var tasks []models.Task
-	if err := config.GetDB().Where("user_id = ? AND status != ? AND due_date IS NULL", userIDUUID, "completed").Find(&tasks).Error; err != nil {
+	if err := config.GetDB().
+		Where("user_id = ? AND status NOT IN ?", userIDUUID, []string{"completed", "complete"}).
+		Where(`NOT EXISTS (SELECT 1 FROM scheduled_tasks st WHERE st.task_id = tasks.id AND st."end" > ?)`, time.Now()).
+		Find(&tasks).Error; err != nil {
 		config.Logger.Errorf("Error fetching tasks for user %s: %v", userIDUUID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch tasks"})
 		return
@@ -873,35 +1114,45 @@ func GetScheduleSuggestions(c *gin.Context) {
 
 	// Get existing scheduled events to avoid conflicts
 	var existingEvents []models.ScheduledTask
-	if err := config.GetDB().Where("user_id = ?", userIDUUID).Find(&existingEvents).Error; err != nil {
+	if err := config.GetDB().Preload("RecurrenceRule").Where("user_id = ?", userIDUUID).Find(&existingEvents).Error; err != nil {
 		config.Logger.Errorf("Error fetching existing events for user %s: %v", userIDUUID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch existing events"})
 		return
 	}
 
 	// Generate algorithmic scheduling suggestions
-	suggestions, err := ai.GenerateScheduleSuggestions(userIDUUID, tasks, existingEvents)
+	plan, err := ai.GenerateScheduleSuggestions(userIDUUID, tasks, existingEvents, util.GetUserLocation(c))
 	if err != nil {
 		config.Logger.Errorf("Error generating schedule suggestions for user %s: %v", userIDUUID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate scheduling suggestions"})
 		return
 	}
 
+	// Keep the suggestions so responses to them can be recorded and learned from
+	recommendations, err := recordSuggestions(config.GetDB(), userIDUUID, plan)
+	if err != nil {
+		config.Logger.Errorf("Error saving schedule suggestions for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save scheduling suggestions"})
+		return
+	}
+
 	// Convert suggestions to response format
 	var response []gin.H
-	for _, suggestion := range suggestions {
+	for _, rec := range recommendations {
 		response = append(response, gin.H{
-			"id":            suggestion.ID,
-			"title":         suggestion.Title,
-			"start":         suggestion.Start,
-			"end":           suggestion.End,
-			"task_id":       suggestion.TaskID,
+			"id":            rec.ID,
+			"title":         rec.Title,
+			"start":         rec.SuggestedStart,
+			"end":           rec.SuggestedEnd,
+			"task_id":       rec.TaskID,
+			"confidence":    rec.Confidence,
 			"created_by_ai": false, // This is algorithmic, not AI
 		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"suggestions": response,
-		"message":     fmt.Sprintf("Generated %d scheduling suggestions", len(response)),
+		"suggestions":   response,
+		"unschedulable": plan.Unschedulable,
+		"message":       fmt.Sprintf("Generated %d scheduling suggestions", len(response)),
 	})
 }
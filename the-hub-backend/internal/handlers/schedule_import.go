@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/ical"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxICSFileSize         = 10 << 20 // 10MB
+	maxImportedOccurrences = 2000
+	defaultImportHorizon   = 180 * 24 * time.Hour
+)
+
+// ScheduleImportConflict describes an imported event that overlaps an existing one
+type ScheduleImportConflict struct {
+	ExternalUID string    `json:"external_uid"`
+	Title       string    `json:"title"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+}
+
+// ScheduleImportResult summarises an .ics import
+type ScheduleImportResult struct {
+	Imported          int                      `json:"imported"`
+	SkippedDuplicates int                      `json:"skipped_duplicates"`
+	ZonesCreated      int                      `json:"zones_created"`
+	Conflicts         []ScheduleImportConflict `json:"conflicts"`
+	Errors            []string                 `json:"errors"`
+	ScheduledTasks    []models.ScheduledTask   `json:"scheduled_tasks"`
+}
+
+// ImportSchedule imports an iCalendar file into the user's schedule.
+// Recurring events are expanded between from and to (default: now to six months ahead),
+// events already imported (matched by UID) are skipped, and overlaps are reported.
+// Times without a timezone are read in the user's, unless ?timezone= names another.
+func ImportSchedule(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	data, err := readICSUpload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Times without a zone are the user's own unless the calendar names another
+	loc := util.GetUserLocation(c)
+	if tz := c.Query("timezone"); tz != "" {
+		if loc, err = ical.LoadLocation(tz); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown timezone: %s", tz)})
+			return
+		}
+	}
+
+	from := time.Now()
+	to := from.Add(defaultImportHorizon)
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected RFC3339"})
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected RFC3339"})
+			return
+		}
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	createZones, _ := strconv.ParseBool(c.DefaultQuery("create_zones", "false"))
+	skipConflicts, _ := strconv.ParseBool(c.DefaultQuery("skip_conflicts", "false"))
+	zoneCategory := c.DefaultQuery("zone_category", "study")
+
+	cal, err := ical.Parse(strings.NewReader(string(data)))
+	if err != nil {
+		config.Logger.Warnf("Invalid iCalendar upload from user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid iCalendar file", "details": err.Error()})
+		return
+	}
+
+	events, err := ical.ParseEvents(cal, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid iCalendar event", "details": err.Error()})
+		return
+	}
+
+	occurrences := ical.ExpandEvents(events, from, to)
+	if len(occurrences) > maxImportedOccurrences {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Too many events. Maximum %d events per import; narrow the from/to range", maxImportedOccurrences),
+		})
+		return
+	}
+
+	result := ScheduleImportResult{
+		Conflicts:      []ScheduleImportConflict{},
+		Errors:         []string{},
+		ScheduledTasks: []models.ScheduledTask{},
+	}
+
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		var existing []string
+		if err := tx.Model(&models.ScheduledTask{}).
+			Where("user_id = ? AND external_uid IS NOT NULL AND external_uid != ''", userIDUUID).
+			Pluck("external_uid", &existing).Error; err != nil {
+			return err
+		}
+		seen := make(map[string]bool, len(existing))
+		for _, uid := range existing {
+			seen[uid] = true
+		}
+
+		for _, occurrence := range occurrences {
+			key := occurrence.Key()
+			if key == "" || seen[key] {
+				result.SkippedDuplicates++
+				continue
+			}
+			seen[key] = true
+
+			title := occurrence.Summary
+			if title == "" {
+				title = "Imported event"
+			}
+			if !occurrence.End.After(occurrence.Start) {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: event has no duration", title))
+				continue
+			}
+			if occurrence.End.Sub(occurrence.Start) > 24*time.Hour {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: events longer than 24 hours are not supported", title))
+				continue
+			}
+
+			conflict, err := hasTimeConflict(tx, userIDUUID, occurrence.Start, occurrence.End)
+			if err != nil {
+				return err
+			}
+			if conflict {
+				result.Conflicts = append(result.Conflicts, ScheduleImportConflict{
+					ExternalUID: key,
+					Title:       title,
+					Start:       occurrence.Start,
+					End:         occurrence.End,
+				})
+				if skipConflicts {
+					continue
+				}
+			}
+
+			schedule := models.ScheduledTask{
+				Title:       title,
+				Start:       occurrence.Start.UTC(),
+				End:         occurrence.End.UTC(),
+				UserID:      userIDUUID,
+				ExternalUID: key,
+			}
+			if err := tx.Create(&schedule).Error; err != nil {
+				return err
+			}
+			result.ScheduledTasks = append(result.ScheduledTasks, schedule)
+			result.Imported++
+		}
+
+		if createZones {
+			created, err := createZonesFromEvents(tx, userIDUUID, events, zoneCategory, util.GetUserLocation(c))
+			if err != nil {
+				return err
+			}
+			result.ZonesCreated = created
+		}
+
+		return nil
+	})
+	if err != nil {
+		config.Logger.Errorf("Error importing schedule for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import schedule"})
+		return
+	}
+
+	config.Logger.Infof("Imported %d events for user %s (%d duplicates, %d conflicts)",
+		result.Imported, userIDUUID, result.SkippedDuplicates, len(result.Conflicts))
+	c.JSON(http.StatusOK, result)
+}
+
+// readICSUpload reads an .ics file from a multipart "file" field or the raw request body
+func readICSUpload(c *gin.Context) ([]byte, error) {
+	var reader io.Reader
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("No file uploaded")
+		}
+		defer file.Close()
+		if header.Size > maxICSFileSize {
+			return nil, fmt.Errorf("File too large. Maximum size is 10MB")
+		}
+		reader = file
+	} else {
+		reader = c.Request.Body
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxICSFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("Could not read uploaded file")
+	}
+	if len(data) > maxICSFileSize {
+		return nil, fmt.Errorf("File too large. Maximum size is 10MB")
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("No file uploaded")
+	}
+	return data, nil
+}
+
+// ZoneFromEvent turns a weekly recurring event, such as a class in a timetable, into a
+// calendar zone on the same days and hours, as wall-clock times in loc, the user's
+// timezone. ok is false for events that do not make a zone.
+func ZoneFromEvent(event ical.EventSpec, category string, loc *time.Location) (zone models.CalendarZone, ok bool) {
+	if event.RRule == nil || event.RRule.Freq != "WEEKLY" || event.AllDay || event.RecurrenceID != nil {
+		return zone, false
+	}
+
+	// The event's days are in its own timezone; in the user's, its start may fall on
+	// the day before or after
+	start, end := event.Start.In(loc), event.End.In(loc)
+	shift := (start.Weekday() - event.Start.Weekday() + 7) % 7
+	days := models.NewWeekdaySet(start.Weekday())
+	if len(event.RRule.ByDay) > 0 {
+		days = 0
+		for _, d := range event.RRule.ByDay {
+			days |= models.NewWeekdaySet((d.Day + shift) % 7)
+		}
+	}
+
+	name := event.Summary
+	if name == "" {
+		name = "Imported zone"
+	}
+	recurrenceStart := event.Start
+	return models.CalendarZone{
+		Name:            name,
+		Description:     event.Description,
+		Category:        category,
+		Color:           "#3b82f6",
+		StartTime:       time.Date(2000, 1, 1, start.Hour(), start.Minute(), 0, 0, loc),
+		EndTime:         time.Date(2000, 1, 1, end.Hour(), end.Minute(), 0, 0, loc),
+		DaysOfWeek:      days,
+		Priority:        5,
+		IsActive:        true,
+		AllowScheduling: false,
+		IsRecurring:     true,
+		RecurrenceStart: &recurrenceStart,
+		RecurrenceEnd:   event.RRule.Until,
+	}, true
+}
+
+// createZonesFromEvents turns weekly recurring events, such as a class timetable, into
+// calendar zones in the user's timezone loc, skipping zones the user already has
+func createZonesFromEvents(tx *gorm.DB, userID uuid.UUID, events []ical.EventSpec, category string, loc *time.Location) (int, error) {
+	created := 0
+	for _, event := range events {
+		zone, ok := ZoneFromEvent(event, category, loc)
+		if !ok {
+			continue
+		}
+		zone.UserID = userID
+
+		var count int64
+		if err := tx.Model(&models.CalendarZone{}).
+			Where("user_id = ? AND name = ? AND days_of_week = ? AND start_time = ? AND end_time = ?",
+				userID, zone.Name, zone.DaysOfWeek, zone.StartTime, zone.EndTime).
+			Count(&count).Error; err != nil {
+			return created, err
+		}
+		if count > 0 {
+			continue
+		}
+
+		if err := tx.Create(&zone).Error; err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, nil
+}
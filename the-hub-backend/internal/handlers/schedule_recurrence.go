@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/ical"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Edit scopes for recurring events, as offered by calendar apps
+const (
+	scopeThis      = "this"      // this occurrence only
+	scopeFollowing = "following" // this and following occurrences
+	scopeAll       = "all"       // the whole series
+)
+
+// maxScheduleRange bounds how far GetSchedule expands recurring events in one request
+const maxScheduleRange = 366 * 24 * time.Hour
+
+var errNotAnOccurrence = errors.New("recurrence_id is not an occurrence of this event")
+
+// parseScheduleBound reads a range bound given as RFC 3339 or as a date in loc
+func parseScheduleBound(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
+
+// resolveScope picks the edit scope for a request, defaulting to the whole event unless
+// a single occurrence is addressed
+func resolveScope(scope string, schedule *models.ScheduledTask, recurrenceID *time.Time) (string, error) {
+	switch scope {
+	case "":
+		if schedule.RecurringEventID != nil || recurrenceID != nil {
+			return scopeThis, nil
+		}
+		return scopeAll, nil
+	case scopeThis, scopeFollowing, scopeAll:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("invalid scope %q: use this, following or all", scope)
+	}
+}
+
+// editsSeries reports whether a change in scope goes through the series rather than
+// being applied to the addressed row alone
+func editsSeries(scope string, schedule *models.ScheduledTask) bool {
+	if schedule.RecurringEventID != nil {
+		return scope != scopeThis
+	}
+	return scope != scopeAll
+}
+
+// loadSeries returns the series a scoped edit applies to and the occurrence it starts from.
+// For override rows that is the row's master and the occurrence it replaces.
+func loadSeries(db *gorm.DB, schedule *models.ScheduledTask, recurrenceID *time.Time, loc *time.Location) (*models.ScheduledTask, time.Time, error) {
+	master := schedule
+	if schedule.RecurringEventID != nil {
+		master = &models.ScheduledTask{}
+		if err := db.Preload("RecurrenceRule").Where("id = ? AND user_id = ?", *schedule.RecurringEventID, schedule.UserID).
+			First(master).Error; err != nil {
+			return nil, time.Time{}, err
+		}
+		recurrenceID = schedule.RecurrenceID
+	}
+	if !master.IsRecurring() {
+		return nil, time.Time{}, errors.New("this event does not repeat")
+	}
+	if recurrenceID == nil {
+		return nil, time.Time{}, errors.New("recurrence_id is required to edit part of a series")
+	}
+
+	starts, err := master.OccurrenceStarts(*recurrenceID, recurrenceID.Add(time.Second), loc)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	for _, start := range starts {
+		if start.Equal(*recurrenceID) {
+			return master, start, nil
+		}
+	}
+	return nil, time.Time{}, errNotAnOccurrence
+}
+
+// newOverride returns an unsaved override row for the occurrence of master at recurrenceID
+func newOverride(master *models.ScheduledTask, recurrenceID time.Time) models.ScheduledTask {
+	override := master.Occurrence(recurrenceID.UTC())
+	override.ID = uuid.Nil
+	override.RecurrenceRuleID = nil
+	override.RecurrenceRule = nil
+	override.Task = nil
+	override.ExternalUID = ""
+	override.CreatedAt = time.Time{}
+	override.UpdatedAt = time.Time{}
+	return override
+}
+
+// findOrCreateOverride returns the override row for one occurrence of a series, creating
+// it from the occurrence when the occurrence has not been changed before
+func findOrCreateOverride(tx *gorm.DB, master *models.ScheduledTask, recurrenceID time.Time) (*models.ScheduledTask, error) {
+	var override models.ScheduledTask
+	err := tx.Where("recurring_event_id = ? AND recurrence_id = ?", master.ID, recurrenceID.UTC()).First(&override).Error
+	if err == nil {
+		return &override, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	override = newOverride(master, recurrenceID)
+	if err := tx.Omit(clause.Associations).Create(&override).Error; err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// copyRecurrenceRule stores a copy of rule so one series can change without
+// affecting others that share it
+func copyRecurrenceRule(tx *gorm.DB, rule *models.RecurrenceRule, edit func(*models.RecurrenceRule)) (*models.RecurrenceRule, error) {
+	copied := *rule
+	copied.ID = uuid.Nil
+	copied.Tasks = nil
+	copied.CreatedAt = time.Time{}
+	copied.UpdatedAt = time.Time{}
+	edit(&copied)
+	if err := tx.Omit(clause.Associations).Create(&copied).Error; err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
+
+// endSeriesBefore stops a series before the occurrence at recurrenceID and removes the
+// overrides of the occurrences it no longer has
+func endSeriesBefore(tx *gorm.DB, master *models.ScheduledTask, recurrenceID time.Time) error {
+	rule, err := copyRecurrenceRule(tx, master.RecurrenceRule, func(rr *models.RecurrenceRule) {
+		until := recurrenceID.Add(-time.Second)
+		rr.EndDate = &until
+		rr.Count = nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(master).Update("recurrence_rule_id", rule.ID).Error; err != nil {
+		return err
+	}
+	master.RecurrenceRuleID = &rule.ID
+	master.RecurrenceRule = rule
+
+	return tx.Where("recurring_event_id = ? AND recurrence_id >= ?", master.ID, recurrenceID.UTC()).
+		Delete(&models.ScheduledTask{}).Error
+}
+
+// splitSeries ends a series before recurrenceID and starts a new series there with the
+// remaining occurrences. Overrides of those occurrences move to the new series.
+func splitSeries(tx *gorm.DB, master *models.ScheduledTask, recurrenceID time.Time, loc *time.Location) (*models.ScheduledTask, error) {
+	original := master.RecurrenceRule
+
+	remaining := original.Count
+	if original.Count != nil {
+		before, err := master.OccurrenceStarts(master.Start, recurrenceID, loc)
+		if err != nil {
+			return nil, err
+		}
+		left := *original.Count - len(before)
+		remaining = &left
+	}
+	rule, err := copyRecurrenceRule(tx, original, func(rr *models.RecurrenceRule) {
+		rr.Count = remaining
+		start := recurrenceID
+		rr.StartDate = &start
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	series := *master
+	series.ID = uuid.Nil
+	series.Start = recurrenceID
+	series.End = recurrenceID.Add(master.End.Sub(master.Start))
+	series.RecurrenceRuleID = &rule.ID
+	series.RecurrenceRule = nil
+	series.Task = nil
+	series.ExternalUID = ""
+	if err := tx.Omit(clause.Associations).Create(&series).Error; err != nil {
+		return nil, err
+	}
+	series.RecurrenceRule = rule
+
+	if err := tx.Model(&models.ScheduledTask{}).
+		Where("recurring_event_id = ? AND recurrence_id >= ?", master.ID, recurrenceID.UTC()).
+		Update("recurring_event_id", series.ID).Error; err != nil {
+		return nil, err
+	}
+
+	if err := endSeriesBefore(tx, master, recurrenceID); err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// shiftOverrides keeps overrides attached to their occurrences when a series' start moves
+func shiftOverrides(tx *gorm.DB, master *models.ScheduledTask, delta time.Duration) error {
+	if delta == 0 {
+		return nil
+	}
+	var overrides []models.ScheduledTask
+	if err := tx.Where("recurring_event_id = ?", master.ID).Find(&overrides).Error; err != nil {
+		return err
+	}
+	for _, override := range overrides {
+		if override.RecurrenceID == nil {
+			continue
+		}
+		shifted := override.RecurrenceID.Add(delta)
+		if err := tx.Model(&override).Update("recurrence_id", shifted).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupOverrides separates override rows from the events they belong to
+func groupOverrides(rows []models.ScheduledTask) ([]models.ScheduledTask, map[uuid.UUID][]models.ScheduledTask) {
+	var events []models.ScheduledTask
+	overrides := make(map[uuid.UUID][]models.ScheduledTask)
+	for _, row := range rows {
+		if row.RecurringEventID != nil {
+			overrides[*row.RecurringEventID] = append(overrides[*row.RecurringEventID], row)
+		} else {
+			events = append(events, row)
+		}
+	}
+	return events, overrides
+}
+
+// scheduleEvents renders a scheduled task as VEVENTs sharing uid: the event itself and,
+// for a series, one VEVENT per changed occurrence. Cancelled occurrences become EXDATEs.
+func scheduleEvents(item models.ScheduledTask, overrides []models.ScheduledTask, uid string) []ical.Event {
+	event := ical.Event{
+		UID:          uid,
+		Summary:      item.Title,
+		Start:        item.Start,
+		End:          item.End,
+		LastModified: item.UpdatedAt,
+	}
+	if item.Task != nil {
+		event.Description = item.Task.Description
+	}
+	if item.RecurrenceRule == nil {
+		return []ical.Event{event}
+	}
+	event.RRule = item.RecurrenceRule.RRule()
+	if event.RRule == "" {
+		return []ical.Event{event}
+	}
+
+	events := []ical.Event{event}
+	for _, override := range overrides {
+		if override.RecurrenceID == nil {
+			continue
+		}
+		if override.Cancelled {
+			events[0].ExDates = append(events[0].ExDates, *override.RecurrenceID)
+			if override.UpdatedAt.After(events[0].LastModified) {
+				events[0].LastModified = override.UpdatedAt
+			}
+			continue
+		}
+		events = append(events, ical.Event{
+			UID:          uid,
+			Summary:      override.Title,
+			Description:  event.Description,
+			Start:        override.Start,
+			End:          override.End,
+			RecurrenceID: *override.RecurrenceID,
+			LastModified: override.UpdatedAt,
+		})
+	}
+	return events
+}
+
+// saveOverrides replaces a series' overrides with the changed and removed occurrences
+// of an iCalendar event
+func saveOverrides(tx *gorm.DB, master *models.ScheduledTask, events []ical.EventSpec, exdates []time.Time) error {
+	if err := tx.Where("recurring_event_id = ?", master.ID).Delete(&models.ScheduledTask{}).Error; err != nil {
+		return err
+	}
+
+	overrides := make(map[int64]models.ScheduledTask)
+	for _, exdate := range exdates {
+		occurrence := newOverride(master, exdate)
+		occurrence.Cancelled = true
+		overrides[exdate.Unix()] = occurrence
+	}
+	for _, event := range events {
+		if event.RecurrenceID == nil || !event.End.After(event.Start) {
+			continue
+		}
+		occurrence := newOverride(master, *event.RecurrenceID)
+		if event.Summary != "" {
+			occurrence.Title = event.Summary
+		}
+		occurrence.Start = event.Start.UTC()
+		occurrence.End = event.End.UTC()
+		occurrence.Cancelled = event.Status == "CANCELLED"
+		overrides[event.RecurrenceID.Unix()] = occurrence
+	}
+
+	for _, override := range overrides {
+		if err := tx.Omit(clause.Associations).Create(&override).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
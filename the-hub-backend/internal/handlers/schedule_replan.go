@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/ai"
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	errStaleReplan    = errors.New("the schedule changed since this replan was proposed; replan again")
+	errReplanConflict = errors.New("the accepted changes conflict with other events; accept the related changes together or replan again")
+)
+
+// ReplanSchedule godoc
+// @Summary      Replan schedule
+// @Description  Find missed, overrunning and conflicting task blocks and propose moving the affected work. Pinned, recurring and synced events stay where they are. The proposal replaces any earlier pending one.
+// @Tags         schedule
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /schedule/replan [post]
+func ReplanSchedule(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	proposal, err := ai.ProposeReplan(userIDUUID, util.GetUserLocation(c), false)
+	if err != nil {
+		config.Logger.Errorf("Error replanning schedule for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not replan schedule"})
+		return
+	}
+	if proposal == nil {
+		c.JSON(http.StatusOK, gin.H{"proposal": nil, "message": "Nothing needs to be replanned"})
+		return
+	}
+
+	config.Logger.Infof("Proposed replan %s for user %s", proposal.ID, userIDUUID)
+	c.JSON(http.StatusOK, gin.H{"proposal": proposal.ToResponse()})
+}
+
+// GetReplanProposal godoc
+// @Summary      Get pending replan
+// @Description  Get the replan proposal waiting to be accepted or rejected, if any
+// @Tags         schedule
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /schedule/replan [get]
+func GetReplanProposal(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	var proposal models.ReplanProposal
+	err := config.GetDB().Where("user_id = ? AND status = ?", userIDUUID, models.ReplanPending).
+		Order("created_at DESC").First(&proposal).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusOK, gin.H{"proposal": nil})
+		return
+	}
+	if err != nil {
+		config.Logger.Errorf("Error fetching replan proposal for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch replan proposal"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"proposal": proposal.ToResponse()})
+}
+
+// AcceptReplan godoc
+// @Summary      Accept replan
+// @Description  Apply a pending replan proposal. Pass task_ids to apply only the changes for those tasks. Fails with 409 if the affected blocks changed since the proposal was made.
+// @Tags         schedule
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        proposalID  path      string  true  "Replan proposal ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /schedule/replan/{proposalID}/accept [post]
+func AcceptReplan(c *gin.Context) {
+	proposal, userIDUUID, ok := pendingReplan(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		TaskIDs []uuid.UUID `json:"task_ids"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+			return
+		}
+	}
+	selected := make(map[uuid.UUID]bool, len(input.TaskIDs))
+	for _, id := range input.TaskIDs {
+		selected[id] = true
+	}
+
+	var schedule []models.ScheduledTask
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		for _, change := range proposal.GetChanges() {
+			if len(change.After) == 0 || (len(selected) > 0 && !selected[change.TaskID]) {
+				continue
+			}
+			applied, err := applyReplanChange(tx, userIDUUID, change)
+			if err != nil {
+				return err
+			}
+			schedule = append(schedule, applied...)
+		}
+
+		// Check once everything has moved, so blocks can swap places within one proposal
+		for _, block := range schedule {
+			conflict, err := hasTimeConflict(tx, userIDUUID, block.Start, block.End, block.ID)
+			if err != nil {
+				return err
+			}
+			if conflict {
+				return errReplanConflict
+			}
+		}
+
+		now := time.Now()
+		proposal.Status = models.ReplanAccepted
+		proposal.ResolvedAt = &now
+		return tx.Model(proposal).Select("status", "resolved_at").Updates(proposal).Error
+	})
+	if errors.Is(err, errStaleReplan) || errors.Is(err, errReplanConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		config.Logger.Errorf("Error applying replan %s for user %s: %v", proposal.ID, userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not apply replan"})
+		return
+	}
+
+	config.Logger.Infof("Applied replan %s for user %s: %d blocks", proposal.ID, userIDUUID, len(schedule))
+	c.JSON(http.StatusOK, gin.H{"proposal": proposal.ToResponse(), "schedule": schedule})
+}
+
+// RejectReplan godoc
+// @Summary      Reject replan
+// @Description  Reject a pending replan proposal and leave the schedule as it is
+// @Tags         schedule
+// @Produce      json
+// @Security     BearerAuth
+// @Param        proposalID  path      string  true  "Replan proposal ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /schedule/replan/{proposalID}/reject [post]
+func RejectReplan(c *gin.Context) {
+	proposal, userIDUUID, ok := pendingReplan(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	proposal.Status = models.ReplanRejected
+	proposal.ResolvedAt = &now
+	if err := config.GetDB().Model(proposal).Select("status", "resolved_at").Updates(proposal).Error; err != nil {
+		config.Logger.Errorf("Error rejecting replan %s for user %s: %v", proposal.ID, userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not reject replan"})
+		return
+	}
+
+	config.Logger.Infof("Rejected replan %s for user %s", proposal.ID, userIDUUID)
+	c.JSON(http.StatusOK, gin.H{"proposal": proposal.ToResponse()})
+}
+
+// pendingReplan loads the pending proposal named in the request, writing the error
+// response when there is none
+func pendingReplan(c *gin.Context) (*models.ReplanProposal, uuid.UUID, bool) {
+	proposalIDStr := c.Param("proposalID")
+	proposalID, err := uuid.Parse(proposalIDStr)
+	if err != nil {
+		config.Logger.Warnf("Invalid replan proposal ID param: %s", proposalIDStr)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposal ID"})
+		return nil, uuid.Nil, false
+	}
+
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, uuid.Nil, false
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	var proposal models.ReplanProposal
+	if err := config.GetDB().Where("id = ? AND user_id = ?", proposalID, userIDUUID).First(&proposal).Error; err != nil {
+		config.Logger.Warnf("Replan proposal %s not found for user %s", proposalID, userIDUUID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Replan proposal not found"})
+		return nil, uuid.Nil, false
+	}
+	if proposal.Status != models.ReplanPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "This replan has already been " + proposal.Status})
+		return nil, uuid.Nil, false
+	}
+	return &proposal, userIDUUID, true
+}
+
+// applyReplanChange moves the blocks of one change, reusing existing rows where the
+// change says so. Blocks must still be where the proposal found them.
+func applyReplanChange(tx *gorm.DB, userID uuid.UUID, change models.ReplanChange) ([]models.ScheduledTask, error) {
+	current := make(map[uuid.UUID]*models.ScheduledTask, len(change.Before))
+	for _, block := range change.Before {
+		var row models.ScheduledTask
+		err := tx.Where("id = ? AND user_id = ?", *block.ID, userID).First(&row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errStaleReplan
+		}
+		if err != nil {
+			return nil, err
+		}
+		if row.Pinned || !row.Start.Equal(block.Start) || !row.End.Equal(block.End) {
+			return nil, errStaleReplan
+		}
+		current[row.ID] = &row
+	}
+
+	var applied []models.ScheduledTask
+	kept := make(map[uuid.UUID]bool)
+	for _, block := range change.After {
+		if block.ID != nil {
+			row := current[*block.ID]
+			if row == nil {
+				return nil, errStaleReplan
+			}
+			kept[row.ID] = true
+			if err := tx.Model(row).Updates(map[string]interface{}{
+				"title": block.Title,
+				"start": block.Start,
+				"end":   block.End,
+			}).Error; err != nil {
+				return nil, err
+			}
+			applied = append(applied, *row)
+			continue
+		}
+
+		taskID := change.TaskID
+		row := models.ScheduledTask{
+			Title:       block.Title,
+			Start:       block.Start,
+			End:         block.End,
+			UserID:      userID,
+			TaskID:      &taskID,
+			CreatedByAI: true,
+		}
+		if err := tx.Create(&row).Error; err != nil {
+			return nil, err
+		}
+		applied = append(applied, row)
+	}
+
+	for id, row := range current {
+		if !kept[id] {
+			if err := tx.Delete(row).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+	return applied, nil
+}
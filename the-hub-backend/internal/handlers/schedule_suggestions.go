@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/ai"
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errSuggestionNotFound = errors.New("suggestion not found")
+	errSuggestionAnswered = errors.New("suggestion has already been answered or replaced by newer suggestions")
+	errSuggestionConflict = errors.New("suggestion conflicts with an existing event")
+)
+
+// SuggestionResponse answers one schedule suggestion. Modify schedules the block at
+// start-end instead of the suggested time.
+type SuggestionResponse struct {
+	ID     uuid.UUID  `json:"id" binding:"required"`
+	Action string     `json:"action" binding:"required,oneof=accept reject modify"`
+	Start  *time.Time `json:"start"`
+	End    *time.Time `json:"end"`
+}
+
+// RespondToSuggestionsRequest answers several suggestions at once
+type RespondToSuggestionsRequest struct {
+	Responses []SuggestionResponse `json:"responses" binding:"required,min=1,dive"`
+}
+
+// recordSuggestions stores a plan's suggestions, replacing any the user left unanswered
+func recordSuggestions(db *gorm.DB, userID uuid.UUID, plan *ai.ScheduleResult) ([]models.AIRecommendation, error) {
+	recommendations := make([]models.AIRecommendation, 0, len(plan.Suggestions))
+	for i, suggestion := range plan.Suggestions {
+		rec := models.AIRecommendation{
+			UserID:         userID,
+			TaskID:         *suggestion.TaskID,
+			Title:          suggestion.Title,
+			SuggestedStart: suggestion.Start,
+			SuggestedEnd:   suggestion.End,
+			Status:         models.RecommendationPending,
+		}
+		if i < len(plan.Confidence) {
+			rec.Confidence = plan.Confidence[i]
+		}
+		recommendations = append(recommendations, rec)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AIRecommendation{}).
+			Where("user_id = ? AND status = ?", userID, models.RecommendationPending).
+			Update("status", models.RecommendationExpired).Error; err != nil {
+			return err
+		}
+		if len(recommendations) == 0 {
+			return nil
+		}
+		return tx.Create(&recommendations).Error
+	})
+	return recommendations, err
+}
+
+// RespondToSuggestions godoc
+// @Summary      Answer schedule suggestions
+// @Description  Accept, reject or modify pending schedule suggestions. Accepted and modified suggestions are added to the schedule, all in one transaction: if any answer fails, nothing is saved. Answers are learned from when placing later suggestions.
+// @Tags         schedule
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        responses  body      RespondToSuggestionsRequest  true  "Answers"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /schedule/suggestions/respond [post]
+func RespondToSuggestions(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	var input RespondToSuggestionsRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid suggestion responses: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	seen := make(map[uuid.UUID]bool, len(input.Responses))
+	for _, response := range input.Responses {
+		if seen[response.ID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Suggestion %s is answered twice", response.ID)})
+			return
+		}
+		seen[response.ID] = true
+		if response.Action == "modify" && (response.Start == nil || response.End == nil || !response.End.After(*response.Start)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Modified suggestions need a start before their end"})
+			return
+		}
+	}
+
+	var recommendations []models.AIRecommendation
+	var schedule []models.ScheduledTask
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, response := range input.Responses {
+			var rec models.AIRecommendation
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ? AND user_id = ?", response.ID, userIDUUID).First(&rec).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %s", errSuggestionNotFound, response.ID)
+			}
+			if err != nil {
+				return err
+			}
+			if rec.Status != models.RecommendationPending {
+				return fmt.Errorf("%w: %q", errSuggestionAnswered, rec.Title)
+			}
+
+			rec.RespondedAt = &now
+			switch response.Action {
+			case "reject":
+				rec.Status = models.RecommendationRejected
+			case "accept", "modify":
+				start, end := rec.SuggestedStart, rec.SuggestedEnd
+				rec.Status = models.RecommendationAccepted
+				if response.Action == "modify" {
+					start, end = *response.Start, *response.End
+					rec.Status = models.RecommendationModified
+				}
+
+				conflict, err := hasTimeConflict(tx, userIDUUID, start, end)
+				if err != nil {
+					return err
+				}
+				if conflict {
+					return fmt.Errorf("%w: %q", errSuggestionConflict, rec.Title)
+				}
+
+				taskID := rec.TaskID
+				block := models.ScheduledTask{
+					Title:       rec.Title,
+					Start:       start,
+					End:         end,
+					UserID:      userIDUUID,
+					TaskID:      &taskID,
+					CreatedByAI: true,
+				}
+				if err := tx.Create(&block).Error; err != nil {
+					return err
+				}
+				schedule = append(schedule, block)
+
+				rec.Accepted = true
+				rec.FinalStart, rec.FinalEnd = &start, &end
+				rec.ScheduledTaskID = &block.ID
+			}
+
+			if err := tx.Model(&rec).
+				Select("status", "accepted", "final_start", "final_end", "scheduled_task_id", "responded_at").
+				Updates(&rec).Error; err != nil {
+				return err
+			}
+			recommendations = append(recommendations, rec)
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errSuggestionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errSuggestionAnswered), errors.Is(err, errSuggestionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		config.Logger.Errorf("Error answering suggestions for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save suggestion responses"})
+		return
+	}
+
+	config.Logger.Infof("User %s answered %d suggestions, scheduling %d blocks", userIDUUID, len(recommendations), len(schedule))
+	if schedule == nil {
+		schedule = []models.ScheduledTask{}
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestions": recommendations, "schedule": schedule})
+}
+
+// GetSuggestionReport godoc
+// @Summary      Schedule suggestion report
+// @Description  Show how often schedule suggestions were accepted, moved or rejected over the last days (default 90), overall and by hour and weekday
+// @Tags         schedule
+// @Produce      json
+// @Security     BearerAuth
+// @Param        days  query     int  false  "Days to cover (1-365)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /schedule/suggestions/report [get]
+func GetSuggestionReport(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	days := ai.FeedbackWindowDays
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+			return
+		}
+		days = parsed
+	}
+
+	var recommendations []models.AIRecommendation
+	if err := config.GetDB().Where("user_id = ? AND created_at > ?", userIDUUID, time.Now().AddDate(0, 0, -days)).
+		Find(&recommendations).Error; err != nil {
+		config.Logger.Errorf("Error fetching suggestions for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not build suggestion report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":   days,
+		"report": ai.BuildSuggestionReport(recommendations, util.GetUserLocation(c)),
+	})
+}
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/srs"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// fitReviewLimit caps how many of a user's latest reviews parameters are fit to
+const fitReviewLimit = 20000
+
+// loadSRSParameters returns the user's fitted FSRS weights, or the defaults
+func loadSRSParameters(db *gorm.DB, userID uuid.UUID) srs.Parameters {
+	var stored models.SRSParameters
+	if err := db.Where("user_id = ?", userID).First(&stored).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			config.Logger.Warnf("Could not load SRS parameters for user %s: %v", userID, err)
+		}
+		return srs.DefaultParameters
+	}
+	return stored.Parameters()
+}
+
+// deckScheduler returns the scheduler a deck has chosen
+func deckScheduler(deck *models.Deck, params srs.Parameters) srs.Scheduler {
+	scheduler, err := srs.New(deck.Scheduler, params, deck.DesiredRetention)
+	if err != nil {
+		config.Logger.Warnf("Deck %s has unknown scheduler %q, using SM-2", deck.ID, deck.Scheduler)
+		return srs.SM2{}
+	}
+	return scheduler
+}
+
+// rescheduleDeck moves the due date of every reviewed card in the deck to what the
+// deck's scheduler now gives, seeding FSRS state for cards only reviewed with SM-2.
+// Collaborators' own review state is rescheduled with their parameters.
+func rescheduleDeck(tx *gorm.DB, deck *models.Deck, params srs.Parameters) error {
+	scheduler := deckScheduler(deck, params)
+	fsrs := srs.NewFSRS(params, deck.DesiredRetention)
+
+	var cards []models.Card
+	if err := tx.Where("deck_id = ? AND (repetitions > 0 OR stability > 0 OR last_reviewed > ?)", deck.ID, time.Time{}).
+		Find(&cards).Error; err != nil {
+		return err
+	}
+	for i := range cards {
+		updates := reseed(cards[i].Memory(), scheduler, fsrs)
+		if err := tx.Model(&cards[i]).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	var collaborators []uuid.UUID
+	if err := tx.Model(&models.CardProgress{}).Where("deck_id = ?", deck.ID).
+		Distinct("user_id").Pluck("user_id", &collaborators).Error; err != nil {
+		return err
+	}
+	for _, userID := range collaborators {
+		if err := rescheduleProgress(tx, deck, userID, loadSRSParameters(tx, userID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rescheduleProgress reschedules a collaborator's own review state in a deck
+func rescheduleProgress(tx *gorm.DB, deck *models.Deck, userID uuid.UUID, params srs.Parameters) error {
+	scheduler := deckScheduler(deck, params)
+	fsrs := srs.NewFSRS(params, deck.DesiredRetention)
+
+	var progress []models.CardProgress
+	if err := tx.Where("deck_id = ? AND user_id = ? AND (repetitions > 0 OR stability > 0 OR last_reviewed > ?)",
+		deck.ID, userID, time.Time{}).Find(&progress).Error; err != nil {
+		return err
+	}
+	for i := range progress {
+		updates := reseed(progress[i].Memory(), scheduler, fsrs)
+		if err := tx.Model(&progress[i]).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reseed returns the column updates that seed FSRS state from memory and move its
+// due date to what scheduler gives
+func reseed(memory srs.Memory, scheduler srs.Scheduler, fsrs srs.FSRS) map[string]interface{} {
+	seeded := fsrs.Seed(memory)
+	updates := map[string]interface{}{
+		"stability":  seeded.Stability,
+		"difficulty": seeded.Difficulty,
+	}
+	if !memory.LastReviewed.IsZero() {
+		updates["next_review"] = memory.LastReviewed.AddDate(0, 0, scheduler.Interval(seeded))
+	}
+	return updates
+}
+
+// GetSRSParameters godoc
+// @Summary      Get spaced repetition parameters
+// @Description  Get the FSRS weights used for the user's decks: fitted to their reviews if they have been, otherwise the defaults
+// @Tags         cards
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /srs/parameters [get]
+func GetSRSParameters(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	var reviews int64
+	if err := config.GetDB().Model(&models.CardReview{}).Where("user_id = ?", userIDUUID).Count(&reviews).Error; err != nil {
+		config.Logger.Errorf("Error counting reviews for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch parameters"})
+		return
+	}
+
+	var stored models.SRSParameters
+	err := config.GetDB().Where("user_id = ?", userIDUUID).First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusOK, gin.H{
+			"parameters":      srs.DefaultParameters,
+			"fitted":          false,
+			"logged_reviews":  reviews,
+			"min_fit_reviews": srs.MinFitReviews,
+		})
+		return
+	}
+	if err != nil {
+		config.Logger.Errorf("Error fetching SRS parameters for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch parameters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"parameters":      stored.Parameters(),
+		"fitted":          true,
+		"fit":             stored,
+		"logged_reviews":  reviews,
+		"min_fit_reviews": srs.MinFitReviews,
+	})
+}
+
+// FitSRSParameters godoc
+// @Summary      Fit spaced repetition parameters
+// @Description  Fit the FSRS weights to the user's review history and reschedule their FSRS decks with them. Needs enough logged reviews of cards first reviewed after logging began.
+// @Tags         cards
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      422  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /srs/parameters/fit [post]
+func FitSRSParameters(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+	db := config.GetDB()
+
+	var reviews []models.CardReview
+	if err := db.Where("user_id = ?", userIDUUID).Order("reviewed_at DESC").Limit(fitReviewLimit).
+		Find(&reviews).Error; err != nil {
+		config.Logger.Errorf("Error fetching reviews for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch review history"})
+		return
+	}
+
+	// Group by card, oldest first, keeping only cards whose whole history is logged
+	byCard := make(map[uuid.UUID][]srs.ReviewLog)
+	var order []uuid.UUID
+	for i := len(reviews) - 1; i >= 0; i-- {
+		review := reviews[i]
+		history, seen := byCard[review.CardID]
+		if !seen {
+			if !review.FirstReview {
+				byCard[review.CardID] = nil
+				continue
+			}
+			order = append(order, review.CardID)
+		} else if history == nil {
+			continue
+		}
+		byCard[review.CardID] = append(history, srs.ReviewLog{
+			Rating:      srs.Rating(review.Rating),
+			ElapsedDays: float64(review.ElapsedDays),
+		})
+	}
+	histories := make([][]srs.ReviewLog, 0, len(order))
+	for _, cardID := range order {
+		histories = append(histories, byCard[cardID])
+	}
+
+	result, err := srs.Fit(histories, srs.DefaultParameters)
+	if errors.Is(err, srs.ErrTooFewReviews) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":           err.Error(),
+			"min_fit_reviews": srs.MinFitReviews,
+		})
+		return
+	}
+	if err != nil {
+		config.Logger.Errorf("Error fitting SRS parameters for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fit parameters"})
+		return
+	}
+
+	stored := models.SRSParameters{
+		UserID:     userIDUUID,
+		Reviews:    result.Reviews,
+		LossBefore: result.LossBefore,
+		LossAfter:  result.LossAfter,
+		FittedAt:   time.Now(),
+	}
+	if err := stored.SetParameters(result.Parameters); err != nil {
+		config.Logger.Errorf("Error encoding SRS parameters for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save parameters"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"weights", "reviews", "loss_before", "loss_after", "fitted_at", "updated_at"}),
+		}).Create(&stored).Error; err != nil {
+			return err
+		}
+		return rescheduleUserDecks(tx, userIDUUID, result.Parameters)
+	})
+	if err != nil {
+		config.Logger.Errorf("Error saving SRS parameters for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save parameters"})
+		return
+	}
+
+	config.Logger.Infof("Fit SRS parameters for user %s on %d reviews, log loss %.4f -> %.4f",
+		userIDUUID, result.Reviews, result.LossBefore, result.LossAfter)
+	c.JSON(http.StatusOK, gin.H{"parameters": result.Parameters, "fit": result})
+}
+
+// ResetSRSParameters godoc
+// @Summary      Reset spaced repetition parameters
+// @Description  Forget the fitted FSRS weights and reschedule the user's FSRS decks with the defaults
+// @Tags         cards
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /srs/parameters [delete]
+func ResetSRSParameters(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userIDUUID).Delete(&models.SRSParameters{}).Error; err != nil {
+			return err
+		}
+		return rescheduleUserDecks(tx, userIDUUID, srs.DefaultParameters)
+	})
+	if err != nil {
+		config.Logger.Errorf("Error resetting SRS parameters for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not reset parameters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"parameters": srs.DefaultParameters})
+}
+
+// rescheduleUserDecks reschedules the user's FSRS decks, and their own state in FSRS
+// decks shared with them, after their weights change
+func rescheduleUserDecks(tx *gorm.DB, userID uuid.UUID, params srs.Parameters) error {
+	var decks []models.Deck
+	if err := tx.Where("user_id = ? AND scheduler = ?", userID, srs.SchedulerFSRS).Find(&decks).Error; err != nil {
+		return err
+	}
+	for i := range decks {
+		if err := rescheduleDeck(tx, &decks[i], params); err != nil {
+			return err
+		}
+	}
+
+	var shared []models.Deck
+	if err := tx.Joins("JOIN deck_users ON deck_users.deck_id = decks.id").
+		Where("deck_users.user_id = ? AND decks.scheduler = ?", userID, srs.SchedulerFSRS).
+		Find(&shared).Error; err != nil {
+		return err
+	}
+	for i := range shared {
+		if err := rescheduleProgress(tx, &shared[i], userID, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
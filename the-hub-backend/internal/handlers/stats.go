@@ -7,6 +7,7 @@ import (
 
 	"github.com/TheoMKgosi/The-hub/internal/config"
 	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -47,17 +48,18 @@ func GetTaskStats(c *gin.Context) {
 	endDateStr := c.Query("end_date")
 
 	var startDate, endDate time.Time
-	now := time.Now()
+	loc := util.GetUserLocation(c)
+	now := time.Now().In(loc)
 
 	// Calculate date range based on period or explicit dates
 	if startDateStr != "" && endDateStr != "" {
 		var err error
-		startDate, err = time.Parse("2006-01-02", startDateStr)
+		startDate, err = time.ParseInLocation("2006-01-02", startDateStr, loc)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format. Use YYYY-MM-DD"})
 			return
 		}
-		endDate, err = time.Parse("2006-01-02", endDateStr)
+		endDate, err = time.ParseInLocation("2006-01-02", endDateStr, loc)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format. Use YYYY-MM-DD"})
 			return
@@ -66,12 +68,12 @@ func GetTaskStats(c *gin.Context) {
 		// Use period-based date ranges
 		switch period {
 		case "today":
-			startDate = now.Truncate(24 * time.Hour)
-			endDate = startDate.Add(24 * time.Hour)
+			startDate = util.StartOfDay(now)
+			endDate = startDate.AddDate(0, 0, 1)
 		case "week":
 			// Start of week (Monday)
-			days := int(now.Weekday()-time.Monday) % 7
-			startDate = now.AddDate(0, 0, -days).Truncate(24 * time.Hour)
+			days := (int(now.Weekday()) + 6) % 7
+			startDate = util.StartOfDay(now.AddDate(0, 0, -days))
 			endDate = startDate.AddDate(0, 0, 7)
 		case "month":
 			startDate = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
@@ -147,9 +149,10 @@ func calculateTaskStats(userID uuid.UUID, startDate, endDate time.Time) (map[str
 		},
 	}
 
-	// Calculate current date references
-	now := time.Now()
-	today := now.Truncate(24 * time.Hour)
+	// Calculate current date references in the timezone the range was built in
+	loc := startDate.Location()
+	now := time.Now().In(loc)
+	today := util.StartOfDay(now)
 	tomorrow := today.AddDate(0, 0, 1)
 	weekFromNow := today.AddDate(0, 0, 7)
 
@@ -190,10 +193,10 @@ func calculateTaskStats(userID uuid.UUID, startDate, endDate time.Time) (map[str
 
 		// Time-based analysis
 		if task.DueDate != nil {
-			dueDate := *task.DueDate
-			if dueDate.Truncate(24 * time.Hour).Equal(today) {
+			dueDate := task.DueDate.In(loc)
+			if util.StartOfDay(dueDate).Equal(today) {
 				stats["time_based"].(map[string]int)["due_today"]++
-			} else if dueDate.Truncate(24 * time.Hour).Equal(tomorrow) {
+			} else if util.StartOfDay(dueDate).Equal(tomorrow) {
 				stats["time_based"].(map[string]int)["due_tomorrow"]++
 			} else if dueDate.After(today) && dueDate.Before(weekFromNow) {
 				stats["time_based"].(map[string]int)["due_this_week"]++
@@ -344,12 +347,12 @@ func GetTaskStatsTrends(c *gin.Context) {
 
 	// Calculate trends for each day in the period
 	trends := make([]map[string]interface{}, 0, days)
-	now := time.Now()
+	now := time.Now().In(util.GetUserLocation(c))
 
 	for i := days - 1; i >= 0; i-- {
 		date := now.AddDate(0, 0, -i)
-		startDate := date.Truncate(24 * time.Hour)
-		endDate := startDate.Add(24 * time.Hour)
+		startDate := util.StartOfDay(date)
+		endDate := startDate.AddDate(0, 0, 1)
 
 		stats, err := calculateTaskStats(userIDUUID, startDate, endDate)
 		if err != nil {
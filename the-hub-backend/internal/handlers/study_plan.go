@@ -0,0 +1,634 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/booking"
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/studyplan"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// studyZoneCategory is the calendar zone category study is planned into
+const studyZoneCategory = "study"
+
+var errNoStudyZones = errors.New("no active study calendar zones")
+
+// CreateStudyPlanRequest represents the request body for creating a study plan. It is
+// for either a topic or a learning path.
+type CreateStudyPlanRequest struct {
+	TopicID        *uuid.UUID  `json:"topic_id"`
+	LearningPathID *uuid.UUID  `json:"learning_path_id"`
+	Title          string      `json:"title"`
+	Deadline       *time.Time  `json:"deadline" example:"2025-06-10T09:00:00Z"` // Defaults to the topic's deadline
+	EffortMinutes  int         `json:"effort_minutes" binding:"required,min=15" example:"1200"`
+	MinutesPerDay  int         `json:"minutes_per_day" binding:"omitempty,min=15" example:"90"`
+	DeckIDs        []uuid.UUID `json:"deck_ids"` // Decks to review alongside the study
+}
+
+// UpdateStudyPlanRequest represents the request body for updating a study plan. The
+// plan is planned again afterwards.
+type UpdateStudyPlanRequest struct {
+	Title         *string      `json:"title"`
+	Deadline      *time.Time   `json:"deadline"`
+	EffortMinutes *int         `json:"effort_minutes" binding:"omitempty,min=15"`
+	MinutesPerDay *int         `json:"minutes_per_day" binding:"omitempty,min=0"`
+	DeckIDs       *[]uuid.UUID `json:"deck_ids"`
+}
+
+// StudyPlanResponse is a study plan with its blocks and how much study is left
+type StudyPlanResponse struct {
+	models.StudyPlan
+	DeckIDs          []uuid.UUID            `json:"deck_ids"`
+	RemainingMinutes int                    `json:"remaining_minutes"`
+	Blocks           []models.ScheduledTask `json:"blocks"`
+}
+
+// studyPlanTopicIDs returns the topics a plan covers: its topic, or its learning path's topics
+func studyPlanTopicIDs(db *gorm.DB, plan *models.StudyPlan) ([]uuid.UUID, error) {
+	if plan.TopicID != nil {
+		return []uuid.UUID{*plan.TopicID}, nil
+	}
+	topicIDs := []uuid.UUID{}
+	if plan.LearningPathID == nil {
+		return topicIDs, nil
+	}
+	err := db.Model(&models.LearningPathTopic{}).Where("learning_path_id = ?", *plan.LearningPathID).
+		Pluck("topic_id", &topicIDs).Error
+	return topicIDs, err
+}
+
+// studyPlanStudiedMinutes totals the study sessions finished since the plan was made
+// on its topics, their learning tasks or its decks
+func studyPlanStudiedMinutes(db *gorm.DB, plan *models.StudyPlan) (int, error) {
+	topicIDs, err := studyPlanTopicIDs(db, plan)
+	if err != nil {
+		return 0, err
+	}
+	var minutes int
+	err = db.Model(&models.StudySession{}).Select("COALESCE(SUM(duration_min), 0)").
+		Where("user_id = ? AND status = ? AND ended_at >= ?", plan.UserID, models.StudySessionCompleted, plan.CreatedAt).
+		Where(db.Where("topic_id IN ?", topicIDs).
+			Or("task_id IN (?)", db.Model(&models.Task_learning{}).Select("id").Where("topic_id IN ?", topicIDs)).
+			Or("deck_id IN ?", plan.DeckIDs())).
+		Scan(&minutes).Error
+	return minutes, err
+}
+
+// studyPlanDecks loads the cards of the plan's decks that the user can still study,
+// with when each is next due for them
+func studyPlanDecks(db *gorm.DB, plan *models.StudyPlan) ([]studyplan.Deck, error) {
+	var decks []studyplan.Deck
+	for _, deckID := range plan.DeckIDs() {
+		var deck models.Deck
+		err := db.Where("id = ?", deckID).First(&deck).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		role, err := deckRole(db, &deck, plan.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if role == "" {
+			continue
+		}
+		var cards []models.Card
+		if err := db.Where("deck_id = ?", deckID).Find(&cards).Error; err != nil {
+			return nil, err
+		}
+		if err := withUserState(db, cards, plan.UserID, role); err != nil {
+			return nil, err
+		}
+		studied := studyplan.Deck{ID: deck.ID, Name: deck.Name}
+		for _, card := range cards {
+			if !card.Suspended {
+				studied.Due = append(studied.Due, card.NextReview)
+			}
+		}
+		decks = append(decks, studied)
+	}
+	return decks, nil
+}
+
+// planStudyPlan plans a plan's study again from now: upcoming blocks that are not pinned
+// are replaced by blocks for the study still left, and the plan is completed once the
+// study logged against it covers its effort. Blocks kept, because they are pinned or
+// under way, count towards the study left.
+func planStudyPlan(tx *gorm.DB, plan *models.StudyPlan, now time.Time) error {
+	if err := tx.Where(`study_plan_id = ? AND "start" >= ? AND pinned = ?`, plan.ID, now, false).
+		Delete(&models.ScheduledTask{}).Error; err != nil {
+		return err
+	}
+	if plan.Status != models.StudyPlanActive {
+		return nil
+	}
+
+	studied, err := studyPlanStudiedMinutes(tx, plan)
+	if err != nil {
+		return err
+	}
+	plan.StudiedMinutes = studied
+	plan.UnplannedMinutes = 0
+	plan.PlannedAt = &now
+
+	remaining := plan.EffortMinutes - studied
+	var kept []models.ScheduledTask
+	if err := tx.Where(`study_plan_id = ? AND "end" > ?`, plan.ID, now).Find(&kept).Error; err != nil {
+		return err
+	}
+	for _, block := range kept {
+		start := block.Start
+		if start.Before(now) {
+			start = now
+		}
+		remaining -= int(block.End.Sub(start) / time.Minute)
+	}
+
+	switch {
+	case studied >= plan.EffortMinutes:
+		plan.Status = models.StudyPlanCompleted
+	case remaining > 0 || len(plan.DeckIDs()) > 0:
+		loc := util.LoadUserLocation(tx, plan.UserID)
+		now = now.In(loc)
+
+		allZones, err := models.LoadCalendarZones(tx, plan.UserID)
+		if err != nil {
+			return err
+		}
+		var zones []models.CalendarZone
+		for _, zone := range allZones {
+			if strings.EqualFold(zone.Category, studyZoneCategory) {
+				zones = append(zones, zone)
+			}
+		}
+		if len(zones) == 0 {
+			return errNoStudyZones
+		}
+
+		var schedule []models.ScheduledTask
+		if err := tx.Preload("RecurrenceRule").Where("user_id = ?", plan.UserID).
+			Where(`recurrence_rule_id IS NOT NULL OR recurring_event_id IS NOT NULL OR ("start" < ? AND "end" > ?)`, plan.Deadline, now).
+			Find(&schedule).Error; err != nil {
+			return err
+		}
+		decks, err := studyPlanDecks(tx, plan)
+		if err != nil {
+			return err
+		}
+
+		planned := studyplan.Build(studyplan.Input{
+			Title:         plan.Title,
+			Now:           now,
+			Deadline:      plan.Deadline,
+			StudyMinutes:  max(remaining, 0),
+			Zones:         zones,
+			Busy:          booking.Busy(schedule, now, plan.Deadline, loc),
+			Decks:         decks,
+			MinutesPerDay: plan.MinutesPerDay,
+		})
+		plan.UnplannedMinutes = planned.UnplannedMinutes
+		if len(planned.Blocks) > 0 {
+			blocks := make([]models.ScheduledTask, len(planned.Blocks))
+			for i, block := range planned.Blocks {
+				blocks[i] = models.ScheduledTask{
+					Title:       block.Title,
+					Start:       block.Start,
+					End:         block.End,
+					UserID:      plan.UserID,
+					CreatedByAI: true,
+					StudyPlanID: &plan.ID,
+				}
+			}
+			if err := tx.Create(&blocks).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Model(plan).Select("status", "studied_minutes", "unplanned_minutes", "planned_at").Updates(plan).Error
+}
+
+// adjustStudyPlans plans again the user's active study plans that a finished session
+// counts towards. Failures are logged rather than failing the session.
+func adjustStudyPlans(db *gorm.DB, session *models.StudySession) {
+	if session.Status != models.StudySessionCompleted {
+		return
+	}
+	var plans []models.StudyPlan
+	if err := db.Where("user_id = ? AND status = ?", session.UserID, models.StudyPlanActive).Find(&plans).Error; err != nil {
+		config.Logger.Errorf("Error fetching study plans for user %s: %v", session.UserID, err)
+		return
+	}
+	if len(plans) == 0 {
+		return
+	}
+
+	topicID := session.TopicID
+	if session.TaskID != nil {
+		var task models.Task_learning
+		if err := db.Select("topic_id").Where("id = ?", *session.TaskID).First(&task).Error; err == nil {
+			topicID = &task.TopicID
+		}
+	}
+	for i := range plans {
+		plan := &plans[i]
+		counts := false
+		if session.DeckID != nil {
+			for _, deckID := range plan.DeckIDs() {
+				counts = counts || deckID == *session.DeckID
+			}
+		}
+		if topicID != nil && !counts {
+			topicIDs, err := studyPlanTopicIDs(db, plan)
+			if err != nil {
+				config.Logger.Errorf("Error fetching topics of study plan %s: %v", plan.ID, err)
+				continue
+			}
+			for _, id := range topicIDs {
+				counts = counts || id == *topicID
+			}
+		}
+		if !counts {
+			continue
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return planStudyPlan(tx, plan, time.Now())
+		}); err != nil {
+			config.Logger.Errorf("Error planning study plan %s again after session %s: %v", plan.ID, session.ID, err)
+			continue
+		}
+		config.Logger.Infof("Planned study plan %s again after session %s", plan.ID, session.ID)
+	}
+}
+
+// respondWithStudyPlan writes a plan with its blocks
+func respondWithStudyPlan(c *gin.Context, status int, plan *models.StudyPlan) {
+	response := StudyPlanResponse{
+		StudyPlan:        *plan,
+		DeckIDs:          plan.DeckIDs(),
+		RemainingMinutes: max(plan.EffortMinutes-plan.StudiedMinutes, 0),
+		Blocks:           []models.ScheduledTask{},
+	}
+	if err := config.GetDB().Where("study_plan_id = ?", plan.ID).Order(`"start"`).Find(&response.Blocks).Error; err != nil {
+		config.Logger.Errorf("Error fetching blocks of study plan %s: %v", plan.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch study plan blocks"})
+		return
+	}
+	c.JSON(status, response)
+}
+
+// respondWithPlanningError writes the response for a plan that could not be planned
+func respondWithPlanningError(c *gin.Context, plan *models.StudyPlan, err error) {
+	if errors.Is(err, errNoStudyZones) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Add an active calendar zone with the study category to plan study into"})
+		return
+	}
+	config.Logger.Errorf("Error planning study plan %s for user %s: %v", plan.ID, plan.UserID, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not plan study"})
+}
+
+// ownedStudyPlan loads one of the user's study plans from the ID param, writing the
+// error response otherwise
+func ownedStudyPlan(c *gin.Context) (*models.StudyPlan, bool) {
+	planID, err := uuid.Parse(c.Param("ID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid study plan ID"})
+		return nil, false
+	}
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	var plan models.StudyPlan
+	if err := config.GetDB().Where("id = ? AND user_id = ?", planID, userID).First(&plan).Error; err != nil {
+		config.Logger.Warnf("Study plan ID %s not found for user %v: %v", planID, userID, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Study plan not found"})
+		return nil, false
+	}
+	return &plan, true
+}
+
+// validStudyPlanDecks checks the user can study every deck, writing the error response otherwise
+func validStudyPlanDecks(c *gin.Context, userID uuid.UUID, deckIDs []uuid.UUID) bool {
+	for _, deckID := range deckIDs {
+		if _, ok := accessibleDeck(c, deckID, userID, models.DeckRoleViewer); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateStudyPlan godoc
+// @Summary      Create a study plan
+// @Description  Plan the study of a topic or learning path up to a deadline. The estimated effort, less the study already logged, is spread evenly over the free time in the user's study calendar zones, with reviews of the given decks' due cards before each day's study. The plan is planned again as study sessions on its topics, their learning tasks or its decks are logged.
+// @Tags         study-plans
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        study_plan  body      CreateStudyPlanRequest  true  "What to study and by when"
+// @Success      201  {object}  StudyPlanResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /study-plans [post]
+func CreateStudyPlan(c *gin.Context) {
+	var input CreateStudyPlanRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid study plan input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input for study plan", "details": err.Error()})
+		return
+	}
+
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context during study plan creation")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		config.Logger.Errorf("Invalid userID type in context: %T", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if (input.TopicID == nil) == (input.LearningPathID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Give either a topic_id or a learning_path_id"})
+		return
+	}
+
+	plan := models.StudyPlan{
+		UserID:         userIDUUID,
+		TopicID:        input.TopicID,
+		LearningPathID: input.LearningPathID,
+		Title:          input.Title,
+		EffortMinutes:  input.EffortMinutes,
+		MinutesPerDay:  input.MinutesPerDay,
+		Status:         models.StudyPlanActive,
+	}
+	if input.TopicID != nil {
+		var topic models.Topic
+		if err := config.GetDB().Where("id = ? AND user_id = ?", *input.TopicID, userIDUUID).First(&topic).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Topic not found"})
+			return
+		}
+		if plan.Title == "" {
+			plan.Title = topic.Title
+		}
+		if input.Deadline == nil {
+			input.Deadline = topic.Deadline
+		}
+	} else {
+		var path models.LearningPath
+		if err := config.GetDB().Where("id = ? AND user_id = ?", *input.LearningPathID, userIDUUID).First(&path).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Learning path not found"})
+			return
+		}
+		if plan.Title == "" {
+			plan.Title = path.Title
+		}
+	}
+
+	now := time.Now()
+	if input.Deadline == nil || !input.Deadline.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A study plan needs a deadline in the future"})
+		return
+	}
+	plan.Deadline = *input.Deadline
+	if !validStudyPlanDecks(c, userIDUUID, input.DeckIDs) {
+		return
+	}
+	if err := plan.SetDeckIDs(input.DeckIDs); err != nil {
+		config.Logger.Errorf("Error encoding study plan decks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create study plan"})
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&plan).Error; err != nil {
+			return err
+		}
+		return planStudyPlan(tx, &plan, now)
+	})
+	if err != nil {
+		respondWithPlanningError(c, &plan, err)
+		return
+	}
+
+	config.Logger.Infof("Created study plan %s for user %s: %d minutes by %s", plan.ID, userIDUUID, plan.EffortMinutes, plan.Deadline)
+	respondWithStudyPlan(c, http.StatusCreated, &plan)
+}
+
+// GetStudyPlans godoc
+// @Summary      Get study plans
+// @Description  Fetch the logged-in user's study plans, nearest deadline first
+// @Tags         study-plans
+// @Produce      json
+// @Security     BearerAuth
+// @Param        status  query     string  false  "Filter by status: active or completed"
+// @Success      200  {object}  map[string][]models.StudyPlan
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /study-plans [get]
+func GetStudyPlans(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	query := config.GetDB().Where("user_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var plans []models.StudyPlan
+	if err := query.Order("deadline").Find(&plans).Error; err != nil {
+		config.Logger.Errorf("Error fetching study plans for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch study plans"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"study_plans": plans})
+}
+
+// GetStudyPlan godoc
+// @Summary      Get a study plan
+// @Description  Fetch one of the logged-in user's study plans with its blocks
+// @Tags         study-plans
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Study plan ID"
+// @Success      200  {object}  StudyPlanResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /study-plans/{ID} [get]
+func GetStudyPlan(c *gin.Context) {
+	plan, ok := ownedStudyPlan(c)
+	if !ok {
+		return
+	}
+	respondWithStudyPlan(c, http.StatusOK, plan)
+}
+
+// UpdateStudyPlan godoc
+// @Summary      Update a study plan
+// @Description  Change a study plan's deadline, effort, daily limit or decks and plan it again
+// @Tags         study-plans
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID          path      string                  true  "Study plan ID"
+// @Param        study_plan  body      UpdateStudyPlanRequest  true  "Study plan update data"
+// @Success      200  {object}  StudyPlanResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /study-plans/{ID} [patch]
+func UpdateStudyPlan(c *gin.Context) {
+	plan, ok := ownedStudyPlan(c)
+	if !ok {
+		return
+	}
+	var input UpdateStudyPlanRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid update input for study plan %s: %v", plan.ID, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	if input.Title != nil && *input.Title != "" {
+		plan.Title = *input.Title
+	}
+	if input.Deadline != nil {
+		if !input.Deadline.After(now) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A study plan needs a deadline in the future"})
+			return
+		}
+		plan.Deadline = *input.Deadline
+	}
+	if input.EffortMinutes != nil {
+		plan.EffortMinutes = *input.EffortMinutes
+		// More effort can reopen a completed plan; planning completes it again if not
+		plan.Status = models.StudyPlanActive
+	}
+	if input.MinutesPerDay != nil {
+		plan.MinutesPerDay = *input.MinutesPerDay
+	}
+	if input.DeckIDs != nil {
+		if !validStudyPlanDecks(c, plan.UserID, *input.DeckIDs) {
+			return
+		}
+		if err := plan.SetDeckIDs(*input.DeckIDs); err != nil {
+			config.Logger.Errorf("Error encoding study plan decks: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update study plan"})
+			return
+		}
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(plan).Select("title", "deadline", "effort_minutes", "minutes_per_day", "decks", "status").
+			Updates(plan).Error; err != nil {
+			return err
+		}
+		return planStudyPlan(tx, plan, now)
+	})
+	if err != nil {
+		respondWithPlanningError(c, plan, err)
+		return
+	}
+
+	config.Logger.Infof("Updated study plan %s for user %s", plan.ID, plan.UserID)
+	respondWithStudyPlan(c, http.StatusOK, plan)
+}
+
+// ReplanStudyPlan godoc
+// @Summary      Plan a study plan again
+// @Description  Replace a study plan's upcoming blocks that are not pinned, for instance after the calendar changed
+// @Tags         study-plans
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Study plan ID"
+// @Success      200  {object}  StudyPlanResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /study-plans/{ID}/replan [post]
+func ReplanStudyPlan(c *gin.Context) {
+	plan, ok := ownedStudyPlan(c)
+	if !ok {
+		return
+	}
+	if plan.Status != models.StudyPlanActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Study plan is " + plan.Status, "status": plan.Status})
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		return planStudyPlan(tx, plan, time.Now())
+	})
+	if err != nil {
+		respondWithPlanningError(c, plan, err)
+		return
+	}
+	respondWithStudyPlan(c, http.StatusOK, plan)
+}
+
+// DeleteStudyPlan godoc
+// @Summary      Delete a study plan
+// @Description  Delete a study plan and its upcoming blocks. Blocks already past stay on the calendar.
+// @Tags         study-plans
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Study plan ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /study-plans/{ID} [delete]
+func DeleteStudyPlan(c *gin.Context) {
+	plan, ok := ownedStudyPlan(c)
+	if !ok {
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(`study_plan_id = ? AND "start" >= ?`, plan.ID, time.Now()).
+			Delete(&models.ScheduledTask{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ScheduledTask{}).Where("study_plan_id = ?", plan.ID).
+			Update("study_plan_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(plan).Error
+	})
+	if err != nil {
+		config.Logger.Errorf("Failed to delete study plan %s: %v", plan.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete study plan"})
+		return
+	}
+
+	config.Logger.Infof("Deleted study plan %s for user %s", plan.ID, plan.UserID)
+	c.JSON(http.StatusOK, gin.H{"message": "Study plan deleted successfully"})
+}
@@ -1,14 +1,20 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/TheoMKgosi/The-hub/internal/config"
 	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/srs"
+	"github.com/TheoMKgosi/The-hub/internal/util"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateStudySessionRequest represents the request body for creating a study session
@@ -19,9 +25,36 @@ type CreateStudySessionRequest struct {
 	Notes       string     `json:"notes"`
 }
 
+// validStudySessionTargets checks that the topic and task a session is for, when
+// given, belong to the user, writing the error response otherwise
+func validStudySessionTargets(c *gin.Context, userID uuid.UUID, topicID, taskID *uuid.UUID) bool {
+	// Validate that topic exists and belongs to user (if provided)
+	if topicID != nil {
+		var topic models.Topic
+		if err := config.GetDB().Where("id = ? AND user_id = ?", topicID, userID).First(&topic).Error; err != nil {
+			config.Logger.Warnf("Topic ID %s not found or not owned by user %s", topicID, userID)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Topic not found or access denied"})
+			return false
+		}
+	}
+
+	// Validate that task exists and belongs to user's topic (if provided)
+	if taskID != nil {
+		var task models.Task_learning
+		if err := config.GetDB().Joins("JOIN topics ON task_learnings.topic_id = topics.id").
+			Where("task_learnings.id = ? AND topics.user_id = ?", taskID, userID).
+			First(&task).Error; err != nil {
+			config.Logger.Warnf("Task ID %s not found or not owned by user %s", taskID, userID)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Task not found or access denied"})
+			return false
+		}
+	}
+	return true
+}
+
 // CreateStudySession godoc
-// @Summary      Create a new study session
-// @Description  Create a new study session for the logged-in user
+// @Summary      Log a study session
+// @Description  Log a study session that has just ended for the logged-in user. Use the start endpoint instead to time a session live.
 // @Tags         study-sessions
 // @Accept       json
 // @Produce      json
@@ -55,35 +88,21 @@ func CreateStudySession(c *gin.Context) {
 		return
 	}
 
-	// Validate that topic exists and belongs to user (if provided)
-	if input.TopicID != nil {
-		var topic models.Topic
-		if err := config.GetDB().Where("id = ? AND user_id = ?", input.TopicID, userIDUUID).First(&topic).Error; err != nil {
-			config.Logger.Warnf("Topic ID %s not found or not owned by user %s", input.TopicID, userIDUUID)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Topic not found or access denied"})
-			return
-		}
-	}
-
-	// Validate that task exists and belongs to user's topic (if provided)
-	if input.TaskID != nil {
-		var task models.Task_learning
-		if err := config.GetDB().Joins("JOIN topics ON task_learnings.topic_id = topics.id").
-			Where("task_learnings.id = ? AND topics.user_id = ?", input.TaskID, userIDUUID).
-			First(&task).Error; err != nil {
-			config.Logger.Warnf("Task ID %s not found or not owned by user %s", input.TaskID, userIDUUID)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Task not found or access denied"})
-			return
-		}
+	if !validStudySessionTargets(c, userIDUUID, input.TopicID, input.TaskID) {
+		return
 	}
 
+	// The session is logged once it is over
+	now := time.Now()
 	studySession := models.StudySession{
 		UserID:      userIDUUID,
 		TopicID:     input.TopicID,
 		TaskID:      input.TaskID,
+		Status:      models.StudySessionCompleted,
+		Notes:       input.Notes,
 		DurationMin: input.DurationMin,
-		StartedAt:   time.Now(),
-		EndedAt:     time.Now(), // Will be updated when session ends
+		StartedAt:   now.Add(-time.Duration(input.DurationMin) * time.Minute),
+		EndedAt:     &now,
 	}
 
 	config.Logger.Infof("Creating study session for user %s: %d minutes", userIDUUID, input.DurationMin)
@@ -94,6 +113,7 @@ func CreateStudySession(c *gin.Context) {
 	}
 
 	config.Logger.Infof("Successfully created study session ID %s for user %s", studySession.ID, userIDUUID)
+	adjustStudyPlans(config.GetDB(), &studySession)
 	c.JSON(http.StatusCreated, studySession)
 }
 
@@ -106,6 +126,8 @@ func CreateStudySession(c *gin.Context) {
 // @Security     BearerAuth
 // @Param        topic_id    query     string  false  "Filter by topic ID"
 // @Param        task_id     query     string  false  "Filter by task ID"
+// @Param        deck_id     query     string  false  "Filter by deck ID"
+// @Param        status      query     string  false  "Filter by status: active, paused or completed"
 // @Param        date_from   query     string  false  "Filter from date (YYYY-MM-DD)"
 // @Param        date_to     query     string  false  "Filter to date (YYYY-MM-DD)"
 // @Param        limit       query     int     false  "Limit number of results"  default(50)
@@ -145,6 +167,16 @@ func GetStudySessions(c *gin.Context) {
 		}
 	}
 
+	if deckIDStr := c.Query("deck_id"); deckIDStr != "" {
+		if deckID, err := uuid.Parse(deckIDStr); err == nil {
+			query = query.Where("deck_id = ?", deckID)
+		}
+	}
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
 	if dateFrom := c.Query("date_from"); dateFrom != "" {
 		if parsedDate, err := time.Parse("2006-01-02", dateFrom); err == nil {
 			query = query.Where("started_at >= ?", parsedDate)
@@ -178,7 +210,7 @@ func GetStudySessions(c *gin.Context) {
 
 // GetStudySessionStats godoc
 // @Summary      Get study session statistics
-// @Description  Get aggregated statistics for the user's study sessions
+// @Description  Get aggregated statistics for the user's study sessions and the card reviews made in the period: accuracy, answer time, and time per card in live sessions
 // @Tags         study-sessions
 // @Accept       json
 // @Produce      json
@@ -211,7 +243,9 @@ func GetStudySessionStats(c *gin.Context) {
 		}
 	}
 
-	startDate := time.Now().AddDate(0, 0, -days)
+	// Days are the user's calendar days, ending with today
+	loc := util.GetUserLocation(c)
+	startDate := util.StartOfDay(time.Now().In(loc)).AddDate(0, 0, -(days - 1))
 
 	// Get total study time
 	var totalMinutes int64
@@ -232,9 +266,9 @@ func GetStudySessionStats(c *gin.Context) {
 
 	var dailyStats []DailyStats
 	if err := config.GetDB().Model(&models.StudySession{}).
-		Select("DATE(started_at) as date, COALESCE(SUM(duration_min), 0) as minutes").
+		Select("DATE(started_at AT TIME ZONE ?) as date, COALESCE(SUM(duration_min), 0) as minutes", loc.String()).
 		Where("user_id = ? AND started_at >= ?", userIDUUID, startDate).
-		Group("DATE(started_at)").
+		Group("date").
 		Order("date").
 		Scan(&dailyStats).Error; err != nil {
 		config.Logger.Errorf("Error fetching daily stats for user %s: %v", userIDUUID, err)
@@ -263,6 +297,51 @@ func GetStudySessionStats(c *gin.Context) {
 		return
 	}
 
+	// Get card review metrics, and time per card over the live sessions reviews were made in
+	type ReviewStats struct {
+		Reviews              int64   `json:"reviews"`
+		CardsReviewed        int64   `json:"cards_reviewed"`
+		Correct              int64   `json:"correct"`
+		Accuracy             float64 `json:"accuracy"` // share of reviews answered better than "again"
+		AverageAnswerSeconds float64 `json:"average_answer_seconds"`
+		SessionReviews       int64   `json:"session_reviews"` // reviews made in live study sessions
+		SecondsPerCard       float64 `json:"seconds_per_card"`
+	}
+
+	var reviewStats ReviewStats
+	var averageAnswerMs float64
+	row := config.GetDB().Model(&models.CardReview{}).
+		Select(`COUNT(*), COUNT(DISTINCT card_id), COUNT(*) FILTER (WHERE rating > ?),
+			COALESCE(AVG(time_taken_ms) FILTER (WHERE time_taken_ms > 0), 0), COUNT(study_session_id)`, int(srs.Again)).
+		Where("user_id = ? AND reviewed_at >= ?", userIDUUID, startDate).Row()
+	if err := row.Scan(&reviewStats.Reviews, &reviewStats.CardsReviewed, &reviewStats.Correct, &averageAnswerMs, &reviewStats.SessionReviews); err != nil {
+		config.Logger.Errorf("Error fetching review stats for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch review statistics"})
+		return
+	}
+	if reviewStats.Reviews > 0 {
+		reviewStats.Accuracy = math.Round(float64(reviewStats.Correct)/float64(reviewStats.Reviews)*1000) / 1000
+	}
+	reviewStats.AverageAnswerSeconds = math.Round(averageAnswerMs/100) / 10
+
+	var sessionTime struct {
+		Seconds float64
+		Reviews int64
+	}
+	if err := config.GetDB().Model(&models.StudySession{}).
+		Select(`COALESCE(SUM(EXTRACT(EPOCH FROM (ended_at - started_at)) - paused_seconds), 0) AS seconds,
+			COALESCE(SUM((SELECT COUNT(*) FROM card_reviews WHERE card_reviews.study_session_id = study_sessions.id)), 0) AS reviews`).
+		Where("user_id = ? AND status = ? AND started_at >= ?", userIDUUID, models.StudySessionCompleted, startDate).
+		Where("EXISTS (SELECT 1 FROM card_reviews WHERE card_reviews.study_session_id = study_sessions.id)").
+		Scan(&sessionTime).Error; err != nil {
+		config.Logger.Errorf("Error fetching session review time for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch review statistics"})
+		return
+	}
+	if sessionTime.Reviews > 0 {
+		reviewStats.SecondsPerCard = math.Round(sessionTime.Seconds/float64(sessionTime.Reviews)*10) / 10
+	}
+
 	stats := map[string]interface{}{
 		"total_minutes": totalMinutes,
 		"total_hours":   float64(totalMinutes) / 60,
@@ -270,8 +349,308 @@ func GetStudySessionStats(c *gin.Context) {
 		"daily_stats":   dailyStats,
 		"topic_stats":   topicStats,
 		"average_daily": float64(totalMinutes) / float64(days),
+		"review_stats":  reviewStats,
 	}
 
 	config.Logger.Infof("Calculated study statistics for user %s over %d days", userIDUUID, days)
 	c.JSON(http.StatusOK, stats)
 }
+
+// StartStudySessionRequest represents the request body for starting a live study session
+type StartStudySessionRequest struct {
+	DeckID  *uuid.UUID `json:"deck_id" binding:"omitempty"`
+	TopicID *uuid.UUID `json:"topic_id" binding:"omitempty"`
+	TaskID  *uuid.UUID `json:"task_id" binding:"omitempty"`
+	Notes   string     `json:"notes"`
+}
+
+var errStudySessionState = errors.New("study session cannot make this change in its state")
+
+// liveStudySessionID returns the user's active study session that a review of a card
+// in deckID belongs to, if any: one for that deck or a deck above it, as a session on a
+// deck studies its sub-decks too, or else one not tied to a deck
+func liveStudySessionID(db *gorm.DB, userID, deckID uuid.UUID) *uuid.UUID {
+	var sessions []models.StudySession
+	err := db.Raw(deckChainCTE+`SELECT study_sessions.* FROM study_sessions
+		LEFT JOIN deck_chain ON deck_chain.id = study_sessions.deck_id
+		WHERE study_sessions.user_id = ? AND study_sessions.status = ?
+		AND (study_sessions.deck_id IS NULL OR deck_chain.id IS NOT NULL)
+		ORDER BY deck_chain.depth NULLS LAST LIMIT 1`,
+		deckID, maxDeckDepth, userID, models.StudySessionActive).Scan(&sessions).Error
+	if err != nil {
+		config.Logger.Errorf("Error fetching live study session for user %s: %v", userID, err)
+		return nil
+	}
+	if len(sessions) == 0 {
+		return nil
+	}
+	return &sessions[0].ID
+}
+
+// studySessionSummary summarises the reviews linked to a session
+func studySessionSummary(db *gorm.DB, session *models.StudySession) (srs.SessionSummary, error) {
+	var reviews []models.CardReview
+	if err := db.Where("study_session_id = ?", session.ID).Order("reviewed_at").Find(&reviews).Error; err != nil {
+		return srs.SessionSummary{}, err
+	}
+	events := make([]srs.ReviewEvent, len(reviews))
+	for i := range reviews {
+		events[i] = reviews[i].Event()
+	}
+	return srs.SummarizeSession(events, session.ActiveDuration(time.Now())), nil
+}
+
+// respondWithStudySession writes a session with the summary of its reviews
+func respondWithStudySession(c *gin.Context, status int, session *models.StudySession) {
+	summary, err := studySessionSummary(config.GetDB(), session)
+	if err != nil {
+		config.Logger.Errorf("Error summarising study session %s: %v", session.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not summarise study session"})
+		return
+	}
+	c.JSON(status, gin.H{"study_session": session, "summary": summary})
+}
+
+// changeStudySession applies change to one of the user's live sessions, which must be
+// in one of the from statuses
+func changeStudySession(c *gin.Context, change func(*models.StudySession, time.Time), from ...string) {
+	sessionID, err := uuid.Parse(c.Param("ID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid study session ID"})
+		return
+	}
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var session models.StudySession
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+			return err
+		}
+		allowed := false
+		for _, status := range from {
+			allowed = allowed || session.Status == status
+		}
+		if !allowed {
+			return errStudySessionState
+		}
+		change(&session, time.Now())
+		return tx.Model(&session).
+			Select("status", "paused_at", "paused_seconds", "ended_at", "duration_min").
+			Updates(&session).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Study session not found"})
+		return
+	case errors.Is(err, errStudySessionState):
+		c.JSON(http.StatusConflict, gin.H{"error": "Study session is " + session.Status, "status": session.Status})
+		return
+	case err != nil:
+		config.Logger.Errorf("Error updating study session %s: %v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update study session"})
+		return
+	}
+
+	config.Logger.Infof("Study session %s for user %v is now %s", sessionID, userID, session.Status)
+	adjustStudyPlans(config.GetDB(), &session)
+	respondWithStudySession(c, http.StatusOK, &session)
+}
+
+// StartStudySession godoc
+// @Summary      Start a live study session
+// @Description  Start timing a study session, optionally for a deck, topic or task. Card reviews made while it is active are linked to it: all of them, or only those in its deck when it has one. A user has one live session at a time.
+// @Tags         study-sessions
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        study_session  body      StartStudySessionRequest  true  "What the session is for"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]string
+// @Router       /study-sessions/start [post]
+func StartStudySession(c *gin.Context) {
+	var input StartStudySessionRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid study session input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input for study session", "details": err.Error()})
+		return
+	}
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	if !validStudySessionTargets(c, userIDUUID, input.TopicID, input.TaskID) {
+		return
+	}
+	if input.DeckID != nil {
+		if _, ok := accessibleDeck(c, *input.DeckID, userIDUUID, models.DeckRoleViewer); !ok {
+			return
+		}
+	}
+
+	db := config.GetDB()
+	var live models.StudySession
+	err := db.Where("user_id = ? AND status IN ?", userIDUUID, []string{models.StudySessionActive, models.StudySessionPaused}).
+		First(&live).Error
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Stop your live study session before starting another", "study_session": live})
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		config.Logger.Errorf("Error fetching live study session for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start study session"})
+		return
+	}
+
+	session := models.StudySession{
+		UserID:    userIDUUID,
+		DeckID:    input.DeckID,
+		TopicID:   input.TopicID,
+		TaskID:    input.TaskID,
+		Status:    models.StudySessionActive,
+		Notes:     input.Notes,
+		StartedAt: time.Now(),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		config.Logger.Errorf("Error starting study session for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start study session"})
+		return
+	}
+
+	config.Logger.Infof("User %s started study session %s", userIDUUID, session.ID)
+	c.JSON(http.StatusCreated, gin.H{"study_session": session, "summary": srs.SessionSummary{}})
+}
+
+// GetActiveStudySession godoc
+// @Summary      Get the live study session
+// @Description  Fetch the user's active or paused study session with the summary so far, or null when there is none
+// @Tags         study-sessions
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /study-sessions/active [get]
+func GetActiveStudySession(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var session models.StudySession
+	err := config.GetDB().Where("user_id = ? AND status IN ?", userID, []string{models.StudySessionActive, models.StudySessionPaused}).
+		First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusOK, gin.H{"study_session": nil})
+		return
+	}
+	if err != nil {
+		config.Logger.Errorf("Error fetching live study session for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch study session"})
+		return
+	}
+	respondWithStudySession(c, http.StatusOK, &session)
+}
+
+// GetStudySession godoc
+// @Summary      Get a study session
+// @Description  Fetch one of the user's study sessions with a summary of the card reviews made in it: cards reviewed, accuracy and time per card
+// @Tags         study-sessions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Study session ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /study-sessions/{ID} [get]
+func GetStudySession(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("ID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid study session ID"})
+		return
+	}
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var session models.StudySession
+	if err := config.GetDB().Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Study session not found"})
+		return
+	}
+	respondWithStudySession(c, http.StatusOK, &session)
+}
+
+// PauseStudySession godoc
+// @Summary      Pause a study session
+// @Description  Pause an active study session. Paused time does not count towards it and reviews made meanwhile are not linked to it.
+// @Tags         study-sessions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Study session ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /study-sessions/{ID}/pause [post]
+func PauseStudySession(c *gin.Context) {
+	changeStudySession(c, (*models.StudySession).Pause, models.StudySessionActive)
+}
+
+// ResumeStudySession godoc
+// @Summary      Resume a study session
+// @Description  Carry on with a paused study session
+// @Tags         study-sessions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Study session ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /study-sessions/{ID}/resume [post]
+func ResumeStudySession(c *gin.Context) {
+	changeStudySession(c, (*models.StudySession).Resume, models.StudySessionPaused)
+}
+
+// StopStudySession godoc
+// @Summary      Stop a study session
+// @Description  End a live study session, recording the minutes studied with pauses left out, and return its summary
+// @Tags         study-sessions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        ID  path      string  true  "Study session ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /study-sessions/{ID}/stop [post]
+func StopStudySession(c *gin.Context) {
+	changeStudySession(c, (*models.StudySession).Stop, models.StudySessionActive, models.StudySessionPaused)
+}
@@ -227,8 +227,9 @@ type CreateTaskRequest struct {
 	UseNaturalLanguage   *bool      `json:"use_natural_language" example:"true"`
 }
 
-// ParseNaturalLanguage provides enhanced parsing when AI is not available
-func ParseNaturalLanguage(input string) (string, string, *int, *time.Time, error) {
+// ParseNaturalLanguage provides enhanced parsing when AI is not available. Relative dates
+// and times ("tomorrow at 5pm") are resolved against now, in now's location.
+func ParseNaturalLanguage(input string, now time.Time) (string, string, *int, *time.Time, error) {
 	// Normalize input: trim spaces and convert to lowercase for processing
 	normalizedInput := strings.TrimSpace(strings.ToLower(input))
 	originalInput := strings.TrimSpace(input)
@@ -236,7 +237,6 @@ func ParseNaturalLanguage(input string) (string, string, *int, *time.Time, error
 	// Initialize default values
 	priority := 3
 	var dueDate *time.Time
-	now := time.Now()
 
 	// Priority keywords and their values
 	priorityMap := map[string]int{
@@ -444,7 +444,7 @@ func CreateTask(c *gin.Context) {
 
 	// Handle natural language input
 	if input.UseNaturalLanguage != nil && *input.UseNaturalLanguage && input.NaturalLanguageInput != nil {
-		parsedTitle, parsedDescription, parsedPriority, parsedDueDate, err := ParseNaturalLanguage(*input.NaturalLanguageInput)
+		parsedTitle, parsedDescription, parsedPriority, parsedDueDate, err := ParseNaturalLanguage(*input.NaturalLanguageInput, time.Now().In(util.GetUserLocation(c)))
 		if err != nil {
 			config.Logger.Errorf("Failed to parse natural language input: %v", err)
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse natural language input"})
@@ -7,6 +7,7 @@ import (
 	"github.com/TheoMKgosi/The-hub/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // GetTaskLearnings godoc
@@ -245,6 +246,7 @@ func CreateTaskLearning(c *gin.Context) {
 type UpdateTaskLearningRequest struct {
 	Title  *string `json:"title" example:"Updated task learning title"`
 	Status *string `json:"status" example:"completed"`
+	Notes  *string `json:"notes" example:"Interfaces are satisfied implicitly"`
 }
 
 // UpdateTaskLearning godoc
@@ -325,8 +327,16 @@ func UpdateTaskLearning(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Valid options: not_started, in_progress, completed, on_hold"})
 			return
 		}
+		// Work on a topic cannot start until its learning path prerequisites are done
+		if *input.Status != taskLearning.Status && (*input.Status == "in_progress" || *input.Status == "completed") &&
+			!topicUnlocked(c, userIDUUID, taskLearning.TopicID) {
+			return
+		}
 		updates["status"] = *input.Status
 	}
+	if input.Notes != nil {
+		updates["notes"] = *input.Notes
+	}
 
 	if len(updates) == 0 {
 		config.Logger.Warnf("No valid fields provided for task learning update: ID %d", taskLearningID)
@@ -335,7 +345,17 @@ func UpdateTaskLearning(c *gin.Context) {
 	}
 
 	config.Logger.Infof("Updating task learning ID %s for user %s with data: %+v", taskLearningID, userIDUUID, updates)
-	if err := config.GetDB().Model(&taskLearning).Updates(updates).Error; err != nil {
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&taskLearning).Updates(updates).Error; err != nil {
+			return err
+		}
+		// Cards generated from parts of the notes that changed are now out of date
+		if input.Notes != nil && *input.Notes != taskLearning.Notes {
+			return markStaleSourceCards(tx, models.CardSourceTaskLearning, taskLearning.ID, *input.Notes)
+		}
+		return nil
+	})
+	if err != nil {
 		config.Logger.Errorf("Failed to update task learning ID %d: %v", taskLearningID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task learning"})
 		return
@@ -431,6 +451,14 @@ func DeleteTaskLearning(c *gin.Context) {
 		return
 	}
 
+	// Cards generated from its notes no longer have a source
+	if err := markStaleSourceCards(tx, models.CardSourceTaskLearning, taskLearning.ID, ""); err != nil {
+		tx.Rollback()
+		config.Logger.Errorf("Failed to flag cards of task learning ID %s: %v", taskLearningID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task learning"})
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		config.Logger.Errorf("Failed to commit task learning deletion transaction: %v", err)
@@ -9,6 +9,7 @@ import (
 	"github.com/TheoMKgosi/The-hub/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // GetTopics godoc
@@ -435,6 +436,11 @@ func UpdateTopic(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Valid options: not_started, in_progress, completed, on_hold"})
 			return
 		}
+		// A topic is locked until its learning path prerequisites are done
+		if *input.Status != topic.Status && (*input.Status == "in_progress" || *input.Status == "completed") &&
+			!topicUnlocked(c, userIDUUID, topicID) {
+			return
+		}
 		updates["status"] = *input.Status
 	}
 	if input.EstimatedHours != nil {
@@ -609,6 +615,22 @@ func DeleteTopic(c *gin.Context) {
 		return
 	}
 
+	// Take the topic out of learning paths and out of the prerequisites of the topics left in them
+	userPaths := tx.Model(&models.LearningPath{}).Select("id").Where("user_id = ?", userIDUUID)
+	if err := tx.Where("topic_id = ?", topicID).Delete(&models.LearningPathTopic{}).Error; err != nil {
+		tx.Rollback()
+		config.Logger.Errorf("Failed to remove topic ID %s from learning paths: %v", topicID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear topic associations"})
+		return
+	}
+	if err := tx.Model(&models.LearningPathTopic{}).Where("learning_path_id IN (?)", userPaths).
+		Update("prerequisites", gorm.Expr("prerequisites - ?::text", topicID.String())).Error; err != nil {
+		tx.Rollback()
+		config.Logger.Errorf("Failed to remove topic ID %s from learning path prerequisites: %v", topicID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear topic associations"})
+		return
+	}
+
 	// Delete the topic
 	if err := tx.Delete(&topic).Error; err != nil {
 		tx.Rollback()
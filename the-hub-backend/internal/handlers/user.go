@@ -197,6 +197,13 @@ func UpdateUserSettings(c *gin.Context) {
 		return
 	}
 
+	if tz, ok := util.SettingsTimezone(input); ok {
+		if _, err := util.LoadTimezone(tz); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone", "details": err.Error()})
+			return
+		}
+	}
+
 	// Serialize settings to JSON
 	settingsJSON, err := json.Marshal(input)
 	if err != nil {
@@ -284,6 +291,13 @@ func PatchUserSettings(c *gin.Context) {
 		return
 	}
 
+	if tz, ok := util.SettingsTimezone(input); ok {
+		if _, err := util.LoadTimezone(tz); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone", "details": err.Error()})
+			return
+		}
+	}
+
 	// Parse existing settings
 	var currentSettings map[string]interface{}
 	if user.Settings != "" {
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ZoneBlackoutRequest creates or changes a zone blackout. Omitted fields keep their
+// current value.
+type ZoneBlackoutRequest struct {
+	ZoneID    *string `json:"zone_id"` // empty suspends all zones
+	Name      *string `json:"name"`
+	Kind      *string `json:"kind"`       // holiday, exam, vacation or other
+	StartDate *string `json:"start_date"` // YYYY-MM-DD
+	EndDate   *string `json:"end_date"`   // YYYY-MM-DD, inclusive; defaults to start_date
+}
+
+// applyZoneBlackoutRequest copies the request onto blackout and checks the result
+func applyZoneBlackoutRequest(db *gorm.DB, blackout *models.ZoneBlackout, input ZoneBlackoutRequest) error {
+	if input.ZoneID != nil {
+		blackout.ZoneID = nil
+		if *input.ZoneID != "" {
+			zoneID, err := uuid.Parse(*input.ZoneID)
+			if err != nil {
+				return errors.New("invalid zone_id")
+			}
+			var count int64
+			if err := db.Model(&models.CalendarZone{}).Where("id = ? AND user_id = ?", zoneID, blackout.UserID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return errors.New("zone_id must be one of your calendar zones")
+			}
+			blackout.ZoneID = &zoneID
+		}
+	}
+	if input.Name != nil {
+		blackout.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Kind != nil {
+		blackout.Kind = strings.ToLower(strings.TrimSpace(*input.Kind))
+	}
+	if input.StartDate != nil {
+		date, err := time.Parse("2006-01-02", *input.StartDate)
+		if err != nil {
+			return errors.New("start_date must be YYYY-MM-DD")
+		}
+		blackout.StartDate = date
+		if input.EndDate == nil && blackout.EndDate.Before(date) {
+			blackout.EndDate = date
+		}
+	}
+	if input.EndDate != nil {
+		date, err := time.Parse("2006-01-02", *input.EndDate)
+		if err != nil {
+			return errors.New("end_date must be YYYY-MM-DD")
+		}
+		blackout.EndDate = date
+	}
+
+	if blackout.Name == "" {
+		return errors.New("name is required")
+	}
+	if blackout.StartDate.IsZero() {
+		return errors.New("start_date is required")
+	}
+	if blackout.EndDate.Before(blackout.StartDate) {
+		return errors.New("end_date must not be before start_date")
+	}
+	for _, kind := range models.ZoneBlackoutKinds {
+		if blackout.Kind == kind {
+			return nil
+		}
+	}
+	return errors.New("kind must be one of " + strings.Join(models.ZoneBlackoutKinds, ", "))
+}
+
+// GetZoneBlackouts godoc
+// @Summary      List zone blackouts
+// @Description  List the dates on which the user's calendar zones are suspended. Pass from and to (YYYY-MM-DD) to list only blackouts that meet that range.
+// @Tags         calendar-zones
+// @Produce      json
+// @Security     BearerAuth
+// @Param        from  query     string  false  "First date"
+// @Param        to    query     string  false  "Last date"
+// @Success      200  {object}  map[string][]models.ZoneBlackout
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /calendar-zones/blackouts [get]
+func GetZoneBlackouts(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	query := config.GetDB().Where("user_id = ?", userID)
+	if from := c.Query("from"); from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("end_date >= ?", date)
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("start_date <= ?", date)
+	}
+
+	var blackouts []models.ZoneBlackout
+	if err := query.Order("start_date").Find(&blackouts).Error; err != nil {
+		config.Logger.Errorf("Error fetching zone blackouts for user %v: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch zone blackouts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"blackouts": blackouts})
+}
+
+// CreateZoneBlackout godoc
+// @Summary      Create zone blackout
+// @Description  Suspend one calendar zone, or all of them, over a range of dates such as a holiday, exam week or vacation
+// @Tags         calendar-zones
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        blackout  body      ZoneBlackoutRequest  true  "Blackout"
+// @Success      201  {object}  models.ZoneBlackout
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /calendar-zones/blackouts [post]
+func CreateZoneBlackout(c *gin.Context) {
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userIDUUID := userID.(uuid.UUID)
+
+	var input ZoneBlackoutRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid zone blackout input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	blackout := models.ZoneBlackout{UserID: userIDUUID, Kind: "other"}
+	if err := applyZoneBlackoutRequest(config.GetDB(), &blackout, input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.GetDB().Create(&blackout).Error; err != nil {
+		config.Logger.Errorf("Error creating zone blackout for user %s: %v", userIDUUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create zone blackout"})
+		return
+	}
+
+	config.Logger.Infof("Created zone blackout %s for user %s", blackout.ID, userIDUUID)
+	c.JSON(http.StatusCreated, blackout)
+}
+
+// UpdateZoneBlackout godoc
+// @Summary      Update zone blackout
+// @Description  Change a blackout's dates, name, kind or zone
+// @Tags         calendar-zones
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        blackoutID  path      string               true  "Blackout ID"
+// @Param        blackout    body      ZoneBlackoutRequest  true  "Blackout changes"
+// @Success      200  {object}  models.ZoneBlackout
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /calendar-zones/blackouts/{blackoutID} [put]
+func UpdateZoneBlackout(c *gin.Context) {
+	blackout, ok := ownZoneBlackout(c)
+	if !ok {
+		return
+	}
+
+	var input ZoneBlackoutRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		config.Logger.Warnf("Invalid zone blackout input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if err := applyZoneBlackoutRequest(config.GetDB(), blackout, input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.GetDB().Model(blackout).Select("zone_id", "name", "kind", "start_date", "end_date").Updates(blackout).Error; err != nil {
+		config.Logger.Errorf("Error updating zone blackout %s: %v", blackout.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update zone blackout"})
+		return
+	}
+
+	config.Logger.Infof("Updated zone blackout %s", blackout.ID)
+	c.JSON(http.StatusOK, blackout)
+}
+
+// DeleteZoneBlackout godoc
+// @Summary      Delete zone blackout
+// @Description  Delete a blackout so its zones apply again on those dates
+// @Tags         calendar-zones
+// @Produce      json
+// @Security     BearerAuth
+// @Param        blackoutID  path      string  true  "Blackout ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /calendar-zones/blackouts/{blackoutID} [delete]
+func DeleteZoneBlackout(c *gin.Context) {
+	blackout, ok := ownZoneBlackout(c)
+	if !ok {
+		return
+	}
+
+	if err := config.GetDB().Delete(blackout).Error; err != nil {
+		config.Logger.Errorf("Error deleting zone blackout %s: %v", blackout.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete zone blackout"})
+		return
+	}
+
+	config.Logger.Infof("Deleted zone blackout %s", blackout.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Zone blackout deleted"})
+}
+
+// ownZoneBlackout loads the authenticated user's blackout named in the request,
+// writing the error response when there is none
+func ownZoneBlackout(c *gin.Context) (*models.ZoneBlackout, bool) {
+	blackoutIDStr := c.Param("blackoutID")
+	blackoutID, err := uuid.Parse(blackoutIDStr)
+	if err != nil {
+		config.Logger.Warnf("Invalid zone blackout ID param: %s", blackoutIDStr)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blackout ID"})
+		return nil, false
+	}
+
+	userID, exist := c.Get("userID")
+	if !exist {
+		config.Logger.Warn("userID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	var blackout models.ZoneBlackout
+	if err := config.GetDB().Where("id = ? AND user_id = ?", blackoutID, userID).First(&blackout).Error; err != nil {
+		config.Logger.Warnf("Zone blackout %s not found for user %v", blackoutID, userID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Zone blackout not found"})
+		return nil, false
+	}
+	return &blackout, true
+}
@@ -0,0 +1,208 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EventSpec is a VEVENT as written in the file, before recurrence expansion
+type EventSpec struct {
+	UID          string
+	Summary      string
+	Description  string
+	Location     string
+	Status       string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	RRule        *RRule
+	ExDates      []time.Time
+	RecurrenceID *time.Time // set on overrides of a single occurrence
+	LastModified time.Time
+}
+
+// Occurrence is one concrete instance of an event
+type Occurrence struct {
+	UID          string
+	RecurrenceID *time.Time // original start of the instance for recurring events
+	Summary      string
+	Description  string
+	Location     string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+}
+
+// Key identifies the occurrence across imports
+func (o Occurrence) Key() string {
+	if o.RecurrenceID == nil {
+		return o.UID
+	}
+	return o.UID + "/" + FormatTime(*o.RecurrenceID)
+}
+
+// ParseEvents extracts the VEVENTs of a calendar. Times without a zone are read in fallback.
+func ParseEvents(cal *Component, fallback *time.Location) ([]EventSpec, error) {
+	if fallback == nil {
+		fallback = time.UTC
+	}
+	if tz := cal.Value("X-WR-TIMEZONE"); tz != "" {
+		if loc, err := LoadLocation(tz); err == nil {
+			fallback = loc
+		}
+	}
+
+	var events []EventSpec
+	for _, comp := range cal.Children {
+		if comp.Name != "VEVENT" {
+			continue
+		}
+
+		event, err := parseEvent(comp, fallback)
+		if err != nil {
+			return nil, fmt.Errorf("event %q: %w", comp.Value("UID"), err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func parseEvent(comp *Component, fallback *time.Location) (EventSpec, error) {
+	event := EventSpec{
+		UID:         comp.Value("UID"),
+		Summary:     UnescapeText(comp.Value("SUMMARY")),
+		Description: UnescapeText(comp.Value("DESCRIPTION")),
+		Location:    UnescapeText(comp.Value("LOCATION")),
+		Status:      strings.ToUpper(comp.Value("STATUS")),
+	}
+
+	start, allDay, err := ParseDateTime(comp.Get("DTSTART"), fallback)
+	if err != nil {
+		return event, fmt.Errorf("invalid DTSTART: %w", err)
+	}
+	event.Start = start
+	event.AllDay = allDay
+
+	switch {
+	case comp.Get("DTEND") != nil:
+		end, _, err := ParseDateTime(comp.Get("DTEND"), start.Location())
+		if err != nil {
+			return event, fmt.Errorf("invalid DTEND: %w", err)
+		}
+		event.End = end
+	case comp.Get("DURATION") != nil:
+		d, err := ParseDuration(comp.Value("DURATION"))
+		if err != nil {
+			return event, err
+		}
+		event.End = start.Add(d)
+	case allDay:
+		event.End = start.AddDate(0, 0, 1)
+	default:
+		event.End = start
+	}
+	if event.End.Before(event.Start) {
+		return event, fmt.Errorf("DTEND is before DTSTART")
+	}
+
+	if value := comp.Value("RRULE"); value != "" {
+		rule, err := ParseRRule(value, start.Location())
+		if err != nil {
+			return event, err
+		}
+		event.RRule = rule
+	}
+
+	for _, prop := range comp.GetAll("EXDATE") {
+		for _, value := range strings.Split(prop.Value, ",") {
+			single := Property{Name: prop.Name, Params: prop.Params, Value: value}
+			if exdate, _, err := ParseDateTime(&single, start.Location()); err == nil {
+				event.ExDates = append(event.ExDates, exdate)
+			}
+		}
+	}
+
+	if prop := comp.Get("RECURRENCE-ID"); prop != nil {
+		recurrenceID, _, err := ParseDateTime(prop, start.Location())
+		if err != nil {
+			return event, fmt.Errorf("invalid RECURRENCE-ID: %w", err)
+		}
+		event.RecurrenceID = &recurrenceID
+	}
+
+	if prop := comp.Get("LAST-MODIFIED"); prop != nil {
+		event.LastModified, _, _ = ParseDateTime(prop, time.UTC)
+	}
+
+	return event, nil
+}
+
+// ExpandEvents expands recurring events into occurrences starting in [from, to).
+// EXDATEs and cancelled events are dropped, and RECURRENCE-ID overrides replace
+// the instance they modify.
+func ExpandEvents(events []EventSpec, from, to time.Time) []Occurrence {
+	overrides := map[string]EventSpec{}
+	for _, event := range events {
+		if event.RecurrenceID != nil {
+			overrides[event.UID+"/"+FormatTime(*event.RecurrenceID)] = event
+		}
+	}
+
+	var occurrences []Occurrence
+	for _, event := range events {
+		if event.RecurrenceID != nil {
+			continue
+		}
+		if event.Status == "CANCELLED" {
+			continue
+		}
+
+		if event.RRule == nil {
+			if event.Start.Before(to) && !event.Start.Before(from) {
+				occurrences = append(occurrences, event.occurrence(nil))
+			}
+			continue
+		}
+
+		excluded := map[int64]bool{}
+		for _, exdate := range event.ExDates {
+			excluded[exdate.Unix()] = true
+		}
+
+		for _, start := range event.RRule.Between(event.Start, from, to) {
+			if excluded[start.Unix()] {
+				continue
+			}
+			recurrenceID := start
+			if override, ok := overrides[event.UID+"/"+FormatTime(start)]; ok {
+				if override.Status != "CANCELLED" {
+					occurrences = append(occurrences, override.occurrence(&recurrenceID))
+				}
+				continue
+			}
+
+			instance := event
+			instance.End = start.Add(event.End.Sub(event.Start))
+			instance.Start = start
+			occurrences = append(occurrences, instance.occurrence(&recurrenceID))
+		}
+	}
+
+	sort.SliceStable(occurrences, func(i, j int) bool { return occurrences[i].Start.Before(occurrences[j].Start) })
+	return occurrences
+}
+
+func (e EventSpec) occurrence(recurrenceID *time.Time) Occurrence {
+	return Occurrence{
+		UID:          e.UID,
+		RecurrenceID: recurrenceID,
+		Summary:      e.Summary,
+		Description:  e.Description,
+		Location:     e.Location,
+		Start:        e.Start,
+		End:          e.End,
+		AllDay:       e.AllDay,
+	}
+}
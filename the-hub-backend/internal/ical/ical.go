@@ -0,0 +1,186 @@
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// ProdID identifies The Hub as the producer of generated calendars
+	ProdID = "-//The Hub//Schedule//EN"
+
+	dateTimeFormat = "20060102T150405Z"
+	maxLineOctets  = 75
+)
+
+// Calendar is a minimal VCALENDAR document
+type Calendar struct {
+	Name   string
+	Method string    // e.g. PUBLISH for feeds; must be empty for CalDAV resources
+	Stamp  time.Time // DTSTAMP for every component; defaults to now
+	Events []Event
+	Todos  []Todo
+}
+
+// Event is a VEVENT component
+type Event struct {
+	UID          string
+	Summary      string
+	Description  string
+	Start        time.Time
+	End          time.Time
+	RRule        string      // RRULE value without the "RRULE:" prefix
+	ExDates      []time.Time // occurrences removed from the series
+	RecurrenceID time.Time   // set when the event overrides one occurrence of a series
+	LastModified time.Time
+}
+
+// Todo is a VTODO component
+type Todo struct {
+	UID          string
+	Summary      string
+	Description  string
+	Due          time.Time // optional
+	Status       string    // NEEDS-ACTION, COMPLETED, IN-PROCESS, CANCELLED
+	Completed    *time.Time
+	Priority     int // 1 (highest) - 9 (lowest), 0 = undefined
+	LastModified time.Time
+}
+
+// Encode writes the calendar in iCalendar (RFC 5545) format
+func (cal *Calendar) Encode(w io.Writer) error {
+	lw := &lineWriter{w: w}
+
+	lw.prop("BEGIN", "VCALENDAR")
+	lw.prop("VERSION", "2.0")
+	lw.prop("PRODID", ProdID)
+	lw.prop("CALSCALE", "GREGORIAN")
+	if cal.Method != "" {
+		lw.prop("METHOD", cal.Method)
+	}
+	if cal.Name != "" {
+		lw.prop("X-WR-CALNAME", EscapeText(cal.Name))
+	}
+
+	stamp := cal.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	for _, event := range cal.Events {
+		lw.prop("BEGIN", "VEVENT")
+		lw.prop("UID", event.UID)
+		lw.prop("DTSTAMP", FormatTime(stamp))
+		if !event.RecurrenceID.IsZero() {
+			lw.prop("RECURRENCE-ID", FormatTime(event.RecurrenceID))
+		}
+		lw.prop("DTSTART", FormatTime(event.Start))
+		lw.prop("DTEND", FormatTime(event.End))
+		lw.prop("SUMMARY", EscapeText(event.Summary))
+		if event.Description != "" {
+			lw.prop("DESCRIPTION", EscapeText(event.Description))
+		}
+		if event.RRule != "" {
+			lw.prop("RRULE", event.RRule)
+		}
+		for _, exdate := range event.ExDates {
+			lw.prop("EXDATE", FormatTime(exdate))
+		}
+		if !event.LastModified.IsZero() {
+			lw.prop("LAST-MODIFIED", FormatTime(event.LastModified))
+		}
+		lw.prop("END", "VEVENT")
+	}
+
+	for _, todo := range cal.Todos {
+		lw.prop("BEGIN", "VTODO")
+		lw.prop("UID", todo.UID)
+		lw.prop("DTSTAMP", FormatTime(stamp))
+		if !todo.Due.IsZero() {
+			lw.prop("DUE", FormatTime(todo.Due))
+		}
+		lw.prop("SUMMARY", EscapeText(todo.Summary))
+		if todo.Description != "" {
+			lw.prop("DESCRIPTION", EscapeText(todo.Description))
+		}
+		if todo.Status != "" {
+			lw.prop("STATUS", todo.Status)
+		}
+		if todo.Completed != nil {
+			lw.prop("COMPLETED", FormatTime(*todo.Completed))
+		}
+		if todo.Priority > 0 {
+			lw.prop("PRIORITY", fmt.Sprintf("%d", todo.Priority))
+		}
+		if !todo.LastModified.IsZero() {
+			lw.prop("LAST-MODIFIED", FormatTime(todo.LastModified))
+		}
+		lw.prop("END", "VTODO")
+	}
+
+	lw.prop("END", "VCALENDAR")
+	return lw.err
+}
+
+// Bytes returns the encoded calendar
+func (cal *Calendar) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := cal.Encode(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FormatTime formats a time as a UTC iCalendar DATE-TIME value
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(dateTimeFormat)
+}
+
+// EscapeText escapes a TEXT property value
+func EscapeText(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return replacer.Replace(s)
+}
+
+// lineWriter writes content lines with CRLF endings, folding long lines
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (lw *lineWriter) prop(name, value string) {
+	if lw.err != nil {
+		return
+	}
+	_, lw.err = io.WriteString(lw.w, fold(name+":"+value)+"\r\n")
+}
+
+// fold splits a content line into 75-octet chunks without breaking UTF-8 sequences
+func fold(line string) string {
+	if len(line) <= maxLineOctets {
+		return line
+	}
+
+	var b strings.Builder
+	limit := maxLineOctets
+	count := 0
+	for _, r := range line {
+		size := len(string(r))
+		if count+size > limit {
+			b.WriteString("\r\n ")
+			count = 0
+			limit = maxLineOctets - 1 // continuation lines start with a space
+		}
+		b.WriteRune(r)
+		count += size
+	}
+	return b.String()
+}
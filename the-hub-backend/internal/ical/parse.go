@@ -0,0 +1,322 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	_ "time/tzdata" // production images ship without a zoneinfo database
+)
+
+// Property is a single content line, e.g. DTSTART;TZID=Europe/Berlin:20260302T090000
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Component is a BEGIN/END block such as VCALENDAR, VEVENT or VTIMEZONE
+type Component struct {
+	Name       string
+	Properties []Property
+	Children   []*Component
+}
+
+// Get returns the first property with the given name
+func (c *Component) Get(name string) *Property {
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i]
+		}
+	}
+	return nil
+}
+
+// GetAll returns every property with the given name
+func (c *Component) GetAll(name string) []Property {
+	var props []Property
+	for _, prop := range c.Properties {
+		if prop.Name == name {
+			props = append(props, prop)
+		}
+	}
+	return props
+}
+
+// Value returns the value of the first property with the given name, or ""
+func (c *Component) Value(name string) string {
+	if prop := c.Get(name); prop != nil {
+		return prop.Value
+	}
+	return ""
+}
+
+// Parse reads an iCalendar stream and returns its top-level VCALENDAR component
+func Parse(r io.Reader) (*Component, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var stack []*Component
+	var root *Component
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		switch prop.Name {
+		case "BEGIN":
+			comp := &Component{Name: strings.ToUpper(prop.Value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, comp)
+			} else if root == nil {
+				root = comp
+			}
+			stack = append(stack, comp)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(prop.Value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", n+1, prop.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: property outside of a component", n+1)
+			}
+			current := stack[len(stack)-1]
+			current.Properties = append(current.Properties, prop)
+		}
+	}
+
+	if root == nil || root.Name != "VCALENDAR" {
+		return nil, fmt.Errorf("no VCALENDAR component found")
+	}
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("unterminated %s component", stack[len(stack)-1].Name)
+	}
+	return root, nil
+}
+
+// unfold joins folded continuation lines (lines starting with a space or tab)
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine splits a content line into name, parameters and value, honouring quoted parameter values
+func parseLine(line string) (Property, error) {
+	prop := Property{Params: map[string]string{}}
+
+	inQuotes := false
+	nameEnd, valueStart := -1, -1
+	for i, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ';' && !inQuotes && nameEnd == -1:
+			nameEnd = i
+		case r == ':' && !inQuotes:
+			valueStart = i
+		}
+		if valueStart != -1 {
+			break
+		}
+	}
+	if valueStart == -1 {
+		return prop, fmt.Errorf("missing ':' in %q", line)
+	}
+	if nameEnd == -1 {
+		nameEnd = valueStart
+	}
+
+	prop.Name = strings.ToUpper(line[:nameEnd])
+	prop.Value = line[valueStart+1:]
+
+	if nameEnd < valueStart {
+		for _, param := range splitParams(line[nameEnd+1 : valueStart]) {
+			key, value, found := strings.Cut(param, "=")
+			if !found {
+				continue
+			}
+			prop.Params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+
+	return prop, nil
+}
+
+func splitParams(s string) []string {
+	var params []string
+	inQuotes := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ';' && !inQuotes:
+			params = append(params, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(params, s[start:])
+}
+
+// UnescapeText reverses EscapeText
+func UnescapeText(s string) string {
+	replacer := strings.NewReplacer(
+		`\\`, `\`,
+		`\;`, ";",
+		`\,`, ",",
+		`\n`, "\n",
+		`\N`, "\n",
+	)
+	return replacer.Replace(s)
+}
+
+// windowsZones maps the Windows zone names Outlook and Exchange put in TZID to IANA names
+var windowsZones = map[string]string{
+	"UTC":                            "UTC",
+	"GMT Standard Time":              "Europe/London",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Romance Standard Time":          "Europe/Paris",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"FLE Standard Time":              "Europe/Kiev",
+	"South Africa Standard Time":     "Africa/Johannesburg",
+	"Eastern Standard Time":          "America/New_York",
+	"Central Standard Time":          "America/Chicago",
+	"Mountain Standard Time":         "America/Denver",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"Hawaiian Standard Time":         "Pacific/Honolulu",
+	"India Standard Time":            "Asia/Kolkata",
+	"China Standard Time":            "Asia/Shanghai",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+	"New Zealand Standard Time":      "Pacific/Auckland",
+	"Tonga Standard Time":            "Pacific/Tongatapu",
+	"E. South America Standard Time": "America/Sao_Paulo",
+}
+
+// LoadLocation resolves a TZID, accepting IANA names and common Windows zone names
+func LoadLocation(tzid string) (*time.Location, error) {
+	tzid = strings.Trim(tzid, `"`)
+	if loc, err := time.LoadLocation(tzid); err == nil {
+		return loc, nil
+	}
+	if name, ok := windowsZones[tzid]; ok {
+		return time.LoadLocation(name)
+	}
+	// Some producers prefix the IANA name with a vendor path, e.g. "/mozilla.org/20050126_1/Europe/Berlin"
+	if idx := strings.Index(tzid, "/"); idx != -1 {
+		parts := strings.Split(strings.Trim(tzid, "/"), "/")
+		for i := range parts {
+			if loc, err := time.LoadLocation(strings.Join(parts[i:], "/")); err == nil {
+				return loc, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown time zone %q", tzid)
+}
+
+// ParseDateTime parses a DATE or DATE-TIME property. Floating times and unknown
+// TZIDs are interpreted in fallback. allDay is true for VALUE=DATE values.
+func ParseDateTime(prop *Property, fallback *time.Location) (t time.Time, allDay bool, err error) {
+	if prop == nil {
+		return time.Time{}, false, fmt.Errorf("missing date property")
+	}
+
+	loc := fallback
+	if tzid, ok := prop.Params["TZID"]; ok {
+		if l, err := LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+
+	return parseDateValue(prop.Value, prop.Params["VALUE"] == "DATE", loc)
+}
+
+func parseDateValue(value string, isDate bool, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if isDate || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeFormat, value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// ParseDuration parses an RFC 5545 DURATION value such as PT1H30M or P1D
+func ParseDuration(value string) (time.Duration, error) {
+	s := strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(s, "-") {
+		negative = true
+		s = s[1:]
+	}
+	s = strings.TrimPrefix(s, "+")
+	if !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	s = s[1:]
+
+	var d time.Duration
+	inTime := false
+	num := 0
+	hasNum := false
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			num = num*10 + int(r-'0')
+			hasNum = true
+		case r == 'T':
+			inTime = true
+		case hasNum && r == 'W' && !inTime:
+			d += time.Duration(num) * 7 * 24 * time.Hour
+		case hasNum && r == 'D' && !inTime:
+			d += time.Duration(num) * 24 * time.Hour
+		case hasNum && r == 'H' && inTime:
+			d += time.Duration(num) * time.Hour
+		case hasNum && r == 'M' && inTime:
+			d += time.Duration(num) * time.Minute
+		case hasNum && r == 'S' && inTime:
+			d += time.Duration(num) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		if r < '0' || r > '9' {
+			if r != 'T' {
+				num, hasNum = 0, false
+			}
+		}
+	}
+	if hasNum {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	if negative {
+		d = -d
+	}
+	return d, nil
+}
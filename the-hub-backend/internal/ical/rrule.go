@@ -0,0 +1,337 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRecurrencePeriods bounds expansion of rules without COUNT or UNTIL
+const maxRecurrencePeriods = 5000
+
+// WeekdayNum is a BYDAY entry such as MO, 2TU or -1FR
+type WeekdayNum struct {
+	N   int // 0 = every such weekday in the period
+	Day time.Weekday
+}
+
+// RRule is a parsed recurrence rule
+type RRule struct {
+	Freq       string // DAILY, WEEKLY, MONTHLY, YEARLY
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// WeekdayCode returns the two-letter iCalendar code for a weekday
+func WeekdayCode(day time.Weekday) string {
+	return [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}[day]
+}
+
+// ParseRRule parses an RRULE value. UNTIL values without a zone are read in loc.
+func ParseRRule(value string, loc *time.Location) (*RRule, error) {
+	rule := &RRule{Interval: 1}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, found := strings.Cut(part, "=")
+		if !found {
+			continue
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+
+		switch key {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(val)
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, _, err := parseDateValue(val, false, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %q", val)
+			}
+			if len(val) == 8 {
+				// A DATE UNTIL includes the whole day
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, entry := range strings.Split(val, ",") {
+				entry = strings.ToUpper(strings.TrimSpace(entry))
+				if len(entry) < 2 {
+					continue
+				}
+				day, ok := weekdayCodes[entry[len(entry)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", entry)
+				}
+				n := 0
+				if prefix := entry[:len(entry)-2]; prefix != "" {
+					var err error
+					if n, err = strconv.Atoi(prefix); err != nil {
+						return nil, fmt.Errorf("invalid BYDAY %q", entry)
+					}
+				}
+				rule.ByDay = append(rule.ByDay, WeekdayNum{N: n, Day: day})
+			}
+		case "BYMONTHDAY":
+			for _, entry := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(entry))
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", entry)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, entry := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(entry))
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH %q", entry)
+				}
+				rule.ByMonth = append(rule.ByMonth, n)
+			}
+		}
+	}
+
+	switch rule.Freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return nil, fmt.Errorf("unsupported FREQ %q", rule.Freq)
+	}
+
+	return rule, nil
+}
+
+// String formats the rule as an RRULE value
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, d := range r.ByDay {
+			code := WeekdayCode(d.Day)
+			if d.N != 0 {
+				code = strconv.Itoa(d.N) + code
+			}
+			days = append(days, code)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	} else if r.Until != nil {
+		parts = append(parts, "UNTIL="+FormatTime(*r.Until))
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(values []int) string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = strconv.Itoa(v)
+	}
+	return strings.Join(strs, ",")
+}
+
+// Between returns occurrence start times in [from, to). Occurrences keep the
+// wall-clock time of dtstart in its location, so they follow DST changes.
+func (r *RRule) Between(dtstart, from, to time.Time) []time.Time {
+	var occurrences []time.Time
+	emitted := 0
+
+	for period := 0; period < maxRecurrencePeriods; period++ {
+		candidates := r.periodCandidates(dtstart, period)
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+		for _, candidate := range candidates {
+			if candidate.Before(dtstart) {
+				continue
+			}
+			if r.Until != nil && candidate.After(*r.Until) {
+				return occurrences
+			}
+			if r.Count > 0 && emitted >= r.Count {
+				return occurrences
+			}
+			if !candidate.Before(to) {
+				return occurrences
+			}
+			emitted++
+			if !candidate.Before(from) {
+				occurrences = append(occurrences, candidate)
+			}
+		}
+	}
+
+	return occurrences
+}
+
+// periodCandidates returns the matching dates in the n-th period (day, week, month or year) after dtstart
+func (r *RRule) periodCandidates(dtstart time.Time, n int) []time.Time {
+	loc := dtstart.Location()
+	hour, min, sec := dtstart.Clock()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, loc)
+	}
+	step := n * r.Interval
+
+	var candidates []time.Time
+	switch r.Freq {
+	case "DAILY":
+		day := at(dtstart.Year(), dtstart.Month(), dtstart.Day()+step)
+		if r.matchesMonth(day) && r.matchesMonthDay(day) && r.matchesWeekday(day) {
+			candidates = append(candidates, day)
+		}
+
+	case "WEEKLY":
+		// Weeks start on Monday (WKST=MO)
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		weekStart := at(dtstart.Year(), dtstart.Month(), dtstart.Day()-offset+7*step)
+		days := []time.Weekday{dtstart.Weekday()}
+		if len(r.ByDay) > 0 {
+			days = days[:0]
+			for _, d := range r.ByDay {
+				days = append(days, d.Day)
+			}
+		}
+		for _, day := range days {
+			date := at(weekStart.Year(), weekStart.Month(), weekStart.Day()+(int(day)+6)%7)
+			if r.matchesMonth(date) {
+				candidates = append(candidates, date)
+			}
+		}
+
+	case "MONTHLY":
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(step), 1, 0, 0, 0, 0, loc)
+		if len(r.ByMonth) == 0 || containsInt(r.ByMonth, int(first.Month())) {
+			candidates = r.monthCandidates(first, dtstart.Day(), at)
+		}
+
+	case "YEARLY":
+		year := dtstart.Year() + step
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []int{int(dtstart.Month())}
+		}
+		for _, month := range months {
+			first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, loc)
+			candidates = append(candidates, r.monthCandidates(first, dtstart.Day(), at)...)
+		}
+	}
+
+	return candidates
+}
+
+// monthCandidates expands BYMONTHDAY/BYDAY within the month starting at first
+func (r *RRule) monthCandidates(first time.Time, defaultDay int, at func(int, time.Month, int) time.Time) []time.Time {
+	year, month := first.Year(), first.Month()
+	daysInMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, first.Location()).Day()
+
+	var candidates []time.Time
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			day := md
+			if md < 0 {
+				day = daysInMonth + md + 1
+			}
+			if day >= 1 && day <= daysInMonth {
+				date := at(year, month, day)
+				if r.matchesWeekday(date) {
+					candidates = append(candidates, date)
+				}
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			var matches []int
+			for day := 1; day <= daysInMonth; day++ {
+				if time.Date(year, month, day, 0, 0, 0, 0, first.Location()).Weekday() == wd.Day {
+					matches = append(matches, day)
+				}
+			}
+			switch {
+			case wd.N == 0:
+				for _, day := range matches {
+					candidates = append(candidates, at(year, month, day))
+				}
+			case wd.N > 0 && wd.N <= len(matches):
+				candidates = append(candidates, at(year, month, matches[wd.N-1]))
+			case wd.N < 0 && -wd.N <= len(matches):
+				candidates = append(candidates, at(year, month, matches[len(matches)+wd.N]))
+			}
+		}
+	default:
+		// Months without the start day (e.g. the 31st) are skipped, as RFC 5545 requires
+		if defaultDay <= daysInMonth {
+			candidates = append(candidates, at(year, month, defaultDay))
+		}
+	}
+	return candidates
+}
+
+func (r *RRule) matchesMonth(t time.Time) bool {
+	return len(r.ByMonth) == 0 || containsInt(r.ByMonth, int(t.Month()))
+}
+
+func (r *RRule) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	for _, md := range r.ByMonthDay {
+		if md == t.Day() || (md < 0 && daysInMonth+md+1 == t.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RRule) matchesWeekday(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, d := range r.ByDay {
+		if d.Day == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
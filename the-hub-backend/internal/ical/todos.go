@@ -0,0 +1,65 @@
+package ical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TodoSpec is a parsed VTODO
+type TodoSpec struct {
+	UID          string
+	Summary      string
+	Description  string
+	Due          *time.Time
+	Status       string
+	Completed    *time.Time
+	Priority     int
+	LastModified time.Time
+}
+
+// ParseTodos extracts the VTODOs of a calendar. Times without a zone are read in fallback.
+func ParseTodos(cal *Component, fallback *time.Location) ([]TodoSpec, error) {
+	if fallback == nil {
+		fallback = time.UTC
+	}
+
+	var todos []TodoSpec
+	for _, comp := range cal.Children {
+		if comp.Name != "VTODO" {
+			continue
+		}
+
+		todo := TodoSpec{
+			UID:         comp.Value("UID"),
+			Summary:     UnescapeText(comp.Value("SUMMARY")),
+			Description: UnescapeText(comp.Value("DESCRIPTION")),
+			Status:      strings.ToUpper(comp.Value("STATUS")),
+		}
+
+		if prop := comp.Get("DUE"); prop != nil {
+			due, _, err := ParseDateTime(prop, fallback)
+			if err != nil {
+				return nil, fmt.Errorf("todo %q: invalid DUE: %w", todo.UID, err)
+			}
+			todo.Due = &due
+		}
+		if prop := comp.Get("COMPLETED"); prop != nil {
+			if completed, _, err := ParseDateTime(prop, time.UTC); err == nil {
+				todo.Completed = &completed
+			}
+		}
+		if value := comp.Value("PRIORITY"); value != "" {
+			if priority, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && priority >= 0 && priority <= 9 {
+				todo.Priority = priority
+			}
+		}
+		if prop := comp.Get("LAST-MODIFIED"); prop != nil {
+			todo.LastModified, _, _ = ParseDateTime(prop, time.UTC)
+		}
+
+		todos = append(todos, todo)
+	}
+	return todos, nil
+}
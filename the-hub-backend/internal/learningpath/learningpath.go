@@ -0,0 +1,179 @@
+// Package learningpath checks the prerequisites between a learning path's topics and
+// works out which topics are locked and how far along the path a user is.
+package learningpath
+
+import (
+	"errors"
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// Statuses that count as done. Learning tasks were once marked "done" rather than completed.
+const (
+	StatusCompleted = "completed"
+	TaskStatusDone  = "done"
+)
+
+var (
+	ErrDuplicateTopic      = errors.New("a topic appears in the path more than once")
+	ErrUnknownPrerequisite = errors.New("a prerequisite is not a topic in the path")
+	ErrSelfPrerequisite    = errors.New("a topic cannot be its own prerequisite")
+)
+
+// CycleError is returned for prerequisites that depend on each other in a loop
+type CycleError struct {
+	Cycle []uuid.UUID // topics around the loop, the first repeated at the end
+}
+
+func (e *CycleError) Error() string {
+	return "topic prerequisites form a cycle"
+}
+
+// Topic is a topic in a path with the topics that must be done before it
+type Topic struct {
+	ID            uuid.UUID
+	Prerequisites []uuid.UUID
+}
+
+// Validate checks that the prerequisites form a directed acyclic graph over the
+// path's topics
+func Validate(topics []Topic) error {
+	byID := make(map[uuid.UUID]*Topic, len(topics))
+	for i := range topics {
+		if byID[topics[i].ID] != nil {
+			return ErrDuplicateTopic
+		}
+		byID[topics[i].ID] = &topics[i]
+	}
+	for _, topic := range topics {
+		for _, prerequisite := range topic.Prerequisites {
+			if prerequisite == topic.ID {
+				return ErrSelfPrerequisite
+			}
+			if byID[prerequisite] == nil {
+				return ErrUnknownPrerequisite
+			}
+		}
+	}
+
+	// Depth-first search; reaching a topic still on the stack closes a cycle
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[uuid.UUID]int, len(topics))
+	var stack []uuid.UUID
+	var visit func(id uuid.UUID) error
+	visit = func(id uuid.UUID) error {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, prerequisite := range byID[id].Prerequisites {
+			switch state[prerequisite] {
+			case visiting:
+				for i, onStack := range stack {
+					if onStack == prerequisite {
+						cycle := append([]uuid.UUID{}, stack[i:]...)
+						return &CycleError{Cycle: append(cycle, prerequisite)}
+					}
+				}
+			case unvisited:
+				if err := visit(prerequisite); err != nil {
+					return err
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = visited
+		return nil
+	}
+	for _, topic := range topics {
+		if state[topic.ID] == unvisited {
+			if err := visit(topic.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// TaskDone reports whether a learning task's status counts as done
+func TaskDone(status string) bool {
+	return status == StatusCompleted || status == TaskStatusDone
+}
+
+// TopicState is a path topic with how far its user has got with it
+type TopicState struct {
+	Topic
+	Status     string // the topic's own status
+	TasksTotal int
+	TasksDone  int
+}
+
+// TopicProgress is how far along a topic is and whether it can be started
+type TopicProgress struct {
+	Done       bool        `json:"done"`                 // completed, or all its tasks are
+	Locked     bool        `json:"locked"`               // not done and waiting on prerequisites
+	BlockedBy  []uuid.UUID `json:"blocked_by,omitempty"` // prerequisites not yet done
+	Progress   float64     `json:"progress"`             // 0 to 1
+	TasksTotal int         `json:"tasks_total"`
+	TasksDone  int         `json:"tasks_done"`
+}
+
+// Summary is how far along a whole path is
+type Summary struct {
+	Progress    float64    `json:"progress"` // mean of the topics' progress, 0 to 1
+	TopicsTotal int        `json:"topics_total"`
+	TopicsDone  int        `json:"topics_done"`
+	TasksTotal  int        `json:"tasks_total"`
+	TasksDone   int        `json:"tasks_done"`
+	Completed   bool       `json:"completed"`
+	NextTopicID *uuid.UUID `json:"next_topic_id,omitempty"` // first unlocked topic in path order not yet done
+}
+
+// Evaluate works out each topic's progress and lock, in the order given, and the
+// path's summary. A completed topic is done; otherwise its progress is the share
+// of its tasks done, and it is done once all of them are.
+func Evaluate(topics []TopicState) ([]TopicProgress, Summary) {
+	progress := make([]TopicProgress, len(topics))
+	done := make(map[uuid.UUID]bool, len(topics))
+	for i, topic := range topics {
+		p := TopicProgress{TasksTotal: topic.TasksTotal, TasksDone: min(topic.TasksDone, topic.TasksTotal)}
+		switch {
+		case topic.Status == StatusCompleted:
+			p.Done, p.Progress = true, 1
+		case p.TasksTotal > 0:
+			p.Done = p.TasksDone == p.TasksTotal
+			p.Progress = math.Round(float64(p.TasksDone)/float64(p.TasksTotal)*1000) / 1000
+		}
+		done[topic.ID] = p.Done
+		progress[i] = p
+	}
+
+	summary := Summary{TopicsTotal: len(topics)}
+	var total float64
+	for i, topic := range topics {
+		p := &progress[i]
+		for _, prerequisite := range topic.Prerequisites {
+			if !done[prerequisite] {
+				p.BlockedBy = append(p.BlockedBy, prerequisite)
+			}
+		}
+		p.Locked = !p.Done && len(p.BlockedBy) > 0
+		if p.Done {
+			summary.TopicsDone++
+		} else if !p.Locked && summary.NextTopicID == nil {
+			id := topic.ID
+			summary.NextTopicID = &id
+		}
+		summary.TasksTotal += p.TasksTotal
+		summary.TasksDone += p.TasksDone
+		total += p.Progress
+	}
+	if len(topics) > 0 {
+		summary.Progress = math.Round(total/float64(len(topics))*1000) / 1000
+		summary.Completed = summary.TopicsDone == len(topics)
+	}
+	return progress, summary
+}
@@ -6,13 +6,31 @@ import (
 	"github.com/google/uuid"
 )
 
+// Statuses of a schedule suggestion
+const (
+	RecommendationPending  = "pending"
+	RecommendationAccepted = "accepted" // scheduled as suggested
+	RecommendationModified = "modified" // scheduled at a time the user chose instead
+	RecommendationRejected = "rejected"
+	RecommendationExpired  = "expired" // replaced by newer suggestions before a response
+)
+
+// AIRecommendation is a suggested block of time for a task, kept so the user's
+// responses can improve later suggestions
 type AIRecommendation struct {
-	ID             uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	TaskID         uuid.UUID `gorm:"type:uuid;index"`
-	Task           Task
-	SuggestedStart time.Time
-	SuggestedEnd   time.Time
-	Confidence     float32
-	Accepted       bool
-	CreatedAt      time.Time
+	ID              uuid.UUID  `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;index"`
+	TaskID          uuid.UUID  `json:"task_id" gorm:"type:uuid;index"`
+	Task            Task       `json:"-"`
+	Title           string     `json:"title"`
+	SuggestedStart  time.Time  `json:"suggested_start"`
+	SuggestedEnd    time.Time  `json:"suggested_end"`
+	Confidence      float32    `json:"confidence"` // 0-1, how likely the suggestion is to be taken
+	Accepted        bool       `json:"accepted"`   // Taken, as suggested or moved
+	Status          string     `json:"status" gorm:"default:'pending'"`
+	FinalStart      *time.Time `json:"final_start"` // Where the block was scheduled, if taken
+	FinalEnd        *time.Time `json:"final_end"`
+	ScheduledTaskID *uuid.UUID `json:"scheduled_task_id" gorm:"type:uuid"`
+	RespondedAt     *time.Time `json:"responded_at"`
+	CreatedAt       time.Time  `json:"created_at"`
 }
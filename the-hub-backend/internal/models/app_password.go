@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AppPassword is a revocable password for clients that cannot do the normal login
+// flow, such as CalDAV calendar apps. Only a hash of the password is stored.
+type AppPassword struct {
+	ID           uuid.UUID  `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID       uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	User         User       `json:"-" gorm:"foreignKey:UserID"`
+	Name         string     `json:"name" gorm:"not null"`
+	PasswordHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"-"`
+}
+
+// CalDAVResource maps a resource name chosen by a CalDAV client to the hub object it
+// created, so the client can keep addressing it by its own name and UID
+type CalDAVResource struct {
+	ID         uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID     uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_caldav_resources_name"`
+	Collection string    `json:"collection" gorm:"not null;uniqueIndex:idx_caldav_resources_name"` // schedule, tasks
+	Name       string    `json:"name" gorm:"not null;uniqueIndex:idx_caldav_resources_name"`
+	ObjectID   uuid.UUID `json:"object_id" gorm:"type:uuid;not null;index"`
+	UID        string    `json:"uid"`
+	CreatedAt  time.Time `json:"-"`
+	UpdatedAt  time.Time `json:"-"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookingLink is a public, secret-token link through which others can book time with
+// the user in their bookable calendar zones
+type BookingLink struct {
+	ID              uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID          uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	User            User      `json:"-" gorm:"foreignKey:UserID"`
+	Token           string    `json:"-" gorm:"not null;uniqueIndex"`
+	Title           string    `json:"title" gorm:"not null"`
+	Description     string    `json:"description"`
+	DurationMinutes int       `json:"duration_minutes" gorm:"default:30"`
+	BufferBefore    int       `json:"buffer_before_minutes" gorm:"default:0"` // Free time kept before each booking
+	BufferAfter     int       `json:"buffer_after_minutes" gorm:"default:0"`  // Free time kept after each booking
+	DailyLimit      *int      `json:"daily_limit"`                            // Maximum bookings per day through this link
+	MinNotice       int       `json:"min_notice_minutes" gorm:"default:60"`   // How soon a booking may start
+	MaxDaysAhead    int       `json:"max_days_ahead" gorm:"default:30"`       // How far ahead bookings may be made
+	ZoneIDs         []string  `json:"zone_ids" gorm:"type:text[]"`            // Only offer these bookable zones (empty = all)
+	IsActive        bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt       time.Time `json:"-"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Booking is time someone booked through a booking link. The booked time is held by
+// a scheduled task on the owner's calendar.
+type Booking struct {
+	ID              uuid.UUID      `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	BookingLinkID   uuid.UUID      `json:"booking_link_id" gorm:"type:uuid;not null;index"`
+	BookingLink     BookingLink    `json:"-" gorm:"foreignKey:BookingLinkID"`
+	UserID          uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
+	ScheduledTaskID *uuid.UUID     `json:"scheduled_task_id" gorm:"type:uuid"`
+	ScheduledTask   *ScheduledTask `json:"-" gorm:"foreignKey:ScheduledTaskID"`
+	Name            string         `json:"name" gorm:"not null"`
+	Email           string         `json:"email" gorm:"not null"`
+	Notes           string         `json:"notes"`
+	Start           time.Time      `json:"start" gorm:"not null"`
+	End             time.Time      `json:"end" gorm:"not null"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"-"`
+}
+
+// IncludesZone reports whether the link offers time in zone
+func (l *BookingLink) IncludesZone(zone *CalendarZone) bool {
+	if !zone.IsActive || !zone.IsBookable {
+		return false
+	}
+	if len(l.ZoneIDs) == 0 {
+		return true
+	}
+	for _, id := range l.ZoneIDs {
+		if id == zone.ID.String() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CalendarFeed is a secret-token iCalendar subscription of a user's schedule and task due
+// dates. Only a hash of the token is stored, so its URL is shown only when it is issued.
+type CalendarFeed struct {
+	ID              uuid.UUID  `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID          uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;uniqueIndex"`
+	User            User       `json:"-" gorm:"foreignKey:UserID"`
+	TokenHash       string     `json:"-" gorm:"not null;uniqueIndex"`
+	IsActive        bool       `json:"is_active" gorm:"default:true"`
+	IncludeSchedule bool       `json:"include_schedule" gorm:"default:true"`
+	IncludeTasks    bool       `json:"include_tasks" gorm:"default:true"`
+	GoalIDs         []string   `json:"goal_ids" gorm:"type:text[]"`   // Only include tasks from these goals (empty = all)
+	Categories      []string   `json:"categories" gorm:"type:text[]"` // Only include tasks in these categories (empty = all)
+	LastAccessedAt  *time.Time `json:"last_accessed_at"`
+	CreatedAt       time.Time  `json:"-"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// HasFilters reports whether the feed is restricted to specific goals or categories
+func (f *CalendarFeed) HasFilters() bool {
+	return len(f.GoalIDs) > 0 || len(f.Categories) > 0
+}
+
+// IncludesTask reports whether a task passes the feed's goal and category filters
+func (f *CalendarFeed) IncludesTask(task *Task) bool {
+	if task == nil {
+		return !f.HasFilters()
+	}
+
+	if len(f.GoalIDs) > 0 {
+		if task.GoalID == nil {
+			return false
+		}
+		matched := false
+		for _, goalID := range f.GoalIDs {
+			if goalID == task.GoalID.String() {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.Categories) > 0 {
+		matched := false
+		for _, category := range f.Categories {
+			if category == task.Category {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
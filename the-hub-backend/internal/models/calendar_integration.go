@@ -29,6 +29,7 @@ type CalendarIntegration struct {
 	IsActive       bool             `json:"is_active" gorm:"default:true"`
 	LastSyncAt     *time.Time       `json:"last_sync_at"`
 	SyncEnabled    bool             `json:"sync_enabled" gorm:"default:true"`
+	SyncToken      string           `json:"-"` // Provider token for incremental pulls
 	CreatedAt      time.Time        `json:"-"`
 	UpdatedAt      time.Time        `json:"-"`
 }
@@ -42,6 +43,20 @@ type CalendarEventSync struct {
 	CalendarIntegration   CalendarIntegration `json:"-" gorm:"foreignKey:CalendarIntegrationID"`
 	ExternalEventID       string              `json:"external_event_id" gorm:"not null"` // Event ID in external calendar
 	LastSyncedAt          time.Time           `json:"last_synced_at"`
+	RemoteUpdatedAt       time.Time           `json:"remote_updated_at"` // Provider's last-modified time at the last sync
 	CreatedAt             time.Time           `json:"-"`
 	UpdatedAt             time.Time           `json:"-"`
 }
+
+// CalendarSyncAudit records every change a sync applied, including how conflicts were resolved
+type CalendarSyncAudit struct {
+	ID                    uuid.UUID  `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	CalendarIntegrationID uuid.UUID  `json:"calendar_integration_id" gorm:"type:uuid;not null;index"`
+	ScheduledTaskID       *uuid.UUID `json:"scheduled_task_id" gorm:"type:uuid"`
+	ExternalEventID       string     `json:"external_event_id"`
+	Direction             string     `json:"direction"` // pull, push
+	Action                string     `json:"action"`    // create, update, delete, conflict
+	Winner                string     `json:"winner"`    // local, remote (conflicts only)
+	Details               string     `json:"details"`
+	CreatedAt             time.Time  `json:"created_at"`
+}
@@ -1,7 +1,6 @@
 package models
 
 import (
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,15 +17,16 @@ type CalendarZone struct {
 	Color       string    `json:"color" gorm:"default:'#3b82f6'"` // Hex color for visualization
 
 	// Time constraints
-	StartTime  time.Time `json:"start_time" gorm:"not null"` // Daily start time (e.g., 09:00:00)
-	EndTime    time.Time `json:"end_time" gorm:"not null"`   // Daily end time (e.g., 17:00:00)
-	DaysOfWeek string    `json:"days_of_week"`               // JSON array: ["monday", "tuesday", "wednesday"]
+	StartTime  time.Time  `json:"start_time" gorm:"not null"`    // Daily start time (e.g., 09:00:00)
+	EndTime    time.Time  `json:"end_time" gorm:"not null"`      // Daily end time; at or before the start time for zones that run past midnight
+	DaysOfWeek WeekdaySet `json:"days_of_week" gorm:"type:text"` // Days the zone starts on: ["monday", "tuesday"] (empty = every day)
 
 	// Scheduling preferences
 	Priority        int  `json:"priority" gorm:"default:5"`             // 1-10, higher = more preferred for scheduling
 	IsActive        bool `json:"is_active" gorm:"default:true"`         // Whether this zone is currently active
 	AllowScheduling bool `json:"allow_scheduling" gorm:"default:false"` // Whether AI can schedule events in this zone
 	MaxEventsPerDay *int `json:"max_events_per_day"`                    // Maximum events allowed per day in this zone
+	IsBookable      bool `json:"is_bookable" gorm:"default:false"`      // Whether others can book time in this zone through booking links
 
 	// Enhanced scheduling controls
 	SchedulingMode string `json:"scheduling_mode" gorm:"default:'none'"` // "none", "whitelist", "blacklist", "non_zone"
@@ -48,12 +48,72 @@ type CalendarZone struct {
 	RecurrenceStart *time.Time `json:"recurrence_start"`
 	RecurrenceEnd   *time.Time `json:"recurrence_end"`
 
+	// Blackouts that suspend the zone, filled in by LoadCalendarZones
+	Blackouts []ZoneBlackout `json:"-" gorm:"-"`
+
 	User      User           `json:"-" gorm:"foreignKey:UserID"`
 	CreatedAt time.Time      `json:"-"`
 	UpdatedAt time.Time      `json:"-"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// ZoneBlackout suspends calendar zones over a range of dates, such as a public holiday,
+// an exam week or a vacation
+type ZoneBlackout struct {
+	ID        uuid.UUID  `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	ZoneID    *uuid.UUID `json:"zone_id" gorm:"type:uuid;index"` // nil suspends all of the user's zones
+	Name      string     `json:"name" gorm:"not null"`
+	Kind      string     `json:"kind" gorm:"default:'other'"`          // holiday, exam, vacation, other
+	StartDate time.Time  `json:"start_date" gorm:"type:date;not null"` // First suspended day
+	EndDate   time.Time  `json:"end_date" gorm:"type:date;not null"`   // Last suspended day
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"-"`
+}
+
+// ZoneBlackoutKinds lists the accepted blackout kinds
+var ZoneBlackoutKinds = []string{"holiday", "exam", "vacation", "other"}
+
+// civilDate numbers a calendar date so dates compare in order
+func civilDate(year int, month time.Month, day int) int {
+	return year*10000 + int(month)*100 + day
+}
+
+// Covers reports whether the blackout suspends zone on the given calendar date
+func (b *ZoneBlackout) Covers(zoneID uuid.UUID, year int, month time.Month, day int) bool {
+	if b.ZoneID != nil && *b.ZoneID != zoneID {
+		return false
+	}
+	date := civilDate(year, month, day)
+	return date >= civilDate(b.StartDate.Date()) && date <= civilDate(b.EndDate.Date())
+}
+
+// LoadCalendarZones loads a user's active zones with their blackouts
+func LoadCalendarZones(db *gorm.DB, userID uuid.UUID) ([]CalendarZone, error) {
+	var zones []CalendarZone
+	if err := db.Where("user_id = ? AND is_active = ?", userID, true).Find(&zones).Error; err != nil {
+		return nil, err
+	}
+	var blackouts []ZoneBlackout
+	if err := db.Where("user_id = ?", userID).Find(&blackouts).Error; err != nil {
+		return nil, err
+	}
+	AttachBlackouts(zones, blackouts)
+	return zones, nil
+}
+
+// AttachBlackouts gives each zone the blackouts that apply to it
+func AttachBlackouts(zones []CalendarZone, blackouts []ZoneBlackout) {
+	for i := range zones {
+		zones[i].Blackouts = nil
+		for _, blackout := range blackouts {
+			if blackout.ZoneID == nil || *blackout.ZoneID == zones[i].ID {
+				zones[i].Blackouts = append(zones[i].Blackouts, blackout)
+			}
+		}
+	}
+}
+
 // ZoneCategory represents predefined categories for zones
 type ZoneCategory struct {
 	ID          uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
@@ -80,36 +140,90 @@ func GetDefaultZoneCategories() []ZoneCategory {
 	}
 }
 
-// IsTimeInZone checks if a given time falls within this zone
-func (cz *CalendarZone) IsTimeInZone(checkTime time.Time) bool {
+// minutesOfDay returns the wall-clock minutes of the zone's start and end in loc
+func (cz *CalendarZone) minutesOfDay(loc *time.Location) (int, int) {
+	start, end := cz.StartTime.In(loc), cz.EndTime.In(loc)
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute()
+}
+
+// IsOvernight reports whether the zone runs past midnight, ending the day after it starts
+func (cz *CalendarZone) IsOvernight(loc *time.Location) bool {
+	startMinutes, endMinutes := cz.minutesOfDay(loc)
+	return endMinutes <= startMinutes
+}
+
+// OccursOn reports whether an occurrence of the zone starts on the given date in loc: the
+// date falls on one of its days, within its recurrence range and outside its blackouts
+func (cz *CalendarZone) OccursOn(date time.Time, loc *time.Location) bool {
 	if !cz.IsActive {
 		return false
 	}
-
-	// Check if the day of week matches
-	if cz.DaysOfWeek != "" {
-		// Parse days of week (assuming JSON array format)
-		// For simplicity, we'll check if the day name is contained in the string
-		dayName := checkTime.Weekday().String()
-		if !strings.Contains(strings.ToLower(cz.DaysOfWeek), strings.ToLower(dayName)) {
+	date = date.In(loc)
+	if !cz.DaysOfWeek.Includes(date.Weekday()) {
+		return false
+	}
+	year, month, day := date.Date()
+	if cz.IsRecurring {
+		if cz.RecurrenceStart != nil && civilDate(year, month, day) < civilDate(cz.RecurrenceStart.In(loc).Date()) {
+			return false
+		}
+		if cz.RecurrenceEnd != nil && civilDate(year, month, day) > civilDate(cz.RecurrenceEnd.In(loc).Date()) {
 			return false
 		}
 	}
+	for i := range cz.Blackouts {
+		if cz.Blackouts[i].Covers(cz.ID, year, month, day) {
+			return false
+		}
+	}
+	return true
+}
 
-	// Check if the time of day falls within the zone
-	checkHour := checkTime.Hour()
-	checkMinute := checkTime.Minute()
+// OccurrenceOn returns the span of the zone's occurrence that starts on the given date
+// in loc. An overnight zone's occurrence ends the next day.
+func (cz *CalendarZone) OccurrenceOn(date time.Time, loc *time.Location) (time.Time, time.Time) {
+	date = date.In(loc)
+	startMinutes, endMinutes := cz.minutesOfDay(loc)
+	start := time.Date(date.Year(), date.Month(), date.Day(), startMinutes/60, startMinutes%60, 0, 0, loc)
+	end := time.Date(date.Year(), date.Month(), date.Day(), endMinutes/60, endMinutes%60, 0, 0, loc)
+	if endMinutes <= startMinutes {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end
+}
 
-	zoneStartHour := cz.StartTime.Hour()
-	zoneStartMinute := cz.StartTime.Minute()
-	zoneEndHour := cz.EndTime.Hour()
-	zoneEndMinute := cz.EndTime.Minute()
+// OccurrenceAt returns the occurrence of the zone that contains t, if any. Only an
+// overnight zone's occurrence from the day before can reach into t's day.
+func (cz *CalendarZone) OccurrenceAt(t time.Time, loc *time.Location) (time.Time, time.Time, bool) {
+	t = t.In(loc)
+	for _, daysBack := range []int{0, 1} {
+		date := t.AddDate(0, 0, -daysBack)
+		if daysBack == 1 && !cz.IsOvernight(loc) {
+			break
+		}
+		if !cz.OccursOn(date, loc) {
+			continue
+		}
+		start, end := cz.OccurrenceOn(date, loc)
+		if !t.Before(start) && t.Before(end) {
+			return start, end, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
 
-	checkTimeMinutes := checkHour*60 + checkMinute
-	zoneStartMinutes := zoneStartHour*60 + zoneStartMinute
-	zoneEndMinutes := zoneEndHour*60 + zoneEndMinute
+// IsTimeInZone checks if a given time falls within this zone. The zone's days and
+// hours are wall-clock times in loc, the owner's timezone. A zone covers its start time
+// up to, but not including, its end time.
+func (cz *CalendarZone) IsTimeInZone(checkTime time.Time, loc *time.Location) bool {
+	_, _, ok := cz.OccurrenceAt(checkTime, loc)
+	return ok
+}
 
-	return checkTimeMinutes >= zoneStartMinutes && checkTimeMinutes <= zoneEndMinutes
+// CoversSpan reports whether [start, end) lies within a single occurrence of the zone
+func (cz *CalendarZone) CoversSpan(start, end time.Time, loc *time.Location) bool {
+	_, occurrenceEnd, ok := cz.OccurrenceAt(start, loc)
+	return ok && !end.After(occurrenceEnd)
 }
 
 // GetZoneScore returns a scheduling preference score for this zone
@@ -130,8 +244,9 @@ func (cz *CalendarZone) GetZoneScore() int {
 	return score
 }
 
-// GetAvailableTimeSlots returns available time slots within this zone for a given date
-func (cz *CalendarZone) GetAvailableTimeSlots(date time.Time, existingEvents []ScheduledTask, slotDuration time.Duration) []time.Time {
+// GetAvailableTimeSlots returns available time slots within this zone for a given date,
+// where the date and the zone's hours are taken in loc
+func (cz *CalendarZone) GetAvailableTimeSlots(date time.Time, loc *time.Location, existingEvents []ScheduledTask, slotDuration time.Duration) []time.Time {
 	var availableSlots []time.Time
 
 	if !cz.IsActive || !cz.AllowScheduling {
@@ -139,18 +254,13 @@ func (cz *CalendarZone) GetAvailableTimeSlots(date time.Time, existingEvents []S
 	}
 
 	// Check if this zone applies to the given date
-	dateWithZoneStart := time.Date(date.Year(), date.Month(), date.Day(),
-		cz.StartTime.Hour(), cz.StartTime.Minute(), 0, 0, date.Location())
-	dateWithZoneEnd := time.Date(date.Year(), date.Month(), date.Day(),
-		cz.EndTime.Hour(), cz.EndTime.Minute(), 0, 0, date.Location())
-
-	// If zone doesn't apply to this day, return empty
-	if !cz.IsTimeInZone(dateWithZoneStart) {
+	if !cz.OccursOn(date, loc) {
 		return availableSlots
 	}
+	dateWithZoneStart, dateWithZoneEnd := cz.OccurrenceOn(date, loc)
 
 	current := dateWithZoneStart
-	for current.Before(dateWithZoneEnd) {
+	for !current.Add(slotDuration).After(dateWithZoneEnd) {
 		slotEnd := current.Add(slotDuration)
 
 		// Check if this slot conflicts with existing events
@@ -172,3 +282,46 @@ func (cz *CalendarZone) GetAvailableTimeSlots(date time.Time, existingEvents []S
 
 	return availableSlots
 }
+
+// weekMinutes is the length of a week in minutes
+const weekMinutes = 7 * 24 * 60
+
+// weekSpans returns the zone's weekly occurrences as minutes from Sunday midnight in loc
+func (cz *CalendarZone) weekSpans(loc *time.Location) [][2]int {
+	startMinutes, endMinutes := cz.minutesOfDay(loc)
+	if endMinutes <= startMinutes {
+		endMinutes += 24 * 60
+	}
+	var spans [][2]int
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if cz.DaysOfWeek.Includes(day) {
+			offset := int(day) * 24 * 60
+			spans = append(spans, [2]int{offset + startMinutes, offset + endMinutes})
+		}
+	}
+	return spans
+}
+
+// Overlaps reports whether the two zones' weekly hours meet on some day, ignoring
+// blackouts. Recurring zones whose date ranges do not meet never overlap.
+func (cz *CalendarZone) Overlaps(other *CalendarZone, loc *time.Location) bool {
+	if cz.IsRecurring && other.IsRecurring {
+		if cz.RecurrenceEnd != nil && other.RecurrenceStart != nil && cz.RecurrenceEnd.Before(*other.RecurrenceStart) {
+			return false
+		}
+		if other.RecurrenceEnd != nil && cz.RecurrenceStart != nil && other.RecurrenceEnd.Before(*cz.RecurrenceStart) {
+			return false
+		}
+	}
+	for _, a := range cz.weekSpans(loc) {
+		for _, b := range other.weekSpans(loc) {
+			// Saturday night zones run into the next week's Sunday
+			for _, shift := range []int{-weekMinutes, 0, weekMinutes} {
+				if a[0] < b[1]+shift && b[0]+shift < a[1] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
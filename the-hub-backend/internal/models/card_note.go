@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CardNote is the source of one or more generated cards. Its note type (see package
+// notetype) decides which cards it makes; editing it rewrites their text but keeps
+// their review state.
+type CardNote struct {
+	ID        uuid.UUID      `json:"note_id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	DeckID    uuid.UUID      `json:"deck_id" gorm:"type:uuid;not null;index"`
+	Model     string         `json:"model" gorm:"not null;default:basic"` // notetype.Basic, BasicReversed, Cloze or TypeIn
+	Front     string         `json:"front" gorm:"type:text;not null"`     // Cloze text for cloze notes
+	Back      string         `json:"back" gorm:"type:text"`
+	Extra     string         `json:"extra" gorm:"type:text"`           // Shown below every card's answer
+	SourceID  string         `json:"source_id,omitempty" gorm:"index"` // Anki note GUID for notes imported from a package
+	Cards     []Card         `json:"cards,omitempty" gorm:"foreignKey:NoteID"`
+	Deck      Deck           `json:"-" gorm:"foreignKey:DeckID"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/srs"
+	"github.com/google/uuid"
+)
+
+// CardReview logs one review of a card, with the memory state it left behind
+type CardReview struct {
+	ID             uuid.UUID  `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	CardID         uuid.UUID  `json:"card_id" gorm:"type:uuid;not null;index"`
+	DeckID         uuid.UUID  `json:"deck_id" gorm:"type:uuid;not null"`
+	UserID         uuid.UUID  `json:"user_id" gorm:"type:uuid;not null"`
+	StudySessionID *uuid.UUID `json:"study_session_id,omitempty" gorm:"type:uuid;index"` // Live study session the review was made in
+	Rating         int        `json:"rating"`                                            // FSRS rating (1-4)
+	Quality        int        `json:"quality"`                                           // SM-2 quality (0-5)
+	Scheduler      string     `json:"scheduler"`                                         // Scheduler that chose the next interval
+	FirstReview    bool       `json:"first_review"`                                      // Whether the card had never been reviewed before
+	ElapsedDays    int        `json:"elapsed_days"`                                      // Whole days since the previous review
+	IntervalBefore int        `json:"interval_before"`                                   // Days the card was scheduled for before this review
+	Interval       int        `json:"interval"`                                          // Days until the next review
+	TimeTakenMs    int        `json:"time_taken_ms"`                                     // How long answering took, 0 if not timed
+	Stability      float64    `json:"stability"`
+	Difficulty     float64    `json:"difficulty"`
+	ReviewedAt     time.Time  `json:"reviewed_at" gorm:"index"`
+}
+
+// Event returns the review as analytics need it
+func (r *CardReview) Event() srs.ReviewEvent {
+	return srs.ReviewEvent{
+		CardID:         r.CardID,
+		Rating:         srs.Rating(r.Rating),
+		FirstReview:    r.FirstReview,
+		ElapsedDays:    r.ElapsedDays,
+		IntervalBefore: r.IntervalBefore,
+		TimeTakenMs:    r.TimeTakenMs,
+		ReviewedAt:     r.ReviewedAt,
+	}
+}
+
+// SRSParameters are a user's FSRS weights, fit to their review history
+type SRSParameters struct {
+	ID         uuid.UUID `json:"-" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID     uuid.UUID `json:"-" gorm:"type:uuid;not null;uniqueIndex"`
+	Weights    string    `json:"-" gorm:"type:jsonb;not null"` // JSON array of the FSRS weights
+	Reviews    int       `json:"reviews"`                      // Reviews the weights were fit on
+	LossBefore float64   `json:"loss_before"`
+	LossAfter  float64   `json:"loss_after"`
+	FittedAt   time.Time `json:"fitted_at"`
+	CreatedAt  time.Time `json:"-"`
+	UpdatedAt  time.Time `json:"-"`
+}
+
+func (SRSParameters) TableName() string {
+	return "srs_parameters"
+}
+
+// Parameters decodes the stored weights, falling back to the defaults
+func (p *SRSParameters) Parameters() srs.Parameters {
+	if p == nil {
+		return srs.DefaultParameters
+	}
+	var weights srs.Parameters
+	decodeJSONColumn(p.Weights, &weights)
+	if !weights.Valid() {
+		return srs.DefaultParameters
+	}
+	return weights
+}
+
+// SetParameters encodes weights into the stored column
+func (p *SRSParameters) SetParameters(weights srs.Parameters) error {
+	value, err := encodeJSONColumn(weights, "[]")
+	if err != nil {
+		return err
+	}
+	p.Weights = value
+	return nil
+}
+
+// Memory returns what the scheduler needs to know about the card
+func (c *Card) Memory() srs.Memory {
+	return srs.Memory{
+		Easiness:     c.Easiness,
+		Interval:     c.Interval,
+		Repetitions:  c.Repetitions,
+		Stability:    c.Stability,
+		Difficulty:   c.Difficulty,
+		Lapses:       c.Lapses,
+		LastReviewed: c.LastReviewed,
+	}
+}
+
+// SetMemory stores a scheduler's memory state on the card
+func (c *Card) SetMemory(m srs.Memory) {
+	c.Easiness = m.Easiness
+	c.Interval = m.Interval
+	c.Repetitions = m.Repetitions
+	c.Stability = m.Stability
+	c.Difficulty = m.Difficulty
+	c.Lapses = m.Lapses
+	c.LastReviewed = m.LastReviewed
+}
+
+// State returns the card as analytics need it
+func (c *Card) State() srs.CardState {
+	return srs.CardState{
+		CardID:     c.ID,
+		Question:   c.Question,
+		Lapses:     c.Lapses,
+		Difficulty: c.Difficulty,
+		Easiness:   c.Easiness,
+		Suspended:  c.Suspended,
+		NextReview: c.NextReview,
+		Reviewed:   !c.LastReviewed.IsZero() || c.Repetitions > 0,
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CardTags is a card's set of tags. Tags are lowercase, with spaces replaced by
+// hyphens, and kept sorted without repeats; they are stored as a JSON array.
+type CardTags []string
+
+// NewCardTags tidies tags into a set
+func NewCardTags(tags []string) CardTags {
+	seen := map[string]bool{}
+	set := CardTags{}
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			set = append(set, tag)
+		}
+	}
+	sort.Strings(set)
+	return set
+}
+
+// Has reports whether the set includes tag
+func (t CardTags) Has(tag string) bool {
+	for _, have := range t {
+		if have == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Value stores the set as a JSON array
+func (t CardTags) Value() (driver.Value, error) {
+	data, err := json.Marshal(NewCardTags(t))
+	return string(data), err
+}
+
+// Scan reads a stored set
+func (t *CardTags) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*t = CardTags{}
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot read card tags from %T", value)
+	}
+	var tags []string
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return fmt.Errorf("invalid card tags: %w", err)
+	}
+	*t = NewCardTags(tags)
+	return nil
+}
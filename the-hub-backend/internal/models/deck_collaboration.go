@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/srs"
+	"github.com/google/uuid"
+)
+
+// Deck invite statuses
+const (
+	DeckInvitePending  = "pending"
+	DeckInviteAccepted = "accepted"
+	DeckInviteDeclined = "declined"
+	DeckInviteRevoked  = "revoked"
+)
+
+// DeckInvite is a deck owner's invitation to another user to collaborate on a deck
+type DeckInvite struct {
+	ID          uuid.UUID  `json:"invite_id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	DeckID      uuid.UUID  `json:"deck_id" gorm:"type:uuid;not null;index"`
+	InviterID   uuid.UUID  `json:"inviter_id" gorm:"type:uuid;not null"`
+	InviteeID   uuid.UUID  `json:"invitee_id" gorm:"type:uuid;not null;index"`
+	Role        string     `json:"role" gorm:"not null"`                   // DeckRoleEditor or DeckRoleViewer
+	Status      string     `json:"status" gorm:"not null;default:pending"` // DeckInvitePending, Accepted, Declined or Revoked
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	Deck        *Deck      `json:"deck,omitempty" gorm:"foreignKey:DeckID"`
+	Inviter     *User      `json:"inviter,omitempty" gorm:"foreignKey:InviterID"`
+	Invitee     *User      `json:"invitee,omitempty" gorm:"foreignKey:InviteeID"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"-"`
+}
+
+// CardProgress is a collaborator's own review state for a card in a deck shared with
+// them, so that each studies the shared cards on their own schedule. The owner's
+// state is kept on the card itself.
+type CardProgress struct {
+	ID           uuid.UUID  `json:"-" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	CardID       uuid.UUID  `json:"card_id" gorm:"type:uuid;not null;uniqueIndex:idx_card_progress_card_user"`
+	UserID       uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_card_progress_card_user"`
+	DeckID       uuid.UUID  `json:"deck_id" gorm:"type:uuid;not null;index"`
+	Easiness     float64    `json:"-" gorm:"default:2.5"`
+	Interval     int        `json:"-" gorm:"default:1"`
+	Repetitions  int        `json:"-" gorm:"default:0"`
+	Stability    float64    `json:"-" gorm:"default:0"`
+	Difficulty   float64    `json:"-" gorm:"default:0"`
+	Lapses       int        `json:"lapses" gorm:"default:0"`
+	Suspended    bool       `json:"suspended" gorm:"default:false"`
+	BuriedUntil  *time.Time `json:"buried_until,omitempty"`
+	LastReviewed time.Time  `json:"last_review"`
+	NextReview   time.Time  `json:"next_review" gorm:"index"`
+	CreatedAt    time.Time  `json:"-"`
+	UpdatedAt    time.Time  `json:"-"`
+}
+
+func (CardProgress) TableName() string {
+	return "card_progress"
+}
+
+// Memory returns what the scheduler needs to know about the collaborator's state
+func (p *CardProgress) Memory() srs.Memory {
+	return srs.Memory{
+		Easiness:     p.Easiness,
+		Interval:     p.Interval,
+		Repetitions:  p.Repetitions,
+		Stability:    p.Stability,
+		Difficulty:   p.Difficulty,
+		Lapses:       p.Lapses,
+		LastReviewed: p.LastReviewed,
+	}
+}
+
+// NewCardProgress returns a user's state for card taken from the card's current state
+func NewCardProgress(card *Card, userID uuid.UUID) CardProgress {
+	return CardProgress{
+		CardID:       card.ID,
+		UserID:       userID,
+		DeckID:       card.DeckID,
+		Easiness:     card.Easiness,
+		Interval:     card.Interval,
+		Repetitions:  card.Repetitions,
+		Stability:    card.Stability,
+		Difficulty:   card.Difficulty,
+		Lapses:       card.Lapses,
+		Suspended:    card.Suspended,
+		BuriedUntil:  card.BuriedUntil,
+		LastReviewed: card.LastReviewed,
+		NextReview:   card.NextReview,
+	}
+}
+
+// ApplyProgress replaces the card's review state with a collaborator's, or with that
+// of a card they have never studied when p is nil
+func (c *Card) ApplyProgress(p *CardProgress) {
+	if p == nil {
+		p = &CardProgress{Easiness: 2.5, Interval: 1, NextReview: c.CreatedAt}
+	}
+	c.SetMemory(p.Memory())
+	c.Suspended = p.Suspended
+	c.BuriedUntil = p.BuriedUntil
+	c.NextReview = p.NextReview
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Deck report reasons
+const (
+	DeckReportSpam       = "spam"
+	DeckReportOffensive  = "offensive"
+	DeckReportCopyright  = "copyright"
+	DeckReportMisleading = "misleading"
+	DeckReportOther      = "other"
+)
+
+// Deck report statuses. An actioned report got its deck taken out of the library.
+const (
+	DeckReportPending   = "pending"
+	DeckReportReviewed  = "reviewed"
+	DeckReportActioned  = "actioned"
+	DeckReportDismissed = "dismissed"
+)
+
+// PublicDeck is a deck in the public deck library with what helps pick one
+type PublicDeck struct {
+	Deck
+	OwnerName       string  `json:"owner_name"`
+	CardCount       int64   `json:"card_count"`
+	RatingAverage   float64 `json:"rating_average"`
+	RatingCount     int64   `json:"rating_count"`
+	SubscriberCount int64   `json:"subscriber_count"`
+	Subscribed      bool    `json:"subscribed"` // Whether the requesting user subscribes to it
+}
+
+// DeckRating is a user's rating of a public deck
+type DeckRating struct {
+	ID        uuid.UUID `json:"rating_id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	DeckID    uuid.UUID `json:"deck_id" gorm:"type:uuid;not null;uniqueIndex:idx_deck_ratings_deck_user"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_deck_ratings_deck_user"`
+	Stars     int       `json:"stars" gorm:"not null"` // 1 to 5
+	Review    string    `json:"review" gorm:"type:text"`
+	UserName  string    `json:"user_name,omitempty" gorm:"->;-:migration"` // The rater's name when listed; never stored
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DeckReport is a user's report of abuse in a public deck, for admins to review
+type DeckReport struct {
+	ID            uuid.UUID  `json:"report_id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	DeckID        uuid.UUID  `json:"deck_id" gorm:"type:uuid;not null;index"`
+	ReporterID    uuid.UUID  `json:"reporter_id" gorm:"type:uuid;not null"`
+	Reason        string     `json:"reason" gorm:"not null"` // DeckReportSpam, Offensive, Copyright, Misleading or Other
+	Details       string     `json:"details" gorm:"type:text"`
+	Status        string     `json:"status" gorm:"not null;default:pending"` // DeckReportPending, Reviewed, Actioned or Dismissed
+	AdminResponse string     `json:"admin_response"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+	Deck          *Deck      `json:"deck,omitempty" gorm:"foreignKey:DeckID"`
+	Reporter      *User      `json:"reporter,omitempty" gorm:"foreignKey:ReporterID"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"-"`
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EnergyProfile stores when a user works best. Settings made through the API take
+// precedence; the learned columns are rebuilt from completed tasks and time entries.
+type EnergyProfile struct {
+	ID     uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex"`
+
+	// Manual settings; empty or null values fall back to learned ones
+	TimeSlots          string `json:"-" gorm:"type:jsonb;default:'{}'"` // JSON object: hour (0-23) -> energy level (1-10)
+	Workload           string `json:"-" gorm:"type:jsonb;default:'{}'"` // JSON object: day -> workload score (1-10)
+	WorkDays           string `json:"-" gorm:"type:jsonb;default:'[]'"` // JSON array of days
+	PreferredStartHour *int   `json:"-"`
+	PreferredEndHour   *int   `json:"-"`
+	BreakDuration      *int   `json:"-"` // minutes
+	LearningEnabled    bool   `json:"-" gorm:"default:true"`
+
+	// Learned from the user's history
+	LearnedTimeSlots   string     `json:"-" gorm:"type:jsonb;default:'{}'"` // JSON object: hour -> energy level
+	CategoryTimeSlots  string     `json:"-" gorm:"type:jsonb;default:'{}'"` // JSON object: category -> hour -> energy level
+	LearnedWorkDays    string     `json:"-" gorm:"type:jsonb;default:'[]'"`
+	LearnedStartHour   *int       `json:"-"`
+	LearnedEndHour     *int       `json:"-"`
+	LearnedSampleCount int        `json:"-" gorm:"default:0"`
+	LearnedAt          *time.Time `json:"-"`
+
+	User      User      `json:"-" gorm:"foreignKey:UserID"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// EnergyProfileSettings is the editable part of an energy profile
+type EnergyProfileSettings struct {
+	TimeSlots          map[string]int `json:"time_slots"`
+	Workload           map[string]int `json:"workload"`
+	WorkDays           []string       `json:"work_days"`
+	PreferredStartHour *int           `json:"preferred_start_hour"`
+	PreferredEndHour   *int           `json:"preferred_end_hour"`
+	BreakDuration      *int           `json:"break_duration"`
+	LearningEnabled    bool           `json:"learning_enabled"`
+}
+
+// LearnedEnergy is what was learned about when a user works
+type LearnedEnergy struct {
+	TimeSlots     map[string]int            `json:"time_slots"`
+	CategorySlots map[string]map[string]int `json:"category_time_slots"`
+	WorkDays      []string                  `json:"work_days"`
+	StartHour     *int                      `json:"start_hour"`
+	EndHour       *int                      `json:"end_hour"`
+	SampleCount   int                       `json:"sample_count"`
+	LearnedAt     *time.Time                `json:"learned_at"`
+}
+
+// Settings decodes the manual settings of the profile
+func (p *EnergyProfile) Settings() EnergyProfileSettings {
+	settings := EnergyProfileSettings{
+		TimeSlots:          map[string]int{},
+		Workload:           map[string]int{},
+		WorkDays:           []string{},
+		PreferredStartHour: p.PreferredStartHour,
+		PreferredEndHour:   p.PreferredEndHour,
+		BreakDuration:      p.BreakDuration,
+		LearningEnabled:    p.LearningEnabled,
+	}
+	decodeJSONColumn(p.TimeSlots, &settings.TimeSlots)
+	decodeJSONColumn(p.Workload, &settings.Workload)
+	decodeJSONColumn(p.WorkDays, &settings.WorkDays)
+	return settings
+}
+
+// SetSettings encodes settings into the manual columns of the profile
+func (p *EnergyProfile) SetSettings(settings EnergyProfileSettings) error {
+	var err error
+	if p.TimeSlots, err = encodeJSONColumn(settings.TimeSlots, "{}"); err != nil {
+		return err
+	}
+	if p.Workload, err = encodeJSONColumn(settings.Workload, "{}"); err != nil {
+		return err
+	}
+	if p.WorkDays, err = encodeJSONColumn(settings.WorkDays, "[]"); err != nil {
+		return err
+	}
+	p.PreferredStartHour = settings.PreferredStartHour
+	p.PreferredEndHour = settings.PreferredEndHour
+	p.BreakDuration = settings.BreakDuration
+	p.LearningEnabled = settings.LearningEnabled
+	return nil
+}
+
+// Learned decodes the learned columns of the profile
+func (p *EnergyProfile) Learned() LearnedEnergy {
+	learned := LearnedEnergy{
+		TimeSlots:     map[string]int{},
+		CategorySlots: map[string]map[string]int{},
+		WorkDays:      []string{},
+		StartHour:     p.LearnedStartHour,
+		EndHour:       p.LearnedEndHour,
+		SampleCount:   p.LearnedSampleCount,
+		LearnedAt:     p.LearnedAt,
+	}
+	decodeJSONColumn(p.LearnedTimeSlots, &learned.TimeSlots)
+	decodeJSONColumn(p.CategoryTimeSlots, &learned.CategorySlots)
+	decodeJSONColumn(p.LearnedWorkDays, &learned.WorkDays)
+	return learned
+}
+
+// SetLearned encodes learned values into the profile
+func (p *EnergyProfile) SetLearned(learned LearnedEnergy) error {
+	var err error
+	if p.LearnedTimeSlots, err = encodeJSONColumn(learned.TimeSlots, "{}"); err != nil {
+		return err
+	}
+	if p.CategoryTimeSlots, err = encodeJSONColumn(learned.CategorySlots, "{}"); err != nil {
+		return err
+	}
+	if p.LearnedWorkDays, err = encodeJSONColumn(learned.WorkDays, "[]"); err != nil {
+		return err
+	}
+	p.LearnedStartHour = learned.StartHour
+	p.LearnedEndHour = learned.EndHour
+	p.LearnedSampleCount = learned.SampleCount
+	p.LearnedAt = learned.LearnedAt
+	return nil
+}
+
+func decodeJSONColumn(value string, target interface{}) {
+	if value == "" || value == "null" {
+		return
+	}
+	_ = json.Unmarshal([]byte(value), target)
+}
+
+func encodeJSONColumn(value interface{}, empty string) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	if string(data) == "null" {
+		return empty, nil
+	}
+	return string(data), nil
+}
@@ -8,36 +8,87 @@ import (
 )
 
 type Deck struct {
-	ID        uuid.UUID      `json:"deck_id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	Name      string         `json:"name" gorm:"not null"`
-	UserID    uuid.UUID      `json:"user_id" gorm:"type:uuid;not null"`
-	IsPublic  bool           `json:"is_public" gorm:"default:false"`
-	Cards     []Card         `json:"-"`
-	User      User           `json:"-" gorm:"foreignKey:UserID"`
-	CreatedAt time.Time      `json:"-"`
-	UpdatedAt time.Time      `json:"-"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID               uuid.UUID      `json:"deck_id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Name             string         `json:"name" gorm:"not null"`
+	UserID           uuid.UUID      `json:"user_id" gorm:"type:uuid;not null"`
+	Description      string         `json:"description" gorm:"type:text"`
+	IsPublic         bool           `json:"is_public" gorm:"default:false"`                  // Listed in the public deck library
+	ClonedFromID     *uuid.UUID     `json:"cloned_from_id,omitempty" gorm:"type:uuid;index"` // Library deck this deck was cloned from
+	Scheduler        string         `json:"scheduler" gorm:"default:sm2"`                    // srs.SchedulerSM2 or srs.SchedulerFSRS
+	DesiredRetention float64        `json:"desired_retention" gorm:"default:0.9"`            // Recall probability FSRS schedules reviews for
+	LeechThreshold   int            `json:"leech_threshold" gorm:"default:8"`                // Lapses after which a card is suspended, 0 to never
+	ParentID         *uuid.UUID     `json:"parent_id,omitempty" gorm:"type:uuid;index"`      // Deck this is a sub-deck of; studying a deck includes its sub-decks
+	NewCardsPerDay   int            `json:"new_cards_per_day" gorm:"default:20"`             // New cards studied a day across the deck and its sub-decks, 0 for no limit
+	ReviewsPerDay    int            `json:"reviews_per_day" gorm:"default:200"`              // Reviews a day across the deck and its sub-decks, 0 for no limit
+	Role             string         `json:"role,omitempty" gorm:"->;-:migration"`            // The requesting user's role in the deck; never stored
+	Cards            []Card         `json:"-"`
+	User             User           `json:"-" gorm:"foreignKey:UserID"`
+	CreatedAt        time.Time      `json:"-"`
+	UpdatedAt        time.Time      `json:"-"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// Deck collaborator roles. The deck's creator is its owner; others join as editors,
+// who can change its cards, or viewers, who can only study them. Subscribers to a
+// public deck study it like viewers for as long as it stays public.
+const (
+	DeckRoleOwner      = "owner"
+	DeckRoleEditor     = "editor"
+	DeckRoleViewer     = "viewer"
+	DeckRoleSubscriber = "subscriber"
+)
+
+// deckRoleRanks orders roles by what they allow
+var deckRoleRanks = map[string]int{DeckRoleSubscriber: 1, DeckRoleViewer: 1, DeckRoleEditor: 2, DeckRoleOwner: 3}
+
+// DeckRoleAllows reports whether role grants at least what required does
+func DeckRoleAllows(role, required string) bool {
+	return deckRoleRanks[role] > 0 && deckRoleRanks[role] >= deckRoleRanks[required]
+}
+
+// DeckUser is a collaborator on another user's deck
 type DeckUser struct {
-	ID     uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	DeckID uuid.UUID `gorm:"type:uuid"`
-	UserID uuid.UUID `gorm:"type:uuid"`
-	Role   string    // "owner", "editor", "viewer"
-	Deck   Deck      `gorm:"foreignKey:DeckID"`
-	User   User      `gorm:"foreignKey:UserID"`
+	ID        uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	DeckID    uuid.UUID `json:"deck_id" gorm:"type:uuid;not null;uniqueIndex:idx_deck_users_deck_user"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_deck_users_deck_user"`
+	Role      string    `json:"role" gorm:"not null"` // DeckRoleEditor, DeckRoleViewer or DeckRoleSubscriber
+	Deck      Deck      `json:"-" gorm:"foreignKey:DeckID"`
+	User      User      `json:"user" gorm:"foreignKey:UserID"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"-"`
 }
 
+// Sources a card can be generated from
+const (
+	CardSourceNote         = "note"
+	CardSourceTaskLearning = "task_learning"
+	CardSourceText         = "text"
+	CardSourceMarkdown     = "markdown"
+)
+
 type Card struct {
 	ID           uuid.UUID      `json:"card_id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 	DeckID       uuid.UUID      `json:"deck_id" gorm:"type:uuid;not null"`
 	Question     string         `json:"question" gorm:"not null"`
 	Answer       string         `json:"answer" gorm:"not null"`
-	Easiness     float64        `json:"-" gorm:"default:2.5"`     // SM-2 easiness factor
-	Interval     int            `json:"-" gorm:"default:1"`       // Days until next review
-	Repetitions  int            `json:"-" gorm:"default:0"`       // Successful reviews in a row
-	LastReviewed time.Time      `json:"last_review"`              // Last time card was reviewed
-	NextReview   time.Time      `json:"next_review" gorm:"index"` // When the card should next appear
+	NoteID       *uuid.UUID     `json:"note_id,omitempty" gorm:"type:uuid;index"`   // Note the card was generated from, if any
+	Template     string         `json:"template,omitempty"`                         // Which of its note's cards this is
+	CardType     string         `json:"card_type" gorm:"default:basic"`             // notetype.CardBasic, CardCloze or CardTypeIn
+	SourceType   string         `json:"source_type,omitempty"`                      // CardSourceNote, CardSourceTaskLearning, CardSourceText or CardSourceMarkdown
+	SourceID     *uuid.UUID     `json:"source_id,omitempty" gorm:"type:uuid;index"` // Note or task learning the card was generated from
+	SourceHash   string         `json:"-"`                                          // ai.ChunkHash of the text it was generated from
+	SourceStale  bool           `json:"source_stale" gorm:"default:false"`          // Its source text has changed since
+	Tags         CardTags       `json:"tags" gorm:"type:jsonb;not null;default:'[]'"`
+	Easiness     float64        `json:"-" gorm:"default:2.5"`           // SM-2 easiness factor
+	Interval     int            `json:"-" gorm:"default:1"`             // Days until next review
+	Repetitions  int            `json:"-" gorm:"default:0"`             // Successful reviews in a row
+	Stability    float64        `json:"-" gorm:"default:0"`             // FSRS stability in days, 0 until first reviewed with FSRS
+	Difficulty   float64        `json:"-" gorm:"default:0"`             // FSRS difficulty (1-10)
+	Lapses       int            `json:"lapses" gorm:"default:0"`        // Times forgotten after being learned
+	Suspended    bool           `json:"suspended" gorm:"default:false"` // Left out of reviews, e.g. as a leech
+	BuriedUntil  *time.Time     `json:"buried_until,omitempty"`         // Left out of reviews until then, usually the next day
+	LastReviewed time.Time      `json:"last_review"`                    // Last time card was reviewed
+	NextReview   time.Time      `json:"next_review" gorm:"index"`       // When the card should next appear
 	Deck         Deck           `json:"-" gorm:"foreignKey:DeckID"`
 	CreatedAt    time.Time      `json:"-"`
 	UpdatedAt    time.Time      `json:"-"`
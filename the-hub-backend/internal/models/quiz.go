@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Quiz is a set of AI-generated questions on a deck's cards or a topic's notes. The
+// questions, with their answers, are stored as JSON and only shown graded.
+type Quiz struct {
+	ID            uuid.UUID     `json:"quiz_id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID        uuid.UUID     `json:"user_id" gorm:"type:uuid;not null;index"`
+	DeckID        *uuid.UUID    `json:"deck_id,omitempty" gorm:"type:uuid;index"`
+	TopicID       *uuid.UUID    `json:"topic_id,omitempty" gorm:"type:uuid;index"`
+	Title         string        `json:"title" gorm:"not null"`
+	Questions     string        `json:"-" gorm:"type:jsonb;not null;default:'[]'"` // JSON array of quiz.Question
+	QuestionCount int           `json:"question_count"`
+	Attempts      []QuizAttempt `json:"-" gorm:"foreignKey:QuizID"`
+	User          User          `json:"-" gorm:"foreignKey:UserID"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"-"`
+}
+
+// QuizAttempt is one graded attempt at a quiz
+type QuizAttempt struct {
+	ID             uuid.UUID `json:"quiz_attempt_id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	QuizID         uuid.UUID `json:"quiz_id" gorm:"type:uuid;not null;index"`
+	UserID         uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Results        string    `json:"-" gorm:"type:jsonb;not null;default:'[]'"` // JSON array of quiz.Result, one per question
+	Correct        int       `json:"correct"`
+	Total          int       `json:"total"`
+	Score          float64   `json:"score"`           // Fraction answered correctly, 0-1
+	CardsShortened int       `json:"cards_shortened"` // Cards brought forward for the questions answered wrongly
+	CreatedAt      time.Time `json:"created_at"`
+}
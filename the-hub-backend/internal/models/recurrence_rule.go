@@ -207,3 +207,50 @@ func (rr *RecurrenceRule) CreateTaskFromRule(userID uuid.UUID, occurrenceDate ti
 
 	return task
 }
+
+var rruleWeekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// RRule returns the rule as an iCalendar RRULE value (without the "RRULE:" prefix)
+func (rr *RecurrenceRule) RRule() string {
+	freq := strings.ToUpper(rr.Frequency)
+	switch freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return ""
+	}
+
+	parts := []string{"FREQ=" + freq}
+	if rr.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(rr.Interval))
+	}
+
+	if rr.ByDay != "" {
+		var days []string
+		for _, dayStr := range strings.Split(rr.ByDay, ",") {
+			dayStr = strings.TrimSpace(dayStr)
+			if day, err := strconv.Atoi(dayStr); err == nil && day >= 0 && day < len(rruleWeekdays) {
+				days = append(days, rruleWeekdays[day])
+			} else if len(dayStr) == 2 {
+				days = append(days, strings.ToUpper(dayStr))
+			}
+		}
+		if len(days) > 0 {
+			parts = append(parts, "BYDAY="+strings.Join(days, ","))
+		}
+	}
+
+	if rr.ByMonthDay != nil {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(*rr.ByMonthDay))
+	}
+	if rr.ByMonth != nil {
+		parts = append(parts, "BYMONTH="+strconv.Itoa(*rr.ByMonth))
+	}
+
+	if rr.Count != nil {
+		parts = append(parts, "COUNT="+strconv.Itoa(*rr.Count))
+	} else if rr.EndDate != nil {
+		parts = append(parts, "UNTIL="+rr.EndDate.UTC().Format("20060102T150405Z"))
+	}
+
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Why a block is replanned
+const (
+	ReplanMissed   = "missed"   // the block ended without its task being completed
+	ReplanOverrun  = "overrun"  // work tracked on the block is still running past its end
+	ReplanConflict = "conflict" // the block overlaps another event
+)
+
+// Replan proposal statuses
+const (
+	ReplanPending    = "pending"
+	ReplanAccepted   = "accepted"
+	ReplanRejected   = "rejected"
+	ReplanSuperseded = "superseded" // a newer proposal replaced it before it was answered
+)
+
+// ReplanBlock is a scheduled block before or after a replan. ID is empty for blocks
+// that a replan would create.
+type ReplanBlock struct {
+	ID    *uuid.UUID `json:"id,omitempty"`
+	Title string     `json:"title"`
+	Start time.Time  `json:"start"`
+	End   time.Time  `json:"end"`
+}
+
+// ReplanChange moves the work on one task. Blocks in Before whose ID is not in After are
+// removed. A change with an empty After could not be placed and leaves Before as it is.
+type ReplanChange struct {
+	TaskID        uuid.UUID     `json:"task_id"`
+	Title         string        `json:"title"`
+	Reasons       []string      `json:"reasons"`
+	Before        []ReplanBlock `json:"before"`
+	After         []ReplanBlock `json:"after"`
+	Unschedulable string        `json:"unschedulable,omitempty"`
+}
+
+// ReplanProposal is a set of changes to a user's schedule waiting to be accepted or rejected
+type ReplanProposal struct {
+	ID         uuid.UUID  `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Changes    string     `json:"-" gorm:"type:jsonb;default:'[]'"` // JSON array of ReplanChange
+	Status     string     `json:"status" gorm:"default:'pending'"`
+	Automatic  bool       `json:"automatic" gorm:"default:false"` // proposed by the background job
+	ResolvedAt *time.Time `json:"resolved_at"`
+	User       User       `json:"-" gorm:"foreignKey:UserID"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"-"`
+}
+
+// ReplanProposalResponse is a proposal with its changes decoded
+type ReplanProposalResponse struct {
+	ID         uuid.UUID      `json:"id"`
+	Status     string         `json:"status"`
+	Automatic  bool           `json:"automatic"`
+	Changes    []ReplanChange `json:"changes"`
+	ResolvedAt *time.Time     `json:"resolved_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// GetChanges decodes the proposal's changes
+func (p *ReplanProposal) GetChanges() []ReplanChange {
+	changes := []ReplanChange{}
+	decodeJSONColumn(p.Changes, &changes)
+	return changes
+}
+
+// SetChanges encodes changes into the proposal
+func (p *ReplanProposal) SetChanges(changes []ReplanChange) error {
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	p.Changes = string(data)
+	return nil
+}
+
+func (p *ReplanProposal) ToResponse() ReplanProposalResponse {
+	return ReplanProposalResponse{
+		ID:         p.ID,
+		Status:     p.Status,
+		Automatic:  p.Automatic,
+		Changes:    p.GetChanges(),
+		ResolvedAt: p.ResolvedAt,
+		CreatedAt:  p.CreatedAt,
+	}
+}
@@ -1,8 +1,10 @@
 package models
 
 import (
+	"sort"
 	"time"
 
+	"github.com/TheoMKgosi/The-hub/internal/ical"
 	"github.com/google/uuid"
 )
 
@@ -19,6 +21,91 @@ type ScheduledTask struct {
 	UserID           uuid.UUID       `json:"user_id" gorm:"type:uuid"`
 	User             User            `json:"-" gorm:"foreignKey:UserID"`
 	CreatedByAI      bool            `json:"created_by_ai" gorm:"default:false"`
-	CreatedAt        time.Time       `json:"-"`
-	UpdatedAt        time.Time       `json:"-"`
+	ExternalUID      string          `json:"external_uid,omitempty" gorm:"index"`            // iCalendar UID (plus recurrence ID) of imported events
+	Source           string          `json:"source,omitempty"`                               // Calendar provider the event was pulled from; empty for local events
+	Pinned           bool            `json:"pinned" gorm:"default:false"`                    // Replanning never moves pinned events
+	StudyPlanID      *uuid.UUID      `json:"study_plan_id,omitempty" gorm:"type:uuid;index"` // Study plan that placed the block; planned again with it
+	// Exceptions to a recurring event are stored as override rows pointing at the series
+	RecurringEventID *uuid.UUID `json:"recurring_event_id,omitempty" gorm:"type:uuid"`
+	RecurrenceID     *time.Time `json:"recurrence_id,omitempty"` // original start of the overridden occurrence
+	Cancelled        bool       `json:"cancelled,omitempty" gorm:"default:false"`
+	CreatedAt        time.Time  `json:"-"`
+	UpdatedAt        time.Time  `json:"-"`
+}
+
+// IsRecurring reports whether the row is the master of a recurring series. The
+// recurrence rule must be preloaded.
+func (st *ScheduledTask) IsRecurring() bool {
+	return st.RecurringEventID == nil && st.RecurrenceRule != nil && st.RecurrenceRule.RRule() != ""
+}
+
+// OccurrenceStarts returns the start times of the series' occurrences that overlap
+// [from, to). The rule is evaluated in loc, so a weekly 9:00 event stays at 9:00 local
+// time across DST changes.
+func (st *ScheduledTask) OccurrenceStarts(from, to time.Time, loc *time.Location) ([]time.Time, error) {
+	rule, err := ical.ParseRRule(st.RecurrenceRule.RRule(), loc)
+	if err != nil {
+		return nil, err
+	}
+	duration := st.End.Sub(st.Start)
+	return rule.Between(st.Start.In(loc), from.Add(-duration), to), nil
+}
+
+// Occurrence returns the instance of the series starting at start
+func (st *ScheduledTask) Occurrence(start time.Time) ScheduledTask {
+	occurrence := *st
+	occurrence.Start = start
+	occurrence.End = start.Add(st.End.Sub(st.Start))
+	occurrence.RecurringEventID = &st.ID
+	recurrenceID := start
+	occurrence.RecurrenceID = &recurrenceID
+	return occurrence
+}
+
+// ExpandSchedule turns stored rows into the events visible in [from, to): recurring
+// series are expanded into occurrences, override rows replace the occurrence they
+// point at and cancelled occurrences are dropped. Recurrence rules must be preloaded.
+func ExpandSchedule(rows []ScheduledTask, from, to time.Time, loc *time.Location) []ScheduledTask {
+	overridden := make(map[uuid.UUID]map[int64]bool)
+	for _, row := range rows {
+		if row.RecurringEventID == nil || row.RecurrenceID == nil {
+			continue
+		}
+		if overridden[*row.RecurringEventID] == nil {
+			overridden[*row.RecurringEventID] = make(map[int64]bool)
+		}
+		overridden[*row.RecurringEventID][row.RecurrenceID.Unix()] = true
+	}
+
+	var events []ScheduledTask
+	for i := range rows {
+		row := &rows[i]
+		switch {
+		case row.RecurringEventID != nil:
+			if !row.Cancelled && row.Start.Before(to) && row.End.After(from) {
+				events = append(events, *row)
+			}
+		case row.IsRecurring():
+			starts, err := row.OccurrenceStarts(from, to, loc)
+			if err != nil {
+				// An unreadable rule still shows the first occurrence
+				if row.Start.Before(to) && row.End.After(from) {
+					events = append(events, *row)
+				}
+				continue
+			}
+			for _, start := range starts {
+				if !overridden[row.ID][start.Unix()] {
+					events = append(events, row.Occurrence(start))
+				}
+			}
+		default:
+			if row.Start.Before(to) && row.End.After(from) {
+				events = append(events, *row)
+			}
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events
 }
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Study plan statuses. A plan is completed once the study logged against it reaches its effort.
+const (
+	StudyPlanActive    = "active"
+	StudyPlanCompleted = "completed"
+)
+
+// StudyPlan spreads the study of a topic or learning path up to a deadline as blocks
+// in the user's study calendar zones, planned again as study sessions are logged
+type StudyPlan struct {
+	ID               uuid.UUID  `json:"study_plan_id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID           uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	TopicID          *uuid.UUID `json:"topic_id,omitempty" gorm:"type:uuid;index"`
+	LearningPathID   *uuid.UUID `json:"learning_path_id,omitempty" gorm:"type:uuid;index"`
+	Title            string     `json:"title" gorm:"not null"`
+	Deadline         time.Time  `json:"deadline" gorm:"not null"`
+	EffortMinutes    int        `json:"effort_minutes" gorm:"not null"`            // Estimated study needed in all
+	MinutesPerDay    int        `json:"minutes_per_day" gorm:"default:0"`          // Most study planned on a day, 0 for no limit
+	Decks            string     `json:"-" gorm:"type:jsonb;not null;default:'[]'"` // JSON array of the IDs of decks reviewed alongside
+	Status           string     `json:"status" gorm:"not null;default:active"`
+	StudiedMinutes   int        `json:"studied_minutes"`   // Study logged against the plan when it was last planned
+	UnplannedMinutes int        `json:"unplanned_minutes"` // Study that did not fit before the deadline when last planned
+	PlannedAt        *time.Time `json:"planned_at"`
+	User             User       `json:"-" gorm:"foreignKey:UserID"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"-"`
+}
+
+// DeckIDs decodes the decks reviewed alongside the study
+func (p *StudyPlan) DeckIDs() []uuid.UUID {
+	ids := []uuid.UUID{}
+	decodeJSONColumn(p.Decks, &ids)
+	return ids
+}
+
+// SetDeckIDs encodes the decks reviewed alongside the study into the stored column
+func (p *StudyPlan) SetDeckIDs(ids []uuid.UUID) error {
+	value, err := encodeJSONColumn(ids, "[]")
+	if err != nil {
+		return err
+	}
+	p.Decks = value
+	return nil
+}
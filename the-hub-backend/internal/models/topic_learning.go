@@ -1,8 +1,10 @@
 package models
 
 import (
+	"math"
 	"time"
 
+	"github.com/TheoMKgosi/The-hub/internal/learningpath"
 	"github.com/google/uuid"
 )
 
@@ -47,15 +49,69 @@ type Resource struct {
 	Notes string
 }
 
+// Study session statuses. Sessions logged after the fact are completed from the start;
+// live ones are active or paused until stopped.
+const (
+	StudySessionActive    = "active"
+	StudySessionPaused    = "paused"
+	StudySessionCompleted = "completed"
+)
+
 type StudySession struct {
-	ID          uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	UserID      uuid.UUID `gorm:"type:uuid"`
-	User        User
-	TopicID     *uuid.UUID `gorm:"type:uuid"`
-	TaskID      *uuid.UUID `gorm:"type:uuid"`
-	DurationMin int
-	StartedAt   time.Time
-	EndedAt     time.Time
+	ID            uuid.UUID  `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID        uuid.UUID  `json:"user_id" gorm:"type:uuid"`
+	User          User       `json:"-"`
+	TopicID       *uuid.UUID `json:"topic_id" gorm:"type:uuid"`
+	TaskID        *uuid.UUID `json:"task_id" gorm:"type:uuid"`
+	DeckID        *uuid.UUID `json:"deck_id" gorm:"type:uuid"`
+	Status        string     `json:"status" gorm:"not null;default:completed"` // StudySessionActive, Paused or Completed
+	Notes         string     `json:"notes"`
+	DurationMin   int        `json:"duration_min"`
+	StartedAt     time.Time  `json:"started_at"`
+	PausedAt      *time.Time `json:"paused_at"`      // When the current pause began
+	PausedSeconds int        `json:"paused_seconds"` // Time spent in earlier pauses
+	EndedAt       *time.Time `json:"ended_at"`
+}
+
+// Live reports whether the session has been started and not yet stopped
+func (s *StudySession) Live() bool {
+	return s.Status == StudySessionActive || s.Status == StudySessionPaused
+}
+
+// ActiveDuration is how long the session has been studied for by now, pauses left out
+func (s *StudySession) ActiveDuration(now time.Time) time.Duration {
+	end := now
+	switch {
+	case s.EndedAt != nil:
+		end = *s.EndedAt
+	case s.PausedAt != nil:
+		end = *s.PausedAt
+	}
+	active := end.Sub(s.StartedAt) - time.Duration(s.PausedSeconds)*time.Second
+	return max(0, active)
+}
+
+// Pause starts a pause of an active session
+func (s *StudySession) Pause(now time.Time) {
+	s.Status = StudySessionPaused
+	s.PausedAt = &now
+}
+
+// Resume ends the current pause, adding it to the paused time
+func (s *StudySession) Resume(now time.Time) {
+	if s.PausedAt != nil {
+		s.PausedSeconds += int(now.Sub(*s.PausedAt).Seconds())
+		s.PausedAt = nil
+	}
+	s.Status = StudySessionActive
+}
+
+// Stop ends the session, ending any pause, and records its active minutes
+func (s *StudySession) Stop(now time.Time) {
+	s.Resume(now)
+	s.Status = StudySessionCompleted
+	s.EndedAt = &now
+	s.DurationMin = int(math.Round(s.ActiveDuration(now).Minutes()))
 }
 
 type Tag struct {
@@ -65,23 +121,48 @@ type Tag struct {
 	Color  string    `json:"color"`
 }
 
+// LearningPath is an ordered run of a user's topics, some of which wait on others
 type LearningPath struct {
-	ID          uuid.UUID `json:"learning_path_id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	UserID      uuid.UUID `json:"user_id" gorm:"type:uuid;not null"`
-	User        User      `json:"-"`
-	Title       string    `json:"title" gorm:"not null"`
-	Description string    `json:"description"`
-	Topics      []Topic   `json:"topics" gorm:"many2many:learning_path_topics"`
-	CreatedAt   time.Time `json:"-"`
-	UpdatedAt   time.Time `json:"-"`
+	ID          uuid.UUID           `json:"learning_path_id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID      uuid.UUID           `json:"user_id" gorm:"type:uuid;not null;index"`
+	User        User                `json:"-"`
+	Title       string              `json:"title" gorm:"not null"`
+	Description string              `json:"description"`
+	Topics      []LearningPathTopic `json:"-" gorm:"foreignKey:LearningPathID"`
+	CreatedAt   time.Time           `json:"-"`
+	UpdatedAt   time.Time           `json:"-"`
 }
 
+// LearningPathTopic places a topic in a learning path. A topic stays locked until
+// every prerequisite, each another topic in the same path, is done.
 type LearningPathTopic struct {
 	ID             uuid.UUID    `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	LearningPathID uuid.UUID    `gorm:"type:uuid;not null"`
-	TopicID        uuid.UUID    `gorm:"type:uuid;not null"`
+	LearningPathID uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_learning_path_topics_path_topic"`
+	TopicID        uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_learning_path_topics_path_topic;index"`
 	OrderIndex     int          `gorm:"not null"`
-	PrerequisiteID *uuid.UUID   `gorm:"type:uuid"` // ID of prerequisite topic in this path
+	Prerequisites  string       `gorm:"type:jsonb;not null;default:'[]'"` // JSON array of the IDs of prerequisite topics in this path
 	LearningPath   LearningPath `gorm:"foreignKey:LearningPathID"`
 	Topic          Topic        `gorm:"foreignKey:TopicID"`
 }
+
+// PrerequisiteIDs decodes the topics this one waits on
+func (t *LearningPathTopic) PrerequisiteIDs() []uuid.UUID {
+	ids := []uuid.UUID{}
+	decodeJSONColumn(t.Prerequisites, &ids)
+	return ids
+}
+
+// SetPrerequisiteIDs encodes the topics this one waits on into the stored column
+func (t *LearningPathTopic) SetPrerequisiteIDs(ids []uuid.UUID) error {
+	value, err := encodeJSONColumn(ids, "[]")
+	if err != nil {
+		return err
+	}
+	t.Prerequisites = value
+	return nil
+}
+
+// PathTopic returns the topic as prerequisite checks need it
+func (t *LearningPathTopic) PathTopic() learningpath.Topic {
+	return learningpath.Topic{ID: t.TopicID, Prerequisites: t.PrerequisiteIDs()}
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WeekdaySet is a set of days of the week. An empty set means every day. It is stored
+// and sent as a JSON array of lowercase day names, Monday first, and also reads the
+// comma-separated lists older clients send.
+type WeekdaySet uint8
+
+// weekdayOrder lists the days in the order sets are written
+var weekdayOrder = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday,
+}
+
+// NewWeekdaySet returns the set of the given days
+func NewWeekdaySet(days ...time.Weekday) WeekdaySet {
+	var set WeekdaySet
+	for _, day := range days {
+		set |= 1 << uint(day)
+	}
+	return set
+}
+
+// ParseWeekday reads a day name such as "monday" or "Mon"
+func ParseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) >= 3 {
+		for _, day := range weekdayOrder {
+			if strings.HasPrefix(strings.ToLower(day.String()), name) {
+				return day, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unknown day of the week %q", name)
+}
+
+// ParseWeekdaySet reads a JSON array or comma-separated list of day names
+func ParseWeekdaySet(value string) (WeekdaySet, error) {
+	value = strings.TrimSpace(value)
+	var names []string
+	if strings.HasPrefix(value, "[") {
+		if err := json.Unmarshal([]byte(value), &names); err != nil {
+			return 0, fmt.Errorf("invalid days of the week: %w", err)
+		}
+	} else if value != "" {
+		names = strings.Split(value, ",")
+	}
+
+	var set WeekdaySet
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		day, err := ParseWeekday(name)
+		if err != nil {
+			return 0, err
+		}
+		set |= NewWeekdaySet(day)
+	}
+	return set, nil
+}
+
+// Includes reports whether day is in the set. Every day is in the empty set.
+func (s WeekdaySet) Includes(day time.Weekday) bool {
+	return s == 0 || s&NewWeekdaySet(day) != 0
+}
+
+// IsEmpty reports whether no days were chosen, which means every day
+func (s WeekdaySet) IsEmpty() bool {
+	return s == 0
+}
+
+// Names returns the lowercase names of the days in the set, Monday first
+func (s WeekdaySet) Names() []string {
+	names := []string{}
+	for _, day := range weekdayOrder {
+		if s&NewWeekdaySet(day) != 0 {
+			names = append(names, strings.ToLower(day.String()))
+		}
+	}
+	return names
+}
+
+// MarshalJSON writes the set as an array of day names
+func (s WeekdaySet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Names())
+}
+
+// UnmarshalJSON reads an array of day names, or a string holding a JSON array or a
+// comma-separated list
+func (s *WeekdaySet) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*s = 0
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		set, err := ParseWeekdaySet(text)
+		if err != nil {
+			return err
+		}
+		*s = set
+		return nil
+	}
+	set, err := ParseWeekdaySet(string(data))
+	if err != nil {
+		return err
+	}
+	*s = set
+	return nil
+}
+
+// Value stores the set as a JSON array of day names, or an empty string for every day
+func (s WeekdaySet) Value() (driver.Value, error) {
+	if s == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(s.Names())
+	return string(data), err
+}
+
+// Scan reads a stored set. Rows written before sets were typed may hold free text, so
+// any day name found in it counts.
+func (s *WeekdaySet) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		*s = 0
+		return nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("cannot read days of the week from %T", value)
+	}
+
+	if set, err := ParseWeekdaySet(text); err == nil {
+		*s = set
+		return nil
+	}
+	lower := strings.ToLower(text)
+	*s = 0
+	for _, day := range weekdayOrder {
+		if strings.Contains(lower, strings.ToLower(day.String())) {
+			*s |= NewWeekdaySet(day)
+		}
+	}
+	return nil
+}
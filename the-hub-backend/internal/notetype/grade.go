@@ -0,0 +1,103 @@
+package notetype
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// typoSimilarity is how close a typed answer must be to count as right with a typo
+	typoSimilarity = 0.85
+	// nearSimilarity is how close a wrong answer must be to count as nearly remembered
+	nearSimilarity = 0.6
+)
+
+// TypedGrade is how a typed answer compares with the expected one
+type TypedGrade struct {
+	Correct    bool    `json:"correct"`
+	Exact      bool    `json:"exact"`      // equal once case, accents, punctuation and spacing are ignored
+	Similarity float64 `json:"similarity"` // 0-1, from the edit distance
+	Quality    int     `json:"quality"`    // SM-2 quality (0-5) to review the card with
+	Expected   string  `json:"expected"`
+}
+
+// GradeTypedAnswer compares a typed answer with the expected one. Case, accents,
+// punctuation and spacing are ignored; small typos still count as correct but get a
+// lower quality. An expected answer may list alternatives separated by "|" or ";".
+func GradeTypedAnswer(expected, given string) TypedGrade {
+	grade := TypedGrade{Expected: expected}
+	typed := normaliseAnswer(given)
+	if typed == "" {
+		return grade
+	}
+
+	for _, alternative := range strings.FieldsFunc(expected, func(r rune) bool { return r == '|' || r == ';' }) {
+		want := normaliseAnswer(alternative)
+		if want == "" {
+			continue
+		}
+		similarity := 1 - float64(editDistance(want, typed))/float64(max(len([]rune(want)), len([]rune(typed))))
+		if similarity > grade.Similarity {
+			grade.Similarity = similarity
+		}
+	}
+	grade.Similarity = math.Round(grade.Similarity*1000) / 1000
+
+	switch {
+	case grade.Similarity == 1:
+		grade.Correct, grade.Exact, grade.Quality = true, true, 4
+	case grade.Similarity >= typoSimilarity:
+		grade.Correct, grade.Quality = true, 3
+	case grade.Similarity >= nearSimilarity:
+		grade.Quality = 2
+	default:
+		grade.Quality = 1
+	}
+	return grade
+}
+
+// normaliseAnswer lowercases s, strips accents and punctuation and collapses spaces
+func normaliseAnswer(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// accent left over from decomposing the letter before it
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// editDistance is the Levenshtein distance between a and b in runes
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
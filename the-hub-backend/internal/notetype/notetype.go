@@ -0,0 +1,142 @@
+// Package notetype turns flashcard notes into the cards they generate. A note holds
+// the content once; its note type decides which cards are made from it, so that
+// editing the note can update every card.
+package notetype
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Note types
+const (
+	Basic         = "basic"          // one card, front to back
+	BasicReversed = "basic_reversed" // two cards, front to back and back to front
+	Cloze         = "cloze"          // one card per {{cN::...}} deletion in the front
+	TypeIn        = "type_in"        // one card answered by typing the back
+)
+
+// Card types, which decide how a generated card is answered
+const (
+	CardBasic  = "basic"
+	CardCloze  = "cloze"
+	CardTypeIn = "type_in"
+)
+
+// Templates of the non-cloze cards. Cloze cards are named c1, c2, ...
+const (
+	TemplateForward = "forward"
+	TemplateReverse = "reverse"
+)
+
+// clozeHidden replaces a deletion on the card that asks for it
+const clozeHidden = "[...]"
+
+// Valid reports whether model is a known note type
+func Valid(model string) bool {
+	switch model {
+	case Basic, BasicReversed, Cloze, TypeIn:
+		return true
+	}
+	return false
+}
+
+// Card is one card generated from a note
+type Card struct {
+	Template string // identifies the card within its note, e.g. "forward" or "c2"
+	Type     string // CardBasic, CardCloze or CardTypeIn
+	Question string
+	Answer   string
+}
+
+// Generate returns the cards a note of the given type makes. Extra is shown below the
+// answer of every card.
+func Generate(model, front, back, extra string) ([]Card, error) {
+	front, back, extra = strings.TrimSpace(front), strings.TrimSpace(back), strings.TrimSpace(extra)
+	if front == "" {
+		return nil, fmt.Errorf("front is required")
+	}
+	if model != Cloze && back == "" {
+		return nil, fmt.Errorf("back is required")
+	}
+
+	switch model {
+	case Basic:
+		return []Card{{Template: TemplateForward, Type: CardBasic, Question: front, Answer: withExtra(back, extra)}}, nil
+	case BasicReversed:
+		return []Card{
+			{Template: TemplateForward, Type: CardBasic, Question: front, Answer: withExtra(back, extra)},
+			{Template: TemplateReverse, Type: CardBasic, Question: back, Answer: withExtra(front, extra)},
+		}, nil
+	case TypeIn:
+		return []Card{{Template: TemplateForward, Type: CardTypeIn, Question: front, Answer: back}}, nil
+	case Cloze:
+		return generateCloze(front, withExtra(back, extra))
+	default:
+		return nil, fmt.Errorf("unknown note type %q", model)
+	}
+}
+
+func withExtra(answer, extra string) string {
+	if extra == "" {
+		return answer
+	}
+	if answer == "" {
+		return extra
+	}
+	return answer + "\n\n" + extra
+}
+
+// clozePattern matches {{c1::answer}} and {{c1::answer::hint}}
+var clozePattern = regexp.MustCompile(`\{\{c(\d+)::(.*?)(?:::(.*?))?\}\}`)
+
+// ClozeNumbers returns the deletion numbers used in text, in order
+func ClozeNumbers(text string) []int {
+	seen := make(map[int]bool)
+	var numbers []int
+	for _, match := range clozePattern.FindAllStringSubmatch(text, -1) {
+		n, err := strconv.Atoi(match[1])
+		if err != nil || n < 1 || seen[n] {
+			continue
+		}
+		seen[n] = true
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+func generateCloze(text, back string) ([]Card, error) {
+	numbers := ClozeNumbers(text)
+	if len(numbers) == 0 {
+		return nil, fmt.Errorf("cloze text needs at least one deletion like {{c1::answer}}")
+	}
+	cards := make([]Card, 0, len(numbers))
+	for _, n := range numbers {
+		cards = append(cards, Card{
+			Template: "c" + strconv.Itoa(n),
+			Type:     CardCloze,
+			Question: renderCloze(text, n, true),
+			Answer:   withExtra(renderCloze(text, n, false), back),
+		})
+	}
+	return cards, nil
+}
+
+// renderCloze shows every deletion's text, except deletion n which is hidden behind
+// its hint, or [...] without one, when hide is set
+func renderCloze(text string, n int, hide bool) string {
+	return clozePattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := clozePattern.FindStringSubmatch(match)
+		if number, _ := strconv.Atoi(parts[1]); hide && number == n {
+			if parts[3] != "" {
+				return "[" + parts[3] + "]"
+			}
+			return clozeHidden
+		}
+		return parts[2]
+	})
+}
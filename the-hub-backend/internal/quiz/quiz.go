@@ -0,0 +1,247 @@
+// Package quiz validates AI-generated quiz questions against the schema the model is
+// asked to follow, and grades answers to them. Multiple choice and true/false answers
+// must match; short answers are graded like typed flashcard answers, so small typos
+// still count.
+package quiz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/notetype"
+	"github.com/google/uuid"
+)
+
+// Question types
+const (
+	TypeMultipleChoice = "multiple_choice"
+	TypeTrueFalse      = "true_false"
+	TypeShortAnswer    = "short_answer"
+)
+
+// Types lists every question type
+var Types = []string{TypeMultipleChoice, TypeTrueFalse, TypeShortAnswer}
+
+const (
+	minOptions = 3
+	maxOptions = 6
+)
+
+// Schema is the JSON schema generated quizzes must follow. Parse enforces it.
+const Schema = `{
+  "type": "object",
+  "required": ["questions"],
+  "additionalProperties": false,
+  "properties": {
+    "questions": {
+      "type": "array",
+      "minItems": 1,
+      "items": {
+        "type": "object",
+        "required": ["type", "question", "answer", "source"],
+        "additionalProperties": false,
+        "properties": {
+          "type": {"enum": ["multiple_choice", "true_false", "short_answer"]},
+          "question": {"type": "string", "minLength": 1},
+          "options": {"type": "array", "items": {"type": "string", "minLength": 1}, "minItems": 3, "maxItems": 6, "uniqueItems": true},
+          "answer": {"type": "string", "minLength": 1},
+          "explanation": {"type": "string"},
+          "source": {"type": "integer", "minimum": 1}
+        }
+      }
+    }
+  }
+}`
+
+// Question is one quiz question. Options are only set for multiple choice, where the
+// answer is one of them; true/false answers are "true" or "false".
+type Question struct {
+	Type        string     `json:"type"`
+	Question    string     `json:"question"`
+	Options     []string   `json:"options,omitempty"`
+	Answer      string     `json:"answer"`
+	Explanation string     `json:"explanation,omitempty"`
+	Source      int        `json:"source"`              // 1-based number of the source material it tests
+	SourceID    *uuid.UUID `json:"source_id,omitempty"` // Card or task learning that source was
+}
+
+// ValidationError is a generated quiz that does not follow the schema
+type ValidationError struct {
+	Path    string
+	Problem string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Path + ": " + e.Problem
+}
+
+// Parse reads a generated quiz from a model's response, which may wrap the JSON in
+// other text, and validates it against Schema. Each question must test one of the
+// sources numbered 1 to sources and be of one of the allowed types. Answers are
+// tidied: multiple choice answers take their option's spelling, true/false answers are
+// lower case.
+func Parse(response string, sources int, allowed []string) ([]Question, error) {
+	start, end := strings.Index(response, "{"), strings.LastIndex(response, "}")
+	if start == -1 || end <= start {
+		return nil, &ValidationError{Path: "$", Problem: "no JSON object found"}
+	}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(response[start : end+1])))
+	decoder.DisallowUnknownFields()
+	var quiz struct {
+		Questions []Question `json:"questions"`
+	}
+	if err := decoder.Decode(&quiz); err != nil {
+		return nil, &ValidationError{Path: "$", Problem: err.Error()}
+	}
+	if len(quiz.Questions) == 0 {
+		return nil, &ValidationError{Path: "questions", Problem: "must have at least 1 item"}
+	}
+
+	types := map[string]bool{}
+	for _, t := range allowed {
+		types[t] = true
+	}
+	for i := range quiz.Questions {
+		if err := validate(&quiz.Questions[i], sources, types); err != nil {
+			err.Path = fmt.Sprintf("questions[%d].%s", i, err.Path)
+			return nil, err
+		}
+	}
+	return quiz.Questions, nil
+}
+
+func validate(q *Question, sources int, types map[string]bool) *ValidationError {
+	q.Question, q.Answer = strings.TrimSpace(q.Question), strings.TrimSpace(q.Answer)
+	q.Explanation = strings.TrimSpace(q.Explanation)
+	switch {
+	case !types[q.Type]:
+		return &ValidationError{Path: "type", Problem: fmt.Sprintf("must be one of %s", strings.Join(keys(types), ", "))}
+	case q.Question == "":
+		return &ValidationError{Path: "question", Problem: "must not be empty"}
+	case q.Answer == "":
+		return &ValidationError{Path: "answer", Problem: "must not be empty"}
+	case q.Source < 1 || q.Source > sources:
+		return &ValidationError{Path: "source", Problem: fmt.Sprintf("must be between 1 and %d", sources)}
+	}
+	q.SourceID = nil
+
+	if q.Type != TypeMultipleChoice {
+		if len(q.Options) > 0 {
+			return &ValidationError{Path: "options", Problem: "only multiple_choice questions have options"}
+		}
+		if q.Type == TypeTrueFalse {
+			value, ok := parseBool(q.Answer)
+			if !ok {
+				return &ValidationError{Path: "answer", Problem: `must be "true" or "false"`}
+			}
+			q.Answer = fmt.Sprint(value)
+		}
+		return nil
+	}
+
+	if len(q.Options) < minOptions || len(q.Options) > maxOptions {
+		return &ValidationError{Path: "options", Problem: fmt.Sprintf("must have %d to %d items", minOptions, maxOptions)}
+	}
+	seen := map[string]bool{}
+	answer := -1
+	for i := range q.Options {
+		q.Options[i] = strings.TrimSpace(q.Options[i])
+		key := strings.ToLower(q.Options[i])
+		if key == "" || seen[key] {
+			return &ValidationError{Path: fmt.Sprintf("options[%d]", i), Problem: "must be non-empty and unique"}
+		}
+		seen[key] = true
+		if strings.EqualFold(q.Options[i], q.Answer) {
+			answer = i
+		}
+	}
+	if answer < 0 {
+		return &ValidationError{Path: "answer", Problem: "must be one of the options"}
+	}
+	q.Answer = q.Options[answer]
+	return nil
+}
+
+func keys(set map[string]bool) []string {
+	var out []string
+	for _, t := range Types {
+		if set[t] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// parseBool reads a true/false answer
+func parseBool(s string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "t", "yes", "y":
+		return true, true
+	case "false", "f", "no", "n":
+		return false, true
+	}
+	return false, false
+}
+
+// Result is how one answer was graded
+type Result struct {
+	Given       string  `json:"given"`
+	Correct     bool    `json:"correct"`
+	Expected    string  `json:"expected"`
+	Explanation string  `json:"explanation,omitempty"`
+	Similarity  float64 `json:"similarity,omitempty"` // short answers only, 0-1
+}
+
+// Grade grades an answer to q. A multiple choice answer may be the option itself or
+// its letter (A for the first option).
+func Grade(q Question, given string) Result {
+	result := Result{Given: given, Expected: q.Answer, Explanation: q.Explanation}
+	given = strings.TrimSpace(given)
+	switch q.Type {
+	case TypeMultipleChoice:
+		if len(given) == 1 {
+			if i := int(strings.ToUpper(given)[0] - 'A'); i >= 0 && i < len(q.Options) {
+				given = q.Options[i]
+			}
+		}
+		result.Correct = strings.EqualFold(given, q.Answer)
+	case TypeTrueFalse:
+		value, ok := parseBool(given)
+		result.Correct = ok && fmt.Sprint(value) == q.Answer
+	default:
+		typed := notetype.GradeTypedAnswer(q.Answer, given)
+		result.Correct, result.Similarity = typed.Correct, typed.Similarity
+	}
+	return result
+}
+
+// Score grades each answer, an unanswered question counting as wrong, and returns how
+// many were right
+func Score(questions []Question, answers []string) ([]Result, int) {
+	results := make([]Result, len(questions))
+	correct := 0
+	for i, q := range questions {
+		given := ""
+		if i < len(answers) {
+			given = answers[i]
+		}
+		results[i] = Grade(q, given)
+		if results[i].Correct {
+			correct++
+		}
+	}
+	return results, correct
+}
+
+// Shorten brings a card tested by a wrongly answered question back sooner: its
+// interval is halved, and it is due no later than that many days from now
+func Shorten(interval int, nextReview, now time.Time) (int, time.Time) {
+	interval = max(interval/2, 1)
+	if due := now.AddDate(0, 0, interval); due.Before(nextReview) {
+		nextReview = due
+	}
+	return interval, nextReview
+}
@@ -23,6 +23,23 @@ func RegisterRoutes(router *gin.Engine) {
 	router.POST("/auth/refresh", handlers.RefreshToken)
 	router.POST("/auth/logout", handlers.Logout)
 
+	// Calendar feed subscription (authenticated by the secret token in the URL)
+	router.GET("/calendar/feed/:token", handlers.ServeCalendarFeed)
+
+	// Public booking pages (authenticated by the secret token in the URL)
+	router.GET("/book/:token", handlers.GetPublicBookingLink)
+	router.GET("/book/:token/slots", handlers.GetBookingSlots)
+	router.POST("/book/:token", handlers.CreateBooking)
+
+	// CalDAV (authenticated with app passwords over HTTP Basic)
+	router.Handle("GET", "/.well-known/caldav", handlers.CalDAVWellKnown)
+	router.Handle("PROPFIND", "/.well-known/caldav", handlers.CalDAVWellKnown)
+	dav := router.Group("/caldav")
+	dav.Use(util.AppPasswordAuthMiddleware(handlers.CalDAVRealm))
+	for _, method := range handlers.CalDAVMethods {
+		dav.Handle(method, "/*path", handlers.CalDAV)
+	}
+
 	protected := router.Group("/")
 	protected.Use(util.JWTAuthMiddleware())
 
@@ -80,6 +97,13 @@ func RegisterRoutes(router *gin.Engine) {
 	protected.POST("/schedule/bulk", handlers.BulkCreateSchedule)
 	protected.DELETE("/schedule/bulk", handlers.BulkDeleteSchedule)
 	protected.GET("/schedule/suggestions", handlers.GetScheduleSuggestions)
+	protected.POST("/schedule/suggestions/respond", handlers.RespondToSuggestions)
+	protected.GET("/schedule/suggestions/report", handlers.GetSuggestionReport)
+	protected.POST("/schedule/import", handlers.ImportSchedule)
+	protected.GET("/schedule/replan", handlers.GetReplanProposal)
+	protected.POST("/schedule/replan", handlers.ReplanSchedule)
+	protected.POST("/schedule/replan/:proposalID/accept", handlers.AcceptReplan)
+	protected.POST("/schedule/replan/:proposalID/reject", handlers.RejectReplan)
 
 	// -- Recurrence rule routes
 	protected.POST("/recurrence-rules", handlers.CreateRecurrenceRule)
@@ -92,14 +116,43 @@ func RegisterRoutes(router *gin.Engine) {
 	protected.PUT("/calendar-zones/:zoneID", handlers.UpdateCalendarZone)
 	protected.DELETE("/calendar-zones/:zoneID", handlers.DeleteCalendarZone)
 	protected.GET("/calendar-zones/categories", handlers.GetZoneCategories)
+	protected.GET("/calendar-zones/blackouts", handlers.GetZoneBlackouts)
+	protected.POST("/calendar-zones/blackouts", handlers.CreateZoneBlackout)
+	protected.PUT("/calendar-zones/blackouts/:blackoutID", handlers.UpdateZoneBlackout)
+	protected.DELETE("/calendar-zones/blackouts/:blackoutID", handlers.DeleteZoneBlackout)
+
+	// -- Energy profile routes
+	protected.GET("/energy-profile", handlers.GetEnergyProfile)
+	protected.PUT("/energy-profile", handlers.UpdateEnergyProfile)
+	protected.DELETE("/energy-profile", handlers.ResetEnergyProfile)
+	protected.POST("/energy-profile/learn", handlers.LearnEnergyProfile)
 
 	// Calendar integration routes
-	protected.POST("/calendar/google/auth", handlers.InitiateGoogleCalendarAuth)
-	protected.GET("/calendar/google/callback", handlers.HandleGoogleCalendarCallback)
+	protected.POST("/calendar/:provider/auth", handlers.InitiateCalendarAuth)
+	protected.GET("/calendar/:provider/callback", handlers.HandleCalendarCallback)
 	protected.GET("/calendar/integrations", handlers.GetCalendarIntegrations)
 	protected.POST("/calendar/integrations/:integrationID/sync", handlers.SyncCalendarEvents)
+	protected.GET("/calendar/integrations/:integrationID/audit", handlers.GetCalendarSyncAudit)
 	protected.DELETE("/calendar/integrations/:integrationID", handlers.DeleteCalendarIntegration)
 
+	// -- Calendar feed routes
+	protected.GET("/calendar/feed", handlers.GetCalendarFeed)
+	protected.POST("/calendar/feed/rotate", handlers.RotateCalendarFeedToken)
+	protected.PATCH("/calendar/feed", handlers.UpdateCalendarFeed)
+	protected.DELETE("/calendar/feed", handlers.RevokeCalendarFeed)
+
+	// -- Booking link routes
+	protected.GET("/booking-links", handlers.GetBookingLinks)
+	protected.POST("/booking-links", handlers.CreateBookingLink)
+	protected.PUT("/booking-links/:linkID", handlers.UpdateBookingLink)
+	protected.DELETE("/booking-links/:linkID", handlers.DeleteBookingLink)
+	protected.GET("/bookings", handlers.GetBookings)
+
+	// -- App password routes
+	protected.GET("/app-passwords", handlers.GetAppPasswords)
+	protected.POST("/app-passwords", handlers.CreateAppPassword)
+	protected.DELETE("/app-passwords/:ID", handlers.DeleteAppPassword)
+
 	// Learning routes
 	// -- Deck routes
 	protected.GET("/decks", handlers.GetDecks)
@@ -107,6 +160,27 @@ func RegisterRoutes(router *gin.Engine) {
 	protected.POST("/decks", handlers.CreateDeck)
 	protected.PATCH("/decks/:ID", handlers.UpdateDeck)
 	protected.DELETE("/decks/:ID", handlers.DeleteDeck)
+	protected.GET("/decks/analytics/:deckID", handlers.GetDeckAnalytics)
+
+	// -- Public deck library routes
+	protected.GET("/decks/library", handlers.GetPublicDecks)
+	protected.GET("/decks/library/:deckID", handlers.GetPublicDeck)
+	protected.POST("/decks/library/:deckID/clone", handlers.CloneDeck)
+	protected.POST("/decks/library/:deckID/subscribe", handlers.SubscribeDeck)
+	protected.DELETE("/decks/library/:deckID/subscribe", handlers.UnsubscribeDeck)
+	protected.PUT("/decks/library/:deckID/rating", handlers.RateDeck)
+	protected.DELETE("/decks/library/:deckID/rating", handlers.DeleteDeckRating)
+	protected.POST("/decks/library/:deckID/reports", handlers.ReportDeck)
+
+	// -- Deck collaboration routes
+	protected.GET("/decks/collaborators/:deckID", handlers.GetDeckCollaborators)
+	protected.PATCH("/decks/collaborators/:deckID/:userID", handlers.UpdateDeckCollaborator)
+	protected.DELETE("/decks/collaborators/:deckID/:userID", handlers.RemoveDeckCollaborator)
+	protected.POST("/decks/invites/:deckID", handlers.CreateDeckInvite)
+	protected.GET("/deck-invites", handlers.GetDeckInvites)
+	protected.POST("/deck-invites/:ID/accept", handlers.AcceptDeckInvite)
+	protected.POST("/deck-invites/:ID/decline", handlers.DeclineDeckInvite)
+	protected.DELETE("/deck-invites/:ID", handlers.RevokeDeckInvite)
 
 	// -- Card routes
 	protected.GET("/decks/cards/:deckID", handlers.GetCards)
@@ -117,13 +191,33 @@ func RegisterRoutes(router *gin.Engine) {
 
 	protected.POST("/cards/review/:ID", handlers.ReviewCard)
 	protected.GET("/cards/due/:deckID", handlers.GetDueCards)
+	protected.GET("/cards/study", handlers.CustomStudy)
+	protected.GET("/cards/tags", handlers.GetCardTags)
+	protected.POST("/cards/bury/:ID", handlers.BuryCard)
+	protected.DELETE("/cards/bury/:ID", handlers.UnburyCard)
+	protected.POST("/cards/suspend/:ID", handlers.SuspendCard)
+	protected.DELETE("/cards/suspend/:ID", handlers.UnsuspendCard)
+
+	// -- Card note routes
+	protected.GET("/card-notes", handlers.GetCardNotes)
+	protected.GET("/card-notes/:ID", handlers.GetCardNote)
+	protected.POST("/card-notes", handlers.CreateCardNote)
+	protected.PATCH("/card-notes/:ID", handlers.UpdateCardNote)
+	protected.DELETE("/card-notes/:ID", handlers.DeleteCardNote)
+
+	// -- Spaced repetition parameter routes
+	protected.GET("/srs/parameters", handlers.GetSRSParameters)
+	protected.POST("/srs/parameters/fit", handlers.FitSRSParameters)
+	protected.DELETE("/srs/parameters", handlers.ResetSRSParameters)
 
 	// -- AI Flashcard routes
 	protected.POST("/flashcards/from-pdf", handlers.GenerateFlashcardsFromPDF)
+	protected.POST("/flashcards/from-text", handlers.GenerateFlashcardsFromSource)
 
 	// -- Card import/export routes
 	protected.GET("/decks/export/:deckID/cards", handlers.ExportCards)
 	protected.POST("/decks/import/:deckID/cards", handlers.ImportCards)
+	protected.POST("/decks/import/apkg", handlers.ImportAnkiPackage)
 
 	// -- Topic routes
 	protected.GET("/topics", handlers.GetTopics)
@@ -139,10 +233,39 @@ func RegisterRoutes(router *gin.Engine) {
 	protected.PATCH("/task-learning/:ID", handlers.UpdateTaskLearning)
 	protected.DELETE("/task-learning/:ID", handlers.DeleteTaskLearning)
 
+	// -- Learning path routes
+	protected.GET("/learning-paths", handlers.GetLearningPaths)
+	protected.POST("/learning-paths", handlers.CreateLearningPath)
+	protected.GET("/learning-paths/:ID", handlers.GetLearningPath)
+	protected.PATCH("/learning-paths/:ID", handlers.UpdateLearningPath)
+	protected.DELETE("/learning-paths/:ID", handlers.DeleteLearningPath)
+
 	// -- Study Session routes
 	protected.GET("/study-sessions", handlers.GetStudySessions)
 	protected.POST("/study-sessions", handlers.CreateStudySession)
 	protected.GET("/study-sessions/stats", handlers.GetStudySessionStats)
+	protected.POST("/study-sessions/start", handlers.StartStudySession)
+	protected.GET("/study-sessions/active", handlers.GetActiveStudySession)
+	protected.GET("/study-sessions/:ID", handlers.GetStudySession)
+	protected.POST("/study-sessions/:ID/pause", handlers.PauseStudySession)
+	protected.POST("/study-sessions/:ID/resume", handlers.ResumeStudySession)
+	protected.POST("/study-sessions/:ID/stop", handlers.StopStudySession)
+
+	// -- Study plan routes
+	protected.GET("/study-plans", handlers.GetStudyPlans)
+	protected.POST("/study-plans", handlers.CreateStudyPlan)
+	protected.GET("/study-plans/:ID", handlers.GetStudyPlan)
+	protected.PATCH("/study-plans/:ID", handlers.UpdateStudyPlan)
+	protected.DELETE("/study-plans/:ID", handlers.DeleteStudyPlan)
+	protected.POST("/study-plans/:ID/replan", handlers.ReplanStudyPlan)
+
+	// -- Quiz routes
+	protected.GET("/quizzes", handlers.GetQuizzes)
+	protected.POST("/quizzes", handlers.CreateQuiz)
+	protected.GET("/quizzes/:ID", handlers.GetQuiz)
+	protected.DELETE("/quizzes/:ID", handlers.DeleteQuiz)
+	protected.GET("/quizzes/:ID/attempts", handlers.GetQuizAttempts)
+	protected.POST("/quizzes/:ID/attempts", handlers.SubmitQuizAttempt)
 
 	// -- Resource routes
 	protected.GET("/resources", handlers.GetResources)
@@ -220,4 +343,8 @@ func RegisterRoutes(router *gin.Engine) {
 	// Admin feedback management
 	admin.GET("/feedback", handlers.GetAllFeedback)
 	admin.PATCH("/feedback/:id", handlers.UpdateFeedbackStatus)
+
+	// Admin deck library moderation
+	admin.GET("/deck-reports", handlers.GetDeckReports)
+	admin.PATCH("/deck-reports/:id", handlers.UpdateDeckReport)
 }
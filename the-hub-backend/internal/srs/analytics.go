@@ -0,0 +1,286 @@
+package srs
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultLeechThreshold is how many lapses make a card a leech in new decks
+	DefaultLeechThreshold = 8
+	// MatureInterval is the interval in days from which a card counts as mature
+	MatureInterval = 21
+	// ForecastDays is how far ahead due cards are forecast
+	ForecastDays = 30
+	// HeatmapDays is how far back the review heatmap reaches
+	HeatmapDays = 365
+)
+
+// IsLeech reports whether a card that has just lapsed for the lapses-th time should be
+// suspended as a leech: at threshold lapses, then again every half threshold after
+// that, so a card unsuspended by hand gets a few more chances. A threshold of 0
+// disables leech detection.
+func IsLeech(lapses, threshold int) bool {
+	if threshold <= 0 || lapses < threshold {
+		return false
+	}
+	return (lapses-threshold)%max(1, threshold/2) == 0
+}
+
+// ReviewEvent is one logged review, as analytics need it
+type ReviewEvent struct {
+	CardID         uuid.UUID
+	Rating         Rating
+	FirstReview    bool
+	ElapsedDays    int
+	IntervalBefore int
+	TimeTakenMs    int
+	ReviewedAt     time.Time
+}
+
+// CardState is a card's current state, as analytics need it
+type CardState struct {
+	CardID     uuid.UUID
+	Question   string
+	Lapses     int
+	Difficulty float64
+	Easiness   float64
+	Suspended  bool
+	NextReview time.Time
+	Reviewed   bool
+}
+
+// RetentionStats is how often cards were recalled when they came up for review
+type RetentionStats struct {
+	Reviews   int     `json:"reviews"`
+	Recalled  int     `json:"recalled"`
+	Retention float64 `json:"retention"` // share recalled, 0 without reviews
+}
+
+func (r *RetentionStats) add(recalled bool) {
+	r.Reviews++
+	if recalled {
+		r.Recalled++
+	}
+}
+
+func (r *RetentionStats) finish() {
+	if r.Reviews > 0 {
+		r.Retention = math.Round(float64(r.Recalled)/float64(r.Reviews)*1000) / 1000
+	}
+}
+
+// ForecastDay is how many cards fall due on one day
+type ForecastDay struct {
+	Date string `json:"date"`
+	Due  int    `json:"due"`
+}
+
+// HeatmapDay is how many reviews were done on one day
+type HeatmapDay struct {
+	Date    string `json:"date"`
+	Reviews int    `json:"reviews"`
+}
+
+// HardCard is one of a deck's most often forgotten cards
+type HardCard struct {
+	CardID     uuid.UUID `json:"card_id"`
+	Question   string    `json:"question"`
+	Lapses     int       `json:"lapses"`
+	Reviews    int       `json:"reviews"`
+	AgainRate  float64   `json:"again_rate"` // share of reviews answered "again"
+	Difficulty float64   `json:"difficulty"`
+	Suspended  bool      `json:"suspended"`
+}
+
+// DeckAnalytics summarises how well a deck is being learned
+type DeckAnalytics struct {
+	Reviews            int            `json:"reviews"`
+	AverageTimeSeconds float64        `json:"average_time_seconds"` // over reviews that were timed
+	Retention          RetentionStats `json:"retention"`            // true retention: reviews of learned cards only
+	YoungRetention     RetentionStats `json:"young_retention"`      // cards with intervals under MatureInterval days
+	MatureRetention    RetentionStats `json:"mature_retention"`
+	Cards              int            `json:"cards"`
+	NewCards           int            `json:"new_cards"`
+	Suspended          int            `json:"suspended"`
+	Forecast           []ForecastDay  `json:"forecast"`
+	Heatmap            []HeatmapDay   `json:"heatmap"`
+	HardestCards       []HardCard     `json:"hardest_cards"`
+}
+
+// BuildDeckAnalytics summarises a deck's cards and the reviews logged for them. Retention
+// counts reviews since since; the heatmap and forecast use days in loc.
+func BuildDeckAnalytics(cards []CardState, events []ReviewEvent, since, now time.Time, loc *time.Location, hardest int) DeckAnalytics {
+	analytics := DeckAnalytics{
+		Cards:        len(cards),
+		Forecast:     Forecast(cards, now, loc, ForecastDays),
+		Heatmap:      Heatmap(events, now.AddDate(0, 0, -HeatmapDays), loc),
+		HardestCards: HardestCards(cards, events, hardest),
+	}
+
+	var timed, totalMs int
+	for _, event := range events {
+		if event.ReviewedAt.Before(since) {
+			continue
+		}
+		analytics.Reviews++
+		if event.TimeTakenMs > 0 {
+			timed++
+			totalMs += event.TimeTakenMs
+		}
+		if event.FirstReview || event.ElapsedDays < 1 {
+			continue
+		}
+		recalled := event.Rating > Again
+		analytics.Retention.add(recalled)
+		if event.IntervalBefore >= MatureInterval {
+			analytics.MatureRetention.add(recalled)
+		} else {
+			analytics.YoungRetention.add(recalled)
+		}
+	}
+	analytics.Retention.finish()
+	analytics.YoungRetention.finish()
+	analytics.MatureRetention.finish()
+	if timed > 0 {
+		analytics.AverageTimeSeconds = math.Round(float64(totalMs)/float64(timed)/100) / 10
+	}
+
+	for _, card := range cards {
+		if card.Suspended {
+			analytics.Suspended++
+		}
+		if !card.Reviewed {
+			analytics.NewCards++
+		}
+	}
+	return analytics
+}
+
+// Forecast counts the reviewed, unsuspended cards due on each of the next days in loc.
+// Overdue cards are counted today.
+func Forecast(cards []CardState, now time.Time, loc *time.Location, days int) []ForecastDay {
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	forecast := make([]ForecastDay, days)
+	for i := range forecast {
+		forecast[i].Date = today.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	for _, card := range cards {
+		if card.Suspended || !card.Reviewed {
+			continue
+		}
+		due := card.NextReview.In(loc)
+		day := 0
+		if due.After(today) {
+			day = int(time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, loc).Sub(today).Hours()+12) / 24
+		}
+		if day < days {
+			forecast[day].Due++
+		}
+	}
+	return forecast
+}
+
+// Heatmap counts reviews per day in loc from from on, leaving out days without any
+func Heatmap(events []ReviewEvent, from time.Time, loc *time.Location) []HeatmapDay {
+	counts := make(map[string]int)
+	for _, event := range events {
+		if !event.ReviewedAt.Before(from) {
+			counts[event.ReviewedAt.In(loc).Format("2006-01-02")]++
+		}
+	}
+	heatmap := make([]HeatmapDay, 0, len(counts))
+	for date, reviews := range counts {
+		heatmap = append(heatmap, HeatmapDay{Date: date, Reviews: reviews})
+	}
+	sort.Slice(heatmap, func(i, j int) bool { return heatmap[i].Date < heatmap[j].Date })
+	return heatmap
+}
+
+// HardestCards returns up to limit cards that have lapsed, most lapses first, then by
+// how often they were answered "again" and how difficult they are
+func HardestCards(cards []CardState, events []ReviewEvent, limit int) []HardCard {
+	reviews := make(map[uuid.UUID]int)
+	again := make(map[uuid.UUID]int)
+	for _, event := range events {
+		reviews[event.CardID]++
+		if event.Rating == Again {
+			again[event.CardID]++
+		}
+	}
+
+	hard := []HardCard{}
+	for _, card := range cards {
+		if card.Lapses == 0 {
+			continue
+		}
+		entry := HardCard{
+			CardID:     card.CardID,
+			Question:   card.Question,
+			Lapses:     card.Lapses,
+			Reviews:    reviews[card.CardID],
+			Difficulty: math.Round(card.Difficulty*100) / 100,
+			Suspended:  card.Suspended,
+		}
+		if entry.Reviews > 0 {
+			entry.AgainRate = math.Round(float64(again[card.CardID])/float64(entry.Reviews)*1000) / 1000
+		}
+		hard = append(hard, entry)
+	}
+	sort.SliceStable(hard, func(i, j int) bool {
+		if hard[i].Lapses != hard[j].Lapses {
+			return hard[i].Lapses > hard[j].Lapses
+		}
+		if hard[i].AgainRate != hard[j].AgainRate {
+			return hard[i].AgainRate > hard[j].AgainRate
+		}
+		return hard[i].Difficulty > hard[j].Difficulty
+	})
+	if len(hard) > limit {
+		hard = hard[:limit]
+	}
+	return hard
+}
+
+// SessionSummary summarises the reviews made in a study session
+type SessionSummary struct {
+	Reviews              int     `json:"reviews"`
+	CardsReviewed        int     `json:"cards_reviewed"` // distinct cards, however often each came up
+	Correct              int     `json:"correct"`        // reviews answered better than "again"
+	Accuracy             float64 `json:"accuracy"`       // share correct, 0 without reviews
+	ActiveSeconds        int     `json:"active_seconds"` // time studied, pauses left out
+	SecondsPerCard       float64 `json:"seconds_per_card"`
+	AverageAnswerSeconds float64 `json:"average_answer_seconds"` // over reviews that were timed
+}
+
+// SummarizeSession summarises a session's reviews and the time it was studied for.
+// Time per card is the active time spread over the reviews, so that it includes
+// reading answers and moving on as well as answering.
+func SummarizeSession(events []ReviewEvent, active time.Duration) SessionSummary {
+	summary := SessionSummary{Reviews: len(events), ActiveSeconds: int(active.Seconds())}
+	cards := make(map[uuid.UUID]bool, len(events))
+	var timed, totalMs int
+	for _, event := range events {
+		cards[event.CardID] = true
+		if event.Rating > Again {
+			summary.Correct++
+		}
+		if event.TimeTakenMs > 0 {
+			timed++
+			totalMs += event.TimeTakenMs
+		}
+	}
+	summary.CardsReviewed = len(cards)
+	if summary.Reviews > 0 {
+		summary.Accuracy = math.Round(float64(summary.Correct)/float64(summary.Reviews)*1000) / 1000
+		summary.SecondsPerCard = math.Round(active.Seconds()/float64(summary.Reviews)*10) / 10
+	}
+	if timed > 0 {
+		summary.AverageAnswerSeconds = math.Round(float64(totalMs)/float64(timed)/100) / 10
+	}
+	return summary
+}
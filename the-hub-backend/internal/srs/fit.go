@@ -0,0 +1,145 @@
+package srs
+
+import (
+	"errors"
+	"math"
+)
+
+// MinFitReviews is how many reviews, not counting a card's first or same-day repeats,
+// it takes before FSRS weights are fit to a user
+const MinFitReviews = 100
+
+// ErrTooFewReviews is returned when a review history is too short to fit weights to
+var ErrTooFewReviews = errors.New("not enough reviews to fit scheduler parameters")
+
+const (
+	fitIterations     = 80
+	fitLearningRate   = 0.02
+	fitStep           = 1e-3
+	fitRegularisation = 0.01 // how strongly weights are kept near the defaults
+)
+
+// ReviewLog is one past review of a card
+type ReviewLog struct {
+	Rating      Rating
+	ElapsedDays float64 // whole days since the card's previous review
+}
+
+// FitResult is the outcome of fitting FSRS weights to a review history
+type FitResult struct {
+	Parameters Parameters `json:"parameters"`
+	Reviews    int        `json:"reviews"`     // reviews the fit was scored on
+	LossBefore float64    `json:"loss_before"` // log loss of the starting weights
+	LossAfter  float64    `json:"loss_after"`  // log loss of the fitted weights
+}
+
+// Fit tunes FSRS weights, starting from start, to predict whether the cards in
+// histories were recalled. Each history holds one card's reviews in order, starting
+// with its first. The weights are fit by gradient descent on the log loss of the
+// predicted recall probability, kept near the defaults so a short history cannot
+// pull them anywhere extreme.
+func Fit(histories [][]ReviewLog, start Parameters) (FitResult, error) {
+	reviews := 0
+	for _, history := range histories {
+		for _, review := range history[min(1, len(history)):] {
+			if review.ElapsedDays > 0 {
+				reviews++
+			}
+		}
+	}
+	if reviews < MinFitReviews {
+		return FitResult{}, ErrTooFewReviews
+	}
+	if !start.Valid() {
+		start = DefaultParameters
+	}
+
+	prior := normalise(DefaultParameters)
+	objective := func(u [ParameterCount]float64) float64 {
+		penalty := 0.0
+		for i := range u {
+			penalty += (u[i] - prior[i]) * (u[i] - prior[i])
+		}
+		return logLoss(histories, denormalise(u)) + fitRegularisation*penalty
+	}
+
+	// Adam over the weights rescaled to 0-1 within their bounds, so one learning
+	// rate suits weights of very different sizes
+	u := normalise(start)
+	best, bestLoss := u, objective(u)
+	var moment, velocity [ParameterCount]float64
+	for step := 1; step <= fitIterations; step++ {
+		for i := range u {
+			up, down := u, u
+			up[i] = math.Min(1, u[i]+fitStep)
+			down[i] = math.Max(0, u[i]-fitStep)
+			gradient := (objective(up) - objective(down)) / (up[i] - down[i])
+
+			moment[i] = 0.9*moment[i] + 0.1*gradient
+			velocity[i] = 0.999*velocity[i] + 0.001*gradient*gradient
+			m := moment[i] / (1 - math.Pow(0.9, float64(step)))
+			v := velocity[i] / (1 - math.Pow(0.999, float64(step)))
+			u[i] = math.Max(0, math.Min(1, u[i]-fitLearningRate*m/(math.Sqrt(v)+1e-8)))
+		}
+		if loss := objective(u); loss < bestLoss {
+			best, bestLoss = u, loss
+		}
+	}
+
+	fitted := denormalise(best)
+	return FitResult{
+		Parameters: fitted,
+		Reviews:    reviews,
+		LossBefore: logLoss(histories, start),
+		LossAfter:  logLoss(histories, fitted),
+	}, nil
+}
+
+// logLoss replays every history under params and returns the mean log loss of the
+// recall probability predicted before each review a day or more after the last
+func logLoss(histories [][]ReviewLog, params Parameters) float64 {
+	f := FSRS{Params: params, Retention: DefaultRetention}
+	total, count := 0.0, 0
+	for _, history := range histories {
+		if len(history) == 0 {
+			continue
+		}
+		stability := f.initialStability(history[0].Rating)
+		difficulty := f.initialDifficulty(history[0].Rating)
+		for _, review := range history[1:] {
+			if review.ElapsedDays > 0 {
+				p := math.Max(1e-6, math.Min(1-1e-6, Retrievability(review.ElapsedDays, stability)))
+				if review.Rating == Again {
+					total -= math.Log(1 - p)
+				} else {
+					total -= math.Log(p)
+				}
+				count++
+			}
+			stability = math.Max(0.01, f.NextStability(stability, difficulty, review.ElapsedDays, review.Rating))
+			difficulty = f.nextDifficulty(difficulty, review.Rating)
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
+
+func normalise(p Parameters) [ParameterCount]float64 {
+	var u [ParameterCount]float64
+	for i, w := range p {
+		lo, hi := parameterBounds[i][0], parameterBounds[i][1]
+		u[i] = math.Max(0, math.Min(1, (w-lo)/(hi-lo)))
+	}
+	return u
+}
+
+func denormalise(u [ParameterCount]float64) Parameters {
+	var p Parameters
+	for i := range u {
+		lo, hi := parameterBounds[i][0], parameterBounds[i][1]
+		p[i] = lo + u[i]*(hi-lo)
+	}
+	return p
+}
@@ -0,0 +1,181 @@
+package srs
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// fsrsDecay and fsrsFactor shape the FSRS forgetting curve so that recall
+	// probability is exactly 90% after Stability days
+	fsrsDecay  = -0.5
+	fsrsFactor = 19.0 / 81.0
+
+	maxInterval = 36500
+)
+
+// ParameterCount is the number of FSRS-5 weights
+const ParameterCount = 19
+
+// Parameters are the FSRS-5 model weights
+type Parameters [ParameterCount]float64
+
+// DefaultParameters are the FSRS-5 weights fit on a large public review dataset,
+// used until a user has enough reviews of their own
+var DefaultParameters = Parameters{
+	0.40255, 1.18385, 3.173, 15.69105, 7.1949, 0.5345, 1.4604, 0.0046, 1.54575, 0.1192,
+	1.01925, 1.9395, 0.11, 0.29605, 2.2698, 0.2315, 2.9898, 0.51655, 0.6621,
+}
+
+// parameterBounds are the ranges each weight is kept within, as in the reference optimizer
+var parameterBounds = [ParameterCount][2]float64{
+	{0.01, 100}, {0.01, 100}, {0.01, 100}, {0.01, 100},
+	{1, 10}, {0.001, 4}, {0.001, 4}, {0.001, 0.75},
+	{0, 4.5}, {0, 0.8}, {0.001, 3.5}, {0.001, 5},
+	{0.001, 0.25}, {0.001, 0.9}, {0, 4}, {0, 1},
+	{1, 6}, {0, 2}, {0, 2},
+}
+
+// Valid reports whether every weight is within its bounds
+func (p Parameters) Valid() bool {
+	for i, w := range p {
+		if math.IsNaN(w) || w < parameterBounds[i][0] || w > parameterBounds[i][1] {
+			return false
+		}
+	}
+	return true
+}
+
+// FSRS is the Free Spaced Repetition Scheduler, version 5. It models each card by its
+// stability and difficulty and schedules it for when recall probability is expected
+// to fall to the desired retention.
+type FSRS struct {
+	Params    Parameters
+	Retention float64
+}
+
+// NewFSRS returns an FSRS scheduler, falling back to the defaults for invalid settings
+func NewFSRS(params Parameters, retention float64) FSRS {
+	if !params.Valid() {
+		params = DefaultParameters
+	}
+	if !ValidRetention(retention) {
+		retention = DefaultRetention
+	}
+	return FSRS{Params: params, Retention: retention}
+}
+
+// Name implements Scheduler
+func (FSRS) Name() string { return SchedulerFSRS }
+
+// Review implements Scheduler
+func (f FSRS) Review(m Memory, grade Grade, now time.Time) Memory {
+	m = f.Seed(m)
+	if m.Stability == 0 {
+		m.Stability = f.initialStability(grade.Rating)
+		m.Difficulty = f.initialDifficulty(grade.Rating)
+		return m
+	}
+
+	elapsed := elapsedDays(m.LastReviewed, now)
+	if m.LastReviewed.IsZero() {
+		// Imported cards may carry SM-2 progress without a review date
+		elapsed = float64(m.Interval)
+	}
+	m.Stability = f.NextStability(m.Stability, m.Difficulty, elapsed, grade.Rating)
+	m.Difficulty = f.nextDifficulty(m.Difficulty, grade.Rating)
+	return m
+}
+
+// Interval implements Scheduler
+func (f FSRS) Interval(m Memory) int {
+	m = f.Seed(m)
+	if m.Stability == 0 {
+		return 1
+	}
+	return f.IntervalFor(m.Stability)
+}
+
+// Seed fills in the FSRS state of a card that has so far only been reviewed with SM-2.
+// Its stability is the interval SM-2 chose, since that is when FSRS at 90% retention
+// would have scheduled it, and its difficulty is the one that makes FSRS grow that
+// interval by the card's easiness factor on a good review.
+func (f FSRS) Seed(m Memory) Memory {
+	if m.Stability > 0 || (m.LastReviewed.IsZero() && m.Repetitions == 0) {
+		return m
+	}
+	w := f.Params
+	m.Stability = math.Max(float64(m.Interval), 0.1)
+	easiness := m.Easiness
+	if easiness == 0 {
+		easiness = 2.5
+	}
+	growth := math.Exp(w[8]) * math.Pow(m.Stability, -w[9]) * (math.Exp(w[10]*(1-0.9)) - 1)
+	m.Difficulty = clampDifficulty(11 - (easiness-1)/growth)
+	return m
+}
+
+// Retrievability is the probability of recalling a card elapsed days after a review
+// that left it with stability
+func Retrievability(elapsed, stability float64) float64 {
+	if stability <= 0 {
+		return 0
+	}
+	return math.Pow(1+fsrsFactor*elapsed/stability, fsrsDecay)
+}
+
+// IntervalFor returns the days until recall probability falls to the desired retention
+func (f FSRS) IntervalFor(stability float64) int {
+	interval := stability / fsrsFactor * (math.Pow(f.Retention, 1/fsrsDecay) - 1)
+	return int(math.Max(1, math.Min(maxInterval, math.Round(interval))))
+}
+
+// NextStability returns the stability after a review elapsed days after the last one
+func (f FSRS) NextStability(stability, difficulty, elapsed float64, rating Rating) float64 {
+	w := f.Params
+	if elapsed == 0 {
+		// Reviewed again the same day: only a short-term change
+		return stability * math.Exp(w[17]*(float64(rating)-3+w[18]))
+	}
+
+	r := Retrievability(elapsed, stability)
+	if rating == Again {
+		forget := w[11] * math.Pow(difficulty, -w[12]) * (math.Pow(stability+1, w[13]) - 1) * math.Exp(w[14]*(1-r))
+		return math.Min(forget, stability/math.Exp(w[17]*w[18]))
+	}
+
+	hardPenalty, easyBonus := 1.0, 1.0
+	if rating == Hard {
+		hardPenalty = w[15]
+	}
+	if rating == Easy {
+		easyBonus = w[16]
+	}
+	return stability * (math.Exp(w[8])*(11-difficulty)*math.Pow(stability, -w[9])*
+		(math.Exp(w[10]*(1-r))-1)*hardPenalty*easyBonus + 1)
+}
+
+func (f FSRS) initialStability(rating Rating) float64 {
+	return math.Max(f.Params[rating-1], 0.1)
+}
+
+func (f FSRS) initialDifficulty(rating Rating) float64 {
+	return clampDifficulty(f.rawInitialDifficulty(rating))
+}
+
+func (f FSRS) rawInitialDifficulty(rating Rating) float64 {
+	return f.Params[4] - math.Exp(f.Params[5]*(float64(rating)-1)) + 1
+}
+
+// nextDifficulty moves difficulty up for poor ratings and down for good ones, less so
+// the closer it already is to the limit, then pulls it towards a new card's "easy" value
+func (f FSRS) nextDifficulty(difficulty float64, rating Rating) float64 {
+	w := f.Params
+	delta := -w[6] * (float64(rating) - 3)
+	damped := difficulty + delta*(10-difficulty)/9
+	return clampDifficulty(w[7]*f.rawInitialDifficulty(Easy) + (1-w[7])*damped)
+}
+
+func clampDifficulty(d float64) float64 {
+	return math.Max(1, math.Min(10, d))
+}
@@ -0,0 +1,46 @@
+package srs
+
+import "time"
+
+// SM2 is the SuperMemo 2 algorithm
+type SM2 struct{}
+
+// Name implements Scheduler
+func (SM2) Name() string { return SchedulerSM2 }
+
+// Review implements Scheduler. Qualities of 3 and above grow the interval by the
+// easiness factor; lower ones start the card over.
+func (SM2) Review(m Memory, grade Grade, now time.Time) Memory {
+	quality := float64(grade.Quality)
+	if m.Easiness == 0 {
+		m.Easiness = 2.5
+	}
+
+	if quality >= 3 {
+		if m.Repetitions == 0 {
+			m.Interval = 1
+		} else if m.Repetitions == 1 {
+			m.Interval = 6
+		} else {
+			m.Interval = int(float64(m.Interval) * m.Easiness)
+		}
+		m.Repetitions++
+	} else {
+		m.Repetitions = 0
+		m.Interval = 1
+	}
+
+	m.Easiness = m.Easiness + (0.1 - (5-quality)*(0.08+(5-quality)*0.02))
+	if m.Easiness < 1.3 {
+		m.Easiness = 1.3
+	}
+	return m
+}
+
+// Interval implements Scheduler
+func (SM2) Interval(m Memory) int {
+	if m.Interval < 1 {
+		return 1
+	}
+	return m.Interval
+}
@@ -0,0 +1,117 @@
+// Package srs schedules flashcard reviews. Every review updates the memory state kept
+// for each algorithm, SM-2 and FSRS, so a deck can switch between them without losing
+// anything; the deck's chosen scheduler decides when the card is due next.
+package srs
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Names of the available schedulers
+const (
+	SchedulerSM2  = "sm2"
+	SchedulerFSRS = "fsrs"
+)
+
+// DefaultRetention is the desired retention of new decks
+const DefaultRetention = 0.9
+
+// Rating is how well a card was recalled, as FSRS grades it
+type Rating int
+
+const (
+	Again Rating = iota + 1 // forgotten
+	Hard                    // recalled with serious difficulty
+	Good                    // recalled after some hesitation
+	Easy                    // recalled easily
+)
+
+// Grade is the answer to one review, both as an SM-2 quality (0-5) and an FSRS rating
+type Grade struct {
+	Quality int
+	Rating  Rating
+}
+
+// GradeFromQuality converts an SM-2 quality of 0-5. Qualities below 3 are lapses.
+func GradeFromQuality(quality int) Grade {
+	switch {
+	case quality < 3:
+		return Grade{Quality: quality, Rating: Again}
+	case quality == 3:
+		return Grade{Quality: quality, Rating: Hard}
+	case quality == 4:
+		return Grade{Quality: quality, Rating: Good}
+	default:
+		return Grade{Quality: quality, Rating: Easy}
+	}
+}
+
+// GradeFromRating converts an FSRS rating of 1-4
+func GradeFromRating(rating Rating) Grade {
+	return Grade{Quality: int(rating) + 1, Rating: rating}
+}
+
+// Memory is what is known about how well a card is remembered
+type Memory struct {
+	// SM-2
+	Easiness    float64
+	Interval    int // days
+	Repetitions int
+
+	// FSRS
+	Stability  float64 // days until recall probability falls to 90%; 0 before the first FSRS review
+	Difficulty float64 // 1-10
+
+	Lapses       int       // times the card was forgotten after being learned
+	LastReviewed time.Time // zero for new cards
+}
+
+// Scheduler is one spaced repetition algorithm
+type Scheduler interface {
+	// Name is the name decks select the scheduler by
+	Name() string
+	// Review updates the algorithm's part of m for a review at now
+	Review(m Memory, grade Grade, now time.Time) Memory
+	// Interval returns the days from the last review until the card is due
+	Interval(m Memory) int
+}
+
+// New returns the named scheduler. FSRS uses params and aims for retention.
+func New(name string, params Parameters, retention float64) (Scheduler, error) {
+	switch name {
+	case SchedulerSM2, "":
+		return SM2{}, nil
+	case SchedulerFSRS:
+		return NewFSRS(params, retention), nil
+	default:
+		return nil, fmt.Errorf("unknown scheduler %q", name)
+	}
+}
+
+// ValidRetention reports whether retention is a sensible desired retention
+func ValidRetention(retention float64) bool {
+	return retention >= 0.7 && retention <= 0.99
+}
+
+// Review applies a review to every algorithm's memory state and returns the new state
+// and the days until the card is due under active
+func Review(m Memory, grade Grade, now time.Time, active Scheduler, params Parameters, retention float64) (Memory, int) {
+	if grade.Rating == Again && !m.LastReviewed.IsZero() {
+		m.Lapses++
+	}
+	// FSRS first: a card new to it is seeded from the SM-2 state before this review
+	m = NewFSRS(params, retention).Review(m, grade, now)
+	m = SM2{}.Review(m, grade, now)
+	m.LastReviewed = now
+	return m, active.Interval(m)
+}
+
+// elapsedDays counts the whole days between two reviews
+func elapsedDays(from, to time.Time) float64 {
+	if from.IsZero() || !to.After(from) {
+		return 0
+	}
+	return math.Floor(to.Sub(from).Hours() / 24)
+}
@@ -0,0 +1,306 @@
+// Package studyplan turns a deadline and an estimate of the study left into blocks in
+// a user's study calendar zones. Each day with free study time first gets a review of
+// the related decks' cards that have come due, then an even share of the study that is
+// left, so the work is spread out up to the deadline rather than crammed before it.
+package studyplan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/booking"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+)
+
+// Block kinds
+const (
+	KindStudy  = "study"
+	KindReview = "review"
+)
+
+const (
+	quantum = 15 * time.Minute
+
+	// MinBlockMinutes is the shortest study block planned, unless less than that is left
+	MinBlockMinutes = 30
+	// MaxBlockMinutes is the longest study block planned
+	MaxBlockMinutes = 120
+
+	reviewSecondsPerCard = 20
+	maxReviewMinutes     = 45
+	breakMinutes         = 15 // kept free between blocks in the same stretch of free time
+)
+
+// Deck is a deck to review alongside the study, with when each of its cards is next due
+type Deck struct {
+	ID   uuid.UUID
+	Name string
+	Due  []time.Time // next reviews of the cards that are not suspended
+}
+
+// Input is everything Build needs. It does no database access of its own.
+type Input struct {
+	Title         string    // what is being studied, for the blocks' titles
+	Now           time.Time // in the user's timezone
+	Deadline      time.Time
+	StudyMinutes  int                   // study left to plan
+	Zones         []models.CalendarZone // the user's study zones
+	Busy          []booking.Interval    // time already taken, sorted and merged as booking.Busy returns it
+	Decks         []Deck
+	MinutesPerDay int // most study planned on a day, 0 for no limit
+}
+
+// Block is a planned stretch of study or review
+type Block struct {
+	Kind    string      `json:"kind"` // KindStudy or KindReview
+	Title   string      `json:"title"`
+	Start   time.Time   `json:"start"`
+	End     time.Time   `json:"end"`
+	DeckIDs []uuid.UUID `json:"deck_ids,omitempty"` // decks a review block covers
+}
+
+// Minutes is the block's length
+func (b Block) Minutes() int {
+	return int(b.End.Sub(b.Start) / time.Minute)
+}
+
+// Plan is the blocks planned up to the deadline
+type Plan struct {
+	Blocks           []Block `json:"blocks"` // ordered by start
+	StudyMinutes     int     `json:"study_minutes"`
+	ReviewMinutes    int     `json:"review_minutes"`
+	UnplannedMinutes int     `json:"unplanned_minutes"` // study that did not fit before the deadline
+}
+
+// day is the free study time on one calendar day
+type day struct {
+	end  time.Time // midnight after the day
+	free []booking.Interval
+}
+
+// Build plans in.StudyMinutes of study, and reviews of the decks' due cards, into the
+// free time of the study zones between now and the deadline
+func Build(in Input) Plan {
+	plan := Plan{Blocks: []Block{}}
+	days := freeDays(in)
+
+	// Reviews come first on a day, so the cards due by then are refreshed before studying
+	var due []dueCard
+	for d := range in.Decks {
+		for _, at := range in.Decks[d].Due {
+			due = append(due, dueCard{deck: d, at: at})
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].at.Before(due[j].at) })
+	for i := range days {
+		n := 0
+		for n < len(due) && due[n].at.Before(days[i].end) {
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		block, ok := allot(&days[i].free, reviewMinutes(n), reviewMinutes(n))
+		if !ok {
+			continue
+		}
+		block.Kind = KindReview
+		var names []string
+		seen := map[int]bool{}
+		for _, card := range due[:n] {
+			if !seen[card.deck] {
+				seen[card.deck] = true
+				block.DeckIDs = append(block.DeckIDs, in.Decks[card.deck].ID)
+				names = append(names, in.Decks[card.deck].Name)
+			}
+		}
+		block.Title = "Review: " + strings.Join(names, ", ")
+		plan.Blocks = append(plan.Blocks, block)
+		plan.ReviewMinutes += block.Minutes()
+		due = due[n:]
+	}
+
+	// Study is shared out evenly over the days that are left, carrying over what a day cannot hold
+	left := roundUp(max(in.StudyMinutes, 0))
+	var study []Block
+	for i := range days {
+		if left == 0 {
+			break
+		}
+		target := roundUp((left + len(days) - i - 1) / (len(days) - i))
+		target = min(max(target, MinBlockMinutes), left)
+		if in.MinutesPerDay > 0 {
+			target = min(target, roundUp(in.MinutesPerDay))
+		}
+		for target > 0 {
+			size := min(target, MaxBlockMinutes)
+			block, ok := allot(&days[i].free, size, min(MinBlockMinutes, size))
+			if !ok {
+				break
+			}
+			block.Kind = KindStudy
+			study = append(study, block)
+			target -= block.Minutes()
+			left -= block.Minutes()
+		}
+	}
+	for i := range study {
+		study[i].Title = "Study: " + in.Title
+		if len(study) > 1 {
+			study[i].Title = fmt.Sprintf("Study: %s (%d/%d)", in.Title, i+1, len(study))
+		}
+		plan.StudyMinutes += study[i].Minutes()
+	}
+	plan.Blocks = append(plan.Blocks, study...)
+	plan.UnplannedMinutes = left
+
+	sort.SliceStable(plan.Blocks, func(i, j int) bool { return plan.Blocks[i].Start.Before(plan.Blocks[j].Start) })
+	return plan
+}
+
+type dueCard struct {
+	deck int
+	at   time.Time
+}
+
+// reviewMinutes is the time set aside to review n cards
+func reviewMinutes(n int) int {
+	return min(roundUp((n*reviewSecondsPerCard+59)/60), maxReviewMinutes)
+}
+
+// roundUp rounds minutes up to whole quanta
+func roundUp(minutes int) int {
+	step := int(quantum / time.Minute)
+	return (minutes + step - 1) / step * step
+}
+
+// freeDays lists the days with free study time between now and the deadline. Time
+// belongs to the day a stretch of it starts on.
+func freeDays(in Input) []day {
+	loc := in.Now.Location()
+	from := in.Now.Truncate(quantum)
+	if from.Before(in.Now) {
+		from = from.Add(quantum)
+	}
+	to := in.Deadline.Truncate(quantum)
+	if !to.After(from) {
+		return nil
+	}
+
+	// Overnight zones reach into the first day from the day before
+	var spans []booking.Interval
+	first := time.Date(from.In(loc).Year(), from.In(loc).Month(), from.In(loc).Day(), 0, 0, 0, 0, loc).AddDate(0, 0, -1)
+	for date := first; date.Before(to); date = date.AddDate(0, 0, 1) {
+		for i := range in.Zones {
+			if !in.Zones[i].OccursOn(date, loc) {
+				continue
+			}
+			start, end := in.Zones[i].OccurrenceOn(date, loc)
+			start, end = maxTime(start, from), minTime(end, to)
+			if end.After(start) {
+				spans = append(spans, booking.Interval{Start: start, End: end})
+			}
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start.Before(spans[j].Start) })
+
+	var days []day
+	for _, span := range subtract(merge(spans), in.Busy) {
+		start := span.Start.Truncate(quantum)
+		if start.Before(span.Start) {
+			start = start.Add(quantum)
+		}
+		end := span.End.Truncate(quantum)
+		if !end.After(start) {
+			continue
+		}
+		local := start.In(loc)
+		midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+		if n := len(days); n == 0 || !days[n-1].end.Equal(midnight) {
+			days = append(days, day{end: midnight})
+		}
+		days[len(days)-1].free = append(days[len(days)-1].free, booking.Interval{Start: start, End: end})
+	}
+	return days
+}
+
+// allot takes a block of want minutes from the first stretch of free time long enough,
+// or failing that all of the longest stretch of at least least minutes
+func allot(free *[]booking.Interval, want, least int) (Block, bool) {
+	chosen, minutes := -1, 0
+	for i, span := range *free {
+		length := int(span.End.Sub(span.Start) / time.Minute)
+		if length >= want {
+			chosen, minutes = i, want
+			break
+		}
+		if length >= least && length > minutes {
+			chosen, minutes = i, length
+		}
+	}
+	if chosen < 0 || minutes == 0 {
+		return Block{}, false
+	}
+
+	span := &(*free)[chosen]
+	block := Block{Start: span.Start, End: span.Start.Add(time.Duration(minutes) * time.Minute)}
+	span.Start = block.End.Add(breakMinutes * time.Minute)
+	if span.End.Sub(span.Start) < quantum {
+		*free = append((*free)[:chosen], (*free)[chosen+1:]...)
+	}
+	return block, true
+}
+
+// merge joins overlapping spans, which must be sorted by start
+func merge(spans []booking.Interval) []booking.Interval {
+	var merged []booking.Interval
+	for _, span := range spans {
+		if n := len(merged); n > 0 && !span.Start.After(merged[n-1].End) {
+			merged[n-1].End = maxTime(merged[n-1].End, span.End)
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// subtract removes the busy spans from the free ones; both must be sorted and merged
+func subtract(free, busy []booking.Interval) []booking.Interval {
+	var out []booking.Interval
+	for _, span := range free {
+		start := span.Start
+		for _, taken := range busy {
+			if !taken.End.After(start) {
+				continue
+			}
+			if !taken.Start.Before(span.End) {
+				break
+			}
+			if taken.Start.After(start) {
+				out = append(out, booking.Interval{Start: start, End: taken.Start})
+			}
+			start = maxTime(start, taken.End)
+		}
+		if span.End.After(start) {
+			out = append(out, booking.Interval{Start: start, End: span.End})
+		}
+	}
+	return out
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
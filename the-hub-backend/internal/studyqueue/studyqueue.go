@@ -0,0 +1,177 @@
+// Package studyqueue picks the cards to study today from a deck and its sub-decks.
+// Every deck has daily limits on new cards and reviews; a card counts against the
+// limits of its own deck and of each deck above it up to the one being studied, so a
+// parent's limits cap the study of all its sub-decks together.
+package studyqueue
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/google/uuid"
+)
+
+// Daily limits of new decks
+const (
+	DefaultNewPerDay     = 20
+	DefaultReviewsPerDay = 200
+)
+
+// Limits is how many new cards and reviews a deck allows a day, 0 for no limit
+type Limits struct {
+	New     int `json:"new"`
+	Reviews int `json:"reviews"`
+}
+
+// Counts is how many new cards and reviews were studied, or are left
+type Counts struct {
+	New     int `json:"new"`
+	Reviews int `json:"reviews"`
+}
+
+// Deck is one deck of the tree being studied
+type Deck struct {
+	ID       uuid.UUID
+	ParentID *uuid.UUID
+	Limits   Limits
+	Studied  Counts // studied today in this deck itself, not its sub-decks
+}
+
+// Queue is the cards to study today, reviews first
+type Queue struct {
+	Cards   []models.Card `json:"cards"`
+	New     int           `json:"new_count"`
+	Reviews int           `json:"review_count"`
+	Held    int           `json:"held_back"` // due cards left for another day by the limits
+	Left    Counts        `json:"left"`      // what the limits of the deck studied still allow after these; -1 for no limit
+}
+
+// IsNew reports whether a card has never been reviewed
+func IsNew(card *models.Card) bool {
+	return card.LastReviewed.IsZero()
+}
+
+// Available reports whether a card can be studied at now: it is neither suspended nor buried
+func Available(card *models.Card, now time.Time) bool {
+	return !card.Suspended && (card.BuriedUntil == nil || !card.BuriedUntil.After(now))
+}
+
+// Build picks today's cards from the due ones, which may come from any deck of the tree
+// under root: reviews, the most overdue first, then new cards in the order they were
+// added, each while the limits of its deck and of every deck above it allow
+func Build(root uuid.UUID, decks []Deck, due []models.Card, now time.Time) Queue {
+	byID := make(map[uuid.UUID]*Deck, len(decks))
+	for i := range decks {
+		byID[decks[i].ID] = &decks[i]
+	}
+
+	// What each deck has left is its limit less what was studied in it and below it
+	left := make(map[uuid.UUID]*Counts, len(decks))
+	for i := range decks {
+		left[decks[i].ID] = &Counts{New: decks[i].Limits.New, Reviews: decks[i].Limits.Reviews}
+	}
+	chain := func(deckID uuid.UUID) []*Deck {
+		var decks []*Deck
+		for deck := byID[deckID]; deck != nil && len(decks) < len(byID); {
+			decks = append(decks, deck)
+			if deck.ID == root || deck.ParentID == nil {
+				break
+			}
+			deck = byID[*deck.ParentID]
+		}
+		return decks
+	}
+	for i := range decks {
+		for _, above := range chain(decks[i].ID) {
+			left[above.ID].New -= decks[i].Studied.New
+			left[above.ID].Reviews -= decks[i].Studied.Reviews
+		}
+	}
+
+	var reviews, fresh []models.Card
+	for _, card := range due {
+		if !Available(&card, now) || card.NextReview.After(now) {
+			continue
+		}
+		if IsNew(&card) {
+			fresh = append(fresh, card)
+		} else {
+			reviews = append(reviews, card)
+		}
+	}
+	sort.SliceStable(reviews, func(i, j int) bool { return reviews[i].NextReview.Before(reviews[j].NextReview) })
+	sort.SliceStable(fresh, func(i, j int) bool { return fresh[i].CreatedAt.Before(fresh[j].CreatedAt) })
+
+	queue := Queue{Cards: []models.Card{}}
+	take := func(cards []models.Card, isNew bool) {
+		for _, card := range cards {
+			decks := chain(card.DeckID)
+			allowed := len(decks) > 0
+			for _, deck := range decks {
+				limit, remaining := deck.Limits.Reviews, left[deck.ID].Reviews
+				if isNew {
+					limit, remaining = deck.Limits.New, left[deck.ID].New
+				}
+				if limit > 0 && remaining <= 0 {
+					allowed = false
+					break
+				}
+			}
+			if !allowed {
+				queue.Held++
+				continue
+			}
+			for _, deck := range decks {
+				if isNew {
+					left[deck.ID].New--
+				} else {
+					left[deck.ID].Reviews--
+				}
+			}
+			queue.Cards = append(queue.Cards, card)
+			if isNew {
+				queue.New++
+			} else {
+				queue.Reviews++
+			}
+		}
+	}
+	take(reviews, false)
+	take(fresh, true)
+
+	queue.Left = Counts{New: -1, Reviews: -1}
+	if deck := byID[root]; deck != nil {
+		if deck.Limits.New > 0 {
+			queue.Left.New = max(left[root].New, 0)
+		}
+		if deck.Limits.Reviews > 0 {
+			queue.Left.Reviews = max(left[root].Reviews, 0)
+		}
+	}
+	return queue
+}
+
+// BuryUntil is when a card buried at now comes back: the start of the next day in loc
+func BuryUntil(now time.Time, loc *time.Location) time.Time {
+	return util.StartOfDay(now.In(loc)).AddDate(0, 0, 1)
+}
+
+// Custom picks cards for custom study from those a query matched: the ones available
+// at now, only those due if dueOnly, and new ones only if includeNew, the most overdue
+// first, up to limit. Daily limits do not apply to custom study.
+func Custom(cards []models.Card, dueOnly, includeNew bool, limit int, now time.Time) []models.Card {
+	picked := []models.Card{}
+	for _, card := range cards {
+		if !Available(&card, now) || (dueOnly && card.NextReview.After(now)) || (!includeNew && IsNew(&card)) {
+			continue
+		}
+		picked = append(picked, card)
+	}
+	sort.SliceStable(picked, func(i, j int) bool { return picked[i].NextReview.Before(picked[j].NextReview) })
+	if limit > 0 && len(picked) > limit {
+		picked = picked[:limit]
+	}
+	return picked
+}
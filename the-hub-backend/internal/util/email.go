@@ -7,6 +7,7 @@ import (
 	"net/smtp"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/TheoMKgosi/The-hub/internal/config"
 )
@@ -78,6 +79,67 @@ This is an automated message. Please do not reply to this email.`, resetURL),
 	return es.sendEmail(toEmail, template)
 }
 
+// bookingTimeFormat shows when a booking is, in the timezone of the times given
+const bookingTimeFormat = "Monday, January 2, 2006 15:04 MST"
+
+// SendBookingConfirmationEmail confirms a booking to the person who made it. start and
+// end should be in the timezone they booked in.
+func (es *EmailService) SendBookingConfirmationEmail(toEmail, guestName, ownerName, title string, start, end time.Time) error {
+	if es.SMTPHost == "" || es.SMTPPort == "" {
+		config.Logger.Warn("SMTP not configured, skipping email send")
+		return nil
+	}
+
+	template := EmailTemplate{
+		Subject: fmt.Sprintf("Booking confirmed: %s with %s", title, ownerName),
+		Body: fmt.Sprintf(`Hello %s,
+
+Your booking with %s is confirmed.
+
+What: %s
+When: %s - %s
+
+Best regards,
+The Hub Team
+
+---
+This is an automated message. Please do not reply to this email.`, guestName, ownerName, title, start.Format(bookingTimeFormat), end.Format("15:04 MST")),
+	}
+
+	return es.sendEmail(toEmail, template)
+}
+
+// SendNewBookingEmail tells the owner of a booking link that someone booked time with
+// them. start and end should be in the owner's timezone.
+func (es *EmailService) SendNewBookingEmail(toEmail, guestName, guestEmail, title, notes string, start, end time.Time) error {
+	if es.SMTPHost == "" || es.SMTPPort == "" {
+		config.Logger.Warn("SMTP not configured, skipping email send")
+		return nil
+	}
+
+	if notes == "" {
+		notes = "(none)"
+	}
+	template := EmailTemplate{
+		Subject: fmt.Sprintf("New booking: %s with %s", title, guestName),
+		Body: fmt.Sprintf(`Hello,
+
+%s (%s) booked time with you. It has been added to your schedule.
+
+What: %s
+When: %s - %s
+Notes: %s
+
+Best regards,
+The Hub Team
+
+---
+This is an automated message. Please do not reply to this email.`, guestName, guestEmail, title, start.Format(bookingTimeFormat), end.Format("15:04 MST"), notes),
+	}
+
+	return es.sendEmail(toEmail, template)
+}
+
 // sendEmail sends an email using SMTP
 func (es *EmailService) sendEmail(toEmail string, template EmailTemplate) error {
 	// Set up authentication information
@@ -97,7 +159,7 @@ func (es *EmailService) sendEmail(toEmail string, template EmailTemplate) error
 		return fmt.Errorf("failed to send email: %w", err)
 	}
 
-	config.Logger.Infof("Password reset email sent successfully to %s", toEmail)
+	config.Logger.Infof("Email %q sent successfully to %s", template.Subject, toEmail)
 	return nil
 }
 
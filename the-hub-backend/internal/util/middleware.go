@@ -3,6 +3,7 @@ package util
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/TheoMKgosi/The-hub/internal/config"
 	"github.com/TheoMKgosi/The-hub/internal/models"
@@ -95,3 +96,34 @@ func AdminMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AppPasswordAuthMiddleware authenticates HTTP Basic credentials made of the user's
+// email and one of their app passwords. It is used by clients such as CalDAV apps.
+func AppPasswordAuthMiddleware(realm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username, password, ok := c.Request.BasicAuth()
+		if !ok || password == "" {
+			c.Header("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		var appPassword models.AppPassword
+		if err := config.GetDB().Preload("User").
+			Where("password_hash = ?", HashAppPassword(password)).
+			First(&appPassword).Error; err != nil || !strings.EqualFold(appPassword.User.Email, username) {
+			c.Header("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+
+		// Clients poll often; only record usage once a minute
+		now := time.Now()
+		if appPassword.LastUsedAt == nil || now.Sub(*appPassword.LastUsedAt) > time.Minute {
+			config.GetDB().Model(&appPassword).Update("last_used_at", now)
+		}
+
+		c.Set("userID", appPassword.UserID)
+		c.Next()
+	}
+}
@@ -153,7 +153,7 @@ func (s *PushNotificationService) sendWebPush(subscription models.PushSubscripti
 func (s *PushNotificationService) SendTaskReminder(taskID uuid.UUID, userID uuid.UUID, taskTitle string, dueDate *time.Time) error {
 	var body string
 	if dueDate != nil {
-		body = fmt.Sprintf("Task '%s' is due on %s", taskTitle, dueDate.Format("Jan 2, 2006"))
+		body = fmt.Sprintf("Task '%s' is due on %s", taskTitle, dueDate.In(LoadUserLocation(s.db, userID)).Format("Jan 2, 2006"))
 	} else {
 		body = fmt.Sprintf("Don't forget about task '%s'", taskTitle)
 	}
@@ -175,7 +175,7 @@ func (s *PushNotificationService) SendTaskReminder(taskID uuid.UUID, userID uuid
 
 // SendGoalDeadlineReminder sends a goal deadline reminder
 func (s *PushNotificationService) SendGoalDeadlineReminder(goalID uuid.UUID, userID uuid.UUID, goalTitle string, dueDate time.Time) error {
-	body := fmt.Sprintf("Goal '%s' is due on %s", goalTitle, dueDate.Format("Jan 2, 2006"))
+	body := fmt.Sprintf("Goal '%s' is due on %s", goalTitle, dueDate.In(LoadUserLocation(s.db, userID)).Format("Jan 2, 2006"))
 
 	event := NotificationEvent{
 		UserID: userID,
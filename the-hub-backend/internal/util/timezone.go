@@ -0,0 +1,86 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// userLocationKey caches the resolved location in the gin context
+const userLocationKey = "userLocation"
+
+// LoadTimezone loads an IANA zone name such as "Pacific/Honolulu". Empty means UTC.
+// "Local" is rejected so nothing ends up depending on the server's zone.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "UTC" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+// SettingsTimezone returns preferences.timezone from a settings object, if set
+func SettingsTimezone(settings map[string]interface{}) (string, bool) {
+	prefs, ok := settings["preferences"].(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	tz, ok := prefs["timezone"].(string)
+	return tz, ok
+}
+
+// UserLocation resolves the timezone stored in a user's settings JSON, falling back to UTC
+func UserLocation(settingsJSON string) *time.Location {
+	var settings map[string]interface{}
+	if err := json.Unmarshal([]byte(settingsJSON), &settings); err != nil {
+		return time.UTC
+	}
+	tz, _ := SettingsTimezone(settings)
+	loc, err := LoadTimezone(tz)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// LoadUserLocation reads a user's timezone from the database, falling back to UTC
+func LoadUserLocation(db *gorm.DB, userID uuid.UUID) *time.Location {
+	var user models.User
+	if err := db.Select("settings").Where("id = ?", userID).First(&user).Error; err != nil {
+		config.Logger.Warnf("Could not load timezone for user %s, using UTC: %v", userID, err)
+		return time.UTC
+	}
+	return UserLocation(user.Settings)
+}
+
+// GetUserLocation returns the authenticated user's timezone. It is resolved on first use
+// and cached on the context, so each request reads the settings at most once.
+func GetUserLocation(c *gin.Context) *time.Location {
+	if loc, ok := c.Get(userLocationKey); ok {
+		return loc.(*time.Location)
+	}
+
+	loc := time.UTC
+	if userID, ok := c.Get("userID"); ok {
+		loc = LoadUserLocation(config.GetDB(), userID.(uuid.UUID))
+	}
+	c.Set(userLocationKey, loc)
+	return loc
+}
+
+// StartOfDay returns midnight of t's calendar day in t's location
+func StartOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -2,6 +2,8 @@ package util
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"os"
 	"time"
@@ -67,6 +69,27 @@ func GenerateRefreshToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// GenerateSecureToken creates a URL-safe random token for secret links
+func GenerateSecureToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// HashAppPassword hashes an app password for storage and lookup. App passwords are
+// 256-bit random tokens, so a fast hash is safe and lets them be looked up directly.
+func HashAppPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashFeedToken hashes a calendar feed token for storage and lookup, like an app password
+func HashFeedToken(token string) string {
+	return HashAppPassword(token)
+}
+
 // HashRefreshToken hashes a refresh token for secure storage
 func HashRefreshToken(token string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(token), bcrypt.DefaultCost)
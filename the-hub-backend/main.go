@@ -6,9 +6,11 @@ import (
 	"os"
 	"strings"
 	"time"
+	_ "time/tzdata" // user timezones must resolve even on images without zoneinfo
 
 	_ "github.com/TheoMKgosi/The-hub/docs"
 	"github.com/TheoMKgosi/The-hub/internal/ai"
+	"github.com/TheoMKgosi/The-hub/internal/calendarsync"
 	"github.com/TheoMKgosi/The-hub/internal/config"
 	"github.com/TheoMKgosi/The-hub/internal/routes"
 	"github.com/gin-contrib/cors"
@@ -35,6 +37,9 @@ func main() {
 		log.Fatal("Database health check failed:", err)
 	}
 
+	startCalendarSyncWorker()
+	startReplanWorker()
+
 	router := gin.Default()
 
 	if os.Getenv("GIN_MODE") == "release" {
@@ -62,3 +67,45 @@ func main() {
 	}
 	router.Run(":" + port)
 }
+
+// startCalendarSyncWorker runs periodic calendar sync in the background.
+// CALENDAR_SYNC_INTERVAL is a Go duration (default 15m); 0 disables the worker.
+func startCalendarSyncWorker() {
+	interval := 15 * time.Minute
+	if value := os.Getenv("CALENDAR_SYNC_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("Invalid CALENDAR_SYNC_INTERVAL %q, using %s", value, interval)
+		} else {
+			interval = parsed
+		}
+	}
+	if interval <= 0 {
+		log.Println("Calendar sync worker disabled")
+		return
+	}
+
+	worker := calendarsync.NewWorker(config.GetDB(), interval, calendarsync.DefaultRegistry())
+	go worker.Run(context.Background())
+}
+
+// startReplanWorker proposes replans in the background when scheduled work slips.
+// REPLAN_INTERVAL is a Go duration; the worker is off unless it is set.
+func startReplanWorker() {
+	value := os.Getenv("REPLAN_INTERVAL")
+	if value == "" {
+		return
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid REPLAN_INTERVAL %q, replan worker disabled", value)
+		return
+	}
+	if interval <= 0 {
+		log.Println("Replan worker disabled")
+		return
+	}
+
+	worker := ai.NewReplanWorker(interval)
+	go worker.Run(context.Background())
+}
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    include_schedule BOOLEAN DEFAULT true,
+    include_tasks BOOLEAN DEFAULT true,
+    goal_ids TEXT[],
+    categories TEXT[],
+    last_accessed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_user_id ON calendar_feeds(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_token ON calendar_feeds(token);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_scheduled_tasks_external_uid;
+
+ALTER TABLE scheduled_tasks
+  DROP COLUMN IF EXISTS external_uid;
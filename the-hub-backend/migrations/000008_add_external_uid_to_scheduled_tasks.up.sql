@@ -0,0 +1,6 @@
+-- Track the iCalendar UID of imported events so re-imports skip duplicates
+ALTER TABLE scheduled_tasks
+  ADD COLUMN IF NOT EXISTS external_uid TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_tasks_external_uid
+  ON scheduled_tasks(user_id, external_uid);
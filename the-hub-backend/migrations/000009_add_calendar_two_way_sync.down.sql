@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_calendar_sync_audits_calendar_integration_id;
+DROP TABLE IF EXISTS calendar_sync_audits;
+
+ALTER TABLE scheduled_tasks
+  DROP COLUMN IF EXISTS source;
+
+ALTER TABLE calendar_event_syncs
+  DROP COLUMN IF EXISTS remote_updated_at;
+
+ALTER TABLE calendar_integrations
+  DROP COLUMN IF EXISTS sync_token;
@@ -0,0 +1,25 @@
+-- Incremental two-way calendar sync
+
+ALTER TABLE calendar_integrations
+  ADD COLUMN IF NOT EXISTS sync_token TEXT;
+
+ALTER TABLE calendar_event_syncs
+  ADD COLUMN IF NOT EXISTS remote_updated_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE scheduled_tasks
+  ADD COLUMN IF NOT EXISTS source TEXT;
+
+CREATE TABLE IF NOT EXISTS calendar_sync_audits (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  calendar_integration_id UUID NOT NULL,
+  scheduled_task_id UUID,
+  external_event_id TEXT,
+  direction TEXT,
+  action TEXT,
+  winner TEXT,
+  details TEXT,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_calendar_sync_audits_calendar_integration_id
+  ON calendar_sync_audits(calendar_integration_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS caldav_resources;
+DROP TABLE IF EXISTS app_passwords;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS app_passwords (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    password_hash TEXT NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_app_passwords_user_id ON app_passwords(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_app_passwords_password_hash ON app_passwords(password_hash);
+
+CREATE TABLE IF NOT EXISTS caldav_resources (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    collection TEXT NOT NULL,
+    name TEXT NOT NULL,
+    object_id UUID NOT NULL,
+    uid TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_caldav_resources_name ON caldav_resources(user_id, collection, name);
+CREATE INDEX IF NOT EXISTS idx_caldav_resources_object_id ON caldav_resources(object_id);
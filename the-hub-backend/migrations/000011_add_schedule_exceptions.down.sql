@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_scheduled_tasks_recurrence;
+
+ALTER TABLE scheduled_tasks
+  DROP COLUMN IF EXISTS cancelled,
+  DROP COLUMN IF EXISTS recurrence_id,
+  DROP COLUMN IF EXISTS recurring_event_id;
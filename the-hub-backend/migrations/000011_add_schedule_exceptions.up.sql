@@ -0,0 +1,8 @@
+-- Exceptions to recurring events are stored as override rows pointing at the series
+ALTER TABLE scheduled_tasks
+  ADD COLUMN IF NOT EXISTS recurring_event_id UUID REFERENCES scheduled_tasks(id) ON DELETE CASCADE,
+  ADD COLUMN IF NOT EXISTS recurrence_id TIMESTAMP WITH TIME ZONE,
+  ADD COLUMN IF NOT EXISTS cancelled BOOLEAN DEFAULT false;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_tasks_recurrence
+  ON scheduled_tasks(recurring_event_id, recurrence_id);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS energy_profiles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    time_slots JSONB DEFAULT '{}',
+    workload JSONB DEFAULT '{}',
+    work_days JSONB DEFAULT '[]',
+    preferred_start_hour INTEGER,
+    preferred_end_hour INTEGER,
+    break_duration INTEGER,
+    learning_enabled BOOLEAN DEFAULT true,
+    learned_time_slots JSONB DEFAULT '{}',
+    category_time_slots JSONB DEFAULT '{}',
+    learned_work_days JSONB DEFAULT '[]',
+    learned_start_hour INTEGER,
+    learned_end_hour INTEGER,
+    learned_sample_count INTEGER DEFAULT 0,
+    learned_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_energy_profiles_user_id ON energy_profiles(user_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS replan_proposals;
+
+ALTER TABLE scheduled_tasks DROP COLUMN IF EXISTS pinned;
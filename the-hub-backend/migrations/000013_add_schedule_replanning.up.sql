@@ -0,0 +1,15 @@
+-- Pinned events stay where they are when the schedule is replanned
+ALTER TABLE scheduled_tasks ADD COLUMN IF NOT EXISTS pinned BOOLEAN DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS replan_proposals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    changes JSONB DEFAULT '[]',
+    status TEXT DEFAULT 'pending',
+    automatic BOOLEAN DEFAULT false,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_replan_proposals_user_id ON replan_proposals(user_id, status);
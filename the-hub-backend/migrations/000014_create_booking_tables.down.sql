@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS bookings;
+DROP TABLE IF EXISTS booking_links;
+
+ALTER TABLE calendar_zones DROP COLUMN IF EXISTS is_bookable;
@@ -0,0 +1,39 @@
+ALTER TABLE calendar_zones ADD COLUMN IF NOT EXISTS is_bookable BOOLEAN DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS booking_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    duration_minutes INTEGER DEFAULT 30,
+    buffer_before INTEGER DEFAULT 0,
+    buffer_after INTEGER DEFAULT 0,
+    daily_limit INTEGER,
+    min_notice INTEGER DEFAULT 60,
+    max_days_ahead INTEGER DEFAULT 30,
+    zone_ids TEXT[],
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_links_user_id ON booking_links(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_booking_links_token ON booking_links(token);
+
+CREATE TABLE IF NOT EXISTS bookings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    booking_link_id UUID NOT NULL REFERENCES booking_links(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scheduled_task_id UUID REFERENCES scheduled_tasks(id) ON DELETE SET NULL,
+    name TEXT NOT NULL,
+    email TEXT NOT NULL,
+    notes TEXT,
+    start TIMESTAMP WITH TIME ZONE NOT NULL,
+    "end" TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookings_booking_link_id ON bookings(booking_link_id, start);
+CREATE INDEX IF NOT EXISTS idx_bookings_user_id ON bookings(user_id);
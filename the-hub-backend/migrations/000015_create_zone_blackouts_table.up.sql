@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS zone_blackouts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    zone_id UUID REFERENCES calendar_zones(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT DEFAULT 'other',
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_zone_blackouts_user_id ON zone_blackouts(user_id, start_date);
+CREATE INDEX IF NOT EXISTS idx_zone_blackouts_zone_id ON zone_blackouts(zone_id);
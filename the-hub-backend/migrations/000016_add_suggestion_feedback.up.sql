@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS ai_recommendations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    task_id UUID REFERENCES tasks(id) ON DELETE CASCADE,
+    suggested_start TIMESTAMP WITH TIME ZONE,
+    suggested_end TIMESTAMP WITH TIME ZONE,
+    confidence REAL,
+    accepted BOOLEAN DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+ALTER TABLE ai_recommendations
+    ADD COLUMN IF NOT EXISTS user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS title TEXT,
+    ADD COLUMN IF NOT EXISTS status TEXT DEFAULT 'pending',
+    ADD COLUMN IF NOT EXISTS final_start TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS final_end TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS scheduled_task_id UUID REFERENCES scheduled_tasks(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS responded_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_ai_recommendations_task_id ON ai_recommendations(task_id);
+CREATE INDEX IF NOT EXISTS idx_ai_recommendations_user_status ON ai_recommendations(user_id, status, created_at);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS srs_parameters;
+DROP TABLE IF EXISTS card_reviews;
+
+ALTER TABLE cards
+    DROP COLUMN IF EXISTS stability,
+    DROP COLUMN IF EXISTS difficulty,
+    DROP COLUMN IF EXISTS lapses;
+
+ALTER TABLE decks
+    DROP COLUMN IF EXISTS scheduler,
+    DROP COLUMN IF EXISTS desired_retention;
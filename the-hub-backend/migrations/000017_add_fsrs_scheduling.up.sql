@@ -0,0 +1,41 @@
+-- Existing SM-2 state is kept as it is; a card's FSRS state is seeded from it the
+-- first time the card is scheduled with FSRS.
+ALTER TABLE decks
+    ADD COLUMN IF NOT EXISTS scheduler TEXT DEFAULT 'sm2',
+    ADD COLUMN IF NOT EXISTS desired_retention DOUBLE PRECISION DEFAULT 0.9;
+
+ALTER TABLE cards
+    ADD COLUMN IF NOT EXISTS stability DOUBLE PRECISION DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS difficulty DOUBLE PRECISION DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS lapses BIGINT DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS card_reviews (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    card_id UUID NOT NULL,
+    deck_id UUID NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating INTEGER NOT NULL,
+    quality INTEGER NOT NULL,
+    scheduler TEXT NOT NULL,
+    first_review BOOLEAN DEFAULT false,
+    elapsed_days INTEGER DEFAULT 0,
+    "interval" INTEGER DEFAULT 1,
+    stability DOUBLE PRECISION,
+    difficulty DOUBLE PRECISION,
+    reviewed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_reviews_card_id ON card_reviews(card_id, reviewed_at);
+CREATE INDEX IF NOT EXISTS idx_card_reviews_user_id ON card_reviews(user_id, reviewed_at);
+
+CREATE TABLE IF NOT EXISTS srs_parameters (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    weights JSONB NOT NULL,
+    reviews INTEGER DEFAULT 0,
+    loss_before DOUBLE PRECISION,
+    loss_after DOUBLE PRECISION,
+    fitted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_card_reviews_deck_id;
+
+ALTER TABLE decks
+    DROP COLUMN IF EXISTS leech_threshold;
+
+ALTER TABLE cards
+    DROP COLUMN IF EXISTS suspended;
+
+ALTER TABLE card_reviews
+    DROP COLUMN IF EXISTS interval_before,
+    DROP COLUMN IF EXISTS time_taken_ms;
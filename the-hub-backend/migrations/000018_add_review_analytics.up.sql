@@ -0,0 +1,11 @@
+ALTER TABLE card_reviews
+    ADD COLUMN IF NOT EXISTS interval_before INTEGER DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS time_taken_ms INTEGER DEFAULT 0;
+
+ALTER TABLE cards
+    ADD COLUMN IF NOT EXISTS suspended BOOLEAN DEFAULT false;
+
+ALTER TABLE decks
+    ADD COLUMN IF NOT EXISTS leech_threshold INTEGER DEFAULT 8;
+
+CREATE INDEX IF NOT EXISTS idx_card_reviews_deck_id ON card_reviews(deck_id, reviewed_at);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_cards_note_id;
+
+ALTER TABLE cards
+    DROP COLUMN IF EXISTS note_id,
+    DROP COLUMN IF EXISTS template,
+    DROP COLUMN IF EXISTS card_type;
+
+DROP TABLE IF EXISTS card_notes;
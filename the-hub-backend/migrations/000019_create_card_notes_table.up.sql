@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS card_notes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    deck_id UUID NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+    model TEXT NOT NULL DEFAULT 'basic',
+    front TEXT NOT NULL,
+    back TEXT,
+    extra TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_notes_deck_id ON card_notes(deck_id);
+CREATE INDEX IF NOT EXISTS idx_card_notes_deleted_at ON card_notes(deleted_at);
+
+ALTER TABLE cards
+    ADD COLUMN IF NOT EXISTS note_id UUID REFERENCES card_notes(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS template TEXT,
+    ADD COLUMN IF NOT EXISTS card_type TEXT DEFAULT 'basic';
+
+CREATE INDEX IF NOT EXISTS idx_cards_note_id ON cards(note_id);
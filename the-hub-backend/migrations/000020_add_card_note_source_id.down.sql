@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_card_notes_source_id;
+
+ALTER TABLE card_notes
+    DROP COLUMN IF EXISTS source_id;
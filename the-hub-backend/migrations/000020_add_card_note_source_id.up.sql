@@ -0,0 +1,4 @@
+ALTER TABLE card_notes
+    ADD COLUMN IF NOT EXISTS source_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_card_notes_source_id ON card_notes(source_id);
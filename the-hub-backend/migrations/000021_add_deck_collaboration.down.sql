@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS card_progress;
+DROP TABLE IF EXISTS deck_invites;
+DROP TABLE IF EXISTS deck_users;
@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS deck_users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    deck_id UUID NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deck_users_deck_user ON deck_users(deck_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_deck_users_user_id ON deck_users(user_id);
+
+CREATE TABLE IF NOT EXISTS deck_invites (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    deck_id UUID NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+    inviter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    invitee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    responded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_deck_invites_deck_id ON deck_invites(deck_id);
+CREATE INDEX IF NOT EXISTS idx_deck_invites_invitee_id ON deck_invites(invitee_id);
+
+CREATE TABLE IF NOT EXISTS card_progress (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    card_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    deck_id UUID NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+    easiness DECIMAL(5,2) DEFAULT 2.5,
+    "interval" BIGINT DEFAULT 1,
+    repetitions BIGINT DEFAULT 0,
+    stability DOUBLE PRECISION DEFAULT 0,
+    difficulty DOUBLE PRECISION DEFAULT 0,
+    lapses BIGINT DEFAULT 0,
+    suspended BOOLEAN DEFAULT FALSE,
+    last_reviewed TIMESTAMP WITH TIME ZONE,
+    next_review TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_card_progress_card_user ON card_progress(card_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_card_progress_deck_id ON card_progress(deck_id);
+CREATE INDEX IF NOT EXISTS idx_card_progress_next_review ON card_progress(next_review);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS deck_reports;
+DROP TABLE IF EXISTS deck_ratings;
+
+DROP INDEX IF EXISTS idx_decks_is_public;
+DROP INDEX IF EXISTS idx_decks_cloned_from_id;
+
+ALTER TABLE decks DROP COLUMN IF EXISTS cloned_from_id;
+ALTER TABLE decks DROP COLUMN IF EXISTS description;
@@ -0,0 +1,33 @@
+ALTER TABLE decks ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE decks ADD COLUMN IF NOT EXISTS cloned_from_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_decks_cloned_from_id ON decks(cloned_from_id);
+CREATE INDEX IF NOT EXISTS idx_decks_is_public ON decks(is_public) WHERE is_public;
+
+CREATE TABLE IF NOT EXISTS deck_ratings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    deck_id UUID NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    stars INTEGER NOT NULL CHECK (stars >= 1 AND stars <= 5),
+    review TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deck_ratings_deck_user ON deck_ratings(deck_id, user_id);
+
+CREATE TABLE IF NOT EXISTS deck_reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    deck_id UUID NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'offensive', 'copyright', 'misleading', 'other')),
+    details TEXT,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'reviewed', 'actioned', 'dismissed')),
+    admin_response TEXT,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_deck_reports_deck_id ON deck_reports(deck_id);
+CREATE INDEX IF NOT EXISTS idx_deck_reports_status ON deck_reports(status);
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_card_reviews_study_session_id;
+ALTER TABLE card_reviews DROP COLUMN IF EXISTS study_session_id;
+
+DROP INDEX IF EXISTS idx_study_sessions_live;
+DROP INDEX IF EXISTS idx_study_sessions_user_started;
+
+ALTER TABLE study_sessions
+    DROP COLUMN IF EXISTS paused_seconds,
+    DROP COLUMN IF EXISTS paused_at,
+    DROP COLUMN IF EXISTS notes,
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS deck_id;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS study_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    topic_id UUID,
+    task_id UUID,
+    duration_min BIGINT,
+    started_at TIMESTAMP WITH TIME ZONE,
+    ended_at TIMESTAMP WITH TIME ZONE
+);
+
+ALTER TABLE study_sessions
+    ADD COLUMN IF NOT EXISTS deck_id UUID REFERENCES decks(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'completed',
+    ADD COLUMN IF NOT EXISTS notes TEXT,
+    ADD COLUMN IF NOT EXISTS paused_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS paused_seconds BIGINT DEFAULT 0;
+
+ALTER TABLE study_sessions ALTER COLUMN ended_at DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_study_sessions_user_started ON study_sessions(user_id, started_at);
+-- A user has at most one live session, which reviews are linked to
+CREATE UNIQUE INDEX IF NOT EXISTS idx_study_sessions_live ON study_sessions(user_id) WHERE status IN ('active', 'paused');
+
+ALTER TABLE card_reviews ADD COLUMN IF NOT EXISTS study_session_id UUID REFERENCES study_sessions(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_card_reviews_study_session_id ON card_reviews(study_session_id);
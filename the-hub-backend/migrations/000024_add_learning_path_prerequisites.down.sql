@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_learning_path_topics_topic_id;
+DROP INDEX IF EXISTS idx_learning_path_topics_path_topic;
+DROP INDEX IF EXISTS idx_learning_paths_user_id;
+
+ALTER TABLE learning_path_topics ADD COLUMN IF NOT EXISTS prerequisite_id UUID;
+UPDATE learning_path_topics SET prerequisite_id = (prerequisites->>0)::uuid
+    WHERE jsonb_array_length(prerequisites) > 0;
+ALTER TABLE learning_path_topics DROP COLUMN IF EXISTS prerequisites;
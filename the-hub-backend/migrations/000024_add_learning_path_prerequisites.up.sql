@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS learning_paths (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS learning_path_topics (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    learning_path_id UUID NOT NULL REFERENCES learning_paths(id) ON DELETE CASCADE,
+    topic_id UUID NOT NULL,
+    order_index BIGINT NOT NULL DEFAULT 0
+);
+
+-- A topic can wait on several others in its path, not just one
+ALTER TABLE learning_path_topics ADD COLUMN IF NOT EXISTS prerequisites JSONB NOT NULL DEFAULT '[]';
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'learning_path_topics' AND column_name = 'prerequisite_id') THEN
+        UPDATE learning_path_topics SET prerequisites = jsonb_build_array(prerequisite_id::text)
+            WHERE prerequisite_id IS NOT NULL;
+        ALTER TABLE learning_path_topics DROP COLUMN prerequisite_id;
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_learning_paths_user_id ON learning_paths(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_learning_path_topics_path_topic ON learning_path_topics(learning_path_id, topic_id);
+CREATE INDEX IF NOT EXISTS idx_learning_path_topics_topic_id ON learning_path_topics(topic_id);
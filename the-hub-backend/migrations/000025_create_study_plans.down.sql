@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_scheduled_tasks_study_plan_id;
+ALTER TABLE scheduled_tasks DROP COLUMN IF EXISTS study_plan_id;
+
+DROP TABLE IF EXISTS study_plans;
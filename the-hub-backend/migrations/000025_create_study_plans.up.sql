@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS study_plans (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    topic_id UUID,
+    learning_path_id UUID REFERENCES learning_paths(id) ON DELETE SET NULL,
+    title TEXT NOT NULL,
+    deadline TIMESTAMP WITH TIME ZONE NOT NULL,
+    effort_minutes BIGINT NOT NULL CHECK (effort_minutes > 0),
+    minutes_per_day BIGINT DEFAULT 0,
+    decks JSONB NOT NULL DEFAULT '[]',
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed')),
+    studied_minutes BIGINT DEFAULT 0,
+    unplanned_minutes BIGINT DEFAULT 0,
+    planned_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_study_plans_user_id ON study_plans(user_id);
+CREATE INDEX IF NOT EXISTS idx_study_plans_topic_id ON study_plans(topic_id);
+CREATE INDEX IF NOT EXISTS idx_study_plans_learning_path_id ON study_plans(learning_path_id);
+
+-- Blocks a study plan placed, replaced whenever the plan is planned again
+ALTER TABLE scheduled_tasks ADD COLUMN IF NOT EXISTS study_plan_id UUID REFERENCES study_plans(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_tasks_study_plan_id ON scheduled_tasks(study_plan_id);
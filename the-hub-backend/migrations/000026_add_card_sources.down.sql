@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_cards_source_id;
+
+ALTER TABLE cards DROP COLUMN IF EXISTS source_stale;
+ALTER TABLE cards DROP COLUMN IF EXISTS source_hash;
+ALTER TABLE cards DROP COLUMN IF EXISTS source_id;
+ALTER TABLE cards DROP COLUMN IF EXISTS source_type;
@@ -0,0 +1,7 @@
+-- Where a generated card came from, so edits to its source can flag it as stale
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS source_type TEXT;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS source_id UUID;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS source_hash TEXT;
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS source_stale BOOLEAN DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_cards_source_id ON cards(source_id);
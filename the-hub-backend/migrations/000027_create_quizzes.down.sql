@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS quiz_attempts;
+DROP TABLE IF EXISTS quizzes;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS quizzes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    deck_id UUID REFERENCES decks(id) ON DELETE SET NULL,
+    topic_id UUID,
+    title TEXT NOT NULL,
+    questions JSONB NOT NULL DEFAULT '[]',
+    question_count BIGINT DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_quizzes_user_id ON quizzes(user_id);
+CREATE INDEX IF NOT EXISTS idx_quizzes_deck_id ON quizzes(deck_id);
+CREATE INDEX IF NOT EXISTS idx_quizzes_topic_id ON quizzes(topic_id);
+
+CREATE TABLE IF NOT EXISTS quiz_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    quiz_id UUID NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    results JSONB NOT NULL DEFAULT '[]',
+    correct BIGINT DEFAULT 0,
+    total BIGINT DEFAULT 0,
+    score DOUBLE PRECISION DEFAULT 0,
+    cards_shortened BIGINT DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_quiz_attempts_quiz_id ON quiz_attempts(quiz_id);
+CREATE INDEX IF NOT EXISTS idx_quiz_attempts_user_id ON quiz_attempts(user_id);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_cards_tags;
+
+ALTER TABLE card_progress DROP COLUMN IF EXISTS buried_until;
+ALTER TABLE cards DROP COLUMN IF EXISTS buried_until;
+ALTER TABLE cards DROP COLUMN IF EXISTS tags;
+
+DROP INDEX IF EXISTS idx_decks_parent_id;
+
+ALTER TABLE decks DROP COLUMN IF EXISTS reviews_per_day;
+ALTER TABLE decks DROP COLUMN IF EXISTS new_cards_per_day;
+ALTER TABLE decks DROP COLUMN IF EXISTS parent_id;
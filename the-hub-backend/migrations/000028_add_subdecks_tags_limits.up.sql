@@ -0,0 +1,13 @@
+-- Sub-decks and daily limits on new cards and reviews, 0 for no limit
+ALTER TABLE decks ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES decks(id) ON DELETE SET NULL;
+ALTER TABLE decks ADD COLUMN IF NOT EXISTS new_cards_per_day INTEGER DEFAULT 20;
+ALTER TABLE decks ADD COLUMN IF NOT EXISTS reviews_per_day INTEGER DEFAULT 200;
+
+CREATE INDEX IF NOT EXISTS idx_decks_parent_id ON decks(parent_id);
+
+-- Card tags, and cards buried until the next day
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS buried_until TIMESTAMPTZ;
+ALTER TABLE card_progress ADD COLUMN IF NOT EXISTS buried_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_cards_tags ON cards USING GIN (tags);
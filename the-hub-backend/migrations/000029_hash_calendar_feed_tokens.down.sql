@@ -0,0 +1,10 @@
+-- Tokens cannot be recovered from their hashes, so feeds are switched off and need a
+-- new token to be issued
+ALTER TABLE calendar_feeds ADD COLUMN IF NOT EXISTS token TEXT;
+UPDATE calendar_feeds SET token = token_hash, is_active = false WHERE token IS NULL;
+ALTER TABLE calendar_feeds ALTER COLUMN token SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_calendar_feeds_token_hash;
+ALTER TABLE calendar_feeds DROP COLUMN IF EXISTS token_hash;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_token ON calendar_feeds(token);
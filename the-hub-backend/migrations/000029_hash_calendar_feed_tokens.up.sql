@@ -0,0 +1,16 @@
+-- Keep only a hash of calendar feed tokens; existing subscription URLs keep working
+ALTER TABLE calendar_feeds ADD COLUMN IF NOT EXISTS token_hash TEXT;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'calendar_feeds' AND column_name = 'token') THEN
+        UPDATE calendar_feeds SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex') WHERE token_hash IS NULL;
+    END IF;
+END $$;
+
+ALTER TABLE calendar_feeds ALTER COLUMN token_hash SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_calendar_feeds_token;
+ALTER TABLE calendar_feeds DROP COLUMN IF EXISTS token;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_token_hash ON calendar_feeds(token_hash);
@@ -0,0 +1,129 @@
+package unit
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/anki"
+	"github.com/TheoMKgosi/The-hub/internal/notetype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readFixture reads testdata/anki/sample.apkg: Basic, reversed, cloze and type-in
+// notes in "Spanish" and "Spanish::Verbs", with review, suspended, learning, new and
+// filtered-deck cards and two media files
+func readFixture(t *testing.T) *anki.Package {
+	data, err := os.ReadFile("testdata/anki/sample.apkg")
+	require.NoError(t, err)
+	pkg, err := anki.Read(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	return pkg
+}
+
+func TestReadAnkiPackage(t *testing.T) {
+	pkg := readFixture(t)
+	const spanish, verbs = 1600000000001, 1600000000002
+	day := func(d int) time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, d) }
+
+	names := make([]string, len(pkg.Decks))
+	for i, deck := range pkg.Decks {
+		names[i] = deck.Name
+	}
+	assert.Equal(t, []string{"Default", "Filtered Deck 1", "Spanish", "Spanish::Verbs"}, names)
+	require.Len(t, pkg.Notes, 4)
+
+	basic := pkg.Notes[0]
+	assert.Equal(t, notetype.Basic, basic.Type)
+	assert.Equal(t, "What is the capital of France?", basic.Front)
+	assert.Equal(t, `Paris<br><img src="paris.jpg">`, basic.Back)
+	assert.Equal(t, []string{"geo"}, basic.Tags)
+	assert.Equal(t, []anki.Card{{DeckID: spanish, Template: notetype.TemplateForward, Schedule: anki.Schedule{
+		Interval: 30, Easiness: 2.3, Repetitions: 6, Lapses: 1, LastReview: day(90), Due: day(120),
+	}}}, basic.Cards)
+
+	reversed := pkg.Notes[1]
+	assert.Equal(t, notetype.BasicReversed, reversed.Type)
+	require.Len(t, reversed.Cards, 2)
+	assert.Equal(t, verbs, int(reversed.Cards[0].DeckID))
+	assert.True(t, reversed.Cards[0].Schedule.Suspended)
+	assert.Equal(t, 3, reversed.Cards[0].Schedule.Lapses)
+	assert.Equal(t, notetype.TemplateReverse, reversed.Cards[1].Template)
+	assert.Equal(t, anki.Schedule{New: true}, reversed.Cards[1].Schedule)
+
+	cloze := pkg.Notes[2]
+	assert.Equal(t, notetype.Cloze, cloze.Type)
+	assert.Equal(t, "", cloze.Back)
+	assert.Equal(t, "[sound:hola audio.mp3]", cloze.Extra, "a cloze note's other fields are its extra")
+	require.Len(t, cloze.Cards, 2)
+	assert.Equal(t, "c1", cloze.Cards[0].Template)
+	assert.Equal(t, spanish, int(cloze.Cards[0].DeckID), "cards in a filtered deck belong to their original deck")
+	assert.Equal(t, day(110), cloze.Cards[0].Schedule.Due)
+	assert.Equal(t, "c2", cloze.Cards[1].Template)
+	assert.Equal(t, time.Date(2025, 7, 20, 4, 0, 0, 0, time.UTC), cloze.Cards[1].Schedule.Due, "learning cards are due at a time")
+
+	assert.Equal(t, notetype.TypeIn, pkg.Notes[3].Type)
+
+	assert.Equal(t, map[string][]byte{
+		"paris.jpg":      []byte("\xff\xd8\xff\xe0fake-jpeg"),
+		"hola audio.mp3": []byte("ID3fake-mp3"),
+	}, pkg.Media)
+}
+
+func TestAnkiPackageRoundTrip(t *testing.T) {
+	pkg := readFixture(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, anki.Write(&buf, pkg))
+	again, err := anki.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	// Learning cards are written as review cards due on their day
+	learning := &pkg.Notes[2].Cards[1].Schedule
+	learning.Due = learning.Due.Truncate(24 * time.Hour)
+	learning.LastReview = learning.Due.AddDate(0, 0, -learning.Interval)
+
+	assert.Equal(t, pkg.Created, again.Created)
+	assert.Equal(t, pkg.Decks, again.Decks)
+	assert.Equal(t, pkg.Notes, again.Notes)
+	assert.Equal(t, pkg.Media, again.Media)
+}
+
+func TestWriteAnkiPackageFromNotes(t *testing.T) {
+	due := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	pkg := &anki.Package{
+		Created: time.Date(2025, 3, 1, 8, 30, 0, 0, time.UTC),
+		Decks:   []anki.Deck{{ID: 2, Name: "Chemistry"}},
+		Notes: []anki.Note{
+			{GUID: "water", Type: notetype.Cloze, Front: "{{c1::H2O}} is water", Extra: "Formula", Cards: []anki.Card{
+				{DeckID: 2, Template: "c1", Schedule: anki.Schedule{Interval: 9, Easiness: 2.6, Repetitions: 3, LastReview: due.AddDate(0, 0, -9), Due: due}},
+			}},
+			{GUID: "salt", Type: notetype.BasicReversed, Front: "NaCl", Back: "salt", Cards: []anki.Card{
+				{DeckID: 2, Template: notetype.TemplateForward, Schedule: anki.Schedule{New: true}},
+				{DeckID: 2, Template: notetype.TemplateReverse, Schedule: anki.Schedule{New: true, Suspended: true}},
+			}},
+		},
+		Media: map[string][]byte{},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, anki.Write(&buf, pkg))
+	again, err := anki.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	assert.Equal(t, pkg.Notes, again.Notes)
+	assert.Equal(t, []anki.Deck{{ID: 2, Name: "Chemistry"}, {ID: 1, Name: "Default"}}, again.Decks)
+
+	pkg.Notes[0].Cards[0].Template = "reverse"
+	assert.Error(t, anki.Write(&bytes.Buffer{}, pkg), "cloze notes have no reverse card")
+}
+
+func TestRewriteAnkiMedia(t *testing.T) {
+	text := `<img src="a b.png"> <img src='c.gif'> <img src=d.jpg> [sound:e f.mp3]`
+	assert.Equal(t, []string{"a b.png", "c.gif", "d.jpg", "e f.mp3"}, anki.MediaRefs(text))
+
+	rewritten := anki.RewriteMedia(text, func(name string) string { return "/uploads/flashcards/u/" + name })
+	assert.Equal(t, `<img src="/uploads/flashcards/u/a b.png"> <img src='/uploads/flashcards/u/c.gif'> <img src=/uploads/flashcards/u/d.jpg> [sound:/uploads/flashcards/u/e f.mp3]`, rewritten)
+}
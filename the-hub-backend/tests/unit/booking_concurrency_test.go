@@ -0,0 +1,74 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/handlers"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentBookingsThroughTwoLinks(t *testing.T) {
+	db := openCalendarSyncDB(t)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.CalendarZone{}, &models.ZoneBlackout{},
+		&models.RecurrenceRule{}, &models.BookingLink{}, &models.Booking{}))
+	config.SetTestDB(db)
+
+	owner := models.User{Name: "Owner", Email: uuid.NewString() + "@example.com", Password: "x"}
+	require.NoError(t, db.Create(&owner).Error)
+	zone := models.CalendarZone{
+		UserID:     owner.ID,
+		Name:       "Office hours",
+		Category:   "work",
+		StartTime:  time.Date(2000, 1, 1, 8, 0, 0, 0, time.UTC),
+		EndTime:    time.Date(2000, 1, 1, 18, 0, 0, 0, time.UTC),
+		IsActive:   true,
+		IsBookable: true,
+	}
+	require.NoError(t, db.Create(&zone).Error)
+
+	// Two links of the same owner offer the same free time
+	var tokens []string
+	for _, title := range []string{"Intro call", "Mentoring"} {
+		link := models.BookingLink{UserID: owner.ID, Token: uuid.NewString(), Title: title, DurationMinutes: 30, MaxDaysAhead: 30, MinNotice: 60, IsActive: true}
+		require.NoError(t, db.Create(&link).Error)
+		tokens = append(tokens, link.Token)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/book/:token", handlers.CreateBooking)
+
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1)
+	start := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 10, 0, 0, 0, time.UTC)
+	body := `{"start": "` + start.Format(time.RFC3339) + `", "name": "Guest", "email": "guest@example.com"}`
+
+	codes := make([]int, len(tokens))
+	var wg sync.WaitGroup
+	for i, token := range tokens {
+		wg.Add(1)
+		go func(i int, token string) {
+			defer wg.Done()
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/book/"+token, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(recorder, req)
+			codes[i] = recorder.Code
+		}(i, token)
+	}
+	wg.Wait()
+
+	assert.ElementsMatch(t, []int{http.StatusCreated, http.StatusConflict}, codes)
+	var events int64
+	db.Model(&models.ScheduledTask{}).Where("user_id = ? AND start = ?", owner.ID, start).Count(&events)
+	assert.Equal(t, int64(1), events, "only one booking holds the time")
+}
@@ -0,0 +1,109 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/booking"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bookingAt(day, hour, minute int) time.Time {
+	return time.Date(2025, 3, day, hour, minute, 0, 0, schedulerNow.Location())
+}
+
+// bookingZone is bookable 09:00-12:00 on Mondays and Tuesdays
+func bookingZone() models.CalendarZone {
+	return models.CalendarZone{
+		ID:         uuid.New(),
+		Name:       "Office hours",
+		StartTime:  bookingAt(3, 9, 0),
+		EndTime:    bookingAt(3, 12, 0),
+		DaysOfWeek: models.NewWeekdaySet(time.Monday, time.Tuesday),
+		IsActive:   true,
+		IsBookable: true,
+	}
+}
+
+func slotStarts(slots []booking.Interval) []string {
+	starts := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		starts = append(starts, slot.Start.In(schedulerNow.Location()).Format("Mon 15:04"))
+	}
+	return starts
+}
+
+func TestBookingBusyMergesOverlappingEvents(t *testing.T) {
+	loc := schedulerNow.Location()
+	schedule := []models.ScheduledTask{
+		{ID: uuid.New(), Start: bookingAt(3, 10, 0), End: bookingAt(3, 11, 0)},
+		{ID: uuid.New(), Start: bookingAt(3, 9, 0), End: bookingAt(3, 10, 0)},
+		{ID: uuid.New(), Start: bookingAt(3, 10, 30), End: bookingAt(3, 10, 45)},
+		{ID: uuid.New(), Start: bookingAt(3, 14, 0), End: bookingAt(3, 15, 0)},
+	}
+
+	busy := booking.Busy(schedule, bookingAt(3, 0, 0), bookingAt(4, 0, 0), loc)
+	require.Len(t, busy, 2)
+	assert.True(t, busy[0].Start.Equal(bookingAt(3, 9, 0)))
+	assert.True(t, busy[0].End.Equal(bookingAt(3, 11, 0)))
+	assert.True(t, busy[1].Start.Equal(bookingAt(3, 14, 0)))
+}
+
+func TestBookingSlots(t *testing.T) {
+	loc := schedulerNow.Location()
+	zones := []models.CalendarZone{bookingZone()}
+	busy := booking.Busy([]models.ScheduledTask{
+		{ID: uuid.New(), Start: bookingAt(3, 10, 0), End: bookingAt(3, 10, 30)},
+	}, bookingAt(3, 0, 0), bookingAt(5, 0, 0), loc)
+	rules := booking.Rules{
+		Duration:     30 * time.Minute,
+		BufferBefore: 15 * time.Minute,
+		BufferAfter:  15 * time.Minute,
+		Earliest:     bookingAt(3, 9, 0),
+	}
+
+	slots := booking.Slots(zones, busy, nil, bookingAt(3, 0, 0), bookingAt(4, 0, 0), loc, rules)
+	// Slots keep 15 minutes clear of the 10:00 event and end by the zone's 12:00 close
+	assert.Equal(t, []string{"Mon 09:00", "Mon 09:15", "Mon 10:45", "Mon 11:00", "Mon 11:15", "Mon 11:30"}, slotStarts(slots))
+
+	// Minimum notice hides the morning's earlier slots
+	rules.Earliest = bookingAt(3, 11, 5)
+	slots = booking.Slots(zones, busy, nil, bookingAt(3, 0, 0), bookingAt(4, 0, 0), loc, rules)
+	assert.Equal(t, []string{"Mon 11:15", "Mon 11:30"}, slotStarts(slots))
+
+	// Wednesday is outside the zone's days
+	slots = booking.Slots(zones, busy, nil, bookingAt(5, 0, 0), bookingAt(6, 0, 0), loc, rules)
+	assert.Empty(t, slots)
+}
+
+func TestBookingSlotsDailyLimit(t *testing.T) {
+	loc := schedulerNow.Location()
+	zones := []models.CalendarZone{bookingZone()}
+	rules := booking.Rules{Duration: time.Hour, DailyLimit: 2, Earliest: bookingAt(3, 0, 0)}
+	booked := map[string]int{booking.DayKey(bookingAt(3, 9, 0), loc): 2}
+
+	slots := booking.Slots(zones, nil, booked, bookingAt(3, 0, 0), bookingAt(5, 0, 0), loc, rules)
+	require.NotEmpty(t, slots)
+	for _, slot := range slots {
+		assert.Equal(t, "2025-03-04", booking.DayKey(slot.Start, loc), "Monday is fully booked")
+	}
+}
+
+func TestBookingIsBookable(t *testing.T) {
+	loc := schedulerNow.Location()
+	zones := []models.CalendarZone{bookingZone()}
+	busy := []booking.Interval{{Start: bookingAt(3, 10, 0), End: bookingAt(3, 11, 0)}}
+	rules := booking.Rules{Duration: 30 * time.Minute, BufferAfter: 10 * time.Minute, Earliest: bookingAt(3, 8, 0)}
+
+	assert.True(t, booking.IsBookable(zones, busy, nil, bookingAt(3, 9, 15), loc, rules))
+	assert.False(t, booking.IsBookable(zones, busy, nil, bookingAt(3, 9, 30), loc, rules), "the buffer runs into the 10:00 event")
+	assert.False(t, booking.IsBookable(zones, busy, nil, bookingAt(3, 9, 10), loc, rules), "not on a slot boundary")
+	assert.False(t, booking.IsBookable(zones, busy, nil, bookingAt(3, 11, 45), loc, rules), "runs past the zone")
+
+	zones[0].IsBookable = false
+	link := models.BookingLink{}
+	assert.False(t, link.IncludesZone(&zones[0]), "zones must be marked bookable")
+}
@@ -0,0 +1,124 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/caldav"
+	"github.com/TheoMKgosi/The-hub/internal/ical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePropfind(t *testing.T) {
+	req, err := caldav.ParsePropfind(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.True(t, req.AllProp)
+
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:cs="http://calendarserver.org/ns/">
+  <d:prop><d:getetag/><cs:getctag/></d:prop>
+</d:propfind>`
+	req, err = caldav.ParsePropfind(strings.NewReader(body))
+	require.NoError(t, err)
+	assert.False(t, req.AllProp)
+	assert.Equal(t, []xml.Name{
+		caldav.Name(caldav.NSDAV, "getetag"),
+		caldav.Name(caldav.NSCalendarServer, "getctag"),
+	}, req.PropNames)
+
+	_, err = caldav.ParsePropfind(strings.NewReader("<not-xml"))
+	assert.Error(t, err)
+}
+
+func TestParseReport(t *testing.T) {
+	query := `<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT">
+        <c:time-range start="20250101T000000Z" end="20250201T000000Z"/>
+      </c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`
+	req, err := caldav.ParseReport(strings.NewReader(query))
+	require.NoError(t, err)
+	assert.Equal(t, caldav.ReportCalendarQuery, req.Kind)
+	assert.Equal(t, "VEVENT", req.Component)
+	require.NotNil(t, req.Start)
+	require.NotNil(t, req.End)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), *req.Start)
+	assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), *req.End)
+	assert.Len(t, req.PropNames, 2)
+
+	multiget := `<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/></d:prop>
+  <d:href>/caldav/calendars/tasks/a.ics</d:href>
+  <d:href>/caldav/calendars/tasks/b.ics</d:href>
+</c:calendar-multiget>`
+	req, err = caldav.ParseReport(strings.NewReader(multiget))
+	require.NoError(t, err)
+	assert.Equal(t, caldav.ReportCalendarMultiget, req.Kind)
+	assert.Equal(t, []string{"/caldav/calendars/tasks/a.ics", "/caldav/calendars/tasks/b.ics"}, req.Hrefs)
+}
+
+func TestWriteMultistatus(t *testing.T) {
+	available := []caldav.Prop{
+		caldav.TextProp(caldav.Name(caldav.NSDAV, "getetag"), `"abc"`),
+		caldav.ElementsProp(caldav.Name(caldav.NSDAV, "resourcetype"),
+			caldav.Name(caldav.NSDAV, "collection"), caldav.Name(caldav.NSCalDAV, "calendar")),
+	}
+	found, notFound := caldav.Select(available, &caldav.PropfindRequest{PropNames: []xml.Name{
+		caldav.Name(caldav.NSDAV, "resourcetype"),
+		caldav.Name("http://example.com/ns/", "color"),
+	}})
+
+	var buf bytes.Buffer
+	require.NoError(t, caldav.WriteMultistatus(&buf, []caldav.Response{
+		{Href: "/caldav/calendars/schedule/", Found: found, NotFound: notFound},
+		{Href: "/caldav/calendars/schedule/missing.ics", Status: http.StatusNotFound},
+	}))
+	out := buf.String()
+
+	assert.Contains(t, out, "<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>")
+	assert.Contains(t, out, `<x:color xmlns:x="http://example.com/ns/"/>`)
+	assert.Contains(t, out, "HTTP/1.1 404 Not Found")
+	assert.NotContains(t, out, "getetag")
+
+	// The output must be well-formed XML
+	var parsed struct {
+		Responses []struct {
+			Href string `xml:"DAV: href"`
+		} `xml:"DAV: response"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &parsed))
+	require.Len(t, parsed.Responses, 2)
+	assert.Equal(t, "/caldav/calendars/schedule/", parsed.Responses[0].Href)
+}
+
+func TestParseTodos(t *testing.T) {
+	data := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:todo-1@example.com\r\n" +
+		"SUMMARY:Write report\r\nDUE;VALUE=DATE:20250310\r\nSTATUS:COMPLETED\r\n" +
+		"COMPLETED:20250309T120000Z\r\nPRIORITY:1\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	cal, err := ical.Parse(strings.NewReader(data))
+	require.NoError(t, err)
+
+	todos, err := ical.ParseTodos(cal, time.UTC)
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+
+	todo := todos[0]
+	assert.Equal(t, "todo-1@example.com", todo.UID)
+	assert.Equal(t, "Write report", todo.Summary)
+	assert.Equal(t, "COMPLETED", todo.Status)
+	assert.Equal(t, 1, todo.Priority)
+	require.NotNil(t, todo.Due)
+	assert.Equal(t, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), *todo.Due)
+	require.NotNil(t, todo.Completed)
+	assert.Equal(t, time.Date(2025, 3, 9, 12, 0, 0, 0, time.UTC), *todo.Completed)
+}
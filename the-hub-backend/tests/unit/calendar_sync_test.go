@@ -0,0 +1,461 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/calendarsync"
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeEvents is an in-memory EventsService
+type fakeEvents struct {
+	events    map[string]calendarsync.RemoteEvent
+	changed   []string
+	syncToken string
+	nextID    int
+	clock     time.Time
+}
+
+func newFakeEvents() *fakeEvents {
+	return &fakeEvents{
+		events: map[string]calendarsync.RemoteEvent{},
+		clock:  time.Now().Add(-time.Hour),
+	}
+}
+
+func (f *fakeEvents) touch(event calendarsync.RemoteEvent) calendarsync.RemoteEvent {
+	f.clock = f.clock.Add(time.Second)
+	event.Updated = f.clock
+	f.events[event.ID] = event
+	f.changed = append(f.changed, event.ID)
+	return event
+}
+
+func (f *fakeEvents) List(ctx context.Context, calendarID, syncToken, pageToken string) (*calendarsync.Page, error) {
+	if syncToken != "" && syncToken != f.syncToken {
+		return nil, calendarsync.ErrSyncTokenExpired
+	}
+
+	var items []calendarsync.RemoteEvent
+	if syncToken == "" {
+		for _, event := range f.events {
+			items = append(items, event)
+		}
+	} else {
+		for _, id := range f.changed {
+			items = append(items, f.events[id])
+		}
+	}
+	f.changed = nil
+	f.syncToken = fmt.Sprintf("token-%d", f.clock.UnixNano())
+	return &calendarsync.Page{Events: items, SyncToken: f.syncToken}, nil
+}
+
+func (f *fakeEvents) Insert(ctx context.Context, calendarID string, event calendarsync.RemoteEvent) (*calendarsync.RemoteEvent, error) {
+	f.nextID++
+	event.ID = fmt.Sprintf("local-%d", f.nextID)
+	saved := f.touch(event)
+	return &saved, nil
+}
+
+func (f *fakeEvents) Update(ctx context.Context, calendarID, eventID string, event calendarsync.RemoteEvent) (*calendarsync.RemoteEvent, error) {
+	if _, ok := f.events[eventID]; !ok {
+		return nil, calendarsync.ErrEventNotFound
+	}
+	event.ID = eventID
+	saved := f.touch(event)
+	return &saved, nil
+}
+
+func (f *fakeEvents) Delete(ctx context.Context, calendarID, eventID string) error {
+	event, ok := f.events[eventID]
+	if !ok || event.Cancelled {
+		return calendarsync.ErrEventNotFound
+	}
+	event.Cancelled = true
+	f.touch(event)
+	return nil
+}
+
+func remoteEvent(id, title string, start time.Time) calendarsync.RemoteEvent {
+	return calendarsync.RemoteEvent{ID: id, Title: title, Start: start, End: start.Add(time.Hour)}
+}
+
+func TestResolveConflict(t *testing.T) {
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, calendarsync.WinnerRemote, calendarsync.ResolveConflict(base, base.Add(time.Second)))
+	assert.Equal(t, calendarsync.WinnerLocal, calendarsync.ResolveConflict(base.Add(time.Second), base))
+	assert.Equal(t, calendarsync.WinnerLocal, calendarsync.ResolveConflict(base, base))
+}
+
+func TestRemoteEventBusy(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	assert.True(t, remoteEvent("a", "Meeting", start).Busy())
+
+	free := remoteEvent("b", "Focus", start)
+	free.Free = true
+	assert.False(t, free.Busy())
+
+	allDay := remoteEvent("c", "Holiday", start)
+	allDay.AllDay = true
+	assert.False(t, allDay.Busy())
+
+	cancelled := remoteEvent("d", "Cancelled", start)
+	cancelled.Cancelled = true
+	assert.False(t, cancelled.Busy())
+}
+
+func TestGoogleEventsServiceErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Get("syncToken") == "stale":
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"error":{"code":410,"message":"Sync token is no longer valid"}}`))
+		case r.Method == http.MethodGet:
+			assert.NotEmpty(t, r.URL.Query().Get("timeMin"), "full syncs are bounded")
+			w.Write([]byte(`{"items":[{"id":"e1","summary":"Lecture","updated":"2026-03-01T10:00:00.000Z",` +
+				`"start":{"dateTime":"2026-03-02T09:00:00+02:00"},"end":{"dateTime":"2026-03-02T10:00:00+02:00"}},` +
+				`{"id":"e2","status":"cancelled"},` +
+				`{"id":"e3","summary":"Public holiday","start":{"date":"2026-03-21"},"end":{"date":"2026-03-22"}}],"nextSyncToken":"fresh"}`))
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"message":"Not Found"}}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	svc, err := calendar.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithHTTPClient(server.Client()))
+	require.NoError(t, err)
+	loc, err := time.LoadLocation("Africa/Johannesburg")
+	require.NoError(t, err)
+	events := calendarsync.NewGoogleEventsService(svc, loc)
+
+	_, err = events.List(context.Background(), "primary", "stale", "")
+	assert.ErrorIs(t, err, calendarsync.ErrSyncTokenExpired)
+
+	page, err := events.List(context.Background(), "primary", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "fresh", page.SyncToken)
+	require.Len(t, page.Events, 3)
+	assert.Equal(t, "Lecture", page.Events[0].Title)
+	assert.Equal(t, time.Date(2026, 3, 2, 7, 0, 0, 0, time.UTC), page.Events[0].Start)
+	assert.True(t, page.Events[0].Busy())
+	assert.True(t, page.Events[1].Cancelled)
+	// All-day dates are days in the user's timezone, not UTC
+	assert.True(t, page.Events[2].AllDay)
+	assert.True(t, time.Date(2026, 3, 21, 0, 0, 0, 0, loc).Equal(page.Events[2].Start))
+	assert.Equal(t, 24*time.Hour, page.Events[2].End.Sub(page.Events[2].Start))
+
+	err = events.Delete(context.Background(), "primary", "missing")
+	assert.ErrorIs(t, err, calendarsync.ErrEventNotFound)
+}
+
+func TestGraphEventsService(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/me/calendars/cal-1/calendarView/delta" && r.URL.Query().Get("page") == "":
+			assert.NotEmpty(t, r.URL.Query().Get("endDateTime"))
+			fmt.Fprintf(w, `{"value":[{"id":"m1","subject":"Standup","showAs":"busy","lastModifiedDateTime":"2026-03-01T08:00:00.1234567Z",`+
+				`"start":{"dateTime":"2026-03-02T09:00:00.0000000","timeZone":"UTC"},"end":{"dateTime":"2026-03-02T09:15:00.0000000","timeZone":"UTC"}}],`+
+				`"@odata.nextLink":"%s/me/calendars/cal-1/calendarView/delta?page=2"}`, server.URL)
+		case r.Method == http.MethodGet && r.URL.Query().Get("page") == "2":
+			fmt.Fprintf(w, `{"value":[{"id":"m2","@removed":{"reason":"deleted"}},`+
+				`{"id":"m3","subject":"Offsite","start":{"dateTime":"2026-03-03T09:00:00.0000000","timeZone":"W. Europe Standard Time"},`+
+				`"end":{"dateTime":"2026-03-03T17:00:00.0000000","timeZone":"W. Europe Standard Time"}},`+
+				`{"id":"m4","subject":"Holiday","isAllDay":true,"start":{"dateTime":"2026-03-05T00:00:00.0000000","timeZone":"UTC"},`+
+				`"end":{"dateTime":"2026-03-06T00:00:00.0000000","timeZone":"UTC"}}],`+
+				`"@odata.deltaLink":"%s/delta?token=abc"}`, server.URL)
+		case r.Method == http.MethodGet && r.URL.Path == "/delta":
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"error":{"code":"SyncStateNotFound","message":"resync required"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/me/calendars/cal-1/events":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, "Study", body["subject"])
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"new-1","subject":"Study","lastModifiedDateTime":"2026-03-01T09:00:00Z",` +
+				`"start":{"dateTime":"2026-03-04T10:00:00.0000000","timeZone":"UTC"},"end":{"dateTime":"2026-03-04T11:00:00.0000000","timeZone":"UTC"}}`))
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":"ErrorItemNotFound","message":"not found"}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	events := calendarsync.NewGraphEventsService(server.Client(), server.URL, loc)
+	ctx := context.Background()
+
+	page, err := events.List(ctx, "cal-1", "", "")
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.Equal(t, "Standup", page.Events[0].Title)
+	assert.Equal(t, 15*time.Minute, page.Events[0].End.Sub(page.Events[0].Start))
+	assert.Equal(t, 2026, page.Events[0].Updated.Year())
+	require.NotEmpty(t, page.NextPageToken)
+
+	page, err = events.List(ctx, "cal-1", "", page.NextPageToken)
+	require.NoError(t, err)
+	require.Len(t, page.Events, 3)
+	assert.True(t, page.Events[0].Cancelled)
+	// Windows zone names are resolved; 09:00 CET is 08:00 UTC
+	assert.Equal(t, time.Date(2026, 3, 3, 8, 0, 0, 0, time.UTC), page.Events[1].Start)
+	// All-day events are floating, so their dates are days in the user's timezone
+	assert.True(t, page.Events[2].AllDay)
+	assert.True(t, time.Date(2026, 3, 5, 0, 0, 0, 0, loc).Equal(page.Events[2].Start))
+	assert.True(t, time.Date(2026, 3, 6, 0, 0, 0, 0, loc).Equal(page.Events[2].End))
+	assert.Equal(t, server.URL+"/delta?token=abc", page.SyncToken)
+
+	_, err = events.List(ctx, "cal-1", page.SyncToken, "")
+	assert.ErrorIs(t, err, calendarsync.ErrSyncTokenExpired)
+
+	start := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	created, err := events.Insert(ctx, "cal-1", remoteEvent("", "Study", start))
+	require.NoError(t, err)
+	assert.Equal(t, "new-1", created.ID)
+	assert.Equal(t, start, created.Start)
+
+	err = events.Delete(ctx, "cal-1", "gone")
+	assert.ErrorIs(t, err, calendarsync.ErrEventNotFound)
+}
+
+func TestCalendarProviderRegistry(t *testing.T) {
+	registry := calendarsync.NewRegistry(
+		calendarsync.NewGoogleProvider("id", "secret", "http://localhost/callback"),
+		calendarsync.NewMicrosoftProvider("id", "secret", "http://localhost/callback", "common", ""),
+	)
+
+	assert.Equal(t, []models.CalendarProvider{models.ProviderGoogle, models.ProviderOutlook}, registry.Names())
+
+	outlook, err := registry.Get(models.ProviderOutlook)
+	require.NoError(t, err)
+	assert.Contains(t, outlook.OAuthConfig().AuthCodeURL("state"), "login.microsoftonline.com/common")
+
+	_, err = registry.Get(models.ProviderApple)
+	assert.ErrorIs(t, err, calendarsync.ErrUnknownProvider)
+}
+
+func openCalendarSyncDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+		getEnvOrDefault("DB_HOST", "localhost"),
+		getEnvOrDefault("DB_USER", "postgres"),
+		getEnvOrDefault("DB_PASSWORD", "postgres"),
+		getEnvOrDefault("DB_NAME", "the_hub_test"),
+		getEnvOrDefault("DB_PORT", "5432"),
+	)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Skipf("test database unavailable: %v", err)
+	}
+	require.NoError(t, db.AutoMigrate(&models.ScheduledTask{}, &models.CalendarIntegration{},
+		&models.CalendarEventSync{}, &models.CalendarSyncAudit{}, &models.RecurrenceRule{}))
+	return db
+}
+
+func TestCalendarSyncTwoWay(t *testing.T) {
+	db := openCalendarSyncDB(t)
+	config.InitLogger()
+
+	integration := models.CalendarIntegration{
+		UserID:         uuid.New(),
+		Provider:       models.ProviderGoogle,
+		ProviderUserID: "test",
+		AccessToken:    "token",
+		RefreshToken:   "refresh",
+		TokenExpiry:    time.Now().Add(time.Hour),
+		CalendarID:     "primary",
+		IsActive:       true,
+		SyncEnabled:    true,
+	}
+	require.NoError(t, db.Create(&integration).Error)
+
+	remote := newFakeEvents()
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	remote.touch(remoteEvent("r1", "Dentist", start.UTC()))
+
+	local := models.ScheduledTask{Title: "Study", Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour), UserID: integration.UserID}
+	require.NoError(t, db.Create(&local).Error)
+
+	syncer := calendarsync.NewSyncer(db)
+
+	// First run: pull the remote event, push the local one
+	result, err := syncer.Sync(context.Background(), remote, &integration)
+	require.NoError(t, err)
+	assert.True(t, result.FullSync)
+	assert.Equal(t, 1, result.Pulled)
+	assert.Equal(t, 1, result.Pushed)
+
+	var pulled models.ScheduledTask
+	require.NoError(t, db.Where("user_id = ? AND source = ?", integration.UserID, "google").First(&pulled).Error)
+	assert.Equal(t, "Dentist", pulled.Title)
+
+	// Second run: our own push comes back as a change but is recognised as an echo
+	result, err = syncer.Sync(context.Background(), remote, &integration)
+	require.NoError(t, err)
+	assert.False(t, result.FullSync)
+	assert.Zero(t, result.Pulled)
+	assert.Zero(t, result.Pushed)
+
+	// Remote deletion removes the local copy; local deletion removes the remote copy
+	require.NoError(t, remote.Delete(context.Background(), "primary", "r1"))
+	require.NoError(t, db.Delete(&local).Error)
+	result, err = syncer.Sync(context.Background(), remote, &integration)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.DeletedLocal)
+	assert.Equal(t, 1, result.DeletedRemote)
+
+	var remaining int64
+	db.Model(&models.ScheduledTask{}).Where("user_id = ?", integration.UserID).Count(&remaining)
+	assert.Zero(t, remaining)
+
+	var audits int64
+	db.Model(&models.CalendarSyncAudit{}).Where("calendar_integration_id = ?", integration.ID).Count(&audits)
+	assert.Equal(t, int64(4), audits)
+}
+
+func TestCalendarSyncSkipsRecurringSeries(t *testing.T) {
+	db := openCalendarSyncDB(t)
+
+	integration := models.CalendarIntegration{
+		UserID:         uuid.New(),
+		Provider:       models.ProviderGoogle,
+		ProviderUserID: "test",
+		AccessToken:    "token",
+		RefreshToken:   "refresh",
+		TokenExpiry:    time.Now().Add(time.Hour),
+		CalendarID:     "primary",
+		IsActive:       true,
+		SyncEnabled:    true,
+	}
+	require.NoError(t, db.Create(&integration).Error)
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour).UTC()
+	weekly := models.RecurrenceRule{UserID: integration.UserID, Frequency: "weekly", Interval: 1}
+	require.NoError(t, db.Create(&weekly).Error)
+	series := models.ScheduledTask{Title: "Standup", Start: start, End: start.Add(30 * time.Minute), UserID: integration.UserID, RecurrenceRuleID: &weekly.ID}
+	require.NoError(t, db.Create(&series).Error)
+
+	// A moved and a cancelled occurrence of the series
+	moved := start.AddDate(0, 0, 7)
+	cancelled := start.AddDate(0, 0, 14)
+	for _, override := range []models.ScheduledTask{
+		{Title: "Standup (late)", Start: moved.Add(time.Hour), End: moved.Add(90 * time.Minute), RecurringEventID: &series.ID, RecurrenceID: &moved},
+		{Title: "Standup", Start: cancelled, End: cancelled.Add(30 * time.Minute), RecurringEventID: &series.ID, RecurrenceID: &cancelled, Cancelled: true},
+	} {
+		override.UserID = integration.UserID
+		require.NoError(t, db.Create(&override).Error)
+	}
+	single := models.ScheduledTask{Title: "Review", Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour), UserID: integration.UserID}
+	require.NoError(t, db.Create(&single).Error)
+
+	remote := newFakeEvents()
+	syncer := calendarsync.NewSyncer(db)
+
+	// Only the single event is pushed; the series and its overrides stay local
+	result, err := syncer.Sync(context.Background(), remote, &integration)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Pushed)
+	assert.Equal(t, 1, result.SkippedRecurring)
+	require.Len(t, remote.events, 1)
+	for _, event := range remote.events {
+		assert.Equal(t, single.ID.String(), event.LocalID)
+	}
+
+	// An event pushed before its task became recurring is taken off the remote calendar
+	require.NoError(t, db.Model(&single).Update("recurrence_rule_id", weekly.ID).Error)
+	result, err = syncer.Sync(context.Background(), remote, &integration)
+	require.NoError(t, err)
+	assert.Zero(t, result.Pushed)
+	assert.Equal(t, 2, result.SkippedRecurring)
+	assert.Equal(t, 1, result.DeletedRemote)
+	for _, event := range remote.events {
+		assert.True(t, event.Cancelled)
+	}
+
+	var mappings int64
+	db.Model(&models.CalendarEventSync{}).Where("calendar_integration_id = ?", integration.ID).Count(&mappings)
+	assert.Zero(t, mappings)
+
+	// Nothing came back through the pull either
+	var local int64
+	db.Model(&models.ScheduledTask{}).Where("user_id = ?", integration.UserID).Count(&local)
+	assert.Equal(t, int64(4), local)
+}
+
+func TestOutlookSyncLeavesRecurringSeriesLocal(t *testing.T) {
+	db := openCalendarSyncDB(t)
+
+	var inserted int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"value":[],"@odata.deltaLink":"delta-1"}`))
+		case r.Method == http.MethodPost:
+			inserted++
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.NotContains(t, body, "recurrence")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"new-1","lastModifiedDateTime":"2026-03-01T09:00:00Z"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	integration := models.CalendarIntegration{
+		UserID:         uuid.New(),
+		Provider:       models.ProviderOutlook,
+		ProviderUserID: "test",
+		AccessToken:    "token",
+		RefreshToken:   "refresh",
+		TokenExpiry:    time.Now().Add(time.Hour),
+		CalendarID:     "cal-1",
+		IsActive:       true,
+		SyncEnabled:    true,
+	}
+	require.NoError(t, db.Create(&integration).Error)
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour).UTC()
+	daily := models.RecurrenceRule{UserID: integration.UserID, Frequency: "daily", Interval: 1}
+	require.NoError(t, db.Create(&daily).Error)
+	series := models.ScheduledTask{Title: "Gym", Start: start, End: start.Add(time.Hour), UserID: integration.UserID, RecurrenceRuleID: &daily.ID}
+	require.NoError(t, db.Create(&series).Error)
+
+	events := calendarsync.NewGraphEventsService(server.Client(), server.URL, time.UTC)
+	result, err := calendarsync.NewSyncer(db).Sync(context.Background(), events, &integration)
+	require.NoError(t, err)
+	assert.Zero(t, result.Pushed)
+	assert.Equal(t, 1, result.SkippedRecurring)
+	assert.Zero(t, inserted, "a series is not sent to Outlook as a single event")
+}
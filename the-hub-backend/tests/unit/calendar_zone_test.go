@@ -0,0 +1,117 @@
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func zoneHours(startHour, endHour int, days ...time.Weekday) models.CalendarZone {
+	return models.CalendarZone{
+		ID:         uuid.New(),
+		StartTime:  time.Date(2000, 1, 1, startHour, 0, 0, 0, time.UTC),
+		EndTime:    time.Date(2000, 1, 1, endHour, 0, 0, 0, time.UTC),
+		DaysOfWeek: models.NewWeekdaySet(days...),
+		IsActive:   true,
+	}
+}
+
+func TestWeekdaySetParsing(t *testing.T) {
+	weekend := models.NewWeekdaySet(time.Saturday, time.Sunday)
+
+	for _, input := range []string{`["saturday","sunday"]`, `"Saturday, Sunday"`, `"[\"sat\",\"sun\"]"`} {
+		var set models.WeekdaySet
+		require.NoError(t, json.Unmarshal([]byte(input), &set), input)
+		assert.Equal(t, weekend, set, input)
+	}
+
+	var set models.WeekdaySet
+	assert.Error(t, json.Unmarshal([]byte(`["someday"]`), &set))
+
+	data, err := json.Marshal(models.NewWeekdaySet(time.Sunday, time.Monday))
+	require.NoError(t, err)
+	assert.JSONEq(t, `["monday","sunday"]`, string(data))
+
+	// Rows written before sets were typed keep working
+	require.NoError(t, set.Scan("Mondays and Fridays"))
+	assert.Equal(t, models.NewWeekdaySet(time.Monday, time.Friday), set)
+
+	assert.True(t, models.WeekdaySet(0).Includes(time.Wednesday), "an empty set means every day")
+}
+
+func TestOvernightZone(t *testing.T) {
+	loc := time.UTC
+	night := zoneHours(22, 6, time.Friday)
+
+	assert.True(t, night.IsOvernight(loc))
+	friday := time.Date(2025, 3, 7, 0, 0, 0, 0, loc)
+	assert.True(t, night.IsTimeInZone(friday.Add(23*time.Hour), loc))
+	assert.True(t, night.IsTimeInZone(friday.Add(29*time.Hour), loc), "Friday's zone runs into Saturday morning")
+	assert.False(t, night.IsTimeInZone(friday.Add(5*time.Hour), loc), "Thursday has no zone to run into Friday")
+	assert.False(t, night.IsTimeInZone(friday.Add(30*time.Hour), loc), "the zone ends at 06:00")
+
+	assert.True(t, night.CoversSpan(friday.Add(23*time.Hour), friday.Add(26*time.Hour), loc))
+	assert.False(t, night.CoversSpan(friday.Add(5*time.Hour+30*time.Minute), friday.Add(6*time.Hour+30*time.Minute), loc))
+}
+
+func TestZoneBlackouts(t *testing.T) {
+	loc := time.UTC
+	work := zoneHours(7, 15, time.Monday, time.Tuesday)
+	study := zoneHours(16, 18, time.Monday, time.Tuesday)
+
+	holiday := models.ZoneBlackout{
+		Name:      "Public holiday",
+		StartDate: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC),
+	}
+	exams := models.ZoneBlackout{
+		ZoneID:    &study.ID,
+		Name:      "Exam week",
+		StartDate: time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2025, 3, 8, 0, 0, 0, 0, time.UTC),
+	}
+	zones := []models.CalendarZone{work, study}
+	models.AttachBlackouts(zones, []models.ZoneBlackout{holiday, exams})
+	require.Len(t, zones[0].Blackouts, 1, "exam week only suspends the study zone")
+	require.Len(t, zones[1].Blackouts, 2)
+
+	monday := time.Date(2025, 3, 3, 10, 0, 0, 0, loc)
+	tuesday := monday.AddDate(0, 0, 1)
+	assert.False(t, zones[0].IsTimeInZone(monday, loc))
+	assert.True(t, zones[0].IsTimeInZone(tuesday, loc))
+	assert.False(t, zones[1].IsTimeInZone(tuesday.Add(7*time.Hour), loc))
+	assert.True(t, zones[1].IsTimeInZone(tuesday.AddDate(0, 0, 7).Add(7*time.Hour), loc))
+}
+
+func TestZoneOverlaps(t *testing.T) {
+	loc := time.UTC
+	work := zoneHours(9, 17, time.Monday, time.Tuesday)
+
+	lunch := zoneHours(12, 13, time.Tuesday)
+	evening := zoneHours(17, 20, time.Monday)
+	weekend := zoneHours(10, 12, time.Saturday)
+	assert.True(t, work.Overlaps(&lunch, loc))
+	assert.False(t, work.Overlaps(&evening, loc), "zones may meet end to start")
+	assert.False(t, work.Overlaps(&weekend, loc))
+
+	// A Sunday night zone reaches into Monday morning, and a Saturday one into Sunday
+	sundayNight := zoneHours(23, 10, time.Sunday)
+	saturdayNight := zoneHours(22, 2, time.Saturday)
+	earlySunday := zoneHours(1, 3, time.Sunday)
+	assert.True(t, work.Overlaps(&sundayNight, loc))
+	assert.True(t, earlySunday.Overlaps(&saturdayNight, loc))
+	assert.True(t, saturdayNight.Overlaps(&earlySunday, loc))
+
+	// Recurring zones in different terms do not overlap
+	firstTerm, secondTerm := lunch, work
+	firstEnd := time.Date(2025, 6, 30, 0, 0, 0, 0, loc)
+	secondStart := time.Date(2025, 7, 14, 0, 0, 0, 0, loc)
+	firstTerm.IsRecurring, firstTerm.RecurrenceEnd = true, &firstEnd
+	secondTerm.IsRecurring, secondTerm.RecurrenceStart = true, &secondStart
+	assert.False(t, firstTerm.Overlaps(&secondTerm, loc))
+}
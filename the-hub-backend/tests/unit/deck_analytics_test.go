@@ -0,0 +1,73 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/srs"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeechDetection(t *testing.T) {
+	var leeches []int
+	for lapses := 1; lapses <= 16; lapses++ {
+		if srs.IsLeech(lapses, 8) {
+			leeches = append(leeches, lapses)
+		}
+	}
+	assert.Equal(t, []int{8, 12, 16}, leeches, "suspended at the threshold, then every half threshold")
+	assert.False(t, srs.IsLeech(50, 0), "a threshold of 0 never suspends")
+	assert.True(t, srs.IsLeech(2, 1))
+}
+
+func TestDeckAnalytics(t *testing.T) {
+	loc := time.UTC
+	now := time.Date(2025, 3, 10, 15, 0, 0, 0, loc)
+	easy, hard, fresh := uuid.New(), uuid.New(), uuid.New()
+
+	cards := []srs.CardState{
+		{CardID: easy, Question: "easy", Reviewed: true, NextReview: now.Add(-48 * time.Hour)},
+		{CardID: hard, Question: "hard", Reviewed: true, Lapses: 3, Difficulty: 8.456, NextReview: now.AddDate(0, 0, 2)},
+		{CardID: fresh, Question: "new", NextReview: now},
+		{CardID: uuid.New(), Question: "leech", Reviewed: true, Lapses: 8, Suspended: true, NextReview: now},
+	}
+	at := func(daysAgo int) time.Time { return now.AddDate(0, 0, -daysAgo) }
+	events := []srs.ReviewEvent{
+		{CardID: easy, Rating: srs.Good, FirstReview: true, ReviewedAt: at(40), TimeTakenMs: 4000},
+		{CardID: easy, Rating: srs.Good, ElapsedDays: 25, IntervalBefore: 25, ReviewedAt: at(15), TimeTakenMs: 2000},
+		{CardID: hard, Rating: srs.Good, FirstReview: true, ReviewedAt: at(10)},
+		{CardID: hard, Rating: srs.Again, ElapsedDays: 3, IntervalBefore: 3, ReviewedAt: at(7)},
+		{CardID: hard, Rating: srs.Good, ElapsedDays: 0, ReviewedAt: at(7)},
+		{CardID: hard, Rating: srs.Hard, ElapsedDays: 2, IntervalBefore: 2, ReviewedAt: at(5)},
+	}
+
+	analytics := srs.BuildDeckAnalytics(cards, events, at(30), now, loc, 10)
+
+	assert.Equal(t, 5, analytics.Reviews, "reviews before the window are left out")
+	assert.Equal(t, 2.0, analytics.AverageTimeSeconds)
+	assert.Equal(t, srs.RetentionStats{Reviews: 3, Recalled: 2, Retention: 0.667}, analytics.Retention,
+		"first reviews and same-day repeats do not count towards retention")
+	assert.Equal(t, 1, analytics.MatureRetention.Reviews)
+	assert.Equal(t, 2, analytics.YoungRetention.Reviews)
+	assert.Equal(t, 1, analytics.NewCards)
+	assert.Equal(t, 1, analytics.Suspended)
+
+	require.Len(t, analytics.Forecast, srs.ForecastDays)
+	assert.Equal(t, srs.ForecastDay{Date: "2025-03-10", Due: 1}, analytics.Forecast[0], "overdue cards are due today")
+	assert.Equal(t, srs.ForecastDay{Date: "2025-03-12", Due: 1}, analytics.Forecast[2])
+
+	assert.Equal(t, []srs.HeatmapDay{
+		{Date: "2025-01-29", Reviews: 1},
+		{Date: "2025-02-23", Reviews: 1},
+		{Date: "2025-02-28", Reviews: 1},
+		{Date: "2025-03-03", Reviews: 2},
+		{Date: "2025-03-05", Reviews: 1},
+	}, analytics.Heatmap)
+
+	require.Len(t, analytics.HardestCards, 2)
+	assert.Equal(t, "leech", analytics.HardestCards[0].Question)
+	assert.Equal(t, srs.HardCard{CardID: hard, Question: "hard", Lapses: 3, Reviews: 4, AgainRate: 0.25, Difficulty: 8.46},
+		analytics.HardestCards[1])
+}
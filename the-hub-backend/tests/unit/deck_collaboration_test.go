@@ -0,0 +1,60 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeckRoleAllows(t *testing.T) {
+	assert.True(t, models.DeckRoleAllows(models.DeckRoleOwner, models.DeckRoleEditor))
+	assert.True(t, models.DeckRoleAllows(models.DeckRoleEditor, models.DeckRoleEditor))
+	assert.True(t, models.DeckRoleAllows(models.DeckRoleViewer, models.DeckRoleViewer))
+	assert.False(t, models.DeckRoleAllows(models.DeckRoleViewer, models.DeckRoleEditor))
+	assert.False(t, models.DeckRoleAllows(models.DeckRoleEditor, models.DeckRoleOwner))
+	assert.True(t, models.DeckRoleAllows(models.DeckRoleSubscriber, models.DeckRoleViewer), "subscribers study like viewers")
+	assert.False(t, models.DeckRoleAllows(models.DeckRoleSubscriber, models.DeckRoleEditor))
+	assert.False(t, models.DeckRoleAllows("", models.DeckRoleViewer), "no role allows nothing")
+	assert.False(t, models.DeckRoleAllows("admin", models.DeckRoleViewer))
+}
+
+func TestCardProgressKeepsReviewStatePerUser(t *testing.T) {
+	created := time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC)
+	reviewed := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	owners := models.Card{
+		ID: uuid.New(), DeckID: uuid.New(), Question: "q", Answer: "a", CreatedAt: created,
+		Easiness: 2.2, Interval: 12, Repetitions: 4, Stability: 14.5, Difficulty: 6.1, Lapses: 1,
+		Suspended: true, LastReviewed: reviewed, NextReview: reviewed.AddDate(0, 0, 12),
+	}
+
+	// A collaborator who never studied the card starts it fresh, due from its creation
+	card := owners
+	card.ApplyProgress(nil)
+	assert.Equal(t, 2.5, card.Easiness)
+	assert.Equal(t, 1, card.Interval)
+	assert.Zero(t, card.Repetitions)
+	assert.Zero(t, card.Stability)
+	assert.Zero(t, card.Lapses)
+	assert.False(t, card.Suspended)
+	assert.True(t, card.LastReviewed.IsZero())
+	assert.Equal(t, created, card.NextReview)
+	assert.Equal(t, owners.Question, card.Question, "content is shared")
+
+	// Their state round-trips through their progress
+	collaborator := uuid.New()
+	card.SetMemory(owners.Memory())
+	card.NextReview = reviewed.AddDate(0, 0, 3)
+	progress := models.NewCardProgress(&card, collaborator)
+	assert.Equal(t, collaborator, progress.UserID)
+	assert.Equal(t, card.ID, progress.CardID)
+	assert.Equal(t, card.DeckID, progress.DeckID)
+
+	other := owners
+	other.ApplyProgress(&progress)
+	assert.Equal(t, card.Memory(), other.Memory())
+	assert.Equal(t, reviewed.AddDate(0, 0, 3), other.NextReview)
+	assert.False(t, other.Suspended)
+}
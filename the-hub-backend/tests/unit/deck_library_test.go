@@ -0,0 +1,202 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/handlers"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func openDeckLibraryDB(t *testing.T) *gorm.DB {
+	db := openDeckAccessDB(t)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.CardNote{}, &models.DeckRating{}, &models.DeckReport{}))
+	config.SetTestDB(db)
+	return db
+}
+
+func deckLibraryRequest(userID uuid.UUID, method, path, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Next()
+	})
+	router.GET("/decks/library/:deckID", handlers.GetPublicDeck)
+	router.POST("/decks/library/:deckID/clone", handlers.CloneDeck)
+	router.POST("/decks/library/:deckID/subscribe", handlers.SubscribeDeck)
+	router.PUT("/decks/library/:deckID/rating", handlers.RateDeck)
+	router.POST("/decks/library/:deckID/reports", handlers.ReportDeck)
+	router.PATCH("/decks/:ID", handlers.UpdateDeck)
+	router.GET("/decks/cards/:deckID", handlers.GetCards)
+	router.POST("/cards", handlers.CreateCard)
+	router.PATCH("/cards/:ID", handlers.UpdateCard)
+	admin := router.Group("/admin", util.AdminMiddleware())
+	admin.GET("/deck-reports", handlers.GetDeckReports)
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func createLibraryUser(t *testing.T, db *gorm.DB, name, role string) models.User {
+	user := models.User{Name: name, Email: uuid.NewString() + "@example.com", Password: "x", Role: role}
+	require.NoError(t, db.Create(&user).Error)
+	return user
+}
+
+// createLibraryDeck creates a public deck owned by owner with one card its owner has
+// already studied
+func createLibraryDeck(t *testing.T, db *gorm.DB, owner uuid.UUID) (models.Deck, models.Card) {
+	deck := models.Deck{Name: "Spanish " + uuid.NewString(), UserID: owner, IsPublic: true}
+	require.NoError(t, db.Create(&deck).Error)
+	reviewed := time.Now().AddDate(0, 0, -3)
+	card := models.Card{
+		DeckID: deck.ID, Question: "perro", Answer: "dog", Easiness: 2.8, Interval: 12, Repetitions: 4,
+		Stability: 9.5, Difficulty: 4.2, Lapses: 2, LastReviewed: reviewed, NextReview: reviewed.AddDate(0, 0, 12),
+	}
+	require.NoError(t, db.Create(&card).Error)
+	return deck, card
+}
+
+func TestCloneDeckCopiesCardsWithFreshReviewState(t *testing.T) {
+	db := openDeckLibraryDB(t)
+	owner := createLibraryUser(t, db, "Owner", "user")
+	cloner := createLibraryUser(t, db, "Cloner", "user")
+	source, original := createLibraryDeck(t, db, owner.ID)
+
+	recorder := deckLibraryRequest(cloner.ID, http.MethodPost, "/decks/library/"+source.ID.String()+"/clone", "")
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var response struct {
+		Deck      models.Deck `json:"deck"`
+		CardCount int         `json:"card_count"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.CardCount)
+
+	var clone models.Deck
+	require.NoError(t, db.First(&clone, "id = ?", response.Deck.ID).Error)
+	assert.Equal(t, cloner.ID, clone.UserID)
+	require.NotNil(t, clone.ClonedFromID)
+	assert.Equal(t, source.ID, *clone.ClonedFromID)
+	assert.False(t, clone.IsPublic)
+
+	var cards []models.Card
+	require.NoError(t, db.Where("deck_id = ?", clone.ID).Find(&cards).Error)
+	require.Len(t, cards, 1)
+	copied := cards[0]
+	assert.NotEqual(t, original.ID, copied.ID)
+	assert.Equal(t, original.Question, copied.Question)
+	assert.Zero(t, copied.Repetitions)
+	assert.Zero(t, copied.Lapses)
+	assert.Zero(t, copied.Stability)
+	assert.Equal(t, 1, copied.Interval)
+	assert.Equal(t, 2.5, copied.Easiness)
+	assert.True(t, copied.LastReviewed.IsZero(), "the copy has never been reviewed")
+
+	// Editing the copy leaves the original alone
+	require.Equal(t, http.StatusOK, deckLibraryRequest(cloner.ID, http.MethodPatch, "/cards/"+copied.ID.String(), `{"question": "gato"}`).Code)
+	var unchanged models.Card
+	require.NoError(t, db.First(&unchanged, "id = ?", original.ID).Error)
+	assert.Equal(t, "perro", unchanged.Question)
+	assert.Equal(t, 4, unchanged.Repetitions)
+}
+
+func TestSubscriberReadsButCannotWrite(t *testing.T) {
+	db := openDeckLibraryDB(t)
+	owner := createLibraryUser(t, db, "Owner", "user")
+	subscriber := createLibraryUser(t, db, "Subscriber", "user")
+	deck, card := createLibraryDeck(t, db, owner.ID)
+	deckPath := deck.ID.String()
+
+	require.Equal(t, http.StatusCreated, deckLibraryRequest(subscriber.ID, http.MethodPost, "/decks/library/"+deckPath+"/subscribe", "").Code)
+	assert.Equal(t, http.StatusConflict, deckLibraryRequest(subscriber.ID, http.MethodPost, "/decks/library/"+deckPath+"/subscribe", "").Code)
+
+	assert.Equal(t, http.StatusOK, deckLibraryRequest(subscriber.ID, http.MethodGet, "/decks/library/"+deckPath, "").Code)
+	assert.Equal(t, http.StatusOK, deckLibraryRequest(subscriber.ID, http.MethodGet, "/decks/cards/"+deckPath, "").Code)
+
+	created := deckLibraryRequest(subscriber.ID, http.MethodPost, "/cards", `{"deck_id": "`+deckPath+`", "question": "gato", "answer": "cat"}`)
+	assert.Equal(t, http.StatusForbidden, created.Code)
+	assert.Equal(t, http.StatusForbidden, deckLibraryRequest(subscriber.ID, http.MethodPatch, "/cards/"+card.ID.String(), `{"question": "gato"}`).Code)
+	assert.Equal(t, http.StatusNotFound, deckLibraryRequest(subscriber.ID, http.MethodPatch, "/decks/"+deckPath, `{"name": "Mine now"}`).Code)
+
+	var count int64
+	db.Model(&models.Card{}).Where("deck_id = ?", deck.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+	var stored models.Card
+	require.NoError(t, db.First(&stored, "id = ?", card.ID).Error)
+	assert.Equal(t, "perro", stored.Question)
+
+	// Once the deck leaves the library its subscribers lose it
+	require.NoError(t, db.Model(&deck).Update("is_public", false).Error)
+	assert.Equal(t, http.StatusNotFound, deckLibraryRequest(subscriber.ID, http.MethodGet, "/decks/cards/"+deckPath, "").Code)
+}
+
+func TestRateDeckKeepsOneRatingPerUser(t *testing.T) {
+	db := openDeckLibraryDB(t)
+	owner := createLibraryUser(t, db, "Owner", "user")
+	rater := createLibraryUser(t, db, "Rater", "user")
+	deck, _ := createLibraryDeck(t, db, owner.ID)
+	path := "/decks/library/" + deck.ID.String() + "/rating"
+
+	require.Equal(t, http.StatusOK, deckLibraryRequest(rater.ID, http.MethodPut, path, `{"stars": 2}`).Code)
+	require.Equal(t, http.StatusOK, deckLibraryRequest(rater.ID, http.MethodPut, path, `{"stars": 5, "review": "Better now"}`).Code)
+
+	var ratings []models.DeckRating
+	require.NoError(t, db.Where("deck_id = ?", deck.ID).Find(&ratings).Error)
+	require.Len(t, ratings, 1, "a second rating replaces the first")
+	assert.Equal(t, rater.ID, ratings[0].UserID)
+	assert.Equal(t, 5, ratings[0].Stars)
+	assert.Equal(t, "Better now", ratings[0].Review)
+
+	assert.Equal(t, http.StatusBadRequest, deckLibraryRequest(owner.ID, http.MethodPut, path, `{"stars": 5}`).Code)
+	assert.Equal(t, http.StatusBadRequest, deckLibraryRequest(rater.ID, http.MethodPut, path, `{"stars": 6}`).Code)
+}
+
+func TestDeckReportReachesAdminQueue(t *testing.T) {
+	db := openDeckLibraryDB(t)
+	owner := createLibraryUser(t, db, "Owner", "user")
+	reporter := createLibraryUser(t, db, "Reporter", "user")
+	admin := createLibraryUser(t, db, "Admin", "admin")
+	deck, _ := createLibraryDeck(t, db, owner.ID)
+	path := "/decks/library/" + deck.ID.String() + "/reports"
+
+	recorder := deckLibraryRequest(reporter.ID, http.MethodPost, path, `{"reason": "spam", "details": "Links to a shop"}`)
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var report models.DeckReport
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.Equal(t, models.DeckReportPending, report.Status)
+	assert.Equal(t, http.StatusConflict, deckLibraryRequest(reporter.ID, http.MethodPost, path, `{"reason": "spam"}`).Code)
+
+	queue := deckLibraryRequest(admin.ID, http.MethodGet, "/admin/deck-reports?status=pending&limit=100", "")
+	require.Equal(t, http.StatusOK, queue.Code, queue.Body.String())
+	var listed struct {
+		Reports []models.DeckReport `json:"reports"`
+	}
+	require.NoError(t, json.Unmarshal(queue.Body.Bytes(), &listed))
+	var found bool
+	for _, r := range listed.Reports {
+		if r.ID == report.ID {
+			found = true
+			assert.Equal(t, deck.ID, r.DeckID)
+			assert.Equal(t, reporter.ID, r.ReporterID)
+		}
+	}
+	assert.True(t, found, "the report is in the admin queue")
+
+	assert.Equal(t, http.StatusForbidden, deckLibraryRequest(reporter.ID, http.MethodGet, "/admin/deck-reports", "").Code)
+	assert.Equal(t, http.StatusForbidden, deckLibraryRequest(owner.ID, http.MethodGet, "/admin/deck-reports", "").Code)
+}
@@ -0,0 +1,119 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/ai"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// energySamples has two hours of work in the afternoon on twelve weekdays and an hour of
+// exercise early on six mornings of the first week, in the scheduler tests' timezone
+func energySamples() []ai.WorkSample {
+	loc := schedulerNow.Location()
+	var samples []ai.WorkSample
+	for day := 3; len(samples) < 12; day++ {
+		date := time.Date(2025, 3, day, 14, 0, 0, 0, loc)
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			continue
+		}
+		// Stored in UTC like time entries; hours are read in the user's timezone
+		samples = append(samples, ai.WorkSample{Category: "Work", Start: date.UTC(), Minutes: 120})
+	}
+	for day := 3; day <= 8; day++ {
+		samples = append(samples, ai.WorkSample{Category: "exercise", Start: time.Date(2025, 3, day, 7, 0, 0, 0, loc), Minutes: 60})
+	}
+	return samples
+}
+
+func TestLearnEnergy(t *testing.T) {
+	learned := ai.LearnEnergy(energySamples(), schedulerNow.Location())
+
+	assert.Equal(t, 18, learned.SampleCount)
+	assert.Equal(t, 10, learned.TimeSlots["14"])
+	assert.Equal(t, 10, learned.TimeSlots["15"])
+	assert.Equal(t, 6, learned.TimeSlots["7"])
+	assert.Equal(t, 1, learned.TimeSlots["3"])
+	assert.Len(t, learned.TimeSlots, 24)
+
+	require.Contains(t, learned.CategorySlots, "work")
+	require.Contains(t, learned.CategorySlots, "exercise")
+	assert.Equal(t, 10, learned.CategorySlots["work"]["14"])
+	assert.Equal(t, 1, learned.CategorySlots["work"]["7"])
+	assert.Equal(t, 10, learned.CategorySlots["exercise"]["7"])
+	assert.Equal(t, 1, learned.CategorySlots["exercise"]["14"])
+
+	// One hour of exercise on Saturday is too little for it to count as a work day
+	assert.Equal(t, []string{"monday", "tuesday", "wednesday", "thursday", "friday"}, learned.WorkDays)
+	require.NotNil(t, learned.StartHour)
+	require.NotNil(t, learned.EndHour)
+	assert.Equal(t, 7, *learned.StartHour)
+	assert.Equal(t, 15, *learned.EndHour)
+}
+
+func TestLearnEnergyNeedsEnoughSamples(t *testing.T) {
+	learned := ai.LearnEnergy(energySamples()[:ai.MinEnergySamples-1], time.UTC)
+	assert.Equal(t, ai.MinEnergySamples-1, learned.SampleCount)
+	assert.Empty(t, learned.TimeSlots)
+	assert.Empty(t, learned.CategorySlots)
+	assert.Nil(t, learned.StartHour)
+}
+
+func TestBuildEnergyProfilePrecedence(t *testing.T) {
+	userID := uuid.New()
+	defaults := ai.BuildEnergyProfile(userID, nil)
+	assert.Equal(t, 9, defaults.PreferredStartHour)
+	assert.Equal(t, 5, defaults.EnergyAt(10, "work"), "hours without a level are moderate")
+
+	stored := &models.EnergyProfile{UserID: userID, LearningEnabled: true}
+	require.NoError(t, stored.SetLearned(ai.LearnEnergy(energySamples(), schedulerNow.Location())))
+	start, breakMinutes := 8, 30
+	require.NoError(t, stored.SetSettings(models.EnergyProfileSettings{
+		TimeSlots:          map[string]int{"15": 4},
+		PreferredStartHour: &start,
+		BreakDuration:      &breakMinutes,
+		LearningEnabled:    true,
+	}))
+
+	profile := ai.BuildEnergyProfile(userID, stored)
+	assert.Equal(t, 8, profile.PreferredStartHour, "settings win over learned hours")
+	assert.Equal(t, 15, profile.PreferredEndHour, "learned hours win over defaults")
+	assert.Equal(t, 30, profile.BreakDuration)
+	assert.Equal(t, 4, profile.TimeSlots["15"])
+	assert.Equal(t, 10, profile.EnergyAt(14, "work"))
+	assert.Equal(t, 1, profile.EnergyAt(14, " Exercise "), "categories are matched case-insensitively")
+	assert.Equal(t, 10, profile.EnergyAt(14, "study"), "other categories use the overall levels")
+
+	stored.LearningEnabled = false
+	profile = ai.BuildEnergyProfile(userID, stored)
+	assert.Equal(t, 17, profile.PreferredEndHour, "learned values are ignored when learning is off")
+	assert.Nil(t, profile.CategorySlots)
+	assert.Equal(t, 4, profile.TimeSlots["15"])
+}
+
+func TestSolveScheduleUsesLearnedEnergy(t *testing.T) {
+	stored := &models.EnergyProfile{LearningEnabled: true}
+	require.NoError(t, stored.SetLearned(ai.LearnEnergy(energySamples(), schedulerNow.Location())))
+
+	task := schedulerTask("Deep work", 60)
+	task.Category = "work"
+	for _, tc := range []struct {
+		name    string
+		profile *ai.EnergyProfile
+		hour    int
+	}{
+		{name: "defaults", profile: ai.BuildEnergyProfile(uuid.Nil, nil), hour: 9},
+		{name: "learned", profile: ai.BuildEnergyProfile(uuid.Nil, stored), hour: 14},
+	} {
+		result := ai.SolveSchedule(ai.SchedulerInput{Tasks: []models.Task{task}, Profile: tc.profile, Now: schedulerNow})
+		blocks := blocksFor(result, task.ID)
+		require.Len(t, blocks, 1, tc.name)
+		start := blocks[0].Start.In(schedulerNow.Location())
+		assert.Equal(t, schedulerNow.Day(), start.Day(), tc.name)
+		assert.Equal(t, tc.hour, start.Hour(), tc.name)
+	}
+}
@@ -0,0 +1,106 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TheoMKgosi/The-hub/internal/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubOpenRouter serves chat completions from reply, recording the prompts it was sent
+func stubOpenRouter(t *testing.T, reply func(prompt string) string) *[]string {
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/chat/completions", r.URL.Path)
+		var body struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		prompt := body.Messages[len(body.Messages)-1].Content
+		prompts = append(prompts, prompt)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": reply(prompt)}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+	t.Setenv("OPENROUTER_BASE_URL", server.URL)
+	return &prompts
+}
+
+func TestChunkText(t *testing.T) {
+	assert.Empty(t, ai.ChunkText(" \n\n ", 100))
+	assert.Equal(t, []string{"One.\n\nTwo."}, ai.ChunkText("One.\r\n\r\nTwo.", 100))
+
+	// Headings start a new chunk once the current one is half full
+	text := "# A\n\n" + strings.Repeat("a", 60) + "\n\n# B\n\nshort"
+	assert.Equal(t, []string{"# A\n\n" + strings.Repeat("a", 60), "# B\n\nshort"}, ai.ChunkText(text, 100))
+
+	// Overlong paragraphs split between words, and no chunk is longer than asked
+	words := strings.TrimSpace(strings.Repeat("word ", 100))
+	chunks := ai.ChunkText(words, 42)
+	require.Greater(t, len(chunks), 1)
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, len(chunk), 42)
+	}
+	assert.Equal(t, words, strings.Join(chunks, " "))
+}
+
+func TestChunkHashAndNormalize(t *testing.T) {
+	assert.Equal(t, ai.ChunkHash("Go  has\ninterfaces"), ai.ChunkHash("Go has interfaces"))
+	assert.NotEqual(t, ai.ChunkHash("Go has interfaces"), ai.ChunkHash("Go has generics"))
+	assert.Equal(t, "what is a goroutine", ai.NormalizeFlashcardText("  What is a *goroutine*? "))
+}
+
+func TestGenerateFlashcardsFromText(t *testing.T) {
+	prompts := stubOpenRouter(t, func(prompt string) string {
+		if strings.Contains(prompt, "part 1 of 2") {
+			return "Here you go:\n```json\n" + `[{"front":"What is a goroutine?","back":"A lightweight thread"},` +
+				`{"front":"","back":"dropped"}]` + "\n```"
+		}
+		return `[{"front":"what is a Goroutine","back":"Repeated"},{"front":"What is a channel?","back":"A typed pipe","category":"Go"}]`
+	})
+	client, err := ai.NewOpenRouterClient()
+	require.NoError(t, err)
+
+	text := "# Goroutines\n\n" + strings.Repeat("Goroutines are cheap. ", 200) +
+		"\n\n# Channels\n\n" + strings.Repeat("Channels connect goroutines. ", 200)
+	cards, chunks, err := client.GenerateFlashcardsFromText(text, ai.FormatMarkdown, 6, "Keep answers short.")
+	require.NoError(t, err)
+
+	require.Len(t, chunks, 2)
+	require.Len(t, *prompts, 2)
+	for _, prompt := range *prompts {
+		assert.Contains(t, prompt, "from the markdown notes below. Keep answers short.")
+	}
+	assert.Contains(t, (*prompts)[0], "Generate 3 flashcards")
+	assert.Contains(t, (*prompts)[1], "# Channels")
+
+	// Blank and repeated fronts are dropped; each card knows its chunk
+	require.Len(t, cards, 2)
+	assert.Equal(t, "What is a goroutine?", cards[0].Front)
+	assert.Equal(t, 0, cards[0].Chunk)
+	assert.Equal(t, "What is a channel?", cards[1].Front)
+	assert.Equal(t, "Go", cards[1].Category)
+	assert.Equal(t, 1, cards[1].Chunk)
+}
+
+func TestGenerateFlashcardsFromTextBadResponse(t *testing.T) {
+	stubOpenRouter(t, func(string) string { return "Sorry, I can't help with that." })
+	client, err := ai.NewOpenRouterClient()
+	require.NoError(t, err)
+
+	_, _, err = client.GenerateFlashcardsFromText("Some notes.", ai.FormatText, 5, "")
+	assert.Error(t, err)
+
+	_, _, err = client.GenerateFlashcardsFromText("  ", ai.FormatText, 5, "")
+	assert.EqualError(t, err, "no text to generate flashcards from")
+}
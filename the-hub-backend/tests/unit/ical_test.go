@@ -0,0 +1,188 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/handlers"
+	"github.com/TheoMKgosi/The-hub/internal/ical"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecurrenceRuleRRule(t *testing.T) {
+	count := 10
+	until := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		rule     models.RecurrenceRule
+		expected string
+	}{
+		{
+			name:     "daily",
+			rule:     models.RecurrenceRule{Frequency: "daily", Interval: 1},
+			expected: "FREQ=DAILY",
+		},
+		{
+			name:     "weekly with numeric days and count",
+			rule:     models.RecurrenceRule{Frequency: "weekly", Interval: 2, ByDay: "1,3,5", Count: &count},
+			expected: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE,FR;COUNT=10",
+		},
+		{
+			name:     "weekly with until",
+			rule:     models.RecurrenceRule{Frequency: "weekly", ByDay: "TU", EndDate: &until},
+			expected: "FREQ=WEEKLY;BYDAY=TU;UNTIL=20261231T000000Z",
+		},
+		{
+			name:     "unknown frequency",
+			rule:     models.RecurrenceRule{Frequency: "hourly"},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.rule.RRule())
+		})
+	}
+}
+
+func TestCalendarEncode(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	cal := ical.Calendar{
+		Name:  "The Hub",
+		Stamp: start,
+		Events: []ical.Event{{
+			UID:     "schedule-1@the-hub",
+			Summary: "Review; notes, chapter 3",
+			Start:   start,
+			End:     start.Add(time.Hour),
+			RRule:   "FREQ=WEEKLY",
+		}},
+		Todos: []ical.Todo{{
+			UID:         "task-1@the-hub",
+			Summary:     "Submit essay",
+			Description: strings.Repeat("long description ", 10),
+			Due:         start.Add(48 * time.Hour),
+			Status:      "NEEDS-ACTION",
+			Priority:    1,
+		}},
+	}
+
+	data, err := cal.Bytes()
+	assert.NoError(t, err)
+
+	out := string(data)
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n"))
+	assert.Contains(t, out, "DTSTART:20260302T090000Z\r\n")
+	assert.Contains(t, out, `SUMMARY:Review\; notes\, chapter 3`)
+	assert.Contains(t, out, "RRULE:FREQ=WEEKLY\r\n")
+	assert.Contains(t, out, "DUE:20260304T090000Z\r\n")
+	assert.Contains(t, out, "PRIORITY:1\r\n")
+
+	for _, line := range strings.Split(out, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, "content lines must be folded")
+	}
+}
+
+const timetableICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:lecture-1\r\n" +
+	"SUMMARY:Networks\\, lecture\r\n" +
+	"DTSTART;TZID=America/New_York:20260302T090000\r\n" +
+	"DTEND;TZID=America/New_York:20260302T103000\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=6\r\n" +
+	"EXDATE;TZID=America/New_York:20260304T090000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:lecture-1\r\n" +
+	"RECURRENCE-ID;TZID=America/New_York:20260309T090000\r\n" +
+	"SUMMARY:Networks (moved)\r\n" +
+	"DTSTART;TZID=America/New_York:20260309T140000\r\n" +
+	"DTEND;TZID=America/New_York:20260309T153000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:exam-1\r\n" +
+	"SUMMARY:Final exam\r\n" +
+	"DTSTART:20260320T130000Z\r\n" +
+	"DURATION:PT2H\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestExpandEvents(t *testing.T) {
+	cal, err := ical.Parse(strings.NewReader(timetableICS))
+	assert.NoError(t, err)
+
+	events, err := ical.ParseEvents(cal, time.UTC)
+	assert.NoError(t, err)
+	assert.Len(t, events, 3)
+	assert.Equal(t, "Networks, lecture", events[0].Summary)
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	occurrences := ical.ExpandEvents(events, from, to)
+
+	// 6 lectures minus one EXDATE, plus the exam
+	assert.Len(t, occurrences, 6)
+
+	// Before the US DST switch on March 8, 09:00 EST is 14:00 UTC
+	assert.Equal(t, time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC), occurrences[0].Start.UTC())
+	assert.Equal(t, 90*time.Minute, occurrences[0].End.Sub(occurrences[0].Start))
+
+	// The overridden instance keeps its recurrence ID but moves to 14:00 EDT
+	moved := occurrences[1]
+	assert.Equal(t, "Networks (moved)", moved.Summary)
+	assert.Equal(t, time.Date(2026, 3, 9, 18, 0, 0, 0, time.UTC), moved.Start.UTC())
+	assert.Equal(t, "lecture-1/20260309T130000Z", moved.Key())
+
+	// After the DST switch, 09:00 EDT is 13:00 UTC
+	assert.Equal(t, time.Date(2026, 3, 11, 13, 0, 0, 0, time.UTC), occurrences[2].Start.UTC())
+
+	exam := occurrences[len(occurrences)-1]
+	assert.Equal(t, "exam-1", exam.Key())
+	assert.Equal(t, 2*time.Hour, exam.End.Sub(exam.Start))
+}
+
+func TestZoneFromEventInUserTimezone(t *testing.T) {
+	cal, err := ical.Parse(strings.NewReader(timetableICS))
+	assert.NoError(t, err)
+	events, err := ical.ParseEvents(cal, time.UTC)
+	assert.NoError(t, err)
+
+	// 09:00-10:30 in New York is 16:00-17:30 in Gaborone before the US DST switch
+	gaborone, err := time.LoadLocation("Africa/Gaborone")
+	assert.NoError(t, err)
+	zone, ok := handlers.ZoneFromEvent(events[0], "study", gaborone)
+	assert.True(t, ok)
+	assert.Equal(t, models.NewWeekdaySet(time.Monday, time.Wednesday), zone.DaysOfWeek)
+	assert.True(t, zone.IsTimeInZone(time.Date(2026, 3, 2, 16, 30, 0, 0, gaborone), gaborone))
+	assert.False(t, zone.IsTimeInZone(time.Date(2026, 3, 2, 9, 30, 0, 0, gaborone), gaborone))
+
+	// In Auckland the lectures start at 03:00 the next day
+	auckland, err := time.LoadLocation("Pacific/Auckland")
+	assert.NoError(t, err)
+	zone, ok = handlers.ZoneFromEvent(events[0], "study", auckland)
+	assert.True(t, ok)
+	assert.Equal(t, models.NewWeekdaySet(time.Tuesday, time.Thursday), zone.DaysOfWeek)
+	assert.True(t, zone.IsTimeInZone(time.Date(2026, 3, 3, 3, 0, 0, 0, auckland), auckland))
+
+	// A one-off event makes no zone
+	_, ok = handlers.ZoneFromEvent(events[2], "study", gaborone)
+	assert.False(t, ok)
+}
+
+func TestRRuleBetweenMonthly(t *testing.T) {
+	rule, err := ical.ParseRRule("FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", time.UTC)
+	assert.NoError(t, err)
+
+	start := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	occurrences := rule.Between(start, start, start.AddDate(1, 0, 0))
+	assert.Equal(t, []time.Time{
+		time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC),
+		time.Date(2026, 2, 27, 10, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 27, 10, 0, 0, 0, time.UTC),
+	}, occurrences)
+}
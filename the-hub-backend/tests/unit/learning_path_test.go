@@ -0,0 +1,73 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/TheoMKgosi/The-hub/internal/learningpath"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLearningPathPrerequisites(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+
+	assert.NoError(t, learningpath.Validate([]learningpath.Topic{
+		{ID: a},
+		{ID: b, Prerequisites: []uuid.UUID{a}},
+		{ID: c, Prerequisites: []uuid.UUID{a, b}},
+	}))
+	assert.ErrorIs(t, learningpath.Validate([]learningpath.Topic{{ID: a}, {ID: a}}), learningpath.ErrDuplicateTopic)
+	assert.ErrorIs(t, learningpath.Validate([]learningpath.Topic{{ID: a, Prerequisites: []uuid.UUID{a}}}), learningpath.ErrSelfPrerequisite)
+	assert.ErrorIs(t, learningpath.Validate([]learningpath.Topic{{ID: a, Prerequisites: []uuid.UUID{b}}}), learningpath.ErrUnknownPrerequisite)
+
+	err := learningpath.Validate([]learningpath.Topic{
+		{ID: a, Prerequisites: []uuid.UUID{c}},
+		{ID: b, Prerequisites: []uuid.UUID{a}},
+		{ID: c, Prerequisites: []uuid.UUID{b}},
+	})
+	var cycle *learningpath.CycleError
+	require.ErrorAs(t, err, &cycle)
+	assert.Equal(t, []uuid.UUID{a, c, b, a}, cycle.Cycle)
+}
+
+func TestEvaluateLearningPath(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	topics := []learningpath.TopicState{
+		{Topic: learningpath.Topic{ID: a}, Status: "in_progress", TasksTotal: 4, TasksDone: 4},
+		{Topic: learningpath.Topic{ID: b, Prerequisites: []uuid.UUID{a}}, Status: "in_progress", TasksTotal: 4, TasksDone: 1},
+		{Topic: learningpath.Topic{ID: c, Prerequisites: []uuid.UUID{a, b}}, Status: "not_started"},
+	}
+
+	progress, summary := learningpath.Evaluate(topics)
+	assert.True(t, progress[0].Done, "all tasks done completes a topic")
+	assert.False(t, progress[1].Locked)
+	assert.Equal(t, 0.25, progress[1].Progress)
+	assert.True(t, progress[2].Locked)
+	assert.Equal(t, []uuid.UUID{b}, progress[2].BlockedBy)
+
+	assert.Equal(t, 1, summary.TopicsDone)
+	assert.Equal(t, 8, summary.TasksTotal)
+	assert.Equal(t, 5, summary.TasksDone)
+	assert.Equal(t, 0.417, summary.Progress)
+	assert.Equal(t, &b, summary.NextTopicID)
+	assert.False(t, summary.Completed)
+
+	topics[1].Status = "completed"
+	progress, summary = learningpath.Evaluate(topics)
+	assert.False(t, progress[2].Locked)
+	assert.Equal(t, &c, summary.NextTopicID)
+}
+
+func TestLearningPathTopicPrerequisites(t *testing.T) {
+	row := models.LearningPathTopic{TopicID: uuid.New()}
+	assert.Empty(t, row.PrerequisiteIDs())
+
+	prerequisite := uuid.New()
+	require.NoError(t, row.SetPrerequisiteIDs([]uuid.UUID{prerequisite}))
+	assert.Equal(t, []uuid.UUID{prerequisite}, row.PathTopic().Prerequisites)
+
+	require.NoError(t, row.SetPrerequisiteIDs(nil))
+	assert.Equal(t, "[]", row.Prerequisites)
+}
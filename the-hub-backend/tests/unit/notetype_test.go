@@ -0,0 +1,106 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/handlers"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/notetype"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClozeNoteGeneratesOneCardPerDeletion(t *testing.T) {
+	cards, err := notetype.Generate(notetype.Cloze,
+		"{{c1::Paris}} is the capital of {{c2::France::country}}, on the {{c1::Seine}}", "", "Since 508 AD")
+	require.NoError(t, err)
+	require.Len(t, cards, 2)
+
+	assert.Equal(t, "c1", cards[0].Template)
+	assert.Equal(t, notetype.CardCloze, cards[0].Type)
+	assert.Equal(t, "[...] is the capital of France, on the [...]", cards[0].Question)
+	assert.Equal(t, "Paris is the capital of France, on the Seine\n\nSince 508 AD", cards[0].Answer)
+	assert.Equal(t, "Paris is the capital of [country], on the Seine", cards[1].Question)
+
+	_, err = notetype.Generate(notetype.Cloze, "No deletions here", "", "")
+	assert.Error(t, err)
+}
+
+func TestReversedNoteGeneratesBothDirections(t *testing.T) {
+	cards, err := notetype.Generate(notetype.BasicReversed, "hund", "dog", "")
+	require.NoError(t, err)
+	require.Len(t, cards, 2)
+	assert.Equal(t, notetype.Card{Template: notetype.TemplateForward, Type: notetype.CardBasic, Question: "hund", Answer: "dog"}, cards[0])
+	assert.Equal(t, notetype.Card{Template: notetype.TemplateReverse, Type: notetype.CardBasic, Question: "dog", Answer: "hund"}, cards[1])
+
+	_, err = notetype.Generate(notetype.BasicReversed, "hund", " ", "")
+	assert.Error(t, err, "both sides are needed")
+}
+
+func TestGradeTypedAnswer(t *testing.T) {
+	exact := notetype.GradeTypedAnswer("Champs-Élysées", "  champs elysees ")
+	assert.True(t, exact.Correct)
+	assert.True(t, exact.Exact)
+	assert.Equal(t, 4, exact.Quality)
+
+	typo := notetype.GradeTypedAnswer("photosynthesis", "photosynthsis")
+	assert.True(t, typo.Correct)
+	assert.False(t, typo.Exact)
+	assert.Equal(t, 3, typo.Quality)
+
+	near := notetype.GradeTypedAnswer("mitochondria", "mitochon")
+	assert.False(t, near.Correct)
+	assert.Equal(t, 2, near.Quality)
+
+	wrong := notetype.GradeTypedAnswer("Paris", "Berlin")
+	assert.False(t, wrong.Correct)
+	assert.Equal(t, 1, wrong.Quality)
+
+	alternative := notetype.GradeTypedAnswer("colour | color", "Color")
+	assert.True(t, alternative.Exact)
+
+	assert.Equal(t, 0, notetype.GradeTypedAnswer("Paris", "   ").Quality)
+}
+
+func TestImportCardTypes(t *testing.T) {
+	assert.NoError(t, handlers.ValidateImportCard(handlers.ImportCard{Type: "cloze", Question: "{{c1::H2O}} is water"}))
+	assert.Error(t, handlers.ValidateImportCard(handlers.ImportCard{Type: "cloze", Question: "H2O is water"}))
+	assert.Error(t, handlers.ValidateImportCard(handlers.ImportCard{Type: "picture", Question: "q", Answer: "a"}))
+	assert.Error(t, handlers.ValidateImportCard(handlers.ImportCard{Type: "type_in", Question: "q"}))
+
+	csvData := `question,answer,type,extra,template,interval
+"{{c1::H2O}} is {{c2::water}}",,cloze,Chemistry,c2,12
+plain question,plain answer,,,,`
+	cards, errs := handlers.ParseCSVImport(newMockMultipartFile(csvData))
+	require.Empty(t, errs)
+	require.Len(t, cards, 2)
+	assert.Equal(t, handlers.ImportCard{Type: "cloze", Question: "{{c1::H2O}} is {{c2::water}}", Extra: "Chemistry", Template: "c2", Interval: 12}, cards[0])
+	assert.Equal(t, "", cards[1].Type)
+}
+
+func TestExportNoteCard(t *testing.T) {
+	note := models.CardNote{ID: uuid.New(), Model: notetype.Cloze, Front: "{{c1::H2O}} is water", Extra: "Chemistry"}
+	reviewed := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	card := models.Card{
+		NoteID:       &note.ID,
+		Template:     "c1",
+		Question:     "[...] is water",
+		Answer:       "H2O is water\n\nChemistry",
+		Interval:     6,
+		LastReviewed: reviewed,
+	}
+
+	export := handlers.NewExportCard(card, []models.CardNote{note})
+	assert.Equal(t, "cloze", export.Type)
+	assert.Equal(t, note.Front, export.Question, "note cards export their note's content")
+	assert.Equal(t, "Chemistry", export.Extra)
+	assert.Equal(t, "c1", export.Template)
+	assert.Equal(t, 6, export.Interval)
+	assert.Equal(t, &reviewed, export.LastReviewed)
+
+	plain := handlers.NewExportCard(models.Card{Question: "q", Answer: "a"}, nil)
+	assert.Equal(t, "", plain.Type)
+	assert.Nil(t, plain.LastReviewed)
+}
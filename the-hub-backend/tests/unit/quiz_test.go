@@ -0,0 +1,122 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/ai"
+	"github.com/TheoMKgosi/The-hub/internal/quiz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validQuiz = `Sure! {"questions": [
+	{"type": "multiple_choice", "question": "Which keyword starts a goroutine?", "options": ["go", " defer ", "chan"], "answer": "GO", "source": 1},
+	{"type": "true_false", "question": "Channels are typed.", "answer": "True", "explanation": "A channel carries one type.", "source": 2},
+	{"type": "short_answer", "question": "What closes a channel?", "answer": "close", "source": 2}
+]}`
+
+func TestParseQuiz(t *testing.T) {
+	questions, err := quiz.Parse(validQuiz, 2, quiz.Types)
+	require.NoError(t, err)
+	require.Len(t, questions, 3)
+	assert.Equal(t, []string{"go", "defer", "chan"}, questions[0].Options)
+	assert.Equal(t, "go", questions[0].Answer)
+	assert.Equal(t, "true", questions[1].Answer)
+	assert.Equal(t, 2, questions[2].Source)
+}
+
+func TestParseQuizRejectsSchemaViolations(t *testing.T) {
+	cases := map[string]struct {
+		response string
+		path     string
+	}{
+		"not json":        {"I cannot do that", "$"},
+		"unknown field":   {`{"questions": [{"type": "short_answer", "question": "Q", "answer": "A", "source": 1, "hint": "x"}]}`, "$"},
+		"no questions":    {`{"questions": []}`, "questions"},
+		"disallowed type": {`{"questions": [{"type": "essay", "question": "Q", "answer": "A", "source": 1}]}`, "questions[0].type"},
+		"bad source":      {`{"questions": [{"type": "short_answer", "question": "Q", "answer": "A", "source": 3}]}`, "questions[0].source"},
+		"answer not an option": {`{"questions": [{"type": "multiple_choice", "question": "Q", "options": ["a", "b", "c"], "answer": "d", "source": 1}]}`,
+			"questions[0].answer"},
+		"too few options": {`{"questions": [{"type": "multiple_choice", "question": "Q", "options": ["a", "b"], "answer": "a", "source": 1}]}`,
+			"questions[0].options"},
+		"duplicate options": {`{"questions": [{"type": "multiple_choice", "question": "Q", "options": ["a", "A", "c"], "answer": "a", "source": 1}]}`,
+			"questions[0].options[1]"},
+		"true/false options": {`{"questions": [{"type": "true_false", "question": "Q", "options": ["true", "false", "maybe"], "answer": "true", "source": 1}]}`,
+			"questions[0].options"},
+		"true/false answer": {`{"questions": [{"type": "true_false", "question": "Q", "answer": "maybe", "source": 1}]}`, "questions[0].answer"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := quiz.Parse(tc.response, 2, quiz.Types)
+			var invalid *quiz.ValidationError
+			require.ErrorAs(t, err, &invalid)
+			assert.Equal(t, tc.path, invalid.Path)
+		})
+	}
+
+	_, err := quiz.Parse(validQuiz, 2, []string{quiz.TypeMultipleChoice})
+	assert.EqualError(t, err, "questions[1].type: must be one of multiple_choice")
+}
+
+func TestGradeQuiz(t *testing.T) {
+	questions, err := quiz.Parse(validQuiz, 2, quiz.Types)
+	require.NoError(t, err)
+
+	results, correct := quiz.Score(questions, []string{"a", "no"})
+	assert.Equal(t, 1, correct)
+	assert.True(t, results[0].Correct, "the letter of the right option")
+	assert.False(t, results[1].Correct)
+	assert.Equal(t, "A channel carries one type.", results[1].Explanation)
+	assert.False(t, results[2].Correct, "unanswered")
+
+	assert.True(t, quiz.Grade(questions[0], " Go ").Correct)
+	assert.False(t, quiz.Grade(questions[0], "b").Correct)
+	assert.True(t, quiz.Grade(questions[1], "yes").Correct)
+	short := quiz.Grade(questions[2], "Close()")
+	assert.True(t, short.Correct)
+	assert.Equal(t, 1.0, short.Similarity)
+}
+
+func TestShortenQuizCard(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	interval, next := quiz.Shorten(20, now.AddDate(0, 0, 15), now)
+	assert.Equal(t, 10, interval)
+	assert.Equal(t, now.AddDate(0, 0, 10), next)
+
+	// Never below a day, and a card already due sooner keeps its date
+	interval, next = quiz.Shorten(1, now.Add(time.Hour), now)
+	assert.Equal(t, 1, interval)
+	assert.Equal(t, now.Add(time.Hour), next)
+}
+
+func TestGenerateQuizRetriesInvalidOutput(t *testing.T) {
+	prompts := stubOpenRouter(t, func(prompt string) string {
+		if strings.Contains(prompt, "does not match the schema") {
+			return validQuiz
+		}
+		return `{"questions": [{"type": "multiple_choice", "question": "Q", "options": ["a", "b", "c"], "answer": "d", "source": 1}]}`
+	})
+	client, err := ai.NewOpenRouterClient()
+	require.NoError(t, err)
+
+	questions, err := client.GenerateQuiz([]string{"Q: What starts a goroutine?\nA: go", "Channels are typed pipes."}, 3, quiz.Types, "")
+	require.NoError(t, err)
+	assert.Len(t, questions, 3)
+	require.Len(t, *prompts, 2)
+	assert.Contains(t, (*prompts)[0], "[2]\nChannels are typed pipes.")
+	assert.Contains(t, (*prompts)[1], "questions[0].answer: must be one of the options")
+}
+
+func TestGenerateQuizGivesUpAfterRetry(t *testing.T) {
+	prompts := stubOpenRouter(t, func(string) string { return `{"questions": []}` })
+	client, err := ai.NewOpenRouterClient()
+	require.NoError(t, err)
+
+	_, err = client.GenerateQuiz([]string{"Some notes."}, 5, quiz.Types, "")
+	var invalid *quiz.ValidationError
+	assert.ErrorAs(t, err, &invalid)
+	assert.Len(t, *prompts, 2)
+}
@@ -0,0 +1,134 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/ai"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Monday 2025-03-03 13:00, in the scheduler tests' timezone
+var replanNow = schedulerNow.Add(5 * time.Hour)
+
+func replanAt(hour, minute int) time.Time {
+	return time.Date(2025, 3, 3, hour, minute, 0, 0, schedulerNow.Location()).UTC()
+}
+
+func replanBlock(task *models.Task, start, end time.Time) models.ScheduledTask {
+	block := models.ScheduledTask{ID: uuid.New(), Title: "Block", Start: start, End: end}
+	if task != nil {
+		block.TaskID = &task.ID
+		block.Title = task.Title
+	}
+	return block
+}
+
+func changeFor(changes []models.ReplanChange, taskID uuid.UUID) *models.ReplanChange {
+	for i := range changes {
+		if changes[i].TaskID == taskID {
+			return &changes[i]
+		}
+	}
+	return nil
+}
+
+func TestPlanReplan(t *testing.T) {
+	report := schedulerTask("Report", 60)
+	slides := schedulerTask("Slides", 60)
+	code := schedulerTask("Code", 60)
+	email := schedulerTask("Email", 45)
+	review := schedulerTask("Review", 60)
+	plan := schedulerTask("Plan", 30)
+	done := schedulerTask("Done", 60)
+	done.Status = "completed"
+
+	missed := replanBlock(&report, replanAt(9, 0), replanAt(10, 0))
+	worked := replanBlock(&slides, replanAt(10, 30), replanAt(11, 30))
+	overrun := replanBlock(&code, replanAt(11, 45), replanAt(12, 45))
+	conflict := replanBlock(&email, replanAt(13, 15), replanAt(14, 0))
+	pinned := replanBlock(&review, replanAt(15, 0), replanAt(16, 0))
+	pinned.Pinned = true
+	untouched := replanBlock(&plan, replanAt(16, 30), replanAt(17, 0))
+	finished := replanBlock(&done, replanAt(8, 0), replanAt(8, 45))
+	lunch := replanBlock(nil, replanAt(14, 0), replanAt(15, 0))
+	lunch.Source = "google"
+	meeting := replanBlock(nil, replanAt(15, 30), replanAt(16, 30))
+	meeting.Source = "google"
+
+	slidesEnd := replanAt(11, 15)
+	in := ai.ReplanInput{
+		Schedule: []models.ScheduledTask{missed, worked, overrun, conflict, pinned, untouched, finished, lunch, meeting},
+		Tasks:    []models.Task{report, slides, code, email, review, plan, done},
+		TimeEntries: []models.TimeEntry{
+			{TaskID: slides.ID, StartTime: replanAt(10, 30), EndTime: &slidesEnd, Duration: 45},
+			{TaskID: code.ID, StartTime: replanAt(11, 50), IsRunning: true},
+		},
+		Profile: schedulerProfile(),
+		Now:     replanNow,
+	}
+	changes := ai.PlanReplan(in)
+	require.Len(t, changes, 3)
+	assert.Equal(t, []uuid.UUID{report.ID, code.ID, email.ID}, []uuid.UUID{changes[0].TaskID, changes[1].TaskID, changes[2].TaskID})
+
+	// The overrunning block runs on for another half hour
+	extended := changeFor(changes, code.ID)
+	assert.Equal(t, []string{models.ReplanOverrun}, extended.Reasons)
+	require.Len(t, extended.After, 1)
+	assert.Equal(t, overrun.ID, *extended.After[0].ID)
+	assert.True(t, extended.After[0].Start.Equal(overrun.Start))
+	assert.True(t, extended.After[0].End.Equal(replanAt(13, 30)))
+
+	assert.Equal(t, []string{models.ReplanMissed}, changeFor(changes, report.ID).Reasons)
+	assert.Equal(t, []string{models.ReplanConflict}, changeFor(changes, email.ID).Reasons)
+
+	// Moved work keeps its block, lands in free time and leaves room for breaks
+	buffer := time.Duration(in.Profile.BreakDuration) * time.Minute
+	stays := []models.ScheduledTask{worked, pinned, untouched, lunch, meeting, {Start: overrun.Start, End: replanAt(13, 30)}}
+	for _, taskID := range []uuid.UUID{report.ID, email.ID} {
+		change := changeFor(changes, taskID)
+		require.Empty(t, change.Unschedulable)
+		require.Len(t, change.After, 1)
+		block := change.After[0]
+		assert.Equal(t, change.Before[0].ID, block.ID)
+		assert.False(t, block.Start.Before(replanNow))
+		assert.Equal(t, change.Before[0].End.Sub(change.Before[0].Start), block.End.Sub(block.Start))
+		for _, event := range stays {
+			assert.False(t, block.Start.Before(event.End.Add(buffer)) && event.Start.Before(block.End.Add(buffer)),
+				"%s moved next to an event that stays", change.Title)
+		}
+	}
+}
+
+func TestPlanReplanSplitsLongMissedWork(t *testing.T) {
+	report := schedulerTask("Report", 180)
+	missed := replanBlock(&report, replanAt(8, 0), replanAt(11, 0))
+
+	changes := ai.PlanReplan(ai.ReplanInput{
+		Schedule: []models.ScheduledTask{missed},
+		Tasks:    []models.Task{report},
+		Profile:  schedulerProfile(),
+		Now:      replanNow,
+	})
+	require.Len(t, changes, 1)
+	after := changes[0].After
+	require.Len(t, after, 2)
+	assert.Equal(t, missed.ID, *after[0].ID, "the first placement reuses the missed block")
+	assert.Nil(t, after[1].ID, "the rest of the work needs a new block")
+	assert.Equal(t, 3*time.Hour, after[0].End.Sub(after[0].Start)+after[1].End.Sub(after[1].Start))
+}
+
+func TestPlanReplanNothingToDo(t *testing.T) {
+	task := schedulerTask("Report", 60)
+	changes := ai.PlanReplan(ai.ReplanInput{
+		Schedule: []models.ScheduledTask{replanBlock(&task, replanAt(15, 0), replanAt(16, 0))},
+		Tasks:    []models.Task{task},
+		Profile:  schedulerProfile(),
+		Now:      replanNow,
+	})
+	assert.NotNil(t, changes)
+	assert.Empty(t, changes)
+}
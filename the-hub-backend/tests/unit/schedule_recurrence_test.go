@@ -0,0 +1,144 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/ical"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func weeklyStandup(t *testing.T, loc *time.Location) models.ScheduledTask {
+	t.Helper()
+	// Mondays at 9:00; daylight saving time starts in New York on 2025-03-09
+	return models.ScheduledTask{
+		ID:             uuid.New(),
+		Title:          "Standup",
+		Start:          time.Date(2025, 3, 3, 9, 0, 0, 0, loc).UTC(),
+		End:            time.Date(2025, 3, 3, 9, 30, 0, 0, loc).UTC(),
+		RecurrenceRule: &models.RecurrenceRule{Frequency: "weekly", Interval: 1, ByDay: "MO"},
+	}
+}
+
+func TestExpandScheduleRecurringSeries(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	master := weeklyStandup(t, loc)
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, loc)
+	to := time.Date(2025, 4, 1, 0, 0, 0, 0, loc)
+
+	events := models.ExpandSchedule([]models.ScheduledTask{master}, from, to, loc)
+	require.Len(t, events, 5)
+	for i, event := range events {
+		local := event.Start.In(loc)
+		assert.Equal(t, 3+7*i, local.Day())
+		assert.Equal(t, 9, local.Hour(), "occurrences keep their wall-clock time across DST")
+		assert.Equal(t, 30*time.Minute, event.End.Sub(event.Start))
+		assert.Equal(t, master.ID, event.ID)
+		require.NotNil(t, event.RecurringEventID)
+		assert.Equal(t, master.ID, *event.RecurringEventID)
+		require.NotNil(t, event.RecurrenceID)
+		assert.True(t, event.RecurrenceID.Equal(event.Start))
+	}
+}
+
+func TestExpandScheduleOverrides(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	master := weeklyStandup(t, loc)
+
+	// The standup on the 10th moves to 11:00 and the one on the 17th is cancelled
+	moved := time.Date(2025, 3, 10, 9, 0, 0, 0, loc).UTC()
+	cancelled := time.Date(2025, 3, 17, 9, 0, 0, 0, loc).UTC()
+	rows := []models.ScheduledTask{
+		master,
+		{
+			ID:               uuid.New(),
+			Title:            "Standup (moved)",
+			Start:            time.Date(2025, 3, 10, 11, 0, 0, 0, loc).UTC(),
+			End:              time.Date(2025, 3, 10, 11, 30, 0, 0, loc).UTC(),
+			RecurringEventID: &master.ID,
+			RecurrenceID:     &moved,
+		},
+		{
+			ID:               uuid.New(),
+			Title:            "Standup",
+			Start:            cancelled,
+			End:              cancelled.Add(30 * time.Minute),
+			RecurringEventID: &master.ID,
+			RecurrenceID:     &cancelled,
+			Cancelled:        true,
+		},
+		{
+			ID:    uuid.New(),
+			Title: "Dentist",
+			Start: time.Date(2025, 3, 12, 14, 0, 0, 0, loc).UTC(),
+			End:   time.Date(2025, 3, 12, 15, 0, 0, 0, loc).UTC(),
+		},
+		{
+			ID:    uuid.New(),
+			Title: "Outside the range",
+			Start: time.Date(2025, 5, 1, 14, 0, 0, 0, loc).UTC(),
+			End:   time.Date(2025, 5, 1, 15, 0, 0, 0, loc).UTC(),
+		},
+	}
+
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, loc)
+	to := time.Date(2025, 4, 1, 0, 0, 0, 0, loc)
+	events := models.ExpandSchedule(rows, from, to, loc)
+
+	var titles []string
+	for _, event := range events {
+		titles = append(titles, event.Title+" "+event.Start.In(loc).Format("01-02 15:04"))
+	}
+	assert.Equal(t, []string{
+		"Standup 03-03 09:00",
+		"Standup (moved) 03-10 11:00",
+		"Dentist 03-12 14:00",
+		"Standup 03-24 09:00",
+		"Standup 03-31 09:00",
+	}, titles)
+}
+
+func TestExpandScheduleCountAndRange(t *testing.T) {
+	loc, err := time.LoadLocation("Pacific/Tongatapu")
+	require.NoError(t, err)
+	master := weeklyStandup(t, loc)
+	count := 3
+	master.RecurrenceRule.Count = &count
+
+	// An occurrence that started before the range but is still running is included
+	from := time.Date(2025, 3, 10, 9, 15, 0, 0, loc)
+	events := models.ExpandSchedule([]models.ScheduledTask{master}, from, from.AddDate(0, 1, 0), loc)
+	require.Len(t, events, 2)
+	assert.Equal(t, 10, events[0].Start.In(loc).Day())
+	assert.Equal(t, 17, events[1].Start.In(loc).Day())
+}
+
+func TestCalendarEncodesOverrides(t *testing.T) {
+	start := time.Date(2025, 3, 3, 14, 0, 0, 0, time.UTC)
+	moved := start.AddDate(0, 0, 7)
+	cancelled := start.AddDate(0, 0, 14)
+	cal := ical.Calendar{Events: []ical.Event{
+		{UID: "standup@the-hub", Summary: "Standup", Start: start, End: start.Add(30 * time.Minute), RRule: "FREQ=WEEKLY", ExDates: []time.Time{cancelled}},
+		{UID: "standup@the-hub", Summary: "Standup (moved)", Start: moved.Add(2 * time.Hour), End: moved.Add(150 * time.Minute), RecurrenceID: moved},
+	}}
+	data, err := cal.Bytes()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "EXDATE:20250317T140000Z")
+	assert.Contains(t, string(data), "RECURRENCE-ID:20250310T140000Z")
+
+	root, err := ical.Parse(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	events, err := ical.ParseEvents(root, time.UTC)
+	require.NoError(t, err)
+	occurrences := ical.ExpandEvents(events, start, start.AddDate(0, 0, 21))
+	require.Len(t, occurrences, 2)
+	assert.Equal(t, "Standup", occurrences[0].Summary)
+	assert.Equal(t, "Standup (moved)", occurrences[1].Summary)
+	assert.True(t, occurrences[1].Start.Equal(moved.Add(2*time.Hour)))
+}
@@ -0,0 +1,346 @@
+package unit
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/ai"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Monday 2025-03-03 08:00 in Johannesburg, which has no daylight saving time
+var schedulerNow = time.Date(2025, 3, 3, 8, 0, 0, 0, mustLoadLocation("Africa/Johannesburg"))
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+func schedulerTask(title string, minutes int) models.Task {
+	return models.Task{ID: uuid.New(), Title: title, TimeEstimate: &minutes, Status: "pending"}
+}
+
+func schedulerProfile() *ai.EnergyProfile {
+	return &ai.EnergyProfile{
+		TimeSlots:          map[string]int{"9": 8, "12": 6, "15": 7},
+		PreferredStartHour: 9,
+		PreferredEndHour:   17,
+		WorkDays:           []string{"monday", "tuesday", "wednesday", "thursday", "friday"},
+		BreakDuration:      15,
+	}
+}
+
+func blocksFor(result ai.ScheduleResult, taskID uuid.UUID) []models.ScheduledTask {
+	var blocks []models.ScheduledTask
+	for _, block := range result.Suggestions {
+		if block.TaskID != nil && *block.TaskID == taskID {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// assertValidPlan checks that blocks stay apart from each other and from existing events
+// by the break duration and that dependencies and deadlines hold
+func assertValidPlan(t *testing.T, in ai.SchedulerInput, result ai.ScheduleResult) {
+	t.Helper()
+	buffer := time.Duration(in.Profile.BreakDuration) * time.Minute
+	horizon := in.Now.AddDate(0, 0, ai.SchedulingHorizonDays+1)
+
+	for i, a := range result.Suggestions {
+		require.True(t, a.End.After(a.Start))
+		assert.False(t, a.Start.Before(in.Now), "%s starts in the past", a.Title)
+		assert.True(t, a.End.Before(horizon), "%s ends after the horizon", a.Title)
+		for _, b := range result.Suggestions[i+1:] {
+			assert.False(t, a.Start.Before(b.End.Add(buffer)) && b.Start.Before(a.End.Add(buffer)),
+				"%s and %s are closer than the break duration", a.Title, b.Title)
+		}
+		for _, event := range in.Events {
+			assert.False(t, a.Start.Before(event.End.Add(buffer)) && event.Start.Before(a.End.Add(buffer)),
+				"%s overlaps %s", a.Title, event.Title)
+		}
+	}
+
+	ends := map[uuid.UUID]time.Time{}
+	starts := map[uuid.UUID]time.Time{}
+	for _, block := range result.Suggestions {
+		id := *block.TaskID
+		if block.End.After(ends[id]) {
+			ends[id] = block.End
+		}
+		if first, ok := starts[id]; !ok || block.Start.Before(first) {
+			starts[id] = block.Start
+		}
+	}
+	for _, task := range in.Tasks {
+		if end, ok := ends[task.ID]; ok && task.DueDate != nil && task.DueDate.After(in.Now) {
+			assert.False(t, end.After(*task.DueDate), "%s ends after it is due", task.Title)
+		}
+	}
+	for _, dep := range in.Dependencies {
+		start, scheduled := starts[dep.TaskID]
+		if !scheduled {
+			continue
+		}
+		end, ok := ends[dep.DependsOnID]
+		if assert.True(t, ok, "dependent scheduled without its prerequisite") {
+			assert.False(t, start.Before(end), "dependent starts before its prerequisite ends")
+		}
+	}
+}
+
+func TestSolveScheduleSplitsLongTasks(t *testing.T) {
+	task := schedulerTask("Write report", 300)
+	in := ai.SchedulerInput{Tasks: []models.Task{task}, Profile: schedulerProfile(), Now: schedulerNow}
+	result := ai.SolveSchedule(in)
+
+	require.Empty(t, result.Unschedulable)
+	blocks := blocksFor(result, task.ID)
+	require.Len(t, blocks, 3)
+	var total time.Duration
+	for i, block := range blocks {
+		assert.Equal(t, fmt.Sprintf("Write report (%d/3)", i+1), block.Title)
+		assert.LessOrEqual(t, block.End.Sub(block.Start), 2*time.Hour)
+		assert.True(t, block.CreatedByAI)
+		total += block.End.Sub(block.Start)
+	}
+	assert.Equal(t, 5*time.Hour, total)
+	assertValidPlan(t, in, result)
+}
+
+func TestSolveScheduleDependencies(t *testing.T) {
+	design := schedulerTask("Design", 120)
+	build := schedulerTask("Build", 180)
+	loopA := schedulerTask("Chicken", 60)
+	loopB := schedulerTask("Egg", 60)
+	after := schedulerTask("Omelette", 60)
+	waiting := schedulerTask("Deploy", 60)
+	external := schedulerTask("Approval", 60)
+
+	in := ai.SchedulerInput{
+		Tasks: []models.Task{build, design, loopA, loopB, after, waiting},
+		Dependencies: []models.TaskDependency{
+			{TaskID: build.ID, DependsOnID: design.ID, DependsOn: design},
+			{TaskID: loopA.ID, DependsOnID: loopB.ID, DependsOn: loopB},
+			{TaskID: loopB.ID, DependsOnID: loopA.ID, DependsOn: loopA},
+			{TaskID: after.ID, DependsOnID: loopA.ID, DependsOn: loopA},
+			{TaskID: waiting.ID, DependsOnID: external.ID, DependsOn: external},
+		},
+		Profile: schedulerProfile(),
+		Now:     schedulerNow,
+	}
+	result := ai.SolveSchedule(in)
+
+	require.NotEmpty(t, blocksFor(result, design.ID))
+	require.NotEmpty(t, blocksFor(result, build.ID))
+	assertValidPlan(t, in, result)
+
+	reasons := map[string]string{}
+	for _, u := range result.Unschedulable {
+		reasons[u.Title] = u.Reason
+	}
+	assert.Len(t, reasons, 4)
+	assert.Contains(t, reasons["Chicken"], "circular dependency with \"Egg\"")
+	assert.Contains(t, reasons["Egg"], "circular dependency with \"Chicken\"")
+	assert.Contains(t, reasons["Omelette"], "part of a circular dependency")
+	assert.Contains(t, reasons["Deploy"], "\"Approval\", which is not finished or scheduled")
+}
+
+func TestSolveScheduleDeadlines(t *testing.T) {
+	urgent := schedulerTask("Submit form", 90)
+	due := schedulerNow.Add(3 * time.Hour) // Monday 11:00
+	urgent.DueDate = &due
+	important := schedulerTask("Quarterly plan", 120)
+	priority := 5
+	important.Priority = &priority
+	impossible := schedulerTask("Thesis", 240)
+	tooSoon := schedulerNow.Add(4 * time.Hour)
+	impossible.DueDate = &tooSoon
+
+	// A meeting fills Monday 9:00-10:00, leaving 10:15-11:00 before the form is due
+	meeting := models.ScheduledTask{
+		Title: "Meeting",
+		Start: schedulerNow.Add(time.Hour),
+		End:   schedulerNow.Add(2 * time.Hour),
+	}
+	in := ai.SchedulerInput{
+		Tasks:   []models.Task{important, impossible, urgent},
+		Events:  []models.ScheduledTask{meeting},
+		Profile: schedulerProfile(),
+		Now:     schedulerNow,
+	}
+	result := ai.SolveSchedule(in)
+	assertValidPlan(t, in, result)
+
+	require.Len(t, result.Unschedulable, 2)
+	titles := []string{result.Unschedulable[0].Title, result.Unschedulable[1].Title}
+	assert.ElementsMatch(t, []string{"Submit form", "Thesis"}, titles)
+	for _, u := range result.Unschedulable {
+		assert.Contains(t, u.Reason, "not enough free time before it is due")
+	}
+	assert.NotEmpty(t, blocksFor(result, important.ID))
+
+	// Without the meeting the form fits in before it is due
+	in.Events = nil
+	result = ai.SolveSchedule(in)
+	assertValidPlan(t, in, result)
+	blocks := blocksFor(result, urgent.ID)
+	require.NotEmpty(t, blocks)
+	assert.False(t, blocks[len(blocks)-1].End.After(due))
+}
+
+func TestSolveScheduleZoneLimits(t *testing.T) {
+	loc := schedulerNow.Location()
+	limit := 1
+	zone := models.CalendarZone{
+		ID:                     uuid.New(),
+		Name:                   "Deep work",
+		StartTime:              time.Date(1970, 1, 1, 9, 0, 0, 0, loc),
+		EndTime:                time.Date(1970, 1, 1, 12, 0, 0, 0, loc),
+		DaysOfWeek:             models.NewWeekdaySet(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday),
+		IsActive:               true,
+		AllowScheduling:        true,
+		MaxEventsPerDay:        &limit,
+		SchedulingMode:         "whitelist",
+		AllowedTaskCategories:  []string{"work"},
+		AllowNonZoneScheduling: true,
+	}
+
+	var tasks []models.Task
+	for i := 0; i < 3; i++ {
+		task := schedulerTask(fmt.Sprintf("Work %d", i), 60)
+		task.Category = "work"
+		tasks = append(tasks, task)
+	}
+	personal := schedulerTask("Groceries", 60)
+	personal.Category = "personal"
+	tasks = append(tasks, personal)
+
+	in := ai.SchedulerInput{
+		Tasks:   tasks,
+		Zones:   []models.CalendarZone{zone},
+		Profile: schedulerProfile(),
+		Now:     schedulerNow,
+	}
+	result := ai.SolveSchedule(in)
+	assertValidPlan(t, in, result)
+
+	// Outside zones the default working hours are used
+	days := map[string]int{}
+	for _, block := range result.Suggestions {
+		local := block.Start.In(loc)
+		if local.Hour() >= 9 && local.Hour() < 12 {
+			days[local.Format("2006-01-02")]++
+		}
+	}
+	for day, count := range days {
+		assert.Equal(t, 1, count, "zone limit exceeded on %s", day)
+	}
+	assert.Empty(t, result.Unschedulable)
+
+	// With non-zone scheduling off, only zone time is available
+	in.Zones[0].AllowNonZoneScheduling = false
+	result = ai.SolveSchedule(in)
+	assertValidPlan(t, in, result)
+	require.Len(t, result.Unschedulable, 1)
+	assert.Equal(t, "Groceries", result.Unschedulable[0].Title)
+	assert.Contains(t, result.Unschedulable[0].Reason, "no calendar zone or working hours")
+	for _, task := range tasks[:3] {
+		require.Len(t, blocksFor(result, task.ID), 1)
+	}
+}
+
+func schedulerWorkload(n int) ai.SchedulerInput {
+	rng := rand.New(rand.NewSource(42))
+	loc := schedulerNow.Location()
+	estimates := []int{15, 30, 45, 60, 90, 120, 180, 240}
+
+	in := ai.SchedulerInput{Profile: schedulerProfile(), Now: schedulerNow}
+	for i := 0; i < n; i++ {
+		task := schedulerTask(fmt.Sprintf("Task %03d", i), estimates[rng.Intn(len(estimates))])
+		priority := 1 + rng.Intn(5)
+		task.Priority = &priority
+		task.Category = []string{"work", "study", "personal"}[rng.Intn(3)]
+		if rng.Intn(4) == 0 {
+			due := schedulerNow.Add(time.Duration(1+rng.Intn(14*24)) * time.Hour)
+			task.DueDate = &due
+		}
+		in.Tasks = append(in.Tasks, task)
+		if i > 0 && rng.Intn(5) == 0 {
+			prereq := in.Tasks[rng.Intn(i)]
+			in.Dependencies = append(in.Dependencies, models.TaskDependency{TaskID: task.ID, DependsOnID: prereq.ID, DependsOn: prereq})
+		}
+	}
+	for day := 0; day < 14; day++ {
+		date := schedulerNow.AddDate(0, 0, day)
+		for _, hour := range []int{10, 13, 16} {
+			start := time.Date(date.Year(), date.Month(), date.Day(), hour, 0, 0, 0, loc)
+			in.Events = append(in.Events, models.ScheduledTask{Title: "Meeting", Start: start, End: start.Add(45 * time.Minute)})
+		}
+	}
+	in.Zones = []models.CalendarZone{{
+		ID:                     uuid.New(),
+		Name:                   "Evening study",
+		StartTime:              time.Date(1970, 1, 1, 18, 0, 0, 0, loc),
+		EndTime:                time.Date(1970, 1, 1, 21, 0, 0, 0, loc),
+		DaysOfWeek:             models.NewWeekdaySet(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday),
+		IsActive:               true,
+		AllowScheduling:        true,
+		SchedulingMode:         "whitelist",
+		AllowedTaskCategories:  []string{"study"},
+		AllowNonZoneScheduling: true,
+	}}
+	return in
+}
+
+func TestSolveScheduleLargeWorkload(t *testing.T) {
+	in := schedulerWorkload(500)
+	result := ai.SolveSchedule(in)
+	assertValidPlan(t, in, result)
+
+	scheduled := map[uuid.UUID]bool{}
+	for _, block := range result.Suggestions {
+		scheduled[*block.TaskID] = true
+	}
+	// Two weeks cannot hold 500 tasks; every task is either planned or explained
+	assert.Equal(t, len(in.Tasks), len(scheduled)+len(result.Unschedulable))
+	assert.NotEmpty(t, result.Suggestions)
+	for _, u := range result.Unschedulable {
+		assert.NotEmpty(t, u.Reason)
+	}
+}
+
+func TestSolveScheduleIsDeterministic(t *testing.T) {
+	in := schedulerWorkload(120)
+	first := ai.SolveSchedule(in)
+
+	shuffled := in
+	shuffled.Tasks = append([]models.Task(nil), in.Tasks...)
+	shuffled.Dependencies = append([]models.TaskDependency(nil), in.Dependencies...)
+	rand.New(rand.NewSource(7)).Shuffle(len(shuffled.Tasks), func(i, j int) {
+		shuffled.Tasks[i], shuffled.Tasks[j] = shuffled.Tasks[j], shuffled.Tasks[i]
+	})
+	rand.New(rand.NewSource(8)).Shuffle(len(shuffled.Dependencies), func(i, j int) {
+		shuffled.Dependencies[i], shuffled.Dependencies[j] = shuffled.Dependencies[j], shuffled.Dependencies[i]
+	})
+	second := ai.SolveSchedule(shuffled)
+
+	assert.Equal(t, first, second)
+}
+
+// BenchmarkSolveSchedule plans 500 tasks over two weeks
+func BenchmarkSolveSchedule(b *testing.B) {
+	in := schedulerWorkload(500)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ai.SolveSchedule(in)
+	}
+}
@@ -0,0 +1,164 @@
+package unit
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/srs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The expected values are those of the reference FSRS-5 implementation, fsrs-rs 1.4
+// (github.com/open-spaced-repetition/fsrs-rs), whose DEFAULT_PARAMETERS are
+// srs.DefaultParameters. Each row is one call of
+//
+//	FSRS::new(Some(&DEFAULT_PARAMETERS))?.next_states(state, 0.9, days_elapsed)?
+//
+// starting from None, with days_elapsed the previous row's interval and the returned
+// interval rounded to whole days. fsrs-rs works in f32, hence the tolerances.
+func TestFSRSReferenceOutputs(t *testing.T) {
+	fsrs := srs.NewFSRS(srs.DefaultParameters, 0.9)
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+	// First reviews: stability is the rating's weight, difficulty w4 - e^(w5(G-1)) + 1
+	first := []struct {
+		rating     srs.Rating
+		stability  float64
+		difficulty float64
+		interval   int
+	}{
+		{srs.Again, 0.40255, 7.1949, 1},
+		{srs.Hard, 1.18385, 6.4883, 1},
+		{srs.Good, 3.173, 5.2824, 3},
+		{srs.Easy, 15.69105, 3.2245, 16},
+	}
+	for _, want := range first {
+		m := fsrs.Review(srs.Memory{}, srs.GradeFromRating(want.rating), start)
+		assert.InDelta(t, want.stability, m.Stability, 1e-4, "rating %d", want.rating)
+		assert.InDelta(t, want.difficulty, m.Difficulty, 1e-4, "rating %d", want.rating)
+		assert.Equal(t, want.interval, fsrs.Interval(m), "rating %d", want.rating)
+	}
+
+	// A card reviewed each time it falls due
+	steps := []struct {
+		rating     srs.Rating
+		stability  float64
+		difficulty float64
+		interval   int
+	}{
+		{srs.Good, 3.173, 5.2824, 3},
+		{srs.Good, 10.7389, 5.2730, 11},
+		{srs.Good, 34.5776, 5.2635, 35},
+		{srs.Again, 3.8182, 6.7842, 4},
+		{srs.Good, 11.0196, 6.7679, 11},
+		{srs.Easy, 63.6648, 6.2295, 64},
+		{srs.Hard, 85.3394, 6.8247, 85},
+	}
+	var m srs.Memory
+	now := start
+	for i, step := range steps {
+		m, _ = srs.Review(m, srs.GradeFromRating(step.rating), now, fsrs, srs.DefaultParameters, 0.9)
+		assert.InDelta(t, step.stability, m.Stability, 1e-3, "review %d", i+1)
+		assert.InDelta(t, step.difficulty, m.Difficulty, 1e-3, "review %d", i+1)
+		require.Equal(t, step.interval, fsrs.Interval(m), "review %d", i+1)
+		now = now.AddDate(0, 0, step.interval)
+	}
+	assert.Equal(t, 1, m.Lapses)
+}
+
+func TestFSRSRetention(t *testing.T) {
+	assert.InDelta(t, 0.9, srs.Retrievability(10, 10), 1e-9, "recall is 90% after stability days")
+	assert.Equal(t, 10, srs.NewFSRS(srs.DefaultParameters, 0.9).IntervalFor(10))
+	assert.Equal(t, 24, srs.NewFSRS(srs.DefaultParameters, 0.8).IntervalFor(10))
+	assert.Equal(t, 5, srs.NewFSRS(srs.DefaultParameters, 0.95).IntervalFor(10))
+}
+
+func TestSM2MatchesClassicSchedule(t *testing.T) {
+	var m srs.Memory
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	var intervals []int
+	for _, quality := range []int{5, 4, 4, 3, 1, 4} {
+		m = srs.SM2{}.Review(m, srs.GradeFromQuality(quality), now)
+		intervals = append(intervals, m.Interval)
+	}
+	assert.Equal(t, []int{1, 6, 15, 39, 1, 1}, intervals)
+	assert.InDelta(t, 1.92, m.Easiness, 1e-9)
+}
+
+func TestSwitchingSchedulersKeepsState(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	sm2 := srs.SM2{}
+	fsrs := srs.NewFSRS(srs.DefaultParameters, 0.9)
+
+	// Reviews under SM-2 still track FSRS state, and the reverse
+	var m srs.Memory
+	for _, quality := range []int{4, 4, 5} {
+		var interval int
+		m, interval = srs.Review(m, srs.GradeFromQuality(quality), now, sm2, srs.DefaultParameters, 0.9)
+		assert.Equal(t, m.Interval, interval)
+		now = now.AddDate(0, 0, interval)
+	}
+	assert.Equal(t, 3, m.Repetitions)
+	assert.Greater(t, m.Stability, 0.0)
+	sm2State := m
+
+	m, interval := srs.Review(m, srs.GradeFromRating(srs.Good), now, fsrs, srs.DefaultParameters, 0.9)
+	assert.Equal(t, fsrs.Interval(m), interval)
+	assert.Equal(t, 4, m.Repetitions, "SM-2 keeps counting while FSRS schedules")
+	assert.Greater(t, m.Interval, sm2State.Interval)
+
+	// Cards reviewed before FSRS existed are seeded from their SM-2 interval and easiness
+	legacy := srs.Memory{Easiness: 2.5, Interval: 20, Repetitions: 4, LastReviewed: now}
+	seeded := fsrs.Seed(legacy)
+	assert.InDelta(t, 20, seeded.Stability, 1e-9)
+	assert.Equal(t, 20, fsrs.Interval(legacy), "switching to FSRS at 90% keeps the due date")
+	assert.Equal(t, legacy.Easiness, seeded.Easiness)
+	assert.Equal(t, legacy.Interval, seeded.Interval)
+
+	// A good review at the due date grows the interval about as SM-2 would have
+	next := fsrs.Review(legacy, srs.GradeFromRating(srs.Good), now.AddDate(0, 0, 20))
+	assert.InDelta(t, 20*2.5, next.Stability, 1)
+
+	// A harder card is seeded as more difficult
+	hard := fsrs.Seed(srs.Memory{Easiness: 1.3, Interval: 20, Repetitions: 4, LastReviewed: now})
+	assert.Greater(t, hard.Difficulty, seeded.Difficulty)
+}
+
+func TestFitImprovesPredictions(t *testing.T) {
+	truth := srs.DefaultParameters
+	truth[8] = 1.0  // memories grow more slowly than the defaults assume
+	truth[11] = 1.2 // and survive lapses worse
+	fsrs := srs.NewFSRS(truth, 0.9)
+	rng := rand.New(rand.NewSource(42))
+
+	histories := make([][]srs.ReviewLog, 0, 150)
+	for card := 0; card < 150; card++ {
+		now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+		rating := srs.Rating(rng.Intn(3) + 2)
+		history := []srs.ReviewLog{{Rating: rating}}
+		m, interval := srs.Review(srs.Memory{}, srs.GradeFromRating(rating), now, fsrs, truth, 0.9)
+		for review := 0; review < 6; review++ {
+			elapsed := max(1, interval+rng.Intn(5)-2)
+			rating = srs.Again
+			if rng.Float64() < srs.Retrievability(float64(elapsed), m.Stability) {
+				rating = srs.Rating(rng.Intn(3) + 2)
+			}
+			history = append(history, srs.ReviewLog{Rating: rating, ElapsedDays: float64(elapsed)})
+			now = now.AddDate(0, 0, elapsed)
+			m, interval = srs.Review(m, srs.GradeFromRating(rating), now, fsrs, truth, 0.9)
+		}
+		histories = append(histories, history)
+	}
+
+	_, err := srs.Fit(histories[:10], srs.DefaultParameters)
+	assert.ErrorIs(t, err, srs.ErrTooFewReviews)
+
+	result, err := srs.Fit(histories, srs.DefaultParameters)
+	require.NoError(t, err)
+	assert.Equal(t, 900, result.Reviews)
+	assert.True(t, result.Parameters.Valid())
+	assert.Less(t, result.LossAfter, result.LossBefore)
+	assert.Less(t, result.Parameters[8], srs.DefaultParameters[8], "fit moves towards the slower growth")
+}
@@ -0,0 +1,90 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/TheoMKgosi/The-hub/internal/booking"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/studyplan"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// studyZone is for study 18:00-20:00 every day
+func studyZone() models.CalendarZone {
+	return models.CalendarZone{
+		ID:        uuid.New(),
+		Name:      "Evening study",
+		Category:  "study",
+		StartTime: bookingAt(3, 18, 0),
+		EndTime:   bookingAt(3, 20, 0),
+		IsActive:  true,
+	}
+}
+
+func blockSpans(blocks []studyplan.Block) []string {
+	spans := make([]string, len(blocks))
+	for i, block := range blocks {
+		spans[i] = block.Start.In(schedulerNow.Location()).Format("Mon 15:04") + "-" +
+			block.End.In(schedulerNow.Location()).Format("15:04") + " " + block.Kind
+	}
+	return spans
+}
+
+func TestStudyPlanSpreadsStudyAndReviewsFirst(t *testing.T) {
+	loc := schedulerNow.Location()
+	deck := studyplan.Deck{ID: uuid.New(), Name: "Anatomy"}
+	for i := 0; i < 10; i++ {
+		deck.Due = append(deck.Due, bookingAt(3, 12, 0))
+	}
+
+	plan := studyplan.Build(studyplan.Input{
+		Title:        "Anatomy exam",
+		Now:          schedulerNow,
+		Deadline:     bookingAt(6, 0, 0),
+		StudyMinutes: 240,
+		Zones:        []models.CalendarZone{studyZone()},
+		Busy: booking.Busy([]models.ScheduledTask{
+			{ID: uuid.New(), Start: bookingAt(4, 18, 0), End: bookingAt(4, 19, 0)},
+		}, schedulerNow, bookingAt(6, 0, 0), loc),
+		Decks: []studyplan.Deck{deck},
+	})
+
+	assert.Equal(t, []string{
+		"Mon 18:00-18:15 review",
+		"Mon 18:30-20:00 study",
+		"Tue 19:00-20:00 study",
+		"Wed 18:00-19:30 study",
+	}, blockSpans(plan.Blocks))
+	assert.Equal(t, "Review: Anatomy", plan.Blocks[0].Title)
+	assert.Equal(t, []uuid.UUID{deck.ID}, plan.Blocks[0].DeckIDs)
+	assert.Equal(t, "Study: Anatomy exam (3/3)", plan.Blocks[3].Title)
+	assert.Equal(t, 240, plan.StudyMinutes)
+	assert.Equal(t, 15, plan.ReviewMinutes)
+	assert.Zero(t, plan.UnplannedMinutes)
+}
+
+func TestStudyPlanReportsStudyThatDoesNotFit(t *testing.T) {
+	plan := studyplan.Build(studyplan.Input{
+		Title:         "Anatomy exam",
+		Now:           schedulerNow,
+		Deadline:      bookingAt(5, 0, 0),
+		StudyMinutes:  300,
+		Zones:         []models.CalendarZone{studyZone()},
+		MinutesPerDay: 90,
+	})
+
+	require.Len(t, plan.Blocks, 2)
+	assert.Equal(t, 180, plan.StudyMinutes)
+	assert.Equal(t, 120, plan.UnplannedMinutes)
+
+	plan = studyplan.Build(studyplan.Input{
+		Title:        "Anatomy exam",
+		Now:          schedulerNow,
+		Deadline:     bookingAt(5, 0, 0),
+		StudyMinutes: 60,
+	})
+	assert.Empty(t, plan.Blocks, "no study zones leaves nothing planned")
+	assert.Equal(t, 60, plan.UnplannedMinutes)
+}
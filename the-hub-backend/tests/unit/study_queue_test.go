@@ -0,0 +1,102 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/studyqueue"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func studyCard(deckID uuid.UUID, reviewed bool, due time.Time) models.Card {
+	card := models.Card{ID: uuid.New(), DeckID: deckID, NextReview: due, CreatedAt: due}
+	if reviewed {
+		card.LastReviewed = due.AddDate(0, 0, -3)
+	}
+	return card
+}
+
+func TestBuildStudyQueueAppliesLimitsUpTheTree(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	root, child := uuid.New(), uuid.New()
+	decks := []studyqueue.Deck{
+		{ID: root, Limits: studyqueue.Limits{New: 3, Reviews: 0}, Studied: studyqueue.Counts{New: 1}},
+		{ID: child, ParentID: &root, Limits: studyqueue.Limits{New: 5, Reviews: 1}},
+	}
+	var due []models.Card
+	for i := 0; i < 4; i++ {
+		due = append(due, studyCard(child, false, now.Add(-time.Duration(i)*time.Hour)))
+	}
+	due = append(due,
+		studyCard(child, true, now.Add(-2*time.Hour)),
+		studyCard(child, true, now.Add(-time.Hour)),
+		studyCard(root, true, now.Add(-time.Hour)),
+		studyCard(root, true, now.Add(time.Hour)), // not due yet
+	)
+
+	queue := studyqueue.Build(root, decks, due, now)
+	// The child allows one review; the root's reviews are unlimited; the root's new
+	// limit of 3, with 1 studied today, caps the child's new cards at 2
+	assert.Equal(t, 2, queue.Reviews)
+	assert.Equal(t, 2, queue.New)
+	assert.Equal(t, 3, queue.Held)
+	assert.Equal(t, studyqueue.Counts{New: 0, Reviews: -1}, queue.Left)
+	require.Len(t, queue.Cards, 4)
+	assert.Equal(t, due[4].ID, queue.Cards[0].ID, "the most overdue review first")
+	assert.Equal(t, due[3].ID, queue.Cards[2].ID, "new cards in the order they were added")
+}
+
+func TestBuildStudyQueueSkipsSuspendedAndBuried(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	deckID := uuid.New()
+	suspended := studyCard(deckID, true, now)
+	suspended.Suspended = true
+	buried := studyCard(deckID, true, now)
+	tomorrow := studyqueue.BuryUntil(now, time.UTC)
+	buried.BuriedUntil = &tomorrow
+	wasBuried := studyCard(deckID, true, now)
+	yesterday := now.Add(-time.Hour)
+	wasBuried.BuriedUntil = &yesterday
+
+	queue := studyqueue.Build(deckID, []studyqueue.Deck{{ID: deckID}}, []models.Card{suspended, buried, wasBuried}, now)
+	require.Len(t, queue.Cards, 1)
+	assert.Equal(t, wasBuried.ID, queue.Cards[0].ID)
+	assert.Equal(t, studyqueue.Counts{New: -1, Reviews: -1}, queue.Left)
+	assert.Equal(t, time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC), tomorrow)
+}
+
+func TestCustomStudy(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	deckID := uuid.New()
+	later := studyCard(deckID, true, now.AddDate(0, 0, 2))
+	overdue := studyCard(deckID, true, now.AddDate(0, 0, -2))
+	fresh := studyCard(deckID, false, now.Add(-time.Hour))
+	cards := []models.Card{later, overdue, fresh}
+
+	picked := studyqueue.Custom(cards, false, true, 0, now)
+	require.Len(t, picked, 3)
+	assert.Equal(t, overdue.ID, picked[0].ID)
+	assert.Len(t, studyqueue.Custom(cards, true, true, 0, now), 2)
+	assert.Len(t, studyqueue.Custom(cards, false, false, 0, now), 2)
+	assert.Len(t, studyqueue.Custom(cards, false, true, 1, now), 1)
+}
+
+func TestCardTags(t *testing.T) {
+	tags := models.NewCardTags([]string{"Networking", " TCP  IP ", "networking", ""})
+	assert.Equal(t, models.CardTags{"networking", "tcp-ip"}, tags)
+	assert.True(t, tags.Has("tcp-ip"))
+
+	value, err := tags.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `["networking","tcp-ip"]`, value)
+
+	var scanned models.CardTags
+	require.NoError(t, scanned.Scan([]byte(`["b","A"]`)))
+	assert.Equal(t, models.CardTags{"a", "b"}, scanned)
+	require.NoError(t, scanned.Scan(nil))
+	assert.Empty(t, scanned)
+	assert.Error(t, scanned.Scan(42))
+}
@@ -0,0 +1,55 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/srs"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveStudySessionLeavesOutPauses(t *testing.T) {
+	start := time.Date(2025, 5, 1, 18, 0, 0, 0, time.UTC)
+	session := models.StudySession{Status: models.StudySessionActive, StartedAt: start}
+	assert.True(t, session.Live())
+
+	session.Pause(start.Add(10 * time.Minute))
+	assert.Equal(t, models.StudySessionPaused, session.Status)
+	assert.Equal(t, 10*time.Minute, session.ActiveDuration(start.Add(time.Hour)), "time stands still while paused")
+
+	session.Resume(start.Add(25 * time.Minute))
+	assert.Equal(t, 15*60, session.PausedSeconds)
+	assert.Nil(t, session.PausedAt)
+
+	// Stopping while paused ends the pause first
+	session.Pause(start.Add(40 * time.Minute))
+	session.Stop(start.Add(50 * time.Minute))
+	assert.Equal(t, models.StudySessionCompleted, session.Status)
+	assert.False(t, session.Live())
+	assert.Equal(t, 25*60, session.PausedSeconds)
+	assert.Equal(t, 25, session.DurationMin)
+	assert.Equal(t, 25*time.Minute, session.ActiveDuration(start.Add(3*time.Hour)))
+}
+
+func TestSummarizeSession(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	events := []srs.ReviewEvent{
+		{CardID: a, Rating: srs.Again, TimeTakenMs: 9000},
+		{CardID: b, Rating: srs.Good, TimeTakenMs: 3000},
+		{CardID: a, Rating: srs.Hard},
+		{CardID: b, Rating: srs.Easy, TimeTakenMs: 1500},
+	}
+
+	summary := srs.SummarizeSession(events, 2*time.Minute)
+	assert.Equal(t, 4, summary.Reviews)
+	assert.Equal(t, 2, summary.CardsReviewed)
+	assert.Equal(t, 3, summary.Correct)
+	assert.Equal(t, 0.75, summary.Accuracy)
+	assert.Equal(t, 120, summary.ActiveSeconds)
+	assert.Equal(t, 30.0, summary.SecondsPerCard)
+	assert.Equal(t, 4.5, summary.AverageAnswerSeconds, "untimed reviews are left out")
+
+	assert.Equal(t, srs.SessionSummary{ActiveSeconds: 60}, srs.SummarizeSession(nil, time.Minute))
+}
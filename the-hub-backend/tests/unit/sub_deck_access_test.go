@@ -0,0 +1,107 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/config"
+	"github.com/TheoMKgosi/The-hub/internal/handlers"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func openDeckAccessDB(t *testing.T) *gorm.DB {
+	db := openCalendarSyncDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Deck{}, &models.DeckUser{}, &models.Card{}, &models.CardProgress{},
+		&models.CardReview{}, &models.SRSParameters{}, &models.StudySession{}))
+	config.SetTestDB(db)
+	return db
+}
+
+func deckAccessRouter(userID uuid.UUID) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Next()
+	})
+	router.POST("/cards/review/:ID", handlers.ReviewCard)
+	router.POST("/cards/bury/:ID", handlers.BuryCard)
+	router.PATCH("/cards/:ID", handlers.UpdateCard)
+	router.POST("/study-sessions/start", handlers.StartStudySession)
+	return router
+}
+
+func deckAccessRequest(userID uuid.UUID, method, path, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	deckAccessRouter(userID).ServeHTTP(recorder, req)
+	return recorder
+}
+
+// createSubDeckCard creates a deck owned by owner with a sub-deck holding one card
+func createSubDeckCard(t *testing.T, db *gorm.DB, owner uuid.UUID) (models.Deck, models.Deck, models.Card) {
+	parent := models.Deck{Name: "Networking", UserID: owner}
+	require.NoError(t, db.Create(&parent).Error)
+	sub := models.Deck{Name: "TCP", UserID: owner, ParentID: &parent.ID}
+	require.NoError(t, db.Create(&sub).Error)
+	card := models.Card{DeckID: sub.ID, Question: "What does SYN start?", Answer: "A handshake", Easiness: 2.5, Interval: 1, NextReview: time.Now()}
+	require.NoError(t, db.Create(&card).Error)
+	return parent, sub, card
+}
+
+func TestParentDeckViewerStudiesSubDeckCards(t *testing.T) {
+	db := openDeckAccessDB(t)
+
+	owner, viewer := uuid.New(), uuid.New()
+	parent, _, card := createSubDeckCard(t, db, owner)
+	require.NoError(t, db.Create(&models.DeckUser{DeckID: parent.ID, UserID: viewer, Role: models.DeckRoleViewer}).Error)
+
+	request := func(userID uuid.UUID, method, path, body string) int {
+		return deckAccessRequest(userID, method, path, body).Code
+	}
+
+	// The viewer's role in the parent deck covers the sub-deck's cards
+	assert.Equal(t, http.StatusOK, request(viewer, http.MethodPost, "/cards/review/"+card.ID.String(), `{"rating": 3}`))
+	assert.Equal(t, http.StatusOK, request(viewer, http.MethodPost, "/cards/bury/"+card.ID.String(), ""))
+
+	var progress models.CardProgress
+	require.NoError(t, db.Where("card_id = ? AND user_id = ?", card.ID, viewer).First(&progress).Error)
+	assert.Equal(t, 1, progress.Repetitions)
+	assert.NotNil(t, progress.BuriedUntil)
+
+	var owners models.Card
+	require.NoError(t, db.First(&owners, "id = ?", card.ID).Error)
+	assert.Zero(t, owners.Repetitions, "the owner's review state is untouched")
+
+	// A viewer still cannot edit, and someone without a role cannot see the card
+	assert.Equal(t, http.StatusForbidden, request(viewer, http.MethodPatch, "/cards/"+card.ID.String(), `{"question": "Q"}`))
+	assert.Equal(t, http.StatusNotFound, request(uuid.New(), http.MethodPost, "/cards/review/"+card.ID.String(), `{"rating": 3}`))
+}
+
+func TestParentDeckSessionRecordsSubDeckReviews(t *testing.T) {
+	db := openDeckAccessDB(t)
+
+	owner := uuid.New()
+	parent, _, card := createSubDeckCard(t, db, owner)
+
+	started := deckAccessRequest(owner, http.MethodPost, "/study-sessions/start", `{"deck_id": "`+parent.ID.String()+`"}`)
+	require.Equal(t, http.StatusCreated, started.Code, started.Body.String())
+	var session models.StudySession
+	require.NoError(t, db.Where("user_id = ? AND deck_id = ?", owner, parent.ID).First(&session).Error)
+
+	// A card of the sub-deck, reviewed from the parent deck's queue, counts in its session
+	require.Equal(t, http.StatusOK, deckAccessRequest(owner, http.MethodPost, "/cards/review/"+card.ID.String(), `{"rating": 3}`).Code)
+	var review models.CardReview
+	require.NoError(t, db.Where("card_id = ? AND user_id = ?", card.ID, owner).First(&review).Error)
+	require.NotNil(t, review.StudySessionID)
+	assert.Equal(t, session.ID, *review.StudySessionID)
+}
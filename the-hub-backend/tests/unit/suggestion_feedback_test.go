@@ -0,0 +1,93 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/ai"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// suggestionAt is a suggestion for hour on Monday 2025-03-03 in the scheduler tests' timezone
+func suggestionAt(hour int, status string) models.AIRecommendation {
+	start := time.Date(2025, 3, 3, hour, 0, 0, 0, schedulerNow.Location())
+	return models.AIRecommendation{
+		SuggestedStart: start,
+		SuggestedEnd:   start.Add(time.Hour),
+		Status:         status,
+		Confidence:     0.5,
+	}
+}
+
+func TestSuggestionFeedbackAdjustsSlots(t *testing.T) {
+	loc := schedulerNow.Location()
+	var recs []models.AIRecommendation
+	for i := 0; i < 8; i++ {
+		recs = append(recs, suggestionAt(9, models.RecommendationAccepted), suggestionAt(15, models.RecommendationRejected))
+	}
+	feedback := ai.BuildSuggestionFeedback(recs, loc)
+
+	assert.Positive(t, feedback.Adjustment(9, time.Tuesday), "mornings are usually taken")
+	assert.Negative(t, feedback.Adjustment(15, time.Tuesday), "afternoons are usually refused")
+	assert.Zero(t, feedback.Adjustment(11, time.Tuesday), "no answers, no change")
+
+	var none *ai.SuggestionFeedback
+	assert.Zero(t, none.Adjustment(9, time.Monday))
+
+	// Feedback moves confidence towards how often the hour's suggestions are taken
+	profile := schedulerProfile()
+	task := schedulerTask("Report", 60)
+	morning := ai.TimeSlot{Start: recs[0].SuggestedStart, End: recs[0].SuggestedEnd}
+	afternoon := ai.TimeSlot{Start: recs[1].SuggestedStart, End: recs[1].SuggestedEnd}
+	without := ai.SuggestionConfidence(afternoon, task, profile)
+	profile.Feedback = feedback
+	assert.Less(t, ai.SuggestionConfidence(afternoon, task, profile), without)
+	assert.Greater(t, ai.SuggestionConfidence(morning, task, profile), ai.SuggestionConfidence(afternoon, task, profile))
+}
+
+func TestSuggestionFeedbackCountsMovedBlocks(t *testing.T) {
+	moved := suggestionAt(9, models.RecommendationModified)
+	final := moved.SuggestedStart.Add(5 * time.Hour)
+	moved.FinalStart = &final
+
+	feedback := ai.BuildSuggestionFeedback([]models.AIRecommendation{moved}, schedulerNow.Location())
+	assert.Equal(t, ai.SlotFeedback{Refused: 1}, feedback.Hours[9])
+	assert.Equal(t, ai.SlotFeedback{Taken: 1}, feedback.Hours[14])
+	assert.Equal(t, ai.SlotFeedback{Taken: 1, Refused: 1}, feedback.Weekdays[time.Monday])
+}
+
+func TestSuggestionReport(t *testing.T) {
+	moved := suggestionAt(10, models.RecommendationModified)
+	final := moved.SuggestedStart.Add(90 * time.Minute)
+	moved.FinalStart = &final
+	rejected := suggestionAt(15, models.RecommendationRejected)
+	rejected.Confidence = 0.2
+
+	report := ai.BuildSuggestionReport([]models.AIRecommendation{
+		suggestionAt(9, models.RecommendationAccepted),
+		suggestionAt(9, models.RecommendationExpired),
+		moved,
+		rejected,
+		suggestionAt(16, models.RecommendationPending),
+	}, schedulerNow.Location())
+
+	assert.Equal(t, 5, report.Suggested)
+	assert.Equal(t, 1, report.Accepted)
+	assert.Equal(t, 1, report.Modified)
+	assert.Equal(t, 1, report.Rejected)
+	assert.Equal(t, 1, report.Expired)
+	assert.Equal(t, 1, report.Pending)
+	assert.InDelta(t, 2.0/3, report.AcceptanceRate, 0.001, "expired and pending suggestions were never answered")
+	assert.Equal(t, 90.0, report.AverageShiftMinutes)
+	assert.Equal(t, 0.5, report.AcceptedConfidence)
+	assert.Equal(t, 0.2, report.RejectedConfidence)
+
+	require.Len(t, report.ByHour, 4)
+	assert.Equal(t, 9, report.ByHour[0].Hour)
+	assert.Equal(t, 2, report.ByHour[0].Suggested)
+	assert.Equal(t, 1.0, report.ByHour[0].AcceptanceRate)
+	require.Len(t, report.ByWeekday, 1)
+	assert.Equal(t, "Monday", report.ByWeekday[0].Weekday)
+}
@@ -48,7 +48,7 @@ func TestParseNaturalLanguage(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			title, _, _, dueDate, err := handlers.ParseNaturalLanguage(tt.input)
+			title, _, _, dueDate, err := handlers.ParseNaturalLanguage(tt.input, time.Now())
 
 			if err != nil {
 				t.Errorf("ParseNaturalLanguage() error = %v", err)
@@ -0,0 +1,122 @@
+package unit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TheoMKgosi/The-hub/internal/handlers"
+	"github.com/TheoMKgosi/The-hub/internal/models"
+	"github.com/TheoMKgosi/The-hub/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Honolulu is UTC-10 and Tongatapu UTC+13, neither with daylight saving time
+var timezoneCases = []struct {
+	name   string
+	tz     string
+	offset time.Duration
+}{
+	{name: "UTC-10", tz: "Pacific/Honolulu", offset: -10 * time.Hour},
+	{name: "UTC+13", tz: "Pacific/Tongatapu", offset: 13 * time.Hour},
+}
+
+func TestLoadTimezone(t *testing.T) {
+	loc, err := util.LoadTimezone("")
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	_, err = util.LoadTimezone("Local")
+	assert.Error(t, err)
+	_, err = util.LoadTimezone("Mars/Olympus_Mons")
+	assert.Error(t, err)
+
+	for _, tc := range timezoneCases {
+		loc, err := util.LoadTimezone(tc.tz)
+		require.NoError(t, err, tc.name)
+		_, offset := time.Date(2025, 6, 1, 12, 0, 0, 0, loc).Zone()
+		assert.Equal(t, int(tc.offset.Seconds()), offset, tc.name)
+	}
+}
+
+func TestUserLocation(t *testing.T) {
+	assert.Equal(t, time.UTC, util.UserLocation(""))
+	assert.Equal(t, time.UTC, util.UserLocation(`{"preferences":{"timezone":"Nowhere/Invalid"}}`))
+
+	for _, tc := range timezoneCases {
+		loc := util.UserLocation(`{"theme":{"mode":"dark"},"preferences":{"timezone":"` + tc.tz + `"}}`)
+		assert.Equal(t, tc.tz, loc.String(), tc.name)
+	}
+
+	// Without an authenticated user the request falls back to UTC
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Equal(t, time.UTC, util.GetUserLocation(c))
+}
+
+func TestStartOfDay(t *testing.T) {
+	for _, tc := range timezoneCases {
+		loc, _ := time.LoadLocation(tc.tz)
+		// 2025-03-10 08:00 UTC is still the 9th in Honolulu and already the 10th in Tonga
+		instant := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC).In(loc)
+		start := util.StartOfDay(instant)
+
+		assert.Equal(t, 0, start.Hour(), tc.name)
+		assert.Equal(t, instant.Day(), start.Day(), tc.name)
+		assert.Equal(t, time.Date(instant.Year(), instant.Month(), instant.Day(), 0, 0, 0, 0, time.UTC).Add(-tc.offset), start.UTC(), tc.name)
+	}
+}
+
+func TestParseNaturalLanguageInUserTimezone(t *testing.T) {
+	for _, tc := range timezoneCases {
+		t.Run(tc.name, func(t *testing.T) {
+			loc, _ := time.LoadLocation(tc.tz)
+			// Sunday 2025-03-09 22:00 UTC is Sunday noon in Honolulu and Monday 11:00 in Tonga
+			now := time.Date(2025, 3, 9, 22, 0, 0, 0, time.UTC).In(loc)
+
+			_, _, _, dueDate, err := handlers.ParseNaturalLanguage("call mom tomorrow at 5pm", now)
+			require.NoError(t, err)
+			require.NotNil(t, dueDate)
+
+			local := dueDate.In(loc)
+			assert.Equal(t, 17, local.Hour())
+			assert.Equal(t, now.AddDate(0, 0, 1).Day(), local.Day())
+			want := time.Date(local.Year(), local.Month(), local.Day(), 17, 0, 0, 0, time.UTC).Add(-tc.offset)
+			assert.True(t, want.Equal(*dueDate), "due %s, want %s", dueDate.UTC(), want)
+		})
+	}
+}
+
+func TestCalendarZoneInUserTimezone(t *testing.T) {
+	for _, tc := range timezoneCases {
+		t.Run(tc.name, func(t *testing.T) {
+			loc, _ := time.LoadLocation(tc.tz)
+			// Zones are saved as instants of the wall-clock hours in the user's timezone
+			zone := models.CalendarZone{
+				Name:            "Work",
+				StartTime:       time.Date(1970, 1, 1, 9, 0, 0, 0, loc),
+				EndTime:         time.Date(1970, 1, 1, 17, 0, 0, 0, loc),
+				DaysOfWeek:      models.NewWeekdaySet(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday),
+				IsActive:        true,
+				AllowScheduling: true,
+			}
+
+			monday10 := time.Date(2025, 3, 10, 10, 0, 0, 0, loc)
+			assert.True(t, zone.IsTimeInZone(monday10.UTC(), loc))
+			assert.False(t, zone.IsTimeInZone(monday10.Add(8*time.Hour).UTC(), loc))
+			assert.False(t, zone.IsTimeInZone(monday10.AddDate(0, 0, -1).UTC(), loc), "Sunday is not a work day")
+
+			busy := models.ScheduledTask{
+				Start: time.Date(2025, 3, 10, 11, 0, 0, 0, loc).UTC(),
+				End:   time.Date(2025, 3, 10, 12, 0, 0, 0, loc).UTC(),
+			}
+			slots := zone.GetAvailableTimeSlots(monday10.UTC(), loc, []models.ScheduledTask{busy}, time.Hour)
+			require.Len(t, slots, 7)
+			assert.Equal(t, 9, slots[0].In(loc).Hour())
+			assert.Equal(t, 10, slots[1].In(loc).Hour())
+			assert.Equal(t, 12, slots[2].In(loc).Hour())
+			assert.Equal(t, 16, slots[6].In(loc).Hour())
+		})
+	}
+}
@@ -188,3 +188,11 @@ func TestPasswordHashConsistency(t *testing.T) {
 		t.Error("Second hash should validate against original password")
 	}
 }
+
+func TestHashFeedToken(t *testing.T) {
+	// Must match the hex SHA-256 the token_hash migration computes for existing feeds
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := util.HashFeedToken("abc"); got != want {
+		t.Errorf("HashFeedToken() = %s, want %s", got, want)
+	}
+}
@@ -10,7 +10,7 @@ interface CalendarZone {
   color: string
   start_time: string
   end_time: string
-  days_of_week: string
+  days_of_week: string[] // empty means every day
   priority: number
   is_active: boolean
   allow_scheduling: boolean
@@ -126,9 +126,8 @@ export const useCalendarZonesStore = defineStore('calendarZones', () => {
       if (!zone.is_active) return false
 
       // Check if zone applies to this day
-      if (zone.days_of_week) {
-        const daysArray = zone.days_of_week.toLowerCase().split(',')
-        return daysArray.some(day => day.trim() === dayName)
+      if (zone.days_of_week?.length) {
+        return zone.days_of_week.includes(dayName)
       }
 
       return true // If no days specified, applies to all days